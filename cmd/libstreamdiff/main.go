@@ -0,0 +1,95 @@
+//go:build cgo
+
+// Command libstreamdiff builds stream-diff as a C shared library, so data
+// platforms in Python, Java and other languages can embed comparisons
+// without shelling out to the binary.
+//
+// Build it with:
+//
+//	go build -buildmode=c-shared -o libstreamdiff.so ./cmd/libstreamdiff
+//
+// which also writes the libstreamdiff.h header. Every function returning a
+// string returns a JSON document allocated in C memory that the caller must
+// release with stream_diff_free. Results have the shape {"result": {...}},
+// with the same fields as the YAML report, or {"error": "..."}.
+//
+// The functions below form the stable surface of the library; their
+// signatures only change together with STREAM_DIFF_API_VERSION.
+package main
+
+/*
+#include <stdlib.h>
+
+#define STREAM_DIFF_API_VERSION 1
+*/
+import "C"
+
+import (
+	"data-comparator/internal/pkg/comparator"
+	"data-comparator/internal/pkg/config"
+	"encoding/json"
+	"fmt"
+	"unsafe"
+)
+
+// stream_diff_api_version returns the version of the library's C API.
+//
+//export stream_diff_api_version
+func stream_diff_api_version() C.int {
+	return C.STREAM_DIFF_API_VERSION
+}
+
+// stream_diff_compare_files compares the sources of two config files, joining
+// them on key1 and key2. Empty or NULL keys are taken from the configs or
+// inferred from the data.
+//
+//export stream_diff_compare_files
+func stream_diff_compare_files(configPath1, configPath2, key1, key2 *C.char) *C.char {
+	config1, err := config.Load(C.GoString(configPath1))
+	if err != nil {
+		return errorJSON(err)
+	}
+	config2, err := config.Load(C.GoString(configPath2))
+	if err != nil {
+		return errorJSON(err)
+	}
+	return compare(config1, config2, C.GoString(key1), C.GoString(key2))
+}
+
+// stream_diff_compare_run compares the sources of an inline run configuration:
+// a JSON object with config1 and config2 members holding config file contents.
+//
+//export stream_diff_compare_run
+func stream_diff_compare_run(run *C.char) *C.char {
+	parsed, err := config.ParseRun(C.GoString(run))
+	if err != nil {
+		return errorJSON(err)
+	}
+	return compare(parsed.Config1, parsed.Config2, "", "")
+}
+
+// stream_diff_free releases a string returned by the library.
+//
+//export stream_diff_free
+func stream_diff_free(s *C.char) {
+	C.free(unsafe.Pointer(s))
+}
+
+func compare(config1, config2 *config.Config, key1, key2 string) *C.char {
+	result, err := comparator.CompareConfigs(config1, config2, key1, key2)
+	if err != nil {
+		return errorJSON(err)
+	}
+	data, err := result.JSON()
+	if err != nil {
+		return errorJSON(err)
+	}
+	return C.CString(fmt.Sprintf(`{"result":%s}`, data))
+}
+
+func errorJSON(err error) *C.char {
+	data, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return C.CString(string(data))
+}
+
+func main() {}
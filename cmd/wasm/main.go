@@ -0,0 +1,151 @@
+//go:build js && wasm
+
+// Command wasm exposes the comparator to JavaScript, so small CSV and JSON
+// comparisons can run entirely in the browser without uploading data.
+//
+// Build it with:
+//
+//	GOOS=js GOARCH=wasm go build -o stream-diff.wasm ./cmd/wasm
+//
+// and load it with the wasm_exec.js shipped in $(go env GOROOT)/lib/wasm.
+// It registers a global streamDiff object:
+//
+//	streamDiff.compare(text1, text2, options) -> string
+//
+// text1 and text2 hold the contents of the two sources. options is a JSON
+// string or object with optional fields type1 and type2 (csv, json or auto,
+// the default), key, key1 and key2 (inferred when empty), and comparison,
+// which takes the same settings as the comparison section of a config file.
+// The result is the comparison result as a JSON string, or {"error": "..."}.
+package main
+
+import (
+	"data-comparator/internal/pkg/comparator"
+	"data-comparator/internal/pkg/config"
+	"data-comparator/internal/pkg/datareader"
+	"data-comparator/internal/pkg/schema"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"syscall/js"
+
+	"gopkg.in/yaml.v3"
+)
+
+// options are the settings accepted by streamDiff.compare.
+type options struct {
+	Type1      string             `yaml:"type1"`
+	Type2      string             `yaml:"type2"`
+	Key        string             `yaml:"key"`
+	Key1       string             `yaml:"key1"`
+	Key2       string             `yaml:"key2"`
+	Comparison *config.Comparison `yaml:"comparison"`
+}
+
+func main() {
+	js.Global().Set("streamDiff", js.ValueOf(map[string]interface{}{
+		"compare": js.FuncOf(compare),
+	}))
+	select {}
+}
+
+func compare(_ js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return errorJSON(fmt.Errorf("compare needs the contents of both sources"))
+	}
+	var opts options
+	if len(args) > 2 && args[2].Type() != js.TypeUndefined && args[2].Type() != js.TypeNull {
+		raw := args[2]
+		if raw.Type() == js.TypeObject {
+			raw = js.Global().Get("JSON").Call("stringify", raw)
+		}
+		if err := yaml.Unmarshal([]byte(raw.String()), &opts); err != nil {
+			return errorJSON(fmt.Errorf("invalid options: %w", err))
+		}
+	}
+
+	result, err := run(args[0].String(), args[1].String(), opts)
+	if err != nil {
+		return errorJSON(err)
+	}
+	data, err := result.JSON()
+	if err != nil {
+		return errorJSON(err)
+	}
+	return string(data)
+}
+
+func run(text1, text2 string, opts options) (*comparator.Result, error) {
+	src1 := config.Source{Type: orDefault(opts.Type1, "auto"), Path: "source1"}
+	src2 := config.Source{Type: orDefault(opts.Type2, "auto"), Path: "source2"}
+
+	key1, key2 := orDefault(opts.Key1, opts.Key), orDefault(opts.Key2, opts.Key)
+	var err error
+	if key1 == "" {
+		if key1, err = inferKey(text1, src1); err != nil {
+			return nil, err
+		}
+	}
+	if key2 == "" {
+		if key2, err = inferKey(text2, src2); err != nil {
+			return nil, err
+		}
+	}
+	if key1 == "" || key2 == "" {
+		return nil, fmt.Errorf("no key field found; set key, key1 or key2")
+	}
+
+	reader1, err := datareader.NewFromReader(strings.NewReader(text1), src1)
+	if err != nil {
+		return nil, err
+	}
+	defer reader1.Close()
+	reader2, err := datareader.NewFromReader(strings.NewReader(text2), src2)
+	if err != nil {
+		return nil, err
+	}
+	defer reader2.Close()
+
+	if opts.Comparison != nil && opts.Comparison.Script != nil {
+		return nil, fmt.Errorf("comparison.script runs a process, which the browser cannot")
+	}
+	if opts.Comparison != nil && opts.Comparison.Baseline != "" {
+		return nil, fmt.Errorf("comparison.baseline reads a file, which the browser cannot")
+	}
+	options := comparator.OptionsFromConfig(opts.Comparison)
+	if options.FieldRules, err = comparator.CompileFieldRules(opts.Comparison); err != nil {
+		return nil, err
+	}
+	c := comparator.New("")
+	c.SetKeys(key1, key2)
+	c.SetOptions(options)
+	if opts.Comparison != nil {
+		c.SetSkipParseErrors(opts.Comparison.SkipParseErrors)
+	}
+	return c.Compare(reader1, reader2)
+}
+
+func inferKey(text string, src config.Source) (string, error) {
+	reader, err := datareader.NewFromReader(strings.NewReader(text), src)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	s, err := schema.Generate(reader, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to infer the key of %s: %w", src.Path, err)
+	}
+	return s.Key, nil
+}
+
+func errorJSON(err error) string {
+	data, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return string(data)
+}
+
+func orDefault(value, fallback string) string {
+	if value != "" {
+		return value
+	}
+	return fallback
+}
@@ -3,3 +3,12 @@ module data-comparator
 go 1.23
 
 require gopkg.in/yaml.v3 v3.0.1
+
+require (
+	github.com/go-sql-driver/mysql v1.9.3
+	github.com/klauspost/compress v1.18.0
+	github.com/lib/pq v1.10.9
+	google.golang.org/protobuf v1.36.6
+)
+
+require filippo.io/edwards25519 v1.1.0 // indirect
@@ -0,0 +1,8 @@
+// Package integration tests the readers of external sources end to end,
+// through schema generation and comparison, against real servers run in
+// Docker containers. The tests are built with the integration tag:
+//
+//	go test -tags integration ./internal/integration/
+//
+// They are skipped when Docker is not available.
+package integration
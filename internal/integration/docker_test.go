@@ -0,0 +1,165 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"data-comparator/internal/pkg/comparator"
+	"data-comparator/internal/pkg/config"
+	"data-comparator/internal/pkg/datareader"
+	"data-comparator/internal/pkg/schema"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startupTimeout is how long a container may take to accept connections.
+const startupTimeout = 2 * time.Minute
+
+// docker runs the docker CLI and returns its trimmed output.
+func docker(args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("docker", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("docker %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// container is a Docker container started for a test and removed after it.
+type container struct {
+	id string
+}
+
+// startContainer runs image with env and args, publishing its ports on
+// random host ports. The test is skipped if Docker is not available.
+func startContainer(t *testing.T, image string, env []string, args ...string) *container {
+	t.Helper()
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("docker not available")
+	}
+	if _, err := docker("info"); err != nil {
+		t.Skipf("docker daemon not available: %v", err)
+	}
+
+	runArgs := []string{"run", "--detach", "--publish-all"}
+	for _, e := range env {
+		runArgs = append(runArgs, "--env", e)
+	}
+	runArgs = append(append(runArgs, image), args...)
+	id, err := docker(runArgs...)
+	if err != nil {
+		t.Fatalf("failed to start %s: %v", image, err)
+	}
+	t.Cleanup(func() {
+		if _, err := docker("rm", "--force", "--volumes", id); err != nil {
+			t.Logf("failed to remove container %s: %v", id, err)
+		}
+	})
+	return &container{id: id}
+}
+
+// address returns the host address the container port, e.g. "5432/tcp", is
+// published on.
+func (c *container) address(t *testing.T, port string) string {
+	t.Helper()
+	out, err := docker("port", c.id, port)
+	if err != nil {
+		t.Fatalf("failed to find the host port of %s: %v", port, err)
+	}
+	// The first line is the IPv4 binding, e.g. 0.0.0.0:49153.
+	binding := strings.SplitN(out, "\n", 2)[0]
+	return "127.0.0.1" + binding[strings.LastIndex(binding, ":"):]
+}
+
+// exec runs a command in the container.
+func (c *container) exec(t *testing.T, args ...string) {
+	t.Helper()
+	if _, err := docker(append([]string{"exec", c.id}, args...)...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitFor calls ready until it succeeds or startupTimeout passes.
+func waitFor(t *testing.T, what string, ready func() error) {
+	t.Helper()
+	deadline := time.Now().Add(startupTimeout)
+	for {
+		err := ready()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s not ready after %v: %v", what, startupTimeout, err)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// testRows are the rows loaded into every external source, keyed by id.
+var testRows = []struct {
+	id     int
+	name   string
+	amount int
+}{
+	{1, "alice", 100}, {2, "bob", 250}, {3, "carol", 75}, {4, "dave", 310}, {5, "erin", 42},
+	{6, "frank", 990}, {7, "grace", 18}, {8, "heidi", 505}, {9, "ivan", 64}, {10, "judy", 230},
+}
+
+// writeSnapshot writes testRows as a CSV file, as a local snapshot of an
+// external source that missed the last row and has a stale amount for id 3.
+func writeSnapshot(t *testing.T) config.Source {
+	t.Helper()
+	var buf bytes.Buffer
+	buf.WriteString("id,name,amount\n")
+	for _, row := range testRows[:len(testRows)-1] {
+		amount := row.amount
+		if row.id == 3 {
+			amount++
+		}
+		fmt.Fprintf(&buf, "%d,%s,%d\n", row.id, row.name, amount)
+	}
+	path := filepath.Join(t.TempDir(), "snapshot.csv")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return config.Source{Type: "csv", Path: path, Key: "id"}
+}
+
+// checkSource generates the schema of src, which holds testRows, and
+// compares it with the snapshot of writeSnapshot.
+func checkSource(t *testing.T, src config.Source) {
+	t.Helper()
+	reader, err := datareader.New(src)
+	if err != nil {
+		t.Fatalf("datareader.New() error = %v", err)
+	}
+	generated, err := schema.Generate(reader, nil)
+	reader.Close()
+	if err != nil {
+		t.Fatalf("schema.Generate() error = %v", err)
+	}
+	for _, field := range []string{"id", "name", "amount"} {
+		if generated.Fields[field] == nil {
+			t.Errorf("schema fields got = %v, want %s among them", generated.Fields, field)
+		}
+	}
+
+	result, err := comparator.CompareConfigs(&config.Config{Source: src}, &config.Config{Source: writeSnapshot(t)}, "id", "id")
+	if err != nil {
+		t.Fatalf("CompareConfigs() error = %v", err)
+	}
+	s := result.Summary
+	if s.Source1Rows != len(testRows) || s.MatchingKeys != len(testRows)-1 || s.IdenticalRows != len(testRows)-2 || s.KeysOnlyInSource1 != 1 || s.KeysOnlyInSource2 != 0 {
+		t.Errorf("Summary got = %+v, want %d rows, all but one matching and one of those differing", s, len(testRows))
+	}
+	if diffs := result.ValueDiffs["3"]; len(diffs) != 1 || diffs[0].Field != "amount" {
+		t.Errorf("ValueDiffs[3] got = %v, want the amount", diffs)
+	}
+}
@@ -0,0 +1,94 @@
+//go:build integration
+
+package integration
+
+import (
+	"data-comparator/internal/pkg/config"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+)
+
+// loadTable creates a table of testRows in the database db.
+func loadTable(t *testing.T, db *sql.DB, placeholders func(i int) string) {
+	t.Helper()
+	if _, err := db.Exec("CREATE TABLE accounts (id INT PRIMARY KEY, name VARCHAR(50), amount INT)"); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	insert := fmt.Sprintf("INSERT INTO accounts (id, name, amount) VALUES (%s, %s, %s)", placeholders(1), placeholders(2), placeholders(3))
+	for _, row := range testRows {
+		if _, err := db.Exec(insert, row.id, row.name, row.amount); err != nil {
+			t.Fatalf("failed to insert row %d: %v", row.id, err)
+		}
+	}
+}
+
+func TestPostgres(t *testing.T) {
+	c := startContainer(t, "postgres:16-alpine", []string{"POSTGRES_PASSWORD=secret"})
+	dsn := fmt.Sprintf("postgres://postgres:secret@%s/postgres?sslmode=disable", c.address(t, "5432/tcp"))
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	waitFor(t, "postgres", db.Ping)
+	loadTable(t, db, func(i int) string { return fmt.Sprintf("$%d", i) })
+
+	checkSource(t, config.Source{Type: "postgres", DSN: dsn, Table: "public.accounts", Key: "id", FetchSize: 3})
+	checkSource(t, config.Source{Type: "postgres", DSN: dsn, Query: "SELECT id, name, amount FROM accounts ORDER BY id", Key: "id"})
+}
+
+func TestMySQL(t *testing.T) {
+	c := startContainer(t, "mysql:8.4", []string{"MYSQL_ROOT_PASSWORD=secret", "MYSQL_DATABASE=test"})
+	dsn := fmt.Sprintf("root:secret@tcp(%s)/test", c.address(t, "3306/tcp"))
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	waitFor(t, "mysql", db.Ping)
+	loadTable(t, db, func(int) string { return "?" })
+
+	checkSource(t, config.Source{Type: "mysql", DSN: dsn, Table: "accounts", Key: "id"})
+}
+
+func TestMinIO(t *testing.T) {
+	c := startContainer(t, "minio/minio:latest", []string{"MINIO_ROOT_USER=minio", "MINIO_ROOT_PASSWORD=minio-secret"}, "server", "/data")
+	endpoint := "http://" + c.address(t, "9000/tcp")
+	waitFor(t, "minio", func() error {
+		resp, err := http.Get(endpoint + "/minio/health/ready")
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("health check returned %s", resp.Status)
+		}
+		return nil
+	})
+
+	var data []byte
+	data = append(data, "id,name,amount\n"...)
+	for _, row := range testRows {
+		data = fmt.Appendf(data, "%d,%s,%d\n", row.id, row.name, row.amount)
+	}
+	path := filepath.Join(t.TempDir(), "accounts.csv")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := docker("cp", path, c.id+":/tmp/accounts.csv"); err != nil {
+		t.Fatal(err)
+	}
+	c.exec(t, "mc", "alias", "set", "local", "http://localhost:9000", "minio", "minio-secret")
+	c.exec(t, "mc", "mb", "local/exports")
+	c.exec(t, "mc", "cp", "/tmp/accounts.csv", "local/exports/accounts.csv")
+
+	store := &config.ObjectStore{Endpoint: endpoint, AccessKeyID: "minio", SecretAccessKey: "minio-secret", PartSize: 64}
+	checkSource(t, config.Source{Type: "csv", Path: "s3://exports/accounts.csv", Key: "id", ObjectStore: store})
+}
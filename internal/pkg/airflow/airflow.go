@@ -0,0 +1,58 @@
+// Package airflow writes the key metrics of a comparison as an Airflow XCom
+// return value, so DAGs can branch on diff rates without parsing the report.
+package airflow
+
+import (
+	"data-comparator/internal/pkg/comparator"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DefaultXComPath is where Airflow's KubernetesPodOperator and
+// DockerOperator pick up the return value of a task.
+const DefaultXComPath = "/airflow/xcom/return.json"
+
+// XCom holds the key metrics of a comparison.
+type XCom struct {
+	InSync            bool    `json:"in_sync"`
+	Source1Rows       int     `json:"source1_rows"`
+	Source2Rows       int     `json:"source2_rows"`
+	MatchingKeys      int     `json:"matching_keys"`
+	IdenticalRows     int     `json:"identical_rows"`
+	KeysOnlyInSource1 int     `json:"keys_only_in_source1"`
+	KeysOnlyInSource2 int     `json:"keys_only_in_source2"`
+	DiffRate          float64 `json:"diff_rate"`
+}
+
+// NewXCom extracts the key metrics of result.
+func NewXCom(result *comparator.Result) XCom {
+	s := result.Summary
+	return XCom{
+		InSync:            result.Holds(),
+		Source1Rows:       s.Source1Rows,
+		Source2Rows:       s.Source2Rows,
+		MatchingKeys:      s.MatchingKeys,
+		IdenticalRows:     s.IdenticalRows,
+		KeysOnlyInSource1: s.KeysOnlyInSource1,
+		KeysOnlyInSource2: s.KeysOnlyInSource2,
+		DiffRate:          s.DiffRate(),
+	}
+}
+
+// WriteXCom writes the key metrics of result as JSON to path, creating its
+// directory if needed.
+func WriteXCom(path string, result *comparator.Result) error {
+	data, err := json.Marshal(NewXCom(result))
+	if err != nil {
+		return fmt.Errorf("failed to marshal xcom: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create xcom directory for %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write xcom file %s: %w", path, err)
+	}
+	return nil
+}
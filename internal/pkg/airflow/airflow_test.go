@@ -0,0 +1,44 @@
+package airflow
+
+import (
+	"data-comparator/internal/pkg/comparator"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteXCom(t *testing.T) {
+	result := &comparator.Result{Summary: comparator.Summary{
+		Source1Rows: 5, Source2Rows: 5, MatchingKeys: 4, IdenticalRows: 3,
+		KeysOnlyInSource1: 1, KeysOnlyInSource2: 1,
+	}}
+	path := filepath.Join(t.TempDir(), "airflow", "xcom", "return.json")
+
+	if err := WriteXCom(path, result); err != nil {
+		t.Fatalf("WriteXCom() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	var xcom XCom
+	if err := json.Unmarshal(data, &xcom); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	expected := XCom{
+		Source1Rows: 5, Source2Rows: 5, MatchingKeys: 4, IdenticalRows: 3,
+		KeysOnlyInSource1: 1, KeysOnlyInSource2: 1, DiffRate: 0.25,
+	}
+	if xcom != expected {
+		t.Errorf("XCom got = %+v, want %+v", xcom, expected)
+	}
+}
+
+func TestNewXCom_InSync(t *testing.T) {
+	xcom := NewXCom(&comparator.Result{Summary: comparator.Summary{MatchingKeys: 3, IdenticalRows: 3}})
+	if !xcom.InSync || xcom.DiffRate != 0 {
+		t.Errorf("XCom got = %+v, want in sync with zero diff rate", xcom)
+	}
+}
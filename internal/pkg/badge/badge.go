@@ -0,0 +1,87 @@
+// Package badge renders the parity score of a comparison as a shields.io
+// endpoint badge or a standalone SVG, for embedding in wikis and READMEs.
+package badge
+
+import (
+	"data-comparator/internal/pkg/comparator"
+	"encoding/json"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// Label is the text on the left side of a badge.
+const Label = "data parity"
+
+// Endpoint is the JSON schema shields.io reads from an endpoint badge URL.
+type Endpoint struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+// colors maps the lowest parity score of each band to its badge color.
+var colors = []struct {
+	min   float64
+	color string
+}{
+	{99, "brightgreen"}, {95, "green"}, {90, "yellowgreen"}, {80, "yellow"}, {50, "orange"}, {0, "red"},
+}
+
+// hexColors holds the shields.io colors used in standalone SVG badges.
+var hexColors = map[string]string{
+	"brightgreen": "#4c1", "green": "#97ca00", "yellowgreen": "#a4a61d",
+	"yellow": "#dfb317", "orange": "#fe7d37", "red": "#e05d44",
+}
+
+// New builds the badge of a comparison result from its parity score.
+func New(result *comparator.Result) Endpoint {
+	parity := 0.0
+	if result.Scorecard != nil {
+		parity = result.Scorecard.Parity
+	}
+	color := colors[len(colors)-1].color
+	for _, band := range colors {
+		if parity >= band.min {
+			color = band.color
+			break
+		}
+	}
+	return Endpoint{
+		SchemaVersion: 1,
+		Label:         Label,
+		Message:       strconv.FormatFloat(parity, 'f', -1, 64) + "%",
+		Color:         color,
+	}
+}
+
+// JSON renders the badge for a shields.io endpoint URL.
+func (e Endpoint) JSON() ([]byte, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal badge: %w", err)
+	}
+	return data, nil
+}
+
+// SVG renders the badge as a flat standalone image.
+func (e Endpoint) SVG() []byte {
+	// Approximate Verdana 11px text width, as shields.io does for its flat style.
+	labelWidth := 10 + 7*len(e.Label)
+	messageWidth := 10 + 7*len(e.Message)
+	width := labelWidth + messageWidth
+	label, message := html.EscapeString(e.Label), html.EscapeString(e.Message)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`, width, label, message)
+	fmt.Fprintf(&b, `<title>%s: %s</title>`, label, message)
+	fmt.Fprintf(&b, `<rect width="%d" height="20" fill="#555"/>`, labelWidth)
+	fmt.Fprintf(&b, `<rect x="%d" width="%d" height="20" fill="%s"/>`, labelWidth, messageWidth, hexColors[e.Color])
+	fmt.Fprintf(&b, `<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`)
+	fmt.Fprintf(&b, `<text x="%d" y="14">%s</text>`, labelWidth/2, label)
+	fmt.Fprintf(&b, `<text x="%d" y="14">%s</text>`, labelWidth+messageWidth/2, message)
+	b.WriteString(`</g></svg>`)
+	return []byte(b.String())
+}
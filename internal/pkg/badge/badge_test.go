@@ -0,0 +1,38 @@
+package badge
+
+import (
+	"data-comparator/internal/pkg/comparator"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		parity  float64
+		message string
+		color   string
+	}{
+		{100, "100%", "brightgreen"},
+		{99.5, "99.5%", "brightgreen"},
+		{96.25, "96.25%", "green"},
+		{85, "85%", "yellow"},
+		{50, "50%", "orange"},
+		{12.3, "12.3%", "red"},
+		{0, "0%", "red"},
+	}
+	for _, tt := range tests {
+		got := New(&comparator.Result{Scorecard: &comparator.Scorecard{Parity: tt.parity}})
+		if got.Message != tt.message || got.Color != tt.color || got.SchemaVersion != 1 {
+			t.Errorf("New(%v) got = %+v, want message %s and color %s", tt.parity, got, tt.message, tt.color)
+		}
+	}
+}
+
+func TestSVG(t *testing.T) {
+	svg := string(New(&comparator.Result{Scorecard: &comparator.Scorecard{Parity: 42}}).SVG())
+	for _, want := range []string{"<svg", "data parity: 42%", "#e05d44"} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("SVG missing %q: %s", want, svg)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Package capture records the records of a source to a capture file, which
+// can be replayed later as a source of type "capture", e.g. to debug an issue
+// seen against a live source offline.
+package capture
+
+import (
+	"data-comparator/internal/pkg/config"
+	"data-comparator/internal/pkg/datareader"
+	"data-comparator/internal/pkg/schema"
+	"fmt"
+	"io"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Write reads every record of src and writes them to w as a capture, with the
+// time each record was read and the schema inferred from the first records.
+// now is called for every record; nil means time.Now. It returns the number
+// of records captured.
+func Write(w io.Writer, src config.Source, now func() time.Time) (int, error) {
+	if now == nil {
+		now = time.Now
+	}
+	reader, err := datareader.New(src)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create reader: %w", err)
+	}
+	defer reader.Close()
+
+	cw, err := datareader.NewCaptureWriter(w, src, now())
+	if err != nil {
+		return 0, err
+	}
+	sampleSize := schema.DefaultSampleSize
+	if src.Sampler != nil && src.Sampler.SampleSize > 0 {
+		sampleSize = src.Sampler.SampleSize
+	}
+
+	var sample []datareader.Record
+	count := 0
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return count, fmt.Errorf("failed to read record %d: %w", count+1, err)
+		}
+		if err := cw.Write(rec, now()); err != nil {
+			return count, err
+		}
+		count++
+		if len(sample) < sampleSize {
+			sample = append(sample, rec)
+		}
+	}
+
+	inferred, err := schema.Generate(datareader.NewSliceReader(sample), &config.Sampler{SampleSize: sampleSize})
+	if err != nil {
+		return count, fmt.Errorf("failed to infer schema: %w", err)
+	}
+	schemaYAML, err := yaml.Marshal(inferred)
+	if err != nil {
+		return count, fmt.Errorf("failed to marshal schema: %w", err)
+	}
+	return count, cw.Close(string(schemaYAML))
+}
@@ -0,0 +1,107 @@
+package capture
+
+import (
+	"bytes"
+	"compress/gzip"
+	"data-comparator/internal/pkg/config"
+	"data-comparator/internal/pkg/datareader"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readAll(t *testing.T, reader datareader.DataReader) []datareader.Record {
+	t.Helper()
+	var records []datareader.Record
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			return records
+		}
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		records = append(records, rec)
+	}
+}
+
+func TestWrite_Replay(t *testing.T) {
+	src := config.Source{Type: "csv", Path: "../../../testdata/testcase1_simple_csv/source1.csv"}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	ticks := 0
+	now := func() time.Time {
+		ticks++
+		return start.Add(time.Duration(ticks-1) * time.Second)
+	}
+
+	var buf bytes.Buffer
+	count, err := Write(&buf, src, now)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if count != 5 {
+		t.Errorf("Write() count got = %d, want 5", count)
+	}
+	path := filepath.Join(t.TempDir(), "capture.sdz")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	original, err := datareader.New(src)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer original.Close()
+	replay, err := datareader.New(config.Source{Type: "capture", Path: path})
+	if err != nil {
+		t.Fatalf("New() capture error = %v", err)
+	}
+	defer replay.Close()
+
+	if got, want := readAll(t, replay), readAll(t, original); !reflect.DeepEqual(got, want) {
+		t.Errorf("Replayed records got = %v, want %v", got, want)
+	}
+	cr := replay.(*datareader.CaptureReader)
+	if cr.Header().Source != src || !cr.Header().CapturedAt.Equal(start) {
+		t.Errorf("Header() got = %+v", cr.Header())
+	}
+	if cr.Offset() != 5*time.Second {
+		t.Errorf("Offset() got = %v, want 5s", cr.Offset())
+	}
+	trailer := cr.Trailer()
+	if trailer == nil || trailer.Records != 5 || !strings.Contains(trailer.Schema, "key: user_id") {
+		t.Errorf("Trailer() got = %+v, want 5 records and a schema keyed on user_id", trailer)
+	}
+}
+
+func TestReplay_Truncated(t *testing.T) {
+	// A capture interrupted before its trailer was written.
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(`{"format": "stream-diff-capture", "version": 1, "source": {"type": "json", "path": "live"}}` + "\n"))
+	gz.Write([]byte(`{"t": 0, "r": {"id": "1"}}` + "\n"))
+	gz.Close()
+	path := filepath.Join(t.TempDir(), "capture.sdz")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := datareader.New(config.Source{Type: "capture", Path: path})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer reader.Close()
+	if _, err := reader.Read(); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	_, err = reader.Read()
+	var perr *datareader.ParseError
+	if !errors.As(err, &perr) {
+		t.Errorf("Read() past the last record got = %v, want a ParseError", err)
+	}
+}
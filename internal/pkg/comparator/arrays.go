@@ -0,0 +1,176 @@
+package comparator
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ArrayMode is how the array values of a field are compared.
+type ArrayMode string
+
+const (
+	// ArrayOrdered compares arrays element by element, as whole values.
+	ArrayOrdered ArrayMode = "ordered"
+	// ArraySet compares arrays ignoring the order of their elements, as
+	// multisets of their canonical forms.
+	ArraySet ArrayMode = "set"
+	// ArrayKeyed matches the object elements of arrays by a key field and
+	// compares the matched elements field by field.
+	ArrayKeyed ArrayMode = "keyed"
+)
+
+// ArrayModes lists the array modes, the default first.
+var ArrayModes = []ArrayMode{ArrayOrdered, ArraySet, ArrayKeyed}
+
+// parseArrayMode returns the array mode named s, or "" for an empty s.
+func parseArrayMode(s string) (ArrayMode, error) {
+	if s == "" {
+		return "", nil
+	}
+	names := make([]string, len(ArrayModes))
+	for i, mode := range ArrayModes {
+		if string(mode) == s {
+			return mode, nil
+		}
+		names[i] = string(mode)
+	}
+	return "", fmt.Errorf("unsupported array mode %s, use one of %s", s, strings.Join(names, ", "))
+}
+
+// compareArrays compares the values of field by its array mode if both are
+// arrays and the mode is not ordered. It returns false to compare them as
+// whole values instead.
+func compareArrays(field string, v1, v2 interface{}, options Options) ([]FieldDiff, bool) {
+	a1, ok1 := v1.([]interface{})
+	a2, ok2 := v2.([]interface{})
+	if !ok1 || !ok2 {
+		return nil, false
+	}
+	rule := options.FieldRules.of(field)
+	switch rule.array {
+	case ArraySet:
+		if sameElements(field, a1, a2, options) {
+			return nil, true
+		}
+		return []FieldDiff{{Field: field, Source1Value: v1, Source2Value: v2}}, true
+	case ArrayKeyed:
+		return compareKeyedArrays(field, a1, a2, rule.arrayKey, options)
+	default:
+		return nil, false
+	}
+}
+
+// sameElements reports whether a1 and a2 hold the same elements, counted
+// with repetition, in any order.
+func sameElements(field string, a1, a2 []interface{}, options Options) bool {
+	if len(a1) != len(a2) {
+		return false
+	}
+	e1, e2 := encodeElements(field, a1, options), encodeElements(field, a2, options)
+	for i := range e1 {
+		if e1[i] != e2[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeElements returns the sorted canonical JSON of the elements of a.
+func encodeElements(field string, a []interface{}, options Options) []string {
+	encoded := make([]string, len(a))
+	for i, element := range a {
+		data, err := json.Marshal(options.canonicalTree(field+"[]", element))
+		if err != nil {
+			data = []byte(fmt.Sprintf("%v", element))
+		}
+		encoded[i] = string(data)
+	}
+	sort.Strings(encoded)
+	return encoded
+}
+
+// compareKeyedArrays matches the elements of a1 and a2 by their key field
+// and returns the diffs of matched elements and the elements only in one
+// array, named like items[sku=A1].qty. It returns false if an element is
+// not an object with a unique key.
+func compareKeyedArrays(field string, a1, a2 []interface{}, key string, options Options) ([]FieldDiff, bool) {
+	elements1, ok := elementsByKey(a1, key)
+	if !ok {
+		return nil, false
+	}
+	elements2, ok := elementsByKey(a2, key)
+	if !ok {
+		return nil, false
+	}
+
+	keys := make([]string, 0, len(elements1)+len(elements2))
+	for k := range elements1 {
+		keys = append(keys, k)
+	}
+	for k := range elements2 {
+		if _, ok := elements1[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var diffs []FieldDiff
+	for _, k := range keys {
+		element := fmt.Sprintf("%s[%s=%s]", field, key, k)
+		e1, ok1 := elements1[k]
+		e2, ok2 := elements2[k]
+		if !ok1 || !ok2 {
+			diff := FieldDiff{Field: element}
+			if ok1 {
+				diff.Source1Value = e1
+			} else {
+				diff.Source2Value = e2
+			}
+			diffs = append(diffs, diff)
+			continue
+		}
+		for _, diff := range compareFields(field+"[]", e1, e2, options) {
+			diff.Field = element + strings.TrimPrefix(diff.Field, field+"[]")
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs, true
+}
+
+// elementsByKey indexes the object elements of a by the value of their key
+// field. It returns false if an element is not an object or its key is
+// missing or repeated.
+func elementsByKey(a []interface{}, key string) (map[string]map[string]interface{}, bool) {
+	elements := make(map[string]map[string]interface{}, len(a))
+	for _, element := range a {
+		m, ok := element.(map[string]interface{})
+		if !ok || m[key] == nil {
+			return nil, false
+		}
+		k := fmt.Sprintf("%v", m[key])
+		if _, repeated := elements[k]; repeated {
+			return nil, false
+		}
+		elements[k] = m
+	}
+	return elements, true
+}
+
+// sortElements sorts canonical array elements by their JSON encoding.
+func sortElements(a []interface{}) {
+	type encoded struct {
+		json    string
+		element interface{}
+	}
+	elements := make([]encoded, len(a))
+	for i, element := range a {
+		data, _ := json.Marshal(element)
+		elements[i] = encoded{json: string(data), element: element}
+	}
+	sort.SliceStable(elements, func(i, j int) bool { return elements[i].json < elements[j].json })
+	for i, e := range elements {
+		a[i] = e.element
+	}
+}
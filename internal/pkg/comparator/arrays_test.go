@@ -0,0 +1,101 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/config"
+	"data-comparator/internal/pkg/datareader"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func arrayOptions(t *testing.T, rules map[string]config.FieldRule) Options {
+	t.Helper()
+	compiled, err := CompileFieldRules(&config.Comparison{FieldRules: rules})
+	if err != nil {
+		t.Fatalf("CompileFieldRules() error = %v", err)
+	}
+	options := DefaultOptions()
+	options.FieldRules = compiled
+	return options
+}
+
+func TestCompareRecords_ArrayModes(t *testing.T) {
+	rec1 := datareader.Record{
+		"tags": []interface{}{"a", "b", "b"},
+		"line_items": []interface{}{
+			map[string]interface{}{"sku": "A1", "qty": 1.0},
+			map[string]interface{}{"sku": "B2", "qty": 2.0},
+			map[string]interface{}{"sku": "C3", "qty": 3.0},
+		},
+	}
+	rec2 := datareader.Record{
+		"tags": []interface{}{"b", "a", "b"},
+		"line_items": []interface{}{
+			map[string]interface{}{"sku": "B2", "qty": 5.0},
+			map[string]interface{}{"sku": "A1", "qty": 1.0},
+			map[string]interface{}{"sku": "D4", "qty": 4.0},
+		},
+	}
+
+	if diffs := compareRecords(rec1, rec2, DefaultOptions()); len(diffs) != 2 {
+		t.Errorf("compareRecords() ordered got = %v, want both arrays differing", diffs)
+	}
+
+	options := arrayOptions(t, map[string]config.FieldRule{
+		"tags":       {Array: "set"},
+		"line_items": {Array: "keyed", ArrayKey: "sku"},
+	})
+	got := compareRecords(rec1, rec2, options)
+	want := []FieldDiff{
+		{Field: "line_items[sku=B2].qty", Source1Value: 2.0, Source2Value: 5.0},
+		{Field: "line_items[sku=C3]", Source1Value: map[string]interface{}{"sku": "C3", "qty": 3.0}},
+		{Field: "line_items[sku=D4]", Source2Value: map[string]interface{}{"sku": "D4", "qty": 4.0}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("compareRecords() got = %+v, want %+v", got, want)
+	}
+
+	// A set differing in how often an element occurs differs as a whole.
+	rec2["tags"] = []interface{}{"a", "a", "b"}
+	got = compareRecords(datareader.Record{"tags": rec1["tags"]}, datareader.Record{"tags": rec2["tags"]}, options)
+	if len(got) != 1 || got[0].Field != "tags" {
+		t.Errorf("compareRecords() of differing sets got = %+v, want a diff of tags", got)
+	}
+}
+
+func TestCompareRecords_KeyedArrayFallback(t *testing.T) {
+	options := arrayOptions(t, map[string]config.FieldRule{"items": {Array: "keyed", ArrayKey: "id"}})
+	rec1 := datareader.Record{"items": []interface{}{map[string]interface{}{"id": 1.0}, map[string]interface{}{"id": 1.0}}}
+	rec2 := datareader.Record{"items": []interface{}{map[string]interface{}{"id": 1.0}}}
+	got := compareRecords(rec1, rec2, options)
+	if len(got) != 1 || got[0].Field != "items" {
+		t.Errorf("compareRecords() with repeated element keys got = %+v, want a diff of the whole array", got)
+	}
+}
+
+func TestHash_UnorderedArrays(t *testing.T) {
+	options := arrayOptions(t, map[string]config.FieldRule{"**.tags": {Array: "set"}})
+	h1, err := options.Hash(datareader.Record{"doc": map[string]interface{}{"tags": []interface{}{"x", "y"}}})
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	h2, err := options.Hash(datareader.Record{"doc": map[string]interface{}{"tags": []interface{}{"y", "x"}}})
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	if h1 != h2 {
+		t.Errorf("Hash() of reordered set got = %s and %s, want equal", h1, h2)
+	}
+}
+
+func TestCompileFieldRules_ArrayErrors(t *testing.T) {
+	for want, rule := range map[string]config.FieldRule{
+		"unsupported array mode": {Array: "bag"},
+		"need an array_key":      {Array: "keyed"},
+	} {
+		_, err := CompileFieldRules(&config.Comparison{FieldRules: map[string]config.FieldRule{"items": rule}})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("CompileFieldRules(%+v) error = %v, want one containing %q", rule, err, want)
+		}
+	}
+}
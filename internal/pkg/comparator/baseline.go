@@ -0,0 +1,153 @@
+package comparator
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Baseline lists known, accepted diffs that are subtracted from comparison
+// results, so recurring discrepancies don't drown new regressions.
+type Baseline struct {
+	Entries []BaselineEntry `yaml:"entries"`
+}
+
+// BaselineEntry accepts diffs on the fields matching Fields for the keys
+// matching Key. Both use shell glob patterns as in path.Match, e.g. "*" or
+// "metrics.*". An entry without fields accepts every field of its keys.
+// Entries with an expiry acknowledge a diff temporarily and stop applying
+// once it has passed.
+type BaselineEntry struct {
+	Key     string     `yaml:"key"`
+	Fields  []string   `yaml:"fields,omitempty"`
+	Reason  string     `yaml:"reason,omitempty"`
+	Expires *time.Time `yaml:"expires,omitempty"`
+}
+
+// LoadBaseline reads a baseline YAML file.
+func LoadBaseline(filePath string) (*Baseline, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline file %s: %w", filePath, err)
+	}
+
+	var b Baseline
+	if err := yaml.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal yaml from %s: %w", filePath, err)
+	}
+	for _, entry := range b.Entries {
+		if err := entry.validate(); err != nil {
+			return nil, fmt.Errorf("invalid baseline %s: %w", filePath, err)
+		}
+	}
+	return &b, nil
+}
+
+// BaselineFromReport builds a baseline accepting every value diff of a
+// previously written comparison report.
+func BaselineFromReport(filePath string) (*Baseline, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report file %s: %w", filePath, err)
+	}
+
+	var result Result
+	if err := yaml.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal yaml from %s: %w", filePath, err)
+	}
+	return BaselineFromResult(&result), nil
+}
+
+// BaselineFromResult builds a baseline accepting every value diff of result.
+func BaselineFromResult(result *Result) *Baseline {
+	b := &Baseline{}
+	keys := make([]string, 0, len(result.ValueDiffs))
+	for key := range result.ValueDiffs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		entry := BaselineEntry{Key: key}
+		for _, diff := range result.ValueDiffs[key] {
+			entry.Fields = append(entry.Fields, diff.Field)
+		}
+		b.Entries = append(b.Entries, entry)
+	}
+	return b
+}
+
+// Acknowledge adds an entry accepting diffs on field for key until expires,
+// or indefinitely if expires is zero.
+func (b *Baseline) Acknowledge(key, field, reason string, expires time.Time) {
+	entry := BaselineEntry{Key: key, Reason: reason}
+	if field != "" {
+		entry.Fields = []string{field}
+	}
+	if !expires.IsZero() {
+		entry.Expires = &expires
+	}
+	b.Entries = append(b.Entries, entry)
+}
+
+// Accepts reports whether a diff on field for key is a known, accepted diff
+// at time now.
+func (b *Baseline) Accepts(key, field string, now time.Time) bool {
+	if b == nil {
+		return false
+	}
+	for _, entry := range b.Entries {
+		if entry.Expires != nil && !now.Before(*entry.Expires) {
+			continue
+		}
+		if !matchPattern(entry.Key, key) {
+			continue
+		}
+		if len(entry.Fields) == 0 {
+			return true
+		}
+		for _, pattern := range entry.Fields {
+			if matchPattern(pattern, field) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// subtract returns the diffs of key that the baseline does not accept.
+func (b *Baseline) subtract(key string, diffs []FieldDiff, now time.Time) []FieldDiff {
+	if b == nil {
+		return diffs
+	}
+	var kept []FieldDiff
+	for _, diff := range diffs {
+		if !b.Accepts(key, diff.Field, now) {
+			kept = append(kept, diff)
+		}
+	}
+	return kept
+}
+
+func (e BaselineEntry) validate() error {
+	if e.Key == "" {
+		return fmt.Errorf("entry without key")
+	}
+	for _, pattern := range append([]string{e.Key}, e.Fields...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("bad pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+func matchPattern(pattern, name string) bool {
+	if pattern == name {
+		return true
+	}
+	ok, err := path.Match(pattern, name)
+	return err == nil && ok
+}
@@ -0,0 +1,117 @@
+package comparator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestBaseline_Accepts(t *testing.T) {
+	b := &Baseline{Entries: []BaselineEntry{
+		{Key: "1", Fields: []string{"last_login", "metrics.*"}},
+		{Key: "evt-*"},
+	}}
+
+	tests := []struct {
+		key, field string
+		want       bool
+	}{
+		{"1", "last_login", true},
+		{"1", "metrics.latency_ms", true},
+		{"1", "age", false},
+		{"2", "last_login", false},
+		{"evt-001", "anything", true},
+	}
+	for _, tt := range tests {
+		if got := b.Accepts(tt.key, tt.field, time.Now()); got != tt.want {
+			t.Errorf("Accepts(%q, %q) got = %v, want %v", tt.key, tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestCompare_Baseline(t *testing.T) {
+	reader1, reader2 := openReaders(t, "testcase1_simple_csv", "csv", "csv")
+
+	c := New("user_id")
+	c.SetBaseline(&Baseline{Entries: []BaselineEntry{{Key: "1", Fields: []string{"age", "last_login"}}}})
+	result, err := c.Compare(reader1, reader2)
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+
+	if result.Summary.BaselinedDiffs != 2 {
+		t.Errorf("BaselinedDiffs got = %d, want 2", result.Summary.BaselinedDiffs)
+	}
+	if diffs := result.ValueDiffs["1"]; len(diffs) != 1 || diffs[0].Field != "plan_type" {
+		t.Errorf("ValueDiffs[1] got = %v, want only plan_type", diffs)
+	}
+
+	reader1, reader2 = openReaders(t, "testcase1_simple_csv", "csv", "csv")
+	c.SetBaseline(&Baseline{Entries: []BaselineEntry{{Key: "1"}}})
+	result, err = c.Compare(reader1, reader2)
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	if len(result.ValueDiffs) != 0 || result.Summary.IdenticalRows != 4 {
+		t.Errorf("Expected fully baselined key to count as identical, got %+v", result.Summary)
+	}
+}
+
+func TestBaselineFromReport(t *testing.T) {
+	reader1, reader2 := openReaders(t, "testcase1_simple_csv", "csv", "csv")
+	result, err := New("user_id").Compare(reader1, reader2)
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	data, err := yaml.Marshal(result)
+	if err != nil {
+		t.Fatalf("yaml.Marshal() error = %v", err)
+	}
+	report := filepath.Join(t.TempDir(), "report.yaml")
+	if err := os.WriteFile(report, data, 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	b, err := BaselineFromReport(report)
+	if err != nil {
+		t.Fatalf("BaselineFromReport() error = %v", err)
+	}
+	expected := []BaselineEntry{{Key: "1", Fields: []string{"age", "last_login", "plan_type"}}}
+	if !reflect.DeepEqual(b.Entries, expected) {
+		t.Errorf("Entries got = %+v, want %+v", b.Entries, expected)
+	}
+}
+
+func TestLoadBaseline_InvalidPattern(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "baseline.yaml")
+	if err := os.WriteFile(file, []byte("entries:\n  - key: \"[\"\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if _, err := LoadBaseline(file); err == nil {
+		t.Error("LoadBaseline() expected error for bad pattern, got nil")
+	}
+}
+
+func TestBaseline_AcknowledgeExpires(t *testing.T) {
+	now := time.Date(2025, 9, 10, 12, 0, 0, 0, time.UTC)
+	b := &Baseline{}
+	b.Acknowledge("1", "age", "birthday backfill", now.Add(24*time.Hour))
+	b.Acknowledge("2", "", "known bad row", time.Time{})
+
+	if !b.Accepts("1", "age", now) {
+		t.Error("Expected acknowledged diff to be accepted before expiry")
+	}
+	if b.Accepts("1", "age", now.Add(24*time.Hour)) {
+		t.Error("Expected acknowledged diff to apply no longer after expiry")
+	}
+	if b.Accepts("1", "plan_type", now) {
+		t.Error("Expected other fields of an acknowledged key not to be accepted")
+	}
+	if !b.Accepts("2", "plan_type", now.AddDate(10, 0, 0)) {
+		t.Error("Expected acknowledgment without expiry to apply indefinitely")
+	}
+}
@@ -0,0 +1,75 @@
+package comparator
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// CategoryBinary marks diffs of binary values, which are reported as digests.
+const CategoryBinary = "binary"
+
+// BlobDigest stands in for a binary value in diffs, so reports show what
+// changed without dumping the data itself.
+type BlobDigest struct {
+	Size   int    `yaml:"size" json:"size"`
+	SHA256 string `yaml:"sha256" json:"sha256"`
+}
+
+// compareBlobs compares two values as binary data when either of them is a
+// []byte or the field is configured as binary. It reports whether the values
+// were handled as blobs and, if they differ, the resulting diff.
+func compareBlobs(field string, v1, v2 interface{}, options Options) (*FieldDiff, bool) {
+	d1, ok1 := blobDigest(field, v1, options)
+	d2, ok2 := blobDigest(field, v2, options)
+	if !ok1 && !ok2 {
+		return nil, false
+	}
+	if ok1 && ok2 && *d1 == *d2 {
+		return nil, true
+	}
+
+	diff := &FieldDiff{Field: field, Category: CategoryBinary}
+	if d1 != nil {
+		diff.Source1Value = *d1
+	} else {
+		diff.Source1Value = v1
+	}
+	if d2 != nil {
+		diff.Source2Value = *d2
+	} else {
+		diff.Source2Value = v2
+	}
+	return diff, true
+}
+
+func blobDigest(field string, v interface{}, options Options) (*BlobDigest, bool) {
+	var data []byte
+	switch b := v.(type) {
+	case []byte:
+		data = b
+	case string:
+		if !options.isBinary(field) {
+			return nil, false
+		}
+		data = decodeBase64(b)
+	default:
+		return nil, false
+	}
+
+	sum := sha256.Sum256(data)
+	return &BlobDigest{Size: len(data), SHA256: hex.EncodeToString(sum[:])}, true
+}
+
+// decodeBase64 decodes standard or URL-safe base64, padded or not. Strings
+// that are not valid base64 are hashed as they are.
+func decodeBase64(s string) []byte {
+	for _, enc := range []*base64.Encoding{
+		base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding,
+	} {
+		if data, err := enc.DecodeString(s); err == nil {
+			return data
+		}
+	}
+	return []byte(s)
+}
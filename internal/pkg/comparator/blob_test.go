@@ -0,0 +1,33 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"testing"
+)
+
+func TestCompareRecords_BinaryFields(t *testing.T) {
+	options := DefaultOptions()
+	options.BinaryFields = []string{"payload"}
+
+	rec1 := datareader.Record{"payload": "aGVsbG8=", "raw": []byte("abc")}
+	rec2 := datareader.Record{"payload": "aGVsbG8", "raw": []byte("abd")}
+
+	diffs := compareRecords(rec1, rec2, options)
+	if len(diffs) != 1 {
+		t.Fatalf("Expected 1 diff, got %d: %v", len(diffs), diffs)
+	}
+	if diffs[0].Field != "raw" || diffs[0].Category != CategoryBinary {
+		t.Errorf("Diff got = %+v, want binary diff on raw", diffs[0])
+	}
+	digest, ok := diffs[0].Source1Value.(BlobDigest)
+	if !ok || digest.Size != 3 {
+		t.Errorf("Source1Value got = %v, want BlobDigest of size 3", diffs[0].Source1Value)
+	}
+}
+
+func TestCompareRecords_BinaryMissingOnOneSide(t *testing.T) {
+	diffs := compareRecords(datareader.Record{"raw": []byte("abc")}, datareader.Record{}, DefaultOptions())
+	if len(diffs) != 1 || diffs[0].Source2Value != nil {
+		t.Fatalf("Expected one diff with nil source2 value, got %v", diffs)
+	}
+}
@@ -0,0 +1,148 @@
+package comparator
+
+import (
+	"crypto/sha256"
+	"data-comparator/internal/pkg/datareader"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// canonicalDateTimeLayouts are the datetime layouts recognized by Canonical.DateTimes.
+var canonicalDateTimeLayouts = []string{
+	time.RFC3339Nano, "2006-01-02 15:04:05.999999999", "2006-01-02 15:04:05Z07:00", "2006-01-02T15:04:05",
+}
+
+// Canonical selects the normalizations applied to values before they are
+// compared or hashed. Every comparison mode goes through the same
+// canonicalization, so they agree on what "equal" means.
+type Canonical struct {
+	TrimWhitespace  bool
+	CaseInsensitive bool
+	DateTimes       bool
+	Numbers         bool
+
+	// DateTimeLayouts are time layouts tried before the built-in ones.
+	// Setting them enables DateTimes.
+	DateTimeLayouts []string
+	// DateTimePrecision truncates datetimes to a multiple of it. Setting it
+	// enables DateTimes.
+	DateTimePrecision time.Duration
+}
+
+func (c Canonical) enabled() bool {
+	return c.TrimWhitespace || c.CaseInsensitive || c.dateTimes() || c.Numbers
+}
+
+func (c Canonical) dateTimes() bool {
+	return c.DateTimes || len(c.DateTimeLayouts) > 0 || c.DateTimePrecision > 0
+}
+
+// dateTime returns the UTC RFC 3339 form of at, truncated to the precision.
+func (c Canonical) dateTime(at time.Time) string {
+	at = at.UTC()
+	if c.DateTimePrecision > 0 {
+		at = at.Truncate(c.DateTimePrecision)
+	}
+	return at.Format(time.RFC3339Nano)
+}
+
+// Canonicalize returns a normalized deep copy of the record, after applying
+// the Normalize function if set.
+func (o Options) Canonicalize(rec datareader.Record) datareader.Record {
+	if o.Normalize != nil {
+		rec = o.Normalize(rec)
+	}
+	out, _ := o.canonicalTree("", map[string]interface{}(rec)).(map[string]interface{})
+	return datareader.Record(out)
+}
+
+// Hash returns a hash of the record's canonical JSON form. It does not depend
+// on field order, as encoding/json writes map keys sorted.
+func (o Options) Hash(rec datareader.Record) (string, error) {
+	data, err := json.Marshal(o.Canonicalize(rec))
+	if err != nil {
+		return "", fmt.Errorf("failed to encode record for hashing: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (o Options) canonicalTree(field string, v interface{}) interface{} {
+	switch t := v.(type) {
+	case datareader.Record:
+		return o.canonicalTree(field, map[string]interface{}(t))
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, child := range t {
+			name := k
+			if field != "" {
+				name = field + "." + k
+			}
+			if o.FieldRules.of(name).ignore {
+				continue
+			}
+			out[k] = o.canonicalTree(name, child)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, child := range t {
+			out[i] = o.canonicalTree(field+"[]", child)
+		}
+		// Arrays compared regardless of order hash alike in any order.
+		if mode := o.FieldRules.of(field).array; mode == ArraySet || mode == ArrayKeyed {
+			sortElements(out)
+		}
+		return out
+	default:
+		return o.canonicalValue(field, v)
+	}
+}
+
+// canonicalValue normalizes a single leaf value of the named field by its
+// field rule, then by Canonical. Fields compared as exact strings or as binary
+// data are not canonicalized.
+func (o Options) canonicalValue(field string, v interface{}) interface{} {
+	v = o.FieldRules.of(field).apply(v)
+	c := o.Canonical
+	if !c.enabled() || o.isExactString(field) || o.isBinary(field) {
+		return v
+	}
+	if at, ok := v.(time.Time); ok && c.dateTimes() {
+		return c.dateTime(at)
+	}
+
+	if s, ok := v.(string); ok {
+		if c.TrimWhitespace {
+			s = strings.TrimSpace(s)
+		}
+		if c.dateTimes() {
+			for _, layouts := range [][]string{c.DateTimeLayouts, canonicalDateTimeLayouts} {
+				for _, layout := range layouts {
+					if at, err := time.Parse(layout, s); err == nil {
+						return c.dateTime(at)
+					}
+				}
+			}
+		}
+		if c.Numbers {
+			if n, ok := toNumber(s); ok {
+				return n
+			}
+		}
+		if c.CaseInsensitive {
+			s = strings.ToLower(s)
+		}
+		return s
+	}
+
+	if c.Numbers {
+		if n, ok := toNumber(v); ok {
+			return n
+		}
+	}
+	return v
+}
@@ -0,0 +1,109 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"testing"
+	"time"
+)
+
+func TestCanonical_SharedByHashAndCompare(t *testing.T) {
+	options := DefaultOptions()
+	options.Canonical = Canonical{TrimWhitespace: true, CaseInsensitive: true, DateTimes: true, Numbers: true}
+	options.ExactStringFields = []string{"zip"}
+
+	rec1 := datareader.Record{
+		"name":    " Alice ",
+		"amount":  "1.50",
+		"created": "2025-09-10T14:00:00+02:00",
+		"zip":     "00123",
+		"meta":    map[string]interface{}{"tier": "GOLD"},
+	}
+	rec2 := datareader.Record{
+		"name":    "alice",
+		"amount":  float64(1.5),
+		"created": "2025-09-10 12:00:00Z",
+		"zip":     "00123",
+		"meta":    map[string]interface{}{"tier": "gold"},
+	}
+
+	if diffs := compareRecords(rec1, rec2, options); len(diffs) != 0 {
+		t.Errorf("compareRecords() got diffs %v, want none", diffs)
+	}
+
+	hash1, err := options.Hash(rec1)
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	hash2, err := options.Hash(rec2)
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	if hash1 != hash2 {
+		t.Error("Expected canonically equal records to hash alike")
+	}
+
+	rec2["zip"] = "123"
+	if diffs := compareRecords(rec1, rec2, options); len(diffs) != 1 || diffs[0].Field != "zip" {
+		t.Errorf("compareRecords() got diffs %v, want a diff on the exact-string field zip", diffs)
+	}
+}
+
+func TestCanonical_DisabledKeepsValues(t *testing.T) {
+	rec := datareader.Record{"name": " Alice "}
+	if got := DefaultOptions().Canonicalize(rec); got["name"] != " Alice " {
+		t.Errorf("Canonicalize() got = %q, want value untouched", got["name"])
+	}
+}
+
+func TestCanonical_DateTimeLayoutsAndPrecision(t *testing.T) {
+	options := DefaultOptions()
+	options.Canonical = Canonical{DateTimeLayouts: []string{"02/01/2006 15:04:05 MST"}, DateTimePrecision: time.Second}
+
+	rec1 := datareader.Record{
+		"logged":   "2025-09-10T12:00:00Z",
+		"exported": "2025-09-10 12:00:00.123456+00:00",
+		"custom":   "10/09/2025 14:00:00 UTC",
+		"native":   time.Date(2025, 9, 10, 14, 0, 0, 999, time.FixedZone("CEST", 2*3600)),
+	}
+	rec2 := datareader.Record{
+		"logged":   "2025-09-10 12:00:00+00:00",
+		"exported": "2025-09-10T12:00:00.9Z",
+		"custom":   "2025-09-10T14:00:00Z",
+		"native":   "2025-09-10T12:00:00Z",
+	}
+	if diffs := compareRecords(rec1, rec2, options); len(diffs) != 0 {
+		t.Errorf("compareRecords() got diffs %v, want none", diffs)
+	}
+
+	rec2["logged"] = "2025-09-10T12:00:01Z"
+	if diffs := compareRecords(rec1, rec2, options); len(diffs) != 1 || diffs[0].Field != "logged" {
+		t.Errorf("compareRecords() got diffs %v, want a diff on logged", diffs)
+	}
+}
+
+func TestCanonical_Keys(t *testing.T) {
+	options := DefaultOptions()
+	options.Canonical = Canonical{TrimWhitespace: true, CaseInsensitive: true, Numbers: true}
+
+	c := New("id")
+	c.SetOptions(options)
+	reader1 := datareader.NewSliceReader([]datareader.Record{{"id": " ABC "}, {"id": "1234567"}})
+	reader2 := datareader.NewSliceReader([]datareader.Record{{"id": "abc"}, {"id": float64(1234567)}})
+	result, err := c.Compare(reader1, reader2)
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	if result.Summary.MatchingKeys != 2 || result.Summary.KeysOnlyInSource1 != 0 {
+		t.Errorf("Summary got = %+v, want 2 matching keys", result.Summary)
+	}
+
+	config1 := writeCSV(t, "id\nA\n001\n")
+	config2 := writeCSV(t, "id\na\n1\n")
+	o, err := CheckKeyOverlap(config1, config2, "id", "id", 0, options)
+	if err != nil {
+		t.Fatalf("CheckKeyOverlap() error = %v", err)
+	}
+	if o.Shared != 2 {
+		t.Errorf("CheckKeyOverlap() got = %+v, want 2 shared keys", o)
+	}
+}
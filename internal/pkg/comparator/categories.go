@@ -0,0 +1,163 @@
+package comparator
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Categories of value diffs, besides CategoryWhitespaceOnly and
+// CategoryBinary, classified as the diffs are found.
+const (
+	// CategoryMissingField marks fields present in only one of the records.
+	CategoryMissingField = "missing_field"
+	// CategoryTypeChange marks values of different types, such as a string
+	// and a number.
+	CategoryTypeChange = "type_change"
+	// CategoryPrecisionLoss marks numbers equal once the more precise one is
+	// rounded or cut to the decimals of the other.
+	CategoryPrecisionLoss = "precision_loss"
+	// CategoryTruncation marks strings of which one is a shorter prefix of
+	// the other, possibly ending in an ellipsis.
+	CategoryTruncation = "truncation"
+	// CategoryCaseChange marks strings differing only in letter case.
+	CategoryCaseChange = "case_change"
+	// CategoryNullVsEmpty marks a null value against an empty string or array.
+	CategoryNullVsEmpty = "null_vs_empty"
+)
+
+// categorize returns the category of a diff between v1 and v2, which are
+// missing from their record unless present1 and present2, or "" if the
+// diff fits none.
+func categorize(v1, v2 interface{}, present1, present2 bool) string {
+	switch {
+	case !present1 || !present2:
+		return CategoryMissingField
+	case v1 == nil || v2 == nil:
+		if isEmptyValue(v1) && isEmptyValue(v2) {
+			return CategoryNullVsEmpty
+		}
+		return ""
+	case kindOf(v1) != kindOf(v2):
+		return CategoryTypeChange
+	}
+
+	if precisionLost(v1, v2) {
+		return CategoryPrecisionLoss
+	}
+	s1, ok1 := v1.(string)
+	s2, ok2 := v2.(string)
+	if !ok1 || !ok2 {
+		return ""
+	}
+	switch {
+	case strings.EqualFold(s1, s2):
+		return CategoryCaseChange
+	case truncates(s1, s2) || truncates(s2, s1):
+		return CategoryTruncation
+	}
+	return ""
+}
+
+// isEmptyValue reports whether v is null, an empty string or an empty array.
+func isEmptyValue(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// kindOf returns the JSON type of v, taking numeric strings, as read from
+// CSV, for numbers.
+func kindOf(v interface{}) string {
+	switch v.(type) {
+	case string:
+		if _, ok := toFloat(v); ok {
+			return "number"
+		}
+		return "string"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	if _, ok := toFloat(v); ok {
+		return "number"
+	}
+	return "other"
+}
+
+// precisionLost reports whether v1 and v2 are numbers of which the one with
+// more decimals, rounded or cut to the decimals of the other, equals it.
+func precisionLost(v1, v2 interface{}) bool {
+	f1, ok1 := toFloat(v1)
+	f2, ok2 := toFloat(v2)
+	if !ok1 || !ok2 || math.IsNaN(f1) || math.IsNaN(f2) || math.IsInf(f1, 0) || math.IsInf(f2, 0) {
+		return false
+	}
+	d1, d2 := decimals(v1), decimals(v2)
+	if d1 == d2 {
+		return false
+	}
+	precise, coarse, places := f1, f2, d2
+	if d2 > d1 {
+		precise, coarse, places = f2, f1, d1
+	}
+	scale := math.Pow10(places)
+	return math.Round(precise*scale)/scale == coarse || math.Trunc(precise*scale)/scale == coarse
+}
+
+// decimals returns the number of decimals v is written with.
+func decimals(v interface{}) int {
+	var s string
+	switch n := v.(type) {
+	case string:
+		s = strings.TrimSpace(n)
+	case json.Number:
+		s = n.String()
+	default:
+		f, _ := toFloat(v)
+		s = strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		return len(s) - i - 1
+	}
+	return 0
+}
+
+// truncates reports whether short is a non-empty prefix of long cut within
+// a word, or one followed by an ellipsis in place of the cut. Prefixes ending
+// at a word boundary, like premium of premium_plus, are likely other values.
+func truncates(short, long string) bool {
+	if short == "" || len(short) >= len(long) {
+		return false
+	}
+	for _, ellipsis := range []string{"...", "…"} {
+		if stem := strings.TrimSuffix(short, ellipsis); stem != short && stem != "" && strings.HasPrefix(long, stem) {
+			return true
+		}
+	}
+	if !strings.HasPrefix(long, short) {
+		return false
+	}
+	last, _ := utf8.DecodeLastRuneInString(short)
+	next, _ := utf8.DecodeRuneInString(long[len(short):])
+	return isWordRune(last) && isWordRune(next)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,64 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"encoding/json"
+	"testing"
+)
+
+func TestCategorize(t *testing.T) {
+	tests := []struct {
+		name               string
+		v1, v2             interface{}
+		present1, present2 bool
+		want               string
+	}{
+		{"missing in source2", "a", nil, true, false, CategoryMissingField},
+		{"missing in source1", nil, 1.0, false, true, CategoryMissingField},
+		{"null vs empty string", nil, "", true, true, CategoryNullVsEmpty},
+		{"empty array vs null", []interface{}{}, nil, true, true, CategoryNullVsEmpty},
+		{"null vs value", nil, "a", true, true, ""},
+		{"string vs number", "abc", 1.0, true, true, CategoryTypeChange},
+		{"bool vs string", true, "yes", true, true, CategoryTypeChange},
+		{"rounded", 3.14159, 3.14, true, true, CategoryPrecisionLoss},
+		{"cut", "2.718", "2.7", true, true, CategoryPrecisionLoss},
+		{"json number", json.Number("10.25"), 10.3, true, true, CategoryPrecisionLoss},
+		{"other number", 3.14159, 3.2, true, true, ""},
+		{"case", "Alice", "ALICE", true, true, CategoryCaseChange},
+		{"truncated", "Hello wor", "Hello world", true, true, CategoryTruncation},
+		{"ellipsis", "A long descr...", "A long description", true, true, CategoryTruncation},
+		{"longer value", "premium", "premium_plus", true, true, ""},
+		{"other string", "red", "blue", true, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := categorize(tt.v1, tt.v2, tt.present1, tt.present2); got != tt.want {
+				t.Errorf("categorize(%v, %v) got = %q, want %q", tt.v1, tt.v2, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompare_DiffsByCategory(t *testing.T) {
+	records1 := []datareader.Record{
+		{"id": "1", "name": "Alice", "score": 1.25, "note": ""},
+		{"id": "2", "name": "bob", "score": 2.5, "tier": "gold"},
+	}
+	records2 := []datareader.Record{
+		{"id": "1", "name": "alice", "score": 1.3, "note": nil},
+		{"id": "2", "name": "Bob", "score": 2.5},
+	}
+	result, err := New("id").Compare(datareader.NewSliceReader(records1), datareader.NewSliceReader(records2))
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	want := map[string]int{CategoryCaseChange: 2, CategoryPrecisionLoss: 1, CategoryNullVsEmpty: 1, CategoryMissingField: 1}
+	if len(result.DiffsByCategory) != len(want) {
+		t.Errorf("DiffsByCategory got = %v, want %v", result.DiffsByCategory, want)
+	}
+	for category, n := range want {
+		if result.DiffsByCategory[category] != n {
+			t.Errorf("DiffsByCategory[%s] got = %d, want %d", category, result.DiffsByCategory[category], n)
+		}
+	}
+}
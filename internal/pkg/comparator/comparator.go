@@ -0,0 +1,963 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/constraint"
+	"data-comparator/internal/pkg/datareader"
+	"data-comparator/internal/pkg/schema"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Side identifies which of the two compared sources a record comes from.
+type Side int
+
+const (
+	// Source1 is the first (reference) source.
+	Source1 Side = iota + 1
+	// Source2 is the second source.
+	Source2
+)
+
+func (s Side) String() string {
+	switch s {
+	case Source1:
+		return "source1"
+	case Source2:
+		return "source2"
+	default:
+		return fmt.Sprintf("Side(%d)", int(s))
+	}
+}
+
+// Result holds the outcome of comparing two data sources by key.
+type Result struct {
+	StartedAt  time.Time              `yaml:"started_at"`
+	FinishedAt time.Time              `yaml:"finished_at"`
+	Keys       KeyMapping             `yaml:"keys"`
+	Summary    Summary                `yaml:"summary"`
+	ValueDiffs map[string][]FieldDiff `yaml:"value_diffs_by_key"`
+	KeysOnly   KeysOnly               `yaml:"keys_only"`
+	// DiffsByTag counts field diffs per schema tag, when an annotated schema is set.
+	DiffsByTag map[string]int `yaml:"diffs_by_tag,omitempty"`
+	// DiffsByCategory counts field diffs per category, such as type_change
+	// or truncation, for triage.
+	DiffsByCategory map[string]int `yaml:"diffs_by_category,omitempty"`
+	// FieldStats summarizes the diffs per field, the most often differing
+	// field first.
+	FieldStats []FieldStat `yaml:"field_stats,omitempty"`
+	// Records holds the complete records of the first diffing keys, when
+	// Options.MaxRecords is set.
+	Records map[string]RecordPair `yaml:"records_by_key,omitempty"`
+	// Violations holds the first schema and constraint violations of each
+	// source, when a schema or constraints are set.
+	Violations *Violations `yaml:"schema_violations,omitempty"`
+	// Truncations holds the first truncations of each source's records, when
+	// Options.Limits are set.
+	Truncations *Truncations `yaml:"truncations,omitempty"`
+	// Skipped holds the first records of each source skipped for failing to
+	// parse, with SetSkipParseErrors.
+	Skipped   *SkippedRecords `yaml:"skipped_records,omitempty"`
+	Scorecard *Scorecard      `yaml:"scorecard"`
+	// EmptySources lists the sources that held no records at all.
+	EmptySources []string `yaml:"empty_sources,omitempty"`
+	// Stalls lists the sources that stopped producing records for the stall
+	// timeout, when stall detection is enabled.
+	Stalls []Stall `yaml:"stalls,omitempty"`
+	// Incomplete lists the sources that were not read to their end because
+	// of a read timeout or the deadline. The result then only covers the
+	// records read.
+	Incomplete []Incompletion `yaml:"incomplete,omitempty"`
+	// PausedFor is the time the comparison spent paused with Pause.
+	PausedFor time.Duration `yaml:"paused_for,omitempty"`
+	// Warnings describe what the readers of the sources went on past, such
+	// as values cut off by their parser limits, prefixed by the source, and
+	// the findings of the key pre-check when it does not fail.
+	Warnings []string `yaml:"warnings,omitempty"`
+	// Resolved lists the keys whose records differed and later matched,
+	// when tracked with SetTrackResolved.
+	Resolved *Resolved `yaml:"resolved,omitempty"`
+	// Lag is the consistency lag of the matched keys, when measured with SetLag.
+	Lag *LagStats `yaml:"lag,omitempty"`
+	// Duplicates lists the keys read again on a side while pending.
+	Duplicates *Duplicates `yaml:"duplicates,omitempty"`
+	// Expectation is what the comparison asserted, when not ExpectEqual.
+	Expectation Expectation `yaml:"expectation,omitempty"`
+	// MissingKeySeverity is the severity of keys only in each source, when set.
+	MissingKeySeverity *MissingKeySeverity `yaml:"missing_key_severity,omitempty"`
+	// Severity is the highest severity of the value diffs, which are errors,
+	// and the missing keys, if there are any.
+	Severity Severity `yaml:"severity,omitempty"`
+	// Thresholds bound the discrepancies of the comparison, when set.
+	Thresholds *Thresholds `yaml:"thresholds,omitempty"`
+	// ThresholdsExceeded describes the thresholds the discrepancies exceed,
+	// failing the comparison.
+	ThresholdsExceeded []string `yaml:"thresholds_exceeded,omitempty"`
+	// Run describes how the result was produced, when compared from configs.
+	Run *RunInfo `yaml:"run,omitempty"`
+}
+
+// MaxViolations is the number of violations kept per source. All of
+// them are counted in the Summary.
+const MaxViolations = 100
+
+// Violations lists schema and constraint violations per source, numbered by record within the source.
+type Violations struct {
+	Source1 []schema.Violation `yaml:"source1"`
+	Source2 []schema.Violation `yaml:"source2"`
+}
+
+// Summary holds the record and key counts of a comparison.
+type Summary struct {
+	Source1Rows       int `yaml:"source1_rows" json:"source1_rows"`
+	Source2Rows       int `yaml:"source2_rows" json:"source2_rows"`
+	MatchingKeys      int `yaml:"matching_keys" json:"matching_keys"`
+	IdenticalRows     int `yaml:"identical_rows" json:"identical_rows"`
+	KeysOnlyInSource1 int `yaml:"keys_only_in_source1" json:"keys_only_in_source1"`
+	KeysOnlyInSource2 int `yaml:"keys_only_in_source2" json:"keys_only_in_source2"`
+	DuplicateKeys     int `yaml:"duplicate_keys" json:"duplicate_keys"`
+	// BaselinedDiffs counts field diffs accepted by the baseline and left out.
+	BaselinedDiffs int `yaml:"baselined_diffs" json:"baselined_diffs"`
+	// Source1Violations and Source2Violations count schema and constraint violations.
+	Source1Violations int `yaml:"source1_violations,omitempty" json:"source1_violations,omitempty"`
+	Source2Violations int `yaml:"source2_violations,omitempty" json:"source2_violations,omitempty"`
+	// Source1TruncatedRecords and Source2TruncatedRecords count records truncated to Options.Limits.
+	Source1TruncatedRecords int `yaml:"source1_truncated_records,omitempty" json:"source1_truncated_records,omitempty"`
+	Source2TruncatedRecords int `yaml:"source2_truncated_records,omitempty" json:"source2_truncated_records,omitempty"`
+	// Source1SkippedRecords and Source2SkippedRecords count records skipped
+	// for failing to parse.
+	Source1SkippedRecords int `yaml:"source1_skipped_records,omitempty" json:"source1_skipped_records,omitempty"`
+	Source2SkippedRecords int `yaml:"source2_skipped_records,omitempty" json:"source2_skipped_records,omitempty"`
+	// ResolvedDiffs counts differing keys that later matched, which are
+	// counted as identical rows, when tracked with SetTrackResolved.
+	ResolvedDiffs int `yaml:"resolved_diffs,omitempty" json:"resolved_diffs,omitempty"`
+	// OmittedDiffs counts field diffs left out of ValueDiffs by
+	// Options.MaxDiffsPerField and Options.MaxTotalDiffs.
+	OmittedDiffs int `yaml:"omitted_diffs,omitempty" json:"omitted_diffs,omitempty"`
+}
+
+// DiffRate is the share of matching keys whose records differ.
+func (s Summary) DiffRate() float64 {
+	if s.MatchingKeys == 0 {
+		return 0
+	}
+	return float64(s.MatchingKeys-s.IdenticalRows) / float64(s.MatchingKeys)
+}
+
+// KeyMapping records the key field used to join each source.
+type KeyMapping struct {
+	Source1 string `yaml:"source1" json:"source1"`
+	Source2 string `yaml:"source2" json:"source2"`
+}
+
+// KeysOnly lists the keys that were found in only one of the sources.
+type KeysOnly struct {
+	InSource1 []string `yaml:"in_source1"`
+	InSource2 []string `yaml:"in_source2"`
+}
+
+// FieldDiff describes a single field whose value differs between the sources.
+type FieldDiff struct {
+	Field        string      `yaml:"field" json:"field"`
+	Source1Value interface{} `yaml:"source1_value" json:"source1_value"`
+	Source2Value interface{} `yaml:"source2_value" json:"source2_value"`
+	Category     string      `yaml:"category,omitempty" json:"category,omitempty"`
+	Edits        []Edit      `yaml:"edits,omitempty" json:"edits,omitempty"`
+	Tags         []string    `yaml:"tags,omitempty" json:"tags,omitempty"`
+}
+
+// Hooks are optional callbacks invoked as soon as the outcome for a key is known,
+// so callers can act on individual records without waiting for the final Result.
+type Hooks struct {
+	// OnMatch is called when a key is present in both sources with identical values.
+	OnMatch func(key string, rec1, rec2 datareader.Record)
+	// OnDiff is called when a key is present in both sources with differing values.
+	OnDiff func(key string, diffs []FieldDiff, rec1, rec2 datareader.Record)
+	// OnOnlyInSource1 is called for each key that was never seen in source2.
+	OnOnlyInSource1 func(key string, rec datareader.Record)
+	// OnOnlyInSource2 is called for each key that was never seen in source1.
+	OnOnlyInSource2 func(key string, rec datareader.Record)
+	// OnDuplicateKey is called when a key arrives again on the same side before
+	// it was matched. The later record replaces the earlier one.
+	OnDuplicateKey func(side Side, key string, rec datareader.Record)
+	// OnTruncated is called when the n-th record of side exceeds Options.Limits,
+	// before the truncated record is joined.
+	OnTruncated func(side Side, n int, truncations []Truncation)
+	// OnProgress is called with a snapshot of the running counts every progress
+	// interval, as measured by the comparator's clock.
+	OnProgress func(summary Summary)
+	// OnHeartbeat is called alongside OnProgress with the time each source
+	// last produced a record and the sources currently stalled.
+	OnHeartbeat func(h Heartbeat)
+	// OnStall is called when side produced no record for the stall timeout
+	// set with SetStall.
+	OnStall func(side Side, stall Stall)
+	// OnIncomplete is called when side is no longer read because of its read
+	// timeout or the deadline.
+	OnIncomplete func(side Side, reason string)
+	// OnSnapshot is called with the state of the comparison once it is
+	// requested with RequestSnapshot.
+	OnSnapshot func(s Snapshot)
+	// OnResolved is called when a key that differed matches after a later
+	// record, with the records that match, when tracked with SetTrackResolved.
+	OnResolved func(key string, latency time.Duration, rec1, rec2 datareader.Record)
+}
+
+// StreamComparator compares two data sources record by record, joining them on a key field.
+// It does not perform any I/O itself: records are pushed in with Add and the result is
+// collected with Finish, while Compare drives the engine from two DataReaders.
+type StreamComparator struct {
+	key1             string
+	key2             string
+	hooks            Hooks
+	clock            Clock
+	progressInterval time.Duration
+	options          Options
+	schema           *schema.Schema
+	constraints      []*constraint.Constraint
+	baseline         *Baseline
+	spill            *SpillOptions
+	stall            StallOptions
+	readTimeouts     [2]time.Duration
+	deadline         time.Duration
+	// discardDiffs stops value diffs from being collected into the Result,
+	// for consumers that handle them through hooks.
+	discardDiffs bool
+	// trackResolved keeps differing keys to compare their later records.
+	trackResolved bool
+	// lookup reads source2 whole before verifying source1 against it.
+	lookup bool
+	// duplicatePolicy handles keys read again on a side.
+	duplicatePolicy DuplicatePolicy
+	lag             *LagOptions
+	// expectation is what the comparison asserts about the keys of the sources.
+	expectation Expectation
+	// missingKeySeverity ranks the keys only in each source.
+	missingKeySeverity MissingKeySeverity
+	// thresholds bound the discrepancies of the result.
+	thresholds Thresholds
+	// skipParseErrors skips records that fail to parse recoverably.
+	skipParseErrors bool
+	// checkWarnings are the warnings of the checks run before comparing,
+	// added to each result.
+	checkWarnings []string
+	// script runs the configured normalize and equal functions, and is
+	// stopped when the comparison ends.
+	script *Script
+
+	// state of the comparison in progress
+	result         *Result
+	pending1       map[string]datareader.Record
+	pending2       map[string]datareader.Record
+	lastProgress   time.Time
+	lastRecord     [2]time.Time
+	stalled        [2]bool
+	incomplete     [2]bool
+	quality        [2]qualityCounts
+	requiredFields int
+	spiller        *spiller
+	// outstanding follows the pending keys between heartbeats, when they are reported.
+	outstanding *outstandingTracker
+	// differing holds the keys whose records differ, when resolved diffs are
+	// tracked, and resolvedLatencies the latencies of those resolved.
+	differing         map[string]*differingKey
+	resolvedLatencies []time.Duration
+	// lagTotal and lagInterval accumulate the lags of the comparison and of
+	// the current heartbeat interval, and arrivals holds the arrival of
+	// pending keys when lags are measured by arrival.
+	lagTotal, lagInterval *lagRecorder
+	arrivals              map[string]time.Duration
+	// verified holds the snapshot records already matched in lookup mode.
+	verified map[string]datareader.Record
+	// extra holds the records of pending keys beyond the first per side,
+	// under DuplicatesMultiset, and duplicateIndex the position of listed
+	// duplicated keys in the result.
+	extra          [2]map[string][]datareader.Record
+	duplicateIndex [2]map[string]int
+	// seen holds the keys read per side, when a duplicate policy is set, to
+	// find the records of keys already matched.
+	seen [2]map[string]bool
+	// skippedKeys holds the keys of the records skipped per side.
+	skippedKeys [2]map[string]bool
+	// fields tallies the diffs per field for the field stats.
+	fields map[string]*fieldTally
+	// keptDiffs and keptByField count the value diffs kept in the result,
+	// when they are capped.
+	keptDiffs   int
+	keptByField map[string]int
+	// snapshotRequested is set by RequestSnapshot, from any goroutine.
+	snapshotRequested atomic.Bool
+	pause             pauser
+}
+
+// New creates a StreamComparator that joins records on the given key field.
+func New(key string) *StreamComparator {
+	return &StreamComparator{key1: key, key2: key, clock: SystemClock, options: DefaultOptions()}
+}
+
+// SetOptions replaces the options that control value equality.
+func (c *StreamComparator) SetOptions(options Options) {
+	c.options = options
+}
+
+// SetHooks registers the callbacks invoked while comparing.
+func (c *StreamComparator) SetHooks(hooks Hooks) {
+	c.hooks = hooks
+}
+
+// SetDiscardDiffs stops value diffs, and the records of differing keys, from
+// being collected into the Result, for callers that handle them in OnDiff,
+// e.g. writing them to a file. The counts and field stats stay exact.
+func (c *StreamComparator) SetDiscardDiffs(on bool) {
+	c.discardDiffs = on
+}
+
+// SetKeys sets the key field of each source separately, for sources whose key
+// columns are named differently. The key fields themselves are not compared.
+func (c *StreamComparator) SetKeys(key1, key2 string) {
+	c.key1, c.key2 = key1, key2
+}
+
+// SetBaseline sets the known, accepted diffs left out of the result. Keys
+// whose diffs are all accepted count as identical rows.
+func (c *StreamComparator) SetBaseline(b *Baseline) {
+	c.baseline = b
+}
+
+// SetSchema sets the schema whose field annotations are attached to diffs.
+// Every record is also checked against its types and matchers.
+func (c *StreamComparator) SetSchema(s *schema.Schema) {
+	c.schema = s
+}
+
+// SetConstraints sets cross-field assertions checked on every record of both sources.
+func (c *StreamComparator) SetConstraints(constraints []*constraint.Constraint) {
+	c.constraints = constraints
+}
+
+// SetClock replaces the clock used for timestamps and progress intervals.
+func (c *StreamComparator) SetClock(clock Clock) {
+	c.clock = clock
+}
+
+// SetProgressInterval sets how often the OnProgress hook fires. Zero disables it.
+func (c *StreamComparator) SetProgressInterval(interval time.Duration) {
+	c.progressInterval = interval
+}
+
+// Compare reads both sources alternately and joins their records on the key field.
+// Records are matched as soon as both sides have been seen, so hooks fire while
+// the sources are still being read; keys left unmatched are reported at the end.
+// Records with recoverable parse errors fail the comparison, unless skipped
+// with SetSkipParseErrors.
+// With SetSpill, unmatched records beyond the memory budget go to disk.
+func (c *StreamComparator) Compare(reader1, reader2 datareader.DataReader) (*Result, error) {
+	if c.key1 == "" || c.key2 == "" {
+		return nil, fmt.Errorf("comparison key is not set")
+	}
+	c.reset()
+	defer func() {
+		if c.spiller != nil {
+			c.spiller.remove()
+			c.spiller = nil
+		}
+		c.script.Close()
+	}()
+
+	if err := c.readAll(reader1, reader2); err != nil {
+		c.result = nil
+		return nil, err
+	}
+	c.addWarnings(Source1, reader1)
+	c.addWarnings(Source2, reader2)
+	result := c.Finish()
+	// Spilled records are compared after reading, and the script may fail
+	// only as it exits.
+	if err := c.script.Close(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// addWarnings adds the warnings of the reader of side, if it has any, to the
+// result.
+func (c *StreamComparator) addWarnings(side Side, reader datareader.DataReader) {
+	if w, ok := reader.(datareader.Warner); ok {
+		for _, warning := range w.Warnings() {
+			c.result.Warnings = append(c.result.Warnings, fmt.Sprintf("%s: %s", side, warning))
+		}
+	}
+}
+
+func (c *StreamComparator) readAll(reader1, reader2 datareader.DataReader) error {
+	reader1, stop1 := c.watch(reader1, Source1)
+	defer stop1()
+	reader2, stop2 := c.watch(reader2, Source2)
+	defer stop2()
+
+	done1, done2 := false, false
+	for !done1 || !done2 {
+		// In lookup mode, the stream waits for the snapshot to be loaded.
+		if !done1 && (done2 || !c.lookup) {
+			var err error
+			if done1, err = c.readNext(reader1, Source1); err != nil {
+				return err
+			}
+		}
+		if !done2 {
+			var err error
+			if done2, err = c.readNext(reader2, Source2); err != nil {
+				return err
+			}
+		}
+		if c.spill != nil && c.spiller == nil && !c.lookup && c.duplicatePolicy != DuplicatesMultiset && len(c.pending1)+len(c.pending2) > c.spill.MemoryRecords {
+			if err := c.startSpilling(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if c.spiller != nil {
+		return c.joinSpilled()
+	}
+	return nil
+}
+
+func (c *StreamComparator) readNext(reader datareader.DataReader, side Side) (bool, error) {
+	c.waitWhilePaused()
+	rec, err := reader.Read()
+	if err == io.EOF {
+		return true, nil
+	}
+	if err != nil {
+		if perr := recoverable(err); perr != nil && c.skipParseErrors {
+			c.skip(side, perr)
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read from %s: %w", side, err)
+	}
+	if c.spiller != nil {
+		return false, c.addSpilled(side, rec)
+	}
+	return false, c.Add(side, rec)
+}
+
+// Add pushes a single record from the given side into the comparison.
+// The first Add after New or Finish starts a new comparison.
+func (c *StreamComparator) Add(side Side, rec datareader.Record) error {
+	if c.key1 == "" || c.key2 == "" {
+		return fmt.Errorf("comparison key is not set")
+	}
+	if c.result == nil {
+		c.reset()
+	}
+
+	own, other := c.pending1, c.pending2
+	switch side {
+	case Source1:
+	case Source2:
+		own, other = c.pending2, c.pending1
+	default:
+		return fmt.Errorf("invalid side: %s", side)
+	}
+	rec, n := c.count(side, rec)
+	if err := c.join(rec, own, other, side); err != nil {
+		return fmt.Errorf("%s record %d: %w", side, n, err)
+	}
+	if err := c.script.Err(); err != nil {
+		return fmt.Errorf("%s record %d: %w", side, n, err)
+	}
+
+	c.maybeReportProgress()
+	return nil
+}
+
+// count counts rec as the next record of side, limits and checks it, and
+// returns the record to join with its number.
+func (c *StreamComparator) count(side Side, rec datareader.Record) (datareader.Record, int) {
+	n := &c.result.Summary.Source1Rows
+	if side == Source2 {
+		n = &c.result.Summary.Source2Rows
+	}
+	*n++
+	c.lastRecord[side-1] = c.clock.Now()
+	rec = c.limit(side, rec, *n)
+	c.checkRecord(side, rec, *n)
+	return rec, *n
+}
+
+// Finish reports all keys still unmatched as only present in one source and
+// returns the final result. The comparator is ready for a new comparison afterwards.
+func (c *StreamComparator) Finish() *Result {
+	if c.result == nil {
+		c.reset()
+	}
+	result := c.result
+
+	result.KeysOnly.InSource1 = c.withoutSkipped(sortedKeys(c.pending1), Source2)
+	for _, key := range result.KeysOnly.InSource1 {
+		// In lookup mode they were reported as soon as they were read.
+		if c.hooks.OnOnlyInSource1 != nil && !c.lookup {
+			c.hooks.OnOnlyInSource1(key, c.pending1[key])
+		}
+	}
+	result.KeysOnly.InSource2 = c.withoutSkipped(sortedKeys(c.pending2), Source1)
+	for _, key := range result.KeysOnly.InSource2 {
+		if c.hooks.OnOnlyInSource2 != nil {
+			c.hooks.OnOnlyInSource2(key, c.pending2[key])
+		}
+	}
+	result.KeysOnly.InSource1 = c.withoutSkipped(c.spiller.mergeUnmatched(Source1, result.KeysOnly.InSource1), Source2)
+	result.KeysOnly.InSource2 = c.withoutSkipped(c.spiller.mergeUnmatched(Source2, result.KeysOnly.InSource2), Source1)
+	result.Summary.KeysOnlyInSource1 = len(result.KeysOnly.InSource1)
+	result.Summary.KeysOnlyInSource2 = len(result.KeysOnly.InSource2)
+	result.FinishedAt = c.clock.Now()
+	result.PausedFor = c.pausedFor(result.FinishedAt)
+	result.Scorecard = c.scorecard()
+	result.FieldStats = c.fieldStats()
+	result.Severity = result.highestSeverity()
+	result.ThresholdsExceeded = result.exceededThresholds()
+	if result.Resolved != nil {
+		result.Resolved.Latency = latencyDistribution(c.resolvedLatencies)
+	}
+	result.Lag = c.lagTotal.stats()
+	for _, side := range c.emptySides() {
+		result.EmptySources = append(result.EmptySources, side.String())
+	}
+
+	c.result, c.pending1, c.pending2 = nil, nil, nil
+	c.differing, c.resolvedLatencies = nil, nil
+	c.lagTotal, c.lagInterval, c.arrivals = nil, nil, nil
+	c.verified, c.fields, c.keptByField = nil, nil, nil
+	c.extra, c.duplicateIndex = [2]map[string][]datareader.Record{}, [2]map[string]int{}
+	c.seen, c.skippedKeys = [2]map[string]bool{}, [2]map[string]bool{}
+	return result
+}
+
+func (c *StreamComparator) reset() {
+	now := c.clock.Now()
+	c.result = &Result{
+		StartedAt:  now,
+		Keys:       KeyMapping{Source1: c.key1, Source2: c.key2},
+		ValueDiffs: make(map[string][]FieldDiff),
+		Warnings:   append([]string(nil), c.checkWarnings...),
+	}
+	if c.expectation != ExpectEqual {
+		c.result.Expectation = c.expectation
+	}
+	if c.missingKeySeverity != (MissingKeySeverity{}) {
+		severity := c.missingKeySeverity
+		c.result.MissingKeySeverity = &severity
+	}
+	if c.thresholds != (Thresholds{}) {
+		thresholds := c.thresholds
+		c.result.Thresholds = &thresholds
+	}
+	c.pending1 = make(map[string]datareader.Record)
+	c.pending2 = make(map[string]datareader.Record)
+	c.lastProgress = now
+	c.resetPause(now)
+	c.lastRecord = [2]time.Time{}
+	c.stalled = [2]bool{}
+	c.incomplete = [2]bool{}
+	c.quality = [2]qualityCounts{}
+	c.requiredFields = 0
+	c.fields = nil
+	c.keptDiffs, c.keptByField = 0, nil
+	c.outstanding = nil
+	c.verified = nil
+	if c.lookup {
+		c.verified = make(map[string]datareader.Record)
+	}
+	c.extra, c.duplicateIndex = [2]map[string][]datareader.Record{}, [2]map[string]int{}
+	if c.duplicatePolicy == DuplicatesMultiset {
+		c.extra = [2]map[string][]datareader.Record{make(map[string][]datareader.Record), make(map[string][]datareader.Record)}
+	}
+	c.seen, c.skippedKeys = [2]map[string]bool{}, [2]map[string]bool{}
+	if c.duplicatePolicy != "" {
+		c.seen = [2]map[string]bool{make(map[string]bool), make(map[string]bool)}
+	}
+	c.resetLag()
+	c.differing, c.resolvedLatencies = nil, nil
+	if c.trackResolved {
+		c.differing = make(map[string]*differingKey)
+	}
+	// Snapshot keys do not wait for the stream, so they are not tracked in lookup mode.
+	if c.progressInterval > 0 && c.hooks.OnHeartbeat != nil && !c.lookup {
+		c.outstanding = newOutstandingTracker()
+	}
+	if c.schema != nil {
+		for _, field := range c.schema.Fields {
+			if field != nil && field.RequiresValue() {
+				c.requiredFields++
+			}
+		}
+	}
+}
+
+// checkRecord records the schema and constraint violations of the n-th record of side.
+func (c *StreamComparator) checkRecord(side Side, rec datareader.Record, n int) {
+	var violations []schema.Violation
+	if c.schema != nil {
+		violations = c.schema.Check(rec)
+	}
+	for _, con := range c.constraints {
+		ok, err := con.Check(rec)
+		switch {
+		case err != nil:
+			violations = append(violations, schema.Violation{Field: con.Name, Rule: "constraint", Message: err.Error()})
+		case !ok:
+			violations = append(violations, schema.Violation{Field: con.Name, Rule: "constraint", Message: fmt.Sprintf("%s does not hold", con.Expr)})
+		}
+	}
+	c.quality[side-1].addChecked(c.requiredFields, violations)
+	if len(violations) == 0 {
+		return
+	}
+	if c.result.Violations == nil {
+		c.result.Violations = &Violations{}
+	}
+
+	count, kept := &c.result.Summary.Source1Violations, &c.result.Violations.Source1
+	if side == Source2 {
+		count, kept = &c.result.Summary.Source2Violations, &c.result.Violations.Source2
+	}
+	*count += len(violations)
+	for _, v := range violations {
+		if len(*kept) >= MaxViolations {
+			break
+		}
+		v.Record = n
+		*kept = append(*kept, v)
+	}
+}
+
+func (c *StreamComparator) maybeReportProgress() {
+	c.maybeReportSnapshot()
+	if c.progressInterval <= 0 || (c.hooks.OnProgress == nil && c.hooks.OnHeartbeat == nil) {
+		return
+	}
+	now := c.clock.Now()
+	if now.Sub(c.lastProgress) < c.progressInterval {
+		return
+	}
+	c.lastProgress = now
+	if c.hooks.OnProgress != nil {
+		c.hooks.OnProgress(c.result.Summary)
+	}
+	if c.hooks.OnHeartbeat != nil {
+		c.hooks.OnHeartbeat(c.heartbeat())
+	}
+}
+
+// join matches rec against the records pending on the other side, or parks it
+// in own until its counterpart arrives.
+func (c *StreamComparator) join(rec datareader.Record, own, other map[string]datareader.Record, side Side) error {
+	key, err := c.keyOf(rec, side)
+	if err != nil {
+		return err
+	}
+	if d, ok := c.differing[key]; ok {
+		c.rejoin(d, key, rec, side)
+		return nil
+	}
+	if c.lookup && side == Source1 {
+		c.verify(key, rec)
+		return nil
+	}
+
+	repeated := c.seen[side-1][key]
+	if c.seen[side-1] != nil {
+		c.seen[side-1][key] = true
+	}
+	if _, ok := other[key]; !ok {
+		if _, dup := own[key]; dup {
+			return c.duplicate(side, key, rec, own)
+		}
+		if repeated {
+			// The key was matched before. Its comparison stands, except
+			// under multiset, where the record waits for another match.
+			if err := c.countDuplicate(side, key, rec); err != nil || c.duplicatePolicy != DuplicatesMultiset {
+				return err
+			}
+		}
+		own[key] = rec
+		c.outstanding.wait(key, side)
+		c.arrived(key)
+		return nil
+	}
+	if repeated {
+		if err := c.countDuplicate(side, key, rec); err != nil {
+			return err
+		}
+	}
+	counterpart := c.counterpart(key, rec, side, other)
+
+	rec1, rec2 := rec, counterpart
+	if side == Source2 {
+		rec1, rec2 = counterpart, rec
+	}
+	c.matched(key, rec1, rec2, side)
+	return nil
+}
+
+// matched compares the records of a key present in both sources, the later
+// of them read from side.
+func (c *StreamComparator) matched(key string, rec1, rec2 datareader.Record, side Side) {
+	result := c.result
+	result.Summary.MatchingKeys++
+	c.measureLag(key, rec1, rec2, side)
+	diffs, baselined := c.diff(key, rec1, rec2)
+	result.Summary.BaselinedDiffs += baselined
+	if len(diffs) == 0 {
+		result.Summary.IdenticalRows++
+		if c.hooks.OnMatch != nil {
+			c.hooks.OnMatch(key, rec1, rec2)
+		}
+		return
+	}
+
+	c.annotate(diffs)
+	c.countCategories(diffs)
+	c.tallyFields(diffs)
+	if c.differing != nil {
+		c.differing[key] = &differingKey{records: [2]datareader.Record{rec1, rec2}, since: c.activeFor(c.clock.Now())}
+	}
+	if !c.discardDiffs {
+		if kept := c.sampleDiffs(diffs); len(kept) > 0 {
+			result.ValueDiffs[key] = kept
+			c.keepRecords(key, rec1, rec2)
+		}
+	}
+	if c.hooks.OnDiff != nil {
+		c.hooks.OnDiff(key, diffs, rec1, rec2)
+	}
+}
+
+// diff compares the records of key, and returns their diffs and the number
+// of diffs accepted by the baseline and left out.
+func (c *StreamComparator) diff(key string, rec1, rec2 datareader.Record) ([]FieldDiff, int) {
+	cmp1, cmp2 := c.comparable(rec1, rec2)
+	diffs := compareRecords(cmp1, cmp2, c.options)
+	if c.baseline == nil {
+		return diffs, 0
+	}
+	kept := c.baseline.subtract(key, diffs, c.clock.Now())
+	return kept, len(diffs) - len(kept)
+}
+
+// comparable returns the records of a key as compared: without differently
+// named keys, with fields mapped and normalized.
+func (c *StreamComparator) comparable(rec1, rec2 datareader.Record) (datareader.Record, datareader.Record) {
+	cmp1, cmp2 := rec1, rec2
+	if c.key1 != c.key2 {
+		cmp1, cmp2 = without(rec1, c.key1), without(rec2, c.key2)
+	}
+	if len(c.options.FieldMappings) > 0 {
+		cmp2 = renamed(cmp2, c.options.FieldMappings)
+	}
+	if normalize := c.options.Normalize; normalize != nil {
+		cmp1, cmp2 = normalize(cmp1), normalize(cmp2)
+	}
+	return cmp1, cmp2
+}
+
+// annotate attaches schema tags to diffs and counts them per tag.
+func (c *StreamComparator) annotate(diffs []FieldDiff) {
+	if c.schema == nil {
+		return
+	}
+	for i := range diffs {
+		field, ok := c.schema.Fields[diffs[i].Field]
+		if !ok || len(field.Tags) == 0 {
+			continue
+		}
+		diffs[i].Tags = field.Tags
+		if c.result.DiffsByTag == nil {
+			c.result.DiffsByTag = make(map[string]int)
+		}
+		for _, tag := range field.Tags {
+			c.result.DiffsByTag[tag]++
+		}
+	}
+}
+
+// countCategories counts diffs per category.
+func (c *StreamComparator) countCategories(diffs []FieldDiff) {
+	for _, diff := range diffs {
+		if diff.Category == "" {
+			continue
+		}
+		if c.result.DiffsByCategory == nil {
+			c.result.DiffsByCategory = make(map[string]int)
+		}
+		c.result.DiffsByCategory[diff.Category]++
+	}
+}
+
+func (c *StreamComparator) keyOf(rec datareader.Record, side Side) (string, error) {
+	keyField := c.key1
+	if side == Source2 {
+		keyField = c.key2
+	}
+	value, ok := rec[keyField]
+	if !ok || value == nil {
+		return "", fmt.Errorf("key field %q is missing", keyField)
+	}
+	return formatKey(c.options.canonicalValue(keyField, value)), nil
+}
+
+// formatKey returns the string a key value is matched by. Integral floats,
+// such as the IDs of JSON sources read without exact numbers, are written
+// in plain decimal, so 1234567 matches its integer and CSV forms rather than
+// becoming 1.234567e+06.
+func formatKey(v interface{}) string {
+	switch n := v.(type) {
+	case float64:
+		return formatFloatKey(n, 64)
+	case float32:
+		return formatFloatKey(float64(n), 32)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func formatFloatKey(f float64, bitSize int) string {
+	if f == math.Trunc(f) && math.Abs(f) < 1e21 {
+		return strconv.FormatFloat(f, 'f', -1, bitSize)
+	}
+	return strconv.FormatFloat(f, 'g', -1, bitSize)
+}
+
+// without returns a shallow copy of rec lacking the given field.
+func without(rec datareader.Record, field string) datareader.Record {
+	out := make(datareader.Record, len(rec))
+	for k, v := range rec {
+		if k != field {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// renamed returns a shallow copy of a source2 record with its mapped fields
+// under their source1 names.
+func renamed(rec datareader.Record, mappings map[string]string) datareader.Record {
+	names := make(map[string]string, len(mappings))
+	for name1, name2 := range mappings {
+		names[name2] = name1
+	}
+	out := make(datareader.Record, len(rec))
+	for k, v := range rec {
+		if name, ok := names[k]; ok {
+			k = name
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// compareRecords returns the differing leaf fields of two records, sorted by field name.
+func compareRecords(rec1, rec2 datareader.Record, options Options) []FieldDiff {
+	return compareFields("", rec1, rec2, options)
+}
+
+// compareFields returns the differing leaf fields of two objects nested
+// under prefix, sorted by field name. Arrays are compared by their field's
+// array mode.
+func compareFields(prefix string, m1, m2 map[string]interface{}, options Options) []FieldDiff {
+	flat1 := make(map[string]interface{})
+	flat2 := make(map[string]interface{})
+	flatten(m1, prefix, flat1)
+	flatten(m2, prefix, flat2)
+
+	names := make(map[string]struct{}, len(flat1))
+	for name := range flat1 {
+		names[name] = struct{}{}
+	}
+	for name := range flat2 {
+		names[name] = struct{}{}
+	}
+
+	var diffs []FieldDiff
+	for name := range names {
+		if options.FieldRules.of(name).ignore {
+			continue
+		}
+		v1, present1 := flat1[name]
+		v2, present2 := flat2[name]
+		if arrayDiffs, ok := compareArrays(name, v1, v2, options); ok {
+			diffs = append(diffs, arrayDiffs...)
+			continue
+		}
+		if diff, isBlob := compareBlobs(name, v1, v2, options); isBlob {
+			if diff != nil {
+				diffs = append(diffs, *diff)
+			}
+			continue
+		}
+		if !valuesEqual(name, options.canonicalValue(name, v1), options.canonicalValue(name, v2), options) {
+			diff := newFieldDiff(name, v1, v2, options)
+			if diff.Category == "" {
+				diff.Category = categorize(v1, v2, present1, present2)
+			}
+			diffs = append(diffs, diff)
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Field < diffs[j].Field })
+	return diffs
+}
+
+// newFieldDiff builds the FieldDiff for two differing values, classifying
+// whitespace-only differences and optionally making them visible. Other long
+// string differences get an inline edit script when enabled.
+func newFieldDiff(field string, v1, v2 interface{}, options Options) FieldDiff {
+	diff := FieldDiff{Field: field, Source1Value: v1, Source2Value: v2}
+
+	s1, ok1 := v1.(string)
+	s2, ok2 := v2.(string)
+	if !ok1 || !ok2 {
+		return diff
+	}
+	if stripInvisible(s1) != stripInvisible(s2) {
+		if options.InlineDiffMinLength > 0 && max(len(s1), len(s2)) >= options.InlineDiffMinLength {
+			diff.Edits = inlineDiff(s1, s2)
+		}
+		return diff
+	}
+
+	diff.Category = CategoryWhitespaceOnly
+	if options.VisualizeWhitespace {
+		diff.Source1Value = visualize(s1)
+		diff.Source2Value = visualize(s2)
+	}
+	return diff
+}
+
+// flatten collects the leaf values of nested objects under dotted field names.
+// Arrays are treated as leaf values.
+func flatten(data map[string]interface{}, prefix string, out map[string]interface{}) {
+	for key, value := range data {
+		name := key
+		if prefix != "" {
+			name = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			flatten(nested, name, out)
+			continue
+		}
+		out[name] = value
+	}
+}
+
+func sortedKeys(m map[string]datareader.Record) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
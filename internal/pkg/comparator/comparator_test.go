@@ -0,0 +1,356 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/config"
+	"data-comparator/internal/pkg/constraint"
+	"data-comparator/internal/pkg/datareader"
+	"data-comparator/internal/pkg/schema"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func openReaders(t *testing.T, dir, ext, sourceType string) (datareader.DataReader, datareader.DataReader) {
+	t.Helper()
+	reader1, err := datareader.New(config.Source{Type: sourceType, Path: "../../../testdata/" + dir + "/source1." + ext})
+	if err != nil {
+		t.Fatalf("Failed to create reader for source1: %v", err)
+	}
+	reader2, err := datareader.New(config.Source{Type: sourceType, Path: "../../../testdata/" + dir + "/source2." + ext})
+	if err != nil {
+		t.Fatalf("Failed to create reader for source2: %v", err)
+	}
+	t.Cleanup(func() {
+		reader1.Close()
+		reader2.Close()
+	})
+	return reader1, reader2
+}
+
+func TestCompare_SimpleCSV(t *testing.T) {
+	reader1, reader2 := openReaders(t, "testcase1_simple_csv", "csv", "csv")
+
+	result, err := New("user_id").Compare(reader1, reader2)
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+
+	expectedSummary := Summary{
+		Source1Rows:       5,
+		Source2Rows:       5,
+		MatchingKeys:      4,
+		IdenticalRows:     3,
+		KeysOnlyInSource1: 1,
+		KeysOnlyInSource2: 1,
+	}
+	if result.Summary != expectedSummary {
+		t.Errorf("Summary got = %+v, want %+v", result.Summary, expectedSummary)
+	}
+
+	expectedDiffs := []FieldDiff{
+		{Field: "age", Source1Value: "30", Source2Value: "31"},
+		{Field: "last_login", Source1Value: "2025-09-10T12:00:00Z", Source2Value: "2025-09-10T13:00:00Z"},
+		{Field: "plan_type", Source1Value: "premium", Source2Value: "premium_plus"},
+	}
+	if !reflect.DeepEqual(result.ValueDiffs["1"], expectedDiffs) {
+		t.Errorf("ValueDiffs[1] got = %v, want %v", result.ValueDiffs["1"], expectedDiffs)
+	}
+
+	if !reflect.DeepEqual(result.KeysOnly.InSource1, []string{"5"}) {
+		t.Errorf("KeysOnly.InSource1 got = %v, want [5]", result.KeysOnly.InSource1)
+	}
+	if !reflect.DeepEqual(result.KeysOnly.InSource2, []string{"6"}) {
+		t.Errorf("KeysOnly.InSource2 got = %v, want [6]", result.KeysOnly.InSource2)
+	}
+}
+
+func TestCompare_NestedJSON(t *testing.T) {
+	reader1, reader2 := openReaders(t, "testcase2_nested_json", "jsonl", "json")
+
+	result, err := New("event_id").Compare(reader1, reader2)
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+
+	var fields []string
+	for _, diff := range result.ValueDiffs["evt-001"] {
+		fields = append(fields, diff.Field)
+	}
+	expectedFields := []string{"customer.region", "metrics.latency_ms", "timestamp"}
+	if !reflect.DeepEqual(fields, expectedFields) {
+		t.Errorf("Differing fields for evt-001 got = %v, want %v", fields, expectedFields)
+	}
+}
+
+func TestCompare_Hooks(t *testing.T) {
+	reader1, reader2 := openReaders(t, "testcase1_simple_csv", "csv", "csv")
+
+	var matched, diffed, only1, only2 []string
+	c := New("user_id")
+	c.SetHooks(Hooks{
+		OnMatch: func(key string, _, _ datareader.Record) { matched = append(matched, key) },
+		OnDiff: func(key string, diffs []FieldDiff, _, _ datareader.Record) {
+			if len(diffs) == 0 {
+				t.Errorf("OnDiff called for key %s without diffs", key)
+			}
+			diffed = append(diffed, key)
+		},
+		OnOnlyInSource1: func(key string, _ datareader.Record) { only1 = append(only1, key) },
+		OnOnlyInSource2: func(key string, _ datareader.Record) { only2 = append(only2, key) },
+	})
+
+	if _, err := c.Compare(reader1, reader2); err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+
+	sort.Strings(matched)
+	if !reflect.DeepEqual(matched, []string{"2", "3", "4"}) {
+		t.Errorf("OnMatch keys got = %v, want [2 3 4]", matched)
+	}
+	if !reflect.DeepEqual(diffed, []string{"1"}) {
+		t.Errorf("OnDiff keys got = %v, want [1]", diffed)
+	}
+	if !reflect.DeepEqual(only1, []string{"5"}) {
+		t.Errorf("OnOnlyInSource1 keys got = %v, want [5]", only1)
+	}
+	if !reflect.DeepEqual(only2, []string{"6"}) {
+		t.Errorf("OnOnlyInSource2 keys got = %v, want [6]", only2)
+	}
+}
+
+func TestCompare_MissingKey(t *testing.T) {
+	reader1, reader2 := openReaders(t, "testcase1_simple_csv", "csv", "csv")
+
+	if _, err := New("no_such_field").Compare(reader1, reader2); err == nil {
+		t.Error("Compare() expected error for missing key field, got nil")
+	}
+}
+
+func TestAdd_ProgressWithManualClock(t *testing.T) {
+	start := time.Date(2025, 9, 10, 12, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+
+	var snapshots []Summary
+	c := New("id")
+	c.SetClock(clock)
+	c.SetProgressInterval(time.Minute)
+	c.SetHooks(Hooks{OnProgress: func(summary Summary) { snapshots = append(snapshots, summary) }})
+
+	add := func(side Side, id string) {
+		t.Helper()
+		if err := c.Add(side, datareader.Record{"id": id}); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	add(Source1, "a")
+	clock.Advance(30 * time.Second)
+	add(Source2, "a")
+	if len(snapshots) != 0 {
+		t.Fatalf("Expected no progress before the interval elapsed, got %d", len(snapshots))
+	}
+
+	clock.Advance(30 * time.Second)
+	add(Source1, "b")
+	if len(snapshots) != 1 {
+		t.Fatalf("Expected 1 progress snapshot, got %d", len(snapshots))
+	}
+	if snapshots[0].Source1Rows != 2 || snapshots[0].MatchingKeys != 1 {
+		t.Errorf("Progress snapshot got = %+v, want 2 source1 rows and 1 matching key", snapshots[0])
+	}
+
+	clock.Advance(59 * time.Second)
+	add(Source2, "c")
+	if len(snapshots) != 1 {
+		t.Errorf("Expected interval to restart after a snapshot, got %d snapshots", len(snapshots))
+	}
+
+	clock.Advance(time.Second)
+	result := c.Finish()
+	if !result.StartedAt.Equal(start) {
+		t.Errorf("StartedAt got = %v, want %v", result.StartedAt, start)
+	}
+	if want := start.Add(2 * time.Minute); !result.FinishedAt.Equal(want) {
+		t.Errorf("FinishedAt got = %v, want %v", result.FinishedAt, want)
+	}
+	if result.Summary.KeysOnlyInSource1 != 1 || result.Summary.KeysOnlyInSource2 != 1 {
+		t.Errorf("Summary got = %+v, want one key only in each source", result.Summary)
+	}
+}
+
+func TestAdd_InvalidSide(t *testing.T) {
+	if err := New("id").Add(Side(3), datareader.Record{"id": "a"}); err == nil {
+		t.Error("Add() expected error for invalid side, got nil")
+	}
+}
+
+func TestCompare_DiffsByTag(t *testing.T) {
+	reader1, reader2 := openReaders(t, "testcase1_simple_csv", "csv", "csv")
+
+	c := New("user_id")
+	c.SetSchema(&schema.Schema{Fields: map[string]*schema.Field{
+		"age":       {Type: "numeric", Tags: []string{"pii"}},
+		"plan_type": {Type: "string", Tags: []string{"billing", "derived"}},
+	}})
+	result, err := c.Compare(reader1, reader2)
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+
+	expected := map[string]int{"pii": 1, "billing": 1, "derived": 1}
+	if !reflect.DeepEqual(result.DiffsByTag, expected) {
+		t.Errorf("DiffsByTag got = %v, want %v", result.DiffsByTag, expected)
+	}
+	for _, diff := range result.ValueDiffs["1"] {
+		if diff.Field == "age" && !reflect.DeepEqual(diff.Tags, []string{"pii"}) {
+			t.Errorf("Tags on age diff got = %v, want [pii]", diff.Tags)
+		}
+	}
+}
+
+func TestCompare_DifferentKeyNames(t *testing.T) {
+	c := New("")
+	c.SetKeys("user_id", "uid")
+
+	add := func(side Side, rec datareader.Record) {
+		t.Helper()
+		if err := c.Add(side, rec); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	add(Source1, datareader.Record{"user_id": "1", "name": "alice"})
+	add(Source2, datareader.Record{"uid": "1", "name": "alice"})
+	add(Source1, datareader.Record{"user_id": "2", "name": "bob"})
+	add(Source2, datareader.Record{"uid": "2", "name": "robert"})
+
+	result := c.Finish()
+	if result.Summary.MatchingKeys != 2 || result.Summary.IdenticalRows != 1 {
+		t.Errorf("Summary got = %+v, want 2 matching keys and 1 identical row", result.Summary)
+	}
+	if diffs := result.ValueDiffs["2"]; len(diffs) != 1 || diffs[0].Field != "name" {
+		t.Errorf("ValueDiffs[2] got = %v, want a single diff on name", diffs)
+	}
+	if result.Keys != (KeyMapping{Source1: "user_id", Source2: "uid"}) {
+		t.Errorf("Keys got = %+v", result.Keys)
+	}
+}
+
+func TestCompare_SchemaViolations(t *testing.T) {
+	reader1, reader2 := openReaders(t, "testcase1_simple_csv", "csv", "csv")
+
+	c := New("user_id")
+	c.SetSchema(&schema.Schema{Fields: map[string]*schema.Field{
+		"age":       {Type: "numeric", Matchers: []schema.Matcher{{"max": 30}}},
+		"plan_type": {Type: "string", Matchers: []schema.Matcher{{"oneOf": []interface{}{"basic", "premium"}}}},
+	}})
+	result, err := c.Compare(reader1, reader2)
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+
+	if result.Violations == nil {
+		t.Fatal("Violations is nil")
+	}
+	if result.Summary.Source1Violations != 2 || result.Summary.Source2Violations != 5 {
+		t.Errorf("Violation counts got = %d and %d, want 2 and 5",
+			result.Summary.Source1Violations, result.Summary.Source2Violations)
+	}
+	if len(result.Violations.Source1) != 2 || len(result.Violations.Source2) != 5 {
+		t.Errorf("Kept violations got = %v, want 2 and 5", result.Violations)
+	}
+	for _, v := range result.Violations.Source2 {
+		if v.Record < 1 || v.Record > result.Summary.Source2Rows {
+			t.Errorf("Violation record number got = %d, want within 1..%d", v.Record, result.Summary.Source2Rows)
+		}
+	}
+}
+
+func TestCompare_Constraints(t *testing.T) {
+	adult, err := constraint.Compile("adult", "age >= 30")
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	broken, err := constraint.Compile("", "city * 2 > 0")
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	c := New("id")
+	c.SetConstraints([]*constraint.Constraint{adult, broken})
+	for _, add := range []struct {
+		side Side
+		rec  datareader.Record
+	}{
+		{Source1, datareader.Record{"id": "1", "age": "25"}},
+		{Source2, datareader.Record{"id": "1", "age": "35", "city": "Rome"}},
+	} {
+		if err := c.Add(add.side, add.rec); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	result := c.Finish()
+	if result.Violations == nil {
+		t.Fatal("Violations is nil")
+	}
+	if v := result.Violations.Source1; len(v) != 1 || v[0].Field != "adult" || v[0].Rule != "constraint" || v[0].Record != 1 {
+		t.Errorf("Source1 violations got = %+v, want adult on record 1", v)
+	}
+	if v := result.Violations.Source2; len(v) != 1 || v[0].Field != "city * 2 > 0" {
+		t.Errorf("Source2 violations got = %+v, want an evaluation error", v)
+	}
+}
+
+// warningReader is a reader with warnings.
+type warningReader struct {
+	datareader.DataReader
+	warnings []string
+}
+
+func (r warningReader) Warnings() []string { return r.warnings }
+
+func TestCompare_Warnings(t *testing.T) {
+	records := []datareader.Record{{"id": "1"}}
+	reader1 := warningReader{datareader.NewSliceReader(records), []string{"2 values were left as strings"}}
+	reader2 := datareader.NewSliceReader(records)
+	result, err := New("id").Compare(reader1, reader2)
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	if want := []string{"source1: 2 values were left as strings"}; !reflect.DeepEqual(result.Warnings, want) {
+		t.Errorf("Warnings got = %v, want %v", result.Warnings, want)
+	}
+}
+
+func TestCompare_FloatKeys(t *testing.T) {
+	reader1 := datareader.NewSliceReader([]datareader.Record{{"id": float64(1234567), "v": 1}, {"id": 0.5, "v": 1}})
+	reader2 := datareader.NewSliceReader([]datareader.Record{{"id": "1234567", "v": 1}, {"id": "0.5", "v": 1}})
+	result, err := New("id").Compare(reader1, reader2)
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	if result.Summary.MatchingKeys != 2 {
+		t.Errorf("Summary got = %+v, want 2 matching keys", result.Summary)
+	}
+}
+
+func TestFormatKey(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{float64(1234567), "1234567"},
+		{float64(-42), "-42"},
+		{float32(16777216), "16777216"},
+		{1.5, "1.5"},
+		{1e300, "1e+300"},
+		{int64(7), "7"},
+		{"007", "007"},
+	}
+	for _, tt := range tests {
+		if got := formatKey(tt.value); got != tt.want {
+			t.Errorf("formatKey(%v) got = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
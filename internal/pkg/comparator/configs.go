@@ -0,0 +1,454 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/config"
+	"data-comparator/internal/pkg/constraint"
+	"data-comparator/internal/pkg/datareader"
+	"data-comparator/internal/pkg/schema"
+	"fmt"
+)
+
+// CompareConfigs opens the sources of two configs and joins their records on
+// key1 and key2, as set up by OpenConfigs. The result embeds the effective
+// configuration and the source fingerprints, for Rerun.
+func CompareConfigs(config1, config2 *config.Config, key1, key2 string) (*Result, error) {
+	return CompareConfigsWith(config1, config2, key1, key2, nil)
+}
+
+// CompareConfigsWith is CompareConfigs calling prepare, if not nil, with the
+// comparator before the comparison starts, e.g. to set hooks or hand it to
+// something requesting snapshots.
+func CompareConfigsWith(config1, config2 *config.Config, key1, key2 string, prepare func(c *StreamComparator)) (*Result, error) {
+	c, reader1, reader2, err := OpenConfigs(config1, config2, key1, key2)
+	if err != nil {
+		return nil, err
+	}
+	if prepare != nil {
+		prepare(c)
+	}
+	defer reader1.Close()
+	defer reader2.Close()
+	result, err := c.Compare(reader1, reader2)
+	if err != nil {
+		return nil, err
+	}
+	if result.Run, err = newRunInfo(config1, config2, c.key1, c.key2); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// newRunInfo describes a run of two configs joined on key1 and key2.
+func newRunInfo(config1, config2 *config.Config, key1, key2 string) (*RunInfo, error) {
+	effective1, effective2 := *config1, *config2
+	effective1.Source.Key, effective2.Source.Key = key1, key2
+	// Passwords stay out of reports; reruns take them from the environment.
+	effective1.Source.DSN = datareader.RedactDSN(effective1.Source.DSN)
+	effective2.Source.DSN = datareader.RedactDSN(effective2.Source.DSN)
+	effective1.Source.ObjectStore = datareader.RedactObjectStore(effective1.Source.ObjectStore)
+	effective2.Source.ObjectStore = datareader.RedactObjectStore(effective2.Source.ObjectStore)
+	effective1.Source.HTTP = datareader.RedactHTTP(effective1.Source.HTTP)
+	effective2.Source.HTTP = datareader.RedactHTTP(effective2.Source.HTTP)
+	effective1.Source.Remote = datareader.RedactRemote(effective1.Source.Remote)
+	effective2.Source.Remote = datareader.RedactRemote(effective2.Source.Remote)
+	info := &RunInfo{Config: config.Run{Config1: &effective1, Config2: &effective2}}
+
+	var err error
+	if info.Fingerprints.Source1, err = fingerprintSource(config1.Source); err != nil {
+		return nil, fmt.Errorf("source1: %w", err)
+	}
+	if info.Fingerprints.Source2, err = fingerprintSource(config2.Source); err != nil {
+		return nil, fmt.Errorf("source2: %w", err)
+	}
+	return info, nil
+}
+
+// fingerprintSource fingerprints the file of a source. Database, http,
+// listening and standard input sources have no file and get an empty
+// fingerprint. Archive members are fingerprinted by their archive.
+func fingerprintSource(src config.Source) (Fingerprint, error) {
+	if archive, _, ok := datareader.SplitArchivePath(src.Path); ok && !datareader.IsDatabaseType(src.Type) {
+		src.Path, src.Order = archive, ""
+	}
+	if datareader.IsDatabaseType(src.Type) || src.Type == "http" || src.Path == datareader.StdinPath || datareader.IsListenPath(src.Path) {
+		return Fingerprint{}, nil
+	}
+	if datareader.IsObjectPath(src.Path) {
+		info, err := datareader.StatObject(src.Path, src.ObjectStore)
+		if err != nil {
+			return Fingerprint{}, err
+		}
+		return Fingerprint{Size: info.Size, ETag: info.ETag}, nil
+	}
+	if datareader.IsRemotePath(src.Path) {
+		size, err := datareader.StatRemote(src.Path, src.Remote)
+		if err != nil {
+			return Fingerprint{}, err
+		}
+		return Fingerprint{Size: size}, nil
+	}
+	files, err := datareader.SourceFiles(src)
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	return FingerprintFiles(files)
+}
+
+// OpenConfigs opens readers for the sources of two configs and a comparator
+// joining them on key1 and key2. An empty key falls back to the source's
+// configured key, then to the key inferred from a sample of its data. The
+// comparison settings of both configs apply, merged by Settings.
+// Unless disabled, the keys of the first records of both sources are checked
+// for overlap first, which warns in the result if they share none, or fails
+// with a KeyOverlapError if the check is set to fail. A configured script is
+// started, and stopped by the comparator when its comparison ends.
+// The caller closes the readers.
+func OpenConfigs(config1, config2 *config.Config, key1, key2 string) (*StreamComparator, datareader.DataReader, datareader.DataReader, error) {
+	var err error
+	if key1, err = sourceKey(config1.Source, key1); err != nil {
+		return nil, nil, nil, fmt.Errorf("source1: %w", err)
+	}
+	if key2, err = sourceKey(config2.Source, key2); err != nil {
+		return nil, nil, nil, fmt.Errorf("source2: %w", err)
+	}
+	// An empty source has no key to infer; any key will do for it.
+	if key1 == "" {
+		key1 = key2
+	}
+	if key2 == "" {
+		key2 = key1
+	}
+	if key1 == "" {
+		return nil, nil, nil, fmt.Errorf("no key field found in either source; set source.key")
+	}
+
+	settings, err := Settings(config1, config2)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	constraints, err := constraint.CompileConfig(settings)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	options := OptionsFromConfig(settings)
+	if options.FieldRules, err = CompileFieldRules(settings); err != nil {
+		return nil, nil, nil, err
+	}
+	var duplicates DuplicatePolicy
+	var expectation Expectation
+	var missing MissingKeySeverity
+	var thresholds Thresholds
+	var baseline *Baseline
+	if settings != nil {
+		// Only a configured policy tracks the keys read, to find the
+		// records of keys already matched.
+		if settings.DuplicateKeys != "" {
+			if duplicates, err = ParseDuplicatePolicy(settings.DuplicateKeys); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		if expectation, err = ParseExpectation(settings.Expect); err != nil {
+			return nil, nil, nil, err
+		}
+		if missing, err = parseMissingKeys(settings.MissingKeys); err != nil {
+			return nil, nil, nil, err
+		}
+		if t := settings.Thresholds; t != nil {
+			thresholds = Thresholds{MaxDiffs: t.MaxDiffs, MaxDiffPercent: t.MaxDiffPercent, MaxFieldMismatchRate: t.MaxFieldMismatchRate}
+			if err := thresholds.validate(); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		if settings.Baseline != "" {
+			if baseline, err = LoadBaseline(settings.Baseline); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+	}
+	keyWarning, err := checkKeys(config1, config2, key1, key2, settings, options)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	reader1, err := datareader.New(config1.Source)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create reader for source1: %w", err)
+	}
+	reader2, err := datareader.New(config2.Source)
+	if err != nil {
+		reader1.Close()
+		return nil, nil, nil, fmt.Errorf("failed to create reader for source2: %w", err)
+	}
+	script, err := startScript(settings, &options)
+	if err != nil {
+		reader1.Close()
+		reader2.Close()
+		return nil, nil, nil, err
+	}
+
+	c := New("")
+	c.script = script
+	c.SetKeys(key1, key2)
+	c.SetOptions(options)
+	c.SetConstraints(constraints)
+	c.SetDuplicatePolicy(duplicates)
+	c.SetExpectation(expectation)
+	c.SetMissingKeySeverity(missing)
+	c.SetThresholds(thresholds)
+	c.SetBaseline(baseline)
+	if settings != nil && settings.Spill != nil {
+		c.SetSpill(SpillOptions{
+			Dir:           settings.Spill.Dir,
+			MemoryRecords: settings.Spill.MemoryRecords,
+			Partitions:    settings.Spill.Partitions,
+		})
+	}
+	if settings != nil && settings.Stall != nil {
+		c.SetStall(StallOptions{Timeout: settings.Stall.Timeout, Fail: settings.Stall.Fail})
+	}
+	if settings != nil {
+		c.SetDeadline(settings.Deadline)
+		c.SetTrackResolved(settings.TrackResolved)
+		c.SetSkipParseErrors(settings.SkipParseErrors)
+		c.SetLookup(settings.Lookup)
+		if settings.Lag != nil {
+			c.SetLag(&LagOptions{TimestampField: settings.Lag.TimestampField})
+		}
+	}
+	c.SetReadTimeout(Source1, config1.Source.ReadTimeout)
+	c.SetReadTimeout(Source2, config2.Source.ReadTimeout)
+	if keyWarning != "" {
+		c.checkWarnings = append(c.checkWarnings, keyWarning)
+	}
+	return c, reader1, reader2, nil
+}
+
+// Settings returns the comparison settings of both configs, merged by
+// config.MergeComparison, so each may hold some of them. It returns an error
+// if they set one differently, and nil if neither has any.
+func Settings(config1, config2 *config.Config) (*config.Comparison, error) {
+	return config.MergeComparison(config1.Comparison, config2.Comparison)
+}
+
+// CompareMultisetConfigs opens the sources of two configs and compares them
+// as multisets of whole records, as configured by the multiset comparison
+// settings.
+func CompareMultisetConfigs(config1, config2 *config.Config) (*MultisetResult, error) {
+	settings, err := Settings(config1, config2)
+	if err != nil {
+		return nil, err
+	}
+	options, err := unkeyedOptions(settings)
+	if err != nil {
+		return nil, err
+	}
+	var fuzzyFields []string
+	if settings != nil && settings.Multiset != nil {
+		for _, field := range settings.Multiset.FuzzyFields {
+			fuzzyFields = append(fuzzyFields, datareader.NormalizeFieldPath(field, config1.Source.FieldNames))
+		}
+	}
+
+	reader1, reader2, err := openSources(config1, config2)
+	if err != nil {
+		return nil, err
+	}
+	defer reader1.Close()
+	defer reader2.Close()
+	script, err := startScript(settings, &options)
+	if err != nil {
+		return nil, err
+	}
+	defer script.Close()
+	result, err := CompareMultiset(reader1, reader2, options, fuzzyFields...)
+	if err == nil {
+		err = script.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CompareOrderedConfigs opens the sources of two configs and compares their
+// records by position, as configured by the ordered comparison settings.
+func CompareOrderedConfigs(config1, config2 *config.Config) (*OrderedResult, error) {
+	settings, err := Settings(config1, config2)
+	if err != nil {
+		return nil, err
+	}
+	options, err := unkeyedOptions(settings)
+	if err != nil {
+		return nil, err
+	}
+	opts := OrderedOptions{Equality: &options}
+	if settings != nil && settings.Ordered != nil {
+		opts.Window = settings.Ordered.ResyncWindow
+	}
+
+	reader1, reader2, err := openSources(config1, config2)
+	if err != nil {
+		return nil, err
+	}
+	defer reader1.Close()
+	defer reader2.Close()
+	script, err := startScript(settings, &options)
+	if err != nil {
+		return nil, err
+	}
+	defer script.Close()
+	result, err := CompareOrdered(reader1, reader2, opts)
+	if err == nil {
+		err = script.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CompareWindowedConfigs opens the sources of two configs and pairs their
+// records by timestamp proximity and field similarity, as configured by the
+// window comparison settings.
+func CompareWindowedConfigs(config1, config2 *config.Config) (*WindowResult, error) {
+	settings, err := Settings(config1, config2)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil || settings.Window == nil {
+		return nil, fmt.Errorf("windowed comparison requires comparison.window")
+	}
+	options, err := unkeyedOptions(settings)
+	if err != nil {
+		return nil, err
+	}
+	window := settings.Window
+	if window.MinSimilarity < 0 || window.MinSimilarity > 1 {
+		return nil, fmt.Errorf("comparison.window.min_similarity must be between 0 and 1, got %g", window.MinSimilarity)
+	}
+	opts := WindowOptions{
+		TimestampField: datareader.NormalizeFieldPath(window.TimestampField, config1.Source.FieldNames),
+		Window:         window.Window,
+		MinSimilarity:  window.MinSimilarity,
+		Equality:       &options,
+	}
+	for _, field := range window.Fields {
+		opts.Fields = append(opts.Fields, datareader.NormalizeFieldPath(field, config1.Source.FieldNames))
+	}
+
+	reader1, reader2, err := openSources(config1, config2)
+	if err != nil {
+		return nil, err
+	}
+	defer reader1.Close()
+	defer reader2.Close()
+	script, err := startScript(settings, &options)
+	if err != nil {
+		return nil, err
+	}
+	defer script.Close()
+	result, err := CompareWindowed(reader1, reader2, opts)
+	if err == nil {
+		err = script.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// unkeyedOptions returns the equality options of comparisons without a key.
+// A baseline accepts the diffs of keys, so it fails them.
+func unkeyedOptions(settings *config.Comparison) (Options, error) {
+	if settings != nil && settings.Baseline != "" {
+		return Options{}, fmt.Errorf("comparison.baseline needs a keyed comparison")
+	}
+	options := OptionsFromConfig(settings)
+	var err error
+	options.FieldRules, err = CompileFieldRules(settings)
+	return options, err
+}
+
+// startScript starts the script of settings, if any, and sets the normalize
+// and equal functions of options to it. The caller closes it.
+func startScript(settings *config.Comparison, options *Options) (*Script, error) {
+	if settings == nil || settings.Script == nil {
+		return nil, nil
+	}
+	script, err := StartScript(settings.Script)
+	if err != nil {
+		return nil, err
+	}
+	script.apply(options)
+	return script, nil
+}
+
+// openSources opens readers for the sources of two configs. The caller
+// closes them.
+func openSources(config1, config2 *config.Config) (datareader.DataReader, datareader.DataReader, error) {
+	reader1, err := datareader.New(config1.Source)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create reader for source1: %w", err)
+	}
+	reader2, err := datareader.New(config2.Source)
+	if err != nil {
+		reader1.Close()
+		return nil, nil, fmt.Errorf("failed to create reader for source2: %w", err)
+	}
+	return reader1, reader2, nil
+}
+
+// parseMissingKeys parses the configured severities of keys only in each
+// source, leaving those not set empty.
+func parseMissingKeys(cfg *config.MissingKeys) (MissingKeySeverity, error) {
+	var severity MissingKeySeverity
+	if cfg == nil {
+		return severity, nil
+	}
+	for _, s := range []struct {
+		name  string
+		value *Severity
+	}{{cfg.Source1, &severity.Source1}, {cfg.Source2, &severity.Source2}} {
+		if s.name == "" {
+			continue
+		}
+		parsed, err := ParseSeverity(s.name)
+		if err != nil {
+			return MissingKeySeverity{}, fmt.Errorf("missing_keys: %w", err)
+		}
+		*s.value = parsed
+	}
+	return severity, nil
+}
+
+// sourceKey returns key, else the configured or inferred key of src. Keys
+// are named like the fields read, with normalized names.
+func sourceKey(src config.Source, key string) (string, error) {
+	if key != "" {
+		return datareader.NormalizeFieldPath(key, src.FieldNames), nil
+	}
+	if src.Key != "" {
+		return datareader.NormalizeFieldPath(src.Key, src.FieldNames), nil
+	}
+	if src.Path == datareader.StdinPath {
+		// Inferring the key would use up the records to compare.
+		return "", fmt.Errorf("source.key is required for a source read from standard input")
+	}
+
+	reader, err := datareader.New(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to create reader: %w", err)
+	}
+	defer reader.Close()
+	s, err := schema.Generate(reader, src.Sampler)
+	if err != nil {
+		return "", fmt.Errorf("failed to infer key: %w", err)
+	}
+	if len(s.Fields) == 0 {
+		return "", nil
+	}
+	if s.Key == "" {
+		return "", fmt.Errorf("no key field found; set source.key")
+	}
+	return s.Key, nil
+}
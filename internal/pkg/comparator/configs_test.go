@@ -0,0 +1,163 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/config"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompareConfigs(t *testing.T) {
+	config1 := &config.Config{Source: config.Source{Type: "csv", Path: "../../../testdata/testcase1_simple_csv/source1.csv"}}
+	config2 := &config.Config{Source: config.Source{Type: "csv", Path: "../../../testdata/testcase1_simple_csv/source2.csv"}}
+
+	result, err := CompareConfigs(config1, config2, "", "")
+	if err != nil {
+		t.Fatalf("CompareConfigs() error = %v", err)
+	}
+	if result.Keys != (KeyMapping{Source1: "user_id", Source2: "user_id"}) {
+		t.Errorf("Keys got = %+v, want inferred user_id", result.Keys)
+	}
+	if result.Summary.MatchingKeys != 4 {
+		t.Errorf("MatchingKeys got = %d, want 4", result.Summary.MatchingKeys)
+	}
+
+	data, err := result.JSON()
+	if err != nil {
+		t.Fatalf("JSON() error = %v", err)
+	}
+	var decoded struct {
+		Summary struct {
+			MatchingKeys int `json:"matching_keys"`
+		} `json:"summary"`
+		ValueDiffs map[string][]map[string]interface{} `json:"value_diffs_by_key"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if decoded.Summary.MatchingKeys != 4 || len(decoded.ValueDiffs["1"]) != 3 {
+		t.Errorf("JSON result got = %s", data)
+	}
+
+	config1.Source.Key = "no_such_field"
+	if _, err := CompareConfigs(config1, config2, "", ""); err == nil {
+		t.Error("CompareConfigs() expected error for missing key field, got nil")
+	}
+}
+
+func TestCompareConfigs_EmptySource(t *testing.T) {
+	empty := filepath.Join(t.TempDir(), "empty.csv")
+	if err := os.WriteFile(empty, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	config1 := &config.Config{Source: config.Source{Type: "csv", Path: empty}}
+	config2 := &config.Config{Source: config.Source{Type: "csv", Path: "../../../testdata/testcase1_simple_csv/source2.csv"}}
+
+	result, err := CompareConfigs(config1, config2, "", "")
+	if err != nil {
+		t.Fatalf("CompareConfigs() error = %v", err)
+	}
+	if result.Keys != (KeyMapping{Source1: "user_id", Source2: "user_id"}) {
+		t.Errorf("Keys got = %+v, want user_id taken from source2", result.Keys)
+	}
+	if !reflect.DeepEqual(result.EmptySources, []string{"source1"}) {
+		t.Errorf("EmptySources got = %v, want [source1]", result.EmptySources)
+	}
+	if result.Summary.Source1Rows != 0 || result.Summary.KeysOnlyInSource2 != 5 {
+		t.Errorf("Summary got = %+v, want 0 source1 rows and 5 keys only in source2", result.Summary)
+	}
+
+	config2.Source.Path = empty
+	if _, err := CompareConfigs(config1, config2, "", ""); err == nil {
+		t.Error("CompareConfigs() expected error without any key for two empty sources, got nil")
+	}
+	result, err = CompareConfigs(config1, config2, "id", "id")
+	if err != nil {
+		t.Fatalf("CompareConfigs() error = %v", err)
+	}
+	if !reflect.DeepEqual(result.EmptySources, []string{"source1", "source2"}) || result.Summary != (Summary{}) {
+		t.Errorf("Result got = %+v, want an empty comparison of two empty sources", result)
+	}
+}
+
+func TestCompareConfigs_FieldNames(t *testing.T) {
+	dir := t.TempDir()
+	path1, path2 := filepath.Join(dir, "source1.csv"), filepath.Join(dir, "source2.jsonl")
+	if err := os.WriteFile(path1, []byte("User ID,Email Address\n1,a@x.com\n2,b@x.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path2, []byte(`{"userId": "1", "emailAddress": "a@x.com"}`+"\n"+`{"userId": "2", "emailAddress": "c@x.com"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	names := &config.FieldNames{SnakeCase: true}
+	config1 := &config.Config{Source: config.Source{Type: "csv", Path: path1, Key: "User ID", FieldNames: names}}
+	config2 := &config.Config{Source: config.Source{Type: "json", Path: path2, Key: "userId", FieldNames: names}}
+
+	result, err := CompareConfigs(config1, config2, "", "")
+	if err != nil {
+		t.Fatalf("CompareConfigs() error = %v", err)
+	}
+	if result.Keys != (KeyMapping{Source1: "user_id", Source2: "user_id"}) {
+		t.Errorf("Keys got = %+v, want user_id", result.Keys)
+	}
+	if result.Summary.MatchingKeys != 2 || result.Summary.IdenticalRows != 1 {
+		t.Errorf("Summary got = %+v, want 2 matching keys and 1 identical row", result.Summary)
+	}
+	if diffs := result.ValueDiffs["2"]; len(diffs) != 1 || diffs[0].Field != "email_address" {
+		t.Errorf("ValueDiffs[2] got = %v, want a single diff on email_address", diffs)
+	}
+}
+
+func TestCompareConfigs_MergedSettings(t *testing.T) {
+	maxDiffs := 0
+	config1 := &config.Config{
+		Source:     config.Source{Type: "csv", Path: "../../../testdata/testcase1_simple_csv/source1.csv"},
+		Comparison: &config.Comparison{Thresholds: &config.Thresholds{MaxDiffs: &maxDiffs}},
+	}
+	config2 := &config.Config{
+		Source:     config.Source{Type: "csv", Path: "../../../testdata/testcase1_simple_csv/source2.csv"},
+		Comparison: &config.Comparison{Expect: "subset"},
+	}
+
+	result, err := CompareConfigs(config1, config2, "", "")
+	if err != nil {
+		t.Fatalf("CompareConfigs() error = %v", err)
+	}
+	if result.Expectation != ExpectSubset || len(result.ThresholdsExceeded) == 0 {
+		t.Errorf("result got expectation %q and thresholds exceeded %v, want the settings of both configs", result.Expectation, result.ThresholdsExceeded)
+	}
+
+	otherMaxDiffs := 5
+	config2.Comparison.Thresholds = &config.Thresholds{MaxDiffs: &otherMaxDiffs}
+	if _, err := CompareConfigs(config1, config2, "", ""); err == nil || !strings.Contains(err.Error(), "comparison.thresholds.max_diffs is set differently") {
+		t.Errorf("CompareConfigs() error got = %v, want conflicting max_diffs", err)
+	}
+}
+
+func TestCompareConfigs_Baseline(t *testing.T) {
+	baseline := filepath.Join(t.TempDir(), "baseline.yaml")
+	if err := os.WriteFile(baseline, []byte("entries:\n  - key: \"1\"\n    reason: known upgrade\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	config1 := &config.Config{
+		Source:     config.Source{Type: "csv", Path: "../../../testdata/testcase1_simple_csv/source1.csv"},
+		Comparison: &config.Comparison{Baseline: baseline},
+	}
+	config2 := &config.Config{Source: config.Source{Type: "csv", Path: "../../../testdata/testcase1_simple_csv/source2.csv"}}
+
+	result, err := CompareConfigs(config1, config2, "", "")
+	if err != nil {
+		t.Fatalf("CompareConfigs() error = %v", err)
+	}
+	if _, ok := result.ValueDiffs["1"]; ok || result.Summary.IdenticalRows != 4 {
+		t.Errorf("result got diffs %v and %d identical rows, want key 1 accepted by the baseline", result.ValueDiffs, result.Summary.IdenticalRows)
+	}
+
+	config1.Comparison.Baseline = filepath.Join(t.TempDir(), "missing.yaml")
+	if _, err := CompareConfigs(config1, config2, "", ""); err == nil {
+		t.Error("CompareConfigs() expected an error for a missing baseline, got nil")
+	}
+}
@@ -0,0 +1,62 @@
+package comparator
+
+import (
+	"fmt"
+	"time"
+)
+
+// Incompletion is a source that was not read to its end, because it timed
+// out or the deadline passed. Keys reported only in the other source may
+// then still be in its unread records.
+type Incompletion struct {
+	Source string `yaml:"source"`
+	Reason string `yaml:"reason"`
+	// Records is the number of records read from the source before it was cut off.
+	Records int `yaml:"records"`
+}
+
+// SetReadTimeout sets how long a single read of side may take. A source
+// whose next record does not arrive in time is no longer read and the
+// comparison finishes with the records read so far, flagged as incomplete
+// in Result.Incomplete. Zero disables the timeout.
+func (c *StreamComparator) SetReadTimeout(side Side, timeout time.Duration) {
+	c.readTimeouts[side-1] = timeout
+}
+
+// SetDeadline sets how long Compare and Findings may read the sources,
+// counted from the start of the comparison. Once it passes, reading stops
+// and the comparison finishes with partial, incomplete results. Zero
+// disables the deadline.
+func (c *StreamComparator) SetDeadline(deadline time.Duration) {
+	c.deadline = deadline
+}
+
+// watching reports whether reads go through a watchedReader.
+func (c *StreamComparator) watching() bool {
+	return c.stall.Timeout > 0 || c.deadline > 0 || c.readTimeouts[0] > 0 || c.readTimeouts[1] > 0
+}
+
+// timedOut reports why reading side must stop at now, after waiting for its
+// next record for waited, or "" if it may go on. Paused time is not counted.
+func (c *StreamComparator) timedOut(side Side, waited time.Duration, now time.Time) string {
+	if c.deadline > 0 && c.activeFor(now) >= c.deadline {
+		return fmt.Sprintf("deadline of %s passed", c.deadline)
+	}
+	if timeout := c.readTimeouts[side-1]; timeout > 0 && waited >= timeout {
+		return fmt.Sprintf("no record within the read timeout of %s", timeout)
+	}
+	return ""
+}
+
+// markIncomplete records that side is no longer read.
+func (c *StreamComparator) markIncomplete(side Side, reason string) {
+	records := c.result.Summary.Source1Rows
+	if side == Source2 {
+		records = c.result.Summary.Source2Rows
+	}
+	c.incomplete[side-1] = true
+	c.result.Incomplete = append(c.result.Incomplete, Incompletion{Source: side.String(), Reason: reason, Records: records})
+	if c.hooks.OnIncomplete != nil {
+		c.hooks.OnIncomplete(side, reason)
+	}
+}
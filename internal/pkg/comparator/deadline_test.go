@@ -0,0 +1,66 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCompare_ReadTimeout(t *testing.T) {
+	useFastStallChecks(t)
+	clock := NewManualClock(time.Date(2025, 9, 10, 12, 0, 0, 0, time.UTC))
+	reader1 := datareader.NewSliceReader([]datareader.Record{{"id": "a"}, {"id": "b"}})
+	reader2 := newHangingReader(t, datareader.Record{"id": "a"})
+	blocked := reader2.blocked
+	go func() {
+		<-blocked
+		clock.Advance(2 * time.Minute)
+	}()
+
+	c := New("id")
+	c.SetClock(clock)
+	c.SetReadTimeout(Source2, time.Minute)
+	result, err := c.Compare(reader1, reader2)
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+
+	want := []Incompletion{{Source: "source2", Reason: "no record within the read timeout of 1m0s", Records: 1}}
+	if !reflect.DeepEqual(result.Incomplete, want) {
+		t.Errorf("Incomplete got = %+v, want %+v", result.Incomplete, want)
+	}
+	if result.Summary.IdenticalRows != 1 || !reflect.DeepEqual(result.KeysOnly.InSource1, []string{"b"}) {
+		t.Errorf("Partial result got = %+v, %+v, want a matched and b only in source1", result.Summary, result.KeysOnly)
+	}
+}
+
+func TestFindings_Deadline(t *testing.T) {
+	useFastStallChecks(t)
+	clock := NewManualClock(time.Date(2025, 9, 10, 12, 0, 0, 0, time.UTC))
+	reader1 := newHangingReader(t)
+	blocked := reader1.blocked
+	go func() {
+		<-blocked
+		clock.Advance(time.Hour)
+	}()
+
+	c := New("id")
+	c.SetClock(clock)
+	c.SetDeadline(10 * time.Minute)
+	var kinds []string
+	var reasons []string
+	for f := range c.Findings(reader1, datareader.NewSliceReader([]datareader.Record{{"id": "a"}})) {
+		kinds = append(kinds, f.Kind())
+		if f, ok := f.(Incomplete); ok {
+			reasons = append(reasons, f.Side.String()+": "+f.Reason)
+		}
+	}
+
+	if want := []string{"incomplete", "incomplete", "summary"}; !reflect.DeepEqual(kinds, want) {
+		t.Errorf("Finding kinds got = %v, want %v", kinds, want)
+	}
+	if want := []string{"source1: deadline of 10m0s passed", "source2: deadline of 10m0s passed"}; !reflect.DeepEqual(reasons, want) {
+		t.Errorf("Incomplete reasons got = %v, want %v", reasons, want)
+	}
+}
@@ -0,0 +1,29 @@
+package comparator
+
+// sampleDiffs returns the diffs of a differing key kept in the result: the
+// first Options.MaxDiffsPerField of each field and Options.MaxTotalDiffs in
+// all, where set. Those left out are counted in Summary.OmittedDiffs; the
+// other counts of the result stay exact.
+func (c *StreamComparator) sampleDiffs(diffs []FieldDiff) []FieldDiff {
+	perField, total := c.options.MaxDiffsPerField, c.options.MaxTotalDiffs
+	if perField <= 0 && total <= 0 {
+		return diffs
+	}
+	if perField > 0 && c.keptByField == nil {
+		c.keptByField = make(map[string]int)
+	}
+	var kept []FieldDiff
+	for _, diff := range diffs {
+		field := statField(diff.Field)
+		if total > 0 && c.keptDiffs >= total || perField > 0 && c.keptByField[field] >= perField {
+			c.result.Summary.OmittedDiffs++
+			continue
+		}
+		c.keptDiffs++
+		if perField > 0 {
+			c.keptByField[field]++
+		}
+		kept = append(kept, diff)
+	}
+	return kept
+}
@@ -0,0 +1,69 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/config"
+	"data-comparator/internal/pkg/datareader"
+	"fmt"
+	"testing"
+)
+
+func TestCompare_DiffCaps(t *testing.T) {
+	var records1, records2 []datareader.Record
+	for i := 0; i < 10; i++ {
+		records1 = append(records1, datareader.Record{"id": fmt.Sprint(i), "a": 1, "b": 1})
+		records2 = append(records2, datareader.Record{"id": fmt.Sprint(i), "a": 2, "b": 2})
+	}
+	tests := []struct {
+		name            string
+		perField, total int
+		keys, kept      int
+		omitted         int
+	}{
+		{"uncapped", 0, 0, 10, 20, 0},
+		{"per field", 3, 0, 3, 6, 14},
+		{"total", 0, 5, 3, 5, 15},
+		{"both", 4, 5, 3, 5, 15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := OptionsFromConfig(&config.Comparison{MaxDiffsPerField: tt.perField, MaxTotalDiffs: tt.total})
+			c := New("id")
+			c.SetOptions(options)
+			result, err := c.Compare(datareader.NewSliceReader(records1), datareader.NewSliceReader(records2))
+			if err != nil {
+				t.Fatalf("Compare() error = %v", err)
+			}
+			kept := 0
+			for _, diffs := range result.ValueDiffs {
+				kept += len(diffs)
+			}
+			if len(result.ValueDiffs) != tt.keys || kept != tt.kept || result.Summary.OmittedDiffs != tt.omitted {
+				t.Errorf("got %d keys, %d diffs kept and %d omitted, want %d, %d and %d",
+					len(result.ValueDiffs), kept, result.Summary.OmittedDiffs, tt.keys, tt.kept, tt.omitted)
+			}
+			// Counts stay exact.
+			if s := result.Summary; s.MatchingKeys-s.IdenticalRows != 10 {
+				t.Errorf("differing keys got = %d, want 10", s.MatchingKeys-s.IdenticalRows)
+			}
+			if len(result.FieldStats) != 2 || result.FieldStats[0].Records != 10 {
+				t.Errorf("FieldStats got = %+v, want a and b differing in 10 records", result.FieldStats)
+			}
+		})
+	}
+}
+
+func TestCompare_DiscardDiffs(t *testing.T) {
+	records1 := []datareader.Record{{"id": "1", "a": 1}, {"id": "2", "a": 1}}
+	records2 := []datareader.Record{{"id": "1", "a": 2}, {"id": "2", "a": 1}}
+	c := New("id")
+	handled := 0
+	c.SetHooks(Hooks{OnDiff: func(key string, diffs []FieldDiff, rec1, rec2 datareader.Record) { handled += len(diffs) }})
+	c.SetDiscardDiffs(true)
+	result, err := c.Compare(datareader.NewSliceReader(records1), datareader.NewSliceReader(records2))
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	if len(result.ValueDiffs) != 0 || handled != 1 || len(result.FieldStats) != 1 || result.Summary.IdenticalRows != 1 {
+		t.Errorf("got %d value diffs, %d diffs handled and field stats %v, want 0, 1 and a on its own", len(result.ValueDiffs), handled, result.FieldStats)
+	}
+}
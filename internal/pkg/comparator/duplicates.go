@@ -0,0 +1,173 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"fmt"
+	"strings"
+)
+
+// DuplicatePolicy is what happens to a record whose key was already read on
+// its side, whether still pending or matched by a record of the other side.
+type DuplicatePolicy string
+
+const (
+	// DuplicatesKeepLast replaces the pending record by the later one.
+	DuplicatesKeepLast DuplicatePolicy = "keep_last"
+	// DuplicatesKeepFirst keeps the pending record and drops the later one.
+	DuplicatesKeepFirst DuplicatePolicy = "keep_first"
+	// DuplicatesError fails the comparison with a DuplicateKeyError.
+	DuplicatesError DuplicatePolicy = "error"
+	// DuplicatesMultiset keeps every record of the key. Each record of the
+	// other side is matched with an identical one if there is any, else with
+	// the earliest, and those left over are reported as only in their source.
+	DuplicatesMultiset DuplicatePolicy = "multiset"
+)
+
+// DuplicatePolicies lists the duplicate policies, the default first.
+var DuplicatePolicies = []DuplicatePolicy{DuplicatesKeepLast, DuplicatesKeepFirst, DuplicatesError, DuplicatesMultiset}
+
+// ParseDuplicatePolicy returns the duplicate policy named s, the default for
+// an empty s.
+func ParseDuplicatePolicy(s string) (DuplicatePolicy, error) {
+	if s == "" {
+		return DuplicatesKeepLast, nil
+	}
+	names := make([]string, len(DuplicatePolicies))
+	for i, policy := range DuplicatePolicies {
+		if string(policy) == s {
+			return policy, nil
+		}
+		names[i] = string(policy)
+	}
+	return "", fmt.Errorf("unsupported duplicate_keys policy %s, use one of %s", s, strings.Join(names, ", "))
+}
+
+// DuplicateKeyError reports a duplicate key under DuplicatesError.
+type DuplicateKeyError struct {
+	Side Side
+	Key  string
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("duplicate key %s in %s", e.Key, e.Side)
+}
+
+// MaxDuplicates is the number of duplicated keys listed per source. All
+// duplicates are counted in the Summary.
+const MaxDuplicates = 100
+
+// Duplicates lists the keys read more than once per source.
+type Duplicates struct {
+	Source1 []DuplicatedKey `yaml:"source1,omitempty"`
+	Source2 []DuplicatedKey `yaml:"source2,omitempty"`
+}
+
+// DuplicatedKey is a key and the number of records read with it, the first
+// included.
+type DuplicatedKey struct {
+	Key     string `yaml:"key"`
+	Records int    `yaml:"records"`
+}
+
+// SetDuplicatePolicy sets how records with a key already read on their side
+// are handled. Without a policy, only keys read again while pending are
+// found, and handled as under DuplicatesKeepLast. With one, the keys read
+// are kept per side, so records of keys already matched are found too:
+// DuplicatesKeepFirst and DuplicatesKeepLast drop them, as their key's
+// comparison stands, and under DuplicatesMultiset they wait for another
+// match. Under DuplicatesMultiset, unmatched records are not spilled.
+func (c *StreamComparator) SetDuplicatePolicy(policy DuplicatePolicy) {
+	c.duplicatePolicy = policy
+}
+
+// duplicate handles rec, read from side with a key already pending in own,
+// by the duplicate policy.
+func (c *StreamComparator) duplicate(side Side, key string, rec datareader.Record, own map[string]datareader.Record) error {
+	if err := c.countDuplicate(side, key, rec); err != nil {
+		return err
+	}
+	switch c.duplicatePolicy {
+	case DuplicatesKeepFirst:
+	case DuplicatesMultiset:
+		c.extra[side-1][key] = append(c.extra[side-1][key], rec)
+	default:
+		own[key] = rec
+	}
+	return nil
+}
+
+// countDuplicate counts rec, read from side with a key read before on that
+// side, as a duplicate, or fails under DuplicatesError.
+func (c *StreamComparator) countDuplicate(side Side, key string, rec datareader.Record) error {
+	if c.duplicatePolicy == DuplicatesError {
+		return &DuplicateKeyError{Side: side, Key: key}
+	}
+	c.result.Summary.DuplicateKeys++
+	c.quality[side-1].duplicates++
+	c.listDuplicate(side, key)
+	if c.hooks.OnDuplicateKey != nil {
+		c.hooks.OnDuplicateKey(side, key, rec)
+	}
+	return nil
+}
+
+// listDuplicate counts another record of key in the duplicates of side, up
+// to MaxDuplicates keys.
+func (c *StreamComparator) listDuplicate(side Side, key string) {
+	if c.result.Duplicates == nil {
+		c.result.Duplicates = &Duplicates{}
+	}
+	listed := &c.result.Duplicates.Source1
+	if side == Source2 {
+		listed = &c.result.Duplicates.Source2
+	}
+	if i, ok := c.duplicateIndex[side-1][key]; ok {
+		(*listed)[i].Records++
+		return
+	}
+	if len(*listed) >= MaxDuplicates {
+		return
+	}
+	if c.duplicateIndex[side-1] == nil {
+		c.duplicateIndex[side-1] = make(map[string]int)
+	}
+	c.duplicateIndex[side-1][key] = len(*listed)
+	*listed = append(*listed, DuplicatedKey{Key: key, Records: 2})
+}
+
+// counterpart removes the record of key pending in other, read from the
+// other side than rec, and returns it to match rec with. Under
+// DuplicatesMultiset it picks the record identical to rec, if any, and the
+// next of those left stays pending.
+func (c *StreamComparator) counterpart(key string, rec datareader.Record, side Side, other map[string]datareader.Record) datareader.Record {
+	counterpart := other[key]
+	otherIndex := int(Source2 - side)
+	extra := c.extra[otherIndex][key]
+	if len(extra) == 0 {
+		delete(other, key)
+		c.outstanding.match(key)
+		return counterpart
+	}
+
+	candidates := append([]datareader.Record{counterpart}, extra...)
+	pick := 0
+	for i, candidate := range candidates {
+		rec1, rec2 := rec, candidate
+		if side == Source2 {
+			rec1, rec2 = candidate, rec
+		}
+		cmp1, cmp2 := c.comparable(rec1, rec2)
+		if len(compareRecords(cmp1, cmp2, c.options)) == 0 {
+			pick = i
+			break
+		}
+	}
+	left := append(candidates[:pick:pick], candidates[pick+1:]...)
+	other[key] = left[0]
+	if len(left) > 1 {
+		c.extra[otherIndex][key] = left[1:]
+	} else {
+		delete(c.extra[otherIndex], key)
+	}
+	return candidates[pick]
+}
@@ -0,0 +1,140 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// addDuplicates adds records with duplicated keys: source1 has a twice and
+// b, source2 has a twice, in the other order.
+func addDuplicates(t *testing.T, c *StreamComparator) (*Result, error) {
+	t.Helper()
+	for _, add := range []struct {
+		side Side
+		rec  datareader.Record
+	}{
+		{Source1, datareader.Record{"id": "a", "v": "1"}},
+		{Source1, datareader.Record{"id": "a", "v": "2"}},
+		{Source1, datareader.Record{"id": "b", "v": "1"}},
+		{Source2, datareader.Record{"id": "a", "v": "2"}},
+		{Source2, datareader.Record{"id": "a", "v": "1"}},
+	} {
+		if err := c.Add(add.side, add.rec); err != nil {
+			return nil, err
+		}
+	}
+	return c.Finish(), nil
+}
+
+func TestDuplicatePolicies(t *testing.T) {
+	tests := []struct {
+		policy    DuplicatePolicy
+		identical int
+		only2     []string
+	}{
+		// The later a of source1 matches the first a of source2, and the
+		// second a of source2, read after the match, is dropped.
+		{DuplicatesKeepLast, 1, []string{}},
+		{DuplicatesKeepFirst, 0, []string{}},
+		{DuplicatesMultiset, 2, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			c := New("id")
+			c.SetDuplicatePolicy(tt.policy)
+			result, err := addDuplicates(t, c)
+			if err != nil {
+				t.Fatalf("Add() error = %v", err)
+			}
+			if result.Summary.IdenticalRows != tt.identical || result.Summary.DuplicateKeys != 2 {
+				t.Errorf("Summary got = %+v, want %d identical rows and 2 duplicate keys", result.Summary, tt.identical)
+			}
+			if !reflect.DeepEqual(result.KeysOnly.InSource1, []string{"b"}) || !reflect.DeepEqual(result.KeysOnly.InSource2, tt.only2) {
+				t.Errorf("KeysOnly got = %+v, want b in source1 and %v in source2", result.KeysOnly, tt.only2)
+			}
+			want := &Duplicates{Source1: []DuplicatedKey{{Key: "a", Records: 2}}, Source2: []DuplicatedKey{{Key: "a", Records: 2}}}
+			if !reflect.DeepEqual(result.Duplicates, want) {
+				t.Errorf("Duplicates got = %+v, want %+v", result.Duplicates, want)
+			}
+		})
+	}
+}
+
+func TestDuplicatePolicy_Error(t *testing.T) {
+	c := New("id")
+	c.SetDuplicatePolicy(DuplicatesError)
+	_, err := addDuplicates(t, c)
+	var dupErr *DuplicateKeyError
+	if !errors.As(err, &dupErr) || dupErr.Key != "a" || dupErr.Side != Source1 {
+		t.Errorf("Add() error got = %v, want a DuplicateKeyError for a in source1", err)
+	}
+}
+
+func TestParseDuplicatePolicy(t *testing.T) {
+	if got, err := ParseDuplicatePolicy(""); err != nil || got != DuplicatesKeepLast {
+		t.Errorf("ParseDuplicatePolicy(\"\") got = %v, %v, want keep_last", got, err)
+	}
+	if got, err := ParseDuplicatePolicy("multiset"); err != nil || got != DuplicatesMultiset {
+		t.Errorf("ParseDuplicatePolicy(multiset) got = %v, %v, want multiset", got, err)
+	}
+	if _, err := ParseDuplicatePolicy("keep_all"); err == nil {
+		t.Error("ParseDuplicatePolicy(keep_all) error = nil, want an error")
+	}
+}
+
+func TestDuplicatePolicies_AfterMatch(t *testing.T) {
+	// The second a of source1 is read after the first matched.
+	records := []struct {
+		side Side
+		rec  datareader.Record
+	}{
+		{Source1, datareader.Record{"id": "a", "v": "1"}},
+		{Source2, datareader.Record{"id": "a", "v": "1"}},
+		{Source1, datareader.Record{"id": "a", "v": "2"}},
+	}
+	tests := []struct {
+		policy DuplicatePolicy
+		only1  []string
+	}{
+		{DuplicatesKeepLast, []string{}},
+		{DuplicatesKeepFirst, []string{}},
+		{DuplicatesMultiset, []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			c := New("id")
+			c.SetDuplicatePolicy(tt.policy)
+			for _, add := range records {
+				if err := c.Add(add.side, add.rec); err != nil {
+					t.Fatalf("Add() error = %v", err)
+				}
+			}
+			result := c.Finish()
+			if result.Summary.DuplicateKeys != 1 || result.Summary.IdenticalRows != 1 {
+				t.Errorf("Summary got = %+v, want 1 identical row and 1 duplicate key", result.Summary)
+			}
+			if !reflect.DeepEqual(result.KeysOnly.InSource1, tt.only1) {
+				t.Errorf("KeysOnly.InSource1 got = %v, want %v", result.KeysOnly.InSource1, tt.only1)
+			}
+			want := &Duplicates{Source1: []DuplicatedKey{{Key: "a", Records: 2}}}
+			if !reflect.DeepEqual(result.Duplicates, want) {
+				t.Errorf("Duplicates got = %+v, want %+v", result.Duplicates, want)
+			}
+		})
+	}
+
+	c := New("id")
+	c.SetDuplicatePolicy(DuplicatesError)
+	var err error
+	for _, add := range records {
+		if err = c.Add(add.side, add.rec); err != nil {
+			break
+		}
+	}
+	var dupErr *DuplicateKeyError
+	if !errors.As(err, &dupErr) || dupErr.Key != "a" || dupErr.Side != Source1 {
+		t.Errorf("Add() error got = %v, want a DuplicateKeyError for a in source1", err)
+	}
+}
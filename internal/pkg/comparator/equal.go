@@ -0,0 +1,283 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/config"
+	"data-comparator/internal/pkg/datareader"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Options control when two field values are considered equal.
+type Options struct {
+	// NaNEqual treats NaN as equal to NaN instead of following IEEE 754.
+	NaNEqual bool
+	// SignedZeroEqual treats -0 as equal to 0.
+	SignedZeroEqual bool
+	// ExactStringFields are compared by their string representation only,
+	// so "00123" and 123 differ.
+	ExactStringFields []string
+	// Tolerance lets numeric values of fields without one in FieldTolerances
+	// differ slightly and still be equal.
+	Tolerance Tolerance
+	// FieldTolerances are the tolerances of single fields, by their dotted
+	// names.
+	FieldTolerances map[string]Tolerance
+	// FieldRules ignore or normalize the values of single fields.
+	FieldRules *FieldRules
+	// VisualizeWhitespace renders whitespace-only diffs with visible escapes.
+	VisualizeWhitespace bool
+	// InlineDiffMinLength is the string length from which differing values get
+	// a token-level edit script. Zero disables inline diffs.
+	InlineDiffMinLength int
+	// BinaryFields hold base64-encoded data compared by size and SHA-256.
+	// Values of type []byte are always compared this way.
+	BinaryFields []string
+	// Canonical selects normalizations applied before comparing or hashing.
+	Canonical Canonical
+	// MaxRecords is the number of diffing keys whose complete records are
+	// embedded in the Result. Zero embeds none.
+	MaxRecords int
+	// RedactFields are masked in embedded records.
+	RedactFields []string
+	// MaxDiffsPerField and MaxTotalDiffs bound the value diffs kept in the
+	// Result, per field and in all. Zero keeps every diff.
+	MaxDiffsPerField int
+	MaxTotalDiffs    int
+	// Limits bound the fields, nesting and value sizes of each record.
+	Limits Limits
+	// FieldMappings maps source1 field names to the source2 fields they are
+	// compared with.
+	FieldMappings map[string]string
+
+	// Normalize, if set, rewrites each record before it is compared or hashed,
+	// after the key fields are removed.
+	Normalize func(rec datareader.Record) datareader.Record
+	// Equal, if set, decides equality of two field values. It returns ok
+	// false to fall back to the built-in rules for that pair.
+	Equal func(field string, v1, v2 interface{}) (equal, ok bool)
+}
+
+// DefaultOptions returns the options used when none are configured.
+func DefaultOptions() Options {
+	return Options{NaNEqual: true, SignedZeroEqual: true}
+}
+
+// OptionsFromConfig builds Options from a comparison config section,
+// falling back to the defaults for unset values. Field rules, which may fail
+// to compile, are set from CompileFieldRules.
+func OptionsFromConfig(cfg *config.Comparison) Options {
+	options := DefaultOptions()
+	if cfg == nil {
+		return options
+	}
+	if cfg.NaNEqual != nil {
+		options.NaNEqual = *cfg.NaNEqual
+	}
+	if cfg.SignedZeroEqual != nil {
+		options.SignedZeroEqual = *cfg.SignedZeroEqual
+	}
+	options.ExactStringFields = cfg.ExactStringFields
+	if t := cfg.Tolerance; t != nil {
+		options.Tolerance = Tolerance{Absolute: t.Absolute, Relative: t.Relative}
+		if len(t.Fields) > 0 {
+			options.FieldTolerances = make(map[string]Tolerance, len(t.Fields))
+			for field, ft := range t.Fields {
+				options.FieldTolerances[field] = Tolerance{Absolute: ft.Absolute, Relative: ft.Relative}
+			}
+		}
+	}
+	options.VisualizeWhitespace = cfg.VisualizeWhitespace
+	options.InlineDiffMinLength = cfg.InlineDiffMinLength
+	options.BinaryFields = cfg.BinaryFields
+	if c := cfg.Canonicalize; c != nil {
+		options.Canonical = Canonical{
+			TrimWhitespace:  c.TrimWhitespace,
+			CaseInsensitive: c.CaseInsensitive,
+			DateTimes:       c.DateTimes,
+			Numbers:         c.Numbers,
+
+			DateTimeLayouts:   c.DateTimeLayouts,
+			DateTimePrecision: c.DateTimePrecision,
+		}
+	}
+	if r := cfg.IncludeRecords; r != nil {
+		options.MaxRecords = r.Max
+		if options.MaxRecords <= 0 {
+			options.MaxRecords = DefaultMaxRecords
+		}
+		options.RedactFields = r.Redact
+	}
+	if l := cfg.Limits; l != nil {
+		options.Limits = Limits{MaxFields: l.MaxFields, MaxDepth: l.MaxDepth, MaxValueSize: l.MaxValueSize}
+	}
+	options.FieldMappings = cfg.FieldMappings
+	options.MaxDiffsPerField = cfg.MaxDiffsPerField
+	options.MaxTotalDiffs = cfg.MaxTotalDiffs
+	return options
+}
+
+// Tolerance bounds how far apart two numbers may be and still be equal. They
+// are equal when within either bound; the zero Tolerance requires exact
+// equality.
+type Tolerance struct {
+	// Absolute is the largest difference.
+	Absolute float64
+	// Relative is the largest difference as a share of the larger absolute
+	// value of the two.
+	Relative float64
+}
+
+// within reports whether f1 and f2, both finite, are within t of each other.
+func (t Tolerance) within(f1, f2 float64) bool {
+	diff := math.Abs(f1 - f2)
+	return diff <= t.Absolute || diff <= t.Relative*math.Max(math.Abs(f1), math.Abs(f2))
+}
+
+// toleranceOf returns the tolerance of field.
+func (o Options) toleranceOf(field string) Tolerance {
+	if t, ok := o.FieldTolerances[field]; ok {
+		return t
+	}
+	return o.Tolerance
+}
+
+func (o Options) isExactString(field string) bool {
+	return contains(o.ExactStringFields, field)
+}
+
+func (o Options) isBinary(field string) bool {
+	return contains(o.BinaryFields, field)
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// valuesEqual compares two values of the named field. Unless the field is listed
+// in ExactStringFields, when both values are numeric (native
+// numbers or numeric strings) they are compared as float64, with NaN and signed
+// zero handled according to options and finite values equal within the field's
+// tolerance; infinities equal only infinities of the same sign. Everything else is
+// compared by its string representation.
+func valuesEqual(field string, v1, v2 interface{}, options Options) bool {
+	if options.Equal != nil {
+		if equal, ok := options.Equal(field, v1, v2); ok {
+			return equal
+		}
+	}
+	if v1 == nil || v2 == nil {
+		return v1 == nil && v2 == nil
+	}
+	if options.isExactString(field) {
+		return fmt.Sprintf("%v", v1) == fmt.Sprintf("%v", v2)
+	}
+
+	f1, ok1 := toFloat(v1)
+	f2, ok2 := toFloat(v2)
+	if ok1 && ok2 {
+		// Integers beyond 2^53 that differ may round to the same float64.
+		if i1, ok := toInt(v1); ok && options.toleranceOf(field) == (Tolerance{}) {
+			if i2, ok := toInt(v2); ok && i1 != i2 {
+				return false
+			}
+		}
+		return floatsEqual(f1, f2, options.toleranceOf(field), options)
+	}
+
+	return fmt.Sprintf("%v", v1) == fmt.Sprintf("%v", v2)
+}
+
+func floatsEqual(f1, f2 float64, tolerance Tolerance, options Options) bool {
+	if math.IsNaN(f1) || math.IsNaN(f2) {
+		return options.NaNEqual && math.IsNaN(f1) && math.IsNaN(f2)
+	}
+	if f1 == 0 && f2 == 0 && !options.SignedZeroEqual {
+		return math.Signbit(f1) == math.Signbit(f2)
+	}
+	if f1 == f2 {
+		return true
+	}
+	if math.IsInf(f1, 0) || math.IsInf(f2, 0) {
+		return false
+	}
+	return tolerance.within(f1, f2)
+}
+
+// toFloat converts numeric values and numeric-looking strings to float64.
+// Strings without any digit are only accepted when they spell NaN or an infinity
+// exactly, so words like "nan" or "info" in text fields stay strings.
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case string:
+		s := strings.TrimSpace(n)
+		if !strings.ContainsAny(s, "0123456789") {
+			switch s {
+			case "NaN", "Inf", "+Inf", "-Inf", "Infinity", "+Infinity", "-Infinity":
+			default:
+				return 0, false
+			}
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, false
+		}
+		return f, true
+	default:
+		return 0, false
+	}
+}
+
+// maxExactInt is the magnitude up to which every integer is exactly a
+// float64.
+const maxExactInt = 1 << 53
+
+// toInt converts integer values and integer strings, such as the json.Number
+// of an exactly decoded ID, to int64.
+func toInt(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case json.Number:
+		i, err := strconv.ParseInt(string(n), 10, 64)
+		return i, err == nil
+	case string:
+		i, err := strconv.ParseInt(strings.TrimSpace(n), 10, 64)
+		return i, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// toNumber converts v like toFloat, but to an int64 if it is an integer a
+// float64 cannot hold exactly.
+func toNumber(v interface{}) (interface{}, bool) {
+	if i, ok := toInt(v); ok && (i > maxExactInt || i < -maxExactInt) {
+		return i, true
+	}
+	return toFloat(v)
+}
@@ -0,0 +1,206 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/config"
+	"data-comparator/internal/pkg/datareader"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestValuesEqual_FloatSemantics(t *testing.T) {
+	nan := math.NaN()
+	negZero := math.Copysign(0, -1)
+	strict := Options{}
+
+	tests := []struct {
+		name    string
+		v1, v2  interface{}
+		options Options
+		want    bool
+	}{
+		{"NaN equal by default", nan, nan, DefaultOptions(), true},
+		{"NaN not equal when strict", nan, nan, strict, false},
+		{"NaN string equal by default", "NaN", nan, DefaultOptions(), true},
+		{"NaN never equals a number", nan, 1.0, DefaultOptions(), false},
+		{"signed zero equal by default", negZero, 0.0, DefaultOptions(), true},
+		{"signed zero differs when strict", negZero, 0.0, strict, false},
+		{"signed zero string differs when strict", "-0", "0", strict, false},
+		{"same-sign infinities equal", math.Inf(1), "+Inf", strict, true},
+		{"opposite infinities differ", math.Inf(1), math.Inf(-1), DefaultOptions(), false},
+		{"numeric strings compared as numbers", "1.0", float64(1), DefaultOptions(), true},
+		{"word nan stays a string", "nan", "NaN", DefaultOptions(), false},
+		{"nil only equals nil", nil, "", DefaultOptions(), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := valuesEqual("value", tt.v1, tt.v2, tt.options); got != tt.want {
+				t.Errorf("valuesEqual(%v, %v) got = %v, want %v", tt.v1, tt.v2, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptionsFromConfig(t *testing.T) {
+	if got := OptionsFromConfig(nil); !reflect.DeepEqual(got, DefaultOptions()) {
+		t.Errorf("OptionsFromConfig(nil) got = %+v, want %+v", got, DefaultOptions())
+	}
+
+	no := false
+	got := OptionsFromConfig(&config.Comparison{NaNEqual: &no})
+	if got.NaNEqual || !got.SignedZeroEqual {
+		t.Errorf("OptionsFromConfig() got = %+v, want NaNEqual=false SignedZeroEqual=true", got)
+	}
+}
+
+func TestValuesEqual_ExactStringFields(t *testing.T) {
+	options := DefaultOptions()
+	options.ExactStringFields = []string{"account_id"}
+
+	if valuesEqual("account_id", "00123", float64(123), options) {
+		t.Error("Expected exact string field to treat 00123 and 123 as different")
+	}
+	if !valuesEqual("account_id", "00123", "00123", options) {
+		t.Error("Expected exact string field to treat identical strings as equal")
+	}
+	if !valuesEqual("amount", "00123", float64(123), options) {
+		t.Error("Expected other fields to keep numeric comparison")
+	}
+}
+
+func TestValuesEqual_Tolerance(t *testing.T) {
+	var cfg config.Comparison
+	err := yaml.Unmarshal([]byte(`
+tolerance:
+  absolute: 0.0001
+  fields:
+    price: {relative: 0.01}
+`), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := OptionsFromConfig(&cfg)
+
+	tests := []struct {
+		field  string
+		v1, v2 interface{}
+		want   bool
+	}{
+		{"ratio", 3.14159, "3.1416", true},
+		{"ratio", 3.14159, 3.1417, false},
+		{"ratio", 0.1 + 0.2, 0.3, true},
+		{"price", 1000.0, 1009.0, true},
+		{"price", 1000.0, 1011.0, false},
+		// A field's own tolerance replaces the global one.
+		{"price", 0.0, 0.00005, false},
+		{"ratio", math.Inf(1), math.MaxFloat64, false},
+		{"ratio", "abc", "abd", false},
+	}
+	for _, tt := range tests {
+		if got := valuesEqual(tt.field, tt.v1, tt.v2, options); got != tt.want {
+			t.Errorf("valuesEqual(%s, %v, %v) got = %v, want %v", tt.field, tt.v1, tt.v2, got, tt.want)
+		}
+	}
+}
+
+func TestValuesEqual_LargeIntegers(t *testing.T) {
+	tests := []struct {
+		v1, v2 interface{}
+		want   bool
+	}{
+		{json.Number("9007199254740993"), "9007199254740993", true},
+		{json.Number("9007199254740993"), "9007199254740992", false},
+		{int64(9007199254740993), json.Number("9007199254740992"), false},
+		{json.Number("1.10"), "1.1", true},
+		{json.Number("42"), float64(42), true},
+	}
+	for _, tt := range tests {
+		if got := valuesEqual("id", tt.v1, tt.v2, DefaultOptions()); got != tt.want {
+			t.Errorf("valuesEqual(%v, %v) got = %v, want %v", tt.v1, tt.v2, got, tt.want)
+		}
+	}
+
+	canonical := Options{Canonical: Canonical{Numbers: true}}
+	if got := canonical.canonicalValue("id", "9007199254740993"); got != int64(9007199254740993) {
+		t.Errorf("canonicalValue() got = %v (%T), want the exact int64", got, got)
+	}
+}
+
+func TestCompare_CustomNormalizeAndEqual(t *testing.T) {
+	options := DefaultOptions()
+	options.Normalize = func(rec datareader.Record) datareader.Record {
+		out := make(datareader.Record, len(rec))
+		for k, v := range rec {
+			if k != "updated_at" {
+				out[k] = v
+			}
+		}
+		return out
+	}
+	options.Equal = func(field string, v1, v2 interface{}) (bool, bool) {
+		if field != "name" {
+			return false, false
+		}
+		s1, _ := v1.(string)
+		s2, _ := v2.(string)
+		return strings.EqualFold(s1, s2), true
+	}
+
+	c := New("id")
+	c.SetOptions(options)
+	for _, add := range []struct {
+		side Side
+		rec  datareader.Record
+	}{
+		{Source1, datareader.Record{"id": "1", "name": "Alice", "age": "30", "updated_at": "monday"}},
+		{Source2, datareader.Record{"id": "1", "name": "ALICE", "age": "30", "updated_at": "tuesday"}},
+		{Source1, datareader.Record{"id": "2", "name": "Bob", "age": "40"}},
+		{Source2, datareader.Record{"id": "2", "name": "Bob", "age": "41"}},
+	} {
+		if err := c.Add(add.side, add.rec); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	result := c.Finish()
+	if result.Summary.IdenticalRows != 1 {
+		t.Errorf("IdenticalRows got = %d, want 1", result.Summary.IdenticalRows)
+	}
+	if diffs := result.ValueDiffs["2"]; len(diffs) != 1 || diffs[0].Field != "age" {
+		t.Errorf("ValueDiffs[2] got = %v, want a single diff on age", diffs)
+	}
+}
+
+func TestCompare_FieldMappings(t *testing.T) {
+	options := OptionsFromConfig(&config.Comparison{FieldMappings: map[string]string{"name": "full_name", "age": "years"}})
+
+	c := New("id")
+	c.SetOptions(options)
+	c.SetKeys("id", "customer_id")
+	for _, add := range []struct {
+		side Side
+		rec  datareader.Record
+	}{
+		{Source1, datareader.Record{"id": "1", "name": "Alice", "age": "30"}},
+		{Source2, datareader.Record{"customer_id": "1", "full_name": "Alice", "years": "30"}},
+		{Source1, datareader.Record{"id": "2", "name": "Bob", "age": "40"}},
+		{Source2, datareader.Record{"customer_id": "2", "full_name": "Bob", "years": "41"}},
+	} {
+		if err := c.Add(add.side, add.rec); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	result := c.Finish()
+	if result.Summary.IdenticalRows != 1 {
+		t.Errorf("IdenticalRows got = %d, want 1", result.Summary.IdenticalRows)
+	}
+	if diffs := result.ValueDiffs["2"]; len(diffs) != 1 || diffs[0].Field != "age" {
+		t.Errorf("ValueDiffs[2] got = %v, want a single diff on age", diffs)
+	}
+}
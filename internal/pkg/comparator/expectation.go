@@ -0,0 +1,63 @@
+package comparator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Expectation is what a comparison asserts about how the sources relate.
+// Value diffs of matching keys always fail it; keys only in one source may
+// be allowed.
+type Expectation string
+
+const (
+	// ExpectEqual expects both sources to hold the same keys.
+	ExpectEqual Expectation = "equal"
+	// ExpectSubset expects every key of source1 in source2; source2 may
+	// hold more, e.g. a new system ingesting all an old one produced.
+	ExpectSubset Expectation = "subset"
+	// ExpectSuperset expects every key of source2 in source1; source1 may
+	// hold more.
+	ExpectSuperset Expectation = "superset"
+)
+
+// Expectations lists the expectations, the default first.
+var Expectations = []Expectation{ExpectEqual, ExpectSubset, ExpectSuperset}
+
+// ParseExpectation returns the expectation named s, the default for an
+// empty s.
+func ParseExpectation(s string) (Expectation, error) {
+	if s == "" {
+		return ExpectEqual, nil
+	}
+	names := make([]string, len(Expectations))
+	for i, expectation := range Expectations {
+		if string(expectation) == s {
+			return expectation, nil
+		}
+		names[i] = string(expectation)
+	}
+	return "", fmt.Errorf("unsupported expect %s, use one of %s", s, strings.Join(names, ", "))
+}
+
+// SetExpectation sets what the comparison asserts. It defaults to
+// ExpectEqual. Keys only in a source allowed to hold more are still listed,
+// but do not count against the parity or Holds.
+func (c *StreamComparator) SetExpectation(expectation Expectation) {
+	c.expectation = expectation
+}
+
+// AllowsOnlyIn reports whether keys only in side meet the expectation of
+// the result, being allowed by it or of severity info.
+func (r *Result) AllowsOnlyIn(side Side) bool {
+	return r.MissingKeySeverityOf(side) == SeverityInfo
+}
+
+// Holds reports whether the result meets its expectation: matching keys
+// have identical records and no source holds keys it must not.
+func (r *Result) Holds() bool {
+	s := r.Summary
+	return s.MatchingKeys == s.IdenticalRows &&
+		(s.KeysOnlyInSource1 == 0 || r.AllowsOnlyIn(Source1)) &&
+		(s.KeysOnlyInSource2 == 0 || r.AllowsOnlyIn(Source2))
+}
@@ -0,0 +1,62 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"testing"
+)
+
+func TestExpectations(t *testing.T) {
+	records1 := []datareader.Record{{"id": "1", "v": "a"}, {"id": "2", "v": "b"}}
+	records2 := []datareader.Record{{"id": "1", "v": "a"}, {"id": "2", "v": "b"}, {"id": "3", "v": "c"}}
+	tests := []struct {
+		expectation Expectation
+		holds       bool
+		parity      float64
+	}{
+		{ExpectEqual, false, 66.67},
+		{ExpectSubset, true, 100},
+		{ExpectSuperset, false, 66.67},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.expectation), func(t *testing.T) {
+			c := New("id")
+			c.SetExpectation(tt.expectation)
+			result, err := c.Compare(datareader.NewSliceReader(records1), datareader.NewSliceReader(records2))
+			if err != nil {
+				t.Fatalf("Compare() error = %v", err)
+			}
+			if result.Holds() != tt.holds {
+				t.Errorf("Holds() got = %v, want %v", result.Holds(), tt.holds)
+			}
+			if result.Scorecard.Parity != tt.parity {
+				t.Errorf("Parity got = %v, want %v", result.Scorecard.Parity, tt.parity)
+			}
+			if result.Summary.KeysOnlyInSource2 != 1 {
+				t.Errorf("KeysOnlyInSource2 got = %d, want 1", result.Summary.KeysOnlyInSource2)
+			}
+		})
+	}
+
+	// Value diffs fail any expectation.
+	c := New("id")
+	c.SetExpectation(ExpectSuperset)
+	result, err := c.Compare(datareader.NewSliceReader(records2), datareader.NewSliceReader([]datareader.Record{{"id": "1", "v": "x"}}))
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	if result.Holds() || result.Expectation != ExpectSuperset {
+		t.Errorf("Holds() got = %v with expectation %q, want false with superset", result.Holds(), result.Expectation)
+	}
+}
+
+func TestParseExpectation(t *testing.T) {
+	if got, err := ParseExpectation(""); err != nil || got != ExpectEqual {
+		t.Errorf("ParseExpectation(\"\") got = %v, %v, want equal", got, err)
+	}
+	if got, err := ParseExpectation("subset"); err != nil || got != ExpectSubset {
+		t.Errorf("ParseExpectation(subset) got = %v, %v, want subset", got, err)
+	}
+	if _, err := ParseExpectation("contains"); err == nil {
+		t.Error("ParseExpectation(contains) expected an error, got nil")
+	}
+}
@@ -0,0 +1,165 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/config"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FieldRules are the comparison rules of fields, selected by patterns of
+// dotted field names in which * matches within one name segment and **
+// across any number of them. Of several patterns setting how an array is
+// compared, the last by name wins.
+type FieldRules struct {
+	patterns []fieldPattern
+	// byField caches the rule of every field name seen.
+	byField sync.Map
+}
+
+type fieldPattern struct {
+	match *regexp.Regexp
+	rule  fieldRule
+}
+
+// fieldRule is the rule of a field, merged from every pattern matching it.
+type fieldRule struct {
+	ignore          bool
+	caseInsensitive bool
+	trimWhitespace  bool
+	normalize       []regexNormalization
+	// array and arrayKey are how array values are compared.
+	array    ArrayMode
+	arrayKey string
+	// script has the comparison script decide equality.
+	script bool
+}
+
+type regexNormalization struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// CompileFieldRules compiles the field_rules of a comparison config section.
+// It returns nil if there are none.
+func CompileFieldRules(cfg *config.Comparison) (*FieldRules, error) {
+	if cfg == nil || len(cfg.FieldRules) == 0 {
+		return nil, nil
+	}
+	// Normalizations of several matching patterns apply in pattern order.
+	names := make([]string, 0, len(cfg.FieldRules))
+	for name := range cfg.FieldRules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rules := &FieldRules{}
+	for _, name := range names {
+		r := cfg.FieldRules[name]
+		rule := fieldRule{ignore: r.Ignore, caseInsensitive: r.CaseInsensitive, trimWhitespace: r.TrimWhitespace, script: r.Script}
+		if r.Script && cfg.Script == nil {
+			return nil, fmt.Errorf("field rule %s: script needs a comparison.script", name)
+		}
+		var err error
+		if rule.array, err = parseArrayMode(r.Array); err != nil {
+			return nil, fmt.Errorf("field rule %s: %w", name, err)
+		}
+		if rule.array == ArrayKeyed && r.ArrayKey == "" {
+			return nil, fmt.Errorf("field rule %s: keyed arrays need an array_key", name)
+		}
+		rule.arrayKey = r.ArrayKey
+		if n := r.NormalizeRegex; n != nil {
+			pattern, err := regexp.Compile(n.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid normalize_regex of field rule %s: %w", name, err)
+			}
+			rule.normalize = []regexNormalization{{pattern: pattern, replacement: n.Replacement}}
+		}
+		rules.patterns = append(rules.patterns, fieldPattern{match: fieldPatternRegexp(name), rule: rule})
+	}
+	return rules, nil
+}
+
+// fieldPatternRegexp returns the regexp matching the field names of pattern.
+func fieldPatternRegexp(pattern string) *regexp.Regexp {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString(`[^.]*`)
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String())
+}
+
+// noRule is the rule of fields no pattern matches.
+var noRule = &fieldRule{}
+
+// of returns the rule of field.
+func (r *FieldRules) of(field string) *fieldRule {
+	if r == nil {
+		return noRule
+	}
+	if rule, ok := r.byField.Load(field); ok {
+		return rule.(*fieldRule)
+	}
+	// Array elements follow the rule of their array.
+	name := strings.ReplaceAll(field, "[]", "")
+	rule := &fieldRule{}
+	for _, p := range r.patterns {
+		if !p.match.MatchString(name) {
+			continue
+		}
+		rule.ignore = rule.ignore || p.rule.ignore
+		rule.caseInsensitive = rule.caseInsensitive || p.rule.caseInsensitive
+		rule.trimWhitespace = rule.trimWhitespace || p.rule.trimWhitespace
+		rule.script = rule.script || p.rule.script
+		rule.normalize = append(rule.normalize, p.rule.normalize...)
+		if p.rule.array != "" {
+			rule.array, rule.arrayKey = p.rule.array, p.rule.arrayKey
+		}
+	}
+	r.byField.Store(field, rule)
+	return rule
+}
+
+// scripted reports whether any rule has the comparison script decide
+// equality.
+func (r *FieldRules) scripted() bool {
+	if r == nil {
+		return false
+	}
+	for _, p := range r.patterns {
+		if p.rule.script {
+			return true
+		}
+	}
+	return false
+}
+
+// apply normalizes a string value by the rule.
+func (r *fieldRule) apply(v interface{}) interface{} {
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+	if r.trimWhitespace {
+		s = strings.TrimSpace(s)
+	}
+	for _, n := range r.normalize {
+		s = n.pattern.ReplaceAllString(s, n.replacement)
+	}
+	if r.caseInsensitive {
+		s = strings.ToLower(s)
+	}
+	return s
+}
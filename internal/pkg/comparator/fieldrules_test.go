@@ -0,0 +1,115 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/config"
+	"data-comparator/internal/pkg/datareader"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func fieldRulesOptions(t *testing.T, rules string) Options {
+	t.Helper()
+	var cfg config.Comparison
+	if err := yaml.Unmarshal([]byte(rules), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	options := OptionsFromConfig(&cfg)
+	var err error
+	if options.FieldRules, err = CompileFieldRules(&cfg); err != nil {
+		t.Fatalf("CompileFieldRules() error = %v", err)
+	}
+	return options
+}
+
+func TestCompareRecords_FieldRules(t *testing.T) {
+	options := fieldRulesOptions(t, `
+field_rules:
+  updated_at: {ignore: true}
+  "**.etag": {ignore: true}
+  email: {case_insensitive: true, trim_whitespace: true}
+  "address.*": {trim_whitespace: true}
+  phone:
+    normalize_regex: {pattern: "[^0-9]"}
+  status:
+    normalize_regex: {pattern: "^(ok|success)$", replacement: "ok"}
+`)
+
+	rec1 := datareader.Record{
+		"updated_at": "monday",
+		"meta":       map[string]interface{}{"etag": "a", "version": 1},
+		"email":      " Alice@Example.com",
+		"address":    map[string]interface{}{"city": "Paris ", "zip": "75001"},
+		"phone":      "+1 (555) 010-0000",
+		"status":     "success",
+		"name":       "Alice",
+	}
+	rec2 := datareader.Record{
+		"updated_at": "tuesday",
+		"meta":       map[string]interface{}{"etag": "b", "version": 1},
+		"email":      "alice@example.com",
+		"address":    map[string]interface{}{"city": "Paris", "zip": "75001"},
+		"phone":      "15550100000",
+		"status":     "ok",
+		"name":       "ALICE",
+	}
+
+	diffs := compareRecords(rec1, rec2, options)
+	if len(diffs) != 1 || diffs[0].Field != "name" {
+		t.Errorf("diffs got = %v, want a single diff on name", diffs)
+	}
+}
+
+func TestFieldRules_Patterns(t *testing.T) {
+	options := fieldRulesOptions(t, `
+field_rules:
+  "*_at": {ignore: true}
+  "items.**": {case_insensitive: true}
+`)
+
+	tests := []struct {
+		field  string
+		ignore bool
+	}{
+		{"created_at", true},
+		{"meta.created_at", false},
+		{"created", false},
+	}
+	for _, tt := range tests {
+		if got := options.FieldRules.of(tt.field).ignore; got != tt.ignore {
+			t.Errorf("of(%s).ignore got = %v, want %v", tt.field, got, tt.ignore)
+		}
+	}
+	if got := options.FieldRules.of("items[].sku").caseInsensitive; !got {
+		t.Errorf("of(items[].sku).caseInsensitive got = %v, want true", got)
+	}
+}
+
+func TestCanonicalize_FieldRules(t *testing.T) {
+	options := fieldRulesOptions(t, `
+field_rules:
+  updated_at: {ignore: true}
+  name: {case_insensitive: true}
+`)
+
+	h1, err := options.Hash(datareader.Record{"id": 1, "name": "Alice", "updated_at": "monday"})
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	h2, err := options.Hash(datareader.Record{"id": 1, "name": "ALICE", "updated_at": "tuesday"})
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	if h1 != h2 {
+		t.Errorf("Hash() got %s and %s, want the same hash", h1, h2)
+	}
+}
+
+func TestCompileFieldRules_InvalidRegex(t *testing.T) {
+	cfg := &config.Comparison{FieldRules: map[string]config.FieldRule{
+		"phone": {NormalizeRegex: &config.RegexNormalization{Pattern: "("}},
+	}}
+	if _, err := CompileFieldRules(cfg); err == nil {
+		t.Error("CompileFieldRules() error = nil, want an invalid regex error")
+	}
+}
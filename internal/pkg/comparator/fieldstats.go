@@ -0,0 +1,127 @@
+package comparator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TopValuePairs is the number of most frequent value pairs listed per field.
+const TopValuePairs = 5
+
+// maxTrackedPairs is the number of distinct value pairs counted per field.
+// Pairs first seen after that are left out of the top value pairs.
+const maxTrackedPairs = 1000
+
+// FieldStat summarizes the diffs of one field over all matching keys, so a
+// broken column stands out without going through the diffs by key.
+type FieldStat struct {
+	Field string `yaml:"field"`
+	// Records is the number of matching keys whose records differ in the field.
+	Records int `yaml:"records"`
+	// MismatchRate is the percentage of matching keys whose records differ in
+	// the field.
+	MismatchRate float64 `yaml:"mismatch_rate"`
+	// TopValuePairs are the most frequent pairs of differing values, the most
+	// frequent first.
+	TopValuePairs []ValuePair `yaml:"top_value_pairs"`
+}
+
+// ValuePair is a pair of differing values of a field and how many records
+// differ by it.
+type ValuePair struct {
+	Source1Value interface{} `yaml:"source1_value"`
+	Source2Value interface{} `yaml:"source2_value"`
+	Count        int         `yaml:"count"`
+}
+
+type fieldTally struct {
+	records int
+	pairs   map[string]*ValuePair
+	order   []string
+}
+
+// tallyFields counts the diffs of a record per field. Elements of keyed
+// arrays count under their array field, like items[].qty.
+func (c *StreamComparator) tallyFields(diffs []FieldDiff) {
+	if c.fields == nil {
+		c.fields = make(map[string]*fieldTally)
+	}
+	seen := make(map[string]bool, len(diffs))
+	for _, diff := range diffs {
+		field := statField(diff.Field)
+		tally, ok := c.fields[field]
+		if !ok {
+			tally = &fieldTally{pairs: make(map[string]*ValuePair)}
+			c.fields[field] = tally
+		}
+		if !seen[field] {
+			seen[field] = true
+			tally.records++
+		}
+		pair := fmt.Sprintf("%#v\x00%#v", diff.Source1Value, diff.Source2Value)
+		if p, ok := tally.pairs[pair]; ok {
+			p.Count++
+		} else if len(tally.pairs) < maxTrackedPairs {
+			tally.pairs[pair] = &ValuePair{Source1Value: diff.Source1Value, Source2Value: diff.Source2Value, Count: 1}
+			tally.order = append(tally.order, pair)
+		}
+	}
+}
+
+// fieldStats returns the stats of the fields that differed, the most
+// often differing first.
+func (c *StreamComparator) fieldStats() []FieldStat {
+	if len(c.fields) == 0 {
+		return nil
+	}
+	stats := make([]FieldStat, 0, len(c.fields))
+	for field, tally := range c.fields {
+		stat := FieldStat{Field: field, Records: tally.records}
+		if matching := c.result.Summary.MatchingKeys; matching > 0 {
+			stat.MismatchRate = round(100 * float64(tally.records) / float64(matching))
+		}
+		pairs := make([]ValuePair, 0, len(tally.order))
+		for _, pair := range tally.order {
+			pairs = append(pairs, *tally.pairs[pair])
+		}
+		// Stable, so ties keep the order the pairs were first seen in.
+		sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].Count > pairs[j].Count })
+		stat.TopValuePairs = pairs[:min(len(pairs), TopValuePairs)]
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Records != stats[j].Records {
+			return stats[i].Records > stats[j].Records
+		}
+		return stats[i].Field < stats[j].Field
+	})
+	return stats
+}
+
+// statField drops the keys of keyed array elements from field, so
+// items[sku=A1].qty and items[sku=B2].qty count as items[].qty.
+func statField(field string) string {
+	if !strings.Contains(field, "[") {
+		return field
+	}
+	var b strings.Builder
+	depth := 0
+	for _, r := range field {
+		switch {
+		case r == '[':
+			if depth == 0 {
+				b.WriteRune(r)
+			}
+			depth++
+		case r == ']' && depth > 0:
+			depth--
+			if depth == 0 {
+				b.WriteRune(r)
+			}
+		case depth == 0:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/config"
+	"data-comparator/internal/pkg/datareader"
+	"reflect"
+	"testing"
+)
+
+func TestCompare_FieldStats(t *testing.T) {
+	records1 := []datareader.Record{
+		{"id": "1", "status": "active", "city": "Berlin"},
+		{"id": "2", "status": "active", "city": "Paris"},
+		{"id": "3", "status": "closed", "city": "Rome"},
+		{"id": "4", "status": "active", "city": "Oslo"},
+	}
+	records2 := []datareader.Record{
+		{"id": "1", "status": "ACTIVE", "city": "Berlin"},
+		{"id": "2", "status": "ACTIVE", "city": "Paris"},
+		{"id": "3", "status": "CLOSED", "city": "Roma"},
+		{"id": "4", "status": "active", "city": "Oslo"},
+	}
+	result, err := New("id").Compare(datareader.NewSliceReader(records1), datareader.NewSliceReader(records2))
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	want := []FieldStat{
+		{Field: "status", Records: 3, MismatchRate: 75, TopValuePairs: []ValuePair{
+			{Source1Value: "active", Source2Value: "ACTIVE", Count: 2},
+			{Source1Value: "closed", Source2Value: "CLOSED", Count: 1},
+		}},
+		{Field: "city", Records: 1, MismatchRate: 25, TopValuePairs: []ValuePair{
+			{Source1Value: "Rome", Source2Value: "Roma", Count: 1},
+		}},
+	}
+	if !reflect.DeepEqual(result.FieldStats, want) {
+		t.Errorf("FieldStats got = %+v, want %+v", result.FieldStats, want)
+	}
+}
+
+func TestCompare_FieldStatsKeyedArrays(t *testing.T) {
+	options := DefaultOptions()
+	rules, err := CompileFieldRules(&config.Comparison{FieldRules: map[string]config.FieldRule{"items": {Array: "keyed", ArrayKey: "sku"}}})
+	if err != nil {
+		t.Fatalf("CompileFieldRules() error = %v", err)
+	}
+	options.FieldRules = rules
+	c := New("id")
+	c.SetOptions(options)
+	items := func(qty1, qty2 int) []interface{} {
+		return []interface{}{
+			map[string]interface{}{"sku": "A1", "qty": qty1},
+			map[string]interface{}{"sku": "B2", "qty": qty2},
+		}
+	}
+	result, err := c.Compare(
+		datareader.NewSliceReader([]datareader.Record{{"id": "1", "items": items(1, 1)}}),
+		datareader.NewSliceReader([]datareader.Record{{"id": "1", "items": items(2, 3)}}))
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	if len(result.FieldStats) != 1 || result.FieldStats[0].Field != "items[].qty" || result.FieldStats[0].Records != 1 {
+		t.Errorf("FieldStats got = %+v, want items[].qty differing in 1 record", result.FieldStats)
+	}
+}
+
+func TestStatField(t *testing.T) {
+	tests := map[string]string{
+		"a.b":                    "a.b",
+		"items[sku=A1].qty":      "items[].qty",
+		"o[k=x].inner[id=[1]].v": "o[].inner[].v",
+		"tags[]":                 "tags[]",
+	}
+	for field, want := range tests {
+		if got := statField(field); got != want {
+			t.Errorf("statField(%s) got = %s, want %s", field, got, want)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"fmt"
+	"io"
+	"iter"
+	"time"
+)
+
+// Finding is a single typed outcome of a keyed comparison.
+type Finding interface {
+	// Kind names the type of finding, e.g. "missing_key".
+	Kind() string
+}
+
+// MissingKey is a key found only in Side, of the severity of its keys.
+type MissingKey struct {
+	Key      string
+	Side     Side
+	Record   datareader.Record
+	Severity Severity
+}
+
+// RecordDiff is a key present in both sources with differing field values.
+type RecordDiff struct {
+	Key     string
+	Diffs   []FieldDiff
+	Record1 datareader.Record
+	Record2 datareader.Record
+}
+
+// DuplicateKey is a key seen again on Side before it was matched.
+type DuplicateKey struct {
+	Key    string
+	Side   Side
+	Record datareader.Record
+}
+
+// TruncatedRecord is the Record-th record of Side, truncated to Options.Limits.
+type TruncatedRecord struct {
+	Side        Side
+	Record      int
+	Truncations []Truncation
+}
+
+// ParseFailure is a record of Side that could not be read. Comparison goes
+// on after recoverable parse errors; otherwise it is the last finding.
+type ParseFailure struct {
+	Side Side
+	Err  error
+}
+
+// EmptySource is a source that held no records at all. It comes right
+// before Completed.
+type EmptySource struct {
+	Side Side
+}
+
+// Stalled is a source that produced no record for the stall timeout set
+// with SetStall. Comparison goes on unless StallOptions.Fail is set, in
+// which case a ParseFailure holding a StallError follows.
+type Stalled struct {
+	Side  Side
+	Stall Stall
+}
+
+// Incomplete is a source no longer read because of its read timeout or the
+// deadline. The comparison finishes with the records read so far.
+type Incomplete struct {
+	Side   Side
+	Reason string
+}
+
+// ResolvedDiff is a key reported by an earlier RecordDiff whose records
+// matched after a later record, Latency after the diff, when resolved diffs
+// are tracked with SetTrackResolved.
+type ResolvedDiff struct {
+	Key     string
+	Latency time.Duration
+	Record1 datareader.Record
+	Record2 datareader.Record
+}
+
+// Completed is always the last finding of a comparison that ran to the end.
+type Completed struct {
+	Summary Summary
+	Keys    KeyMapping
+}
+
+func (MissingKey) Kind() string      { return "missing_key" }
+func (RecordDiff) Kind() string      { return "field_diff" }
+func (DuplicateKey) Kind() string    { return "duplicate_key" }
+func (TruncatedRecord) Kind() string { return "truncated_record" }
+func (ParseFailure) Kind() string    { return "parse_error" }
+func (EmptySource) Kind() string     { return "empty_source" }
+func (Stalled) Kind() string         { return "stalled" }
+func (Incomplete) Kind() string      { return "incomplete" }
+func (ResolvedDiff) Kind() string    { return "resolved_diff" }
+func (Completed) Kind() string       { return "summary" }
+
+// Findings compares the two sources like Compare, but yields each finding as
+// soon as it is known instead of collecting value diffs into a Result. The
+// comparator's own hooks still fire. Stopping the iteration stops reading.
+func (c *StreamComparator) Findings(reader1, reader2 datareader.DataReader) iter.Seq[Finding] {
+	return func(yield func(Finding) bool) {
+		userHooks := c.hooks
+		stopped := false
+		emit := func(f Finding) {
+			if !stopped && !yield(f) {
+				stopped = true
+			}
+		}
+
+		c.hooks = Hooks{
+			OnMatch: userHooks.OnMatch,
+			OnDiff: func(key string, diffs []FieldDiff, rec1, rec2 datareader.Record) {
+				if userHooks.OnDiff != nil {
+					userHooks.OnDiff(key, diffs, rec1, rec2)
+				}
+				emit(RecordDiff{Key: key, Diffs: diffs, Record1: rec1, Record2: rec2})
+			},
+			OnOnlyInSource1: func(key string, rec datareader.Record) {
+				if userHooks.OnOnlyInSource1 != nil {
+					userHooks.OnOnlyInSource1(key, rec)
+				}
+				emit(MissingKey{Key: key, Side: Source1, Record: rec, Severity: c.result.MissingKeySeverityOf(Source1)})
+			},
+			OnOnlyInSource2: func(key string, rec datareader.Record) {
+				if userHooks.OnOnlyInSource2 != nil {
+					userHooks.OnOnlyInSource2(key, rec)
+				}
+				emit(MissingKey{Key: key, Side: Source2, Record: rec, Severity: c.result.MissingKeySeverityOf(Source2)})
+			},
+			OnDuplicateKey: func(side Side, key string, rec datareader.Record) {
+				if userHooks.OnDuplicateKey != nil {
+					userHooks.OnDuplicateKey(side, key, rec)
+				}
+				emit(DuplicateKey{Key: key, Side: side, Record: rec})
+			},
+			OnTruncated: func(side Side, n int, truncations []Truncation) {
+				if userHooks.OnTruncated != nil {
+					userHooks.OnTruncated(side, n, truncations)
+				}
+				emit(TruncatedRecord{Side: side, Record: n, Truncations: truncations})
+			},
+			OnStall: func(side Side, stall Stall) {
+				if userHooks.OnStall != nil {
+					userHooks.OnStall(side, stall)
+				}
+				emit(Stalled{Side: side, Stall: stall})
+			},
+			OnIncomplete: func(side Side, reason string) {
+				if userHooks.OnIncomplete != nil {
+					userHooks.OnIncomplete(side, reason)
+				}
+				emit(Incomplete{Side: side, Reason: reason})
+			},
+			OnResolved: func(key string, latency time.Duration, rec1, rec2 datareader.Record) {
+				if userHooks.OnResolved != nil {
+					userHooks.OnResolved(key, latency, rec1, rec2)
+				}
+				emit(ResolvedDiff{Key: key, Latency: latency, Record1: rec1, Record2: rec2})
+			},
+			OnProgress:  userHooks.OnProgress,
+			OnHeartbeat: userHooks.OnHeartbeat,
+			OnSnapshot:  userHooks.OnSnapshot,
+		}
+		discard := c.discardDiffs
+		c.discardDiffs = true
+		defer func() {
+			c.script.Close()
+			c.hooks = userHooks
+			c.discardDiffs = discard
+			c.result, c.pending1, c.pending2 = nil, nil, nil
+			c.differing, c.resolvedLatencies = nil, nil
+			c.lagTotal, c.lagInterval, c.arrivals = nil, nil, nil
+			c.verified, c.fields = nil, nil
+			c.extra, c.duplicateIndex = [2]map[string][]datareader.Record{}, [2]map[string]int{}
+		}()
+
+		if c.key1 == "" || c.key2 == "" {
+			emit(ParseFailure{Err: fmt.Errorf("comparison key is not set")})
+			return
+		}
+		c.reset()
+
+		reader1, stop1 := c.watch(reader1, Source1)
+		defer stop1()
+		reader2, stop2 := c.watch(reader2, Source2)
+		defer stop2()
+		readers := map[Side]datareader.DataReader{Source1: reader1, Source2: reader2}
+		done := map[Side]bool{}
+		for !done[Source1] || !done[Source2] {
+			for _, side := range []Side{Source1, Source2} {
+				// In lookup mode, the stream waits for the snapshot to be loaded.
+				if done[side] || c.lookup && side == Source1 && !done[Source2] {
+					continue
+				}
+				var fatal bool
+				done[side], fatal = c.readFinding(readers[side], side, emit)
+				if fatal || stopped {
+					return
+				}
+			}
+		}
+
+		empty := c.emptySides()
+		result := c.Finish()
+		if err := c.script.Close(); err != nil {
+			emit(ParseFailure{Err: err})
+			return
+		}
+		for _, side := range empty {
+			emit(EmptySource{Side: side})
+		}
+		emit(Completed{Summary: result.Summary, Keys: result.Keys})
+	}
+}
+
+// readFinding reads and adds one record of side. Read and key errors are
+// emitted as ParseFailures; it reports whether the side reached its end and
+// whether the error was unrecoverable, which ends the comparison.
+func (c *StreamComparator) readFinding(reader datareader.DataReader, side Side, emit func(Finding)) (bool, bool) {
+	c.waitWhilePaused()
+	rec, err := reader.Read()
+	if err == io.EOF {
+		return true, false
+	}
+	if err != nil {
+		emit(ParseFailure{Side: side, Err: err})
+		if perr := recoverable(err); perr != nil {
+			c.skip(side, perr)
+			return false, false
+		}
+		return false, true
+	}
+
+	if err := c.Add(side, rec); err != nil {
+		emit(ParseFailure{Side: side, Err: err})
+		// A failed script fails every comparison after it.
+		return false, c.script.Err() != nil
+	}
+	return false, false
+}
@@ -0,0 +1,88 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"errors"
+	"testing"
+)
+
+func TestFindings(t *testing.T) {
+	reader1, reader2 := openReaders(t, "testcase1_simple_csv", "csv", "csv")
+
+	kinds := make(map[string]int)
+	var last Finding
+	for f := range New("user_id").Findings(reader1, reader2) {
+		kinds[f.Kind()]++
+		last = f
+	}
+
+	if kinds["field_diff"] != 1 || kinds["missing_key"] != 2 || kinds["summary"] != 1 {
+		t.Errorf("Finding kinds got = %v, want 1 field_diff, 2 missing_key, 1 summary", kinds)
+	}
+	completed, ok := last.(Completed)
+	if !ok {
+		t.Fatalf("Last finding got = %T, want Completed", last)
+	}
+	if completed.Summary.MatchingKeys != 4 || completed.Summary.IdenticalRows != 3 {
+		t.Errorf("Completed summary got = %+v", completed.Summary)
+	}
+}
+
+func TestFindings_DuplicatesAndEarlyStop(t *testing.T) {
+	records1 := []datareader.Record{{"id": "a"}, {"id": "a"}, {"id": "b"}}
+	records2 := []datareader.Record{{"id": "c"}}
+
+	var duplicates []DuplicateKey
+	for f := range New("id").Findings(datareader.NewSliceReader(records1), datareader.NewSliceReader(records2)) {
+		if d, ok := f.(DuplicateKey); ok {
+			duplicates = append(duplicates, d)
+		}
+	}
+	if len(duplicates) != 1 || duplicates[0].Key != "a" || duplicates[0].Side != Source1 {
+		t.Errorf("DuplicateKey findings got = %+v, want key a in source1", duplicates)
+	}
+
+	count := 0
+	c := New("id")
+	for range c.Findings(datareader.NewSliceReader(records1), datareader.NewSliceReader(records2)) {
+		count++
+		break
+	}
+	if count != 1 {
+		t.Errorf("Expected iteration to stop after 1 finding, got %d", count)
+	}
+	if result := c.Finish(); result.Summary.Source1Rows != 0 {
+		t.Errorf("Expected a fresh comparison after an early stop, got %+v", result.Summary)
+	}
+}
+
+func TestFindings_EmptySource(t *testing.T) {
+	var findings []Finding
+	for f := range New("id").Findings(datareader.NewSliceReader(nil), datareader.NewSliceReader([]datareader.Record{{"id": "a"}})) {
+		findings = append(findings, f)
+	}
+	if len(findings) != 3 {
+		t.Fatalf("Expected missing_key, empty_source and summary findings, got %v", findings)
+	}
+	if empty, ok := findings[1].(EmptySource); !ok || empty.Side != Source1 {
+		t.Errorf("Finding got = %+v, want EmptySource on source1", findings[1])
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read() (datareader.Record, error) { return nil, errors.New("connection reset") }
+func (failingReader) Close() error                     { return nil }
+
+func TestFindings_UnrecoverableError(t *testing.T) {
+	var findings []Finding
+	for f := range New("id").Findings(failingReader{}, datareader.NewSliceReader(nil)) {
+		findings = append(findings, f)
+	}
+	if len(findings) != 1 {
+		t.Fatalf("Expected only the parse failure, got %v", findings)
+	}
+	if pf, ok := findings[0].(ParseFailure); !ok || pf.Side != Source1 {
+		t.Errorf("Finding got = %+v, want ParseFailure on source1", findings[0])
+	}
+}
@@ -0,0 +1,144 @@
+package comparator
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Edit operations of an inline diff.
+const (
+	EditEqual  = "equal"
+	EditInsert = "insert"
+	EditDelete = "delete"
+)
+
+// maxInlineDiffCells bounds the size of the LCS table; beyond it the differing
+// middle of the values is reported as a single delete and insert.
+const maxInlineDiffCells = 1 << 20
+
+// inlineContext is the number of characters kept at each end of an unchanged
+// segment; longer segments are shortened with an ellipsis.
+const inlineContext = 20
+
+// Edit is one step of a token-level edit script turning the source1 value
+// into the source2 value.
+type Edit struct {
+	Op   string `yaml:"op" json:"op"`
+	Text string `yaml:"text" json:"text"`
+}
+
+// inlineDiff computes a token-level edit script between two strings. Tokens are
+// runs of letters and digits or single other characters, which keeps diffs of
+// JSON blobs and sentences readable.
+func inlineDiff(s1, s2 string) []Edit {
+	t1, t2 := tokenize(s1), tokenize(s2)
+
+	prefix := 0
+	for prefix < len(t1) && prefix < len(t2) && t1[prefix] == t2[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(t1)-prefix && suffix < len(t2)-prefix &&
+		t1[len(t1)-1-suffix] == t2[len(t2)-1-suffix] {
+		suffix++
+	}
+
+	var edits []Edit
+	edits = appendEdit(edits, EditEqual, strings.Join(t1[:prefix], ""))
+	edits = append(edits, diffTokens(t1[prefix:len(t1)-suffix], t2[prefix:len(t2)-suffix])...)
+	edits = appendEdit(edits, EditEqual, strings.Join(t1[len(t1)-suffix:], ""))
+
+	for i := range edits {
+		if edits[i].Op == EditEqual {
+			edits[i].Text = abbreviate(edits[i].Text)
+		}
+	}
+	return edits
+}
+
+// diffTokens computes the edit script of two token slices via their longest
+// common subsequence.
+func diffTokens(a, b []string) []Edit {
+	var edits []Edit
+	if len(a)*len(b) > maxInlineDiffCells {
+		edits = appendEdit(edits, EditDelete, strings.Join(a, ""))
+		return appendEdit(edits, EditInsert, strings.Join(b, ""))
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			edits = appendEdit(edits, EditEqual, a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits = appendEdit(edits, EditDelete, a[i])
+			i++
+		default:
+			edits = appendEdit(edits, EditInsert, b[j])
+			j++
+		}
+	}
+	edits = appendEdit(edits, EditDelete, strings.Join(a[i:], ""))
+	return appendEdit(edits, EditInsert, strings.Join(b[j:], ""))
+}
+
+// appendEdit appends text to the script, merging it into the last edit when
+// the operation is the same.
+func appendEdit(edits []Edit, op, text string) []Edit {
+	if text == "" {
+		return edits
+	}
+	if n := len(edits); n > 0 && edits[n-1].Op == op {
+		edits[n-1].Text += text
+		return edits
+	}
+	return append(edits, Edit{Op: op, Text: text})
+}
+
+func tokenize(s string) []string {
+	var tokens []string
+	start := -1
+	for i, r := range s {
+		word := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if word {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, s[start:i])
+			start = -1
+		}
+		tokens = append(tokens, string(r))
+	}
+	if start >= 0 {
+		tokens = append(tokens, s[start:])
+	}
+	return tokens
+}
+
+func abbreviate(s string) string {
+	runes := []rune(s)
+	if len(runes) <= 2*inlineContext+1 {
+		return s
+	}
+	return string(runes[:inlineContext]) + "…" + string(runes[len(runes)-inlineContext:])
+}
@@ -0,0 +1,43 @@
+package comparator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInlineDiff(t *testing.T) {
+	got := inlineDiff(`{"status":"active","plan":"basic"}`, `{"status":"inactive","plan":"basic"}`)
+	want := []Edit{
+		{Op: EditEqual, Text: `{"status":"`},
+		{Op: EditDelete, Text: "active"},
+		{Op: EditInsert, Text: "inactive"},
+		{Op: EditEqual, Text: `","plan":"basic"}`},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("inlineDiff() got = %v, want %v", got, want)
+	}
+}
+
+func TestInlineDiff_AbbreviatesUnchangedText(t *testing.T) {
+	common := strings.Repeat("x", 100)
+	edits := inlineDiff(common+" a", common+" b")
+	if edits[0].Op != EditEqual || len([]rune(edits[0].Text)) != 2*inlineContext+1 {
+		t.Errorf("Expected abbreviated leading equal segment, got %q", edits[0].Text)
+	}
+}
+
+func TestNewFieldDiff_InlineEdits(t *testing.T) {
+	options := DefaultOptions()
+	options.InlineDiffMinLength = 10
+
+	diff := newFieldDiff("note", "the quick brown fox", "the quick red fox", options)
+	if len(diff.Edits) == 0 {
+		t.Fatal("Expected inline edits for long strings")
+	}
+
+	diff = newFieldDiff("plan", "basic", "premium", options)
+	if diff.Edits != nil {
+		t.Errorf("Expected no inline edits below the minimum length, got %v", diff.Edits)
+	}
+}
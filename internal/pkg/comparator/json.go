@@ -0,0 +1,16 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/output"
+	"fmt"
+)
+
+// JSON renders the result as JSON with the same field names as its YAML
+// report, for consumers in other languages.
+func (r *Result) JSON() ([]byte, error) {
+	data, err := output.JSON(r, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+	return data, nil
+}
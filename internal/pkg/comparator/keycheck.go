@@ -0,0 +1,264 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/config"
+	"data-comparator/internal/pkg/datareader"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// DefaultKeyCheckSample is the number of records whose keys the key overlap
+// pre-check samples from each source.
+const DefaultKeyCheckSample = 10000
+
+// maxKeyExamples is the number of sampled keys of each source named in a
+// key overlap diagnostic.
+const maxKeyExamples = 3
+
+// minCandidateOverlap is the share of the other source's sampled keys a field
+// must hold to be suggested as the key instead.
+const minCandidateOverlap = 0.5
+
+// KeyOverlap estimates how many keys two sources share from the keys of the
+// first records of each.
+type KeyOverlap struct {
+	Key1, Key2 string
+	// Records1 and Records2 are the numbers of records sampled.
+	Records1, Records2 int
+	// Keys1 and Keys2 are the distinct keys among the sampled records.
+	Keys1, Keys2 int
+	// Shared is the number of distinct keys sampled from both sources.
+	Shared int
+	// Overlap is Shared as a share of the smaller number of distinct keys.
+	Overlap float64
+	// Examples1 and Examples2 are the first sampled keys of each source.
+	Examples1, Examples2 []string
+	// FormatOverlap is the overlap after ignoring case, surrounding
+	// whitespace and leading zeros.
+	FormatOverlap float64
+	// Suggestions name other fields holding most of the other source's keys.
+	Suggestions []string
+}
+
+// KeyOverlapError aborts a comparison whose sources share almost no keys,
+// which usually means a wrong key field or mismatched datasets, when the
+// pre-check is set to fail.
+type KeyOverlapError struct {
+	KeyOverlap
+	MinOverlap float64
+}
+
+// Error describes the sampled keys and what likely went wrong.
+func (e *KeyOverlapError) Error() string {
+	return e.describe() + "\n  set comparison.key_check.disabled to compare anyway"
+}
+
+func (e *KeyOverlapError) describe() string {
+	o := e.KeyOverlap
+	var b strings.Builder
+	switch {
+	case o.Keys1 == 0:
+		fmt.Fprintf(&b, "key check: key field %s is missing from all %d sampled source1 records", o.Key1, o.Records1)
+	case o.Keys2 == 0:
+		fmt.Fprintf(&b, "key check: key field %s is missing from all %d sampled source2 records", o.Key2, o.Records2)
+	default:
+		fmt.Fprintf(&b, "key check: only %d of %d sampled source1 keys (%s, e.g. %s) and %d sampled source2 keys (%s, e.g. %s) match, an overlap of %.1f%%",
+			o.Shared, o.Keys1, o.Key1, strings.Join(o.Examples1, ", "), o.Keys2, o.Key2, strings.Join(o.Examples2, ", "), o.Overlap*100)
+		if e.MinOverlap > 0 {
+			fmt.Fprintf(&b, " (minimum %.1f%%)", e.MinOverlap*100)
+		}
+	}
+	if o.FormatOverlap > o.Overlap && o.FormatOverlap >= minCandidateOverlap {
+		fmt.Fprintf(&b, "\n  %.0f%% of keys match when ignoring case, whitespace and leading zeros; the key formats differ", o.FormatOverlap*100)
+	}
+	for _, s := range o.Suggestions {
+		b.WriteString("\n  " + s)
+	}
+	if o.Keys1 > 0 && o.Keys2 > 0 && len(o.Suggestions) == 0 && o.FormatOverlap < minCandidateOverlap {
+		b.WriteString("\n  check that both configs read the same dataset and that the key fields correspond")
+	}
+	return b.String()
+}
+
+// keySample holds the keys and records sampled from one source.
+type keySample struct {
+	records []datareader.Record
+	keys    map[string]bool
+	order   []string
+}
+
+// sampleKeys samples the keys of the first n records of src, canonicalized
+// by options as the comparison does.
+func sampleKeys(src config.Source, key string, n int, options Options) (*keySample, error) {
+	reader, err := datareader.New(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reader: %w", err)
+	}
+	defer reader.Close()
+	sample := &keySample{keys: make(map[string]bool)}
+	for len(sample.records) < n {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Unreadable records are reported by the comparison itself.
+			var perr *datareader.ParseError
+			if errors.As(err, &perr) && perr.Recoverable {
+				continue
+			}
+			return nil, err
+		}
+		sample.records = append(sample.records, rec)
+		if v, ok := rec[key]; ok && v != nil {
+			k := formatKey(options.canonicalValue(key, v))
+			if !sample.keys[k] {
+				sample.keys[k] = true
+				sample.order = append(sample.order, k)
+			}
+		}
+	}
+	return sample, nil
+}
+
+// CheckKeyOverlap samples the keys of the first n records of both sources
+// and estimates their overlap, matching keys after canonicalizing them by
+// options.
+func CheckKeyOverlap(config1, config2 *config.Config, key1, key2 string, n int, options Options) (*KeyOverlap, error) {
+	if n <= 0 {
+		n = DefaultKeyCheckSample
+	}
+	sample1, err := sampleKeys(config1.Source, key1, n, options)
+	if err != nil {
+		return nil, fmt.Errorf("source1: %w", err)
+	}
+	sample2, err := sampleKeys(config2.Source, key2, n, options)
+	if err != nil {
+		return nil, fmt.Errorf("source2: %w", err)
+	}
+
+	o := &KeyOverlap{
+		Key1: key1, Key2: key2,
+		Records1: len(sample1.records), Records2: len(sample2.records),
+		Keys1: len(sample1.keys), Keys2: len(sample2.keys),
+		Examples1: sample1.order[:min(maxKeyExamples, len(sample1.order))],
+		Examples2: sample2.order[:min(maxKeyExamples, len(sample2.order))],
+	}
+	o.Shared = shared(sample1.keys, sample2.keys)
+	o.Overlap = overlapOf(o.Shared, o.Keys1, o.Keys2)
+	norm1, norm2 := normalizedKeys(sample1.keys), normalizedKeys(sample2.keys)
+	o.FormatOverlap = overlapOf(shared(norm1, norm2), len(norm1), len(norm2))
+	o.Suggestions = append(suggestKeys(sample2, key2, sample1.keys, "source2", "-key2"),
+		suggestKeys(sample1, key1, sample2.keys, "source1", "-key1")...)
+	return o, nil
+}
+
+func shared(keys1, keys2 map[string]bool) int {
+	n := 0
+	for k := range keys1 {
+		if keys2[k] {
+			n++
+		}
+	}
+	return n
+}
+
+func overlapOf(shared, n1, n2 int) float64 {
+	if n1 == 0 || n2 == 0 {
+		return 0
+	}
+	return float64(shared) / float64(min(n1, n2))
+}
+
+func normalizedKeys(keys map[string]bool) map[string]bool {
+	out := make(map[string]bool, len(keys))
+	for k := range keys {
+		k = strings.ToLower(strings.TrimSpace(k))
+		if trimmed := strings.TrimLeft(k, "0"); trimmed != "" {
+			k = trimmed
+		}
+		out[k] = true
+	}
+	return out
+}
+
+// suggestKeys names the top-level fields of sample, other than its key,
+// whose values hold most of the other source's sampled keys.
+func suggestKeys(sample *keySample, key string, otherKeys map[string]bool, side, flag string) []string {
+	if len(otherKeys) == 0 {
+		return nil
+	}
+	values := make(map[string]map[string]bool)
+	for _, rec := range sample.records {
+		for name, v := range rec {
+			switch v.(type) {
+			case nil, map[string]interface{}, []interface{}:
+				continue
+			}
+			if name == key {
+				continue
+			}
+			if values[name] == nil {
+				values[name] = make(map[string]bool)
+			}
+			values[name][formatKey(v)] = true
+		}
+	}
+
+	type candidate struct {
+		name  string
+		share float64
+	}
+	var candidates []candidate
+	for name, vals := range values {
+		share := float64(shared(otherKeys, vals)) / float64(len(otherKeys))
+		if share >= minCandidateOverlap {
+			candidates = append(candidates, candidate{name, share})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].share != candidates[j].share {
+			return candidates[i].share > candidates[j].share
+		}
+		return candidates[i].name < candidates[j].name
+	})
+	var out []string
+	for _, c := range candidates {
+		out = append(out, fmt.Sprintf("%s field %s holds %.0f%% of the sampled keys of the other source; try %s %s", side, c.name, c.share*100, flag, c.name))
+	}
+	return out
+}
+
+// checkKeys runs the key overlap pre-check configured by settings. When the
+// sources share too few keys, it returns a KeyOverlapError if the check is
+// set to fail and a warning otherwise, as the first records of sources in
+// different orders need not share keys. Empty sources pass, as there is
+// nothing to match.
+func checkKeys(config1, config2 *config.Config, key1, key2 string, settings *config.Comparison, options Options) (string, error) {
+	var check config.KeyCheck
+	if settings != nil && settings.KeyCheck != nil {
+		check = *settings.KeyCheck
+	}
+	// Standard input can only be read once, by the comparison itself.
+	if check.Disabled || config1.Source.Path == datareader.StdinPath || config2.Source.Path == datareader.StdinPath {
+		return "", nil
+	}
+	o, err := CheckKeyOverlap(config1, config2, key1, key2, check.SampleSize, options)
+	if err != nil {
+		return "", fmt.Errorf("key check failed: %w", err)
+	}
+	if o.Records1 == 0 || o.Records2 == 0 {
+		return "", nil
+	}
+	if o.Shared == 0 || o.Overlap < check.MinOverlap {
+		overlapErr := &KeyOverlapError{KeyOverlap: *o, MinOverlap: check.MinOverlap}
+		if check.Fail {
+			return "", overlapErr
+		}
+		return overlapErr.describe() + "\n  compared anyway, as sources in different key orders can match past the sample; set comparison.key_check.fail to abort instead", nil
+	}
+	return "", nil
+}
@@ -0,0 +1,113 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/config"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeCSV(t *testing.T, content string) *config.Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "data.csv")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return &config.Config{Source: config.Source{Type: "csv", Path: path}}
+}
+
+func TestCompareConfigs_KeyCheck(t *testing.T) {
+	config1 := writeCSV(t, "id,customer,amount\n1,c1,10\n2,c2,20\n3,c3,30\n")
+	config2 := writeCSV(t, "order_id,customer,amount\n101,c1,10\n102,c2,20\n103,c3,30\n")
+
+	result, err := CompareConfigs(config1, config2, "customer", "order_id")
+	if err != nil {
+		t.Fatalf("CompareConfigs() error = %v", err)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "set comparison.key_check.fail to abort instead") {
+		t.Errorf("Warnings got = %q, want the key check diagnostic", result.Warnings)
+	}
+
+	config1.Comparison = &config.Comparison{KeyCheck: &config.KeyCheck{Fail: true}}
+	_, err = CompareConfigs(config1, config2, "customer", "order_id")
+	var overlapErr *KeyOverlapError
+	if !errors.As(err, &overlapErr) {
+		t.Fatalf("CompareConfigs() error got = %v, want a KeyOverlapError", err)
+	}
+	if overlapErr.Shared != 0 || overlapErr.Keys1 != 3 || overlapErr.Keys2 != 3 {
+		t.Errorf("KeyOverlap got = %+v, want 3 keys each and none shared", overlapErr.KeyOverlap)
+	}
+	if !strings.Contains(err.Error(), "source2 field customer holds 100% of the sampled keys of the other source; try -key2 customer") {
+		t.Errorf("Error() got = %q, want a suggestion of source2 field customer", err)
+	}
+
+	config1.Comparison = &config.Comparison{KeyCheck: &config.KeyCheck{Disabled: true}}
+	result, err = CompareConfigs(config1, config2, "customer", "order_id")
+	if err != nil {
+		t.Fatalf("CompareConfigs() with the key check disabled error = %v", err)
+	}
+	if len(result.Warnings) != 0 {
+		t.Errorf("Warnings with the key check disabled got = %q", result.Warnings)
+	}
+	if result.Summary.KeysOnlyInSource1 != 3 {
+		t.Errorf("KeysOnlyInSource1 got = %d, want 3", result.Summary.KeysOnlyInSource1)
+	}
+
+	if _, err := CompareConfigs(config1, config2, "customer", "customer"); err != nil {
+		t.Errorf("CompareConfigs() of matching keys error = %v", err)
+	}
+}
+
+func TestCheckKeyOverlap(t *testing.T) {
+	config1 := writeCSV(t, "id,name\n001,a\n002,b\n003,c\n004,d\n")
+	config2 := writeCSV(t, "id,name\n1,a\n2,b\n003,c\n5,e\n")
+
+	o, err := CheckKeyOverlap(config1, config2, "id", "id", 0, DefaultOptions())
+	if err != nil {
+		t.Fatalf("CheckKeyOverlap() error = %v", err)
+	}
+	if o.Shared != 1 || o.Overlap != 0.25 || o.FormatOverlap != 0.75 {
+		t.Errorf("CheckKeyOverlap() got = %+v, want 1 shared key, overlap 0.25 and format overlap 0.75", o)
+	}
+
+	_, err = checkKeys(config1, config2, "id", "id", &config.Comparison{KeyCheck: &config.KeyCheck{MinOverlap: 0.5, Fail: true}}, DefaultOptions())
+	if err == nil || !strings.Contains(err.Error(), "key formats differ") {
+		t.Errorf("checkKeys() below the minimum overlap got = %v, want a key format diagnostic", err)
+	}
+	if warning, err := checkKeys(config1, config2, "id", "id", nil, DefaultOptions()); warning != "" || err != nil {
+		t.Errorf("checkKeys() with a shared key got = %q, %v", warning, err)
+	}
+
+	warning, err := checkKeys(config1, config2, "no_such_field", "id", nil, DefaultOptions())
+	if err != nil || !strings.Contains(warning, "key field no_such_field is missing from all 4 sampled source1 records") {
+		t.Errorf("checkKeys() of a missing key field got = %q, %v", warning, err)
+	}
+}
+
+func TestCompareConfigs_KeyCheckDifferentOrders(t *testing.T) {
+	// The samples of the first 10000 records share no key, though both
+	// sources hold the same 20000.
+	var ascending, descending strings.Builder
+	ascending.WriteString("id,value\n")
+	descending.WriteString("id,value\n")
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&ascending, "%d,v%d\n", i, i)
+		fmt.Fprintf(&descending, "%d,v%d\n", 19999-i, 19999-i)
+	}
+	config1 := writeCSV(t, ascending.String())
+	config2 := writeCSV(t, descending.String())
+
+	result, err := CompareConfigs(config1, config2, "id", "id")
+	if err != nil {
+		t.Fatalf("CompareConfigs() error = %v", err)
+	}
+	if result.Summary.MatchingKeys != 20000 || result.Summary.IdenticalRows != 20000 {
+		t.Errorf("Summary got = %+v, want 20000 identical rows", result.Summary)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "only 0 of 10000 sampled source1 keys") {
+		t.Errorf("Warnings got = %q, want the key check diagnostic", result.Warnings)
+	}
+}
@@ -0,0 +1,138 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"math/rand/v2"
+	"time"
+)
+
+// lagSamples is the number of lags kept to estimate percentiles from. Count,
+// mean, minimum and maximum are exact.
+const lagSamples = 10000
+
+// LagOptions configure the measurement of the consistency lag between the
+// sources: how much later each matched key appeared in source2 than in
+// source1.
+type LagOptions struct {
+	// TimestampField holds when each record was produced, as a time, an RFC
+	// 3339-like string or Unix epoch seconds, and the lag is the difference
+	// between the timestamps of the matched records. If empty, the lag is the
+	// time between the arrival of the records at the comparator, less the
+	// time spent paused, which is only meaningful for sources read as they
+	// are produced.
+	TimestampField string
+}
+
+// LagStats summarize the consistency lag of matched keys. Positive lags are
+// keys source2 got after source1, negative ones keys it got first.
+type LagStats struct {
+	Count int `yaml:"count" json:"count"`
+	// Unmeasured counts matched keys whose timestamp is missing or invalid
+	// on either side.
+	Unmeasured          int           `yaml:"unmeasured,omitempty" json:"unmeasured,omitempty"`
+	Mean                time.Duration `yaml:"mean" json:"mean"`
+	LatencyDistribution `yaml:",inline"`
+}
+
+// SetLag enables the measurement of the consistency lag of matched keys,
+// reported in Result.Lag, in every Heartbeat for the keys matched since the
+// previous one, and in snapshots. Nil disables it. Keys matched after records
+// start to spill are not measured by arrival.
+func (c *StreamComparator) SetLag(opts *LagOptions) {
+	c.lag = opts
+}
+
+// lagRecorder accumulates lags, sampling them for percentiles.
+type lagRecorder struct {
+	count, unmeasured int
+	// sum is in nanoseconds, as a float so long runs do not overflow.
+	sum      float64
+	min, max time.Duration
+	samples  []time.Duration
+	rand     *rand.Rand
+}
+
+func newLagRecorder() *lagRecorder {
+	return &lagRecorder{rand: rand.New(rand.NewPCG(1, 2))}
+}
+
+func (r *lagRecorder) add(lag time.Duration) {
+	r.count++
+	r.sum += float64(lag)
+	if r.count == 1 || lag < r.min {
+		r.min = lag
+	}
+	if r.count == 1 || lag > r.max {
+		r.max = lag
+	}
+	// Reservoir sampling keeps each lag with the same probability.
+	if len(r.samples) < lagSamples {
+		r.samples = append(r.samples, lag)
+	} else if i := r.rand.IntN(r.count); i < lagSamples {
+		r.samples[i] = lag
+	}
+}
+
+// stats returns the statistics of the lags added, nil if none were measured.
+func (r *lagRecorder) stats() *LagStats {
+	if r == nil || r.count+r.unmeasured == 0 {
+		return nil
+	}
+	stats := &LagStats{Count: r.count, Unmeasured: r.unmeasured}
+	if r.count > 0 {
+		stats.Mean = time.Duration(r.sum / float64(r.count))
+		stats.LatencyDistribution = latencyDistribution(append([]time.Duration(nil), r.samples...))
+		stats.Min, stats.Max = r.min, r.max
+	}
+	return stats
+}
+
+// arrived notes when a key was parked, for lags measured by arrival.
+func (c *StreamComparator) arrived(key string) {
+	if c.arrivals != nil {
+		c.arrivals[key] = c.activeFor(c.clock.Now())
+	}
+}
+
+// measureLag records the lag of key, matched by rec from side with its
+// counterpart.
+func (c *StreamComparator) measureLag(key string, rec1, rec2 datareader.Record, side Side) {
+	if c.lag == nil {
+		return
+	}
+	var lag time.Duration
+	if field := c.lag.TimestampField; field != "" {
+		t1, err1 := parseTimestamp(rec1[field])
+		t2, err2 := parseTimestamp(rec2[field])
+		if err1 != nil || err2 != nil {
+			c.lagTotal.unmeasured++
+			c.lagInterval.unmeasured++
+			return
+		}
+		lag = t2.Sub(t1)
+	} else {
+		arrival, ok := c.arrivals[key]
+		if !ok {
+			return
+		}
+		delete(c.arrivals, key)
+		lag = c.activeFor(c.clock.Now()) - arrival
+		if side == Source1 {
+			lag = -lag
+		}
+	}
+	c.lagTotal.add(lag)
+	c.lagInterval.add(lag)
+}
+
+// resetLag starts measuring afresh for a new comparison.
+func (c *StreamComparator) resetLag() {
+	c.lagTotal, c.lagInterval, c.arrivals = nil, nil, nil
+	if c.lag == nil {
+		return
+	}
+	c.lagTotal, c.lagInterval = newLagRecorder(), newLagRecorder()
+	if c.lag.TimestampField == "" {
+		c.arrivals = make(map[string]time.Duration)
+	}
+}
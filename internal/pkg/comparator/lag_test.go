@@ -0,0 +1,88 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLag_Timestamps(t *testing.T) {
+	c := New("id")
+	c.SetLag(&LagOptions{TimestampField: "updated_at"})
+	records := []struct {
+		side Side
+		rec  datareader.Record
+	}{
+		{Source1, datareader.Record{"id": "a", "updated_at": "2025-09-10T12:00:00Z"}},
+		{Source2, datareader.Record{"id": "a", "updated_at": "2025-09-10T12:00:03Z"}},
+		{Source2, datareader.Record{"id": "b", "updated_at": 1757505601.5}},
+		{Source1, datareader.Record{"id": "b", "updated_at": time.Date(2025, 9, 10, 12, 0, 0, 0, time.UTC)}},
+		{Source1, datareader.Record{"id": "c"}},
+		{Source2, datareader.Record{"id": "c", "updated_at": "2025-09-10T12:00:00Z"}},
+		{Source1, datareader.Record{"id": "d", "updated_at": "2025-09-10T12:00:00Z"}},
+	}
+	for _, r := range records {
+		if err := c.Add(r.side, r.rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := &LagStats{
+		Count: 2, Unmeasured: 1, Mean: 2250 * time.Millisecond,
+		LatencyDistribution: LatencyDistribution{Min: 1500 * time.Millisecond, P50: 1500 * time.Millisecond, P90: 3 * time.Second, P99: 3 * time.Second, Max: 3 * time.Second},
+	}
+	if got := c.Finish().Lag; !reflect.DeepEqual(got, want) {
+		t.Errorf("Lag got = %+v, want %+v", got, want)
+	}
+}
+
+func TestLag_ArrivalHeartbeats(t *testing.T) {
+	clock := NewManualClock(time.Date(2025, 9, 10, 12, 0, 0, 0, time.UTC))
+	var lags []*LagStats
+	c := New("id")
+	c.SetClock(clock)
+	c.SetLag(&LagOptions{})
+	c.SetProgressInterval(time.Minute)
+	c.SetHooks(Hooks{OnHeartbeat: func(h Heartbeat) { lags = append(lags, h.Lag) }})
+
+	add := func(side Side, id string) {
+		t.Helper()
+		if err := c.Add(side, datareader.Record{"id": id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add(Source1, "a")
+	add(Source2, "b")
+	clock.Advance(10 * time.Second)
+	// Source2 got a 10s after source1, and b 10s before it.
+	add(Source2, "a")
+	add(Source1, "b")
+	clock.Advance(time.Minute)
+	add(Source1, "c")
+	clock.Advance(time.Minute)
+	add(Source1, "d")
+	result := c.Finish()
+
+	first := LatencyDistribution{Min: -10 * time.Second, P50: -10 * time.Second, P90: 10 * time.Second, P99: 10 * time.Second, Max: 10 * time.Second}
+	want := []*LagStats{{Count: 2, LatencyDistribution: first}, nil}
+	if !reflect.DeepEqual(lags, want) {
+		t.Errorf("Heartbeat lags got = %+v, want %+v", lags, want)
+	}
+	if !reflect.DeepEqual(result.Lag, want[0]) {
+		t.Errorf("Lag got = %+v, want %+v", result.Lag, want[0])
+	}
+}
+
+func TestLagRecorder_Samples(t *testing.T) {
+	r := newLagRecorder()
+	for i := 1; i <= 3*lagSamples; i++ {
+		r.add(time.Duration(i) * time.Millisecond)
+	}
+	stats := r.stats()
+	if stats.Count != 3*lagSamples || stats.Min != time.Millisecond || stats.Max != 3*lagSamples*time.Millisecond || len(r.samples) != lagSamples {
+		t.Errorf("stats() got = %+v with %d samples, want exact count, min and max over %d samples", stats, len(r.samples), lagSamples)
+	}
+	if p50 := stats.P50; p50 < 13*time.Second || p50 > 17*time.Second {
+		t.Errorf("stats() P50 got = %v, want about 15s", p50)
+	}
+}
@@ -0,0 +1,221 @@
+package comparator
+
+import (
+	"crypto/sha256"
+	"data-comparator/internal/pkg/datareader"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+// Limits bound the size of single records, so very wide or deeply nested
+// records, e.g. exploded JSON, cannot grow the comparison without bound.
+// Records over a limit are truncated and reported; zero disables a limit.
+type Limits struct {
+	// MaxFields is the number of top-level fields kept per record. The key
+	// field is always kept; of the others, the first in name order are.
+	MaxFields int
+	// MaxDepth is the nesting depth of values kept, top-level fields being at
+	// depth 1. Deeper values are replaced by a digest of their contents.
+	MaxDepth int
+	// MaxValueSize is the number of bytes kept of string values. Longer
+	// values are cut and end in a digest of the whole value, so values that
+	// differ after the cut still compare as different.
+	MaxValueSize int
+}
+
+// Truncation is a part of a record dropped or cut to keep within Limits.
+type Truncation struct {
+	// Record is the number of the record within its source.
+	Record int `yaml:"record" json:"record"`
+	// Field is the dotted name of the field truncated; empty for dropped fields.
+	Field string `yaml:"field,omitempty" json:"field,omitempty"`
+	// Limit is max_fields, max_depth or max_value_size.
+	Limit   string `yaml:"limit" json:"limit"`
+	Message string `yaml:"message" json:"message"`
+}
+
+// Truncations lists the first truncations of each source.
+type Truncations struct {
+	Source1 []Truncation `yaml:"source1"`
+	Source2 []Truncation `yaml:"source2"`
+}
+
+func (l Limits) enabled() bool {
+	return l.MaxFields > 0 || l.MaxDepth > 0 || l.MaxValueSize > 0
+}
+
+// apply returns rec within the limits and the truncations made. rec itself is
+// left untouched; a copy is returned when anything was truncated.
+func (l Limits) apply(rec datareader.Record, keyField string) (datareader.Record, []Truncation) {
+	if !l.enabled() {
+		return rec, nil
+	}
+	var truncations []Truncation
+	out := rec
+	copied := false
+	set := func(field string, v interface{}) {
+		if !copied {
+			out = make(datareader.Record, len(rec))
+			for k, v := range rec {
+				out[k] = v
+			}
+			copied = true
+		}
+		out[field] = v
+	}
+
+	if l.MaxFields > 0 && len(rec) > l.MaxFields {
+		names := make([]string, 0, len(rec))
+		for name := range rec {
+			if name != keyField {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		keep := l.MaxFields
+		if _, ok := rec[keyField]; ok {
+			keep--
+		}
+		out = make(datareader.Record, l.MaxFields)
+		if v, ok := rec[keyField]; ok {
+			out[keyField] = v
+		}
+		for _, name := range names[:keep] {
+			out[name] = rec[name]
+		}
+		copied = true
+		truncations = append(truncations, Truncation{
+			Limit:   "max_fields",
+			Message: fmt.Sprintf("kept %d of %d fields", l.MaxFields, len(rec)),
+		})
+	}
+
+	for name, v := range out {
+		if limited, t := l.value(name, v, 1); t != nil {
+			set(name, limited)
+			truncations = append(truncations, t...)
+		}
+	}
+	sort.SliceStable(truncations, func(i, j int) bool { return truncations[i].Field < truncations[j].Field })
+	return out, truncations
+}
+
+// value limits v, found at field and depth, returning nil truncations when
+// v is within the limits.
+func (l Limits) value(field string, v interface{}, depth int) (interface{}, []Truncation) {
+	switch val := v.(type) {
+	case string:
+		if l.MaxValueSize <= 0 || len(val) <= l.MaxValueSize {
+			return v, nil
+		}
+		cut := l.MaxValueSize
+		for cut > 0 && !utf8.RuneStart(val[cut]) {
+			cut--
+		}
+		return fmt.Sprintf("%s...[%d bytes, sha256 %s]", val[:cut], len(val), digest([]byte(val))), []Truncation{{
+			Field:   field,
+			Limit:   "max_value_size",
+			Message: fmt.Sprintf("value of %d bytes cut to %d", len(val), l.MaxValueSize),
+		}}
+	case map[string]interface{}:
+		if l.MaxDepth > 0 && depth >= l.MaxDepth {
+			return tooDeep(field, v, l.MaxDepth)
+		}
+		var truncations []Truncation
+		var out map[string]interface{}
+		for k, elem := range val {
+			limited, t := l.value(field+"."+k, elem, depth+1)
+			if t == nil {
+				continue
+			}
+			if out == nil {
+				out = make(map[string]interface{}, len(val))
+				for k, elem := range val {
+					out[k] = elem
+				}
+			}
+			out[k] = limited
+			truncations = append(truncations, t...)
+		}
+		if out == nil {
+			return v, nil
+		}
+		return out, truncations
+	case []interface{}:
+		if l.MaxDepth > 0 && depth >= l.MaxDepth {
+			return tooDeep(field, v, l.MaxDepth)
+		}
+		var truncations []Truncation
+		var out []interface{}
+		for i, elem := range val {
+			limited, t := l.value(fmt.Sprintf("%s.%d", field, i), elem, depth+1)
+			if t == nil {
+				continue
+			}
+			if out == nil {
+				out = append([]interface{}(nil), val...)
+			}
+			out[i] = limited
+			truncations = append(truncations, t...)
+		}
+		if out == nil {
+			return v, nil
+		}
+		return out, truncations
+	}
+	return v, nil
+}
+
+// tooDeep replaces a container nested beyond maxDepth by a digest of its JSON.
+func tooDeep(field string, v interface{}, maxDepth int) (interface{}, []Truncation) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data = []byte(fmt.Sprintf("%v", v))
+	}
+	return fmt.Sprintf("[nested value, sha256 %s]", digest(data)), []Truncation{{
+		Field:   field,
+		Limit:   "max_depth",
+		Message: fmt.Sprintf("value nested deeper than %d levels replaced by its digest", maxDepth),
+	}}
+}
+
+// digest is a short SHA-256 of data, enough to tell truncated values apart.
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// limit applies the record limits to the n-th record of side, keeping the
+// first truncations in the Result.
+func (c *StreamComparator) limit(side Side, rec datareader.Record, n int) datareader.Record {
+	keyField := c.key1
+	if side == Source2 {
+		keyField = c.key2
+	}
+	rec, truncations := c.options.Limits.apply(rec, keyField)
+	if len(truncations) == 0 {
+		return rec
+	}
+	if c.result.Truncations == nil {
+		c.result.Truncations = &Truncations{}
+	}
+
+	count, kept := &c.result.Summary.Source1TruncatedRecords, &c.result.Truncations.Source1
+	if side == Source2 {
+		count, kept = &c.result.Summary.Source2TruncatedRecords, &c.result.Truncations.Source2
+	}
+	*count++
+	for i := range truncations {
+		truncations[i].Record = n
+		if len(*kept) < MaxViolations {
+			*kept = append(*kept, truncations[i])
+		}
+	}
+	if c.hooks.OnTruncated != nil {
+		c.hooks.OnTruncated(side, n, truncations)
+	}
+	return rec
+}
@@ -0,0 +1,93 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"strings"
+	"testing"
+)
+
+func TestLimits_Apply(t *testing.T) {
+	rec := datareader.Record{
+		"id": "1", "a": "x", "b": "y", "c": "z",
+		"nested": map[string]interface{}{"deep": map[string]interface{}{"v": 1}},
+		"text":   "héllo world",
+	}
+
+	t.Run("max fields keeps key and first names", func(t *testing.T) {
+		got, truncations := Limits{MaxFields: 3}.apply(rec, "id")
+		if len(got) != 3 || got["id"] != "1" || got["a"] != "x" || got["b"] != "y" {
+			t.Errorf("apply() got = %v, want id, a and b", got)
+		}
+		if len(truncations) != 1 || truncations[0].Limit != "max_fields" {
+			t.Errorf("apply() truncations got = %+v, want one max_fields", truncations)
+		}
+		if len(rec) != 6 {
+			t.Errorf("apply() modified its input: %v", rec)
+		}
+	})
+
+	t.Run("max depth replaces deeper values by a digest", func(t *testing.T) {
+		got, truncations := Limits{MaxDepth: 2}.apply(rec, "id")
+		deep := got["nested"].(map[string]interface{})["deep"]
+		if s, ok := deep.(string); !ok || !strings.HasPrefix(s, "[nested value, sha256 ") {
+			t.Errorf("apply() nested.deep got = %v, want a digest", deep)
+		}
+		if len(truncations) != 1 || truncations[0].Field != "nested.deep" || truncations[0].Limit != "max_depth" {
+			t.Errorf("apply() truncations got = %+v, want max_depth of nested.deep", truncations)
+		}
+		if _, ok := rec["nested"].(map[string]interface{})["deep"].(map[string]interface{}); !ok {
+			t.Errorf("apply() modified its input: %v", rec)
+		}
+	})
+
+	t.Run("max value size cuts on a rune boundary", func(t *testing.T) {
+		got, truncations := Limits{MaxValueSize: 2}.apply(rec, "id")
+		if s := got["text"].(string); !strings.HasPrefix(s, "h...[12 bytes, sha256 ") {
+			t.Errorf("apply() text got = %q", s)
+		}
+		if len(truncations) != 1 || truncations[0].Field != "text" {
+			t.Errorf("apply() truncations got = %+v, want max_value_size of text", truncations)
+		}
+	})
+
+	t.Run("records within limits are returned as is", func(t *testing.T) {
+		got, truncations := Limits{MaxFields: 10, MaxDepth: 5, MaxValueSize: 100}.apply(rec, "id")
+		if truncations != nil || len(got) != len(rec) {
+			t.Errorf("apply() got = %v, %+v, want the record unchanged", got, truncations)
+		}
+	})
+}
+
+func TestCompare_Limits(t *testing.T) {
+	long1, long2 := strings.Repeat("a", 100)+"1", strings.Repeat("a", 100)+"2"
+	records1 := []datareader.Record{{"id": "1", "v": long1}, {"id": "2", "v": "same"}}
+	records2 := []datareader.Record{{"id": "1", "v": long2}, {"id": "2", "v": "same"}}
+
+	c := New("id")
+	options := DefaultOptions()
+	options.Limits = Limits{MaxValueSize: 10}
+	c.SetOptions(options)
+	var truncated []TruncatedRecord
+	for f := range c.Findings(datareader.NewSliceReader(records1), datareader.NewSliceReader(records2)) {
+		if tr, ok := f.(TruncatedRecord); ok {
+			truncated = append(truncated, tr)
+		}
+	}
+	if len(truncated) != 2 || truncated[0].Record != 1 || truncated[0].Truncations[0].Field != "v" {
+		t.Errorf("TruncatedRecord findings got = %+v, want record 1 of each source", truncated)
+	}
+
+	result, err := c.Compare(datareader.NewSliceReader(records1), datareader.NewSliceReader(records2))
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	if result.Summary.IdenticalRows != 1 || len(result.ValueDiffs["1"]) != 1 {
+		t.Errorf("Values differing after the cut should still differ, got %+v", result.Summary)
+	}
+	if result.Summary.Source1TruncatedRecords != 1 || result.Summary.Source2TruncatedRecords != 1 {
+		t.Errorf("Truncated record counts got = %+v, want 1 per source", result.Summary)
+	}
+	if result.Truncations == nil || len(result.Truncations.Source1) != 1 {
+		t.Errorf("Truncations got = %+v, want one in source1", result.Truncations)
+	}
+}
@@ -0,0 +1,38 @@
+package comparator
+
+import "data-comparator/internal/pkg/datareader"
+
+// SetLookup makes source2 a static, keyed snapshot that source1, a stream
+// that need not end, is verified against. Compare and Findings then read the
+// snapshot whole into memory before the stream, and compare every stream
+// record with the snapshot record of its key as soon as it is read, so
+// OnMatch, OnDiff and OnOnlyInSource1 fire right away. A key may come again
+// in the stream and is verified again, against the same snapshot record.
+// Snapshot keys the stream never had are reported as only in source2 once it
+// ends. Records are not spilled in lookup mode.
+func (c *StreamComparator) SetLookup(on bool) {
+	c.lookup = on
+}
+
+// verify compares rec, a record of the stream, with the snapshot record of
+// its key.
+func (c *StreamComparator) verify(key string, rec datareader.Record) {
+	snapshot, ok := c.pending2[key]
+	if ok {
+		// Snapshot records move out of pending2 once matched, so that the
+		// keys left there are those the stream never had.
+		delete(c.pending2, key)
+		c.verified[key] = snapshot
+	} else {
+		snapshot, ok = c.verified[key]
+	}
+	if ok {
+		c.matched(key, rec, snapshot, Source1)
+		return
+	}
+
+	if _, reported := c.pending1[key]; !reported && c.hooks.OnOnlyInSource1 != nil {
+		c.hooks.OnOnlyInSource1(key, rec)
+	}
+	c.pending1[key] = rec
+}
@@ -0,0 +1,70 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestCompare_Lookup(t *testing.T) {
+	snapshot := datareader.NewSliceReader([]datareader.Record{
+		{"id": "a", "rate": 1},
+		{"id": "b", "rate": 2},
+		{"id": "c", "rate": 3},
+	})
+	// The stream repeats keys and has not ended when its records are verified.
+	stream := newHangingReader(t,
+		datareader.Record{"id": "a", "rate": 1},
+		datareader.Record{"id": "b", "rate": 5},
+		datareader.Record{"id": "x", "rate": 1},
+		datareader.Record{"id": "a", "rate": 4},
+		datareader.Record{"id": "x", "rate": 1},
+	)
+	blocked := stream.blocked
+
+	var mu sync.Mutex
+	var events []string
+	note := func(event string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}
+	c := New("id")
+	c.SetLookup(true)
+	c.SetHooks(Hooks{
+		OnMatch:         func(key string, rec1, rec2 datareader.Record) { note("match " + key) },
+		OnDiff:          func(key string, diffs []FieldDiff, rec1, rec2 datareader.Record) { note("diff " + key) },
+		OnOnlyInSource1: func(key string, rec datareader.Record) { note("only1 " + key) },
+		OnOnlyInSource2: func(key string, rec datareader.Record) { note("only2 " + key) },
+	})
+
+	done := make(chan *Result)
+	go func() {
+		result, err := c.Compare(stream, snapshot)
+		if err != nil {
+			t.Errorf("Compare() error = %v", err)
+		}
+		done <- result
+	}()
+
+	<-blocked
+	mu.Lock()
+	want := []string{"match a", "diff b", "only1 x", "diff a"}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events before the stream ends got = %v, want %v", events, want)
+	}
+	mu.Unlock()
+	close(stream.release)
+	result := <-done
+
+	if want := append(want, "only2 c"); !reflect.DeepEqual(events, want) {
+		t.Errorf("events got = %v, want %v", events, want)
+	}
+	if s := result.Summary; s.MatchingKeys != 3 || s.IdenticalRows != 1 || s.KeysOnlyInSource1 != 1 || s.KeysOnlyInSource2 != 1 {
+		t.Errorf("Summary got = %+v, want 3 matches, 1 identical, x only in source1 and c only in source2", s)
+	}
+	if !reflect.DeepEqual(result.KeysOnly.InSource2, []string{"c"}) {
+		t.Errorf("KeysOnly.InSource2 got = %v, want [c]", result.KeysOnly.InSource2)
+	}
+}
@@ -0,0 +1,207 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// MultisetResult holds the outcome of comparing two sources as multisets of
+// whole records, ignoring order and without a key.
+type MultisetResult struct {
+	Summary         MultisetSummary `yaml:"summary"`
+	OnlyInSource1   []RecordCount   `yaml:"only_in_source1"`
+	OnlyInSource2   []RecordCount   `yaml:"only_in_source2"`
+	CountMismatches []CountMismatch `yaml:"count_mismatches"`
+	FuzzyMatches    []FuzzyMatch    `yaml:"fuzzy_matches,omitempty"`
+}
+
+// MultisetSummary holds the record counts of a multiset comparison.
+type MultisetSummary struct {
+	Source1Rows       int `yaml:"source1_rows"`
+	Source2Rows       int `yaml:"source2_rows"`
+	DistinctRecords   int `yaml:"distinct_records"`
+	MatchingRecords   int `yaml:"matching_records"`
+	OnlyInSource1     int `yaml:"only_in_source1"`
+	OnlyInSource2     int `yaml:"only_in_source2"`
+	CountMismatchRows int `yaml:"count_mismatches"`
+	FuzzyMatches      int `yaml:"fuzzy_matches,omitempty"`
+}
+
+// RecordCount is a distinct record and how many times it occurred.
+type RecordCount struct {
+	Hash   string            `yaml:"hash"`
+	Count  int               `yaml:"count"`
+	Record datareader.Record `yaml:"record"`
+}
+
+// CountMismatch is a record present in both sources a different number of times.
+type CountMismatch struct {
+	Hash         string            `yaml:"hash"`
+	Source1Count int               `yaml:"source1_count"`
+	Source2Count int               `yaml:"source2_count"`
+	Record       datareader.Record `yaml:"record"`
+}
+
+// FuzzyMatch pairs records only in one source each that agree on the fuzzy
+// fields, with the differences of their other fields.
+type FuzzyMatch struct {
+	Source1Hash string      `yaml:"source1_hash"`
+	Source2Hash string      `yaml:"source2_hash"`
+	Count       int         `yaml:"count"`
+	Differences []FieldDiff `yaml:"differences"`
+}
+
+type multisetEntry struct {
+	record datareader.Record
+	count1 int
+	count2 int
+}
+
+// CompareMultiset reads both sources fully and compares them as multisets of
+// records identified by their canonical hash under options. Only one example of
+// each distinct record is kept in memory. With fuzzyFields, records only in
+// one source are paired with those only in the other that have the same
+// values of these top-level fields, and reported as fuzzy matches instead.
+func CompareMultiset(reader1, reader2 datareader.DataReader, options Options, fuzzyFields ...string) (*MultisetResult, error) {
+	entries := make(map[string]*multisetEntry)
+	result := &MultisetResult{}
+
+	count := func(reader datareader.DataReader, side Side) error {
+		for {
+			rec, err := reader.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read from %s: %w", side, err)
+			}
+
+			hash, err := options.Hash(rec)
+			if err != nil {
+				return fmt.Errorf("%s: %w", side, err)
+			}
+			entry, ok := entries[hash]
+			if !ok {
+				entry = &multisetEntry{record: rec}
+				entries[hash] = entry
+			}
+			if side == Source1 {
+				entry.count1++
+				result.Summary.Source1Rows++
+			} else {
+				entry.count2++
+				result.Summary.Source2Rows++
+			}
+		}
+	}
+	if err := count(reader1, Source1); err != nil {
+		return nil, err
+	}
+	if err := count(reader2, Source2); err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, 0, len(entries))
+	for hash := range entries {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+
+	result.Summary.DistinctRecords = len(entries)
+	for _, hash := range hashes {
+		entry := entries[hash]
+		switch {
+		case entry.count2 == 0:
+			result.OnlyInSource1 = append(result.OnlyInSource1, RecordCount{Hash: hash, Count: entry.count1, Record: entry.record})
+		case entry.count1 == 0:
+			result.OnlyInSource2 = append(result.OnlyInSource2, RecordCount{Hash: hash, Count: entry.count2, Record: entry.record})
+		case entry.count1 != entry.count2:
+			result.CountMismatches = append(result.CountMismatches, CountMismatch{
+				Hash: hash, Source1Count: entry.count1, Source2Count: entry.count2, Record: entry.record,
+			})
+			result.Summary.MatchingRecords += min(entry.count1, entry.count2)
+			result.Summary.CountMismatchRows++
+		default:
+			result.Summary.MatchingRecords += entry.count1
+		}
+	}
+
+	if len(fuzzyFields) > 0 {
+		if err := matchFuzzy(result, options, fuzzyFields); err != nil {
+			return nil, err
+		}
+	}
+	for _, only := range result.OnlyInSource1 {
+		result.Summary.OnlyInSource1 += only.Count
+	}
+	for _, only := range result.OnlyInSource2 {
+		result.Summary.OnlyInSource2 += only.Count
+	}
+	return result, nil
+}
+
+// matchFuzzy pairs the records only in source1 with those only in source2
+// agreeing on fields, in hash order, and takes the paired counts off the
+// only-in lists.
+func matchFuzzy(result *MultisetResult, options Options, fields []string) error {
+	project := func(rec datareader.Record) (string, error) {
+		projected := make(datareader.Record, len(fields))
+		for _, field := range fields {
+			projected[field] = rec[field]
+		}
+		return options.Hash(projected)
+	}
+
+	candidates := make(map[string][]*RecordCount)
+	for i := range result.OnlyInSource1 {
+		only := &result.OnlyInSource1[i]
+		projection, err := project(only.Record)
+		if err != nil {
+			return fmt.Errorf("%s: %w", Source1, err)
+		}
+		candidates[projection] = append(candidates[projection], only)
+	}
+	for i := range result.OnlyInSource2 {
+		only2 := &result.OnlyInSource2[i]
+		projection, err := project(only2.Record)
+		if err != nil {
+			return fmt.Errorf("%s: %w", Source2, err)
+		}
+		for _, only1 := range candidates[projection] {
+			if only2.Count == 0 {
+				break
+			}
+			if only1.Count == 0 {
+				continue
+			}
+			n := min(only1.Count, only2.Count)
+			result.FuzzyMatches = append(result.FuzzyMatches, FuzzyMatch{
+				Source1Hash: only1.Hash,
+				Source2Hash: only2.Hash,
+				Count:       n,
+				Differences: compareRecords(only1.Record, only2.Record, options),
+			})
+			result.Summary.FuzzyMatches += n
+			only1.Count -= n
+			only2.Count -= n
+		}
+	}
+
+	result.OnlyInSource1 = withoutMatched(result.OnlyInSource1)
+	result.OnlyInSource2 = withoutMatched(result.OnlyInSource2)
+	return nil
+}
+
+// withoutMatched drops the records of counts whose every occurrence was
+// fuzzily matched.
+func withoutMatched(counts []RecordCount) []RecordCount {
+	var left []RecordCount
+	for _, count := range counts {
+		if count.Count > 0 {
+			left = append(left, count)
+		}
+	}
+	return left
+}
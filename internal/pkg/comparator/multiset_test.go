@@ -0,0 +1,97 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"reflect"
+	"testing"
+)
+
+func TestCompareMultiset(t *testing.T) {
+	reader1 := datareader.NewSliceReader([]datareader.Record{
+		{"name": "alice", "city": "Paris"},
+		{"name": "bob", "city": "Rome"},
+		{"name": "bob", "city": "Rome"},
+		{"name": "carol", "city": "Oslo"},
+	})
+	reader2 := datareader.NewSliceReader([]datareader.Record{
+		{"city": "Rome", "name": "bob"},
+		{"city": "Paris", "name": "alice"},
+		{"name": "dave", "city": "Lima"},
+	})
+
+	result, err := CompareMultiset(reader1, reader2, DefaultOptions())
+	if err != nil {
+		t.Fatalf("CompareMultiset() error = %v", err)
+	}
+
+	expected := MultisetSummary{
+		Source1Rows:       4,
+		Source2Rows:       3,
+		DistinctRecords:   4,
+		MatchingRecords:   2,
+		OnlyInSource1:     1,
+		OnlyInSource2:     1,
+		CountMismatchRows: 1,
+	}
+	if result.Summary != expected {
+		t.Errorf("Summary got = %+v, want %+v", result.Summary, expected)
+	}
+	if len(result.OnlyInSource1) != 1 || result.OnlyInSource1[0].Record["name"] != "carol" {
+		t.Errorf("OnlyInSource1 got = %v, want carol", result.OnlyInSource1)
+	}
+	if len(result.OnlyInSource2) != 1 || result.OnlyInSource2[0].Record["name"] != "dave" {
+		t.Errorf("OnlyInSource2 got = %v, want dave", result.OnlyInSource2)
+	}
+	if len(result.CountMismatches) != 1 || result.CountMismatches[0].Source1Count != 2 || result.CountMismatches[0].Source2Count != 1 {
+		t.Errorf("CountMismatches got = %v, want bob 2 vs 1", result.CountMismatches)
+	}
+}
+
+func TestCompareMultiset_FuzzyFields(t *testing.T) {
+	reader1 := datareader.NewSliceReader([]datareader.Record{
+		{"name": "alice", "city": "Paris", "age": 30.0},
+		{"name": "bob", "city": "Rome", "age": 40.0},
+		{"name": "bob", "city": "Rome", "age": 40.0},
+		{"name": "carol", "city": "Oslo", "age": 50.0},
+	})
+	reader2 := datareader.NewSliceReader([]datareader.Record{
+		{"name": "alice", "city": "Paris", "age": 30.0},
+		{"name": "bob", "city": "Rome", "age": 41.0},
+		{"name": "dave", "city": "Lima", "age": 60.0},
+	})
+
+	result, err := CompareMultiset(reader1, reader2, DefaultOptions(), "name", "city")
+	if err != nil {
+		t.Fatalf("CompareMultiset() error = %v", err)
+	}
+
+	expected := MultisetSummary{
+		Source1Rows:     4,
+		Source2Rows:     3,
+		DistinctRecords: 5,
+		MatchingRecords: 1,
+		OnlyInSource1:   2,
+		OnlyInSource2:   1,
+		FuzzyMatches:    1,
+	}
+	if result.Summary != expected {
+		t.Errorf("Summary got = %+v, want %+v", result.Summary, expected)
+	}
+	if len(result.FuzzyMatches) != 1 {
+		t.Fatalf("FuzzyMatches got = %v, want one", result.FuzzyMatches)
+	}
+	match := result.FuzzyMatches[0]
+	if match.Count != 1 || len(match.Differences) != 1 || match.Differences[0].Field != "age" {
+		t.Errorf("FuzzyMatches got = %+v, want bob once, differing in age", match)
+	}
+	counts := make(map[interface{}]int)
+	for _, only := range result.OnlyInSource1 {
+		counts[only.Record["name"]] = only.Count
+	}
+	if want := map[interface{}]int{"bob": 1, "carol": 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("OnlyInSource1 got = %v, want %v", counts, want)
+	}
+	if len(result.OnlyInSource2) != 1 || result.OnlyInSource2[0].Record["name"] != "dave" {
+		t.Errorf("OnlyInSource2 got = %v, want dave", result.OnlyInSource2)
+	}
+}
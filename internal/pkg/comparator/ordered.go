@@ -0,0 +1,212 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"fmt"
+	"io"
+)
+
+// DefaultResyncWindow is how many records ahead an ordered comparison looks
+// in each source to resynchronize after a mismatch.
+const DefaultResyncWindow = 100
+
+// MaxOrderedFindings is the number of mismatches, insertions and deletions
+// each listed by an ordered comparison. All of them are counted in the
+// Summary.
+const MaxOrderedFindings = 1000
+
+// OrderedOptions configure an ordered comparison.
+type OrderedOptions struct {
+	// Window is how many records ahead each source is searched for the
+	// next pair of equal records after a mismatch. DefaultResyncWindow is
+	// used if not positive.
+	Window int
+	// Equality controls how records are canonicalized and compared.
+	// DefaultOptions are used if nil.
+	Equality *Options
+}
+
+// OrderedResult holds the outcome of an ordered comparison.
+type OrderedResult struct {
+	Summary    OrderedSummary       `yaml:"summary"`
+	Mismatches []PositionalMismatch `yaml:"mismatches"`
+	// Insertions are records only in source2, Deletions records only in
+	// source1, at their position in the source.
+	Insertions []PositionedRecord `yaml:"insertions"`
+	Deletions  []PositionedRecord `yaml:"deletions"`
+}
+
+// OrderedSummary holds the totals of an ordered comparison.
+type OrderedSummary struct {
+	Source1Rows int `yaml:"source1_rows"`
+	Source2Rows int `yaml:"source2_rows"`
+	Matched     int `yaml:"matched"`
+	Mismatched  int `yaml:"mismatched"`
+	Inserted    int `yaml:"inserted"`
+	Deleted     int `yaml:"deleted"`
+}
+
+// PositionalMismatch is a pair of records at corresponding positions of the
+// sources that differ. Positions count records from 1.
+type PositionalMismatch struct {
+	Source1Position int         `yaml:"source1_position"`
+	Source2Position int         `yaml:"source2_position"`
+	Differences     []FieldDiff `yaml:"differences"`
+}
+
+// PositionedRecord is a record and its position in its source, counted from 1.
+type PositionedRecord struct {
+	Position int               `yaml:"position"`
+	Record   datareader.Record `yaml:"record"`
+}
+
+type orderedRecord struct {
+	position int
+	record   datareader.Record
+	hash     string
+}
+
+type orderedDiff struct {
+	options Options
+	result  *OrderedResult
+	buf1    []orderedRecord
+	buf2    []orderedRecord
+	done1   bool
+	done2   bool
+}
+
+// CompareOrdered compares record N of source1 with record N of source2, like
+// a line diff. After a mismatch it looks up to the window ahead in both
+// sources for the nearest pair of equal records to resynchronize on; the
+// records skipped to reach it are paired as mismatches, and those left over
+// on one side are reported as insertions or deletions. Only the window is
+// kept in memory.
+func CompareOrdered(reader1, reader2 datareader.DataReader, opts OrderedOptions) (*OrderedResult, error) {
+	window := opts.Window
+	if window <= 0 {
+		window = DefaultResyncWindow
+	}
+	d := &orderedDiff{options: DefaultOptions(), result: &OrderedResult{}}
+	if opts.Equality != nil {
+		d.options = *opts.Equality
+	}
+
+	for {
+		if err := d.fill(reader1, Source1, window+1); err != nil {
+			return nil, err
+		}
+		if err := d.fill(reader2, Source2, window+1); err != nil {
+			return nil, err
+		}
+
+		switch {
+		case len(d.buf1) == 0 && len(d.buf2) == 0:
+			return d.result, nil
+		case len(d.buf1) == 0:
+			d.skip(0, 1)
+		case len(d.buf2) == 0:
+			d.skip(1, 0)
+		case d.buf1[0].hash == d.buf2[0].hash:
+			d.result.Summary.Matched++
+			d.buf1, d.buf2 = d.buf1[1:], d.buf2[1:]
+		default:
+			i, j, ok := d.resync()
+			if !ok {
+				i, j = 1, 1
+			}
+			d.skip(i, j)
+		}
+	}
+}
+
+// fill reads records of side until its buffer holds n or the source ends.
+func (d *orderedDiff) fill(reader datareader.DataReader, side Side, n int) error {
+	buf, done, rows := &d.buf1, &d.done1, &d.result.Summary.Source1Rows
+	if side == Source2 {
+		buf, done, rows = &d.buf2, &d.done2, &d.result.Summary.Source2Rows
+	}
+	for !*done && len(*buf) < n {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			*done = true
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read from %s: %w", side, err)
+		}
+		*rows++
+		hash, err := d.options.Hash(rec)
+		if err != nil {
+			return fmt.Errorf("%s record %d: %w", side, *rows, err)
+		}
+		*buf = append(*buf, orderedRecord{position: *rows, record: rec, hash: hash})
+	}
+	return nil
+}
+
+// resync returns how many records of each buffer precede the nearest pair
+// of equal records, the fewest in total, and of those the most evenly
+// split, so a changed record reads as a mismatch rather than a deletion and
+// an insertion.
+func (d *orderedDiff) resync() (int, int, bool) {
+	for total := 1; total <= len(d.buf1)+len(d.buf2)-2; total++ {
+		best, found := 0, false
+		for i := max(0, total-len(d.buf2)+1); i <= total && i < len(d.buf1); i++ {
+			j := total - i
+			if d.buf1[i].hash != d.buf2[j].hash {
+				continue
+			}
+			if !found || absInt(i-j) < absInt(best-(total-best)) {
+				best, found = i, true
+			}
+		}
+		if found {
+			return best, total - best, true
+		}
+	}
+	return 0, 0, false
+}
+
+// skip consumes i records of source1 and j of source2, pairing them as
+// mismatches and reporting those left over as deletions or insertions.
+func (d *orderedDiff) skip(i, j int) {
+	summary := &d.result.Summary
+	paired := min(i, j)
+	for k := 0; k < paired; k++ {
+		rec1, rec2 := d.buf1[k], d.buf2[k]
+		diffs := compareRecords(rec1.record, rec2.record, d.options)
+		if len(diffs) == 0 {
+			// Equal within tolerance, though hashed apart.
+			summary.Matched++
+			continue
+		}
+		summary.Mismatched++
+		if len(d.result.Mismatches) < MaxOrderedFindings {
+			d.result.Mismatches = append(d.result.Mismatches, PositionalMismatch{
+				Source1Position: rec1.position,
+				Source2Position: rec2.position,
+				Differences:     diffs,
+			})
+		}
+	}
+	for _, rec := range d.buf1[paired:i] {
+		summary.Deleted++
+		if len(d.result.Deletions) < MaxOrderedFindings {
+			d.result.Deletions = append(d.result.Deletions, PositionedRecord{Position: rec.position, Record: rec.record})
+		}
+	}
+	for _, rec := range d.buf2[paired:j] {
+		summary.Inserted++
+		if len(d.result.Insertions) < MaxOrderedFindings {
+			d.result.Insertions = append(d.result.Insertions, PositionedRecord{Position: rec.position, Record: rec.record})
+		}
+	}
+	d.buf1, d.buf2 = d.buf1[i:], d.buf2[j:]
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,78 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"testing"
+)
+
+func events(names ...string) []datareader.Record {
+	records := make([]datareader.Record, len(names))
+	for i, name := range names {
+		records[i] = datareader.Record{"event": name}
+	}
+	return records
+}
+
+func TestCompareOrdered(t *testing.T) {
+	reader1 := datareader.NewSliceReader(events("a", "b", "c", "d", "e", "f"))
+	reader2 := datareader.NewSliceReader(events("a", "x", "c", "new", "d", "f", "g"))
+
+	result, err := CompareOrdered(reader1, reader2, OrderedOptions{Window: 3})
+	if err != nil {
+		t.Fatalf("CompareOrdered() error = %v", err)
+	}
+
+	expected := OrderedSummary{Source1Rows: 6, Source2Rows: 7, Matched: 4, Mismatched: 1, Inserted: 2, Deleted: 1}
+	if result.Summary != expected {
+		t.Errorf("Summary got = %+v, want %+v", result.Summary, expected)
+	}
+	if len(result.Mismatches) != 1 || result.Mismatches[0].Source1Position != 2 || result.Mismatches[0].Source2Position != 2 {
+		t.Errorf("Mismatches got = %+v, want b at 2 against x at 2", result.Mismatches)
+	}
+	if len(result.Insertions) != 2 || result.Insertions[0].Position != 4 || result.Insertions[1].Record["event"] != "g" {
+		t.Errorf("Insertions got = %+v, want new at 4 and g", result.Insertions)
+	}
+	if len(result.Deletions) != 1 || result.Deletions[0].Position != 5 || result.Deletions[0].Record["event"] != "e" {
+		t.Errorf("Deletions got = %+v, want e at 5", result.Deletions)
+	}
+}
+
+func TestCompareOrdered_BeyondWindow(t *testing.T) {
+	// The shift is wider than the window, so records pair up by position.
+	reader1 := datareader.NewSliceReader(events("a", "b", "c", "d"))
+	reader2 := datareader.NewSliceReader(events("x", "y", "z", "a", "b", "c", "d"))
+
+	result, err := CompareOrdered(reader1, reader2, OrderedOptions{Window: 2})
+	if err != nil {
+		t.Fatalf("CompareOrdered() error = %v", err)
+	}
+	expected := OrderedSummary{Source1Rows: 4, Source2Rows: 7, Mismatched: 4, Inserted: 3}
+	if result.Summary != expected {
+		t.Errorf("Summary got = %+v, want %+v", result.Summary, expected)
+	}
+
+	result, err = CompareOrdered(datareader.NewSliceReader(events("a", "b", "c", "d")),
+		datareader.NewSliceReader(events("x", "y", "z", "a", "b", "c", "d")), OrderedOptions{Window: 3})
+	if err != nil {
+		t.Fatalf("CompareOrdered() error = %v", err)
+	}
+	expected = OrderedSummary{Source1Rows: 4, Source2Rows: 7, Matched: 4, Inserted: 3}
+	if result.Summary != expected {
+		t.Errorf("Summary with a wide window got = %+v, want %+v", result.Summary, expected)
+	}
+}
+
+func TestCompareOrdered_Tolerance(t *testing.T) {
+	reader1 := datareader.NewSliceReader([]datareader.Record{{"v": 1.0}, {"v": 2.0}})
+	reader2 := datareader.NewSliceReader([]datareader.Record{{"v": 1.0000001}, {"v": 2.0}})
+	options := DefaultOptions()
+	options.Tolerance = Tolerance{Absolute: 0.001}
+
+	result, err := CompareOrdered(reader1, reader2, OrderedOptions{Equality: &options})
+	if err != nil {
+		t.Fatalf("CompareOrdered() error = %v", err)
+	}
+	if result.Summary.Matched != 2 || result.Summary.Mismatched != 0 {
+		t.Errorf("Summary got = %+v, want 2 matched", result.Summary)
+	}
+}
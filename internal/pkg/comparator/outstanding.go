@@ -0,0 +1,95 @@
+package comparator
+
+import "sort"
+
+// MaxOutstandingKeys is the number of keys listed per heartbeat in each of
+// OutstandingChange.New and OutstandingChange.Resolved. All of them are
+// counted.
+const MaxOutstandingKeys = 100
+
+// OutstandingKey is a key read from Source that is waiting for its
+// counterpart in the other source.
+type OutstandingKey struct {
+	Key    string `yaml:"key"`
+	Source string `yaml:"source"`
+}
+
+// OutstandingChange is how the keys waiting for their counterpart changed
+// since the previous heartbeat. Keys still waiting are not listed again and
+// keys that arrived and were matched between two heartbeats not at all, so
+// a long comparison reports each missing key once, when it shows up, and
+// once more if it is resolved.
+type OutstandingChange struct {
+	// New lists the first of the NewCount keys waiting since the previous heartbeat.
+	New      []OutstandingKey `yaml:"new,omitempty"`
+	NewCount int              `yaml:"new_count"`
+	// Resolved lists the first of the ResolvedCount keys reported as waiting
+	// by an earlier heartbeat that have since been matched.
+	Resolved      []OutstandingKey `yaml:"resolved,omitempty"`
+	ResolvedCount int              `yaml:"resolved_count"`
+	// Outstanding counts all keys waiting for their counterpart.
+	Outstanding int `yaml:"outstanding"`
+}
+
+// outstandingTracker follows the keys waiting for their counterpart between
+// heartbeats. A nil tracker tracks nothing.
+type outstandingTracker struct {
+	// reported holds the waiting keys already reported, fresh those waiting
+	// since the last report.
+	reported map[string]Side
+	fresh    map[string]Side
+	resolved []OutstandingKey
+	// resolvedCount counts reported keys matched since the last report.
+	resolvedCount int
+}
+
+func newOutstandingTracker() *outstandingTracker {
+	return &outstandingTracker{reported: make(map[string]Side), fresh: make(map[string]Side)}
+}
+
+// wait records that key of side waits for its counterpart.
+func (t *outstandingTracker) wait(key string, side Side) {
+	if t != nil {
+		t.fresh[key] = side
+	}
+}
+
+// match records that the waiting key was matched.
+func (t *outstandingTracker) match(key string) {
+	if t == nil {
+		return
+	}
+	if _, ok := t.fresh[key]; ok {
+		delete(t.fresh, key)
+		return
+	}
+	if side, ok := t.reported[key]; ok {
+		delete(t.reported, key)
+		t.resolvedCount++
+		if len(t.resolved) < MaxOutstandingKeys {
+			t.resolved = append(t.resolved, OutstandingKey{Key: key, Source: side.String()})
+		}
+	}
+}
+
+// report returns the change since the last report and starts a new one.
+func (t *outstandingTracker) report() *OutstandingChange {
+	if t == nil {
+		return nil
+	}
+	change := &OutstandingChange{NewCount: len(t.fresh), Resolved: t.resolved, ResolvedCount: t.resolvedCount}
+	keys := make([]string, 0, len(t.fresh))
+	for key, side := range t.fresh {
+		keys = append(keys, key)
+		t.reported[key] = side
+	}
+	sort.Strings(keys)
+	for _, key := range keys[:min(len(keys), MaxOutstandingKeys)] {
+		change.New = append(change.New, OutstandingKey{Key: key, Source: t.fresh[key].String()})
+	}
+	change.Outstanding = len(t.reported)
+
+	t.fresh = make(map[string]Side)
+	t.resolved, t.resolvedCount = nil, 0
+	return change
+}
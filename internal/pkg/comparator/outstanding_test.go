@@ -0,0 +1,59 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHeartbeat_Outstanding(t *testing.T) {
+	clock := NewManualClock(time.Date(2025, 9, 10, 12, 0, 0, 0, time.UTC))
+	var changes []*OutstandingChange
+	c := New("id")
+	c.SetClock(clock)
+	c.SetProgressInterval(time.Minute)
+	c.SetHooks(Hooks{OnHeartbeat: func(h Heartbeat) { changes = append(changes, h.Outstanding) }})
+
+	add := func(side Side, id string) {
+		t.Helper()
+		if err := c.Add(side, datareader.Record{"id": id}); err != nil {
+			t.Fatalf("Add(%s, %s) error = %v", side, id, err)
+		}
+	}
+	add(Source1, "a")
+	add(Source1, "b")
+	add(Source2, "x")
+	clock.Advance(time.Minute)
+	add(Source1, "c")
+	// a is resolved, d arrives and is matched within the interval, b is
+	// still waiting and not reported again.
+	add(Source2, "a")
+	add(Source2, "d")
+	add(Source1, "d")
+	add(Source1, "b")
+	clock.Advance(time.Minute)
+	add(Source2, "e")
+	c.Finish()
+
+	want := []*OutstandingChange{
+		{
+			New: []OutstandingKey{
+				{Key: "a", Source: "source1"}, {Key: "b", Source: "source1"},
+				{Key: "c", Source: "source1"}, {Key: "x", Source: "source2"},
+			},
+			NewCount:    4,
+			Outstanding: 4,
+		},
+		{
+			New:           []OutstandingKey{{Key: "e", Source: "source2"}},
+			NewCount:      1,
+			Resolved:      []OutstandingKey{{Key: "a", Source: "source1"}},
+			ResolvedCount: 1,
+			Outstanding:   4,
+		},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Outstanding changes got = %+v, want %+v", changes, want)
+	}
+}
@@ -0,0 +1,99 @@
+package comparator
+
+import (
+	"sync"
+	"time"
+)
+
+// pauser holds the pause state of a comparator, shared with the goroutines
+// calling Pause and Resume.
+type pauser struct {
+	mu sync.Mutex
+	// resumed is closed by Resume; nil while not paused.
+	resumed  chan struct{}
+	pausedAt time.Time
+	// total is the time spent paused before pausedAt in this comparison.
+	total time.Duration
+}
+
+// Pause stops Compare and Findings from reading further records until
+// Resume, e.g. during a maintenance window upstream. Records read so far and
+// pending keys are kept, and a read already in progress completes. Paused
+// time does not count toward the deadline, read timeouts, stall detection
+// or the elapsed time of snapshots, and is reported in Result.PausedFor.
+// It may be called from any goroutine and before the comparison starts.
+func (c *StreamComparator) Pause() {
+	c.pause.mu.Lock()
+	defer c.pause.mu.Unlock()
+	if c.pause.resumed == nil {
+		c.pause.resumed = make(chan struct{})
+		c.pause.pausedAt = c.clock.Now()
+	}
+}
+
+// Resume lets a paused comparison read on.
+func (c *StreamComparator) Resume() {
+	c.pause.mu.Lock()
+	defer c.pause.mu.Unlock()
+	if c.pause.resumed != nil {
+		c.pause.total += c.clock.Now().Sub(c.pause.pausedAt)
+		close(c.pause.resumed)
+		c.pause.resumed = nil
+	}
+}
+
+// Paused reports whether the comparator is paused.
+func (c *StreamComparator) Paused() bool {
+	c.pause.mu.Lock()
+	defer c.pause.mu.Unlock()
+	return c.pause.resumed != nil
+}
+
+// waitWhilePaused blocks until the comparator is resumed, answering
+// snapshot requests meanwhile.
+func (c *StreamComparator) waitWhilePaused() {
+	c.pause.mu.Lock()
+	resumed := c.pause.resumed
+	c.pause.mu.Unlock()
+	if resumed == nil {
+		return
+	}
+	ticker := time.NewTicker(stallCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-resumed:
+			return
+		case <-ticker.C:
+			c.maybeReportSnapshot()
+		}
+	}
+}
+
+// pausedFor returns the time the comparison has spent paused up to now.
+func (c *StreamComparator) pausedFor(now time.Time) time.Duration {
+	c.pause.mu.Lock()
+	defer c.pause.mu.Unlock()
+	total := c.pause.total
+	if c.pause.resumed != nil && now.After(c.pause.pausedAt) {
+		total += now.Sub(c.pause.pausedAt)
+	}
+	return total
+}
+
+// activeFor returns the time since the comparison started, less the time
+// it spent paused.
+func (c *StreamComparator) activeFor(now time.Time) time.Duration {
+	return now.Sub(c.result.StartedAt) - c.pausedFor(now)
+}
+
+// resetPause starts counting paused time afresh for a new comparison, which
+// starts paused if the comparator is.
+func (c *StreamComparator) resetPause(now time.Time) {
+	c.pause.mu.Lock()
+	defer c.pause.mu.Unlock()
+	c.pause.total = 0
+	if c.pause.resumed != nil {
+		c.pause.pausedAt = now
+	}
+}
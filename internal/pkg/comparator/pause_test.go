@@ -0,0 +1,54 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"testing"
+	"time"
+)
+
+func TestCompare_Pause(t *testing.T) {
+	useFastStallChecks(t)
+	clock := NewManualClock(time.Date(2025, 9, 10, 12, 0, 0, 0, time.UTC))
+	reader1 := datareader.NewSliceReader([]datareader.Record{{"id": "a"}, {"id": "b"}})
+	reader2 := datareader.NewSliceReader([]datareader.Record{{"id": "a"}, {"id": "b"}})
+
+	snapshots := make(chan Snapshot, 1)
+	c := New("id")
+	c.SetClock(clock)
+	c.SetDeadline(10 * time.Minute)
+	c.SetHooks(Hooks{OnSnapshot: func(s Snapshot) { snapshots <- s }})
+	c.Pause()
+	c.RequestSnapshot()
+
+	type compared struct {
+		result *Result
+		err    error
+	}
+	done := make(chan compared, 1)
+	go func() {
+		result, err := c.Compare(reader1, reader2)
+		done <- compared{result, err}
+	}()
+
+	// The snapshot is taken while the comparison waits to be resumed.
+	if s := <-snapshots; !s.Paused || s.Summary.Source1Rows != 0 {
+		t.Errorf("Snapshot while paused got = %+v, want paused before the first record", s)
+	}
+	clock.Advance(time.Hour)
+	c.Resume()
+	got := <-done
+	if got.err != nil {
+		t.Fatalf("Compare() error = %v", got.err)
+	}
+
+	if got.result.PausedFor != time.Hour {
+		t.Errorf("PausedFor got = %v, want %v", got.result.PausedFor, time.Hour)
+	}
+	if len(got.result.Incomplete) != 0 || got.result.Summary.IdenticalRows != 2 {
+		t.Errorf("Result got = %+v, %+v, want both rows compared as the paused hour does not count toward the deadline",
+			got.result.Summary, got.result.Incomplete)
+	}
+	if c.Paused() {
+		t.Errorf("Paused() got = true after Resume, want false")
+	}
+}
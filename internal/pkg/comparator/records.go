@@ -0,0 +1,72 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"strings"
+)
+
+// DefaultMaxRecords is the number of diffing keys whose records are embedded
+// when the include_records section does not set a maximum.
+const DefaultMaxRecords = 100
+
+// Redacted replaces the values of redacted fields in embedded records.
+const Redacted = "[REDACTED]"
+
+// RecordPair holds both complete records of a diffing key, so a field diff
+// can be triaged with its sibling fields in view.
+type RecordPair struct {
+	Source1 datareader.Record `yaml:"source1"`
+	Source2 datareader.Record `yaml:"source2"`
+}
+
+// keepRecords embeds the records of a diffing key until MaxRecords keys are kept.
+func (c *StreamComparator) keepRecords(key string, rec1, rec2 datareader.Record) {
+	max := c.options.MaxRecords
+	if max <= 0 || len(c.result.Records) >= max {
+		return
+	}
+	if c.result.Records == nil {
+		c.result.Records = make(map[string]RecordPair)
+	}
+	c.result.Records[key] = RecordPair{
+		Source1: redact(rec1, "", c.options.RedactFields),
+		Source2: redact(rec2, "", c.options.RedactFields),
+	}
+}
+
+// redact returns a copy of data with the fields listed in names masked. Names
+// are dotted paths as in field diffs; masking an object masks all of it.
+func redact(data map[string]interface{}, prefix string, names []string) map[string]interface{} {
+	if len(names) == 0 {
+		return data
+	}
+	out := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		name := k
+		if prefix != "" {
+			name = prefix + "." + k
+		}
+		switch {
+		case contains(names, name):
+			out[k] = Redacted
+		case isRedactedParent(names, name):
+			if nested, ok := v.(map[string]interface{}); ok {
+				out[k] = redact(nested, name, names)
+			} else {
+				out[k] = v
+			}
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func isRedactedParent(names []string, name string) bool {
+	for _, n := range names {
+		if strings.HasPrefix(n, name+".") {
+			return true
+		}
+	}
+	return false
+}
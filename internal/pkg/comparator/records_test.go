@@ -0,0 +1,55 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/config"
+	"data-comparator/internal/pkg/datareader"
+	"reflect"
+	"testing"
+)
+
+func TestCompare_IncludeRecords(t *testing.T) {
+	c := New("id")
+	c.SetOptions(OptionsFromConfig(&config.Comparison{
+		IncludeRecords: &config.IncludeRecords{Max: 1, Redact: []string{"email", "customer.ssn"}},
+	}))
+
+	add := func(side Side, rec datareader.Record) {
+		t.Helper()
+		if err := c.Add(side, rec); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	add(Source1, datareader.Record{"id": "1", "plan": "basic", "email": "a@example.com",
+		"customer": map[string]interface{}{"ssn": "123", "region": "eu"}})
+	add(Source2, datareader.Record{"id": "1", "plan": "premium", "email": "a@example.com",
+		"customer": map[string]interface{}{"ssn": "123", "region": "eu"}})
+	add(Source1, datareader.Record{"id": "2", "plan": "basic"})
+	add(Source2, datareader.Record{"id": "2", "plan": "premium"})
+
+	result := c.Finish()
+	if len(result.ValueDiffs) != 2 {
+		t.Fatalf("Expected 2 diffing keys, got %d", len(result.ValueDiffs))
+	}
+	if len(result.Records) != 1 {
+		t.Fatalf("Expected records of 1 key to be kept, got %d", len(result.Records))
+	}
+
+	expected := datareader.Record{"id": "1", "plan": "basic", "email": Redacted,
+		"customer": map[string]interface{}{"ssn": Redacted, "region": "eu"}}
+	if got := result.Records["1"].Source1; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Records[1].Source1 got = %v, want %v", got, expected)
+	}
+	if got := result.Records["1"].Source2["plan"]; got != "premium" {
+		t.Errorf("Records[1].Source2[plan] got = %v, want premium", got)
+	}
+}
+
+func TestOptionsFromConfig_IncludeRecordsDefaultMax(t *testing.T) {
+	options := OptionsFromConfig(&config.Comparison{IncludeRecords: &config.IncludeRecords{}})
+	if options.MaxRecords != DefaultMaxRecords {
+		t.Errorf("MaxRecords got = %d, want %d", options.MaxRecords, DefaultMaxRecords)
+	}
+	if OptionsFromConfig(nil).MaxRecords != 0 {
+		t.Error("Expected records to be omitted by default")
+	}
+}
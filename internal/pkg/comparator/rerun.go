@@ -0,0 +1,163 @@
+package comparator
+
+import (
+	"crypto/sha256"
+	"data-comparator/internal/pkg/config"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RunInfo records how a result was produced, so the run can be reconstructed
+// from its report.
+type RunInfo struct {
+	// Config is the effective configuration of both sources, with the key
+	// fields resolved.
+	Config config.Run `yaml:"config"`
+	// Fingerprints identify the contents of each source file at the time of the run.
+	Fingerprints SourceFingerprints `yaml:"fingerprints"`
+}
+
+// SourceFingerprints holds the fingerprint of each source.
+type SourceFingerprints struct {
+	Source1 Fingerprint `yaml:"source1"`
+	Source2 Fingerprint `yaml:"source2"`
+}
+
+// Fingerprint identifies the contents of a source file. It is empty for
+// database sources, whose changes are not detected. Objects in object
+// storage are identified by their size and ETag instead of a hash, so they
+// need not be downloaded, and files on SFTP and FTP servers by their size.
+type Fingerprint struct {
+	Size   int64  `yaml:"size"`
+	SHA256 string `yaml:"sha256"`
+	ETag   string `yaml:"etag,omitempty"`
+}
+
+// FingerprintFile computes the fingerprint of the file at path.
+func FingerprintFile(path string) (Fingerprint, error) {
+	return FingerprintFiles([]string{path})
+}
+
+// FingerprintFiles computes the fingerprint of the concatenation of the files
+// at paths, as read by a glob or directory source.
+func FingerprintFiles(paths []string) (Fingerprint, error) {
+	h := sha256.New()
+	var size int64
+	for _, path := range paths {
+		n, err := hashFile(h, path)
+		if err != nil {
+			return Fingerprint{}, err
+		}
+		size += n
+	}
+	return Fingerprint{Size: size, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+func hashFile(w io.Writer, path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+	n, err := io.Copy(w, f)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return n, nil
+}
+
+// Discrepancy is a key reported as differing between the sources.
+type Discrepancy struct {
+	// Kind is value_diff, only_in_source1 or only_in_source2.
+	Kind string `yaml:"kind"`
+	Key  string `yaml:"key"`
+}
+
+// RerunResult tells whether the discrepancies of a report still reproduce.
+type RerunResult struct {
+	// SourcesChanged lists the sources whose contents differ from the report's fingerprints.
+	SourcesChanged []string `yaml:"sources_changed,omitempty"`
+	// Reproduced is set when the rerun reports exactly the discrepancies of the report.
+	Reproduced bool `yaml:"reproduced"`
+	// Persisting discrepancies are reported by both the report and the rerun.
+	Persisting []Discrepancy `yaml:"persisting,omitempty"`
+	// Resolved discrepancies are only reported by the report.
+	Resolved []Discrepancy `yaml:"resolved,omitempty"`
+	// New discrepancies are only reported by the rerun.
+	New     []Discrepancy `yaml:"new,omitempty"`
+	Summary Summary       `yaml:"summary"`
+}
+
+// Rerun reconstructs the run of a previously written report from its
+// embedded configuration, with the same keys, sample sizes and comparison
+// settings, and compares the discrepancies found with those reported.
+func Rerun(reportPath string) (*RerunResult, error) {
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report file %s: %w", reportPath, err)
+	}
+	var report Result
+	if err := yaml.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal yaml from %s: %w", reportPath, err)
+	}
+	if report.Run == nil || report.Run.Config.Config1 == nil || report.Run.Config.Config2 == nil {
+		return nil, fmt.Errorf("report %s does not embed its run configuration", reportPath)
+	}
+
+	run := report.Run.Config
+	result, err := CompareConfigs(run.Config1, run.Config2, report.Keys.Source1, report.Keys.Source2)
+	if err != nil {
+		return nil, err
+	}
+
+	rerun := &RerunResult{Summary: result.Summary}
+	if result.Run.Fingerprints.Source1 != report.Run.Fingerprints.Source1 {
+		rerun.SourcesChanged = append(rerun.SourcesChanged, Source1.String())
+	}
+	if result.Run.Fingerprints.Source2 != report.Run.Fingerprints.Source2 {
+		rerun.SourcesChanged = append(rerun.SourcesChanged, Source2.String())
+	}
+
+	before, after := discrepancies(&report), discrepancies(result)
+	for d := range before {
+		if after[d] {
+			rerun.Persisting = append(rerun.Persisting, d)
+		} else {
+			rerun.Resolved = append(rerun.Resolved, d)
+		}
+	}
+	for d := range after {
+		if !before[d] {
+			rerun.New = append(rerun.New, d)
+		}
+	}
+	for _, list := range [][]Discrepancy{rerun.Persisting, rerun.Resolved, rerun.New} {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Key != list[j].Key {
+				return list[i].Key < list[j].Key
+			}
+			return list[i].Kind < list[j].Kind
+		})
+	}
+	rerun.Reproduced = len(rerun.Resolved) == 0 && len(rerun.New) == 0
+	return rerun, nil
+}
+
+func discrepancies(result *Result) map[Discrepancy]bool {
+	set := make(map[Discrepancy]bool)
+	for key := range result.ValueDiffs {
+		set[Discrepancy{Kind: "value_diff", Key: key}] = true
+	}
+	for _, key := range result.KeysOnly.InSource1 {
+		set[Discrepancy{Kind: "only_in_source1", Key: key}] = true
+	}
+	for _, key := range result.KeysOnly.InSource2 {
+		set[Discrepancy{Kind: "only_in_source2", Key: key}] = true
+	}
+	return set
+}
@@ -0,0 +1,82 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/config"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestRerun(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"source1.csv", "source2.csv"} {
+		data, err := os.ReadFile("../../../testdata/testcase1_simple_csv/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config1 := &config.Config{Source: config.Source{Type: "csv", Path: filepath.Join(dir, "source1.csv")}}
+	config2 := &config.Config{Source: config.Source{Type: "csv", Path: filepath.Join(dir, "source2.csv")}}
+
+	result, err := CompareConfigs(config1, config2, "", "")
+	if err != nil {
+		t.Fatalf("CompareConfigs() error = %v", err)
+	}
+	if result.Run == nil || result.Run.Config.Config1.Source.Key != "user_id" {
+		t.Fatalf("Run got = %+v, want the effective config keyed on user_id", result.Run)
+	}
+	report, err := yaml.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reportPath := filepath.Join(dir, "report.yaml")
+	if err := os.WriteFile(reportPath, report, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rerun, err := Rerun(reportPath)
+	if err != nil {
+		t.Fatalf("Rerun() error = %v", err)
+	}
+	if !rerun.Reproduced || len(rerun.SourcesChanged) != 0 || len(rerun.Persisting) != 3 {
+		t.Errorf("Rerun() of unchanged sources got = %+v, want all 3 discrepancies reproduced", rerun)
+	}
+
+	// Fix the value diff of key 1 in source2.
+	data, _ := os.ReadFile(config2.Source.Path)
+	fixed := strings.Replace(string(data), `1,alice@email.com,31,New York,premium_plus,"2025-09-10T13:00:00Z"`, `1,alice@email.com,30,New York,premium,"2025-09-10T12:00:00Z"`, 1)
+	if err := os.WriteFile(config2.Source.Path, []byte(fixed), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rerun, err = Rerun(reportPath)
+	if err != nil {
+		t.Fatalf("Rerun() error = %v", err)
+	}
+	if rerun.Reproduced {
+		t.Error("Rerun() got Reproduced after the diff was fixed")
+	}
+	if !reflect.DeepEqual(rerun.SourcesChanged, []string{"source2"}) {
+		t.Errorf("SourcesChanged got = %v, want [source2]", rerun.SourcesChanged)
+	}
+	if want := []Discrepancy{{Kind: "value_diff", Key: "1"}}; !reflect.DeepEqual(rerun.Resolved, want) {
+		t.Errorf("Resolved got = %v, want %v", rerun.Resolved, want)
+	}
+	if len(rerun.New) != 0 {
+		t.Errorf("New got = %v, want none", rerun.New)
+	}
+
+	if err := os.WriteFile(reportPath, []byte("summary:\n  matching_keys: 4\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Rerun(reportPath); err == nil {
+		t.Error("Rerun() expected error for a report without run configuration, got nil")
+	}
+}
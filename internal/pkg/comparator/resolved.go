@@ -0,0 +1,99 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"sort"
+	"time"
+)
+
+// MaxResolvedKeys is the number of resolved keys listed in Resolved.Keys.
+// All of them are counted in Summary.ResolvedDiffs.
+const MaxResolvedKeys = 100
+
+// Resolved describes the keys whose records differed and later matched,
+// when resolved diffs are tracked with SetTrackResolved.
+type Resolved struct {
+	// Keys lists the first resolved keys in the order they were resolved.
+	Keys []ResolvedKey `yaml:"keys"`
+	// Latency is the distribution of the times the keys took to resolve.
+	Latency LatencyDistribution `yaml:"latency"`
+}
+
+// ResolvedKey is a key that differed and later matched.
+type ResolvedKey struct {
+	Key string `yaml:"key"`
+	// Latency is the time from the first diff of the key to its match, less
+	// the time the comparison spent paused.
+	Latency time.Duration `yaml:"latency"`
+}
+
+// LatencyDistribution summarizes a set of durations by their percentiles.
+type LatencyDistribution struct {
+	Min time.Duration `yaml:"min" json:"min"`
+	P50 time.Duration `yaml:"p50" json:"p50"`
+	P90 time.Duration `yaml:"p90" json:"p90"`
+	P99 time.Duration `yaml:"p99" json:"p99"`
+	Max time.Duration `yaml:"max" json:"max"`
+}
+
+// differingKey holds the latest records of a key whose records differ.
+type differingKey struct {
+	records [2]datareader.Record
+	// since is the active time of the comparison when the key first differed.
+	since time.Duration
+}
+
+// SetTrackResolved keeps the records of differing keys, so that later
+// records of such a key, such as an upstream retry or a late update, are
+// compared again with the latest record of the other source. A key whose
+// records then match is removed from the value diffs, counts as identical
+// and is reported under Result.Resolved with the time it took. Keys that
+// still differ keep the diffs first found.
+func (c *StreamComparator) SetTrackResolved(on bool) {
+	c.trackResolved = on
+}
+
+// rejoin compares rec, a later record of a key that differed, with the latest
+// record of the other side.
+func (c *StreamComparator) rejoin(d *differingKey, key string, rec datareader.Record, side Side) {
+	d.records[side-1] = rec
+	if diffs, _ := c.diff(key, d.records[0], d.records[1]); len(diffs) > 0 {
+		return
+	}
+
+	latency := c.activeFor(c.clock.Now()) - d.since
+	delete(c.differing, key)
+	delete(c.result.ValueDiffs, key)
+	delete(c.result.Records, key)
+	c.result.Summary.IdenticalRows++
+	c.result.Summary.ResolvedDiffs++
+	c.resolvedLatencies = append(c.resolvedLatencies, latency)
+	if c.result.Resolved == nil {
+		c.result.Resolved = &Resolved{}
+	}
+	if len(c.result.Resolved.Keys) < MaxResolvedKeys {
+		c.result.Resolved.Keys = append(c.result.Resolved.Keys, ResolvedKey{Key: key, Latency: latency})
+	}
+	if c.hooks.OnResolved != nil {
+		c.hooks.OnResolved(key, latency, d.records[0], d.records[1])
+	}
+}
+
+// latencyDistribution returns the percentiles of latencies, which it sorts.
+func latencyDistribution(latencies []time.Duration) LatencyDistribution {
+	if len(latencies) == 0 {
+		return LatencyDistribution{}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	percentile := func(p int) time.Duration {
+		// The nearest-rank percentile.
+		return latencies[(p*len(latencies)+99)/100-1]
+	}
+	return LatencyDistribution{
+		Min: latencies[0],
+		P50: percentile(50),
+		P90: percentile(90),
+		P99: percentile(99),
+		Max: latencies[len(latencies)-1],
+	}
+}
@@ -0,0 +1,87 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTrackResolved(t *testing.T) {
+	clock := NewManualClock(time.Date(2025, 9, 10, 12, 0, 0, 0, time.UTC))
+	var resolved []string
+	c := New("id")
+	c.SetClock(clock)
+	c.SetTrackResolved(true)
+	c.SetHooks(Hooks{OnResolved: func(key string, latency time.Duration, rec1, rec2 datareader.Record) {
+		resolved = append(resolved, key)
+	}})
+
+	add := func(side Side, id string, amount int) {
+		t.Helper()
+		if err := c.Add(side, datareader.Record{"id": id, "amount": amount}); err != nil {
+			t.Fatalf("Add(%s, %s) error = %v", side, id, err)
+		}
+	}
+	add(Source1, "a", 1)
+	add(Source2, "a", 2)
+	add(Source1, "b", 1)
+	add(Source2, "b", 2)
+	clock.Advance(time.Minute)
+	// An upstream retry corrects b; a is updated on both sides but still differs.
+	add(Source2, "b", 1)
+	add(Source1, "a", 3)
+	add(Source2, "a", 4)
+	add(Source1, "c", 1)
+	add(Source2, "c", 2)
+	clock.Advance(2 * time.Minute)
+	add(Source1, "c", 2)
+	result := c.Finish()
+
+	if _, ok := result.ValueDiffs["a"]; !ok || len(result.ValueDiffs) != 1 {
+		t.Errorf("ValueDiffs got = %v, want only a", result.ValueDiffs)
+	}
+	want := &Resolved{
+		Keys:    []ResolvedKey{{Key: "b", Latency: time.Minute}, {Key: "c", Latency: 2 * time.Minute}},
+		Latency: LatencyDistribution{Min: time.Minute, P50: time.Minute, P90: 2 * time.Minute, P99: 2 * time.Minute, Max: 2 * time.Minute},
+	}
+	if !reflect.DeepEqual(result.Resolved, want) {
+		t.Errorf("Resolved got = %+v, want %+v", result.Resolved, want)
+	}
+	if s := result.Summary; s.MatchingKeys != 3 || s.IdenticalRows != 2 || s.ResolvedDiffs != 2 || s.KeysOnlyInSource1+s.KeysOnlyInSource2 != 0 {
+		t.Errorf("Summary got = %+v, want 3 matching keys, 2 of them identical after resolving", s)
+	}
+	if !reflect.DeepEqual(resolved, []string{"b", "c"}) {
+		t.Errorf("OnResolved keys got = %v, want [b c]", resolved)
+	}
+}
+
+func TestTrackResolved_Off(t *testing.T) {
+	c := New("id")
+	for _, rec := range []struct {
+		side   Side
+		amount int
+	}{{Source1, 1}, {Source2, 2}, {Source2, 1}} {
+		if err := c.Add(rec.side, datareader.Record{"id": "a", "amount": rec.amount}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	result := c.Finish()
+	if result.Resolved != nil || len(result.ValueDiffs) != 1 || len(result.KeysOnly.InSource2) != 1 {
+		t.Errorf("Result got = %+v, want the diff kept and the later record unmatched", result)
+	}
+}
+
+func TestLatencyDistribution(t *testing.T) {
+	var latencies []time.Duration
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Second)
+	}
+	want := LatencyDistribution{Min: time.Second, P50: 50 * time.Second, P90: 90 * time.Second, P99: 99 * time.Second, Max: 100 * time.Second}
+	if got := latencyDistribution(latencies); got != want {
+		t.Errorf("latencyDistribution() got = %+v, want %+v", got, want)
+	}
+	if got := latencyDistribution(nil); got != (LatencyDistribution{}) {
+		t.Errorf("latencyDistribution(nil) got = %+v, want zero", got)
+	}
+}
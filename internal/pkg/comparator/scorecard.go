@@ -0,0 +1,124 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/schema"
+	"math"
+)
+
+// Scorecard rates the data quality of each source and the parity of the pair
+// from 0 to 100, giving a single trendable number per run.
+type Scorecard struct {
+	Source1 QualityScore `yaml:"source1"`
+	Source2 QualityScore `yaml:"source2"`
+	// Parity is the share of all keys present in both sources with identical
+	// records. Keys only in a source allowed to hold more are left out.
+	Parity float64 `yaml:"parity"`
+}
+
+// QualityScore is the composite data-quality score of one source and the
+// rates, from 0 to 1, it is made of. Each rate weighs the same.
+type QualityScore struct {
+	Score float64 `yaml:"score"`
+	// ParseErrorRate is the share of records that could not be parsed.
+	ParseErrorRate float64 `yaml:"parse_error_rate"`
+	// NullRate is the share of missing or null values of fields with a notNull matcher.
+	NullRate float64 `yaml:"null_rate"`
+	// ViolationRate is the share of records violating another matcher, their
+	// type or a constraint.
+	ViolationRate float64 `yaml:"violation_rate"`
+	// DuplicateRate is the share of records whose key was already pending.
+	DuplicateRate float64 `yaml:"duplicate_rate"`
+}
+
+// emptySides returns the sides from which no record, not even an unparsable
+// one, was read.
+func (c *StreamComparator) emptySides() []Side {
+	var empty []Side
+	for _, side := range []Side{Source1, Source2} {
+		if q := c.quality[side-1]; q.records == 0 && q.parseErrors == 0 && !c.incomplete[side-1] {
+			empty = append(empty, side)
+		}
+	}
+	return empty
+}
+
+// qualityCounts accumulates the counts behind a QualityScore.
+type qualityCounts struct {
+	records        int
+	parseErrors    int
+	requiredChecks int
+	nulls          int
+	invalid        int
+	duplicates     int
+}
+
+// addChecked counts a checked record with its violations.
+func (q *qualityCounts) addChecked(requiredFields int, violations []schema.Violation) {
+	q.records++
+	q.requiredChecks += requiredFields
+	invalid := false
+	for _, v := range violations {
+		if v.Rule == "notNull" {
+			q.nulls++
+		} else {
+			invalid = true
+		}
+	}
+	if invalid {
+		q.invalid++
+	}
+}
+
+func (q qualityCounts) score() QualityScore {
+	s := QualityScore{
+		ParseErrorRate: rate(q.parseErrors, q.records+q.parseErrors),
+		NullRate:       rate(q.nulls, q.requiredChecks),
+		ViolationRate:  rate(q.invalid, q.records),
+		DuplicateRate:  rate(q.duplicates, q.records),
+	}
+	s.Score = round(100 * (1 - (s.ParseErrorRate+s.NullRate+s.ViolationRate+s.DuplicateRate)/4))
+	return s
+}
+
+// AddParseError counts a record of side that could not be parsed against
+// the source's quality score.
+func (c *StreamComparator) AddParseError(side Side) {
+	if c.result == nil {
+		c.reset()
+	}
+	if side == Source1 || side == Source2 {
+		c.quality[side-1].parseErrors++
+	}
+}
+
+func (c *StreamComparator) scorecard() *Scorecard {
+	s := c.result.Summary
+	keys := s.MatchingKeys
+	if !c.result.AllowsOnlyIn(Source1) {
+		keys += s.KeysOnlyInSource1
+	}
+	if !c.result.AllowsOnlyIn(Source2) {
+		keys += s.KeysOnlyInSource2
+	}
+	parity := 100.0
+	if keys > 0 {
+		parity = round(100 * float64(s.IdenticalRows) / float64(keys))
+	}
+	return &Scorecard{
+		Source1: c.quality[0].score(),
+		Source2: c.quality[1].score(),
+		Parity:  parity,
+	}
+}
+
+func rate(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(n)/float64(total)*10000) / 10000
+}
+
+// round rounds to two decimals.
+func round(f float64) float64 {
+	return math.Round(f*100) / 100
+}
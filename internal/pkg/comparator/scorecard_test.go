@@ -0,0 +1,84 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/config"
+	"data-comparator/internal/pkg/datareader"
+	"data-comparator/internal/pkg/schema"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScorecard(t *testing.T) {
+	c := New("id")
+	c.SetSchema(&schema.Schema{Fields: map[string]*schema.Field{
+		"name": {Type: "string", Matchers: []schema.Matcher{{"notNull": true}}},
+		"age":  {Type: "numeric"},
+	}})
+
+	add := func(side Side, rec datareader.Record) {
+		t.Helper()
+		if err := c.Add(side, rec); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	add(Source1, datareader.Record{"id": "1", "name": "alice", "age": "30"})
+	add(Source1, datareader.Record{"id": "2", "age": "thirty"})
+	add(Source1, datareader.Record{"id": "3", "name": "carol", "age": "40"})
+	add(Source1, datareader.Record{"id": "3", "name": "carol", "age": "40"})
+	c.AddParseError(Source2)
+	add(Source2, datareader.Record{"id": "1", "name": "alice", "age": "30"})
+	add(Source2, datareader.Record{"id": "2", "name": "bob", "age": "31"})
+	add(Source2, datareader.Record{"id": "4", "name": "dave", "age": "50"})
+
+	result := c.Finish()
+	s1 := result.Scorecard.Source1
+	expected1 := QualityScore{NullRate: 0.25, ViolationRate: 0.25, DuplicateRate: 0.25, Score: 81.25}
+	if s1 != expected1 {
+		t.Errorf("Source1 score got = %+v, want %+v", s1, expected1)
+	}
+	s2 := result.Scorecard.Source2
+	expected2 := QualityScore{ParseErrorRate: 0.25, Score: 93.75}
+	if s2 != expected2 {
+		t.Errorf("Source2 score got = %+v, want %+v", s2, expected2)
+	}
+	// keys 1, 2 and 3 and 4: only key 1 is identical
+	if result.Scorecard.Parity != 25 {
+		t.Errorf("Parity got = %v, want 25", result.Scorecard.Parity)
+	}
+}
+
+func TestCompare_SkipsRecoverableParseErrors(t *testing.T) {
+	dir := t.TempDir()
+	path1 := filepath.Join(dir, "source1.csv")
+	path2 := filepath.Join(dir, "source2.csv")
+	if err := os.WriteFile(path1, []byte("id,name\n1,alice\n2,\"bo\"b\"\n3,carol\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := os.WriteFile(path2, []byte("id,name\n1,alice\n3,carol\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	reader1, err := datareader.New(config.Source{Type: "csv", Path: path1})
+	if err != nil {
+		t.Fatalf("Failed to create reader for source1: %v", err)
+	}
+	defer reader1.Close()
+	reader2, err := datareader.New(config.Source{Type: "csv", Path: path2})
+	if err != nil {
+		t.Fatalf("Failed to create reader for source2: %v", err)
+	}
+	defer reader2.Close()
+
+	c := New("id")
+	c.SetSkipParseErrors(true)
+	result, err := c.Compare(reader1, reader2)
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	if result.Summary.IdenticalRows != 2 {
+		t.Errorf("IdenticalRows got = %d, want 2", result.Summary.IdenticalRows)
+	}
+	if got := result.Scorecard.Source1.ParseErrorRate; got != 0.3333 {
+		t.Errorf("Source1 ParseErrorRate got = %v, want 0.3333", got)
+	}
+}
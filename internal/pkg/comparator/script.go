@@ -0,0 +1,232 @@
+package comparator
+
+import (
+	"bufio"
+	"data-comparator/internal/pkg/config"
+	"data-comparator/internal/pkg/datareader"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultScriptTimeout is how long a comparison script may take to answer a
+// request, unless configured.
+const DefaultScriptTimeout = 10 * time.Second
+
+// Script runs the normalize and equal functions of a comparison in an
+// external process. Each request is a line of JSON on the stdin of the
+// process, {"normalize": record} or {"equal": {"field": ..., "source1": ...,
+// "source2": ...}}, answered by a line on its stdout: the rewritten record,
+// or true or false, or null to fall back to the built-in rules. The first
+// failure, such as an answer taking longer than the timeout, stops the
+// process and fails the comparison.
+type Script struct {
+	command   string
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	stdout    *bufio.Reader
+	stderr    strings.Builder
+	timeout   time.Duration
+	normalize bool
+
+	// mu serializes requests, which may come from concurrent comparisons.
+	mu     sync.Mutex
+	err    error
+	closed bool
+}
+
+// StartScript starts the process of a comparison script.
+func StartScript(cfg *config.Script) (*Script, error) {
+	if len(cfg.Command) == 0 {
+		return nil, fmt.Errorf("comparison script has no command")
+	}
+	s := &Script{
+		command:   strings.Join(cfg.Command, " "),
+		cmd:       exec.Command(cfg.Command[0], cfg.Command[1:]...),
+		timeout:   cfg.Timeout,
+		normalize: cfg.Normalize,
+	}
+	if s.timeout <= 0 {
+		s.timeout = DefaultScriptTimeout
+	}
+	s.cmd.Stderr = &s.stderr
+	var err error
+	if s.stdin, err = s.cmd.StdinPipe(); err != nil {
+		return nil, fmt.Errorf("failed to create stdin of %s: %w", s.command, err)
+	}
+	stdout, err := s.cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout of %s: %w", s.command, err)
+	}
+	if err := s.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", s.command, err)
+	}
+	s.stdout = bufio.NewReader(stdout)
+	return s, nil
+}
+
+// apply sets the Normalize and Equal functions of options to the script, as
+// configured by the script and the field rules of options.
+func (s *Script) apply(options *Options) {
+	if s.normalize {
+		options.Normalize = s.Normalize
+	}
+	if rules := options.FieldRules; rules.scripted() {
+		options.Equal = func(field string, v1, v2 interface{}) (bool, bool) {
+			if !rules.of(field).script {
+				return false, false
+			}
+			return s.Equal(field, v1, v2)
+		}
+	}
+}
+
+// Normalize returns rec as rewritten by the script. After a failure it
+// returns rec unchanged; Err reports the failure.
+func (s *Script) Normalize(rec datareader.Record) datareader.Record {
+	answer, err := s.request(map[string]interface{}{"normalize": rec})
+	if err != nil {
+		return rec
+	}
+	var normalized datareader.Record
+	if err := json.Unmarshal(answer, &normalized); err != nil || normalized == nil {
+		s.fail(fmt.Errorf("normalize answered %q, want a JSON object", answer))
+		return rec
+	}
+	return normalized
+}
+
+// Equal returns whether the script finds v1 and v2 of field equal, and ok
+// false if it leaves them to the built-in rules or has failed.
+func (s *Script) Equal(field string, v1, v2 interface{}) (equal, ok bool) {
+	answer, err := s.request(map[string]interface{}{"equal": map[string]interface{}{"field": field, "source1": v1, "source2": v2}})
+	if err != nil {
+		return false, false
+	}
+	var decided *bool
+	if err := json.Unmarshal(answer, &decided); err != nil {
+		s.fail(fmt.Errorf("equal answered %q, want true, false or null", answer))
+		return false, false
+	}
+	if decided == nil {
+		return false, false
+	}
+	return *decided, true
+}
+
+// request sends req to the script and returns its answer.
+func (s *Script) request(req interface{}) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		s.failLocked(fmt.Errorf("failed to encode request: %w", err))
+		return nil, s.err
+	}
+	if _, err := s.stdin.Write(append(data, '\n')); err != nil {
+		s.failLocked(fmt.Errorf("failed to send request: %w", err))
+		return nil, s.err
+	}
+
+	type answer struct {
+		line []byte
+		err  error
+	}
+	answered := make(chan answer, 1)
+	go func() {
+		line, err := s.stdout.ReadBytes('\n')
+		answered <- answer{line, err}
+	}()
+	select {
+	case a := <-answered:
+		if a.err != nil {
+			s.failLocked(fmt.Errorf("failed to read answer: %w", a.err))
+			return nil, s.err
+		}
+		return a.line, nil
+	case <-time.After(s.timeout):
+		s.failLocked(fmt.Errorf("no answer within %s", s.timeout))
+		return nil, s.err
+	}
+}
+
+func (s *Script) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failLocked(err)
+}
+
+// failLocked stops the script and records err as its failure.
+func (s *Script) failLocked(err error) {
+	s.stop()
+	s.setErr(err)
+}
+
+// setErr records err as the failure of the stopped script, with what it
+// wrote to stderr, unless it already failed.
+func (s *Script) setErr(err error) {
+	if s.err != nil {
+		return
+	}
+	s.err = fmt.Errorf("comparison script %s: %w", s.command, err)
+	if stderr := strings.TrimSpace(s.stderr.String()); stderr != "" {
+		s.err = fmt.Errorf("%w: %s", s.err, stderr)
+	}
+}
+
+// stop kills the process, unless it has already been stopped.
+func (s *Script) stop() {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.cmd.Process.Kill()
+	s.cmd.Wait()
+}
+
+// Err returns the first failure of the script, if any.
+func (s *Script) Err() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close ends the input of the script and waits for it to exit, stopping it
+// if it takes longer than the timeout. It returns the first failure of the
+// script, including a failing exit status.
+func (s *Script) Close() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return s.err
+	}
+	s.closed = true
+	s.stdin.Close()
+	exited := make(chan error, 1)
+	go func() { exited <- s.cmd.Wait() }()
+	var err error
+	select {
+	case err = <-exited:
+	case <-time.After(s.timeout):
+		s.cmd.Process.Kill()
+		<-exited
+		err = fmt.Errorf("did not exit within %s", s.timeout)
+	}
+	if err != nil {
+		s.setErr(err)
+	}
+	return s.err
+}
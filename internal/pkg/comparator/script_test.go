@@ -0,0 +1,91 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/config"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testScript lowercases ACTIVE in the records it normalizes, finds every
+// pair of prices equal and leaves other fields to the built-in rules.
+const testScript = `while read -r line; do
+  case "$line" in
+    '{"equal":{"field":"price"'*) echo true ;;
+    '{"equal":'*) echo null ;;
+    '{"normalize":'*) line=${line#'{"normalize":'}; echo "${line%\}}" | sed 's/"ACTIVE"/"active"/' ;;
+  esac
+done`
+
+// scriptConfigs writes two CSV sources and returns their configs, comparing
+// with script.
+func scriptConfigs(t *testing.T, script *config.Script, rules map[string]config.FieldRule) (*config.Config, *config.Config) {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	dir := t.TempDir()
+	sources := map[string]string{
+		"source1.csv": "id,status,price,name\n1,ACTIVE,10,alice\n2,active,20,bob\n",
+		"source2.csv": "id,status,price,name\n1,active,11,alice\n2,active,21,rob\n",
+	}
+	for name, content := range sources {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config1 := &config.Config{
+		Source:     config.Source{Type: "csv", Path: filepath.Join(dir, "source1.csv"), Key: "id"},
+		Comparison: &config.Comparison{Script: script, FieldRules: rules},
+	}
+	config2 := &config.Config{Source: config.Source{Type: "csv", Path: filepath.Join(dir, "source2.csv"), Key: "id"}}
+	return config1, config2
+}
+
+func TestCompareConfigs_Script(t *testing.T) {
+	script := &config.Script{Command: []string{"sh", "-c", testScript}, Normalize: true}
+	config1, config2 := scriptConfigs(t, script, map[string]config.FieldRule{"price": {Script: true}})
+
+	result, err := CompareConfigs(config1, config2, "", "")
+	if err != nil {
+		t.Fatalf("CompareConfigs() error = %v", err)
+	}
+	// Status and price are equal by the script, the names of key 2 are not.
+	if s := result.Summary; s.IdenticalRows != 1 || len(result.ValueDiffs["2"]) != 1 || result.ValueDiffs["2"][0].Field != "name" {
+		t.Errorf("result got = %+v, %+v, want key 1 identical and key 2 differing in name", s, result.ValueDiffs)
+	}
+
+	multiset := *config1.Comparison
+	multiset.Multiset = &config.Multiset{}
+	config1.Comparison = &multiset
+	if _, err := CompareMultisetConfigs(config1, config2); err != nil {
+		t.Errorf("CompareMultisetConfigs() error = %v", err)
+	}
+}
+
+func TestCompareConfigs_ScriptFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  config.Script
+		wantErr string
+	}{
+		{"exits", config.Script{Command: []string{"sh", "-c", "echo broken >&2; exit 3"}, Normalize: true}, "broken"},
+		{"bad answer", config.Script{Command: []string{"sh", "-c", "while read -r line; do echo '[]'; done"}, Normalize: true}, "want a JSON object"},
+		{"timeout", config.Script{Command: []string{"sh", "-c", "exec sleep 5"}, Normalize: true, Timeout: 100 * time.Millisecond}, "no answer within 100ms"},
+	}
+	for _, tt := range tests {
+		config1, config2 := scriptConfigs(t, &tt.script, nil)
+		_, err := CompareConfigs(config1, config2, "", "")
+		if err == nil || !strings.Contains(err.Error(), "comparison script") || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: CompareConfigs() error got = %v, want a script error with %q", tt.name, err, tt.wantErr)
+		}
+	}
+
+	_, err := CompileFieldRules(&config.Comparison{FieldRules: map[string]config.FieldRule{"price": {Script: true}}})
+	if err == nil || !strings.Contains(err.Error(), "needs a comparison.script") {
+		t.Errorf("CompileFieldRules() error got = %v, want a missing script error", err)
+	}
+}
@@ -0,0 +1,96 @@
+package comparator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity ranks findings, like the severities of config validation.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// Severities lists the severities, the default first.
+var Severities = []Severity{SeverityError, SeverityWarning, SeverityInfo}
+
+var severityRank = map[Severity]int{SeverityInfo: 1, SeverityWarning: 2, SeverityError: 3}
+
+// ParseSeverity returns the severity named s, the default for an empty s.
+func ParseSeverity(s string) (Severity, error) {
+	if s == "" {
+		return SeverityError, nil
+	}
+	names := make([]string, len(Severities))
+	for i, severity := range Severities {
+		if string(severity) == s {
+			return severity, nil
+		}
+		names[i] = string(severity)
+	}
+	return "", fmt.Errorf("unsupported severity %s, use one of %s", s, strings.Join(names, ", "))
+}
+
+// MissingKeySeverity is the severity of keys only in each source, e.g.
+// error for keys a migration lost but info for keys it added. An empty
+// severity falls back to the expectation.
+type MissingKeySeverity struct {
+	Source1 Severity `yaml:"source1,omitempty"`
+	Source2 Severity `yaml:"source2,omitempty"`
+}
+
+// SetMissingKeySeverity sets the severity of keys only in each source.
+// Keys only in a source of severity info meet the expectation of the result.
+func (c *StreamComparator) SetMissingKeySeverity(severity MissingKeySeverity) {
+	c.missingKeySeverity = severity
+}
+
+// MissingKeySeverityOf returns the severity of keys only in side: the one
+// set, else info for keys the expectation allows and error for others.
+func (r *Result) MissingKeySeverityOf(side Side) Severity {
+	if s := r.MissingKeySeverity; s != nil {
+		if side == Source1 && s.Source1 != "" {
+			return s.Source1
+		}
+		if side == Source2 && s.Source2 != "" {
+			return s.Source2
+		}
+	}
+	if r.Expectation == ExpectSubset && side == Source2 || r.Expectation == ExpectSuperset && side == Source1 {
+		return SeverityInfo
+	}
+	return SeverityError
+}
+
+// highestSeverity returns the highest severity of the value diffs, of
+// severity error, and the missing keys of the result, or "" if there are
+// none.
+func (r *Result) highestSeverity() Severity {
+	var highest Severity
+	raise := func(severity Severity) {
+		if severityRank[severity] > severityRank[highest] {
+			highest = severity
+		}
+	}
+	s := r.Summary
+	if s.MatchingKeys != s.IdenticalRows {
+		raise(SeverityError)
+	}
+	if s.KeysOnlyInSource1 > 0 {
+		raise(r.MissingKeySeverityOf(Source1))
+	}
+	if s.KeysOnlyInSource2 > 0 {
+		raise(r.MissingKeySeverityOf(Source2))
+	}
+	return highest
+}
+
+// Fails reports whether the result has a value diff or missing key of at
+// least severity threshold.
+func (r *Result) Fails(threshold Severity) bool {
+	highest := r.highestSeverity()
+	return highest != "" && severityRank[highest] >= severityRank[threshold]
+}
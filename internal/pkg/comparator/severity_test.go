@@ -0,0 +1,92 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"testing"
+)
+
+func TestMissingKeySeverity(t *testing.T) {
+	records1 := []datareader.Record{{"id": "1", "v": "a"}, {"id": "2", "v": "b"}}
+	records2 := []datareader.Record{{"id": "1", "v": "a"}, {"id": "3", "v": "c"}}
+	tests := []struct {
+		name     string
+		severity MissingKeySeverity
+		want     Severity
+		holds    bool
+		warning  bool
+	}{
+		{"default", MissingKeySeverity{}, SeverityError, false, true},
+		{"source2 info", MissingKeySeverity{Source1: SeverityError, Source2: SeverityInfo}, SeverityError, false, true},
+		{"source1 warning", MissingKeySeverity{Source1: SeverityWarning, Source2: SeverityInfo}, SeverityWarning, false, true},
+		{"both info", MissingKeySeverity{Source1: SeverityInfo, Source2: SeverityInfo}, SeverityInfo, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New("id")
+			c.SetMissingKeySeverity(tt.severity)
+			result, err := c.Compare(datareader.NewSliceReader(records1), datareader.NewSliceReader(records2))
+			if err != nil {
+				t.Fatalf("Compare() error = %v", err)
+			}
+			if result.Severity != tt.want {
+				t.Errorf("Severity got = %v, want %v", result.Severity, tt.want)
+			}
+			if result.Holds() != tt.holds {
+				t.Errorf("Holds() got = %v, want %v", result.Holds(), tt.holds)
+			}
+			if result.Fails(SeverityWarning) != tt.warning {
+				t.Errorf("Fails(warning) got = %v, want %v", result.Fails(SeverityWarning), tt.warning)
+			}
+			if !result.Fails(SeverityInfo) {
+				t.Errorf("Fails(info) got = false, want true")
+			}
+		})
+	}
+}
+
+func TestMissingKeySeverity_OverridesExpectation(t *testing.T) {
+	c := New("id")
+	c.SetExpectation(ExpectSubset)
+	c.SetMissingKeySeverity(MissingKeySeverity{Source2: SeverityWarning})
+	result, err := c.Compare(datareader.NewSliceReader([]datareader.Record{{"id": "1"}}),
+		datareader.NewSliceReader([]datareader.Record{{"id": "1"}, {"id": "2"}}))
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	if got := result.MissingKeySeverityOf(Source2); got != SeverityWarning {
+		t.Errorf("MissingKeySeverityOf(Source2) got = %v, want warning", got)
+	}
+	if got := result.MissingKeySeverityOf(Source1); got != SeverityError {
+		t.Errorf("MissingKeySeverityOf(Source1) got = %v, want error", got)
+	}
+	if result.Holds() {
+		t.Error("Holds() got = true, want false")
+	}
+}
+
+func TestFindings_MissingKeySeverity(t *testing.T) {
+	c := New("id")
+	c.SetMissingKeySeverity(MissingKeySeverity{Source2: SeverityInfo})
+	got := make(map[Side]Severity)
+	for f := range c.Findings(datareader.NewSliceReader([]datareader.Record{{"id": "1"}}),
+		datareader.NewSliceReader([]datareader.Record{{"id": "2"}})) {
+		if m, ok := f.(MissingKey); ok {
+			got[m.Side] = m.Severity
+		}
+	}
+	if got[Source1] != SeverityError || got[Source2] != SeverityInfo {
+		t.Errorf("MissingKey severities got = %v, want error for source1 and info for source2", got)
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	if got, err := ParseSeverity(""); err != nil || got != SeverityError {
+		t.Errorf("ParseSeverity(\"\") got = %v, %v, want error", got, err)
+	}
+	if got, err := ParseSeverity("info"); err != nil || got != SeverityInfo {
+		t.Errorf("ParseSeverity(info) got = %v, %v, want info", got, err)
+	}
+	if _, err := ParseSeverity("critical"); err == nil {
+		t.Error("ParseSeverity(critical) expected an error, got nil")
+	}
+}
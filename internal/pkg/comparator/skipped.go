@@ -0,0 +1,92 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"errors"
+)
+
+// MaxSkippedRecords is the number of skipped records listed per source. All
+// of them are counted in the Summary.
+const MaxSkippedRecords = 100
+
+// SkippedRecord locates a record skipped for failing to parse.
+type SkippedRecord struct {
+	// Record is the number of the record within its source, counting those
+	// that failed to parse.
+	Record int `yaml:"record" json:"record"`
+	// Line is the line the record starts on, if known.
+	Line int `yaml:"line,omitempty" json:"line,omitempty"`
+	// Key is the key of the record, if it was read before the error.
+	Key   string `yaml:"key,omitempty" json:"key,omitempty"`
+	Error string `yaml:"error" json:"error"`
+}
+
+// SkippedRecords lists the first records of each source skipped for failing
+// to parse.
+type SkippedRecords struct {
+	Source1 []SkippedRecord `yaml:"source1,omitempty"`
+	Source2 []SkippedRecord `yaml:"source2,omitempty"`
+}
+
+// SetSkipParseErrors makes Compare skip the records its readers fail to
+// parse but can read past, such as a CSV row with an unbalanced quote,
+// instead of failing on the first. Skipped records are counted and listed
+// in the Result, and the keys read of them are left out of the keys only in
+// the other source.
+func (c *StreamComparator) SetSkipParseErrors(on bool) {
+	c.skipParseErrors = on
+}
+
+// recoverable returns err as a ParseError the reader can read past, or nil.
+func recoverable(err error) *datareader.ParseError {
+	var perr *datareader.ParseError
+	if errors.As(err, &perr) && perr.Recoverable {
+		return perr
+	}
+	return nil
+}
+
+// skip counts and lists the record of side that failed to parse with perr,
+// and remembers its key, if read, so it is not reported as only in the
+// other source.
+func (c *StreamComparator) skip(side Side, perr *datareader.ParseError) {
+	c.AddParseError(side)
+	if c.result.Skipped == nil {
+		c.result.Skipped = &SkippedRecords{}
+	}
+	count, listed := &c.result.Summary.Source1SkippedRecords, &c.result.Skipped.Source1
+	if side == Source2 {
+		count, listed = &c.result.Summary.Source2SkippedRecords, &c.result.Skipped.Source2
+	}
+	*count++
+
+	skipped := SkippedRecord{Record: perr.Record, Line: perr.Line, Error: perr.Err.Error()}
+	if perr.Fields != nil {
+		if key, err := c.keyOf(perr.Fields, side); err == nil {
+			skipped.Key = key
+			if c.skippedKeys[side-1] == nil {
+				c.skippedKeys[side-1] = make(map[string]bool)
+			}
+			c.skippedKeys[side-1][key] = true
+		}
+	}
+	if len(*listed) < MaxSkippedRecords {
+		*listed = append(*listed, skipped)
+	}
+}
+
+// withoutSkipped returns keys, found only in a source, without those of the
+// records of the other source skipped for failing to parse.
+func (c *StreamComparator) withoutSkipped(keys []string, other Side) []string {
+	skipped := c.skippedKeys[other-1]
+	if len(skipped) == 0 {
+		return keys
+	}
+	kept := keys[:0]
+	for _, key := range keys {
+		if !skipped[key] {
+			kept = append(kept, key)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,58 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/config"
+	"data-comparator/internal/pkg/datareader"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// openCSV writes content to a CSV file and opens a reader of it.
+func openCSV(t *testing.T, content string) datareader.DataReader {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "data.csv")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := datareader.New(config.Source{Type: "csv", Path: path})
+	if err != nil {
+		t.Fatalf("datareader.New() error = %v", err)
+	}
+	t.Cleanup(func() { reader.Close() })
+	return reader
+}
+
+func TestCompare_ParseErrors(t *testing.T) {
+	// Row 2 has an extra field and row 3 an unbalanced quote.
+	source1 := "id,name\n1,alice\n2,bob,extra\n\"3,carol\n"
+	source2 := "id,name\n1,alice\n2,bob\n"
+
+	_, err := New("id").Compare(openCSV(t, source1), openCSV(t, source2))
+	if err == nil || !strings.Contains(err.Error(), "record 2, line 3") {
+		t.Errorf("Compare() error got = %v, want the parse error of record 2", err)
+	}
+
+	c := New("id")
+	c.SetSkipParseErrors(true)
+	result, err := c.Compare(openCSV(t, source1), openCSV(t, source2))
+	if err != nil {
+		t.Fatalf("Compare() skipping parse errors error = %v", err)
+	}
+	if s := result.Summary; s.Source1SkippedRecords != 2 || s.IdenticalRows != 1 || s.KeysOnlyInSource2 != 0 {
+		t.Errorf("Summary got = %+v, want 2 skipped records, 1 identical row and key 2 left out of keys only in source2", s)
+	}
+	if result.Skipped == nil || len(result.Skipped.Source1) != 2 {
+		t.Fatalf("Skipped got = %+v, want 2 records of source1", result.Skipped)
+	}
+	got := result.Skipped.Source1[0]
+	want := SkippedRecord{Record: 2, Line: 3, Key: "2", Error: got.Error}
+	if !reflect.DeepEqual(got, want) || !strings.Contains(got.Error, "wrong number of fields") {
+		t.Errorf("Skipped.Source1[0] got = %+v, want %+v with the field count error", got, want)
+	}
+	if got := result.Skipped.Source1[1]; got.Record != 3 || got.Line != 4 || got.Key != "" {
+		t.Errorf("Skipped.Source1[1] got = %+v, want record 3 on line 4 without a key", got)
+	}
+}
@@ -0,0 +1,59 @@
+package comparator
+
+import "time"
+
+// Snapshot reports the state of a comparison in progress. It is taken on
+// request with RequestSnapshot, independent of the progress interval.
+type Snapshot struct {
+	TakenAt time.Time `yaml:"taken_at" json:"taken_at"`
+	// Elapsed is the time since the comparison started, less PausedFor.
+	Elapsed time.Duration `yaml:"elapsed" json:"elapsed"`
+	// Paused is set while the comparison is paused with Pause.
+	Paused    bool          `yaml:"paused" json:"paused"`
+	PausedFor time.Duration `yaml:"paused_for,omitempty" json:"paused_for,omitempty"`
+	Summary   Summary       `yaml:"summary" json:"summary"`
+	// Source1LastRecord and Source2LastRecord are when each source last
+	// produced a record, zero before its first.
+	Source1LastRecord time.Time `yaml:"source1_last_record" json:"source1_last_record"`
+	Source2LastRecord time.Time `yaml:"source2_last_record" json:"source2_last_record"`
+	// Source1Pending and Source2Pending count the keys read from each source
+	// that are held in memory until their counterpart arrives.
+	Source1Pending int            `yaml:"source1_pending" json:"source1_pending"`
+	Source2Pending int            `yaml:"source2_pending" json:"source2_pending"`
+	Stalls         []Stall        `yaml:"stalls,omitempty" json:"stalls,omitempty"`
+	Incomplete     []Incompletion `yaml:"incomplete,omitempty" json:"incomplete,omitempty"`
+	// Lag is the consistency lag of the keys matched so far, when measured
+	// with SetLag.
+	Lag *LagStats `yaml:"lag,omitempty" json:"lag,omitempty"`
+}
+
+// RequestSnapshot asks the comparison in progress to report its state to the
+// OnSnapshot hook. The snapshot is taken as soon as the next record is read,
+// or at the next check while a watched source is waiting for one or the
+// comparison is paused. It may be called from any goroutine, e.g. a signal
+// handler; requests made before the snapshot is taken are answered together.
+func (c *StreamComparator) RequestSnapshot() {
+	c.snapshotRequested.Store(true)
+}
+
+// maybeReportSnapshot answers a pending RequestSnapshot.
+func (c *StreamComparator) maybeReportSnapshot() {
+	if !c.snapshotRequested.Swap(false) || c.hooks.OnSnapshot == nil {
+		return
+	}
+	now := c.clock.Now()
+	c.hooks.OnSnapshot(Snapshot{
+		TakenAt:           now,
+		Elapsed:           c.activeFor(now),
+		Paused:            c.Paused(),
+		PausedFor:         c.pausedFor(now),
+		Summary:           c.result.Summary,
+		Source1LastRecord: c.lastRecord[0],
+		Source2LastRecord: c.lastRecord[1],
+		Source1Pending:    len(c.pending1),
+		Source2Pending:    len(c.pending2),
+		Stalls:            append([]Stall(nil), c.result.Stalls...),
+		Incomplete:        append([]Incompletion(nil), c.result.Incomplete...),
+		Lag:               c.lagTotal.stats(),
+	})
+}
@@ -0,0 +1,69 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCompare_Snapshot(t *testing.T) {
+	start := time.Date(2025, 9, 10, 12, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+	reader1 := datareader.NewSliceReader([]datareader.Record{{"id": "a"}, {"id": "b"}, {"id": "c"}})
+	reader2 := datareader.NewSliceReader([]datareader.Record{{"id": "a"}, {"id": "x"}})
+
+	var snapshots []Snapshot
+	c := New("id")
+	c.SetClock(clock)
+	c.SetHooks(Hooks{
+		OnMatch: func(key string, rec1, rec2 datareader.Record) {
+			clock.Advance(time.Minute)
+			c.RequestSnapshot()
+			c.RequestSnapshot()
+		},
+		OnSnapshot: func(s Snapshot) { snapshots = append(snapshots, s) },
+	})
+	if _, err := c.Compare(reader1, reader2); err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+
+	want := []Snapshot{{
+		TakenAt:           start.Add(time.Minute),
+		Elapsed:           time.Minute,
+		Summary:           Summary{Source1Rows: 1, Source2Rows: 1, MatchingKeys: 1, IdenticalRows: 1},
+		Source1LastRecord: start,
+		Source2LastRecord: start,
+	}}
+	if !reflect.DeepEqual(snapshots, want) {
+		t.Errorf("Snapshots got = %+v, want %+v", snapshots, want)
+	}
+}
+
+func TestFindings_SnapshotWhileWaiting(t *testing.T) {
+	useFastStallChecks(t)
+	reader1 := datareader.NewSliceReader([]datareader.Record{{"id": "a"}, {"id": "b"}})
+	reader2 := newHangingReader(t, datareader.Record{"id": "a"})
+	blocked, release := reader2.blocked, reader2.release
+
+	c := New("id")
+	c.SetReadTimeout(Source2, time.Hour)
+	var snapshots []Snapshot
+	c.SetHooks(Hooks{OnSnapshot: func(s Snapshot) {
+		snapshots = append(snapshots, s)
+		close(release)
+	}})
+	go func() {
+		<-blocked
+		c.RequestSnapshot()
+	}()
+	for range c.Findings(reader1, reader2) {
+	}
+
+	if len(snapshots) != 1 {
+		t.Fatalf("Snapshots got = %+v, want one taken while source2 was waiting", snapshots)
+	}
+	if s := snapshots[0]; s.Summary.Source1Rows != 2 || s.Summary.Source2Rows != 1 || s.Source1Pending != 1 {
+		t.Errorf("Snapshot got = %+v, want 2 records of source1 read, b pending and 1 record of source2", s)
+	}
+}
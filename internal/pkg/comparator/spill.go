@@ -0,0 +1,224 @@
+package comparator
+
+import (
+	"bufio"
+	"data-comparator/internal/pkg/datareader"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Defaults of SpillOptions.
+const (
+	DefaultSpillMemoryRecords = 1000000
+	DefaultSpillPartitions    = 64
+)
+
+func init() {
+	// Concrete types that occur inside records, for gob to encode them as interface values.
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+	gob.Register(time.Time{})
+	gob.Register(json.Number(""))
+}
+
+// SpillOptions configure an external hash join for inputs too large, and too
+// unordered, to hold their unmatched records in memory.
+type SpillOptions struct {
+	// Dir holds the temporary spill files. The system temp directory is used if empty.
+	Dir string
+	// MemoryRecords is the number of unmatched records held in memory. Once
+	// exceeded, all further records are partitioned by key hash into spill
+	// files. Defaults to DefaultSpillMemoryRecords.
+	MemoryRecords int
+	// Partitions is the number of spill files per source. Each partition is
+	// joined in memory on its own, so it must fit the memory budget.
+	// Defaults to DefaultSpillPartitions.
+	Partitions int
+}
+
+// SetSpill enables spilling to disk in Compare. Without it, Compare holds
+// every unmatched record in memory.
+func (c *StreamComparator) SetSpill(opts SpillOptions) {
+	if opts.MemoryRecords <= 0 {
+		opts.MemoryRecords = DefaultSpillMemoryRecords
+	}
+	if opts.Partitions <= 0 {
+		opts.Partitions = DefaultSpillPartitions
+	}
+	c.spill = &opts
+}
+
+// spillPartition is the pair of spill files of one key hash partition.
+type spillPartition struct {
+	files    [2]*os.File
+	buffers  [2]*bufio.Writer
+	encoders [2]*gob.Encoder
+}
+
+// spiller partitions the records of both sources into spill files.
+type spiller struct {
+	dir        string
+	partitions []spillPartition
+	// unmatched holds the keys of each source left unmatched in joined partitions.
+	unmatched [2][]string
+}
+
+func newSpiller(opts *SpillOptions) (*spiller, error) {
+	dir, err := os.MkdirTemp(opts.Dir, "stream-diff-spill-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spill directory: %w", err)
+	}
+	s := &spiller{dir: dir, partitions: make([]spillPartition, opts.Partitions)}
+	for i := range s.partitions {
+		p := &s.partitions[i]
+		for side := range p.files {
+			f, err := os.Create(filepath.Join(dir, fmt.Sprintf("%d-%d.gob", i, side+1)))
+			if err != nil {
+				s.remove()
+				return nil, fmt.Errorf("failed to create spill file: %w", err)
+			}
+			p.files[side] = f
+			p.buffers[side] = bufio.NewWriter(f)
+			p.encoders[side] = gob.NewEncoder(p.buffers[side])
+		}
+	}
+	return s, nil
+}
+
+// write appends rec to the spill file of its key's partition.
+func (s *spiller) write(side Side, key string, rec datareader.Record) error {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	p := &s.partitions[h.Sum32()%uint32(len(s.partitions))]
+	if err := p.encoders[side-1].Encode(rec); err != nil {
+		return fmt.Errorf("failed to spill record: %w", err)
+	}
+	return nil
+}
+
+// remove closes and deletes all spill files.
+func (s *spiller) remove() {
+	for _, p := range s.partitions {
+		for _, f := range p.files {
+			if f != nil {
+				f.Close()
+			}
+		}
+	}
+	os.RemoveAll(s.dir)
+}
+
+// startSpilling moves the pending records of both sides to spill files. Every
+// record added afterwards is spilled too, until joinSpilled.
+func (c *StreamComparator) startSpilling() error {
+	s, err := newSpiller(c.spill)
+	if err != nil {
+		return err
+	}
+	c.spiller = s
+	// Spilled keys are no longer matched while reading.
+	c.outstanding = nil
+	c.arrivals = nil
+	for side, pending := range [2]map[string]datareader.Record{c.pending1, c.pending2} {
+		for key, rec := range pending {
+			if err := s.write(Side(side+1), key, rec); err != nil {
+				return err
+			}
+		}
+	}
+	c.pending1 = make(map[string]datareader.Record)
+	c.pending2 = make(map[string]datareader.Record)
+	return nil
+}
+
+// joinSpilled joins the spill files partition by partition. Keys left
+// unmatched in a partition are reported right away, keeping memory bounded by
+// the largest partition.
+func (c *StreamComparator) joinSpilled() error {
+	s := c.spiller
+	for i := range s.partitions {
+		p := &s.partitions[i]
+		for side := range p.files {
+			if err := p.buffers[side].Flush(); err != nil {
+				return fmt.Errorf("failed to write spill file: %w", err)
+			}
+			if _, err := p.files[side].Seek(0, io.SeekStart); err != nil {
+				return fmt.Errorf("failed to read spill file: %w", err)
+			}
+		}
+		if err := c.joinPartition(p); err != nil {
+			return fmt.Errorf("spill partition %d: %w", i, err)
+		}
+
+		for side, pending := range [2]map[string]datareader.Record{c.pending1, c.pending2} {
+			keys := sortedKeys(pending)
+			hook := c.hooks.OnOnlyInSource1
+			if side == 1 {
+				hook = c.hooks.OnOnlyInSource2
+			}
+			for _, key := range keys {
+				if hook != nil {
+					hook(key, pending[key])
+				}
+			}
+			s.unmatched[side] = append(s.unmatched[side], keys...)
+		}
+		c.pending1 = make(map[string]datareader.Record)
+		c.pending2 = make(map[string]datareader.Record)
+	}
+	return nil
+}
+
+// joinPartition joins the records of one partition, those of source1 first.
+func (c *StreamComparator) joinPartition(p *spillPartition) error {
+	for side, f := range p.files {
+		decoder := gob.NewDecoder(bufio.NewReader(f))
+		own, other := c.pending1, c.pending2
+		if side == 1 {
+			own, other = c.pending2, c.pending1
+		}
+		for {
+			var rec datareader.Record
+			if err := decoder.Decode(&rec); err == io.EOF {
+				break
+			} else if err != nil {
+				return fmt.Errorf("failed to read spilled record: %w", err)
+			}
+			if err := c.join(rec, own, other, Side(side+1)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// addSpilled counts and checks a record like Add, then spills it.
+func (c *StreamComparator) addSpilled(side Side, rec datareader.Record) error {
+	rec, n := c.count(side, rec)
+	key, err := c.keyOf(rec, side)
+	if err == nil {
+		err = c.spiller.write(side, key, rec)
+	}
+	if err != nil {
+		return fmt.Errorf("%s record %d: %w", side, n, err)
+	}
+	c.maybeReportProgress()
+	return nil
+}
+
+// mergeUnmatched adds the keys left unmatched in spilled partitions to keys.
+func (s *spiller) mergeUnmatched(side Side, keys []string) []string {
+	if s == nil || len(s.unmatched[side-1]) == 0 {
+		return keys
+	}
+	keys = append(keys, s.unmatched[side-1]...)
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,95 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/config"
+	"data-comparator/internal/pkg/datareader"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompare_Spill(t *testing.T) {
+	var records1, records2 []datareader.Record
+	for i := 0; i < 50; i++ {
+		records1 = append(records1, datareader.Record{"id": fmt.Sprint(i), "n": float64(i), "tags": []interface{}{"a"}})
+		j := 59 - i
+		rec := datareader.Record{"id": fmt.Sprint(j), "n": float64(j), "tags": []interface{}{"a"}}
+		if j%7 == 0 {
+			rec["n"] = -1.0
+		}
+		records2 = append(records2, rec)
+	}
+
+	want, err := New("id").Compare(datareader.NewSliceReader(records1), datareader.NewSliceReader(records2))
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+
+	dir := t.TempDir()
+	var onlyIn1 []string
+	c := New("id")
+	c.SetSpill(SpillOptions{Dir: dir, MemoryRecords: 5, Partitions: 3})
+	c.SetHooks(Hooks{OnOnlyInSource1: func(key string, rec datareader.Record) {
+		if rec["id"] != key {
+			t.Errorf("OnOnlyInSource1(%s) got record %v", key, rec)
+		}
+		onlyIn1 = append(onlyIn1, key)
+	}})
+	got, err := c.Compare(datareader.NewSliceReader(records1), datareader.NewSliceReader(records2))
+	if err != nil {
+		t.Fatalf("Compare() with spill error = %v", err)
+	}
+
+	if got.Summary != want.Summary {
+		t.Errorf("Summary got = %+v, want %+v", got.Summary, want.Summary)
+	}
+	if !reflect.DeepEqual(got.ValueDiffs, want.ValueDiffs) {
+		t.Errorf("ValueDiffs got = %v, want %v", got.ValueDiffs, want.ValueDiffs)
+	}
+	if !reflect.DeepEqual(got.KeysOnly, want.KeysOnly) {
+		t.Errorf("KeysOnly got = %+v, want %+v", got.KeysOnly, want.KeysOnly)
+	}
+	if len(onlyIn1) != len(want.KeysOnly.InSource1) {
+		t.Errorf("OnOnlyInSource1 called %d times, want %d", len(onlyIn1), len(want.KeysOnly.InSource1))
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Spill directory got %d entries after Compare, want none", len(entries))
+	}
+}
+
+func TestCompareConfigs_SpillExactNumbers(t *testing.T) {
+	dir := t.TempDir()
+	var data1, data2 strings.Builder
+	for i := 0; i < 20; i++ {
+		fmt.Fprintf(&data1, "{\"id\": %d, \"amount\": 9007199254740993, \"tags\": [1.50]}\n", 9007199254740993+i)
+		amount := "9007199254740993"
+		if i == 7 {
+			amount = "9007199254740992"
+		}
+		fmt.Fprintf(&data2, "{\"id\": %d, \"amount\": %s, \"tags\": [1.50]}\n", 9007199254741012-i, amount)
+	}
+	path1, path2 := filepath.Join(dir, "source1.json"), filepath.Join(dir, "source2.json")
+	for path, data := range map[string]string{path1: data1.String(), path2: data2.String()} {
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	exact := &config.ParserConfig{JSONNumbers: "exact"}
+	config1 := &config.Config{
+		Source:     config.Source{Type: "json", Path: path1, ParserConfig: exact},
+		Comparison: &config.Comparison{Spill: &config.Spill{Dir: t.TempDir(), MemoryRecords: 1, Partitions: 2}},
+	}
+	config2 := &config.Config{Source: config.Source{Type: "json", Path: path2, ParserConfig: exact}}
+
+	result, err := CompareConfigs(config1, config2, "id", "id")
+	if err != nil {
+		t.Fatalf("CompareConfigs() with spill error = %v", err)
+	}
+	diffs := result.ValueDiffs["9007199254741005"]
+	if result.Summary.MatchingKeys != 20 || len(result.ValueDiffs) != 1 || len(diffs) != 1 || diffs[0].Field != "amount" {
+		t.Errorf("result got summary %+v and diffs %v, want only the amount of key 9007199254741005 differing", result.Summary, result.ValueDiffs)
+	}
+}
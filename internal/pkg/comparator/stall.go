@@ -0,0 +1,203 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"fmt"
+	"io"
+	"time"
+)
+
+// stallCheckInterval is how often a source that is being waited on is
+// checked for a stall.
+var stallCheckInterval = time.Second
+
+// StallOptions configure the detection of sources that stop producing
+// records, such as a hung query or a network read that never returns.
+type StallOptions struct {
+	// Timeout is how long a source may produce no record before it counts
+	// as stalled.
+	Timeout time.Duration
+	// Fail ends the comparison with a StallError once a source stalls,
+	// instead of only reporting the stall and waiting on.
+	Fail bool
+}
+
+// Stall is a source that produced no record for the stall timeout.
+type Stall struct {
+	Source string `yaml:"source"`
+	// Since is when the comparator started waiting for the source's next record.
+	Since time.Time `yaml:"since"`
+	// Detected is when the wait exceeded the timeout.
+	Detected time.Time `yaml:"detected"`
+	// Records is the number of records read from the source before it stalled.
+	Records int `yaml:"records"`
+}
+
+// StallError ends a comparison whose source stalled while StallOptions.Fail is set.
+type StallError struct {
+	Stall
+	Timeout time.Duration
+}
+
+func (e *StallError) Error() string {
+	return fmt.Sprintf("%s stalled: no record for %s since %s, after %d records",
+		e.Source, e.Timeout, e.Since.Format(time.RFC3339), e.Records)
+}
+
+// Heartbeat is a periodic report of a running comparison, including when
+// each source last produced a record.
+type Heartbeat struct {
+	Summary Summary
+	// Source1LastRecord and Source2LastRecord are when each source last
+	// produced a record, zero before its first.
+	Source1LastRecord time.Time
+	Source2LastRecord time.Time
+	// Stalled lists the sources that are stalled right now.
+	Stalled []Side
+	// Outstanding is how the keys waiting for their counterpart changed
+	// since the previous heartbeat. It is nil once records are spilled.
+	Outstanding *OutstandingChange
+	// Lag is the consistency lag of the keys matched since the previous
+	// heartbeat, when measured with SetLag. It is nil if none were matched.
+	Lag *LagStats
+}
+
+// SetStall enables stall detection in Compare and Findings. Each source is
+// then read on its own goroutine, so a read that never returns is noticed.
+// A zero timeout disables it.
+func (c *StreamComparator) SetStall(opts StallOptions) {
+	c.stall = opts
+}
+
+// readResult is the outcome of a single Read.
+type readResult struct {
+	rec datareader.Record
+	err error
+}
+
+// watchedReader reads a source on a separate goroutine, while the
+// comparator's goroutine waits for each record and checks for a stall, the
+// source's read timeout and the deadline. A source that times out ends
+// early with io.EOF.
+type watchedReader struct {
+	c        *StreamComparator
+	side     Side
+	reader   datareader.DataReader
+	requests chan struct{}
+	results  chan readResult
+	ticker   *time.Ticker
+	pending  bool
+}
+
+// watch wraps reader in a watchedReader when stall detection, a read
+// timeout or a deadline is set. The returned stop function must be called
+// once reading is over.
+func (c *StreamComparator) watch(reader datareader.DataReader, side Side) (datareader.DataReader, func()) {
+	if !c.watching() {
+		return reader, func() {}
+	}
+	w := &watchedReader{
+		c:        c,
+		side:     side,
+		reader:   reader,
+		requests: make(chan struct{}),
+		results:  make(chan readResult, 1),
+		ticker:   time.NewTicker(stallCheckInterval),
+	}
+	go func() {
+		for range w.requests {
+			rec, err := w.reader.Read()
+			w.results <- readResult{rec, err}
+		}
+	}()
+	return w, w.stop
+}
+
+// Read waits for the next record of the source. While waiting, it reports
+// progress and the source's stall, and fails with a StallError if asked to.
+// Time the comparator spends paused does not count as waiting.
+func (w *watchedReader) Read() (datareader.Record, error) {
+	since := w.c.clock.Now()
+	pausedBefore := w.c.pausedFor(since)
+	if reason := w.c.timedOut(w.side, 0, since); reason != "" {
+		w.c.markIncomplete(w.side, reason)
+		return nil, io.EOF
+	}
+	if !w.pending {
+		w.requests <- struct{}{}
+		w.pending = true
+	}
+	for {
+		select {
+		case res := <-w.results:
+			w.pending = false
+			w.c.stalled[w.side-1] = false
+			return res.rec, res.err
+		case <-w.ticker.C:
+			now := w.c.clock.Now()
+			waited := now.Sub(since) - (w.c.pausedFor(now) - pausedBefore)
+			if err := w.c.checkStall(w.side, since, waited); err != nil {
+				return nil, err
+			}
+			if reason := w.c.timedOut(w.side, waited, now); reason != "" {
+				w.c.markIncomplete(w.side, reason)
+				return nil, io.EOF
+			}
+			w.c.maybeReportProgress()
+		}
+	}
+}
+
+// Close closes the underlying reader.
+func (w *watchedReader) Close() error {
+	return w.reader.Close()
+}
+
+// stop ends the reading goroutine once its pending Read, if any, returns.
+func (w *watchedReader) stop() {
+	w.ticker.Stop()
+	close(w.requests)
+}
+
+// checkStall records a stall of side once it has been waited on since since
+// for the stall timeout, not counting paused time in waited.
+func (c *StreamComparator) checkStall(side Side, since time.Time, waited time.Duration) error {
+	now := c.clock.Now()
+	if c.stall.Timeout <= 0 || c.stalled[side-1] || waited < c.stall.Timeout {
+		return nil
+	}
+	c.stalled[side-1] = true
+	records := c.result.Summary.Source1Rows
+	if side == Source2 {
+		records = c.result.Summary.Source2Rows
+	}
+	stall := Stall{Source: side.String(), Since: since, Detected: now, Records: records}
+	c.result.Stalls = append(c.result.Stalls, stall)
+	if c.hooks.OnStall != nil {
+		c.hooks.OnStall(side, stall)
+	}
+	if c.stall.Fail {
+		return &StallError{Stall: stall, Timeout: c.stall.Timeout}
+	}
+	return nil
+}
+
+// heartbeat reports the running counts and the last record time of each source.
+func (c *StreamComparator) heartbeat() Heartbeat {
+	h := Heartbeat{
+		Summary:           c.result.Summary,
+		Source1LastRecord: c.lastRecord[0],
+		Source2LastRecord: c.lastRecord[1],
+		Outstanding:       c.outstanding.report(),
+		Lag:               c.lagInterval.stats(),
+	}
+	if c.lagInterval != nil {
+		c.lagInterval = newLagRecorder()
+	}
+	for _, side := range []Side{Source1, Source2} {
+		if c.stalled[side-1] {
+			h.Stalled = append(h.Stalled, side)
+		}
+	}
+	return h
+}
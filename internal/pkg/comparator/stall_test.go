@@ -0,0 +1,135 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// hangingReader returns its records, then blocks until released.
+type hangingReader struct {
+	records []datareader.Record
+	blocked chan struct{}
+	release chan struct{}
+}
+
+func newHangingReader(t *testing.T, records ...datareader.Record) *hangingReader {
+	r := &hangingReader{records: records, blocked: make(chan struct{}), release: make(chan struct{})}
+	t.Cleanup(func() {
+		select {
+		case <-r.release:
+		default:
+			close(r.release)
+		}
+	})
+	return r
+}
+
+func (r *hangingReader) Read() (datareader.Record, error) {
+	if len(r.records) > 0 {
+		rec := r.records[0]
+		r.records = r.records[1:]
+		return rec, nil
+	}
+	if r.blocked != nil {
+		close(r.blocked)
+		r.blocked = nil
+		<-r.release
+	}
+	return nil, io.EOF
+}
+
+func (r *hangingReader) Close() error { return nil }
+
+func useFastStallChecks(t *testing.T) {
+	previous := stallCheckInterval
+	stallCheckInterval = time.Millisecond
+	t.Cleanup(func() { stallCheckInterval = previous })
+}
+
+func TestCompare_Stall(t *testing.T) {
+	useFastStallChecks(t)
+	start := time.Date(2025, 9, 10, 12, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+	reader1 := datareader.NewSliceReader([]datareader.Record{{"id": "a"}, {"id": "b"}})
+	reader2 := newHangingReader(t, datareader.Record{"id": "a"})
+	blocked := reader2.blocked
+
+	stalled := make(chan Stall, 1)
+	var heartbeats []Heartbeat
+	c := New("id")
+	c.SetClock(clock)
+	c.SetProgressInterval(time.Minute)
+	c.SetStall(StallOptions{Timeout: time.Minute})
+	c.SetHooks(Hooks{
+		OnStall:     func(side Side, stall Stall) { stalled <- stall },
+		OnHeartbeat: func(h Heartbeat) { heartbeats = append(heartbeats, h) },
+	})
+
+	type outcome struct {
+		result *Result
+		err    error
+	}
+	done := make(chan outcome)
+	go func() {
+		result, err := c.Compare(reader1, reader2)
+		done <- outcome{result, err}
+	}()
+
+	<-blocked
+	clock.Advance(2 * time.Minute)
+	stall := <-stalled
+	close(reader2.release)
+	out := <-done
+	if out.err != nil {
+		t.Fatalf("Compare() error = %v", out.err)
+	}
+
+	want := Stall{Source: "source2", Since: start, Detected: start.Add(2 * time.Minute), Records: 1}
+	if stall != want {
+		t.Errorf("Stall got = %+v, want %+v", stall, want)
+	}
+	if !reflect.DeepEqual(out.result.Stalls, []Stall{want}) {
+		t.Errorf("Result.Stalls got = %+v, want %+v", out.result.Stalls, []Stall{want})
+	}
+	if len(heartbeats) == 0 {
+		t.Fatalf("Expected a heartbeat while source2 stalled")
+	}
+	h := heartbeats[0]
+	if !reflect.DeepEqual(h.Stalled, []Side{Source2}) || !h.Source2LastRecord.Equal(start) || h.Summary.Source2Rows != 1 {
+		t.Errorf("Heartbeat got = %+v, want source2 stalled since its record at %s", h, start)
+	}
+}
+
+func TestFindings_StallFails(t *testing.T) {
+	useFastStallChecks(t)
+	clock := NewManualClock(time.Date(2025, 9, 10, 12, 0, 0, 0, time.UTC))
+	reader1 := newHangingReader(t)
+	blocked := reader1.blocked
+	go func() {
+		<-blocked
+		clock.Advance(time.Hour)
+	}()
+
+	c := New("id")
+	c.SetClock(clock)
+	c.SetStall(StallOptions{Timeout: time.Minute, Fail: true})
+	var kinds []string
+	var last Finding
+	for f := range c.Findings(reader1, datareader.NewSliceReader(nil)) {
+		kinds = append(kinds, f.Kind())
+		last = f
+	}
+
+	if want := []string{"stalled", "parse_error"}; !reflect.DeepEqual(kinds, want) {
+		t.Errorf("Finding kinds got = %v, want %v", kinds, want)
+	}
+	failure, _ := last.(ParseFailure)
+	var stallErr *StallError
+	if !errors.As(failure.Err, &stallErr) || stallErr.Source != "source1" || stallErr.Timeout != time.Minute {
+		t.Errorf("Last finding got = %v, want a StallError of source1", last)
+	}
+}
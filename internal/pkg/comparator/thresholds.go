@@ -0,0 +1,100 @@
+package comparator
+
+import (
+	"fmt"
+	"math"
+)
+
+// Thresholds bound the discrepancies a comparison may find and still pass,
+// so that it can gate a pipeline. A nil bound is not checked.
+type Thresholds struct {
+	// MaxDiffs is the largest number of discrepant keys.
+	MaxDiffs *int `yaml:"max_diffs,omitempty"`
+	// MaxDiffPercent is the largest percentage of discrepant keys among the
+	// keys the parity counts.
+	MaxDiffPercent *float64 `yaml:"max_diff_percent,omitempty"`
+	// MaxFieldMismatchRate is the largest mismatch rate of any one field, a
+	// percentage of the matching keys as in FieldStat.
+	MaxFieldMismatchRate *float64 `yaml:"max_field_mismatch_rate,omitempty"`
+}
+
+// SetThresholds sets the bounds the discrepancies of the result are checked
+// against when the comparison finishes.
+func (c *StreamComparator) SetThresholds(thresholds Thresholds) {
+	c.thresholds = thresholds
+}
+
+// validate rejects negative bounds.
+func (t Thresholds) validate() error {
+	if t.MaxDiffs != nil && *t.MaxDiffs < 0 {
+		return fmt.Errorf("thresholds.max_diffs must not be negative, got %d", *t.MaxDiffs)
+	}
+	if t.MaxDiffPercent != nil && (*t.MaxDiffPercent < 0 || math.IsNaN(*t.MaxDiffPercent)) {
+		return fmt.Errorf("thresholds.max_diff_percent must not be negative, got %v", *t.MaxDiffPercent)
+	}
+	if t.MaxFieldMismatchRate != nil && (*t.MaxFieldMismatchRate < 0 || math.IsNaN(*t.MaxFieldMismatchRate)) {
+		return fmt.Errorf("thresholds.max_field_mismatch_rate must not be negative, got %v", *t.MaxFieldMismatchRate)
+	}
+	return nil
+}
+
+// DiscrepantKeys returns the number of keys with differing records and of
+// keys only in a source the expectation does not allow.
+func (r *Result) DiscrepantKeys() int {
+	discrepant, _ := r.discrepancies()
+	return discrepant
+}
+
+// DiffPercent returns the percentage of discrepant keys among the keys the
+// parity counts, the complement of the parity.
+func (r *Result) DiffPercent() float64 {
+	discrepant, keys := r.discrepancies()
+	if keys == 0 {
+		return 0
+	}
+	return 100 * float64(discrepant) / float64(keys)
+}
+
+// discrepancies returns the number of discrepant keys and of the keys the
+// parity counts.
+func (r *Result) discrepancies() (discrepant, keys int) {
+	s := r.Summary
+	discrepant, keys = s.MatchingKeys-s.IdenticalRows, s.MatchingKeys
+	if !r.AllowsOnlyIn(Source1) {
+		discrepant += s.KeysOnlyInSource1
+		keys += s.KeysOnlyInSource1
+	}
+	if !r.AllowsOnlyIn(Source2) {
+		discrepant += s.KeysOnlyInSource2
+		keys += s.KeysOnlyInSource2
+	}
+	return discrepant, keys
+}
+
+// FieldExceeds reports whether the mismatch rate of a field exceeds
+// Thresholds.MaxFieldMismatchRate, if set.
+func (r *Result) FieldExceeds(stat FieldStat) bool {
+	return r.Thresholds != nil && r.Thresholds.MaxFieldMismatchRate != nil && stat.MismatchRate > *r.Thresholds.MaxFieldMismatchRate
+}
+
+// exceededThresholds describes the thresholds of the result its
+// discrepancies exceed.
+func (r *Result) exceededThresholds() []string {
+	t := r.Thresholds
+	if t == nil {
+		return nil
+	}
+	var exceeded []string
+	if t.MaxDiffs != nil && r.DiscrepantKeys() > *t.MaxDiffs {
+		exceeded = append(exceeded, fmt.Sprintf("%d discrepant keys exceed max_diffs %d", r.DiscrepantKeys(), *t.MaxDiffs))
+	}
+	if t.MaxDiffPercent != nil && r.DiffPercent() > *t.MaxDiffPercent {
+		exceeded = append(exceeded, fmt.Sprintf("%.2f%% discrepant keys exceed max_diff_percent %v", r.DiffPercent(), *t.MaxDiffPercent))
+	}
+	for _, stat := range r.FieldStats {
+		if r.FieldExceeds(stat) {
+			exceeded = append(exceeded, fmt.Sprintf("field %s mismatch rate %.2f%% exceeds max_field_mismatch_rate %v", stat.Field, stat.MismatchRate, *t.MaxFieldMismatchRate))
+		}
+	}
+	return exceeded
+}
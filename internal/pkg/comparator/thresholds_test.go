@@ -0,0 +1,82 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"testing"
+)
+
+func TestThresholds(t *testing.T) {
+	// Key 2 differs, 4 is only in source1 and 5 only in source2: 3 of 5 keys.
+	records1 := []datareader.Record{{"id": "1", "v": "a"}, {"id": "2", "v": "b"}, {"id": "3", "v": "c"}, {"id": "4", "v": "d"}}
+	records2 := []datareader.Record{{"id": "1", "v": "a"}, {"id": "2", "v": "x"}, {"id": "3", "v": "c"}, {"id": "5", "v": "e"}}
+	three, two := 3, 2
+	sixty, fifty, twenty := 60.0, 50.0, 20.0
+	tests := []struct {
+		name        string
+		thresholds  Thresholds
+		expectation Expectation
+		exceeded    int
+	}{
+		{"none", Thresholds{}, ExpectEqual, 0},
+		{"max diffs met", Thresholds{MaxDiffs: &three}, ExpectEqual, 0},
+		{"max diffs exceeded", Thresholds{MaxDiffs: &two}, ExpectEqual, 1},
+		{"max percent met", Thresholds{MaxDiffPercent: &sixty}, ExpectEqual, 0},
+		{"max percent exceeded", Thresholds{MaxDiffPercent: &fifty}, ExpectEqual, 1},
+		{"both exceeded", Thresholds{MaxDiffs: &two, MaxDiffPercent: &fifty}, ExpectEqual, 2},
+		{"allowed keys left out", Thresholds{MaxDiffs: &two, MaxDiffPercent: &fifty}, ExpectSubset, 0},
+		// v differs in 1 of 3 matching keys.
+		{"field rate met", Thresholds{MaxFieldMismatchRate: &fifty}, ExpectEqual, 0},
+		{"field rate exceeded", Thresholds{MaxFieldMismatchRate: &twenty}, ExpectEqual, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New("id")
+			c.SetThresholds(tt.thresholds)
+			c.SetExpectation(tt.expectation)
+			result, err := c.Compare(datareader.NewSliceReader(records1), datareader.NewSliceReader(records2))
+			if err != nil {
+				t.Fatalf("Compare() error = %v", err)
+			}
+			if len(result.ThresholdsExceeded) != tt.exceeded {
+				t.Errorf("ThresholdsExceeded got = %v, want %d", result.ThresholdsExceeded, tt.exceeded)
+			}
+			if (result.Thresholds != nil) != (tt.thresholds != Thresholds{}) {
+				t.Errorf("Thresholds got = %v, want set only if configured", result.Thresholds)
+			}
+		})
+	}
+}
+
+func TestDiffPercent(t *testing.T) {
+	result := &Result{Summary: Summary{MatchingKeys: 8, IdenticalRows: 6, KeysOnlyInSource1: 1, KeysOnlyInSource2: 1}}
+	if got := result.DiscrepantKeys(); got != 4 {
+		t.Errorf("DiscrepantKeys() got = %d, want 4", got)
+	}
+	if got := result.DiffPercent(); got != 40 {
+		t.Errorf("DiffPercent() got = %v, want 40", got)
+	}
+	result.Expectation = ExpectSuperset
+	if got := result.DiffPercent(); got != 3.0/9*100 {
+		t.Errorf("DiffPercent() with superset got = %v, want %v", got, 3.0/9*100)
+	}
+	if got := (&Result{}).DiffPercent(); got != 0 {
+		t.Errorf("DiffPercent() of no keys got = %v, want 0", got)
+	}
+}
+
+func TestThresholds_Validate(t *testing.T) {
+	negative := -1
+	if err := (Thresholds{MaxDiffs: &negative}).validate(); err == nil {
+		t.Error("validate() with negative max_diffs expected an error, got nil")
+	}
+	percent := -0.5
+	if err := (Thresholds{MaxDiffPercent: &percent}).validate(); err == nil {
+		t.Error("validate() with negative max_diff_percent expected an error, got nil")
+	}
+	if err := (Thresholds{MaxFieldMismatchRate: &percent}).validate(); err == nil {
+		t.Error("validate() with negative max_field_mismatch_rate expected an error, got nil")
+	}
+	if err := (Thresholds{}).validate(); err != nil {
+		t.Errorf("validate() error = %v", err)
+	}
+}
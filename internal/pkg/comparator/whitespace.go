@@ -0,0 +1,58 @@
+package comparator
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// CategoryWhitespaceOnly marks diffs whose values differ only in whitespace
+// or invisible control characters.
+const CategoryWhitespaceOnly = "whitespace_only"
+
+// isInvisible reports whether r renders as blank or not at all.
+func isInvisible(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsControl(r) || unicode.Is(unicode.Cf, r)
+}
+
+func stripInvisible(s string) string {
+	return strings.Map(func(r rune) rune {
+		if isInvisible(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// visualize escapes invisible characters so that two values differing only in
+// them no longer look identical. Inner single spaces are kept as they are;
+// leading and trailing spaces are escaped as \x20.
+func visualize(s string) string {
+	inner := strings.TrimRight(strings.TrimLeft(s, " "), " ")
+	start := strings.Index(s, inner)
+	if inner == "" {
+		start = len(s)
+	}
+	end := start + len(inner)
+
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == ' ' && (i < start || i >= end):
+			b.WriteString(`\x20`)
+		case r == ' ':
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case isInvisible(r):
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
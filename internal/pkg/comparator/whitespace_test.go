@@ -0,0 +1,36 @@
+package comparator
+
+import "testing"
+
+func TestNewFieldDiff_WhitespaceOnly(t *testing.T) {
+	options := DefaultOptions()
+	options.VisualizeWhitespace = true
+
+	diff := newFieldDiff("city", "New York ", "New\u00a0York\u200b", options)
+	if diff.Category != CategoryWhitespaceOnly {
+		t.Errorf("Category got = %q, want %q", diff.Category, CategoryWhitespaceOnly)
+	}
+	if diff.Source1Value != `New York\x20` {
+		t.Errorf("Source1Value got = %q, want %q", diff.Source1Value, `New York\x20`)
+	}
+	if diff.Source2Value != `New\u00a0York\u200b` {
+		t.Errorf("Source2Value got = %q, want %q", diff.Source2Value, `New\u00a0York\u200b`)
+	}
+}
+
+func TestNewFieldDiff_WhitespaceOnlyWithoutVisualization(t *testing.T) {
+	diff := newFieldDiff("name", "alice\t", "alice", DefaultOptions())
+	if diff.Category != CategoryWhitespaceOnly {
+		t.Errorf("Category got = %q, want %q", diff.Category, CategoryWhitespaceOnly)
+	}
+	if diff.Source1Value != "alice\t" {
+		t.Errorf("Source1Value got = %q, want raw value", diff.Source1Value)
+	}
+}
+
+func TestNewFieldDiff_RealDifference(t *testing.T) {
+	diff := newFieldDiff("plan_type", "premium", "premium_plus", DefaultOptions())
+	if diff.Category != "" {
+		t.Errorf("Category got = %q, want empty", diff.Category)
+	}
+}
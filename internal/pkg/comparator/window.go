@@ -0,0 +1,283 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// timestampLayouts are the layouts accepted for the timestamp field of a windowed join.
+var timestampLayouts = []string{
+	time.RFC3339Nano, "2006-01-02 15:04:05.999999999", "2006-01-02 15:04:05Z07:00", "2006-01-02T15:04:05",
+}
+
+// WindowOptions configure a sliding-window join.
+type WindowOptions struct {
+	// TimestampField holds each record's event time.
+	TimestampField string
+	// Window is the largest time difference between two paired records. It is
+	// also the width of the buckets unmatched records are reported in.
+	Window time.Duration
+	// MinSimilarity is the fraction of compared fields that must be equal for
+	// two records to pair, from 0 to 1.
+	MinSimilarity float64
+	// Fields are the fields compared for similarity. All fields except the
+	// timestamp are compared if empty.
+	Fields []string
+	// Equality controls how field values are canonicalized and compared.
+	// DefaultOptions are used if nil.
+	Equality *Options
+}
+
+// WindowResult holds the outcome of a sliding-window join.
+type WindowResult struct {
+	Summary WindowSummary `yaml:"summary"`
+	Windows []WindowStats `yaml:"windows"`
+}
+
+// WindowSummary holds the totals of a sliding-window join.
+type WindowSummary struct {
+	Source1Rows        int `yaml:"source1_rows"`
+	Source2Rows        int `yaml:"source2_rows"`
+	Matched            int `yaml:"matched"`
+	UnmatchedInSource1 int `yaml:"unmatched_in_source1"`
+	UnmatchedInSource2 int `yaml:"unmatched_in_source2"`
+}
+
+// WindowStats holds the join counts of one time bucket, attributed by the
+// timestamp of the source1 record for pairs.
+type WindowStats struct {
+	Start              time.Time `yaml:"start"`
+	Matched            int       `yaml:"matched"`
+	UnmatchedInSource1 int       `yaml:"unmatched_in_source1"`
+	UnmatchedInSource2 int       `yaml:"unmatched_in_source2"`
+}
+
+type timedRecord struct {
+	at     time.Time
+	record datareader.Record
+	flat   map[string]interface{}
+}
+
+type windowJoin struct {
+	opts     WindowOptions
+	result   *WindowResult
+	buckets  map[int64]*WindowStats
+	pending1 []*timedRecord
+	pending2 []*timedRecord
+}
+
+// CompareWindowed pairs records of two keyless, roughly time-ordered streams:
+// each record is matched to the most similar unmatched record of the other
+// source within the window. Only records inside the window are kept in memory;
+// older ones are reported as unmatched.
+func CompareWindowed(reader1, reader2 datareader.DataReader, opts WindowOptions) (*WindowResult, error) {
+	if opts.TimestampField == "" || opts.Window <= 0 {
+		return nil, fmt.Errorf("windowed comparison requires a timestamp field and a positive window")
+	}
+
+	j := &windowJoin{opts: opts, result: &WindowResult{}, buckets: make(map[int64]*WindowStats)}
+	next1, err := j.next(reader1, Source1)
+	if err != nil {
+		return nil, err
+	}
+	next2, err := j.next(reader2, Source2)
+	if err != nil {
+		return nil, err
+	}
+
+	for next1 != nil || next2 != nil {
+		if next1 != nil && (next2 == nil || !next2.at.Before(next1.at)) {
+			j.add(next1, Source1)
+			if next1, err = j.next(reader1, Source1); err != nil {
+				return nil, err
+			}
+		} else {
+			j.add(next2, Source2)
+			if next2, err = j.next(reader2, Source2); err != nil {
+				return nil, err
+			}
+		}
+	}
+	j.evict(time.Time{}, true)
+
+	keys := make([]int64, 0, len(j.buckets))
+	for k := range j.buckets {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(a, b int) bool { return keys[a] < keys[b] })
+	for _, k := range keys {
+		j.result.Windows = append(j.result.Windows, *j.buckets[k])
+	}
+	return j.result, nil
+}
+
+func (j *windowJoin) next(reader datareader.DataReader, side Side) (*timedRecord, error) {
+	rec, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from %s: %w", side, err)
+	}
+
+	rows := &j.result.Summary.Source1Rows
+	if side == Source2 {
+		rows = &j.result.Summary.Source2Rows
+	}
+	*rows++
+	if j.opts.Equality != nil && j.opts.Equality.Normalize != nil {
+		rec = j.opts.Equality.Normalize(rec)
+	}
+
+	at, err := parseTimestamp(rec[j.opts.TimestampField])
+	if err != nil {
+		return nil, fmt.Errorf("%s record %d: field %q: %w", side, *rows, j.opts.TimestampField, err)
+	}
+	flat := make(map[string]interface{})
+	flatten(map[string]interface{}(rec), "", flat)
+	return &timedRecord{at: at, record: rec, flat: flat}, nil
+}
+
+// add pairs the record with the best candidate pending on the other side or
+// parks it, then reports records that fell out of the window.
+func (j *windowJoin) add(tr *timedRecord, side Side) {
+	own, other := &j.pending1, &j.pending2
+	if side == Source2 {
+		own, other = &j.pending2, &j.pending1
+	}
+
+	best, bestScore := -1, -1.0
+	for i, candidate := range *other {
+		if absDuration(tr.at.Sub(candidate.at)) > j.opts.Window {
+			continue
+		}
+		score := j.similarity(tr, candidate)
+		if score < j.opts.MinSimilarity {
+			continue
+		}
+		if score > bestScore || (score == bestScore &&
+			absDuration(tr.at.Sub(candidate.at)) < absDuration(tr.at.Sub((*other)[best].at))) {
+			best, bestScore = i, score
+		}
+	}
+
+	if best >= 0 {
+		at := tr.at
+		if side == Source2 {
+			at = (*other)[best].at
+		}
+		j.bucket(at).Matched++
+		j.result.Summary.Matched++
+		*other = append((*other)[:best], (*other)[best+1:]...)
+	} else {
+		*own = append(*own, tr)
+	}
+
+	j.evict(tr.at.Add(-j.opts.Window), false)
+}
+
+// evict reports pending records older than cutoff, or all of them, as unmatched.
+func (j *windowJoin) evict(cutoff time.Time, all bool) {
+	keep := func(pending []*timedRecord, unmatched *int, side Side) []*timedRecord {
+		kept := pending[:0]
+		for _, tr := range pending {
+			if !all && !tr.at.Before(cutoff) {
+				kept = append(kept, tr)
+				continue
+			}
+			*unmatched++
+			if side == Source1 {
+				j.bucket(tr.at).UnmatchedInSource1++
+			} else {
+				j.bucket(tr.at).UnmatchedInSource2++
+			}
+		}
+		return kept
+	}
+	j.pending1 = keep(j.pending1, &j.result.Summary.UnmatchedInSource1, Source1)
+	j.pending2 = keep(j.pending2, &j.result.Summary.UnmatchedInSource2, Source2)
+}
+
+func (j *windowJoin) bucket(at time.Time) *WindowStats {
+	k := at.UnixNano() / int64(j.opts.Window)
+	if at.UnixNano() < 0 && at.UnixNano()%int64(j.opts.Window) != 0 {
+		k--
+	}
+	stats, ok := j.buckets[k]
+	if !ok {
+		stats = &WindowStats{Start: time.Unix(0, k*int64(j.opts.Window)).UTC()}
+		j.buckets[k] = stats
+	}
+	return stats
+}
+
+// similarity returns the fraction of compared fields with equal values.
+func (j *windowJoin) similarity(a, b *timedRecord) float64 {
+	fields := j.opts.Fields
+	if len(fields) == 0 {
+		seen := make(map[string]struct{})
+		for name := range a.flat {
+			seen[name] = struct{}{}
+		}
+		for name := range b.flat {
+			seen[name] = struct{}{}
+		}
+		delete(seen, j.opts.TimestampField)
+		for name := range seen {
+			fields = append(fields, name)
+		}
+	}
+	if len(fields) == 0 {
+		return 1
+	}
+
+	options := DefaultOptions()
+	if j.opts.Equality != nil {
+		options = *j.opts.Equality
+	}
+	equal := 0
+	for _, name := range fields {
+		if options.FieldRules.of(name).ignore {
+			equal++
+			continue
+		}
+		v1, v2 := options.canonicalValue(name, a.flat[name]), options.canonicalValue(name, b.flat[name])
+		if valuesEqual(name, v1, v2, options) {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(fields))
+}
+
+// parseTimestamp accepts time values, RFC 3339-like strings and Unix epoch seconds.
+func parseTimestamp(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case nil:
+		return time.Time{}, fmt.Errorf("timestamp is missing")
+	}
+
+	if f, ok := toFloat(v); ok && !math.IsNaN(f) && !math.IsInf(f, 0) {
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC(), nil
+	}
+	s := fmt.Sprintf("%v", v)
+	for _, layout := range timestampLayouts {
+		if at, err := time.Parse(layout, s); err == nil {
+			return at, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse timestamp %q", s)
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,60 @@
+package comparator
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"testing"
+	"time"
+)
+
+func TestCompareWindowed(t *testing.T) {
+	reader1 := datareader.NewSliceReader([]datareader.Record{
+		{"ts": "2025-09-13T10:00:00Z", "level": "info", "msg": "started"},
+		{"ts": "2025-09-13T10:00:01Z", "level": "warn", "msg": "slow request"},
+		{"ts": "2025-09-13T10:00:30Z", "level": "error", "msg": "timeout"},
+	})
+	reader2 := datareader.NewSliceReader([]datareader.Record{
+		{"ts": "2025-09-13T10:00:00.200Z", "level": "info", "msg": "started"},
+		{"ts": "2025-09-13T10:00:02Z", "level": "warn", "msg": "slow request!"},
+		{"ts": "2025-09-13T10:00:45Z", "level": "info", "msg": "stopped"},
+	})
+
+	result, err := CompareWindowed(reader1, reader2, WindowOptions{
+		TimestampField: "ts",
+		Window:         10 * time.Second,
+		MinSimilarity:  0.5,
+	})
+	if err != nil {
+		t.Fatalf("CompareWindowed() error = %v", err)
+	}
+
+	expected := WindowSummary{
+		Source1Rows:        3,
+		Source2Rows:        3,
+		Matched:            2,
+		UnmatchedInSource1: 1,
+		UnmatchedInSource2: 1,
+	}
+	if result.Summary != expected {
+		t.Errorf("Summary got = %+v, want %+v", result.Summary, expected)
+	}
+
+	if len(result.Windows) != 3 {
+		t.Fatalf("Expected 3 windows, got %v", result.Windows)
+	}
+	if w := result.Windows[0]; w.Matched != 2 || !w.Start.Equal(time.Date(2025, 9, 13, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("First window got = %+v, want 2 matches starting at 10:00:00", w)
+	}
+	if w := result.Windows[2]; w.UnmatchedInSource2 != 1 {
+		t.Errorf("Last window got = %+v, want 1 unmatched in source2", w)
+	}
+}
+
+func TestCompareWindowed_BadTimestamp(t *testing.T) {
+	reader1 := datareader.NewSliceReader([]datareader.Record{{"ts": "yesterday"}})
+	reader2 := datareader.NewSliceReader(nil)
+
+	_, err := CompareWindowed(reader1, reader2, WindowOptions{TimestampField: "ts", Window: time.Second})
+	if err == nil {
+		t.Error("CompareWindowed() expected error for unparseable timestamp, got nil")
+	}
+}
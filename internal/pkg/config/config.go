@@ -1,33 +1,636 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config defines the structure of the user-provided YAML configuration file.
 type Config struct {
-	Source Source `yaml:"source"`
+	Source     Source      `yaml:"source"`
+	Comparison *Comparison `yaml:"comparison,omitempty"`
+	Validation *Validation `yaml:"validation,omitempty"`
 }
 
 // Source defines the data source configuration.
 type Source struct {
-	Type         string        `yaml:"type"`
-	Path         string        `yaml:"path"`
-	ParserConfig *ParserConfig `yaml:"parser_config,omitempty"`
-	Sampler      *Sampler      `yaml:"sampler,omitempty"`
+	Type string `yaml:"type" json:"type"`
+	// Path is the file a source reads. A glob pattern such as
+	// data/part-*.jsonl, or a directory, reads all its files as one stream.
+	Path string `yaml:"path" json:"path"`
+	// Order is the order the files of a glob or directory path are read in:
+	// "lexical" by name, the default, or "mtime" by modification time.
+	Order string `yaml:"order,omitempty" json:"order,omitempty"`
+	// Compression is the compression of a csv or json file: gzip, zstd,
+	// bzip2 or none. By default it is detected from the file's first bytes.
+	Compression string `yaml:"compression,omitempty" json:"compression,omitempty"`
+	// Key is the field records are joined on. Inferred from the data if empty.
+	Key          string        `yaml:"key,omitempty" json:"key,omitempty"`
+	ParserConfig *ParserConfig `yaml:"parser_config,omitempty" json:"parser_config,omitempty"`
+	Sampler      *Sampler      `yaml:"sampler,omitempty" json:"sampler,omitempty"`
+	Transform    *Transform    `yaml:"transform,omitempty" json:"transform,omitempty"`
+	// SchemaRegistry is the URL of a Confluent schema registry. When set, an
+	// avro source holds wire-format messages instead of a container file.
+	SchemaRegistry string `yaml:"schema_registry,omitempty" json:"schema_registry,omitempty"`
+	// DSN is the connection string of a database source, e.g.
+	// postgres://user@host/db or user@tcp(host:3306)/db for mysql. Path is
+	// not used by database sources.
+	DSN string `yaml:"dsn,omitempty" json:"dsn,omitempty"`
+	// Table is the table a database source reads, optionally schema-qualified,
+	// or dataset.table or project.dataset.table for bigquery.
+	Table string `yaml:"table,omitempty" json:"table,omitempty"`
+	// Query is the SQL query a database source reads instead of a table.
+	Query string `yaml:"query,omitempty" json:"query,omitempty"`
+	// Project is the Google Cloud project bigquery queries run in, and of
+	// tables named without one.
+	Project string `yaml:"project,omitempty" json:"project,omitempty"`
+	// FetchSize is the number of rows a postgres or bigquery source fetches
+	// per round trip. Defaults to 10000. Mysql sources stream a single
+	// result set.
+	FetchSize int `yaml:"fetch_size,omitempty" json:"fetch_size,omitempty"`
+	// ObjectStore holds the endpoint and credentials of a Path given as an
+	// s3://, gs:// or az:// URI.
+	ObjectStore *ObjectStore `yaml:"object_store,omitempty" json:"object_store,omitempty"`
+	// Remote holds the credentials of a Path given as an sftp:// or ftp://
+	// URL, e.g. sftp://partner@files.example.com/outgoing/orders.csv.
+	Remote *RemoteConfig `yaml:"remote,omitempty" json:"remote,omitempty"`
+	// ReadTimeout is how long a single record may take to arrive, e.g. "30s".
+	// A source exceeding it is no longer read, and the comparison finishes
+	// with partial results flagged as incomplete.
+	ReadTimeout time.Duration `yaml:"read_timeout,omitempty" json:"read_timeout,omitempty"`
+	// Protobuf describes the messages of a protobuf source.
+	Protobuf *ProtobufParserConfig `yaml:"protobuf,omitempty" json:"protobuf,omitempty"`
+	// XML selects the records of an xml source.
+	XML *XMLParserConfig `yaml:"xml,omitempty" json:"xml,omitempty"`
+	// FixedWidth lays out the columns of a fixed_width source.
+	FixedWidth *FixedWidthConfig `yaml:"fixed_width,omitempty" json:"fixed_width,omitempty"`
+	// LogFile sets the patterns parsing the lines of a logfile source.
+	LogFile *LogFileConfig `yaml:"logfile,omitempty" json:"logfile,omitempty"`
+	// Syslog ends and numbers the messages of a syslog source.
+	Syslog *SyslogConfig `yaml:"syslog,omitempty" json:"syslog,omitempty"`
+	// XLSX selects the sheet and header row of an xlsx source.
+	XLSX *XLSXConfig `yaml:"xlsx,omitempty" json:"xlsx,omitempty"`
+	// HTTP configures the request of an http source, whose Path is the
+	// http:// or https:// URL it gets.
+	HTTP *HTTPConfig `yaml:"http,omitempty" json:"http,omitempty"`
+	// FieldNames normalizes the names of fields as they are read, before
+	// keys, schemas and transforms refer to them.
+	FieldNames *FieldNames `yaml:"field_names,omitempty" json:"field_names,omitempty"`
+}
+
+// FieldNames selects how field names are normalized, so that names such as
+// "User ID" and "user_id" in two sources match. Nested field names are
+// normalized too.
+type FieldNames struct {
+	// Lowercase lowercases names.
+	Lowercase bool `yaml:"lowercase,omitempty" json:"lowercase,omitempty"`
+	// SnakeCase rewrites names in snake_case: "User ID", "userId" and
+	// "User-Id" become "user_id".
+	SnakeCase bool `yaml:"snake_case,omitempty" json:"snake_case,omitempty"`
+	// StripSpaces removes whitespace from names.
+	StripSpaces bool `yaml:"strip_spaces,omitempty" json:"strip_spaces,omitempty"`
+}
+
+// HTTPConfig configures the GET request of an http source.
+type HTTPConfig struct {
+	// Format is the source type of the response body, e.g. csv or json.
+	// By default it is taken from the response's Content-Type, or else
+	// sniffed from its first bytes.
+	Format string `yaml:"format,omitempty" json:"format,omitempty"`
+	// Headers are sent with the request. Their values, the bearer token and
+	// the password may reference environment variables as ${NAME}.
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	// BearerToken is sent as an Authorization: Bearer header.
+	BearerToken string `yaml:"bearer_token,omitempty" json:"bearer_token,omitempty"`
+	// Username and Password authenticate with HTTP basic auth.
+	Username string `yaml:"username,omitempty" json:"username,omitempty"`
+	Password string `yaml:"password,omitempty" json:"password,omitempty"`
+	// Retries is the number of times a failed request, or a response cut
+	// off midway, is retried. A cut-off response resumes where it stopped
+	// with a Range request. Defaults to 3; negative disables retries.
+	Retries int `yaml:"retries,omitempty" json:"retries,omitempty"`
+}
+
+// XLSXConfig selects the cells of an Excel workbook read as records.
+type XLSXConfig struct {
+	// Sheet is the name of the sheet to read, or its 1-based position such
+	// as "2". Defaults to the first sheet.
+	Sheet string `yaml:"sheet,omitempty" json:"sheet,omitempty"`
+	// HeaderRow is the 1-based row holding the column names; rows above it
+	// are skipped. Zero detects it as the first row of text cells that is
+	// as wide as the widest of the rows below, passing over titles.
+	HeaderRow int `yaml:"header_row,omitempty" json:"header_row,omitempty"`
+	// NoHeader names the columns by their letters, A, B, ..., and reads
+	// every row as a record.
+	NoHeader bool `yaml:"no_header,omitempty" json:"no_header,omitempty"`
+}
+
+// FixedWidthConfig lays out the columns of a fixed-width file, such as a
+// mainframe extract.
+type FixedWidthConfig struct {
+	// Columns lists the columns by byte offset and length.
+	Columns []FixedWidthColumn `yaml:"columns,omitempty" json:"columns,omitempty"`
+	// Layout is the path of a copybook-like YAML file listing the fields of
+	// a record in order, by PIC clause or length, used instead of Columns.
+	Layout string `yaml:"layout,omitempty" json:"layout,omitempty"`
+	// RecordLength is the length in bytes of the records of a file without
+	// line breaks between them. Zero reads one record per line.
+	RecordLength int `yaml:"record_length,omitempty" json:"record_length,omitempty"`
+}
+
+// FixedWidthColumn is a column of a fixed-width record.
+type FixedWidthColumn struct {
+	Name string `yaml:"name" json:"name"`
+	// Offset is the 0-based byte position of the column in the record.
+	Offset int `yaml:"offset" json:"offset"`
+	Length int `yaml:"length" json:"length"`
+	// Type is "string", the default, or "number" for numeric columns, whose
+	// last digit may carry an overpunched sign.
+	Type string `yaml:"type,omitempty" json:"type,omitempty"`
+	// Scale is the number of implied decimal places of a number column, as
+	// in PIC 9(5)V99.
+	Scale int `yaml:"scale,omitempty" json:"scale,omitempty"`
+}
+
+// LogFileConfig parses the lines of a plain-text log into records.
+type LogFileConfig struct {
+	// Patterns are grok patterns, such as
+	// "%{TIMESTAMP_ISO8601:time} %{LOGLEVEL:level} %{GREEDYDATA:message}",
+	// or regular expressions with named groups. Each line becomes a record
+	// of the captures of the first pattern matching it.
+	Patterns []string `yaml:"patterns" json:"patterns"`
+	// Definitions adds grok patterns by name, or replaces built-in ones, for
+	// use as %{NAME} in Patterns and each other.
+	Definitions map[string]string `yaml:"definitions,omitempty" json:"definitions,omitempty"`
+	// Unmatched is what happens to lines no pattern matches: "skip", the
+	// default, "error" to fail them as parse errors, or "keep" to read them
+	// as records holding the line under message.
+	Unmatched string `yaml:"unmatched,omitempty" json:"unmatched,omitempty"`
+	// LineField, if set, is a field holding the line number of each record,
+	// e.g. as the key of logs without one.
+	LineField string `yaml:"line_field,omitempty" json:"line_field,omitempty"`
+}
+
+// SyslogConfig configures a syslog source, which reads RFC 5424 messages
+// from a file, or receives them on the udp:// or tcp:// address of its
+// path, e.g. udp://:5514.
+type SyslogConfig struct {
+	// IdleTimeout ends a source listening on an address once no message
+	// arrived for this long, e.g. "30s". Defaults to 10s.
+	IdleTimeout time.Duration `yaml:"idle_timeout,omitempty" json:"idle_timeout,omitempty"`
+	// MaxMessages ends the source after this many messages. Zero reads all.
+	MaxMessages int `yaml:"max_messages,omitempty" json:"max_messages,omitempty"`
+	// SequenceField, if set, is a field holding the 1-based number of each
+	// message, e.g. as the key of messages without one.
+	SequenceField string `yaml:"sequence_field,omitempty" json:"sequence_field,omitempty"`
+}
+
+// XMLParserConfig selects the records of an XML document.
+type XMLParserConfig struct {
+	// RecordElement is the name of the element repeated once per record,
+	// e.g. order, or a path of element names ending in it, e.g.
+	// orders/order, to tell it apart from elements of the same name
+	// elsewhere. Namespace prefixes are left out.
+	RecordElement string `yaml:"record_element" json:"record_element"`
+}
+
+// ProtobufParserConfig describes the binary protobuf messages of a source.
+type ProtobufParserConfig struct {
+	// Descriptor is the path of a FileDescriptorSet holding the message type
+	// and its dependencies, as written by protoc --include_imports
+	// --descriptor_set_out.
+	Descriptor string `yaml:"descriptor" json:"descriptor"`
+	// MessageType is the full name of the message type, e.g. shop.v1.Order.
+	MessageType string `yaml:"message_type" json:"message_type"`
+	// Framing is how consecutive messages are delimited: "varint" for a
+	// varint length before each message, as written by writeDelimitedTo, or
+	// "length_prefixed" for a 4-byte big-endian length. Defaults to varint.
+	Framing string `yaml:"framing,omitempty" json:"framing,omitempty"`
+}
+
+// RemoteConfig authenticates with the SFTP or FTP server of a source path.
+// SFTP runs the system ssh client, so ~/.ssh/config, ssh-agent and
+// known_hosts apply too.
+type RemoteConfig struct {
+	// Username logs in. Defaults to the user of the URL, else anonymous for
+	// FTP and the ssh default for SFTP.
+	Username string `yaml:"username,omitempty" json:"username,omitempty"`
+	// Password may reference environment variables as ${NAME}. Defaults to
+	// SFTP_PASSWORD or FTP_PASSWORD. SFTP passwords need OpenSSH 8.4 or later.
+	Password string `yaml:"password,omitempty" json:"password,omitempty"`
+	// KeyFile is a private key SFTP authenticates with. Defaults to
+	// SFTP_KEY_FILE.
+	KeyFile string `yaml:"key_file,omitempty" json:"key_file,omitempty"`
+	// KnownHosts is the known_hosts file the SFTP server's host key is
+	// verified against, instead of ~/.ssh/known_hosts.
+	KnownHosts string `yaml:"known_hosts,omitempty" json:"known_hosts,omitempty"`
+}
+
+// ObjectStore configures access to object storage. Unset credentials are
+// taken from the provider's usual environment variables.
+type ObjectStore struct {
+	// Endpoint replaces the provider's endpoint, e.g. for MinIO or Azurite.
+	Endpoint string `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
+	// Region is the region of an S3 bucket. Defaults to AWS_REGION, then us-east-1.
+	Region string `yaml:"region,omitempty" json:"region,omitempty"`
+	// AccessKeyID, SecretAccessKey and SessionToken are AWS credentials.
+	// They default to AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+	// AWS_SESSION_TOKEN; without any, S3 requests are anonymous.
+	AccessKeyID     string `yaml:"access_key_id,omitempty" json:"access_key_id,omitempty"`
+	SecretAccessKey string `yaml:"secret_access_key,omitempty" json:"secret_access_key,omitempty"`
+	SessionToken    string `yaml:"session_token,omitempty" json:"session_token,omitempty"`
+	// CredentialsFile is a Google credentials file used for gs:// paths
+	// instead of the application default credentials.
+	CredentialsFile string `yaml:"credentials_file,omitempty" json:"credentials_file,omitempty"`
+	// Account is the Azure storage account of az:// paths. Defaults to AZURE_STORAGE_ACCOUNT.
+	Account string `yaml:"account,omitempty" json:"account,omitempty"`
+	// AccountKey is the Azure shared key. Defaults to AZURE_STORAGE_KEY.
+	AccountKey string `yaml:"account_key,omitempty" json:"account_key,omitempty"`
+	// SASToken is an Azure shared access signature used instead of the
+	// account key. Defaults to AZURE_STORAGE_SAS_TOKEN.
+	SASToken string `yaml:"sas_token,omitempty" json:"sas_token,omitempty"`
+	// PartSize is the number of bytes fetched per range request. Defaults to 8 MiB.
+	PartSize int `yaml:"part_size,omitempty" json:"part_size,omitempty"`
+	// Concurrency is the number of parts fetched ahead in parallel. Defaults to 4.
+	Concurrency int `yaml:"concurrency,omitempty" json:"concurrency,omitempty"`
+}
+
+// Transform rewrites records after they are read and before they are compared.
+type Transform struct {
+	// Exec is a command and its arguments that receives records as JSON
+	// Lines on stdin and writes the transformed records to stdout.
+	Exec []string `yaml:"exec,omitempty" json:"exec,omitempty"`
+	// Fields maps field names, dotted for nested fields, to the steps their
+	// values are rewritten by in order, e.g. ["substring(0, 10)", "lowercase"].
+	// They apply after Exec.
+	Fields map[string][]string `yaml:"fields,omitempty" json:"fields,omitempty"`
+	// Lookups are the tables of the lookup step by name, each mapping values
+	// to their replacements.
+	Lookups map[string]map[string]string `yaml:"lookups,omitempty" json:"lookups,omitempty"`
 }
 
 // ParserConfig holds optional configuration for the data parser.
 type ParserConfig struct {
-	JSONInString bool `yaml:"json_in_string"`
+	JSONInString bool `yaml:"json_in_string" json:"json_in_string"`
+	// Delimiter is the CSV field separator. Defaults to a comma.
+	Delimiter string `yaml:"delimiter,omitempty" json:"delimiter,omitempty"`
+	// NoHeader marks CSV files without a header row; columns are then named
+	// column_1, column_2, and so on.
+	NoHeader bool `yaml:"no_header,omitempty" json:"no_header,omitempty"`
+	// SkipLines is the number of lines above the CSV header, such as the
+	// title and notes of report exports, skipped before it is read.
+	SkipLines int `yaml:"skip_lines,omitempty" json:"skip_lines,omitempty"`
+	// MaxJSONDepth is the nesting depth up to which JSON in CSV fields is
+	// parsed; deeper values are left as strings. Defaults to 64.
+	MaxJSONDepth int `yaml:"max_json_depth,omitempty" json:"max_json_depth,omitempty"`
+	// MaxJSONSize is the length in bytes up to which CSV fields are parsed as
+	// JSON. Defaults to 1 MiB.
+	MaxJSONSize int `yaml:"max_json_size,omitempty" json:"max_json_size,omitempty"`
+	// JSONNumbers is how numbers in JSON files and JSON in CSV fields are
+	// decoded: "float" as float64, or "exact" as the literal they are written
+	// as, which keeps integers beyond 2^53 such as int64 IDs intact. Defaults
+	// to "float".
+	JSONNumbers string `yaml:"json_numbers,omitempty" json:"json_numbers,omitempty"`
 }
 
 // Sampler holds optional configuration for the schema generation sampler.
 type Sampler struct {
-	SampleSize int `yaml:"sample_size"`
+	SampleSize int `yaml:"sample_size" json:"sample_size"`
+	// MaxDepth is the nesting depth up to which values are flattened into
+	// fields. Defaults to 32.
+	MaxDepth int `yaml:"max_depth,omitempty" json:"max_depth,omitempty"`
+	// MaxFields is the number of flattened fields inferred. Defaults to 10000.
+	MaxFields int `yaml:"max_fields,omitempty" json:"max_fields,omitempty"`
+	// Examples is the number of example values kept per field in generated
+	// schemas. Zero keeps none.
+	Examples int `yaml:"examples,omitempty" json:"examples,omitempty"`
+	// RedactExamples lists fields, by dotted name or "*" for all, whose
+	// examples only keep the shape of their values.
+	RedactExamples []string `yaml:"redact_examples,omitempty" json:"redact_examples,omitempty"`
+}
+
+// Comparison holds optional settings for how values are compared between sources.
+type Comparison struct {
+	// NaNEqual treats NaN as equal to NaN. Defaults to true.
+	NaNEqual *bool `yaml:"nan_equal,omitempty"`
+	// SignedZeroEqual treats -0 as equal to 0. Defaults to true.
+	SignedZeroEqual *bool `yaml:"signed_zero_equal,omitempty"`
+	// ExactStringFields lists fields compared as exact strings even when both
+	// values look numeric, e.g. IDs with leading zeros.
+	ExactStringFields []string `yaml:"exact_string_fields,omitempty"`
+	// DuplicateKeys is what happens to a record whose key was already read
+	// on its side: keep_last, keep_first, error or multiset. Setting it keeps
+	// the keys read, so records of keys already matched are found too.
+	// Unset, only keys not matched yet are found, handled as keep_last.
+	DuplicateKeys string `yaml:"duplicate_keys,omitempty"`
+	// Expect is what the comparison asserts about the keys of the sources:
+	// equal, subset for every key of source1 in source2, allowing more in
+	// source2, or superset for the reverse. Defaults to equal.
+	Expect string `yaml:"expect,omitempty"`
+	// MissingKeys sets the severity of keys only in each source.
+	MissingKeys *MissingKeys `yaml:"missing_keys,omitempty"`
+	// Thresholds bound the discrepancies the comparison may find before
+	// it fails.
+	Thresholds *Thresholds `yaml:"thresholds,omitempty"`
+	// Tolerance lets numbers that differ by float round-off still match.
+	Tolerance *Tolerance `yaml:"tolerance,omitempty"`
+	// FieldRules maps field names to the rules their values are compared
+	// by. Names are dotted paths of nested fields, in which * matches within
+	// one segment and ** across segments, e.g. "**.updated_at".
+	FieldRules map[string]FieldRule `yaml:"field_rules,omitempty"`
+	// VisualizeWhitespace renders values that differ only in whitespace or
+	// control characters with visible escapes.
+	VisualizeWhitespace bool `yaml:"visualize_whitespace,omitempty"`
+	// InlineDiffMinLength enables a token-level diff for differing string values
+	// of at least this many characters. Zero disables it.
+	InlineDiffMinLength int `yaml:"inline_diff_min_length,omitempty"`
+	// BinaryFields lists fields holding base64-encoded binary data, which are
+	// compared by size and hash instead of by their text.
+	BinaryFields []string `yaml:"binary_fields,omitempty"`
+	// Canonicalize normalizes values before they are compared or hashed.
+	Canonicalize *Canonicalize `yaml:"canonicalize,omitempty"`
+	// IncludeRecords embeds the complete records of diffing keys in the result.
+	IncludeRecords *IncludeRecords `yaml:"include_records,omitempty"`
+	// MaxDiffsPerField and MaxTotalDiffs bound the value diffs kept in the
+	// result, per field and in all, keeping the first ones as examples. The
+	// counts of the result stay exact. Zero keeps every diff.
+	MaxDiffsPerField int `yaml:"max_diffs_per_field,omitempty"`
+	MaxTotalDiffs    int `yaml:"max_total_diffs,omitempty"`
+	// Constraints are cross-field assertions checked on every record of both sources.
+	Constraints []Constraint `yaml:"constraints,omitempty"`
+	// Spill lets the join of large, unsorted sources go to disk instead of
+	// holding every unmatched record in memory.
+	Spill *Spill `yaml:"spill,omitempty"`
+	// Limits bound the size of single records; records over a limit are
+	// truncated and reported.
+	Limits *Limits `yaml:"limits,omitempty"`
+	// FieldMappings maps top-level fields of source1 to the differently
+	// named fields of source2 holding the same data.
+	FieldMappings map[string]string `yaml:"field_mappings,omitempty"`
+	// KeyCheck configures the key overlap pre-check run before comparing.
+	KeyCheck *KeyCheck `yaml:"key_check,omitempty"`
+	// Stall detects sources that stop producing records.
+	Stall *Stall `yaml:"stall,omitempty"`
+	// Deadline is how long a comparison may read its sources, e.g. "1h".
+	// Once it passes, the comparison finishes with partial results flagged
+	// as incomplete.
+	Deadline time.Duration `yaml:"deadline,omitempty"`
+	// TrackResolved compares later records of a differing key again, and
+	// reports keys that then match as resolved along with how long they
+	// took, telling replication lag apart from lost or corrupted data.
+	TrackResolved bool `yaml:"track_resolved,omitempty"`
+	// SkipParseErrors skips the records the readers fail to parse but can
+	// read past, counting and listing them, instead of failing on the first.
+	SkipParseErrors bool `yaml:"skip_parse_errors,omitempty"`
+	// Baseline is the path of a file of known, accepted diffs left out of
+	// the result, such as one written by -baseline-from.
+	Baseline string `yaml:"baseline,omitempty"`
+	// Lag measures how much later matched keys appear in source2 than in
+	// source1.
+	Lag *Lag `yaml:"lag,omitempty"`
+	// Lookup loads source2 whole as a keyed snapshot and verifies each
+	// record of source1, which need not end, against it as soon as it is read.
+	Lookup bool `yaml:"lookup,omitempty"`
+	// Multiset compares the sources as multisets of whole records, without
+	// a key, instead of joining them.
+	Multiset *Multiset `yaml:"multiset,omitempty"`
+	// Ordered compares the records of the sources by position, like a line
+	// diff, instead of joining them on a key.
+	Ordered *Ordered `yaml:"ordered,omitempty"`
+	// Window pairs the records of keyless, roughly time-ordered sources,
+	// such as logs, by timestamp proximity and field similarity.
+	Window *Window `yaml:"window,omitempty"`
+	// Script runs custom normalize and equal functions in an external
+	// process, for logic the declarative rules cannot express.
+	Script *Script `yaml:"script,omitempty"`
+}
+
+// Script is an external process with the custom comparison logic of a
+// comparison. It is started once per comparison and answers requests as
+// JSON Lines on its stdin and stdout.
+type Script struct {
+	// Command is the command and its arguments, e.g. [python3, compare.py].
+	Command []string `yaml:"command"`
+	// Normalize sends every record to the script to rewrite before it is
+	// compared.
+	Normalize bool `yaml:"normalize,omitempty"`
+	// Timeout is how long the script may take to answer a request before
+	// it is stopped and the comparison fails. Defaults to 10s.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// FieldRule is how the values of a field are compared. When several rules
+// match a field, all of them apply.
+type FieldRule struct {
+	// Ignore leaves the field out of the comparison, e.g. updated_at or etag.
+	Ignore bool `yaml:"ignore,omitempty"`
+	// CaseInsensitive compares string values ignoring case.
+	CaseInsensitive bool `yaml:"case_insensitive,omitempty"`
+	// TrimWhitespace compares string values without leading and trailing
+	// whitespace.
+	TrimWhitespace bool `yaml:"trim_whitespace,omitempty"`
+	// NormalizeRegex rewrites string values before they are compared.
+	NormalizeRegex *RegexNormalization `yaml:"normalize_regex,omitempty"`
+	// Array is how array values are compared: ordered, element by element;
+	// set, ignoring the order of the elements; or keyed, matching object
+	// elements by their ArrayKey field. Defaults to ordered.
+	Array string `yaml:"array,omitempty"`
+	// ArrayKey is the field of the elements of keyed arrays they are matched
+	// by, e.g. sku for line items.
+	ArrayKey string `yaml:"array_key,omitempty"`
+	// Script has the comparison script decide whether two values of the
+	// field are equal.
+	Script bool `yaml:"script,omitempty"`
+}
+
+// RegexNormalization replaces the matches of a regular expression.
+type RegexNormalization struct {
+	// Pattern is a regular expression in Go (RE2) syntax.
+	Pattern string `yaml:"pattern"`
+	// Replacement replaces every match, with $1 for the first group. By
+	// default matches are removed.
+	Replacement string `yaml:"replacement,omitempty"`
+}
+
+// Tolerance bounds how far apart numeric values may be and still match.
+type Tolerance struct {
+	// NumericTolerance applies to every numeric field without one of its own.
+	NumericTolerance `yaml:",inline"`
+	// Fields sets the tolerance of single fields, by their dotted names for
+	// nested ones, instead of the global one.
+	Fields map[string]NumericTolerance `yaml:"fields,omitempty"`
+}
+
+// NumericTolerance bounds the difference of two numeric values that match.
+// Values match when within either bound; zero bounds require exact equality.
+type NumericTolerance struct {
+	// Absolute is the largest difference, e.g. 0.001.
+	Absolute float64 `yaml:"absolute,omitempty"`
+	// Relative is the largest difference as a share of the larger absolute
+	// value, e.g. 1e-9.
+	Relative float64 `yaml:"relative,omitempty"`
+}
+
+// MissingKeys sets the severity, error, warning or info, of keys only in
+// each source. Keys of severity info meet the expectation.
+type MissingKeys struct {
+	Source1 string `yaml:"source1,omitempty"`
+	Source2 string `yaml:"source2,omitempty"`
+}
+
+// Thresholds bound the discrepant keys of a comparison: those with
+// differing records or only in a source the expectation does not allow.
+type Thresholds struct {
+	// MaxDiffs is the largest number of discrepant keys.
+	MaxDiffs *int `yaml:"max_diffs,omitempty"`
+	// MaxDiffPercent is the largest percentage of discrepant keys, e.g. 0.5.
+	MaxDiffPercent *float64 `yaml:"max_diff_percent,omitempty"`
+	// MaxFieldMismatchRate is the largest percentage of matching keys any one
+	// field may differ in.
+	MaxFieldMismatchRate *float64 `yaml:"max_field_mismatch_rate,omitempty"`
+}
+
+// Multiset configures the unkeyed comparison of whole records.
+type Multiset struct {
+	// FuzzyFields pairs records only in one source with those only in the
+	// other that have the same values of these fields, reporting their
+	// other differences.
+	FuzzyFields []string `yaml:"fuzzy_fields,omitempty"`
+}
+
+// Ordered configures the positional comparison of sequence-sensitive
+// sources, such as event logs.
+type Ordered struct {
+	// ResyncWindow is how many records ahead each source is searched to
+	// resynchronize after a mismatch. Defaults to 100.
+	ResyncWindow int `yaml:"resync_window,omitempty"`
+}
+
+// Window configures the sliding-window join of keyless sources.
+type Window struct {
+	// TimestampField holds the event time of each record.
+	TimestampField string `yaml:"timestamp_field"`
+	// Window is the largest time difference between two paired records, and
+	// the width of the time buckets unmatched records are counted in.
+	Window time.Duration `yaml:"window"`
+	// MinSimilarity is the fraction of compared fields, from 0 to 1, that
+	// must be equal for two records to pair.
+	MinSimilarity float64 `yaml:"min_similarity,omitempty"`
+	// Fields are the fields compared for similarity. Defaults to all fields
+	// but the timestamp.
+	Fields []string `yaml:"fields,omitempty"`
+}
+
+// Lag configures the measurement of the consistency lag between the sources.
+type Lag struct {
+	// TimestampField holds when each record was produced. If empty, the lag
+	// is measured by when the records are read, for sources read as they
+	// are produced.
+	TimestampField string `yaml:"timestamp_field,omitempty"`
+}
+
+// Stall configures the detection of sources that produce no records for a while.
+type Stall struct {
+	// Timeout is how long a source may produce no record before it counts as
+	// stalled, e.g. "5m". Zero disables stall detection.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// Fail aborts the comparison once a source stalls, instead of reporting
+	// the stall and waiting on.
+	Fail bool `yaml:"fail,omitempty"`
+}
+
+// KeyCheck configures the pre-check that samples the keys of both sources
+// and warns of, or aborts, a comparison whose sources share almost none of
+// them.
+type KeyCheck struct {
+	// Disabled skips the pre-check.
+	Disabled bool `yaml:"disabled,omitempty"`
+	// SampleSize is the number of records sampled per source. Defaults to 10000.
+	SampleSize int `yaml:"sample_size,omitempty"`
+	// MinOverlap is the share of sampled keys, between 0 and 1, both sources
+	// must have in common. By default only sources sharing no sampled key
+	// are reported.
+	MinOverlap float64 `yaml:"min_overlap,omitempty"`
+	// Fail aborts the comparison before reading on, instead of warning, for
+	// sources sorted alike by key. Sources in different orders can share no
+	// key among their first records and still match in full.
+	Fail bool `yaml:"fail,omitempty"`
+}
+
+// Limits bound the size of single records. Zero disables a limit.
+type Limits struct {
+	// MaxFields is the number of top-level fields kept per record.
+	MaxFields int `yaml:"max_fields,omitempty"`
+	// MaxDepth is the nesting depth of values kept; deeper values are
+	// replaced by a digest.
+	MaxDepth int `yaml:"max_depth,omitempty"`
+	// MaxValueSize is the number of bytes kept of string values.
+	MaxValueSize int `yaml:"max_value_size,omitempty"`
+}
+
+// Spill configures the temporary files of a disk-spilling join.
+type Spill struct {
+	// Dir holds the spill files. Defaults to the system temp directory.
+	Dir string `yaml:"dir,omitempty"`
+	// MemoryRecords is the number of unmatched records held in memory before
+	// the join spills. Defaults to 1000000.
+	MemoryRecords int `yaml:"memory_records,omitempty"`
+	// Partitions is the number of spill files per source, each joined in
+	// memory on its own. Defaults to 64.
+	Partitions int `yaml:"partitions,omitempty"`
+}
+
+// Constraint is a cross-field assertion such as "end_date >= start_date".
+type Constraint struct {
+	Name  string `yaml:"name,omitempty"`
+	Check string `yaml:"check"`
+}
+
+// IncludeRecords configures the full records reported alongside field diffs.
+type IncludeRecords struct {
+	// Max is the number of diffing keys whose records are kept. Defaults to 100.
+	Max int `yaml:"max,omitempty"`
+	// Redact lists fields, by dotted name, whose values are masked.
+	Redact []string `yaml:"redact,omitempty"`
+}
+
+// Canonicalize selects the normalizations applied to values in every comparison mode.
+type Canonicalize struct {
+	TrimWhitespace  bool `yaml:"trim_whitespace,omitempty"`
+	CaseInsensitive bool `yaml:"case_insensitive,omitempty"`
+	// DateTimes rewrites recognized datetimes as UTC RFC 3339.
+	DateTimes bool `yaml:"datetimes,omitempty"`
+	// DateTimeLayouts are Go time layouts tried before the built-in ones,
+	// e.g. "02/01/2006 15:04:05". Setting them enables DateTimes.
+	DateTimeLayouts []string `yaml:"datetime_layouts,omitempty"`
+	// DateTimePrecision truncates datetimes, e.g. to "1s" or "1ms", so
+	// values written with different fractional digits are equal. Setting it
+	// enables DateTimes.
+	DateTimePrecision time.Duration `yaml:"datetime_precision,omitempty"`
+	// Numbers rewrites numeric strings as numbers, so "1.0" and 1 hash alike.
+	Numbers bool `yaml:"numbers,omitempty"`
+}
+
+// Validation holds optional settings for validating configurations.
+type Validation struct {
+	// FailOn is the lowest finding severity (error, warning or info) that
+	// makes validation fail.
+	FailOn string `yaml:"fail_on,omitempty"`
+	// Ignore lists finding types that are suppressed.
+	Ignore []string `yaml:"ignore,omitempty"`
+}
+
+// Run holds the configuration of both sources of a comparison, so a whole
+// run can be passed inline, e.g. as a single JSON flag, without config files.
+type Run struct {
+	Config1 *Config `yaml:"config1"`
+	Config2 *Config `yaml:"config2"`
 }
 
 // Load reads a YAML configuration file from the given path and returns a Config struct.
@@ -38,10 +641,45 @@ func Load(filePath string) (*Config, error) {
 	}
 
 	var cfg Config
-	err = yaml.Unmarshal(data, &cfg)
+	err = decode(data, &cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal yaml from %s: %w", filePath, err)
 	}
 
 	return &cfg, nil
 }
+
+// Parse parses the contents of a configuration file. JSON is accepted as well
+// as YAML.
+func Parse(data []byte) (*Config, error) {
+	var cfg Config
+	if err := decode(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal configuration: %w", err)
+	}
+	return &cfg, nil
+}
+
+// ParseRun parses an inline run configuration. JSON is accepted as well as
+// YAML, since YAML is a superset of JSON.
+func ParseRun(data string) (*Run, error) {
+	var run Run
+	if err := decode([]byte(data), &run); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal run configuration: %w", err)
+	}
+	if run.Config1 == nil || run.Config2 == nil {
+		return nil, fmt.Errorf("run configuration needs both config1 and config2")
+	}
+	return &run, nil
+}
+
+// decode decodes YAML or JSON into v, rejecting fields v does not have, so
+// misspelled settings fail instead of being ignored. Empty data decodes to
+// the zero value.
+func decode(data []byte, v interface{}) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -32,3 +34,68 @@ func TestLoad(t *testing.T) {
 		t.Errorf("Source.ParserConfig.JSONInString got = %v, want %v", cfg.Source.ParserConfig.JSONInString, true)
 	}
 }
+
+func TestParseRun(t *testing.T) {
+	run, err := ParseRun(`{
+		"config1": {"source": {"type": "csv", "path": "a.csv", "key": "id"}},
+		"config2": {"source": {"type": "json", "path": "b.jsonl"}, "comparison": {"nan_equal": false}}
+	}`)
+	if err != nil {
+		t.Fatalf("ParseRun() error = %v", err)
+	}
+	if run.Config1.Source.Path != "a.csv" || run.Config1.Source.Key != "id" {
+		t.Errorf("Config1.Source got = %+v", run.Config1.Source)
+	}
+	if run.Config2.Source.Type != "json" {
+		t.Errorf("Config2.Source.Type got = %v, want json", run.Config2.Source.Type)
+	}
+	if run.Config2.Comparison == nil || run.Config2.Comparison.NaNEqual == nil || *run.Config2.Comparison.NaNEqual {
+		t.Errorf("Config2.Comparison got = %+v, want nan_equal false", run.Config2.Comparison)
+	}
+
+	if _, err := ParseRun(`{"config1": {"source": {"type": "csv"}}}`); err == nil {
+		t.Error("ParseRun() expected error without config2, got nil")
+	}
+}
+
+func TestParse_UnknownFields(t *testing.T) {
+	if _, err := Parse([]byte("source:\n  type: csv\n  pth: a.csv\n")); err == nil || !strings.Contains(err.Error(), "field pth not found") {
+		t.Errorf("Parse() error got = %v, want unknown field pth", err)
+	}
+	if _, err := ParseRun(`{"config1": {"source": {"type": "csv"}}, "config2": {"source": {"type": "csv"}, "comparison": {"nan_equals": false}}}`); err == nil {
+		t.Error("ParseRun() expected error for unknown field nan_equals, got nil")
+	}
+	if cfg, err := Parse(nil); err != nil || cfg == nil {
+		t.Errorf("Parse() of nothing got = %v, %v, want an empty config", cfg, err)
+	}
+}
+
+func TestMergeComparison(t *testing.T) {
+	no, maxDiffs := false, 10
+	c1 := &Comparison{NaNEqual: &no, FieldRules: map[string]FieldRule{"a": {Ignore: true}}, Thresholds: &Thresholds{MaxDiffs: &maxDiffs}}
+	c2 := &Comparison{Expect: "subset", FieldRules: map[string]FieldRule{"b": {TrimWhitespace: true}}, Thresholds: &Thresholds{MaxDiffs: &maxDiffs}, Multiset: &Multiset{}}
+
+	got, err := MergeComparison(c1, c2)
+	if err != nil {
+		t.Fatalf("MergeComparison() error = %v", err)
+	}
+	want := &Comparison{NaNEqual: &no, Expect: "subset", FieldRules: map[string]FieldRule{"a": {Ignore: true}, "b": {TrimWhitespace: true}},
+		Thresholds: &Thresholds{MaxDiffs: &maxDiffs}, Multiset: &Multiset{}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeComparison() got = %+v, want %+v", got, want)
+	}
+	if got, err := MergeComparison(nil, c2); got != c2 || err != nil {
+		t.Errorf("MergeComparison(nil, c2) got = %v, %v, want c2", got, err)
+	}
+
+	otherMax := 20
+	c2.Thresholds = &Thresholds{MaxDiffs: &otherMax}
+	if _, err := MergeComparison(c1, c2); err == nil || err.Error() != "comparison.thresholds.max_diffs is set differently in config1 and config2" {
+		t.Errorf("MergeComparison() error got = %v, want conflicting max_diffs", err)
+	}
+	c2.Thresholds = nil
+	c2.FieldRules["a"] = FieldRule{CaseInsensitive: true}
+	if _, err := MergeComparison(c1, c2); err == nil || !strings.Contains(err.Error(), "comparison.field_rules.a ") {
+		t.Errorf("MergeComparison() error got = %v, want conflicting field rule a", err)
+	}
+}
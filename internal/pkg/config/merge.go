@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// MergeComparison merges the comparison settings of two configs, either of
+// which may be nil, so each config may hold some of them. A setting in only
+// one of them applies; nested settings and maps are merged the same way. It
+// returns an error naming the first setting the two set differently, and
+// nil if neither has any.
+func MergeComparison(c1, c2 *Comparison) (*Comparison, error) {
+	if c1 == nil || c2 == nil {
+		if c1 == nil {
+			return c2, nil
+		}
+		return c1, nil
+	}
+	merged := reflect.New(reflect.TypeOf(Comparison{})).Elem()
+	if err := mergeValue(merged, reflect.ValueOf(*c1), reflect.ValueOf(*c2), "comparison"); err != nil {
+		return nil, err
+	}
+	c := merged.Interface().(Comparison)
+	return &c, nil
+}
+
+// mergeValue sets out to the merge of v1 and v2, settings named path.
+func mergeValue(out, v1, v2 reflect.Value, path string) error {
+	switch {
+	case v1.IsZero():
+		out.Set(v2)
+		return nil
+	case v2.IsZero():
+		out.Set(v1)
+		return nil
+	}
+
+	switch v1.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v1.NumField(); i++ {
+			name, _, _ := strings.Cut(v1.Type().Field(i).Tag.Get("yaml"), ",")
+			fieldPath := path
+			if name != "" {
+				fieldPath += "." + name
+			}
+			if err := mergeValue(out.Field(i), v1.Field(i), v2.Field(i), fieldPath); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Pointer:
+		if v1.Elem().Kind() != reflect.Struct {
+			break
+		}
+		merged := reflect.New(v1.Elem().Type())
+		if err := mergeValue(merged.Elem(), v1.Elem(), v2.Elem(), path); err != nil {
+			return err
+		}
+		out.Set(merged)
+		return nil
+	case reflect.Map:
+		merged := reflect.MakeMap(v1.Type())
+		for _, v := range []reflect.Value{v1, v2} {
+			iter := v.MapRange()
+			for iter.Next() {
+				if existing := merged.MapIndex(iter.Key()); existing.IsValid() && !reflect.DeepEqual(existing.Interface(), iter.Value().Interface()) {
+					return fmt.Errorf("%s.%v is set differently in config1 and config2", path, iter.Key())
+				}
+				merged.SetMapIndex(iter.Key(), iter.Value())
+			}
+		}
+		out.Set(merged)
+		return nil
+	}
+	if !reflect.DeepEqual(v1.Interface(), v2.Interface()) {
+		return fmt.Errorf("%s is set differently in config1 and config2", path)
+	}
+	out.Set(v1)
+	return nil
+}
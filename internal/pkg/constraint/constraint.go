@@ -0,0 +1,324 @@
+// Package constraint evaluates cross-field assertions on single records, such
+// as "end_date >= start_date" or "total == price * quantity".
+//
+// Expressions use Go syntax: field names (dotted for nested fields, or
+// field("name") for names that are not identifiers), number and string
+// literals, true and false, the arithmetic operators + - * /, comparisons,
+// and the logical operators && || !. Numbers compare numerically, datetimes
+// chronologically and anything else as strings. An expression that refers to
+// a missing or null field is not checked.
+package constraint
+
+import (
+	"data-comparator/internal/pkg/config"
+	"data-comparator/internal/pkg/datareader"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// epsilon is the relative tolerance of numeric equality, so that computed
+// values like price * quantity compare equal despite float round-off.
+const epsilon = 1e-9
+
+var dateTimeLayouts = []string{
+	time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02", "01/02/2006",
+}
+
+// Constraint is a compiled assertion.
+type Constraint struct {
+	Name string
+	Expr string
+	root ast.Expr
+}
+
+// Compile parses expr into a Constraint. The name identifies it in violations
+// and defaults to the expression itself.
+func Compile(name, expr string) (*Constraint, error) {
+	root, err := parser.ParseExpr(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid constraint %q: %w", expr, err)
+	}
+	if err := validate(root); err != nil {
+		return nil, fmt.Errorf("invalid constraint %q: %w", expr, err)
+	}
+	if name == "" {
+		name = expr
+	}
+	return &Constraint{Name: name, Expr: expr, root: root}, nil
+}
+
+// CompileConfig compiles the constraints of a comparison config section.
+func CompileConfig(cfg *config.Comparison) ([]*Constraint, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	constraints := make([]*Constraint, 0, len(cfg.Constraints))
+	for _, c := range cfg.Constraints {
+		compiled, err := Compile(c.Name, c.Check)
+		if err != nil {
+			return nil, err
+		}
+		constraints = append(constraints, compiled)
+	}
+	return constraints, nil
+}
+
+// Check evaluates the constraint on rec. It reports false when the assertion
+// does not hold, and true when it holds or refers to a missing value.
+func (c *Constraint) Check(rec datareader.Record) (bool, error) {
+	v, err := eval(c.root, rec)
+	if err != nil {
+		return false, err
+	}
+	switch t := v.(type) {
+	case nil:
+		return true, nil
+	case bool:
+		return t, nil
+	default:
+		return false, fmt.Errorf("constraint %q is not a boolean expression", c.Expr)
+	}
+}
+
+// validate rejects syntax the evaluator does not support, so mistakes surface
+// when the configuration is loaded rather than on the first record.
+func validate(node ast.Expr) error {
+	switch n := node.(type) {
+	case *ast.BasicLit:
+		if n.Kind != token.INT && n.Kind != token.FLOAT && n.Kind != token.STRING {
+			return fmt.Errorf("unsupported literal %s", n.Value)
+		}
+		return nil
+	case *ast.Ident:
+		return nil
+	case *ast.SelectorExpr:
+		_, err := fieldName(n)
+		return err
+	case *ast.ParenExpr:
+		return validate(n.X)
+	case *ast.UnaryExpr:
+		if n.Op != token.SUB && n.Op != token.NOT {
+			return fmt.Errorf("unsupported operator %s", n.Op)
+		}
+		return validate(n.X)
+	case *ast.BinaryExpr:
+		switch n.Op {
+		case token.ADD, token.SUB, token.MUL, token.QUO,
+			token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ,
+			token.LAND, token.LOR:
+		default:
+			return fmt.Errorf("unsupported operator %s", n.Op)
+		}
+		if err := validate(n.X); err != nil {
+			return err
+		}
+		return validate(n.Y)
+	case *ast.CallExpr:
+		_, err := fieldName(n)
+		return err
+	default:
+		return fmt.Errorf("unsupported expression %T", node)
+	}
+}
+
+// fieldName returns the dotted field name a selector or field("...") call refers to.
+func fieldName(node ast.Expr) (string, error) {
+	switch n := node.(type) {
+	case *ast.Ident:
+		return n.Name, nil
+	case *ast.SelectorExpr:
+		parent, err := fieldName(n.X)
+		if err != nil {
+			return "", err
+		}
+		return parent + "." + n.Sel.Name, nil
+	case *ast.CallExpr:
+		fn, ok := n.Fun.(*ast.Ident)
+		if !ok || fn.Name != "field" || len(n.Args) != 1 {
+			return "", fmt.Errorf("only field(\"name\") calls are supported")
+		}
+		lit, ok := n.Args[0].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return "", fmt.Errorf("field() takes a string literal")
+		}
+		return strconv.Unquote(lit.Value)
+	default:
+		return "", fmt.Errorf("unsupported field reference %T", node)
+	}
+}
+
+func eval(node ast.Expr, rec datareader.Record) (interface{}, error) {
+	switch n := node.(type) {
+	case *ast.BasicLit:
+		if n.Kind == token.STRING {
+			return strconv.Unquote(n.Value)
+		}
+		return strconv.ParseFloat(n.Value, 64)
+	case *ast.Ident:
+		switch n.Name {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return lookup(rec, n.Name), nil
+	case *ast.SelectorExpr, *ast.CallExpr:
+		name, err := fieldName(n)
+		if err != nil {
+			return nil, err
+		}
+		return lookup(rec, name), nil
+	case *ast.ParenExpr:
+		return eval(n.X, rec)
+	case *ast.UnaryExpr:
+		x, err := eval(n.X, rec)
+		if err != nil || x == nil {
+			return nil, err
+		}
+		if n.Op == token.NOT {
+			b, ok := x.(bool)
+			if !ok {
+				return nil, fmt.Errorf("! applied to non-boolean %v", x)
+			}
+			return !b, nil
+		}
+		f, ok := toFloat(x)
+		if !ok {
+			return nil, fmt.Errorf("- applied to non-number %v", x)
+		}
+		return -f, nil
+	case *ast.BinaryExpr:
+		return evalBinary(n, rec)
+	default:
+		return nil, fmt.Errorf("unsupported expression %T", node)
+	}
+}
+
+func evalBinary(n *ast.BinaryExpr, rec datareader.Record) (interface{}, error) {
+	x, err := eval(n.X, rec)
+	if err != nil {
+		return nil, err
+	}
+	y, err := eval(n.Y, rec)
+	if err != nil {
+		return nil, err
+	}
+	if x == nil || y == nil {
+		return nil, nil
+	}
+
+	switch n.Op {
+	case token.LAND, token.LOR:
+		bx, okx := x.(bool)
+		by, oky := y.(bool)
+		if !okx || !oky {
+			return nil, fmt.Errorf("%s applied to non-boolean operands %v and %v", n.Op, x, y)
+		}
+		if n.Op == token.LAND {
+			return bx && by, nil
+		}
+		return bx || by, nil
+	case token.ADD, token.SUB, token.MUL, token.QUO:
+		fx, okx := toFloat(x)
+		fy, oky := toFloat(y)
+		if !okx || !oky {
+			return nil, fmt.Errorf("%s applied to non-numbers %v and %v", n.Op, x, y)
+		}
+		switch n.Op {
+		case token.ADD:
+			return fx + fy, nil
+		case token.SUB:
+			return fx - fy, nil
+		case token.MUL:
+			return fx * fy, nil
+		default:
+			if fy == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			return fx / fy, nil
+		}
+	default:
+		return compare(n.Op, x, y), nil
+	}
+}
+
+// compare orders two values numerically, chronologically or as strings.
+func compare(op token.Token, x, y interface{}) bool {
+	var cmp int
+	fx, okx := toFloat(x)
+	fy, oky := toFloat(y)
+	tx, okTx := toTime(x)
+	ty, okTy := toTime(y)
+	switch {
+	case okx && oky:
+		if math.Abs(fx-fy) <= epsilon*math.Max(math.Abs(fx), math.Abs(fy)) {
+			cmp = 0
+		} else if fx < fy {
+			cmp = -1
+		} else {
+			cmp = 1
+		}
+	case okTx && okTy:
+		cmp = tx.Compare(ty)
+	default:
+		cmp = strings.Compare(fmt.Sprintf("%v", x), fmt.Sprintf("%v", y))
+	}
+
+	switch op {
+	case token.EQL:
+		return cmp == 0
+	case token.NEQ:
+		return cmp != 0
+	case token.LSS:
+		return cmp < 0
+	case token.LEQ:
+		return cmp <= 0
+	case token.GTR:
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+// lookup returns the value of a dotted field name, or nil if it is absent.
+func lookup(rec datareader.Record, name string) interface{} {
+	var current interface{} = map[string]interface{}(rec)
+	for _, part := range strings.Split(name, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[part]
+	}
+	return current
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case bool:
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(fmt.Sprintf("%v", v), 64)
+	return f, err == nil
+}
+
+func toTime(v interface{}) (time.Time, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	for _, layout := range dateTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,73 @@
+package constraint
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	rec := datareader.Record{
+		"start_date": "2025-09-01",
+		"end_date":   "2025-09-10T00:00:00Z",
+		"price":      19.99,
+		"quantity":   "3",
+		"total":      "59.97",
+		"status":     "shipped",
+		"customer":   map[string]interface{}{"age": 17.0},
+		"unit-cost":  "5",
+		"note":       nil,
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"end_date >= start_date", true},
+		{"end_date < start_date", false},
+		{"total == price * quantity", true},
+		{"total != price * quantity", false},
+		{`status == "shipped" && quantity > 0`, true},
+		{"customer.age >= 18 || status == \"pending\"", false},
+		{`field("unit-cost") * quantity < 20`, true},
+		{"!(price > 100)", true},
+		{"-price < 0", true},
+		{"note == \"x\"", true}, // missing values are not checked
+		{"missing.field > 1", true},
+	}
+	for _, tt := range tests {
+		c, err := Compile("", tt.expr)
+		if err != nil {
+			t.Errorf("Compile(%q) error = %v", tt.expr, err)
+			continue
+		}
+		got, err := c.Check(rec)
+		if err != nil {
+			t.Errorf("Check(%q) error = %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Check(%q) got = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	for _, expr := range []string{"a >=", "a % 2 == 0", "len(a) > 1", "a[0] == 1", "'c' == a"} {
+		if _, err := Compile("", expr); err == nil {
+			t.Errorf("Compile(%q) expected error, got nil", expr)
+		}
+	}
+}
+
+func TestCheck_Errors(t *testing.T) {
+	rec := datareader.Record{"a": "x", "b": 0.0}
+	for _, expr := range []string{"a * 2 > 1", "1 / b > 0", "a"} {
+		c, err := Compile("", expr)
+		if err != nil {
+			t.Fatalf("Compile(%q) error = %v", expr, err)
+		}
+		if _, err := c.Check(rec); err == nil {
+			t.Errorf("Check(%q) expected error, got nil", expr)
+		}
+	}
+}
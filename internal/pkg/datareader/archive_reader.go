@@ -0,0 +1,383 @@
+package datareader
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"data-comparator/internal/pkg/config"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// archiveExtensions are the file extensions of archives whose members a
+// path can select, each with its archive format. Tar archives may be
+// compressed with any of Compressions.
+var archiveExtensions = []struct {
+	ext    string
+	format string
+}{
+	{".zip", "zip"},
+	{".tar", "tar"},
+	{".tar.gz", "tar"},
+	{".tgz", "tar"},
+	{".tar.zst", "tar"},
+	{".tar.bz2", "tar"},
+}
+
+// SplitArchivePath splits a path like archive.zip!data/*.csv into the path
+// of the archive and the glob pattern selecting its members, which is empty
+// to select them all. ok is false if path does not point into an archive.
+func SplitArchivePath(p string) (archive, pattern string, ok bool) {
+	for i := strings.Index(p, "!"); i >= 0; {
+		if archiveFormat(p[:i]) != "" {
+			return p[:i], p[i+1:], true
+		}
+		next := strings.Index(p[i+1:], "!")
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return "", "", false
+}
+
+// IsArchivePath reports whether path selects members of a zip or tar
+// archive, as archive.zip!pattern.
+func IsArchivePath(p string) bool {
+	_, _, ok := SplitArchivePath(p)
+	return ok
+}
+
+// archiveFormat returns zip or tar for the extension of archive, or "".
+func archiveFormat(archive string) string {
+	lower := strings.ToLower(archive)
+	for _, a := range archiveExtensions {
+		if strings.HasSuffix(lower, a.ext) {
+			return a.format
+		}
+	}
+	return ""
+}
+
+// matchMember reports whether the archive member name is selected by
+// pattern. An empty pattern selects all members, except those whose names
+// start with "." or "_", as in directories.
+func matchMember(pattern, name string) (bool, error) {
+	if pattern == "" {
+		base := path.Base(name)
+		return !strings.HasPrefix(base, ".") && !strings.HasPrefix(base, "_"), nil
+	}
+	matched, err := path.Match(pattern, name)
+	if err != nil {
+		return false, fmt.Errorf("invalid member pattern %s: %w", pattern, err)
+	}
+	return matched, nil
+}
+
+// archiveMembers iterates the selected members of an archive.
+type archiveMembers interface {
+	// next returns the name and contents of the next member, or io.EOF.
+	next() (string, io.Reader, error)
+}
+
+// zipMembers are the members of a zip archive, sorted like the files of a
+// directory path.
+type zipMembers struct {
+	files []*zip.File
+}
+
+func newZipMembers(file sourceFile, pattern, order string) (*zipMembers, error) {
+	size, err := file.Size()
+	if err != nil {
+		return nil, err
+	}
+	var at io.ReaderAt = file
+	if _, local := file.(localFile); !local {
+		at = &blockReaderAt{file: file, size: size}
+	}
+	archive, err := zip.NewReader(at, size)
+	if err != nil {
+		return nil, err
+	}
+	m := &zipMembers{}
+	for _, f := range archive.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		matched, err := matchMember(pattern, f.Name)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			m.files = append(m.files, f)
+		}
+	}
+	sort.Slice(m.files, func(i, j int) bool { return m.files[i].Name < m.files[j].Name })
+	if order == "mtime" {
+		sort.SliceStable(m.files, func(i, j int) bool { return m.files[i].Modified.Before(m.files[j].Modified) })
+	}
+	return m, nil
+}
+
+func (m *zipMembers) next() (string, io.Reader, error) {
+	if len(m.files) == 0 {
+		return "", nil, io.EOF
+	}
+	f := m.files[0]
+	m.files = m.files[1:]
+	contents, err := f.Open()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to open member %s: %w", f.Name, err)
+	}
+	return f.Name, contents, nil
+}
+
+// archiveBlockSize is how much of a zip archive in object storage or on a
+// remote server is fetched at a time.
+const archiveBlockSize = 1 << 20
+
+// blockReaderAt serves the small reads of zip decompressors from whole
+// blocks of file, so a remote archive takes a request per block rather than
+// per read.
+type blockReaderAt struct {
+	file  io.ReaderAt
+	size  int64
+	mu    sync.Mutex
+	start int64
+	block []byte
+}
+
+func (b *blockReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if len(p) >= archiveBlockSize {
+		return b.file.ReadAt(p, off)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := 0
+	for n < len(p) {
+		at := off + int64(n)
+		if at >= b.size {
+			return n, io.EOF
+		}
+		if at < b.start || at >= b.start+int64(len(b.block)) {
+			b.start = at
+			b.block = make([]byte, min(archiveBlockSize, b.size-at))
+			read, err := b.file.ReadAt(b.block, at)
+			b.block = b.block[:read]
+			if read == 0 && err != nil {
+				return n, err
+			}
+		}
+		n += copy(p[n:], b.block[at-b.start:])
+	}
+	return n, nil
+}
+
+// tarMembers are the members of a tar archive, in the order they are
+// stored, as the archive is streamed rather than read twice.
+type tarMembers struct {
+	archive *tar.Reader
+	pattern string
+}
+
+func (m *tarMembers) next() (string, io.Reader, error) {
+	for {
+		header, err := m.archive.Next()
+		if err != nil {
+			return "", nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := strings.TrimPrefix(header.Name, "./")
+		matched, err := matchMember(m.pattern, name)
+		if err != nil {
+			return "", nil, err
+		}
+		if matched {
+			// Closing a member must not close the archive.
+			return name, struct{ io.Reader }{m.archive}, nil
+		}
+	}
+}
+
+// openArchive opens the archive of an archive.zip!pattern path and the
+// iteration of the members pattern selects. The archive may itself be in
+// object storage or on a remote server.
+func openArchive(cfg config.Source) (string, io.Closer, archiveMembers, error) {
+	archive, pattern, ok := SplitArchivePath(cfg.Path)
+	if !ok {
+		return "", nil, nil, fmt.Errorf("%s is not a path into a zip or tar archive", cfg.Path)
+	}
+	if cfg.Order != "" && !slices.Contains(PathOrders, cfg.Order) {
+		return "", nil, nil, fmt.Errorf("unsupported order %s, use one of %s", cfg.Order, strings.Join(PathOrders, ", "))
+	}
+	if _, err := matchMember(pattern, ""); err != nil {
+		return "", nil, nil, err
+	}
+	archiveCfg := cfg
+	archiveCfg.Path = archive
+	file, err := openSource(archiveCfg)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to open archive %s: %w", archive, err)
+	}
+
+	var closer io.Closer = file
+	var members archiveMembers
+	if archiveFormat(archive) == "zip" {
+		members, err = newZipMembers(file, pattern, cfg.Order)
+	} else {
+		// The compression of cfg is that of the members; the archive's own
+		// is detected.
+		var input io.Reader
+		input, closer, _, err = decompress(file, file, config.Source{Path: archive})
+		if err == nil {
+			members = &tarMembers{archive: tar.NewReader(input), pattern: pattern}
+		}
+	}
+	if err != nil {
+		file.Close()
+		return "", nil, nil, fmt.Errorf("failed to read archive %s: %w", archive, err)
+	}
+	return archive, closer, members, nil
+}
+
+// ArchiveMembers returns the names of the members the archive path of cfg
+// selects, in the order they are read.
+func ArchiveMembers(cfg config.Source) ([]string, error) {
+	archive, file, members, err := openArchive(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var names []string
+	for {
+		name, contents, err := members.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive %s: %w", archive, err)
+		}
+		if c, ok := contents.(io.Closer); ok {
+			c.Close()
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		_, pattern, _ := SplitArchivePath(cfg.Path)
+		return nil, fmt.Errorf("no members of %s match %q", archive, pattern)
+	}
+	return names, nil
+}
+
+// ArchiveReader reads the members of a zip or tar archive selected by a
+// glob pattern one after another as a single stream of records, each
+// through NewFromReader, without extracting them. Only one member is open
+// at a time.
+type ArchiveReader struct {
+	cfg      config.Source
+	archive  string
+	file     io.Closer
+	members  archiveMembers
+	current  DataReader
+	name     string
+	warnings []string
+}
+
+// NewArchiveReader opens the archive of an archive.zip!pattern path and the
+// first member matching pattern.
+func NewArchiveReader(cfg config.Source) (*ArchiveReader, error) {
+	archive, file, members, err := openArchive(cfg)
+	if err != nil {
+		return nil, err
+	}
+	r := &ArchiveReader{cfg: cfg, archive: archive, file: file, members: members}
+	// The transform and field names apply to the stream as a whole.
+	r.cfg.Transform, r.cfg.FieldNames = nil, nil
+	if err := r.open(); err != nil {
+		file.Close()
+		if err == io.EOF {
+			_, pattern, _ := SplitArchivePath(cfg.Path)
+			return nil, fmt.Errorf("no members of %s match %q", archive, pattern)
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+// open opens the next member, returning io.EOF after the last.
+func (r *ArchiveReader) open() error {
+	name, contents, err := r.members.next()
+	if err == io.EOF {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read archive %s: %w", r.archive, err)
+	}
+	cfg := r.cfg
+	cfg.Path = r.archive + "!" + name
+	reader, err := NewFromReader(contents, cfg)
+	if err != nil {
+		return err
+	}
+	r.current, r.name = reader, name
+	return nil
+}
+
+// Read returns the next record, moving on to the next member at the end of
+// one, or io.EOF after the last member.
+func (r *ArchiveReader) Read() (Record, error) {
+	for {
+		if r.current == nil {
+			if err := r.open(); err != nil {
+				return nil, err
+			}
+		}
+		rec, err := r.current.Read()
+		if err != io.EOF {
+			return rec, err
+		}
+		if err := r.closeCurrent(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (r *ArchiveReader) closeCurrent() error {
+	if w, ok := r.current.(Warner); ok {
+		for _, warning := range w.Warnings() {
+			r.warnings = append(r.warnings, fmt.Sprintf("%s!%s: %s", r.archive, r.name, warning))
+		}
+	}
+	err := r.current.Close()
+	r.current = nil
+	return err
+}
+
+// Warnings returns the warnings of the members read so far.
+func (r *ArchiveReader) Warnings() []string {
+	warnings := slices.Clone(r.warnings)
+	if w, ok := r.current.(Warner); ok {
+		for _, warning := range w.Warnings() {
+			warnings = append(warnings, fmt.Sprintf("%s!%s: %s", r.archive, r.name, warning))
+		}
+	}
+	return warnings
+}
+
+// Close closes the member being read and the archive.
+func (r *ArchiveReader) Close() error {
+	var err error
+	if r.current != nil {
+		err = r.closeCurrent()
+	}
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
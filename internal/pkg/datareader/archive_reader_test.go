@@ -0,0 +1,142 @@
+package datareader
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"data-comparator/internal/pkg/config"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// archiveTestMembers are written to test archives in this order.
+var archiveTestMembers = []struct {
+	name string
+	data string
+}{
+	{"data/part-1.csv", "id,name\n3,Carol\n"},
+	{"data/part-0.csv", "id,name\n1,Alice\n2,Bob\n"},
+	{"data/_SUCCESS", ""},
+	{"README.txt", "not data"},
+}
+
+func writeTestArchives(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+
+	var zipped bytes.Buffer
+	zw := zip.NewWriter(&zipped)
+	for i, m := range archiveTestMembers {
+		// part-1 is the oldest.
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: m.name, Method: zip.Deflate, Modified: time.Date(2025, 1, 1+i, 0, 0, 0, 0, time.UTC)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(m.data))
+	}
+	zw.Close()
+	zipPath := filepath.Join(dir, "export.zip")
+	if err := os.WriteFile(zipPath, zipped.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var tarred bytes.Buffer
+	tw := tar.NewWriter(&tarred)
+	tw.WriteHeader(&tar.Header{Name: "./data/", Typeflag: tar.TypeDir, Mode: 0755})
+	for _, m := range archiveTestMembers {
+		tw.WriteHeader(&tar.Header{Name: "./" + m.name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(m.data))})
+		tw.Write([]byte(m.data))
+	}
+	tw.Close()
+	tarPath := filepath.Join(dir, "export.tar.gz")
+	if err := os.WriteFile(tarPath, compressTestData(t, "gzip", tarred.Bytes()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return zipPath, tarPath
+}
+
+func TestSplitArchivePath(t *testing.T) {
+	for _, tt := range []struct {
+		path, archive, pattern string
+		ok                     bool
+	}{
+		{"export.zip!data/*.csv", "export.zip", "data/*.csv", true},
+		{"s3://bucket/EXPORT.TGZ!*.json", "s3://bucket/EXPORT.TGZ", "*.json", true},
+		{"dir!/export.tar.zst!", "dir!/export.tar.zst", "", true},
+		{"export.zip", "", "", false},
+		{"data!.csv", "", "", false},
+	} {
+		archive, pattern, ok := SplitArchivePath(tt.path)
+		if archive != tt.archive || pattern != tt.pattern || ok != tt.ok {
+			t.Errorf("SplitArchivePath(%s) got = %s, %s, %v, want %s, %s, %v", tt.path, archive, pattern, ok, tt.archive, tt.pattern, tt.ok)
+		}
+	}
+}
+
+func TestArchiveReader(t *testing.T) {
+	zipPath, tarPath := writeTestArchives(t)
+	ids := func(cfg config.Source) []interface{} {
+		t.Helper()
+		reader, err := New(cfg)
+		if err != nil {
+			t.Fatalf("New(%s) error = %v", cfg.Path, err)
+		}
+		var got []interface{}
+		for _, rec := range readAllRecords(t, reader) {
+			got = append(got, rec["id"])
+		}
+		return got
+	}
+	for _, tt := range []struct {
+		cfg  config.Source
+		want []interface{}
+	}{
+		{config.Source{Type: "csv", Path: zipPath + "!data/*.csv"}, []interface{}{"1", "2", "3"}},
+		{config.Source{Type: "auto", Path: zipPath + "!data/*.csv", Order: "mtime"}, []interface{}{"3", "1", "2"}},
+		{config.Source{Type: "csv", Path: zipPath + "!data/part-0.csv"}, []interface{}{"1", "2"}},
+		// Tar members are read in the order they are stored.
+		{config.Source{Type: "csv", Path: tarPath + "!data/*.csv"}, []interface{}{"3", "1", "2"}},
+	} {
+		if got := ids(tt.cfg); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Records of %s got = %v, want %v", tt.cfg.Path, got, tt.want)
+		}
+	}
+
+	for _, path := range []string{zipPath + "!*.csv", tarPath + "!data/*.json", filepath.Dir(zipPath) + "/missing.zip!*"} {
+		if _, err := New(config.Source{Type: "csv", Path: path}); err == nil {
+			t.Errorf("New(%s) expected an error, got nil", path)
+		}
+	}
+}
+
+func TestArchiveMembers(t *testing.T) {
+	zipPath, tarPath := writeTestArchives(t)
+	want := []string{"README.txt", "data/part-0.csv", "data/part-1.csv"}
+	if got, err := ArchiveMembers(config.Source{Path: zipPath + "!"}); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ArchiveMembers() got = %v, %v, want %v", got, err, want)
+	}
+	want = []string{"data/part-1.csv", "data/part-0.csv"}
+	if got, err := ArchiveMembers(config.Source{Path: tarPath + "!data/part-*"}); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ArchiveMembers() got = %v, %v, want %v", got, err, want)
+	}
+	if _, err := ArchiveMembers(config.Source{Path: tarPath + "![bad"}); err == nil || !strings.Contains(err.Error(), "invalid member pattern") {
+		t.Errorf("ArchiveMembers() error = %v, want an invalid member pattern", err)
+	}
+}
+
+func TestBlockReaderAt(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), archiveBlockSize/4)
+	b := &blockReaderAt{file: bytes.NewReader(data), size: int64(len(data))}
+	for _, off := range []int64{0, 10, archiveBlockSize - 5, int64(len(data)) - 3} {
+		p := make([]byte, 10)
+		n, err := b.ReadAt(p, off)
+		want := data[off:min(off+10, int64(len(data)))]
+		if !bytes.Equal(p[:n], want) || (n < len(p)) != (err != nil) {
+			t.Errorf("ReadAt(%d) got = %q, %v, want %q", off, p[:n], err, want)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package datareader
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"data-comparator/internal/pkg/config"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// avroMagic starts every Avro object container file.
+var avroMagic = []byte("Obj\x01")
+
+// AvroReader reads records from an Avro object container file or, when the
+// source names a schema registry, from concatenated Confluent wire-format
+// messages whose schemas are fetched from the registry by id. Values keep
+// their types like those of the ParquetReader, nested records become nested
+// records as with JSON, and bytes and fixed values stay []byte. Container
+// blocks may be uncompressed, deflate or snappy.
+type AvroReader struct {
+	path    string
+	file    io.Closer
+	input   *bufio.Reader
+	records int
+
+	// container file state
+	schema *avroSchema
+	codec  string
+	sync   []byte
+	block  *avroDecoder
+	left   int64
+
+	// wire format state
+	registry *avroRegistry
+}
+
+// NewAvroReader creates a new reader for Avro files.
+func NewAvroReader(cfg config.Source) (DataReader, error) {
+	file, err := openSource(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open avro file %s: %w", cfg.Path, err)
+	}
+	return newAvroReader(file, file, cfg)
+}
+
+// newAvroReader reads Avro from input, named by cfg.Path, and closes closer
+// when done or on error.
+func newAvroReader(input io.Reader, closer io.Closer, cfg config.Source) (*AvroReader, error) {
+	r := &AvroReader{path: cfg.Path, file: closer, input: bufio.NewReader(input)}
+	if cfg.SchemaRegistry != "" {
+		r.registry = newAvroRegistry(cfg.SchemaRegistry)
+		return r, nil
+	}
+	if err := r.readHeader(); err != nil {
+		closer.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// readHeader reads the schema, codec and sync marker of a container file.
+func (r *AvroReader) readHeader() error {
+	magic := make([]byte, len(avroMagic))
+	if _, err := io.ReadFull(r.input, magic); err != nil || !bytes.Equal(magic, avroMagic) {
+		return fmt.Errorf("%s is not an avro container file: missing magic number", r.path)
+	}
+	d := newAvroDecoder(r.input)
+	meta, err := d.value(&avroSchema{typ: "map", values: &avroSchema{typ: "bytes"}})
+	if err != nil {
+		return fmt.Errorf("failed to read avro header of %s: %w", r.path, err)
+	}
+	header := meta.(map[string]interface{})
+	schemaJSON, _ := header["avro.schema"].([]byte)
+	if r.schema, err = parseAvroSchema(schemaJSON); err != nil {
+		return fmt.Errorf("avro file %s: %w", r.path, err)
+	}
+	if r.schema.typ != "record" {
+		return fmt.Errorf("avro file %s holds %s values, not records", r.path, r.schema.typ)
+	}
+	codec, _ := header["avro.codec"].([]byte)
+	switch r.codec = string(codec); r.codec {
+	case "", "null", "deflate", "snappy":
+	default:
+		return fmt.Errorf("avro file %s: unsupported codec %s (only null, deflate and snappy are supported)", r.path, r.codec)
+	}
+	if r.sync, err = d.bytes(16); err != nil {
+		return fmt.Errorf("failed to read avro header of %s: %w", r.path, err)
+	}
+	return nil
+}
+
+// Read returns the next record, or io.EOF at the end of the file.
+func (r *AvroReader) Read() (Record, error) {
+	var rec interface{}
+	var err error
+	if r.registry != nil {
+		rec, err = r.readMessage()
+	} else {
+		rec, err = r.readContainer()
+	}
+	if err == io.EOF {
+		return nil, err
+	}
+	r.records++
+	if err != nil {
+		return nil, &ParseError{Source: r.path, Record: r.records, Offset: -1, Err: err}
+	}
+	return Record(rec.(map[string]interface{})), nil
+}
+
+func (r *AvroReader) readContainer() (interface{}, error) {
+	for r.left == 0 {
+		if err := r.nextBlock(); err != nil {
+			return nil, err
+		}
+	}
+	r.left--
+	return r.block.value(r.schema)
+}
+
+// nextBlock reads and decompresses the next block of the container file.
+func (r *AvroReader) nextBlock() error {
+	if _, err := r.input.Peek(1); err == io.EOF {
+		return io.EOF
+	}
+	d := newAvroDecoder(r.input)
+	count, err := d.long()
+	if err != nil {
+		return fmt.Errorf("failed to read block header: %w", err)
+	}
+	size, err := d.length()
+	if err != nil {
+		return fmt.Errorf("failed to read block header: %w", err)
+	}
+	if count < 0 {
+		return fmt.Errorf("invalid block record count %d", count)
+	}
+	data, err := d.bytes(size)
+	if err != nil {
+		return fmt.Errorf("failed to read block: %w", err)
+	}
+	sync, err := d.bytes(len(r.sync))
+	if err != nil || !bytes.Equal(sync, r.sync) {
+		return fmt.Errorf("block is not followed by the sync marker")
+	}
+
+	switch r.codec {
+	case "deflate":
+		if data, err = io.ReadAll(flate.NewReader(bytes.NewReader(data))); err != nil {
+			return fmt.Errorf("failed to inflate block: %w", err)
+		}
+	case "snappy":
+		// Snappy blocks end in a CRC-32 of the uncompressed data.
+		if len(data) < 4 {
+			return fmt.Errorf("truncated snappy block")
+		}
+		if data, err = decodeSnappy(data[:len(data)-4]); err != nil {
+			return fmt.Errorf("failed to decompress block: %w", err)
+		}
+	}
+	r.block = newAvroDecoder(bytes.NewReader(data))
+	r.left = count
+	return nil
+}
+
+// readMessage reads the next Confluent wire-format message: a zero byte, the
+// big-endian schema id and the value encoded with that schema.
+func (r *AvroReader) readMessage() (interface{}, error) {
+	magic, err := r.input.ReadByte()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	if magic != 0 {
+		return nil, fmt.Errorf("message does not start with the wire format magic byte")
+	}
+	var id [4]byte
+	if _, err := io.ReadFull(r.input, id[:]); err != nil {
+		return nil, fmt.Errorf("truncated message header")
+	}
+	schema, err := r.registry.schema(binary.BigEndian.Uint32(id[:]))
+	if err != nil {
+		return nil, err
+	}
+	if schema.typ != "record" {
+		return nil, fmt.Errorf("schema %d describes %s values, not records", binary.BigEndian.Uint32(id[:]), schema.typ)
+	}
+	return newAvroDecoder(r.input).value(schema)
+}
+
+// Close closes the underlying file.
+func (r *AvroReader) Close() error {
+	return r.file.Close()
+}
+
+// avroRegistry fetches schemas by id from a Confluent schema registry and
+// caches them. Credentials may be given in the URL's user info.
+type avroRegistry struct {
+	url     string
+	client  *http.Client
+	schemas map[uint32]*avroSchema
+}
+
+func newAvroRegistry(url string) *avroRegistry {
+	return &avroRegistry{
+		url:     strings.TrimRight(url, "/"),
+		client:  &http.Client{Timeout: 30 * time.Second},
+		schemas: make(map[uint32]*avroSchema),
+	}
+}
+
+func (g *avroRegistry) schema(id uint32) (*avroSchema, error) {
+	if s, ok := g.schemas[id]; ok {
+		return s, nil
+	}
+	resp, err := g.client.Get(fmt.Sprintf("%s/schemas/ids/%d", g.url, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch schema %d: %w", id, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch schema %d: registry returned %s", id, resp.Status)
+	}
+	var body struct {
+		Schema     string `json:"schema"`
+		SchemaType string `json:"schemaType"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode schema %d: %w", id, err)
+	}
+	if body.SchemaType != "" && body.SchemaType != "AVRO" {
+		return nil, fmt.Errorf("schema %d is a %s schema, not avro", id, body.SchemaType)
+	}
+	s, err := parseAvroSchema([]byte(body.Schema))
+	if err != nil {
+		return nil, fmt.Errorf("schema %d: %w", id, err)
+	}
+	g.schemas[id] = s
+	return s, nil
+}
@@ -0,0 +1,379 @@
+package datareader
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// This file parses Avro schemas and decodes the Avro binary encoding.
+
+// avroMaxLength bounds the length of strings, bytes and blocks, so corrupt
+// data cannot trigger huge allocations.
+const avroMaxLength = 1 << 28
+
+// avroSchema is a parsed Avro schema node. Named types referenced again
+// share the same node.
+type avroSchema struct {
+	typ      string
+	name     string
+	fields   []avroField
+	items    *avroSchema // array items
+	values   *avroSchema // map values
+	branches []*avroSchema
+	symbols  []string
+	size     int
+	logical  string
+	scale    int
+}
+
+type avroField struct {
+	name   string
+	schema *avroSchema
+}
+
+// parseAvroSchema parses a schema in its JSON form.
+func parseAvroSchema(data []byte) (*avroSchema, error) {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid avro schema: %w", err)
+	}
+	p := &avroSchemaParser{named: make(map[string]*avroSchema)}
+	return p.parse(raw, "")
+}
+
+type avroSchemaParser struct {
+	named map[string]*avroSchema
+}
+
+func (p *avroSchemaParser) parse(raw interface{}, namespace string) (*avroSchema, error) {
+	switch v := raw.(type) {
+	case string:
+		return p.reference(v, namespace)
+	case []interface{}:
+		s := &avroSchema{typ: "union"}
+		for _, branch := range v {
+			b, err := p.parse(branch, namespace)
+			if err != nil {
+				return nil, err
+			}
+			s.branches = append(s.branches, b)
+		}
+		return s, nil
+	case map[string]interface{}:
+		return p.parseObject(v, namespace)
+	default:
+		return nil, fmt.Errorf("invalid avro schema node %v", raw)
+	}
+}
+
+// reference resolves a primitive type name or a previously defined named type.
+func (p *avroSchemaParser) reference(name, namespace string) (*avroSchema, error) {
+	switch name {
+	case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+		return &avroSchema{typ: name}, nil
+	}
+	if s, ok := p.named[fullAvroName(name, namespace)]; ok {
+		return s, nil
+	}
+	if s, ok := p.named[name]; ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("unknown avro type %q", name)
+}
+
+func (p *avroSchemaParser) parseObject(v map[string]interface{}, namespace string) (*avroSchema, error) {
+	typ, _ := v["type"].(string)
+	if typ == "" {
+		// {"type": {...}} wraps another schema.
+		return p.parse(v["type"], namespace)
+	}
+	s := &avroSchema{typ: typ}
+	s.logical, _ = v["logicalType"].(string)
+	if scale, ok := v["scale"].(float64); ok {
+		s.scale = int(scale)
+	}
+
+	switch typ {
+	case "record", "error", "enum", "fixed":
+		name, _ := v["name"].(string)
+		if name == "" {
+			return nil, fmt.Errorf("avro %s without a name", typ)
+		}
+		if ns, ok := v["namespace"].(string); ok && !strings.Contains(name, ".") {
+			namespace = ns
+		}
+		s.name = fullAvroName(name, namespace)
+		if i := strings.LastIndexByte(s.name, '.'); i >= 0 {
+			namespace = s.name[:i]
+		}
+		p.named[s.name] = s
+	}
+
+	switch typ {
+	case "record", "error":
+		s.typ = "record"
+		fields, _ := v["fields"].([]interface{})
+		for _, f := range fields {
+			field, _ := f.(map[string]interface{})
+			name, _ := field["name"].(string)
+			if name == "" {
+				return nil, fmt.Errorf("avro record %s has a field without a name", s.name)
+			}
+			fs, err := p.parse(field["type"], namespace)
+			if err != nil {
+				return nil, fmt.Errorf("field %s.%s: %w", s.name, name, err)
+			}
+			s.fields = append(s.fields, avroField{name: name, schema: fs})
+		}
+	case "enum":
+		symbols, _ := v["symbols"].([]interface{})
+		for _, sym := range symbols {
+			name, _ := sym.(string)
+			s.symbols = append(s.symbols, name)
+		}
+	case "fixed":
+		size, _ := v["size"].(float64)
+		s.size = int(size)
+	case "array":
+		items, err := p.parse(v["items"], namespace)
+		if err != nil {
+			return nil, err
+		}
+		s.items = items
+	case "map":
+		values, err := p.parse(v["values"], namespace)
+		if err != nil {
+			return nil, err
+		}
+		s.values = values
+	case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+	default:
+		return p.reference(typ, namespace)
+	}
+	return s, nil
+}
+
+func fullAvroName(name, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+// avroDecoder reads values in the Avro binary encoding.
+type avroDecoder struct {
+	r interface {
+		io.Reader
+		io.ByteReader
+	}
+}
+
+func newAvroDecoder(r io.Reader) *avroDecoder {
+	if br, ok := r.(interface {
+		io.Reader
+		io.ByteReader
+	}); ok {
+		return &avroDecoder{r: br}
+	}
+	return &avroDecoder{r: bufio.NewReader(r)}
+}
+
+func (d *avroDecoder) long() (int64, error) {
+	v, err := binary.ReadVarint(d.r)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return v, err
+}
+
+func (d *avroDecoder) length() (int, error) {
+	n, err := d.long()
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > avroMaxLength {
+		return 0, fmt.Errorf("invalid avro length %d", n)
+	}
+	return int(n), nil
+}
+
+func (d *avroDecoder) bytes(n int) ([]byte, error) {
+	buf := make([]byte, n)
+	_, err := io.ReadFull(d.r, buf)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return buf, err
+}
+
+// blockCount reads the item count of an array or map block. A negative count
+// is followed by the block's size in bytes, which is not needed.
+func (d *avroDecoder) blockCount() (int, error) {
+	n, err := d.long()
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		n = -n
+		if _, err := d.long(); err != nil {
+			return 0, err
+		}
+	}
+	if n > avroMaxLength {
+		return 0, fmt.Errorf("invalid avro block count %d", n)
+	}
+	return int(n), nil
+}
+
+// value decodes a value of schema s into the Record value of its type:
+// integers are int64, floating point numbers and decimals float64, dates and
+// timestamps time.Time, enums string, records and maps nested records, and
+// unions the value of their branch.
+func (d *avroDecoder) value(s *avroSchema) (interface{}, error) {
+	switch s.typ {
+	case "null":
+		return nil, nil
+	case "boolean":
+		b, err := d.r.ReadByte()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return b != 0, err
+	case "int", "long":
+		v, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		return convertAvroLong(v, s), nil
+	case "float":
+		buf, err := d.bytes(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(buf))), nil
+	case "double":
+		buf, err := d.bytes(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(buf)), nil
+	case "bytes", "string", "fixed":
+		n := s.size
+		if s.typ != "fixed" {
+			var err error
+			if n, err = d.length(); err != nil {
+				return nil, err
+			}
+		}
+		buf, err := d.bytes(n)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case s.typ == "string":
+			return string(buf), nil
+		case s.logical == "decimal":
+			return bigEndianDecimal(buf, s.scale), nil
+		}
+		return buf, nil
+	case "enum":
+		i, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= int64(len(s.symbols)) {
+			return nil, fmt.Errorf("enum %s index %d out of range", s.name, i)
+		}
+		return s.symbols[i], nil
+	case "union":
+		i, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= int64(len(s.branches)) {
+			return nil, fmt.Errorf("union branch %d out of range", i)
+		}
+		return d.value(s.branches[i])
+	case "array":
+		var items []interface{}
+		for {
+			n, err := d.blockCount()
+			if err != nil || n == 0 {
+				return items, err
+			}
+			for ; n > 0; n-- {
+				v, err := d.value(s.items)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, v)
+			}
+		}
+	case "map":
+		m := make(map[string]interface{})
+		for {
+			n, err := d.blockCount()
+			if err != nil || n == 0 {
+				return m, err
+			}
+			for ; n > 0; n-- {
+				kn, err := d.length()
+				if err != nil {
+					return nil, err
+				}
+				key, err := d.bytes(kn)
+				if err != nil {
+					return nil, err
+				}
+				if m[string(key)], err = d.value(s.values); err != nil {
+					return nil, err
+				}
+			}
+		}
+	case "record":
+		rec := make(map[string]interface{}, len(s.fields))
+		for _, f := range s.fields {
+			v, err := d.value(f.schema)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", f.name, err)
+			}
+			rec[f.name] = v
+		}
+		return rec, nil
+	default:
+		return nil, fmt.Errorf("unsupported avro type %s", s.typ)
+	}
+}
+
+// convertAvroLong applies the logical type of an int or long.
+func convertAvroLong(v int64, s *avroSchema) interface{} {
+	switch s.logical {
+	case "date":
+		return time.Unix(v*86400, 0).UTC()
+	case "timestamp-millis", "local-timestamp-millis":
+		return time.UnixMilli(v).UTC()
+	case "timestamp-micros", "local-timestamp-micros":
+		return time.UnixMicro(v).UTC()
+	case "timestamp-nanos", "local-timestamp-nanos":
+		return time.Unix(0, v).UTC()
+	}
+	return v
+}
+
+// bigEndianDecimal converts a big-endian two's complement unscaled decimal
+// value, as stored by Avro and Parquet, to a float64.
+func bigEndianDecimal(raw []byte, scale int) float64 {
+	unscaled := new(big.Int).SetBytes(raw)
+	if len(raw) > 0 && raw[0]&0x80 != 0 {
+		unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(8*len(raw))))
+	}
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(unscaled), new(big.Float).SetFloat64(math.Pow10(scale))).Float64()
+	return f
+}
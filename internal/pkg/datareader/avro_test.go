@@ -0,0 +1,215 @@
+package datareader
+
+import (
+	"bytes"
+	"compress/flate"
+	"data-comparator/internal/pkg/config"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testAvroSchema = `{"type": "record", "name": "User", "namespace": "test", "fields": [
+	{"name": "id", "type": "long"},
+	{"name": "name", "type": ["null", "string"]},
+	{"name": "created", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+	{"name": "address", "type": ["null", {"type": "record", "name": "Address", "fields": [
+		{"name": "city", "type": "string"}]}]},
+	{"name": "tags", "type": {"type": "array", "items": "string"}},
+	{"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["ACTIVE", "INACTIVE"]}},
+	{"name": "price", "type": {"type": "bytes", "logicalType": "decimal", "precision": 9, "scale": 2}}
+]}`
+
+func avroLong(buf []byte, v int64) []byte {
+	return binary.AppendVarint(buf, v)
+}
+
+func avroBytes(buf []byte, data []byte) []byte {
+	return append(avroLong(buf, int64(len(data))), data...)
+}
+
+// testAvroRecords are the binary encodings of the expected records below.
+func testAvroRecords() [][]byte {
+	var rec1 []byte
+	rec1 = avroLong(rec1, 1)
+	rec1 = avroBytes(avroLong(rec1, 1), []byte("alice"))
+	rec1 = avroLong(rec1, 1700000000000)
+	rec1 = avroBytes(avroLong(rec1, 1), []byte("Berlin"))
+	rec1 = avroBytes(avroBytes(avroLong(rec1, 2), []byte("a")), []byte("b"))
+	rec1 = avroLong(avroLong(rec1, 0), 1)
+	rec1 = avroBytes(rec1, []byte{0x30, 0x39})
+
+	var rec2 []byte
+	rec2 = avroLong(rec2, 2)
+	rec2 = avroLong(rec2, 0)
+	rec2 = avroLong(rec2, 0)
+	rec2 = avroLong(rec2, 0)
+	rec2 = avroLong(rec2, 0)
+	rec2 = avroLong(rec2, 0)
+	rec2 = avroBytes(rec2, []byte{0xff})
+	return [][]byte{rec1, rec2}
+}
+
+var testAvroWant = []Record{
+	{
+		"id": int64(1), "name": "alice", "created": time.UnixMilli(1700000000000).UTC(),
+		"address": map[string]interface{}{"city": "Berlin"}, "tags": []interface{}{"a", "b"},
+		"status": "INACTIVE", "price": 123.45,
+	},
+	{
+		"id": int64(2), "name": nil, "created": time.Unix(0, 0).UTC(),
+		"address": nil, "tags": []interface{}(nil), "status": "ACTIVE", "price": -0.01,
+	},
+}
+
+// writeTestAvro writes a container file holding each record in its own block.
+func writeTestAvro(t *testing.T, codec string, records [][]byte) string {
+	t.Helper()
+	sync := []byte("0123456789abcdef")
+	var buf []byte
+	buf = append(buf, avroMagic...)
+	buf = avroLong(buf, 2)
+	buf = avroBytes(avroBytes(buf, []byte("avro.schema")), []byte(testAvroSchema))
+	buf = avroBytes(avroBytes(buf, []byte("avro.codec")), []byte(codec))
+	buf = avroLong(buf, 0)
+	buf = append(buf, sync...)
+
+	for _, rec := range records {
+		data := rec
+		switch codec {
+		case "deflate":
+			var out bytes.Buffer
+			w, _ := flate.NewWriter(&out, flate.DefaultCompression)
+			w.Write(rec)
+			w.Close()
+			data = out.Bytes()
+		case "snappy":
+			data = binary.BigEndian.AppendUint32(snappyLiteral(rec), crc32.ChecksumIEEE(rec))
+		}
+		buf = avroLong(buf, 1)
+		buf = avroBytes(buf, data)
+		buf = append(buf, sync...)
+	}
+
+	path := filepath.Join(t.TempDir(), "test.avro")
+	if err := os.WriteFile(path, buf, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func readAllRecords(t *testing.T, reader DataReader) []Record {
+	t.Helper()
+	defer reader.Close()
+	var got []Record
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			return got
+		}
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		got = append(got, rec)
+	}
+}
+
+func TestAvroReader(t *testing.T) {
+	for _, codec := range []string{"null", "deflate", "snappy"} {
+		t.Run(codec, func(t *testing.T) {
+			path := writeTestAvro(t, codec, testAvroRecords())
+			reader, err := New(config.Source{Type: "auto", Path: path})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if got := readAllRecords(t, reader); !reflect.DeepEqual(got, testAvroWant) {
+				t.Errorf("Records got = %v, want %v", got, testAvroWant)
+			}
+		})
+	}
+}
+
+func TestAvroReader_SchemaRegistry(t *testing.T) {
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/schemas/ids/7" {
+			http.NotFound(w, r)
+			return
+		}
+		fetches++
+		json.NewEncoder(w).Encode(map[string]string{"schema": testAvroSchema})
+	}))
+	defer server.Close()
+
+	var messages []byte
+	for _, rec := range testAvroRecords() {
+		messages = append(append(messages, 0, 0, 0, 0, 7), rec...)
+	}
+
+	reader, err := NewFromReader(bytes.NewReader(messages), config.Source{Type: "avro", Path: "topic", SchemaRegistry: server.URL + "/"})
+	if err != nil {
+		t.Fatalf("NewFromReader() error = %v", err)
+	}
+	if got := readAllRecords(t, reader); !reflect.DeepEqual(got, testAvroWant) {
+		t.Errorf("Records got = %v, want %v", got, testAvroWant)
+	}
+	if fetches != 1 {
+		t.Errorf("Schema fetches got = %d, want 1", fetches)
+	}
+
+	unknown := append([]byte{0, 0, 0, 0, 8}, testAvroRecords()[0]...)
+	reader, err = NewFromReader(bytes.NewReader(unknown), config.Source{Type: "avro", Path: "topic", SchemaRegistry: server.URL})
+	if err != nil {
+		t.Fatalf("NewFromReader() error = %v", err)
+	}
+	defer reader.Close()
+	if _, err := reader.Read(); err == nil {
+		t.Errorf("Read() of a message with an unknown schema id should fail")
+	}
+}
+
+func TestAvroReader_Errors(t *testing.T) {
+	dir := t.TempDir()
+	notAvro := filepath.Join(dir, "not.avro")
+	os.WriteFile(notAvro, []byte("id,name\n1,a\n"), 0o644)
+	if _, err := New(config.Source{Type: "avro", Path: notAvro}); err == nil {
+		t.Errorf("New() of a non-avro file should fail")
+	}
+
+	records := testAvroRecords()
+	truncated := writeTestAvro(t, "null", [][]byte{records[0][:len(records[0])-2]})
+	reader, err := New(config.Source{Type: "avro", Path: truncated})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer reader.Close()
+	_, err = reader.Read()
+	if perr, ok := err.(*ParseError); !ok || perr.Record != 1 {
+		t.Errorf("Read() of a truncated record got = %v, want a ParseError for record 1", err)
+	}
+}
+
+func TestParseAvroSchema_NamedReferences(t *testing.T) {
+	s, err := parseAvroSchema([]byte(`{"type": "record", "name": "Node", "namespace": "ns", "fields": [
+		{"name": "value", "type": "int"},
+		{"name": "next", "type": ["null", "Node"]},
+		{"name": "other", "type": ["null", "ns.Node"]}]}`))
+	if err != nil {
+		t.Fatalf("parseAvroSchema() error = %v", err)
+	}
+	if s.fields[1].schema.branches[1] != s || s.fields[2].schema.branches[1] != s {
+		t.Errorf("Recursive references should resolve to the record itself")
+	}
+
+	if _, err := parseAvroSchema([]byte(`{"type": "record", "name": "R", "fields": [{"name": "a", "type": "Missing"}]}`)); err == nil {
+		t.Errorf("parseAvroSchema() of an unknown type should fail")
+	}
+}
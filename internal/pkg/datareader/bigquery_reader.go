@@ -0,0 +1,345 @@
+package datareader
+
+import (
+	"bytes"
+	"data-comparator/internal/pkg/config"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bigqueryEndpoint is the base URL of the BigQuery REST API.
+var bigqueryEndpoint = "https://bigquery.googleapis.com/bigquery/v2"
+
+// bigqueryWait is how long the API is asked to wait for a query to finish
+// before returning, in milliseconds.
+const bigqueryWait = 60000
+
+// BigQueryReader reads the rows of a BigQuery table or query result page by
+// page, FetchSize rows at a time, through the BigQuery REST API. Integers
+// are int64, floating point, NUMERIC and BIGNUMERIC values float64,
+// TIMESTAMP, DATETIME and DATE values time.Time in UTC, STRUCT values nested
+// records, ARRAY values lists, BYTES []byte, JSON values parsed and
+// everything else strings. Credentials are Google's application default
+// credentials.
+type BigQueryReader struct {
+	name    string
+	client  *http.Client
+	tokens  *googleTokens
+	pageURL func(pageToken string) string
+	fields  []bigqueryField
+	rows    []bigqueryRow
+	next    string
+	records int
+}
+
+type bigqueryField struct {
+	Name   string          `json:"name"`
+	Type   string          `json:"type"`
+	Mode   string          `json:"mode"`
+	Fields []bigqueryField `json:"fields"`
+}
+
+type bigqueryCell struct {
+	V json.RawMessage `json:"v"`
+}
+
+type bigqueryRow struct {
+	F []bigqueryCell `json:"f"`
+}
+
+type bigquerySchema struct {
+	Fields []bigqueryField `json:"fields"`
+}
+
+// bigqueryPage is a page of table data or query results.
+type bigqueryPage struct {
+	JobComplete  *bool `json:"jobComplete"`
+	JobReference struct {
+		ProjectID string `json:"projectId"`
+		JobID     string `json:"jobId"`
+		Location  string `json:"location"`
+	} `json:"jobReference"`
+	Schema    *bigquerySchema `json:"schema"`
+	Rows      []bigqueryRow   `json:"rows"`
+	PageToken string          `json:"pageToken"`
+}
+
+// NewBigQueryReader starts reading cfg.Table, as dataset.table or
+// project.dataset.table, or runs cfg.Query, billed to cfg.Project.
+func NewBigQueryReader(cfg config.Source) (DataReader, error) {
+	if cfg.Table != "" && cfg.Query != "" {
+		return nil, fmt.Errorf("bigquery source sets both table %s and a query", cfg.Table)
+	}
+	fetchSize := cfg.FetchSize
+	if fetchSize <= 0 {
+		fetchSize = DefaultFetchSize
+	}
+	client := &http.Client{Timeout: 2 * time.Minute}
+	r := &BigQueryReader{client: client, tokens: newGoogleTokens(client, bigqueryScope, "")}
+
+	var page *bigqueryPage
+	var err error
+	switch {
+	case cfg.Table != "":
+		page, err = r.startTable(cfg, fetchSize)
+	case cfg.Query != "":
+		page, err = r.startQuery(cfg, fetchSize)
+	default:
+		return nil, fmt.Errorf("bigquery source needs a table or a query")
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.rows, r.next = page.Rows, page.PageToken
+	return r, nil
+}
+
+// startTable reads the schema and first page of a table.
+func (r *BigQueryReader) startTable(cfg config.Source, fetchSize int) (*bigqueryPage, error) {
+	parts := strings.Split(strings.Trim(cfg.Table, "`"), ".")
+	if len(parts) == 2 && cfg.Project != "" {
+		parts = append([]string{cfg.Project}, parts...)
+	}
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("bigquery table %s is not project.dataset.table, and no project is set", cfg.Table)
+	}
+	r.name = "table " + strings.Join(parts, ".")
+	tableURL := fmt.Sprintf("%s/projects/%s/datasets/%s/tables/%s", bigqueryEndpoint,
+		url.PathEscape(parts[0]), url.PathEscape(parts[1]), url.PathEscape(parts[2]))
+
+	var table struct {
+		Schema bigquerySchema `json:"schema"`
+	}
+	if err := r.call(http.MethodGet, tableURL, nil, &table); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", r.name, err)
+	}
+	r.fields = table.Schema.Fields
+	r.pageURL = func(pageToken string) string {
+		q := url.Values{"maxResults": {strconv.Itoa(fetchSize)}, "formatOptions.useInt64Timestamp": {"true"}}
+		if pageToken != "" {
+			q.Set("pageToken", pageToken)
+		}
+		return tableURL + "/data?" + q.Encode()
+	}
+	page, err := r.fetchPage("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", r.name, err)
+	}
+	return page, nil
+}
+
+// startQuery runs the query and waits for its first page of results.
+func (r *BigQueryReader) startQuery(cfg config.Source, fetchSize int) (*bigqueryPage, error) {
+	if cfg.Project == "" {
+		return nil, fmt.Errorf("bigquery query needs a project to run in")
+	}
+	r.name = "query"
+	request := map[string]interface{}{
+		"query":         cfg.Query,
+		"useLegacySql":  false,
+		"maxResults":    fetchSize,
+		"timeoutMs":     bigqueryWait,
+		"formatOptions": map[string]bool{"useInt64Timestamp": true},
+	}
+	var page bigqueryPage
+	if err := r.call(http.MethodPost, fmt.Sprintf("%s/projects/%s/queries", bigqueryEndpoint, url.PathEscape(cfg.Project)), request, &page); err != nil {
+		return nil, fmt.Errorf("failed to run query: %w", err)
+	}
+	job := page.JobReference
+	r.pageURL = func(pageToken string) string {
+		q := url.Values{
+			"maxResults":                      {strconv.Itoa(fetchSize)},
+			"timeoutMs":                       {strconv.Itoa(bigqueryWait)},
+			"formatOptions.useInt64Timestamp": {"true"},
+		}
+		if job.Location != "" {
+			q.Set("location", job.Location)
+		}
+		if pageToken != "" {
+			q.Set("pageToken", pageToken)
+		}
+		return fmt.Sprintf("%s/projects/%s/queries/%s?%s", bigqueryEndpoint, url.PathEscape(job.ProjectID), url.PathEscape(job.JobID), q.Encode())
+	}
+	if page.JobComplete == nil || !*page.JobComplete {
+		next, err := r.fetchPage("")
+		if err != nil {
+			return nil, fmt.Errorf("failed to run query: %w", err)
+		}
+		page = *next
+	}
+	if page.Schema != nil {
+		r.fields = page.Schema.Fields
+	}
+	return &page, nil
+}
+
+// fetchPage fetches a page, waiting for a running query to complete.
+func (r *BigQueryReader) fetchPage(pageToken string) (*bigqueryPage, error) {
+	for {
+		var page bigqueryPage
+		if err := r.call(http.MethodGet, r.pageURL(pageToken), nil, &page); err != nil {
+			return nil, err
+		}
+		if page.JobComplete == nil || *page.JobComplete {
+			return &page, nil
+		}
+	}
+}
+
+// call sends an authorized API request and decodes its JSON response into out.
+func (r *BigQueryReader) call(method, target string, body, out interface{}) error {
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, target, payload)
+	if err != nil {
+		return err
+	}
+	token, err := r.tokens.get()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("bigquery returned %s: %s", resp.Status, apiErr.Error.Message)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Read returns the next row, fetching the next page when the current one is used up.
+func (r *BigQueryReader) Read() (Record, error) {
+	for len(r.rows) == 0 {
+		if r.next == "" {
+			return nil, io.EOF
+		}
+		page, err := r.fetchPage(r.next)
+		if err != nil {
+			return nil, &ParseError{Source: r.name, Record: r.records + 1, Offset: -1, Err: err}
+		}
+		r.rows, r.next = page.Rows, page.PageToken
+	}
+	row := r.rows[0]
+	r.rows = r.rows[1:]
+	r.records++
+	rec, err := convertBigQueryRow(r.fields, row)
+	if err != nil {
+		return nil, &ParseError{Source: r.name, Record: r.records, Offset: -1, Err: err}
+	}
+	return Record(rec), nil
+}
+
+// Close releases the reader. Rows are read over stateless requests, so
+// there is nothing to close on the server.
+func (r *BigQueryReader) Close() error {
+	r.rows, r.next = nil, ""
+	return nil
+}
+
+func convertBigQueryRow(fields []bigqueryField, row bigqueryRow) (map[string]interface{}, error) {
+	if len(row.F) != len(fields) {
+		return nil, fmt.Errorf("row has %d values for %d fields", len(row.F), len(fields))
+	}
+	rec := make(map[string]interface{}, len(fields))
+	for i, field := range fields {
+		v, err := convertBigQueryValue(field, row.F[i].V)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		rec[field.Name] = v
+	}
+	return rec, nil
+}
+
+// convertBigQueryValue turns a value of the REST API's row format into the
+// Record value of its field's type.
+func convertBigQueryValue(field bigqueryField, raw json.RawMessage) (interface{}, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	if field.Mode == "REPEATED" {
+		var cells []bigqueryCell
+		if err := json.Unmarshal(raw, &cells); err != nil {
+			return nil, err
+		}
+		element := field
+		element.Mode = ""
+		list := make([]interface{}, len(cells))
+		for i, cell := range cells {
+			v, err := convertBigQueryValue(element, cell.V)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = v
+		}
+		return list, nil
+	}
+	if field.Type == "RECORD" || field.Type == "STRUCT" {
+		var row bigqueryRow
+		if err := json.Unmarshal(raw, &row); err != nil {
+			return nil, err
+		}
+		return convertBigQueryRow(field.Fields, row)
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, err
+	}
+	switch field.Type {
+	case "INTEGER", "INT64":
+		return strconv.ParseInt(s, 10, 64)
+	case "FLOAT", "FLOAT64", "NUMERIC", "BIGNUMERIC", "DECIMAL", "BIGDECIMAL":
+		return strconv.ParseFloat(s, 64)
+	case "BOOLEAN", "BOOL":
+		return strconv.ParseBool(s)
+	case "TIMESTAMP":
+		// Microseconds since the epoch, or seconds without int64 timestamps.
+		if us, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return time.UnixMicro(us).UTC(), nil
+		}
+		sec, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, err
+		}
+		return time.UnixMicro(int64(sec * 1e6)).UTC(), nil
+	case "DATETIME":
+		return time.Parse("2006-01-02T15:04:05.999999", s)
+	case "DATE":
+		return time.Parse("2006-01-02", s)
+	case "BYTES":
+		return base64.StdEncoding.DecodeString(s)
+	case "JSON":
+		var value interface{}
+		if err := json.Unmarshal([]byte(s), &value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+	return s, nil
+}
@@ -0,0 +1,230 @@
+package datareader
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"data-comparator/internal/pkg/config"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testBigQuerySchema = `{"fields": [
+	{"name": "id", "type": "INTEGER", "mode": "REQUIRED"},
+	{"name": "price", "type": "NUMERIC"},
+	{"name": "created", "type": "TIMESTAMP"},
+	{"name": "address", "type": "RECORD", "fields": [{"name": "city", "type": "STRING"}]},
+	{"name": "tags", "type": "STRING", "mode": "REPEATED"},
+	{"name": "active", "type": "BOOLEAN"},
+	{"name": "blob", "type": "BYTES"}
+]}`
+
+func testBigQueryRow(id string) string {
+	return `{"f": [{"v": "` + id + `"}, {"v": "12.5"}, {"v": "1700000000000000"}, {"v": {"f": [{"v": "Berlin"}]}},
+		{"v": [{"v": "a"}, {"v": "b"}]}, {"v": "true"}, {"v": "AAE="}]}`
+}
+
+func testBigQueryWant(id int64) Record {
+	return Record{
+		"id": id, "price": 12.5, "created": time.UnixMicro(1700000000000000).UTC(),
+		"address": map[string]interface{}{"city": "Berlin"}, "tags": []interface{}{"a", "b"},
+		"active": true, "blob": []byte{0, 1},
+	}
+}
+
+// useFakeBigQuery points the reader at a fake API and a fixed access token.
+func useFakeBigQuery(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			http.Error(w, `{"error": {"message": "unauthorized"}}`, http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	previous := bigqueryEndpoint
+	bigqueryEndpoint = server.URL
+	t.Cleanup(func() { bigqueryEndpoint = previous })
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "test-token")
+}
+
+func TestBigQueryReader_Table(t *testing.T) {
+	useFakeBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/projects/proj/datasets/shop/tables/users":
+			w.Write([]byte(`{"schema": ` + testBigQuerySchema + `}`))
+		case "/projects/proj/datasets/shop/tables/users/data":
+			if r.URL.Query().Get("maxResults") != "1" {
+				t.Errorf("maxResults got = %s, want 1", r.URL.Query().Get("maxResults"))
+			}
+			if r.URL.Query().Get("pageToken") == "" {
+				w.Write([]byte(`{"rows": [` + testBigQueryRow("1") + `], "pageToken": "p2"}`))
+			} else {
+				w.Write([]byte(`{"rows": [` + testBigQueryRow("2") + `]}`))
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	reader, err := New(config.Source{Type: "bigquery", Project: "proj", Table: "shop.users", FetchSize: 1})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	got := readAllRecords(t, reader)
+	want := []Record{testBigQueryWant(1), testBigQueryWant(2)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Records got = %v, want %v", got, want)
+	}
+}
+
+func TestBigQueryReader_Query(t *testing.T) {
+	polls := 0
+	useFakeBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/projects/proj/queries":
+			var req map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&req)
+			if req["query"] != "SELECT * FROM shop.users" || req["useLegacySql"] != false {
+				t.Errorf("Query request got = %v", req)
+			}
+			w.Write([]byte(`{"jobComplete": false, "jobReference": {"projectId": "proj", "jobId": "job1", "location": "EU"}}`))
+		case r.URL.Path == "/projects/proj/queries/job1":
+			if r.URL.Query().Get("location") != "EU" {
+				t.Errorf("location got = %s, want EU", r.URL.Query().Get("location"))
+			}
+			polls++
+			if polls == 1 {
+				w.Write([]byte(`{"jobComplete": false}`))
+				return
+			}
+			w.Write([]byte(`{"jobComplete": true, "schema": ` + testBigQuerySchema + `, "rows": [` + testBigQueryRow("1") + `]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	reader, err := New(config.Source{Type: "bigquery", Project: "proj", Query: "SELECT * FROM shop.users"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	got := readAllRecords(t, reader)
+	if want := []Record{testBigQueryWant(1)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Records got = %v, want %v", got, want)
+	}
+}
+
+func TestBigQueryReader_Errors(t *testing.T) {
+	useFakeBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": {"message": "Not found: Table proj:shop.missing"}}`))
+	})
+	for _, cfg := range []config.Source{
+		{Type: "bigquery", Table: "shop.users"},
+		{Type: "bigquery", Query: "SELECT 1"},
+		{Type: "bigquery", Project: "proj"},
+		{Type: "bigquery", Project: "proj", Table: "shop.users", Query: "SELECT 1"},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("New(%+v) should fail", cfg)
+		}
+	}
+	_, err := New(config.Source{Type: "bigquery", Table: "proj.shop.missing"})
+	if err == nil || !strings.Contains(err.Error(), "Not found: Table proj:shop.missing") {
+		t.Errorf("New() of a missing table got = %v, want the API's message", err)
+	}
+}
+
+func TestConvertBigQueryValue(t *testing.T) {
+	tests := []struct {
+		field bigqueryField
+		raw   string
+		want  interface{}
+	}{
+		{bigqueryField{Type: "FLOAT64"}, `"1.5"`, 1.5},
+		{bigqueryField{Type: "TIMESTAMP"}, `"1.7E9"`, time.Unix(1700000000, 0).UTC()},
+		{bigqueryField{Type: "DATETIME"}, `"2024-03-01T12:30:00.5"`, time.Date(2024, 3, 1, 12, 30, 0, 500000000, time.UTC)},
+		{bigqueryField{Type: "DATE"}, `"2024-03-01"`, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{bigqueryField{Type: "JSON"}, `"{\"a\": [1]}"`, map[string]interface{}{"a": []interface{}{float64(1)}}},
+		{bigqueryField{Type: "STRING"}, `null`, nil},
+		{bigqueryField{Type: "INT64", Mode: "REPEATED"}, `[{"v": "1"}, {"v": "2"}]`, []interface{}{int64(1), int64(2)}},
+		{bigqueryField{Type: "GEOGRAPHY"}, `"POINT(1 2)"`, "POINT(1 2)"},
+	}
+	for _, tt := range tests {
+		got, err := convertBigQueryValue(tt.field, json.RawMessage(tt.raw))
+		if err != nil {
+			t.Errorf("convertBigQueryValue(%s) error = %v", tt.raw, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("convertBigQueryValue(%s) got = %#v, want %#v", tt.raw, got, tt.want)
+		}
+	}
+	if _, err := convertBigQueryValue(bigqueryField{Type: "INTEGER"}, json.RawMessage(`"x"`)); err == nil {
+		t.Errorf("convertBigQueryValue() of a malformed integer should fail")
+	}
+}
+
+func TestGoogleTokens_ServiceAccount(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		r.ParseForm()
+		parts := strings.Split(r.PostForm.Get("assertion"), ".")
+		if len(parts) != 3 {
+			t.Fatalf("assertion got = %q, want a JWT", r.PostForm.Get("assertion"))
+		}
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			t.Errorf("JWT signature does not verify: %v", err)
+		}
+		claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		if !strings.Contains(string(claims), `"iss":"reader@proj.iam.gserviceaccount.com"`) {
+			t.Errorf("JWT claims got = %s", claims)
+		}
+		w.Write([]byte(`{"access_token": "sa-token", "expires_in": 3600}`))
+	}))
+	defer server.Close()
+
+	creds, _ := json.Marshal(googleCredentials{
+		Type:        "service_account",
+		ClientEmail: "reader@proj.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TokenURI:    server.URL,
+	})
+	path := filepath.Join(t.TempDir(), "key.json")
+	if err := os.WriteFile(path, creds, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "")
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", path)
+
+	tokens := newGoogleTokens(http.DefaultClient, bigqueryScope, "")
+	for i := 0; i < 2; i++ {
+		token, err := tokens.get()
+		if err != nil || token != "sa-token" {
+			t.Fatalf("get() got = %q, %v, want sa-token", token, err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Token requests got = %d, want 1 as the token is cached", requests)
+	}
+}
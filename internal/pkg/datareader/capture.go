@@ -0,0 +1,172 @@
+package datareader
+
+import (
+	"compress/gzip"
+	"data-comparator/internal/pkg/config"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// CaptureFormat identifies capture files in their header.
+const CaptureFormat = "stream-diff-capture"
+
+// CaptureVersion is the version of the capture format written by CaptureWriter.
+const CaptureVersion = 1
+
+// A capture is a gzip-compressed JSON-Lines file: a CaptureHeader, one line
+// per record, and a CaptureTrailer, so records seen on a live source can be
+// replayed offline with source type "capture".
+
+// CaptureHeader is the first line of a capture.
+type CaptureHeader struct {
+	Format     string        `json:"format"`
+	Version    int           `json:"version"`
+	Source     config.Source `json:"source"`
+	CapturedAt time.Time     `json:"captured_at"`
+}
+
+// CaptureTrailer is the last line of a capture.
+type CaptureTrailer struct {
+	Records int `json:"records"`
+	// Schema is the YAML schema inferred from the captured records.
+	Schema string `json:"schema"`
+}
+
+// captureLine is any line after the header. Record lines carry the offset in
+// nanoseconds from the start of the capture at which the record was read.
+type captureLine struct {
+	Offset  *int64          `json:"t,omitempty"`
+	Record  Record          `json:"r,omitempty"`
+	Trailer *CaptureTrailer `json:"trailer,omitempty"`
+}
+
+// CaptureWriter writes a capture.
+type CaptureWriter struct {
+	gz      *gzip.Writer
+	encoder *json.Encoder
+	start   time.Time
+	records int
+}
+
+// NewCaptureWriter writes the header of a capture of src started at start to w.
+func NewCaptureWriter(w io.Writer, src config.Source, start time.Time) (*CaptureWriter, error) {
+	gz := gzip.NewWriter(w)
+	cw := &CaptureWriter{gz: gz, encoder: json.NewEncoder(gz), start: start}
+	header := CaptureHeader{Format: CaptureFormat, Version: CaptureVersion, Source: src, CapturedAt: start}
+	if err := cw.encoder.Encode(header); err != nil {
+		return nil, fmt.Errorf("failed to write capture header: %w", err)
+	}
+	return cw, nil
+}
+
+// Write appends a record read at the given time.
+func (cw *CaptureWriter) Write(rec Record, at time.Time) error {
+	offset := int64(at.Sub(cw.start))
+	if err := cw.encoder.Encode(captureLine{Offset: &offset, Record: rec}); err != nil {
+		return fmt.Errorf("failed to write captured record: %w", err)
+	}
+	cw.records++
+	return nil
+}
+
+// Close writes the trailer with the given YAML schema and flushes the capture.
+// It does not close the underlying writer.
+func (cw *CaptureWriter) Close(schemaYAML string) error {
+	trailer := &CaptureTrailer{Records: cw.records, Schema: schemaYAML}
+	if err := cw.encoder.Encode(captureLine{Trailer: trailer}); err != nil {
+		return fmt.Errorf("failed to write capture trailer: %w", err)
+	}
+	if err := cw.gz.Close(); err != nil {
+		return fmt.Errorf("failed to write capture: %w", err)
+	}
+	return nil
+}
+
+// CaptureReader replays the records of a capture file.
+type CaptureReader struct {
+	path    string
+	file    io.Closer
+	decoder *json.Decoder
+	header  CaptureHeader
+	trailer *CaptureTrailer
+	offset  time.Duration
+	records int
+}
+
+// NewCaptureReader opens the capture file at cfg.Path and reads its header.
+func NewCaptureReader(cfg config.Source) (*CaptureReader, error) {
+	file, err := openSource(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open capture file %s: %w", cfg.Path, err)
+	}
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read capture file %s: %w", cfg.Path, err)
+	}
+
+	r := &CaptureReader{path: cfg.Path, file: file, decoder: json.NewDecoder(gz)}
+	if err := r.decoder.Decode(&r.header); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read capture header of %s: %w", cfg.Path, err)
+	}
+	if r.header.Format != CaptureFormat {
+		file.Close()
+		return nil, fmt.Errorf("%s is not a capture file", cfg.Path)
+	}
+	if r.header.Version > CaptureVersion {
+		file.Close()
+		return nil, fmt.Errorf("capture file %s has unsupported version %d", cfg.Path, r.header.Version)
+	}
+	return r, nil
+}
+
+// Header returns the header of the capture, describing its original source.
+func (r *CaptureReader) Header() CaptureHeader {
+	return r.header
+}
+
+// Trailer returns the trailer of the capture once all records have been read,
+// nil before.
+func (r *CaptureReader) Trailer() *CaptureTrailer {
+	return r.trailer
+}
+
+// Offset returns how long after the start of the capture the last record
+// returned by Read was originally read.
+func (r *CaptureReader) Offset() time.Duration {
+	return r.offset
+}
+
+// Read returns the next captured record, or io.EOF after the last one.
+func (r *CaptureReader) Read() (Record, error) {
+	if r.trailer != nil {
+		return nil, io.EOF
+	}
+	var line captureLine
+	if err := r.decoder.Decode(&line); err != nil {
+		if err == io.EOF {
+			err = fmt.Errorf("capture ends without a trailer: %w", io.ErrUnexpectedEOF)
+		}
+		return nil, &ParseError{Source: r.path, Record: r.records + 1, Offset: -1, Err: err}
+	}
+	if line.Trailer != nil {
+		r.trailer = line.Trailer
+		return nil, io.EOF
+	}
+	r.records++
+	if line.Offset != nil {
+		r.offset = time.Duration(*line.Offset)
+	}
+	if line.Record == nil {
+		line.Record = Record{}
+	}
+	return line.Record, nil
+}
+
+// Close closes the capture file.
+func (r *CaptureReader) Close() error {
+	return r.file.Close()
+}
@@ -0,0 +1,66 @@
+package datareader
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"data-comparator/internal/pkg/config"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compressions lists the values accepted for Source.Compression.
+var Compressions = []string{"auto", "none", "gzip", "zstd", "bzip2"}
+
+// decompress wraps input, read from file, in a decompressing reader for
+// cfg.Compression. Without a configured compression, or with "auto", it is
+// detected from the first bytes. It returns the reader, a closer of both
+// the decoder and file, and whether input was compressed at all.
+func decompress(input io.Reader, file io.Closer, cfg config.Source) (io.Reader, io.Closer, bool, error) {
+	compression := cfg.Compression
+	if compression == "" || compression == "auto" {
+		buffered := bufio.NewReader(input)
+		head, _ := buffered.Peek(4)
+		input, compression = buffered, detectCompression(head)
+	}
+
+	var decoder io.Reader
+	var decoderCloser func()
+	var err error
+	switch compression {
+	case "", "none":
+		return input, file, false, nil
+	case "gzip":
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(input); err == nil {
+			decoder, decoderCloser = gz, func() { gz.Close() }
+		}
+	case "zstd":
+		var zr *zstd.Decoder
+		if zr, err = zstd.NewReader(input); err == nil {
+			decoder, decoderCloser = zr, zr.Close
+		}
+	case "bzip2":
+		decoder, decoderCloser = bzip2.NewReader(input), func() {}
+	default:
+		err = fmt.Errorf("unsupported compression %q", compression)
+	}
+	if err != nil {
+		file.Close()
+		return nil, nil, false, fmt.Errorf("failed to decompress %s: %w", cfg.Path, err)
+	}
+	return decoder, decompressCloser{file: file, decoder: decoderCloser}, true, nil
+}
+
+// decompressCloser releases a decoder along with the file it reads.
+type decompressCloser struct {
+	file    io.Closer
+	decoder func()
+}
+
+func (c decompressCloser) Close() error {
+	c.decoder()
+	return c.file.Close()
+}
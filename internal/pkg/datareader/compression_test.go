@@ -0,0 +1,86 @@
+package datareader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"data-comparator/internal/pkg/config"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// testBzip2CSV is "id,name\n1,alice\n2,bob\n" compressed with bzip2, which
+// the standard library cannot write.
+const testBzip2CSV = "425a6839314159265359437d3b99000008d9000010000430003e27a00021a9a335343ca100002c19aba053cf834f6c15f177245385090437d3b990"
+
+func compressTestData(t *testing.T, compression string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	switch compression {
+	case "gzip":
+		w := gzip.NewWriter(&buf)
+		w.Write(data)
+		w.Close()
+	case "zstd":
+		w, err := zstd.NewWriter(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+		w.Close()
+	}
+	return buf.Bytes()
+}
+
+func TestNew_Compressed(t *testing.T) {
+	csv := "id,name\n1,alice\n2,bob\n"
+	bz2, _ := hex.DecodeString(testBzip2CSV)
+	files := map[string][]byte{
+		"data.csv.gz":  compressTestData(t, "gzip", []byte(csv)),
+		"data.csv.zst": compressTestData(t, "zstd", []byte(csv)),
+		"data.csv.bz2": bz2,
+	}
+	want := []Record{{"id": "1", "name": "alice"}, {"id": "2", "name": "bob"}}
+
+	dir := t.TempDir()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		for _, typ := range []string{"csv", "auto"} {
+			reader, err := New(config.Source{Type: typ, Path: path})
+			if err != nil {
+				t.Fatalf("New(%s, %s) error = %v", typ, name, err)
+			}
+			if got := readAllRecords(t, reader); !reflect.DeepEqual(got, want) {
+				t.Errorf("New(%s, %s) records got = %v, want %v", typ, name, got, want)
+			}
+		}
+	}
+
+	sniffed, err := Sniff(filepath.Join(dir, "data.csv.zst"))
+	if err != nil || sniffed.Type != "csv" || sniffed.Compression != "zstd" {
+		t.Errorf("Sniff() got = %+v, %v, want zstd compressed csv", sniffed, err)
+	}
+	_, err = New(config.Source{Type: "csv", Path: filepath.Join(dir, "data.csv.gz"), Compression: "lz4"})
+	if err == nil || !strings.Contains(err.Error(), `unsupported compression "lz4"`) {
+		t.Errorf("New() with an unsupported compression error got = %v", err)
+	}
+}
+
+func TestNewFromReader_Compressed(t *testing.T) {
+	jsonl := []byte(`{"id": 1}` + "\n" + `{"id": 2}` + "\n")
+	reader, err := NewFromReader(bytes.NewReader(compressTestData(t, "zstd", jsonl)), config.Source{Type: "auto", Path: "events.jsonl.zst"})
+	if err != nil {
+		t.Fatalf("NewFromReader() error = %v", err)
+	}
+	if got, want := readAllRecords(t, reader), []Record{{"id": 1.0}, {"id": 2.0}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Records got = %v, want %v", got, want)
+	}
+}
@@ -1,92 +1,255 @@
 package datareader
 
 import (
+	"bufio"
+	"bytes"
 	"data-comparator/internal/pkg/config"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"os"
+	"strings"
 )
 
-// CSVReader reads records from a CSV file.
+// CSVReader reads records from a CSV file or stream.
 type CSVReader struct {
-	file         *os.File
+	path         string
+	records      int
+	file         io.Closer
+	isFile       bool
 	reader       *csv.Reader
 	header       []string
 	parserConfig config.ParserConfig
+	// skippedBytes and skippedLines are the byte order mark and preamble
+	// lines read before the CSV, which parse errors are located after.
+	skippedBytes int64
+	skippedLines int
+	// line is the line the last record read ended on.
+	line int
+	// seeker is the file, if local and uncompressed, which Resume seeks.
+	seeker io.ReadSeeker
+	// counts of values left as strings by the JSON-in-string cutoffs
+	tooDeep  int
+	tooLarge int
 }
 
-// NewCSVReader creates a new reader for CSV files.
+// DefaultMaxJSONDepth is the nesting depth up to which JSON in CSV fields is parsed.
+const DefaultMaxJSONDepth = 64
+
+// DefaultMaxJSONSize is the length in bytes up to which CSV fields are parsed as JSON.
+const DefaultMaxJSONSize = 1 << 20
+
+// NewCSVReader creates a new reader for CSV files, which may be gzip, zstd or
+// bzip2 compressed.
 func NewCSVReader(cfg config.Source) (DataReader, error) {
-	file, err := os.Open(cfg.Path)
+	file, err := openSource(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open csv file %s: %w", cfg.Path, err)
 	}
-
-	reader := csv.NewReader(file)
-	header, err := reader.Read()
+	input, closer, compressed, err := decompress(file, file, cfg)
 	if err != nil {
-		if err == io.EOF {
-			return nil, fmt.Errorf("csv file %s is empty", cfg.Path)
-		}
-		return nil, fmt.Errorf("failed to read header from csv file %s: %w", cfg.Path, err)
+		return nil, err
+	}
+	r, err := newCSVReader(input, closer, cfg)
+	if err != nil {
+		return nil, err
+	}
+	// Offsets into decompressed data cannot be located in the file.
+	r.isFile = !IsObjectPath(cfg.Path) && !IsRemotePath(cfg.Path) && !compressed
+	if f, ok := file.(localFile); ok && !compressed {
+		r.seeker = f
 	}
+	return r, nil
+}
 
+// newCSVReader reads CSV from input, named by cfg.Path, and closes closer
+// when done or on error.
+func newCSVReader(input io.Reader, closer io.Closer, cfg config.Source) (*CSVReader, error) {
+	file := closer
 	var pcfg config.ParserConfig
 	if cfg.ParserConfig != nil {
 		pcfg = *cfg.ParserConfig
 	}
 
-	return &CSVReader{
+	buffered := bufio.NewReader(input)
+	skippedBytes, skippedLines, err := skipPreamble(buffered, pcfg.SkipLines)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read csv file %s: %w", cfg.Path, err)
+	}
+	reader := csv.NewReader(buffered)
+	if pcfg.MaxJSONDepth <= 0 {
+		pcfg.MaxJSONDepth = DefaultMaxJSONDepth
+	}
+	if pcfg.MaxJSONSize <= 0 {
+		pcfg.MaxJSONSize = DefaultMaxJSONSize
+	}
+	if pcfg.Delimiter != "" {
+		delimiter := []rune(pcfg.Delimiter)
+		if len(delimiter) != 1 {
+			file.Close()
+			return nil, fmt.Errorf("csv delimiter must be a single character, got %q", pcfg.Delimiter)
+		}
+		reader.Comma = delimiter[0]
+	}
+
+	r := &CSVReader{
+		path:         cfg.Path,
 		file:         file,
 		reader:       reader,
-		header:       header,
 		parserConfig: pcfg,
-	}, nil
+		skippedBytes: skippedBytes,
+		skippedLines: skippedLines,
+		line:         skippedLines,
+	}
+
+	if pcfg.NoHeader {
+		// Column names are assigned when the first record is read
+		return r, nil
+	}
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		// An empty file has no header and no records.
+		return r, nil
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read header from csv file %s: %w", cfg.Path, err)
+	}
+	// Exports often pad header cells, which would make field names that no
+	// config refers to.
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+	r.header = header
+	r.line = r.endLine(header)
+
+	return r, nil
+}
+
+// skipPreamble skips a UTF-8 byte order mark, as written by Excel, and then
+// lines lines, such as the title and notes BI tools put above the header. It
+// returns the number of bytes and lines skipped.
+func skipPreamble(r *bufio.Reader, lines int) (int64, int, error) {
+	var skipped int64
+	if bom, err := r.Peek(len(utf8BOM)); err == nil && bytes.Equal(bom, utf8BOM) {
+		r.Discard(len(utf8BOM))
+		skipped += int64(len(utf8BOM))
+	}
+	for i := 0; i < lines; i++ {
+		line, err := r.ReadSlice('\n')
+		for err == bufio.ErrBufferFull {
+			skipped += int64(len(line))
+			line, err = r.ReadSlice('\n')
+		}
+		skipped += int64(len(line))
+		if err == io.EOF {
+			return skipped, i, nil
+		}
+		if err != nil {
+			return skipped, i, err
+		}
+	}
+	return skipped, lines, nil
 }
 
 // Read reads the next record from the CSV file.
 func (r *CSVReader) Read() (Record, error) {
 	row, err := r.reader.Read()
+	if err == io.EOF {
+		return nil, err
+	}
+	r.records++
 	if err != nil {
-		return nil, err // This will correctly return io.EOF at the end of the file
+		perr := r.parseError(err)
+		r.line = max(r.line, perr.Line)
+		// Rows with the wrong number of fields are read whole.
+		if row != nil && r.header != nil {
+			perr.Fields = r.record(row)
+		}
+		return nil, perr
 	}
+	r.line = r.endLine(row)
+	if r.header == nil {
+		r.header = make([]string, len(row))
+		for i := range row {
+			r.header[i] = fmt.Sprintf("column_%d", i+1)
+		}
+	}
+	return r.record(row), nil
+}
 
+// record returns the fields of row named by the header.
+func (r *CSVReader) record(row []string) Record {
 	record := make(Record)
 	for i, value := range row {
 		if i < len(r.header) {
 			var processedValue interface{} = value
 			if r.parserConfig.JSONInString {
-				processedValue = r.tryParseJSON(value)
+				processedValue = r.tryParseJSON(value, 0)
 			}
 			record[r.header[i]] = processedValue
 		}
 	}
-	return record, nil
+	return record
+}
+
+// endLine returns the line row, just read, ends on: that of its last field,
+// plus the line breaks quoted within it.
+func (r *CSVReader) endLine(row []string) int {
+	last := len(row) - 1
+	line, _ := r.reader.FieldPos(last)
+	return r.skippedLines + line + strings.Count(row[last], "\n")
+}
+
+func (r *CSVReader) parseError(err error) *ParseError {
+	perr := &ParseError{Source: r.path, Record: r.records, Offset: r.skippedBytes + r.reader.InputOffset(), Err: err}
+	var csvErr *csv.ParseError
+	if errors.As(err, &csvErr) {
+		perr.Line = r.skippedLines + csvErr.StartLine
+		perr.Recoverable = true
+		if r.isFile {
+			perr.Snippet = lineSnippet(r.path, perr.Line)
+		}
+	}
+	return perr
 }
 
-// tryParseJSON attempts to recursively unmarshal a string as JSON.
-// If it fails, it returns the original string.
-func (r *CSVReader) tryParseJSON(s string) interface{} {
+// tryParseJSON attempts to recursively unmarshal a string as JSON, found at
+// the given nesting depth. If it fails, or the JSON is nested deeper than
+// MaxJSONDepth or longer than MaxJSONSize, it returns the original string.
+func (r *CSVReader) tryParseJSON(s string, depth int) interface{} {
 	if s == "" {
 		return s
 	}
+	nesting := 0
+	if mayNest(s) {
+		if len(s) > r.parserConfig.MaxJSONSize {
+			r.tooLarge++
+			return s
+		}
+		var ok bool
+		if nesting, ok = jsonNesting(s, r.parserConfig.MaxJSONDepth-depth); !ok {
+			r.tooDeep++
+			return s
+		}
+	}
 
-	var result interface{}
-	err := json.Unmarshal([]byte(s), &result)
+	result, err := r.decodeJSON(s)
 	if err != nil {
 		return s
 	}
 
 	if strVal, ok := result.(string); ok {
-		return r.tryParseJSON(strVal)
+		return r.tryParseJSON(strVal, depth+1)
 	}
 
 	if mapVal, ok := result.(map[string]interface{}); ok {
 		for k, v := range mapVal {
 			if strV, ok := v.(string); ok {
-				mapVal[k] = r.tryParseJSON(strV)
+				mapVal[k] = r.tryParseJSON(strV, depth+nesting)
 			}
 		}
 		return mapVal
@@ -95,7 +258,105 @@ func (r *CSVReader) tryParseJSON(s string) interface{} {
 	return result
 }
 
-// Close closes the underlying file.
+// decodeJSON decodes s, a single JSON value, with numbers decoded as
+// configured.
+func (r *CSVReader) decodeJSON(s string) (interface{}, error) {
+	var result interface{}
+	if !exactJSONNumbers(&r.parserConfig) {
+		err := json.Unmarshal([]byte(s), &result)
+		return result, err
+	}
+	decoder := json.NewDecoder(strings.NewReader(s))
+	decoder.UseNumber()
+	if err := decoder.Decode(&result); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the JSON value")
+	}
+	return result, nil
+}
+
+// mayNest reports whether s could hold a JSON object, array or string, the
+// values that may nest; bare numbers, booleans and null cannot.
+func mayNest(s string) bool {
+	trimmed := strings.TrimLeft(s, " \t\r\n")
+	return trimmed != "" && strings.ContainsRune("{[\"", rune(trimmed[0]))
+}
+
+// jsonNesting returns the depth of the objects and arrays nested in s,
+// scanning only up to maxDepth levels. It reports false beyond maxDepth.
+func jsonNesting(s string, maxDepth int) (int, bool) {
+	depth, deepest := 0, 0
+	inString, escaped := false, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '{' || c == '[':
+			depth++
+			if depth > deepest {
+				deepest = depth
+			}
+			if deepest > maxDepth {
+				return deepest, false
+			}
+		case c == '}' || c == ']':
+			depth--
+		}
+	}
+	return deepest, true
+}
+
+// Warnings describes the values left as strings by the JSON-in-string cutoffs.
+func (r *CSVReader) Warnings() []string {
+	var warnings []string
+	if r.tooDeep > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d values holding JSON nested deeper than %d levels were left as strings", r.tooDeep, r.parserConfig.MaxJSONDepth))
+	}
+	if r.tooLarge > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d values longer than %d bytes were not parsed as JSON", r.tooLarge, r.parserConfig.MaxJSONSize))
+	}
+	return warnings
+}
+
+// Position returns the number of records read and where the last ended.
+func (r *CSVReader) Position() Position {
+	return Position{Records: r.records, Offset: r.skippedBytes + r.reader.InputOffset(), Line: r.line}
+}
+
+// Resume continues after the records up to pos, seeking a local file to
+// its offset.
+func (r *CSVReader) Resume(pos Position) error {
+	if r.records > 0 {
+		return fmt.Errorf("cannot resume %s after reading from it", r.path)
+	}
+	if r.seeker == nil {
+		return skipRecords(r, pos.Records)
+	}
+	if _, err := r.seeker.Seek(pos.Offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to resume %s at offset %d: %w", r.path, pos.Offset, err)
+	}
+	reader := csv.NewReader(bufio.NewReader(r.seeker))
+	reader.Comma = r.reader.Comma
+	// The field count is set by the first record, which was before pos.
+	reader.FieldsPerRecord = r.reader.FieldsPerRecord
+	r.reader = reader
+	r.records, r.skippedBytes, r.skippedLines, r.line = pos.Records, pos.Offset, pos.Line, pos.Line
+	return nil
+}
+
+// Close closes the underlying file or stream.
 func (r *CSVReader) Close() error {
 	return r.file.Close()
 }
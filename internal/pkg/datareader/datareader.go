@@ -1,10 +1,22 @@
 package datareader
 
 import (
+	"bufio"
+	"bytes"
 	"data-comparator/internal/pkg/config"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 )
 
+// StdinPath is the source path that reads standard input, e.g. for
+// pg_dump ... | data-comparator. Standard input can only be read once.
+const StdinPath = "-"
+
+// stdin is the stream read by sources whose path is StdinPath.
+var stdin io.Reader = os.Stdin
+
 // Record represents a single record from a data source, like a CSV row or a JSON object.
 type Record map[string]interface{}
 
@@ -17,14 +29,239 @@ type DataReader interface {
 	Close() error
 }
 
+// Warner is implemented by readers that go on past defensive cutoffs, such
+// as JSON in CSV fields nested too deeply to parse, and describe them as
+// warnings once reading is done.
+type Warner interface {
+	Warnings() []string
+}
+
+// Position is how far a reader has read: the number of records read, and
+// the byte offset and line number at the end of the last of them.
+type Position struct {
+	Records int   `yaml:"records"`
+	Offset  int64 `yaml:"offset"`
+	Line    int   `yaml:"line,omitempty"`
+}
+
+// Resumer is implemented by readers that can report their Position and
+// continue from one reported by an earlier reader of the same source, so a
+// resumed run skips the records it already processed. Local, uncompressed
+// files are seeked to the offset; other sources read and discard the
+// records before it. Resume must be called before the first Read.
+type Resumer interface {
+	Position() Position
+	Resume(pos Position) error
+}
+
+// skipRecords reads and discards n records of reader, including those that
+// fail to parse recoverably, for Resume on sources that cannot be seeked.
+func skipRecords(reader DataReader, n int) error {
+	for i := 0; i < n; i++ {
+		_, err := reader.Read()
+		if err == io.EOF {
+			return fmt.Errorf("cannot resume after record %d, the source has only %d records", n, i)
+		}
+		var perr *ParseError
+		if err != nil && !(errors.As(err, &perr) && perr.Recoverable) {
+			return err
+		}
+	}
+	return nil
+}
+
+// SupportedTypes lists the source types accepted by New.
+var SupportedTypes = []string{"csv", "json", "parquet", "avro", "protobuf", "xml", "fixed_width", "xlsx", "logfile", "syslog", "journald", "postgres", "mysql", "bigquery", "capture", "http", "auto"}
+
 // New creates a new DataReader based on the provided source configuration.
+// With type "auto", the format and parser config are sniffed from the file.
+// A path of StdinPath reads standard input through NewFromReader, and a glob
+// pattern or directory all its files through a MultiReader, and an
+// archive.zip!pattern path the matching archive members through an
+// ArchiveReader. Type "http"
+// gets the URL at the path and reads the response as it arrives.
+// Configured field name normalization and exec and field transforms are
+// applied to the records read.
 func New(cfg config.Source) (DataReader, error) {
+	if cfg.Path == StdinPath && !IsDatabaseType(cfg.Type) {
+		// Hide Close, so standard input stays open for the process.
+		return NewFromReader(struct{ io.Reader }{stdin}, cfg)
+	}
+	if cfg.Type == "http" {
+		// The response body goes through NewFromReader, which applies the transform.
+		return NewHTTPReader(cfg)
+	}
+	if !IsDatabaseType(cfg.Type) && IsArchivePath(cfg.Path) {
+		reader, err := NewArchiveReader(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return withTransform(reader, cfg)
+	}
+	if !IsDatabaseType(cfg.Type) && isMultiPath(cfg.Path) {
+		reader, err := NewMultiReader(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return withTransform(reader, cfg)
+	}
+	if cfg.Type == "auto" {
+		var err error
+		if cfg, err = autoSource(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	var reader DataReader
+	var err error
+	switch cfg.Type {
+	case "csv":
+		reader, err = NewCSVReader(cfg)
+	case "json":
+		reader, err = NewJSONReader(cfg)
+	case "parquet":
+		reader, err = NewParquetReader(cfg)
+	case "avro":
+		reader, err = NewAvroReader(cfg)
+	case "protobuf":
+		reader, err = NewProtobufReader(cfg)
+	case "xml":
+		reader, err = NewXMLReader(cfg)
+	case "fixed_width":
+		reader, err = NewFixedWidthReader(cfg)
+	case "xlsx":
+		reader, err = NewXLSXReader(cfg)
+	case "logfile":
+		reader, err = NewLogFileReader(cfg)
+	case "syslog":
+		reader, err = NewSyslogReader(cfg)
+	case "journald":
+		reader, err = NewJournaldReader(cfg)
+	case "postgres":
+		reader, err = NewPostgresReader(cfg)
+	case "mysql":
+		reader, err = NewMySQLReader(cfg)
+	case "bigquery":
+		reader, err = NewBigQueryReader(cfg)
+	case "capture":
+		reader, err = NewCaptureReader(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported source type: %s", cfg.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return withTransform(reader, cfg)
+}
+
+// NewFromReader creates a DataReader over an already open stream, such as an
+// in-memory buffer or a network response. The type and parser config come from
+// cfg, whose Path only names the source in errors; with type "auto" the format
+// is sniffed from the first bytes. Compressed streams are decompressed. The
+// stream is closed with the DataReader if it is an io.Closer.
+func NewFromReader(input io.Reader, cfg config.Source) (DataReader, error) {
+	closer, ok := input.(io.Closer)
+	if !ok {
+		closer = io.NopCloser(nil)
+	}
+
+	input, closer, _, err := decompress(input, closer, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Type == "auto" {
+		buffered := bufio.NewReaderSize(input, sniffSize)
+		head, err := buffered.Peek(sniffSize)
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			closer.Close()
+			return nil, fmt.Errorf("failed to read %s: %w", cfg.Path, err)
+		}
+		if cfg, err = applySniffed(cfg, sniffBytes(head)); err != nil {
+			closer.Close()
+			return nil, err
+		}
+		input = buffered
+	}
+
+	var reader DataReader
 	switch cfg.Type {
 	case "csv":
-		return NewCSVReader(cfg)
+		reader, err = newCSVReader(input, closer, cfg)
 	case "json":
-		return NewJSONReader(cfg)
+		reader, err = newJSONReader(input, closer, cfg)
+	case "parquet":
+		// Parquet is read from its footer, so the stream is buffered whole.
+		data, readErr := io.ReadAll(input)
+		if readErr != nil {
+			closer.Close()
+			return nil, fmt.Errorf("failed to read %s: %w", cfg.Path, readErr)
+		}
+		reader, err = newParquetReader(bytes.NewReader(data), int64(len(data)), closer, cfg.Path)
+	case "avro":
+		reader, err = newAvroReader(input, closer, cfg)
+	case "protobuf":
+		message, typeErr := loadProtobufType(cfg)
+		if typeErr != nil {
+			closer.Close()
+			return nil, typeErr
+		}
+		reader, err = newProtobufReader(input, closer, message, cfg)
+	case "xml":
+		reader, err = newXMLReader(input, closer, cfg)
+	case "fixed_width":
+		reader, err = newFixedWidthReader(input, closer, cfg)
+	case "logfile":
+		reader, err = newLogFileReader(input, closer, cfg)
+	case "syslog":
+		if err = ValidateSyslog(cfg.Syslog); err != nil {
+			closer.Close()
+			return nil, fmt.Errorf("syslog source %s: %w", cfg.Path, err)
+		}
+		reader = newSyslogReader(newSyslogFrames(input, closer), cfg)
+	case "journald":
+		reader = newJournaldReader(input, closer, cfg)
+	case "xlsx":
+		// Workbooks are zip archives read from their directory at the end.
+		data, readErr := io.ReadAll(input)
+		if readErr != nil {
+			closer.Close()
+			return nil, fmt.Errorf("failed to read %s: %w", cfg.Path, readErr)
+		}
+		reader, err = newXLSXReader(bytes.NewReader(data), int64(len(data)), closer, cfg)
 	default:
+		closer.Close()
 		return nil, fmt.Errorf("unsupported source type: %s", cfg.Type)
 	}
+	if err != nil {
+		return nil, err
+	}
+	return withTransform(reader, cfg)
+}
+
+// withTransform normalizes field names and applies the exec and field
+// transforms configured for the source, if any.
+func withTransform(reader DataReader, cfg config.Source) (DataReader, error) {
+	if names := cfg.FieldNames; names != nil && *names != (config.FieldNames{}) {
+		reader = &fieldNamesReader{source: reader, names: *names}
+	}
+	if cfg.Transform == nil {
+		return reader, nil
+	}
+	fields, err := CompileFieldTransforms(cfg.Transform)
+	if err != nil {
+		reader.Close()
+		return nil, err
+	}
+	if len(cfg.Transform.Exec) > 0 {
+		transformed, err := NewExecReader(reader, cfg.Transform.Exec)
+		if err != nil {
+			reader.Close()
+			return nil, err
+		}
+		reader = transformed
+	}
+	if fields != nil {
+		reader = &fieldTransformReader{source: reader, transforms: fields}
+	}
+	return reader, nil
 }
@@ -2,8 +2,14 @@ package datareader
 
 import (
 	"data-comparator/internal/pkg/config"
+	"encoding/json"
+	"errors"
 	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -68,6 +74,49 @@ func TestJSONReader(t *testing.T) {
 	}
 }
 
+func TestCSVReader_Preamble(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.csv")
+	content := "\ufeffSales report\nGenerated 2025-09-10\n user_id , Name\n1,alice\n2,bob,extra\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	reader, err := New(config.Source{Type: "csv", Path: path, ParserConfig: &config.ParserConfig{SkipLines: 2}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer reader.Close()
+	rec, err := reader.Read()
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if want := (Record{"user_id": "1", "Name": "alice"}); !reflect.DeepEqual(rec, want) {
+		t.Errorf("Read() got = %v, want %v", rec, want)
+	}
+	_, err = reader.Read()
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Line != 5 || perr.Snippet != "2,bob,extra" {
+		t.Errorf("Read() error got = %+v, want a parse error on line 5", err)
+	}
+}
+
+func TestCSVReader_BOMWithoutHeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bom.csv")
+	if err := os.WriteFile(path, []byte("\ufeff1,alice\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	reader, err := New(config.Source{Type: "csv", Path: path, ParserConfig: &config.ParserConfig{NoHeader: true}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer reader.Close()
+	rec, err := reader.Read()
+	if want := (Record{"column_1": "1", "column_2": "alice"}); err != nil || !reflect.DeepEqual(rec, want) {
+		t.Errorf("Read() got = %v, %v, want %v", rec, err, want)
+	}
+}
+
 func TestCSVReader_WithEmbeddedJSON(t *testing.T) {
 	cfg := config.Source{
 		Type: "csv",
@@ -109,6 +158,41 @@ func TestCSVReader_WithEmbeddedJSON(t *testing.T) {
 	}
 }
 
+func TestCSVReader_EmbeddedJSONCutoffs(t *testing.T) {
+	deep := strings.Repeat("[", 7) + strings.Repeat("]", 7)
+	data := "id,value\n1,\"" + strings.ReplaceAll(`{"a": 1}`, `"`, `""`) + "\"\n2," + deep + "\n3,\"" + strings.ReplaceAll(`"xxxxxxxxxxxxxxxxxxxxxx"`, `"`, `""`) + "\"\n4,42\n"
+	path := filepath.Join(t.TempDir(), "data.csv")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := New(config.Source{Type: "csv", Path: path, ParserConfig: &config.ParserConfig{JSONInString: true, MaxJSONDepth: 5, MaxJSONSize: 16}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer reader.Close()
+	var values []interface{}
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		values = append(values, rec["value"])
+	}
+
+	want := []interface{}{map[string]interface{}{"a": 1.0}, deep, `"xxxxxxxxxxxxxxxxxxxxxx"`, 42.0}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("Values got = %#v, want %#v", values, want)
+	}
+	warnings := reader.(Warner).Warnings()
+	if len(warnings) != 2 {
+		t.Errorf("Warnings() got = %v, want one for depth and one for size", warnings)
+	}
+}
+
 func TestReader_EOF(t *testing.T) {
 	cfg := config.Source{
 		Type: "csv",
@@ -134,3 +218,377 @@ func TestReader_EOF(t *testing.T) {
 		t.Errorf("Expected io.EOF, got %v", err)
 	}
 }
+
+func TestSniff(t *testing.T) {
+	dir := t.TempDir()
+	semicolon := filepath.Join(dir, "data.csv")
+	if err := os.WriteFile(semicolon, []byte("1;alice;30\n2;bob;25\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want SniffResult
+	}{
+		{"../../../testdata/testcase1_simple_csv/source1.csv", SniffResult{Type: "csv"}},
+		{"../../../testdata/testcase2_nested_json/source1.jsonl", SniffResult{Type: "json"}},
+		{"../../../testdata/testcase3_csv_with_json/source1.csv", SniffResult{
+			Type: "csv", ParserConfig: config.ParserConfig{JSONInString: true},
+		}},
+		{semicolon, SniffResult{Type: "csv", ParserConfig: config.ParserConfig{Delimiter: ";", NoHeader: true}}},
+	}
+	for _, tt := range tests {
+		got, err := Sniff(tt.path)
+		if err != nil {
+			t.Fatalf("Sniff(%s) error = %v", tt.path, err)
+		}
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("Sniff(%s) got = %+v, want %+v", tt.path, *got, tt.want)
+		}
+	}
+}
+
+func TestNew_AutoType(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.csv")
+	if err := os.WriteFile(path, []byte("1;alice\n2;bob\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	reader, err := New(config.Source{Type: "auto", Path: path})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer reader.Close()
+
+	rec, err := reader.Read()
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	expected := Record{"column_1": "1", "column_2": "alice"}
+	if !reflect.DeepEqual(rec, expected) {
+		t.Errorf("Read() got = %v, want %v", rec, expected)
+	}
+}
+
+func TestJSONReader_Array(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+	if err := os.WriteFile(path, []byte(" [\n{\"id\": 1},\n{\"id\": 2}\n]\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	reader, err := New(config.Source{Type: "json", Path: path})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer reader.Close()
+
+	for i := 1; i <= 2; i++ {
+		rec, err := reader.Read()
+		if err != nil {
+			t.Fatalf("Read() error on record %d: %v", i, err)
+		}
+		if rec["id"] != float64(i) {
+			t.Errorf("Record %d got id = %v", i, rec["id"])
+		}
+	}
+	if _, err := reader.Read(); err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+}
+
+func TestJSONReader_ExactNumbers(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "data.jsonl")
+	if err := os.WriteFile(jsonPath, []byte(`{"id": 9007199254740993, "price": 1.10}`+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	csvPath := filepath.Join(dir, "data.csv")
+	if err := os.WriteFile(csvPath, []byte("id,details\n"+`9007199254740993,"{""price"": 1.10}"`+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	parserConfig := &config.ParserConfig{JSONNumbers: "exact", JSONInString: true}
+	reader, err := New(config.Source{Type: "json", Path: jsonPath, ParserConfig: parserConfig})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	got := readAllRecords(t, reader)
+	want := []Record{{"id": json.Number("9007199254740993"), "price": json.Number("1.10")}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("json records got = %v, want %v", got, want)
+	}
+
+	reader, err = New(config.Source{Type: "csv", Path: csvPath, ParserConfig: parserConfig})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	got = readAllRecords(t, reader)
+	want = []Record{{"id": json.Number("9007199254740993"), "details": map[string]interface{}{"price": json.Number("1.10")}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("csv records got = %v, want %v", got, want)
+	}
+}
+
+func TestReader_ParseErrorLocation(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name       string
+		sourceType string
+		content    string
+		wantLine   int
+		wantRecord int
+		snippet    string
+	}{
+		{"json", "json", "{\"id\": 1}\n{\"id\": 2,, }\n", 2, 2, `{"id": 2,, }`},
+		{"csv", "csv", "id,name\n1,alice\n2,bob,extra\n", 3, 2, "2,bob,extra"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write file: %v", err)
+			}
+			reader, err := New(config.Source{Type: tt.sourceType, Path: path})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			defer reader.Close()
+
+			if _, err := reader.Read(); err != nil {
+				t.Fatalf("Read() error on first record: %v", err)
+			}
+			_, err = reader.Read()
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("Expected *ParseError, got %T: %v", err, err)
+			}
+			if perr.Source != path || perr.Record != tt.wantRecord || perr.Line != tt.wantLine || perr.Snippet != tt.snippet {
+				t.Errorf("ParseError got = %+v, want record %d line %d snippet %q", perr, tt.wantRecord, tt.wantLine, tt.snippet)
+			}
+		})
+	}
+}
+
+func TestExecReader(t *testing.T) {
+	if _, err := exec.LookPath("sed"); err != nil {
+		t.Skip("sed not available")
+	}
+
+	source := NewSliceReader([]Record{{"id": "1", "name": "alice"}, {"id": "2", "name": "bob"}})
+	reader, err := NewExecReader(source, []string{"sed", "s/alice/ALICE/"})
+	if err != nil {
+		t.Fatalf("NewExecReader() error = %v", err)
+	}
+	defer reader.Close()
+
+	var names []interface{}
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		names = append(names, rec["name"])
+	}
+	if !reflect.DeepEqual(names, []interface{}{"ALICE", "bob"}) {
+		t.Errorf("Transformed names got = %v, want [ALICE bob]", names)
+	}
+}
+
+func TestExecReader_Failure(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	source := NewSliceReader([]Record{{"id": "1"}})
+	reader, err := NewExecReader(source, []string{"sh", "-c", "echo broken >&2; exit 3"})
+	if err != nil {
+		t.Fatalf("NewExecReader() error = %v", err)
+	}
+	defer reader.Close()
+
+	if _, err := reader.Read(); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("Read() error got = %v, want failure mentioning stderr", err)
+	}
+}
+
+func TestNewFromReader(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   config.Source
+		input string
+	}{
+		{"csv", config.Source{Type: "csv", Path: "inline"}, "id,name\n1,alice\n2,bob\n"},
+		{"json", config.Source{Type: "json", Path: "inline"}, `{"id":"1","name":"alice"}` + "\n" + `{"id":"2","name":"bob"}`},
+		{"auto", config.Source{Type: "auto", Path: "inline"}, "id;name\n1;alice\n2;bob\n"},
+	}
+	for _, tt := range tests {
+		reader, err := NewFromReader(strings.NewReader(tt.input), tt.cfg)
+		if err != nil {
+			t.Fatalf("%s: NewFromReader() error = %v", tt.name, err)
+		}
+		var names []interface{}
+		for {
+			rec, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: Read() error = %v", tt.name, err)
+			}
+			names = append(names, rec["name"])
+		}
+		reader.Close()
+		if !reflect.DeepEqual(names, []interface{}{"alice", "bob"}) {
+			t.Errorf("%s: names got = %v, want [alice bob]", tt.name, names)
+		}
+	}
+
+	reader, err := NewFromReader(strings.NewReader("{\"id\": 1}\n{bad\n"), config.Source{Type: "json", Path: "inline"})
+	if err != nil {
+		t.Fatalf("NewFromReader() error = %v", err)
+	}
+	reader.Read()
+	var perr *ParseError
+	if _, err := reader.Read(); !errors.As(err, &perr) || perr.Source != "inline" {
+		t.Errorf("Read() error got = %v, want a ParseError naming the stream", err)
+	}
+}
+
+func TestNew_Stdin(t *testing.T) {
+	defer func(orig io.Reader) { stdin = orig }(stdin)
+	stdin = strings.NewReader(string(compressTestData(t, "gzip", []byte(`{"id":"1","name":"alice"}`+"\n"))))
+
+	reader, err := New(config.Source{Type: "auto", Path: StdinPath})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	want := []Record{{"id": "1", "name": "alice"}}
+	if got := readAllRecords(t, reader); !reflect.DeepEqual(got, want) {
+		t.Errorf("Records got = %v, want %v", got, want)
+	}
+}
+
+func TestReader_EdgeCases(t *testing.T) {
+	huge := strings.Repeat("x", 1<<20)
+	tests := []struct {
+		name    string
+		cfg     config.Source
+		input   string
+		records int
+	}{
+		{"empty csv", config.Source{Type: "csv", Path: "inline"}, "", 0},
+		{"header-only csv", config.Source{Type: "csv", Path: "inline"}, "id,name\n", 0},
+		{"empty json", config.Source{Type: "json", Path: "inline"}, "", 0},
+		{"empty json array", config.Source{Type: "json", Path: "inline"}, "[]", 0},
+		{"empty auto", config.Source{Type: "auto", Path: "inline"}, "", 0},
+		{"one-row csv", config.Source{Type: "csv", Path: "inline"}, "id,name\n1,alice\n", 1},
+		{"one-row json", config.Source{Type: "json", Path: "inline"}, `{"id":"1"}`, 1},
+		{"huge-row csv", config.Source{Type: "csv", Path: "inline"}, "id,name\n1," + huge + "\n", 1},
+		{"huge-row json", config.Source{Type: "json", Path: "inline"}, `{"id":"1","name":"` + huge + `"}`, 1},
+	}
+	for _, tt := range tests {
+		reader, err := NewFromReader(strings.NewReader(tt.input), tt.cfg)
+		if err != nil {
+			t.Fatalf("%s: NewFromReader() error = %v", tt.name, err)
+		}
+		count := 0
+		for {
+			rec, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: Read() error = %v", tt.name, err)
+			}
+			if name, ok := rec["name"].(string); ok && strings.HasPrefix(tt.name, "huge") && len(name) != len(huge) {
+				t.Errorf("%s: name length got = %d, want %d", tt.name, len(name), len(huge))
+			}
+			count++
+		}
+		reader.Close()
+		if count != tt.records {
+			t.Errorf("%s: records got = %d, want %d", tt.name, count, tt.records)
+		}
+	}
+}
+
+func TestReader_Resume(t *testing.T) {
+	dir := t.TempDir()
+	csvContent := "id,name\n1,\"multi\nline\"\n2,bob\n3,carol,extra\n4,dave\n"
+	jsonContent := "\n{\"id\": \"1\"}\n{\"id\": \"2\"}\n{\"id\": \"3\",, }\n"
+	tests := []struct {
+		name       string
+		sourceType string
+		file       string
+		content    []byte
+		wantPos    Position
+		wantIDs    []interface{}
+		wantLine   int
+		snippet    string
+	}{
+		{"csv", "csv", "data.csv", []byte(csvContent), Position{Records: 2, Offset: 29, Line: 4}, []interface{}{"4"}, 5, "3,carol,extra"},
+		// Offsets into compressed files are not seeked, and have no snippets.
+		{"gzip csv", "csv", "data.csv.gz", compressTestData(t, "gzip", []byte(csvContent)), Position{Records: 2, Offset: 29, Line: 4}, []interface{}{"4"}, 5, ""},
+		{"json lines", "json", "data.json", []byte(jsonContent), Position{Records: 2, Offset: 24}, nil, 4, `{"id": "3",, }`},
+		{"json array", "json", "array.json", []byte(`[{"id": "1"}, {"id": "2"}, {"id": "3"}]`), Position{Records: 2, Offset: 25}, []interface{}{"3"}, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(path, tt.content, 0644); err != nil {
+				t.Fatal(err)
+			}
+			cfg := config.Source{Type: tt.sourceType, Path: path}
+			first, err := New(cfg)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			for i := 0; i < 2; i++ {
+				if _, err := first.Read(); err != nil {
+					t.Fatalf("Read() error = %v", err)
+				}
+			}
+			pos := first.(Resumer).Position()
+			first.Close()
+			if pos != tt.wantPos {
+				t.Errorf("Position() got = %+v, want %+v", pos, tt.wantPos)
+			}
+
+			reader, err := New(cfg)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if err := reader.(Resumer).Resume(pos); err != nil {
+				t.Fatalf("Resume() error = %v", err)
+			}
+			records, errs := readAll(t, reader)
+			var ids []interface{}
+			for _, rec := range records {
+				ids = append(ids, rec["id"])
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("ids after Resume() got = %v, want %v", ids, tt.wantIDs)
+			}
+			var perr *ParseError
+			if tt.wantLine == 0 {
+				if len(errs) != 0 {
+					t.Errorf("errors after Resume() got = %v, want none", errs)
+				}
+			} else if len(errs) != 1 || !errors.As(errs[0], &perr) || perr.Record != 3 || perr.Line != tt.wantLine || perr.Snippet != tt.snippet {
+				t.Errorf("errors after Resume() got = %v, want record 3 at line %d, %q", errs, tt.wantLine, tt.snippet)
+			}
+		})
+	}
+
+	reader, err := NewFromReader(strings.NewReader(csvContent), config.Source{Type: "csv", Path: "inline"})
+	if err != nil {
+		t.Fatalf("NewFromReader() error = %v", err)
+	}
+	if err := reader.(Resumer).Resume(Position{Records: 5}); err == nil {
+		t.Error("Resume() past the last record expected an error, got nil")
+	}
+}
@@ -0,0 +1,101 @@
+package datareader
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// maxSnippetLength bounds the raw input quoted in a ParseError.
+const maxSnippetLength = 120
+
+// ParseError describes a record that could not be parsed, with enough context
+// to find it in the source.
+type ParseError struct {
+	Source  string // path of the source file
+	Record  int    // 1-based number of the record that failed
+	Line    int    // 1-based line where the record starts, 0 if unknown
+	Offset  int64  // byte offset of the error, -1 if unknown
+	Snippet string // truncated raw input around the error
+	// Recoverable is set when the reader can continue with the next record.
+	Recoverable bool
+	// Fields holds the fields read of the record before the error, if any,
+	// such as those of a CSV row with the wrong number of fields.
+	Fields Record
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: record %d", e.Source, e.Record)
+	if e.Line > 0 {
+		fmt.Fprintf(&b, ", line %d", e.Line)
+	}
+	if e.Offset >= 0 {
+		fmt.Fprintf(&b, ", offset %d", e.Offset)
+	}
+	fmt.Fprintf(&b, ": %v", e.Err)
+	if e.Snippet != "" {
+		fmt.Fprintf(&b, " (near %q)", e.Snippet)
+	}
+	return b.String()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// locateOffset re-reads the file to find the line containing a byte offset and
+// returns its number and a snippet of it. It is only used on the error path.
+func locateOffset(path string, offset int64) (int, string) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, ""
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	line, pos := 1, int64(0)
+	for {
+		text, err := reader.ReadString('\n')
+		end := pos + int64(len(text))
+		if offset < end || err != nil {
+			if offset > end {
+				return 0, ""
+			}
+			return line, truncate(strings.TrimRight(text, "\r\n"))
+		}
+		line++
+		pos = end
+	}
+}
+
+// lineSnippet returns a snippet of the given 1-based line of a file.
+func lineSnippet(path string, line int) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for i := 1; ; i++ {
+		text, err := reader.ReadString('\n')
+		if i == line {
+			return truncate(strings.TrimRight(text, "\r\n"))
+		}
+		if err == io.EOF || err != nil {
+			return ""
+		}
+	}
+}
+
+func truncate(s string) string {
+	runes := []rune(s)
+	if len(runes) <= maxSnippetLength {
+		return s
+	}
+	return string(runes[:maxSnippetLength]) + "…"
+}
@@ -0,0 +1,130 @@
+package datareader
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// ExecReader pipes the records of another reader as JSON Lines through an
+// external process and reads the transformed records from its output. The
+// process may drop, add or rewrite records; each output line must be a JSON
+// object. This lets teams plug normalization logic written in any language
+// into the pipeline.
+type ExecReader struct {
+	source  DataReader
+	command string
+	cmd     *exec.Cmd
+	stdout  io.ReadCloser
+	decoder *json.Decoder
+	stderr  strings.Builder
+	records int
+
+	// feeding the process runs concurrently with reading its output
+	feedDone chan struct{}
+	feedErr  error
+	closed   sync.Once
+}
+
+// NewExecReader starts command with its arguments and feeds it the records of source.
+func NewExecReader(source DataReader, command []string) (*ExecReader, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("exec transform has no command")
+	}
+
+	r := &ExecReader{
+		source:   source,
+		command:  strings.Join(command, " "),
+		cmd:      exec.Command(command[0], command[1:]...),
+		feedDone: make(chan struct{}),
+	}
+	r.cmd.Stderr = &r.stderr
+	stdin, err := r.cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdin of %s: %w", r.command, err)
+	}
+	r.stdout, err = r.cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout of %s: %w", r.command, err)
+	}
+	if err := r.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", r.command, err)
+	}
+	r.decoder = json.NewDecoder(bufio.NewReader(r.stdout))
+
+	go r.feed(stdin)
+	return r, nil
+}
+
+// feed writes every source record to the process and closes its stdin at the end.
+func (r *ExecReader) feed(stdin io.WriteCloser) {
+	defer close(r.feedDone)
+	defer stdin.Close()
+
+	writer := bufio.NewWriter(stdin)
+	encoder := json.NewEncoder(writer)
+	for {
+		rec, err := r.source.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			r.feedErr = err
+			return
+		}
+		if err := encoder.Encode(rec); err != nil {
+			// The process stopped reading; its exit status tells why.
+			return
+		}
+	}
+	writer.Flush()
+}
+
+// Read returns the next transformed record. Errors reading the source and a
+// failing exit status of the process are reported once its output ends.
+func (r *ExecReader) Read() (Record, error) {
+	var record Record
+	err := r.decoder.Decode(&record)
+	if err == nil {
+		r.records++
+		return record, nil
+	}
+	if err != io.EOF {
+		return nil, &ParseError{Source: r.command, Record: r.records + 1, Offset: r.decoder.InputOffset(), Err: err}
+	}
+
+	<-r.feedDone
+	if r.feedErr != nil {
+		return nil, r.feedErr
+	}
+	if err := r.wait(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+func (r *ExecReader) wait() error {
+	var err error
+	r.closed.Do(func() {
+		if waitErr := r.cmd.Wait(); waitErr != nil {
+			err = fmt.Errorf("%s failed: %w: %s", r.command, waitErr, strings.TrimSpace(r.stderr.String()))
+		}
+	})
+	return err
+}
+
+// Close stops the process if it is still running and closes the source.
+func (r *ExecReader) Close() error {
+	r.closed.Do(func() {
+		if r.cmd.Process != nil {
+			r.cmd.Process.Kill()
+		}
+		r.cmd.Wait()
+	})
+	<-r.feedDone
+	return r.source.Close()
+}
@@ -0,0 +1,122 @@
+package datareader
+
+import (
+	"data-comparator/internal/pkg/config"
+	"strings"
+	"unicode"
+)
+
+// NormalizeFieldName normalizes a field name as selected by names.
+func NormalizeFieldName(name string, names config.FieldNames) string {
+	if names.SnakeCase {
+		return snakeCase(name)
+	}
+	if names.StripSpaces {
+		name = strings.Join(strings.Fields(name), "")
+	}
+	if names.Lowercase {
+		name = strings.ToLower(name)
+	}
+	return name
+}
+
+// NormalizeFieldPath normalizes each name of a dotted path of nested fields,
+// such as a configured key, as selected by names.
+func NormalizeFieldPath(path string, names *config.FieldNames) string {
+	if names == nil {
+		return path
+	}
+	parts := strings.Split(path, ".")
+	for i, part := range parts {
+		parts[i] = NormalizeFieldName(part, *names)
+	}
+	return strings.Join(parts, ".")
+}
+
+// snakeCase lowercases name and joins its words with underscores. Words are
+// separated by anything but letters and digits, and start at each upper case
+// letter following a lower case letter or digit, or followed by a lower case
+// letter after upper case ones, as in "HTTPStatus".
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var out strings.Builder
+	pending := false // a word separator is due before the next letter or digit
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			pending = out.Len() > 0
+			continue
+		}
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			next := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && next {
+				pending = out.Len() > 0
+			}
+		}
+		if pending {
+			out.WriteByte('_')
+			pending = false
+		}
+		out.WriteRune(unicode.ToLower(r))
+	}
+	return out.String()
+}
+
+// normalizeFieldNames renames the fields of v, recursively. When several
+// names of an object normalize to the same one, the value of the name that
+// sorts first is kept.
+func normalizeFieldNames(v interface{}, names config.FieldNames) interface{} {
+	switch t := v.(type) {
+	case Record:
+		return Record(normalizeObject(t, names))
+	case map[string]interface{}:
+		return normalizeObject(t, names)
+	case []interface{}:
+		for i, item := range t {
+			t[i] = normalizeFieldNames(item, names)
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+func normalizeObject(m map[string]interface{}, names config.FieldNames) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	origins := make(map[string]string, len(m))
+	for name, value := range m {
+		normalized := NormalizeFieldName(name, names)
+		if origin, ok := origins[normalized]; ok && origin < name {
+			continue
+		}
+		origins[normalized] = name
+		out[normalized] = normalizeFieldNames(value, names)
+	}
+	return out
+}
+
+// fieldNamesReader normalizes the field names of the records of a reader.
+type fieldNamesReader struct {
+	source DataReader
+	names  config.FieldNames
+}
+
+func (r *fieldNamesReader) Read() (Record, error) {
+	rec, err := r.source.Read()
+	if err != nil {
+		return rec, err
+	}
+	return normalizeFieldNames(rec, r.names).(Record), nil
+}
+
+func (r *fieldNamesReader) Close() error {
+	return r.source.Close()
+}
+
+// Warnings returns the warnings of the source, if it has any.
+func (r *fieldNamesReader) Warnings() []string {
+	if w, ok := r.source.(Warner); ok {
+		return w.Warnings()
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package datareader
+
+import (
+	"data-comparator/internal/pkg/config"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeFieldName(t *testing.T) {
+	tests := []struct {
+		name  string
+		names config.FieldNames
+		want  string
+	}{
+		{"User ID", config.FieldNames{SnakeCase: true}, "user_id"},
+		{"userId", config.FieldNames{SnakeCase: true}, "user_id"},
+		{"User-Id", config.FieldNames{SnakeCase: true}, "user_id"},
+		{"HTTPStatus", config.FieldNames{SnakeCase: true}, "http_status"},
+		{" address line2 ", config.FieldNames{SnakeCase: true}, "address_line2"},
+		{"user_id", config.FieldNames{SnakeCase: true}, "user_id"},
+		{" User ID ", config.FieldNames{StripSpaces: true}, "UserID"},
+		{" User ID ", config.FieldNames{StripSpaces: true, Lowercase: true}, "userid"},
+		{"User ID", config.FieldNames{Lowercase: true}, "user id"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeFieldName(tt.name, tt.names); got != tt.want {
+			t.Errorf("NormalizeFieldName(%q, %+v) got = %q, want %q", tt.name, tt.names, got, tt.want)
+		}
+	}
+}
+
+func TestNew_FieldNames(t *testing.T) {
+	input := `{"User ID": 1, "Address": {"Zip Code": "75001"}, "Tags": [{"Tag Name": "a"}]}` + "\n"
+	reader, err := NewFromReader(strings.NewReader(input), config.Source{
+		Type:       "json",
+		FieldNames: &config.FieldNames{SnakeCase: true},
+		Transform:  &config.Transform{Fields: map[string][]string{"address.zip_code": {"substring(0, 2)"}}},
+	})
+	if err != nil {
+		t.Fatalf("NewFromReader() error = %v", err)
+	}
+	got := readAllRecords(t, reader)
+	want := []Record{{
+		"user_id": float64(1),
+		"address": map[string]interface{}{"zip_code": "75"},
+		"tags":    []interface{}{map[string]interface{}{"tag_name": "a"}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("records got = %v, want %v", got, want)
+	}
+}
+
+func TestNormalizeFieldNames_Collisions(t *testing.T) {
+	got := normalizeFieldNames(Record{"User ID": "a", "user_id": "b", "userId": "c"}, config.FieldNames{SnakeCase: true})
+	if want := (Record{"user_id": "a"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("normalizeFieldNames() got = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,362 @@
+package datareader
+
+import (
+	"data-comparator/internal/pkg/config"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TransformSteps lists the steps of field transforms, as written in configs.
+var TransformSteps = []string{"lowercase", "substring(start, length)", "round(places)", "parse_json", "date_format(layout, input_layout)", "lookup(table)"}
+
+// transformDateTimeLayouts are the layouts datetime strings are parsed with
+// by date_format when it has no input layout.
+var transformDateTimeLayouts = []string{
+	time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02", "01/02/2006",
+}
+
+// FieldTransforms rewrite the values of fields, e.g. to compare values that
+// only differ in representation. Each field has a pipeline of steps written
+// as Go expressions: a step name, or a call of it with name, number or
+// string literal arguments, such as substring(0, 3), lookup(countries) or
+// date_format("2006-01-02"). Steps leave values they do not apply to
+// unchanged.
+type FieldTransforms struct {
+	fields []fieldTransform
+}
+
+type fieldTransform struct {
+	name  string
+	steps []transformStep
+}
+
+// transformStep rewrites a single value.
+type transformStep func(v interface{}) interface{}
+
+// CompileFieldTransforms compiles the field pipelines of cfg. It returns nil
+// if there are none.
+func CompileFieldTransforms(cfg *config.Transform) (*FieldTransforms, error) {
+	if cfg == nil || len(cfg.Fields) == 0 {
+		return nil, nil
+	}
+	names := make([]string, 0, len(cfg.Fields))
+	for name := range cfg.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	t := &FieldTransforms{}
+	for _, name := range names {
+		field := fieldTransform{name: name}
+		for _, expr := range cfg.Fields[name] {
+			step, err := compileStep(expr, cfg.Lookups)
+			if err != nil {
+				return nil, fmt.Errorf("invalid transform %q of field %s: %w", expr, name, err)
+			}
+			field.steps = append(field.steps, step)
+		}
+		t.fields = append(t.fields, field)
+	}
+	return t, nil
+}
+
+func compileStep(expr string, lookups map[string]map[string]string) (transformStep, error) {
+	node, err := parser.ParseExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	var name string
+	var args []interface{}
+	switch n := node.(type) {
+	case *ast.Ident:
+		name = n.Name
+	case *ast.CallExpr:
+		ident, ok := n.Fun.(*ast.Ident)
+		if !ok {
+			return nil, fmt.Errorf("unknown step")
+		}
+		name = ident.Name
+		for _, arg := range n.Args {
+			value, err := stepArg(arg)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, value)
+		}
+	default:
+		return nil, fmt.Errorf("a step is a name or a call, e.g. round(2)")
+	}
+
+	switch name {
+	case "lowercase":
+		if err := checkArgs(args, 0, 0); err != nil {
+			return nil, err
+		}
+		return lowercase, nil
+	case "substring":
+		if err := checkArgs(args, 1, 2, int64(0), int64(0)); err != nil {
+			return nil, err
+		}
+		start, length := args[0].(int64), int64(-1)
+		if len(args) == 2 {
+			length = args[1].(int64)
+		}
+		if start < 0 || len(args) == 2 && length < 0 {
+			return nil, fmt.Errorf("substring start and length must not be negative")
+		}
+		return substring(int(start), int(length)), nil
+	case "round":
+		if err := checkArgs(args, 0, 1, int64(0)); err != nil {
+			return nil, err
+		}
+		places := int64(0)
+		if len(args) == 1 {
+			places = args[0].(int64)
+		}
+		return round(int(places)), nil
+	case "parse_json":
+		if err := checkArgs(args, 0, 0); err != nil {
+			return nil, err
+		}
+		return parseJSON, nil
+	case "date_format":
+		if err := checkArgs(args, 1, 2, "", ""); err != nil {
+			return nil, err
+		}
+		layouts := transformDateTimeLayouts
+		if len(args) == 2 {
+			layouts = []string{args[1].(string)}
+		}
+		return dateFormat(args[0].(string), layouts), nil
+	case "lookup":
+		if err := checkArgs(args, 1, 1, ""); err != nil {
+			return nil, err
+		}
+		table, ok := lookups[args[0].(string)]
+		if !ok {
+			return nil, fmt.Errorf("no lookup table %s in transform.lookups", args[0])
+		}
+		return lookup(table), nil
+	default:
+		return nil, fmt.Errorf("unknown step %s, use one of %s", name, strings.Join(TransformSteps, ", "))
+	}
+}
+
+// stepArg returns the value of an argument literal: an int64, float64 or
+// string. Names, such as those of lookup tables, may be left unquoted.
+func stepArg(arg ast.Expr) (interface{}, error) {
+	if ident, ok := arg.(*ast.Ident); ok {
+		return ident.Name, nil
+	}
+	negative := false
+	if unary, ok := arg.(*ast.UnaryExpr); ok && unary.Op == token.SUB {
+		negative, arg = true, unary.X
+	}
+	lit, ok := arg.(*ast.BasicLit)
+	if !ok {
+		return nil, fmt.Errorf("arguments must be names or number or string literals")
+	}
+	switch lit.Kind {
+	case token.INT:
+		n, err := strconv.ParseInt(lit.Value, 0, 64)
+		if negative {
+			n = -n
+		}
+		return n, err
+	case token.FLOAT:
+		f, err := strconv.ParseFloat(lit.Value, 64)
+		if negative {
+			f = -f
+		}
+		return f, err
+	case token.STRING:
+		if negative {
+			return nil, fmt.Errorf("cannot negate a string")
+		}
+		return strconv.Unquote(lit.Value)
+	default:
+		return nil, fmt.Errorf("arguments must be names or number or string literals")
+	}
+}
+
+// checkArgs checks that there are min to max args, of the types of the
+// examples.
+func checkArgs(args []interface{}, min, max int, examples ...interface{}) error {
+	if len(args) < min || len(args) > max {
+		if min == max {
+			return fmt.Errorf("takes %d arguments, got %d", min, len(args))
+		}
+		return fmt.Errorf("takes %d to %d arguments, got %d", min, max, len(args))
+	}
+	for i, arg := range args {
+		if fmt.Sprintf("%T", arg) != fmt.Sprintf("%T", examples[i]) {
+			return fmt.Errorf("argument %d must be of type %T, got %v", i+1, examples[i], arg)
+		}
+	}
+	return nil
+}
+
+func lowercase(v interface{}) interface{} {
+	if s, ok := v.(string); ok {
+		return strings.ToLower(s)
+	}
+	return v
+}
+
+// substring keeps length characters from start, or all from start if length
+// is negative.
+func substring(start, length int) transformStep {
+	return func(v interface{}) interface{} {
+		s, ok := v.(string)
+		if !ok {
+			return v
+		}
+		runes := []rune(s)
+		from := min(start, len(runes))
+		to := len(runes)
+		if length >= 0 {
+			to = min(from+length, len(runes))
+		}
+		return string(runes[from:to])
+	}
+}
+
+// round rounds numbers and numeric strings to places decimal places.
+func round(places int) transformStep {
+	scale := math.Pow(10, float64(places))
+	return func(v interface{}) interface{} {
+		var f float64
+		switch n := v.(type) {
+		case float64:
+			f = n
+		case float32:
+			f = float64(n)
+		case int, int32, int64:
+			return v
+		case json.Number:
+			var err error
+			if f, err = n.Float64(); err != nil {
+				return v
+			}
+		case string:
+			var err error
+			if f, err = strconv.ParseFloat(strings.TrimSpace(n), 64); err != nil {
+				return v
+			}
+		default:
+			return v
+		}
+		return math.Round(f*scale) / scale
+	}
+}
+
+func parseJSON(v interface{}) interface{} {
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(s), &parsed); err != nil {
+		return v
+	}
+	return parsed
+}
+
+// dateFormat formats datetimes, and strings parsed with one of layouts, with
+// layout.
+func dateFormat(layout string, layouts []string) transformStep {
+	return func(v interface{}) interface{} {
+		switch t := v.(type) {
+		case time.Time:
+			return t.Format(layout)
+		case string:
+			for _, in := range layouts {
+				if at, err := time.Parse(in, strings.TrimSpace(t)); err == nil {
+					return at.Format(layout)
+				}
+			}
+		}
+		return v
+	}
+}
+
+// lookup replaces values found in table by their entry.
+func lookup(table map[string]string) transformStep {
+	return func(v interface{}) interface{} {
+		if v == nil {
+			return v
+		}
+		if replacement, ok := table[fmt.Sprint(v)]; ok {
+			return replacement
+		}
+		return v
+	}
+}
+
+// Apply transforms the fields of rec in place and returns it. Fields are
+// named by their dotted path; a field absent from rec is skipped.
+func (t *FieldTransforms) Apply(rec Record) Record {
+	for _, field := range t.fields {
+		parent, key := map[string]interface{}(rec), field.name
+		if _, ok := parent[key]; !ok {
+			parent, key = nestedParent(parent, field.name)
+		}
+		v, ok := parent[key]
+		if !ok {
+			continue
+		}
+		for _, step := range field.steps {
+			v = step(v)
+		}
+		parent[key] = v
+	}
+	return rec
+}
+
+// nestedParent returns the map holding the nested field name and its key in
+// it, or nil if there is none.
+func nestedParent(m map[string]interface{}, name string) (map[string]interface{}, string) {
+	parts := strings.Split(name, ".")
+	for _, part := range parts[:len(parts)-1] {
+		child, ok := m[part].(map[string]interface{})
+		if !ok {
+			return nil, ""
+		}
+		m = child
+	}
+	return m, parts[len(parts)-1]
+}
+
+// fieldTransformReader applies field transforms to the records of a reader.
+type fieldTransformReader struct {
+	source     DataReader
+	transforms *FieldTransforms
+}
+
+func (r *fieldTransformReader) Read() (Record, error) {
+	rec, err := r.source.Read()
+	if err != nil {
+		return rec, err
+	}
+	return r.transforms.Apply(rec), nil
+}
+
+func (r *fieldTransformReader) Close() error {
+	return r.source.Close()
+}
+
+// Warnings returns the warnings of the source, if it has any.
+func (r *fieldTransformReader) Warnings() []string {
+	if w, ok := r.source.(Warner); ok {
+		return w.Warnings()
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package datareader
+
+import (
+	"data-comparator/internal/pkg/config"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFieldTransforms(t *testing.T) {
+	transforms, err := CompileFieldTransforms(&config.Transform{
+		Fields: map[string][]string{
+			"email":           {"lowercase"},
+			"code":            {"substring(0, 3)"},
+			"amount":          {"round(2)"},
+			"payload":         {"parse_json"},
+			"created_at":      {`date_format("2006-01-02")`},
+			"updated_at":      {`date_format("2006-01-02", "02/01/2006")`},
+			"customer.region": {"lowercase", "lookup(regions)"},
+			"missing":         {"lowercase"},
+		},
+		Lookups: map[string]map[string]string{"regions": {"eu": "europe"}},
+	})
+	if err != nil {
+		t.Fatalf("CompileFieldTransforms() error = %v", err)
+	}
+
+	got := transforms.Apply(Record{
+		"email":      "Alice@Example.COM",
+		"code":       "DEU-1",
+		"amount":     "10.006",
+		"payload":    `{"a": 1}`,
+		"created_at": time.Date(2025, 9, 10, 12, 0, 0, 0, time.UTC),
+		"updated_at": "10/09/2025",
+		"customer":   map[string]interface{}{"region": "EU"},
+		"name":       "Bob",
+	})
+	want := Record{
+		"email":      "alice@example.com",
+		"code":       "DEU",
+		"amount":     10.01,
+		"payload":    map[string]interface{}{"a": float64(1)},
+		"created_at": "2025-09-10",
+		"updated_at": "2025-09-10",
+		"customer":   map[string]interface{}{"region": "europe"},
+		"name":       "Bob",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Apply() got = %v, want %v", got, want)
+	}
+}
+
+func TestFieldTransforms_UnchangedValues(t *testing.T) {
+	transforms, err := CompileFieldTransforms(&config.Transform{Fields: map[string][]string{
+		"a": {"round(1)"}, "b": {"parse_json"}, "c": {`date_format("2006")`}, "d": {"substring(5)"},
+	}})
+	if err != nil {
+		t.Fatalf("CompileFieldTransforms() error = %v", err)
+	}
+	got := transforms.Apply(Record{"a": "n/a", "b": "{not json", "c": "yesterday", "d": "abc"})
+	want := Record{"a": "n/a", "b": "{not json", "c": "yesterday", "d": ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Apply() got = %v, want %v", got, want)
+	}
+}
+
+func TestCompileFieldTransforms_Errors(t *testing.T) {
+	tests := []struct {
+		step string
+		want string
+	}{
+		{"uppercase", "unknown step"},
+		{"round(2, 3)", "arguments"},
+		{"substring(-1)", "negative"},
+		{`lookup("countries")`, "no lookup table"},
+		{"round(x)", "type int64"},
+		{"round(1 + 2)", "literals"},
+		{"round(", "expected"},
+	}
+	for _, tt := range tests {
+		_, err := CompileFieldTransforms(&config.Transform{Fields: map[string][]string{"f": {tt.step}}})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("CompileFieldTransforms(%s) error = %v, want one containing %q", tt.step, err, tt.want)
+		}
+	}
+}
+
+func TestNew_FieldTransforms(t *testing.T) {
+	reader, err := NewFromReader(strings.NewReader("id,name\n1,ALICE\n"), config.Source{
+		Type:      "csv",
+		Transform: &config.Transform{Fields: map[string][]string{"name": {"lowercase"}}},
+	})
+	if err != nil {
+		t.Fatalf("NewFromReader() error = %v", err)
+	}
+	got := readAllRecords(t, reader)
+	if want := []Record{{"id": "1", "name": "alice"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("records got = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,318 @@
+package datareader
+
+import (
+	"bufio"
+	"data-comparator/internal/pkg/config"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FixedWidthReader reads records whose columns sit at fixed byte offsets,
+// one per line or, with a record length, back to back. String values are
+// trimmed of padding; number columns become int64, or float64 with implied
+// decimals, and nil when blank.
+type FixedWidthReader struct {
+	path         string
+	file         io.Closer
+	input        *bufio.Reader
+	columns      []config.FixedWidthColumn
+	recordLength int
+	records      int
+	line         int
+	offset       int64
+}
+
+// NewFixedWidthReader opens the fixed-width file of cfg, laid out by
+// cfg.FixedWidth. Files may be gzip, zstd or bzip2 compressed.
+func NewFixedWidthReader(cfg config.Source) (DataReader, error) {
+	file, err := openSource(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open fixed-width file %s: %w", cfg.Path, err)
+	}
+	input, closer, _, err := decompress(file, file, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newFixedWidthReader(input, closer, cfg)
+}
+
+// newFixedWidthReader reads records from input, named by cfg.Path, and
+// closes closer when done or on error.
+func newFixedWidthReader(input io.Reader, closer io.Closer, cfg config.Source) (*FixedWidthReader, error) {
+	columns, err := fixedWidthColumns(cfg)
+	if err != nil {
+		closer.Close()
+		return nil, err
+	}
+	return &FixedWidthReader{
+		path:         cfg.Path,
+		file:         closer,
+		input:        bufio.NewReader(input),
+		columns:      columns,
+		recordLength: cfg.FixedWidth.RecordLength,
+	}, nil
+}
+
+// fixedWidthColumns returns the configured columns, or those of the layout file.
+func fixedWidthColumns(cfg config.Source) ([]config.FixedWidthColumn, error) {
+	fw := cfg.FixedWidth
+	if fw == nil || (len(fw.Columns) == 0 && fw.Layout == "") {
+		return nil, fmt.Errorf("fixed_width source %s needs fixed_width.columns or fixed_width.layout", cfg.Path)
+	}
+	columns := fw.Columns
+	if fw.Layout != "" {
+		var err error
+		if columns, err = LoadFixedWidthLayout(fw.Layout); err != nil {
+			return nil, err
+		}
+	}
+	for _, col := range columns {
+		switch {
+		case col.Name == "" || col.Length <= 0 || col.Offset < 0:
+			return nil, fmt.Errorf("fixed-width column %q needs a name, an offset and a positive length", col.Name)
+		case col.Type != "" && col.Type != "string" && col.Type != "number":
+			return nil, fmt.Errorf("fixed-width column %s has unsupported type %q (use string or number)", col.Name, col.Type)
+		}
+	}
+	return columns, nil
+}
+
+// layoutField is a field of a copybook-like layout file.
+type layoutField struct {
+	Name string `yaml:"name"`
+	// Pic is a COBOL PIC clause such as X(10), 9(5) or S9(7)V99.
+	Pic    string `yaml:"pic"`
+	Length int    `yaml:"length"`
+	Type   string `yaml:"type"`
+	Scale  int    `yaml:"scale"`
+}
+
+// LoadFixedWidthLayout reads a copybook-like YAML layout: a fields list
+// giving each field of a record in order with its name and either a DISPLAY
+// PIC clause, such as X(10), 9(5) or S9(7)V99, or a length and optional
+// type and scale. Offsets follow from the order. FILLER fields take up
+// their space without becoming columns.
+func LoadFixedWidthLayout(path string) ([]config.FixedWidthColumn, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixed-width layout: %w", err)
+	}
+	var layout struct {
+		Fields []layoutField `yaml:"fields"`
+	}
+	if err := yaml.Unmarshal(data, &layout); err != nil {
+		return nil, fmt.Errorf("failed to parse fixed-width layout %s: %w", path, err)
+	}
+	if len(layout.Fields) == 0 {
+		return nil, fmt.Errorf("fixed-width layout %s has no fields", path)
+	}
+
+	var columns []config.FixedWidthColumn
+	offset := 0
+	for _, f := range layout.Fields {
+		col := config.FixedWidthColumn{Name: f.Name, Offset: offset, Length: f.Length, Type: f.Type, Scale: f.Scale}
+		if f.Pic != "" {
+			if col.Length, col.Type, col.Scale, err = parsePic(f.Pic); err != nil {
+				return nil, fmt.Errorf("fixed-width layout %s field %s: %w", path, f.Name, err)
+			}
+		}
+		if col.Length <= 0 {
+			return nil, fmt.Errorf("fixed-width layout %s field %s needs a pic or a positive length", path, f.Name)
+		}
+		offset += col.Length
+		if !strings.EqualFold(f.Name, "FILLER") {
+			columns = append(columns, col)
+		}
+	}
+	return columns, nil
+}
+
+// parsePic returns the length, type and scale of a DISPLAY PIC clause.
+func parsePic(pic string) (int, string, int, error) {
+	length, scale, afterV, numeric := 0, 0, false, true
+	s := strings.ToUpper(strings.TrimSpace(pic))
+	for len(s) > 0 {
+		symbol := s[0]
+		s = s[1:]
+		count := 1
+		if strings.HasPrefix(s, "(") {
+			end := strings.IndexByte(s, ')')
+			if end < 0 {
+				return 0, "", 0, fmt.Errorf("unclosed repeat count in PIC %s", pic)
+			}
+			n, err := strconv.Atoi(s[1:end])
+			if err != nil || n <= 0 {
+				return 0, "", 0, fmt.Errorf("invalid repeat count in PIC %s", pic)
+			}
+			count, s = n, s[end+1:]
+		}
+		switch symbol {
+		case 'S':
+			// The sign is overpunched on the last digit.
+		case 'V':
+			afterV = true
+		case '9', 'Z':
+			length += count
+			if afterV {
+				scale += count
+			}
+		case 'X', 'A':
+			length += count
+			numeric = false
+		case '.', ',', '+', '-':
+			// Edited numbers hold their point and sign, and parse as written.
+			length += count
+		default:
+			return 0, "", 0, fmt.Errorf("unsupported symbol %q in PIC %s", symbol, pic)
+		}
+	}
+	if !numeric {
+		return length, "string", 0, nil
+	}
+	return length, "number", scale, nil
+}
+
+// Read returns the next record, or io.EOF at the end of the file. Blank
+// lines are skipped.
+func (r *FixedWidthReader) Read() (Record, error) {
+	var data string
+	start := r.offset
+	if r.recordLength > 0 {
+		buf := make([]byte, r.recordLength)
+		n, err := io.ReadFull(r.input, buf)
+		r.offset += int64(n)
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		r.records++
+		if err != nil {
+			return nil, &ParseError{Source: r.path, Record: r.records, Offset: start, Snippet: truncate(string(buf[:n])),
+				Err: fmt.Errorf("truncated record of %d bytes, want %d", n, r.recordLength)}
+		}
+		data = string(buf)
+	} else {
+		for {
+			line, err := r.input.ReadString('\n')
+			start = r.offset
+			r.offset += int64(len(line))
+			if len(line) > 0 {
+				r.line++
+			}
+			if err != nil && err != io.EOF {
+				return nil, fmt.Errorf("failed to read %s: %w", r.path, err)
+			}
+			data = strings.TrimRight(line, "\r\n")
+			if strings.TrimSpace(data) != "" {
+				break
+			}
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+		}
+		r.records++
+	}
+
+	rec := make(Record, len(r.columns))
+	for _, col := range r.columns {
+		value, err := fixedWidthValue(data, col)
+		if err != nil {
+			perr := &ParseError{Source: r.path, Record: r.records, Offset: start + int64(col.Offset), Snippet: truncate(data), Recoverable: true, Fields: rec, Err: err}
+			if r.recordLength == 0 {
+				perr.Line = r.line
+			}
+			return nil, perr
+		}
+		rec[col.Name] = value
+	}
+	return rec, nil
+}
+
+// fixedWidthValue cuts the value of col out of a record. Columns beyond the
+// end of a short record are blank.
+func fixedWidthValue(data string, col config.FixedWidthColumn) (interface{}, error) {
+	var raw string
+	if col.Offset < len(data) {
+		raw = data[col.Offset:min(col.Offset+col.Length, len(data))]
+	}
+	raw = strings.TrimSpace(raw)
+	if col.Type != "number" {
+		return raw, nil
+	}
+	if raw == "" {
+		return nil, nil
+	}
+	return parseDisplayNumber(raw, col.Scale, col.Name)
+}
+
+// overpunch maps the last character of a signed DISPLAY number to its digit
+// and sign.
+var overpunch = map[byte]struct {
+	digit    byte
+	negative bool
+}{
+	'{': {'0', false}, 'A': {'1', false}, 'B': {'2', false}, 'C': {'3', false}, 'D': {'4', false},
+	'E': {'5', false}, 'F': {'6', false}, 'G': {'7', false}, 'H': {'8', false}, 'I': {'9', false},
+	'}': {'0', true}, 'J': {'1', true}, 'K': {'2', true}, 'L': {'3', true}, 'M': {'4', true},
+	'N': {'5', true}, 'O': {'6', true}, 'P': {'7', true}, 'Q': {'8', true}, 'R': {'9', true},
+}
+
+// parseDisplayNumber parses a DISPLAY number with an optional leading or
+// trailing sign, an overpunched last digit, and scale implied decimals
+// unless it holds a decimal point.
+func parseDisplayNumber(raw string, scale int, name string) (interface{}, error) {
+	invalid := fmt.Errorf("column %s: invalid number %q", name, raw)
+	raw = strings.ReplaceAll(raw, ",", "")
+	negative := false
+	switch {
+	case strings.HasPrefix(raw, "-"), strings.HasSuffix(raw, "-"):
+		negative = true
+		raw = strings.Trim(raw, "-")
+	case strings.HasPrefix(raw, "+"), strings.HasSuffix(raw, "+"):
+		raw = strings.Trim(raw, "+")
+	}
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, invalid
+	}
+	if p, ok := overpunch[raw[len(raw)-1]]; ok {
+		negative = negative || p.negative
+		raw = raw[:len(raw)-1] + string(p.digit)
+	}
+	if strings.Trim(raw, "0123456789.") != "" || strings.Count(raw, ".") > 1 {
+		return nil, invalid
+	}
+
+	if scale > 0 && !strings.Contains(raw, ".") {
+		// Insert the implied decimal point, so the value rounds as written.
+		raw = strings.Repeat("0", max(scale-len(raw)+1, 0)) + raw
+		raw = raw[:len(raw)-scale] + "." + raw[len(raw)-scale:]
+	}
+	if strings.Contains(raw, ".") {
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, invalid
+		}
+		if negative {
+			f = -f
+		}
+		return f, nil
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return nil, invalid
+	}
+	if negative {
+		n = -n
+	}
+	return n, nil
+}
+
+// Close closes the underlying file.
+func (r *FixedWidthReader) Close() error {
+	return r.file.Close()
+}
@@ -0,0 +1,129 @@
+package datareader
+
+import (
+	"bytes"
+	"data-comparator/internal/pkg/config"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testLayout = `fields:
+  - name: CUST-ID
+    pic: 9(6)
+  - name: FILLER
+    pic: X(2)
+  - name: CUST-NAME
+    pic: X(10)
+  - name: BALANCE
+    pic: S9(5)V99
+  - name: STATUS
+    length: 1
+`
+
+func TestNewFixedWidthReader_Layout(t *testing.T) {
+	dir := t.TempDir()
+	layout := filepath.Join(dir, "customer.yaml")
+	if err := os.WriteFile(layout, []byte(testLayout), 0644); err != nil {
+		t.Fatal(err)
+	}
+	columns, err := LoadFixedWidthLayout(layout)
+	if err != nil {
+		t.Fatalf("LoadFixedWidthLayout() error = %v", err)
+	}
+	wantColumns := []config.FixedWidthColumn{
+		{Name: "CUST-ID", Offset: 0, Length: 6, Type: "number"},
+		{Name: "CUST-NAME", Offset: 8, Length: 10, Type: "string"},
+		{Name: "BALANCE", Offset: 18, Length: 7, Type: "number", Scale: 2},
+		{Name: "STATUS", Offset: 25, Length: 1},
+	}
+	if !reflect.DeepEqual(columns, wantColumns) {
+		t.Errorf("LoadFixedWidthLayout() got = %+v, want %+v", columns, wantColumns)
+	}
+
+	// Mainframe extracts store records back to back; 000123J is -12.31 with the sign overpunched.
+	data := "000001  ALICE     0012345A" + "000002  BOB       000123JC" + "000003            " + "       I"
+	path := filepath.Join(dir, "customers.dat")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := New(config.Source{Type: "fixed_width", Path: path, FixedWidth: &config.FixedWidthConfig{Layout: layout, RecordLength: 26}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	want := []Record{
+		{"CUST-ID": int64(1), "CUST-NAME": "ALICE", "BALANCE": 123.45, "STATUS": "A"},
+		{"CUST-ID": int64(2), "CUST-NAME": "BOB", "BALANCE": -12.31, "STATUS": "C"},
+		{"CUST-ID": int64(3), "CUST-NAME": "", "BALANCE": nil, "STATUS": "I"},
+	}
+	if got := readAllRecords(t, reader); !reflect.DeepEqual(got, want) {
+		t.Errorf("Records got = %v, want %v", got, want)
+	}
+}
+
+func TestNewFromReader_FixedWidthLines(t *testing.T) {
+	input := "  1 widget   9.50\r\n\n  2 gadget  -3.25\n  x bolt     1.00\n  4 nut\n"
+	reader, err := NewFromReader(bytes.NewReader([]byte(input)), config.Source{
+		Type: "fixed_width", Path: "parts.txt",
+		FixedWidth: &config.FixedWidthConfig{Columns: []config.FixedWidthColumn{
+			{Name: "id", Offset: 0, Length: 3, Type: "number"},
+			{Name: "name", Offset: 4, Length: 8},
+			{Name: "price", Offset: 12, Length: 6, Type: "number"},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("NewFromReader() error = %v", err)
+	}
+	defer reader.Close()
+
+	var got []Record
+	var perr *ParseError
+	for {
+		rec, err := reader.Read()
+		if errors.As(err, &perr) {
+			if !perr.Recoverable || perr.Line != 4 || perr.Record != 3 {
+				t.Errorf("Read() error got = %+v, want a recoverable parse error of record 3 on line 4", perr)
+			}
+			continue
+		}
+		if err != nil {
+			break
+		}
+		got = append(got, rec)
+	}
+	want := []Record{
+		{"id": int64(1), "name": "widget", "price": 9.5},
+		{"id": int64(2), "name": "gadget", "price": -3.25},
+		{"id": int64(4), "name": "nut", "price": nil},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Records got = %v, want %v", got, want)
+	}
+}
+
+func TestParsePic(t *testing.T) {
+	tests := []struct {
+		pic    string
+		length int
+		typ    string
+		scale  int
+	}{
+		{"X(10)", 10, "string", 0},
+		{"9(5)", 5, "number", 0},
+		{"S9(7)V99", 9, "number", 2},
+		{"999V9(3)", 6, "number", 3},
+		{"-ZZ,ZZ9.99", 10, "number", 0},
+		{"A(3)X", 4, "string", 0},
+	}
+	for _, tt := range tests {
+		length, typ, scale, err := parsePic(tt.pic)
+		if err != nil || length != tt.length || typ != tt.typ || scale != tt.scale {
+			t.Errorf("parsePic(%s) got = %d, %s, %d, %v, want %d, %s, %d", tt.pic, length, typ, scale, err, tt.length, tt.typ, tt.scale)
+		}
+	}
+	if _, _, _, err := parsePic("9(5) COMP-3"); err == nil {
+		t.Errorf("parsePic() of a packed decimal error got = nil")
+	}
+}
@@ -0,0 +1,252 @@
+package datareader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ftpFile is a file on an FTP server, read in binary passive mode. Reads
+// stream one transfer; ReadAt starts another at the offset, over a new
+// control connection, as servers disagree on the replies to transfers cut
+// short.
+type ftpFile struct {
+	login remoteLogin
+	ctrl  *ftpConn
+	size  int64
+
+	// sequential read state
+	data   net.Conn
+	offset int64
+}
+
+// ftpConn is a logged-in FTP control connection.
+type ftpConn struct {
+	conn net.Conn
+	text *textproto.Conn
+}
+
+func openFTP(login remoteLogin) (*ftpFile, error) {
+	ctrl, err := dialFTP(login)
+	if err != nil {
+		return nil, err
+	}
+	f := &ftpFile{login: login, ctrl: ctrl, size: -1}
+	if _, msg, err := ctrl.cmd(213, "SIZE %s", login.file); err == nil {
+		f.size, _ = strconv.ParseInt(strings.TrimSpace(msg), 10, 64)
+	} else if code := ftpCode(err); code == 0 || code == 550 {
+		ctrl.close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// dialFTP connects to the server of login, logs in and switches to binary
+// mode. Without a username it logs in as anonymous.
+func dialFTP(login remoteLogin) (*ftpConn, error) {
+	port := login.port
+	if port == "" {
+		port = "21"
+	}
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(login.host, port), remoteTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &ftpConn{conn: conn, text: textproto.NewConn(conn)}
+	if err := c.login(login); err != nil {
+		c.conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *ftpConn) login(login remoteLogin) error {
+	c.conn.SetDeadline(time.Now().Add(remoteTimeout))
+	if _, _, err := c.text.ReadResponse(220); err != nil {
+		return err
+	}
+	username, password := login.username, login.password
+	if username == "" {
+		username, password = "anonymous", firstNonEmpty(password, "anonymous@")
+	}
+	code, _, err := c.cmd(0, "USER %s", username)
+	if err != nil {
+		return err
+	}
+	switch code {
+	case 230:
+	case 331:
+		if _, _, err := c.cmd(230, "PASS %s", password); err != nil {
+			return fmt.Errorf("login as %s failed: %w", username, err)
+		}
+	default:
+		return fmt.Errorf("login as %s failed: unexpected reply %d", username, code)
+	}
+	_, _, err = c.cmd(200, "TYPE I")
+	return err
+}
+
+// cmd sends a command and reads its reply, which must have code expect
+// unless it is 0.
+func (c *ftpConn) cmd(expect int, format string, args ...interface{}) (int, string, error) {
+	c.conn.SetDeadline(time.Now().Add(remoteTimeout))
+	if err := c.text.PrintfLine(format, args...); err != nil {
+		return 0, "", err
+	}
+	return c.text.ReadResponse(expect)
+}
+
+// retrieve starts the transfer of file from offset and returns its data
+// connection.
+func (c *ftpConn) retrieve(file string, offset int64) (net.Conn, error) {
+	data, err := c.passive()
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		if _, _, err := c.cmd(350, "REST %d", offset); err != nil {
+			data.Close()
+			return nil, err
+		}
+	}
+	code, msg, err := c.cmd(0, "RETR %s", file)
+	if err == nil && code != 125 && code != 150 {
+		err = &textproto.Error{Code: code, Msg: msg}
+	}
+	if err != nil {
+		data.Close()
+		return nil, err
+	}
+	// Transfers take as long as the data they hold.
+	c.conn.SetDeadline(time.Time{})
+	return data, nil
+}
+
+// finish reads the reply ending a transfer.
+func (c *ftpConn) finish() error {
+	c.conn.SetDeadline(time.Now().Add(remoteTimeout))
+	_, _, err := c.text.ReadResponse(2)
+	return err
+}
+
+// passive opens a data connection, with EPSV or else PASV. The data
+// connection goes to the host of the control connection, whatever address
+// PASV replies with, as servers behind NAT often announce private ones.
+func (c *ftpConn) passive() (net.Conn, error) {
+	host, _, _ := net.SplitHostPort(c.conn.RemoteAddr().String())
+	var port int
+	if _, msg, err := c.cmd(229, "EPSV"); err == nil {
+		start, end := strings.Index(msg, "(|||"), strings.LastIndex(msg, "|)")
+		if start < 0 || end < start+4 {
+			return nil, fmt.Errorf("invalid EPSV reply %q", msg)
+		}
+		if port, err = strconv.Atoi(msg[start+4 : end]); err != nil {
+			return nil, fmt.Errorf("invalid EPSV reply %q", msg)
+		}
+	} else {
+		_, msg, err := c.cmd(227, "PASV")
+		if err != nil {
+			return nil, err
+		}
+		start, end := strings.Index(msg, "("), strings.Index(msg, ")")
+		if start < 0 || end < start {
+			return nil, fmt.Errorf("invalid PASV reply %q", msg)
+		}
+		fields := strings.Split(msg[start+1:end], ",")
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("invalid PASV reply %q", msg)
+		}
+		high, err1 := strconv.Atoi(fields[4])
+		low, err2 := strconv.Atoi(fields[5])
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("invalid PASV reply %q", msg)
+		}
+		port = high<<8 | low
+	}
+	return net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), remoteTimeout)
+}
+
+func (c *ftpConn) close() {
+	c.conn.SetDeadline(time.Now().Add(remoteTimeout))
+	c.text.PrintfLine("QUIT")
+	c.text.Close()
+}
+
+// ftpCode returns the reply code of an FTP error, 0 if it is not a reply.
+func ftpCode(err error) int {
+	var reply *textproto.Error
+	if errors.As(err, &reply) {
+		return reply.Code
+	}
+	return 0
+}
+
+// Read streams the file in a single transfer.
+func (f *ftpFile) Read(p []byte) (int, error) {
+	if f.data == nil {
+		if f.size >= 0 && f.offset >= f.size {
+			return 0, io.EOF
+		}
+		data, err := f.ctrl.retrieve(f.login.file, f.offset)
+		if err != nil {
+			return 0, err
+		}
+		f.data = data
+	}
+	n, err := f.data.Read(p)
+	f.offset += int64(n)
+	if err == io.EOF {
+		f.data.Close()
+		f.data = nil
+		if err := f.ctrl.finish(); err != nil {
+			return n, err
+		}
+		if f.size >= 0 && f.offset < f.size {
+			return n, fmt.Errorf("transfer ended at %d of %d bytes: %w", f.offset, f.size, io.ErrUnexpectedEOF)
+		}
+		// Without a size, the end of the transfer is the end of the file.
+		f.size = f.offset
+	}
+	return n, err
+}
+
+// ReadAt transfers len(p) bytes from off, cutting the transfer short.
+func (f *ftpFile) ReadAt(p []byte, off int64) (int, error) {
+	at, err := dialFTP(f.login)
+	if err != nil {
+		return 0, err
+	}
+	defer at.close()
+	data, err := at.retrieve(f.login.file, off)
+	if err != nil {
+		return 0, err
+	}
+	defer data.Close()
+	n, err := io.ReadFull(data, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Size returns the size the server reported with SIZE.
+func (f *ftpFile) Size() (int64, error) {
+	if f.size < 0 {
+		return 0, fmt.Errorf("the FTP server does not report the size of %s", f.login.file)
+	}
+	return f.size, nil
+}
+
+// Close ends an unfinished transfer and logs out.
+func (f *ftpFile) Close() error {
+	if f.data != nil {
+		f.data.Close()
+	}
+	f.ctrl.close()
+	return nil
+}
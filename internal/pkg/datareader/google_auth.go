@@ -0,0 +1,214 @@
+package datareader
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OAuth scopes of BigQuery reads and queries, and of Cloud Storage reads.
+const (
+	bigqueryScope = "https://www.googleapis.com/auth/bigquery"
+	storageScope  = "https://www.googleapis.com/auth/devstorage.read_only"
+)
+
+// googleTokenURL is the default OAuth token endpoint of Google accounts.
+const googleTokenURL = "https://oauth2.googleapis.com/token"
+
+// googleMetadataURL returns the access token of the default service account
+// on Google Cloud compute.
+var googleMetadataURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// googleCredentials are the application default credentials of a service
+// account key or of a user logged in with gcloud.
+type googleCredentials struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// googleTokens hands out OAuth access tokens following Google's application
+// default credentials: the GOOGLE_OAUTH_ACCESS_TOKEN environment variable,
+// the key file named by GOOGLE_APPLICATION_CREDENTIALS, the credentials of
+// gcloud auth application-default login, and finally the metadata server.
+// A configured credentials file takes precedence over all of them. Tokens
+// are cached until shortly before they expire.
+type googleTokens struct {
+	client          *http.Client
+	scope           string
+	credentialsFile string
+	mu              sync.Mutex
+	token           string
+	expires         time.Time
+}
+
+func newGoogleTokens(client *http.Client, scope, credentialsFile string) *googleTokens {
+	return &googleTokens{client: client, scope: scope, credentialsFile: credentialsFile}
+}
+
+// get returns a valid access token, fetching a new one when needed.
+func (g *googleTokens) get() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.token != "" && time.Now().Before(g.expires) {
+		return g.token, nil
+	}
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" && g.credentialsFile == "" {
+		return token, nil
+	}
+
+	var token string
+	var lifetime time.Duration
+	creds, err := loadGoogleCredentials(g.credentialsFile)
+	switch {
+	case err != nil:
+		return "", err
+	case creds == nil:
+		token, lifetime, err = g.fromMetadata()
+		if err != nil {
+			return "", fmt.Errorf("no google credentials found: set GOOGLE_APPLICATION_CREDENTIALS or GOOGLE_OAUTH_ACCESS_TOKEN, or run gcloud auth application-default login (%w)", err)
+		}
+	case creds.Type == "service_account":
+		token, lifetime, err = g.fromServiceAccount(creds)
+	case creds.Type == "authorized_user":
+		token, lifetime, err = g.exchange(googleTokenURL, url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {creds.ClientID},
+			"client_secret": {creds.ClientSecret},
+			"refresh_token": {creds.RefreshToken},
+		})
+	default:
+		return "", fmt.Errorf("unsupported google credentials type %q", creds.Type)
+	}
+	if err != nil {
+		return "", err
+	}
+	g.token, g.expires = token, time.Now().Add(lifetime-time.Minute)
+	return token, nil
+}
+
+// loadGoogleCredentials reads the credentials file at path, or the default
+// one if path is empty, or returns nil if there is none.
+func loadGoogleCredentials(path string) (*googleCredentials, error) {
+	if path == "" {
+		path = os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	}
+	if path == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, nil
+		}
+		path = filepath.Join(dir, "gcloud", "application_default_credentials.json")
+		if _, err := os.Stat(path); err != nil {
+			return nil, nil
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read google credentials: %w", err)
+	}
+	var creds googleCredentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("failed to parse google credentials %s: %w", path, err)
+	}
+	return &creds, nil
+}
+
+// fromServiceAccount exchanges a JWT signed with the service account's key
+// for an access token.
+func (g *googleTokens) fromServiceAccount(creds *googleCredentials) (string, time.Duration, error) {
+	block, _ := pem.Decode([]byte(creds.PrivateKey))
+	if block == nil {
+		return "", 0, fmt.Errorf("service account %s has no PEM private key", creds.ClientEmail)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return "", 0, fmt.Errorf("failed to parse private key of service account %s: %w", creds.ClientEmail, err)
+		}
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", 0, fmt.Errorf("service account %s does not have an RSA key", creds.ClientEmail)
+	}
+
+	tokenURI := creds.TokenURI
+	if tokenURI == "" {
+		tokenURI = googleTokenURL
+	}
+	now := time.Now()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss": creds.ClientEmail, "scope": g.scope, "aud": tokenURI,
+		"iat": now.Unix(), "exp": now.Add(time.Hour).Unix(),
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to sign token request: %w", err)
+	}
+	return g.exchange(tokenURI, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)},
+	})
+}
+
+// exchange posts a token request and returns the granted token and its lifetime.
+func (g *googleTokens) exchange(tokenURL string, form url.Values) (string, time.Duration, error) {
+	resp, err := g.client.PostForm(tokenURL, form)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to request google access token: %w", err)
+	}
+	defer resp.Body.Close()
+	return decodeGoogleToken(resp)
+}
+
+func (g *googleTokens) fromMetadata() (string, time.Duration, error) {
+	req, err := http.NewRequest(http.MethodGet, googleMetadataURL, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	client := &http.Client{Timeout: 3 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	return decodeGoogleToken(resp)
+}
+
+func decodeGoogleToken(resp *http.Response) (string, time.Duration, error) {
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", 0, fmt.Errorf("failed to decode google access token: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || body.AccessToken == "" {
+		return "", 0, fmt.Errorf("google access token request failed: %s %s", resp.Status,
+			strings.TrimSpace(body.Error+" "+body.Description))
+	}
+	return body.AccessToken, time.Duration(body.ExpiresIn) * time.Second, nil
+}
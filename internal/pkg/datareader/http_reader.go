@@ -0,0 +1,246 @@
+package datareader
+
+import (
+	"data-comparator/internal/pkg/config"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultHTTPRetries is the default of config.HTTPConfig.Retries.
+const DefaultHTTPRetries = 3
+
+// httpRetryDelay is the wait before the first retry of a request; each
+// further retry waits as long again.
+var httpRetryDelay = time.Second
+
+// HTTPFormats lists the formats the response of an http source can be read
+// as.
+var HTTPFormats = []string{"csv", "json", "parquet", "avro", "protobuf", "xml", "fixed_width", "xlsx", "logfile", "syslog", "journald", "auto"}
+
+// httpFormats are the source types of response Content-Types.
+var httpFormats = map[string]string{
+	"text/csv":                       "csv",
+	"application/csv":                "csv",
+	"application/json":               "json",
+	"application/x-ndjson":           "json",
+	"application/jsonl":              "json",
+	"application/xml":                "xml",
+	"text/xml":                       "xml",
+	"application/vnd.apache.parquet": "parquet",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": "xlsx",
+}
+
+// IsHTTPPath reports whether path is an http:// or https:// URL.
+func IsHTTPPath(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// NewHTTPReader gets the URL at cfg.Path and streams the response body
+// through the reader of its format. A response cut off midway is resumed
+// where it stopped.
+func NewHTTPReader(cfg config.Source) (DataReader, error) {
+	if !IsHTTPPath(cfg.Path) {
+		return nil, fmt.Errorf("http source path %s is not an http:// or https:// URL", cfg.Path)
+	}
+	httpCfg := cfg.HTTP
+	if httpCfg == nil {
+		httpCfg = &config.HTTPConfig{}
+	}
+	body, err := openHTTPBody(cfg.Path, httpCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Type = httpCfg.Format
+	if cfg.Type == "" {
+		cfg.Type = "auto"
+		if mediaType, _, err := mime.ParseMediaType(body.contentType); err == nil && httpFormats[mediaType] != "" {
+			cfg.Type = httpFormats[mediaType]
+		}
+	}
+	return NewFromReader(body, cfg)
+}
+
+// RedactHTTP returns a copy of cfg without its secrets, for reports. Values
+// that reference environment variables are kept.
+func RedactHTTP(cfg *config.HTTPConfig) *config.HTTPConfig {
+	if cfg == nil {
+		return nil
+	}
+	redact := func(value string) string {
+		if value == "" || strings.HasPrefix(value, "$") {
+			return value
+		}
+		return "xxxxx"
+	}
+	redacted := *cfg
+	redacted.BearerToken, redacted.Password = redact(cfg.BearerToken), redact(cfg.Password)
+	if cfg.Headers != nil {
+		redacted.Headers = make(map[string]string, len(cfg.Headers))
+		for name, value := range cfg.Headers {
+			redacted.Headers[name] = redact(value)
+		}
+	}
+	return &redacted
+}
+
+// httpBody is the body of a GET response, which it requests again from
+// where it stopped if it is cut off.
+type httpBody struct {
+	url         string
+	cfg         *config.HTTPConfig
+	client      *http.Client
+	body        io.ReadCloser
+	contentType string
+	// etag and lastModified identify the version of the resource read, so
+	// that a resumed response is known to continue it.
+	etag, lastModified string
+	// offset is the number of bytes read so far.
+	offset int64
+	// retries is the number of retries left.
+	retries int
+}
+
+func openHTTPBody(url string, cfg *config.HTTPConfig) (*httpBody, error) {
+	b := &httpBody{
+		url: url,
+		cfg: cfg,
+		// Transparent decompression would make offsets count decoded bytes,
+		// which Range requests cannot resume from. Compressed files are
+		// still decompressed by their reader.
+		client:  &http.Client{Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, DisableCompression: true}},
+		retries: cfg.Retries,
+	}
+	if b.retries == 0 {
+		b.retries = DefaultHTTPRetries
+	}
+	resp, err := b.get()
+	if err != nil {
+		return nil, err
+	}
+	b.body = resp.Body
+	b.contentType = resp.Header.Get("Content-Type")
+	b.etag, b.lastModified = resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	return b, nil
+}
+
+// get sends the request for the body from offset, retrying server errors
+// and failed connections.
+func (b *httpBody) get() (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := b.do()
+		if err == nil {
+			return resp, nil
+		}
+		var statusErr *httpStatusError
+		retryable := !errors.As(err, &statusErr) || statusErr.code >= 500 || statusErr.code == http.StatusTooManyRequests
+		if !retryable || b.retries <= 0 {
+			return nil, fmt.Errorf("failed to get %s: %w", b.url, err)
+		}
+		b.retries--
+		time.Sleep(time.Duration(attempt) * httpRetryDelay)
+	}
+}
+
+func (b *httpBody) do() (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, b.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range b.cfg.Headers {
+		req.Header.Set(name, os.ExpandEnv(value))
+	}
+	if b.cfg.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+os.ExpandEnv(b.cfg.BearerToken))
+	}
+	if b.cfg.Username != "" {
+		req.SetBasicAuth(b.cfg.Username, os.ExpandEnv(b.cfg.Password))
+	}
+	if b.offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", b.offset))
+		// The range is only sent if the resource is unchanged.
+		if b.etag != "" {
+			req.Header.Set("If-Range", b.etag)
+		} else if b.lastModified != "" {
+			req.Header.Set("If-Range", b.lastModified)
+		}
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return nil, &httpStatusError{status: resp.Status, code: resp.StatusCode}
+	}
+	return resp, nil
+}
+
+// Read reads from the response, resuming it if it is cut off.
+func (b *httpBody) Read(p []byte) (int, error) {
+	for {
+		n, err := b.body.Read(p)
+		b.offset += int64(n)
+		if err == nil || err == io.EOF || b.retries <= 0 {
+			return n, err
+		}
+		b.body.Close()
+		if resumeErr := b.resume(); resumeErr != nil {
+			return n, fmt.Errorf("failed to read %s: %w (resuming: %v)", b.url, err, resumeErr)
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// resume requests the rest of the body from offset.
+func (b *httpBody) resume() error {
+	b.retries--
+	resp, err := b.get()
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusOK {
+		// The server ignored the range, or the resource changed.
+		if b.etag != "" && resp.Header.Get("ETag") != b.etag {
+			resp.Body.Close()
+			return fmt.Errorf("%s changed while it was read", b.url)
+		}
+		if _, err := io.CopyN(io.Discard, resp.Body, b.offset); err != nil {
+			resp.Body.Close()
+			return err
+		}
+	}
+	b.body = resp.Body
+	return nil
+}
+
+func (b *httpBody) Close() error {
+	return b.body.Close()
+}
+
+// httpStatusError is an unsuccessful response status.
+type httpStatusError struct {
+	status string
+	code   int
+}
+
+func (e *httpStatusError) Error() string {
+	return "server returned " + e.status
+}
+
+// Unwrap makes missing resources match os.ErrNotExist, like missing files.
+func (e *httpStatusError) Unwrap() error {
+	if e.code == http.StatusNotFound {
+		return os.ErrNotExist
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package datareader
+
+import (
+	"bytes"
+	"data-comparator/internal/pkg/config"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func noHTTPRetryDelay(t *testing.T) {
+	previous := httpRetryDelay
+	httpRetryDelay = 0
+	t.Cleanup(func() { httpRetryDelay = previous })
+}
+
+func TestNewHTTPReader(t *testing.T) {
+	t.Setenv("TEST_API_TOKEN", "secret")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization got = %q, want %q", got, "Bearer secret")
+		}
+		if got := r.Header.Get("X-Tenant"); got != "acme" {
+			t.Errorf("X-Tenant got = %q, want %q", got, "acme")
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprintln(w, `{"id": 1, "name": "a"}`)
+		fmt.Fprintln(w, `{"id": 2, "name": "b"}`)
+	}))
+	defer server.Close()
+
+	reader, err := New(config.Source{Type: "http", Path: server.URL + "/export", HTTP: &config.HTTPConfig{
+		Headers:     map[string]string{"X-Tenant": "acme"},
+		BearerToken: "${TEST_API_TOKEN}",
+	}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	got := readAllRecords(t, reader)
+	want := []Record{{"id": float64(1), "name": "a"}, {"id": float64(2), "name": "b"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("records got = %v, want %v", got, want)
+	}
+}
+
+func TestNewHTTPReader_Resume(t *testing.T) {
+	noHTTPRetryDelay(t)
+	var data bytes.Buffer
+	data.WriteString("id,name\n")
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&data, "%d,name-%d\n", i, i)
+	}
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Header.Get("Range"))
+		w.Header().Set("ETag", `"v1"`)
+		if len(requests) == 1 {
+			// Cut the connection off halfway through the body.
+			w.Header().Set("Content-Length", fmt.Sprint(data.Len()))
+			w.Write(data.Bytes()[:data.Len()/2])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data.Bytes()))
+	}))
+	defer server.Close()
+
+	reader, err := New(config.Source{Type: "http", Path: server.URL, HTTP: &config.HTTPConfig{Format: "csv"}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	got := readAllRecords(t, reader)
+	if len(got) != 1000 || got[999]["name"] != "name-999" {
+		t.Errorf("records got %d, last %v, want 1000 ending with name-999", len(got), got[len(got)-1])
+	}
+	if len(requests) != 2 || !strings.HasPrefix(requests[1], "bytes=") {
+		t.Errorf("Range headers got = %q, want a second request resuming the body", requests)
+	}
+}
+
+func TestNewHTTPReader_Errors(t *testing.T) {
+	noHTTPRetryDelay(t)
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	_, err := New(config.Source{Type: "http", Path: server.URL + "/missing"})
+	if !errors.Is(err, os.ErrNotExist) || attempts != 1 {
+		t.Errorf("New() of a missing URL got error %v after %d attempts, want os.ErrNotExist after 1", err, attempts)
+	}
+	attempts = 0
+	_, err = New(config.Source{Type: "http", Path: server.URL, HTTP: &config.HTTPConfig{Retries: 2}})
+	if err == nil || attempts != 3 {
+		t.Errorf("New() of an unavailable URL got error %v after %d attempts, want an error after 3", err, attempts)
+	}
+}
+
+func TestRedactHTTP(t *testing.T) {
+	got := RedactHTTP(&config.HTTPConfig{
+		Headers:     map[string]string{"X-Api-Key": "literal", "X-Tenant-Key": "${TENANT_KEY}"},
+		BearerToken: "token",
+		Username:    "user",
+		Password:    "$PASSWORD",
+	})
+	want := &config.HTTPConfig{
+		Headers:     map[string]string{"X-Api-Key": "xxxxx", "X-Tenant-Key": "${TENANT_KEY}"},
+		BearerToken: "xxxxx",
+		Username:    "user",
+		Password:    "$PASSWORD",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RedactHTTP() got = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,125 @@
+package datareader
+
+import (
+	"bufio"
+	"data-comparator/internal/pkg/config"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxJournalField bounds the size of a binary field of a journal entry.
+const maxJournalField = 64 << 20
+
+// JournaldReader reads the systemd journal export format, as written by
+// journalctl -o export: one record per entry, of its fields, such as
+// MESSAGE, _PID and __CURSOR, as strings. Fields repeated in an entry become
+// arrays.
+type JournaldReader struct {
+	path    string
+	file    io.Closer
+	input   *bufio.Reader
+	records int
+	offset  int64
+}
+
+// NewJournaldReader opens the journal export file of cfg. Files may be
+// gzip, zstd or bzip2 compressed.
+func NewJournaldReader(cfg config.Source) (DataReader, error) {
+	file, err := openSource(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal export %s: %w", cfg.Path, err)
+	}
+	input, closer, _, err := decompress(file, file, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newJournaldReader(input, closer, cfg), nil
+}
+
+func newJournaldReader(input io.Reader, closer io.Closer, cfg config.Source) *JournaldReader {
+	return &JournaldReader{path: cfg.Path, file: closer, input: bufio.NewReader(input)}
+}
+
+// Read returns the record of the next entry, or io.EOF at the end of the
+// export. Entries end at a blank line. Text fields are NAME=value lines;
+// binary ones a NAME line followed by the little-endian 64-bit size of the
+// value, the value and a newline.
+func (r *JournaldReader) Read() (Record, error) {
+	rec := make(Record)
+	start := r.offset
+	for {
+		line, err := r.input.ReadString('\n')
+		r.offset += int64(len(line))
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read %s: %w", r.path, err)
+		}
+		name := strings.TrimSuffix(line, "\n")
+		if name == "" {
+			if len(rec) > 0 {
+				r.records++
+				return rec, nil
+			}
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			start = r.offset
+			continue
+		}
+
+		if name, value, ok := strings.Cut(name, "="); ok {
+			addJournalField(rec, name, value)
+		} else if value, binErr := r.readBinary(); binErr != nil {
+			r.records++
+			return nil, &ParseError{Source: r.path, Record: r.records, Offset: start, Snippet: truncate(name), Err: fmt.Errorf("field %s: %w", name, binErr)}
+		} else {
+			addJournalField(rec, name, value)
+		}
+		if err == io.EOF {
+			// The last entry may lack its blank line.
+			r.records++
+			return rec, nil
+		}
+	}
+}
+
+// readBinary reads the size and value of a binary field.
+func (r *JournaldReader) readBinary() (string, error) {
+	var size uint64
+	if err := binary.Read(r.input, binary.LittleEndian, &size); err != nil {
+		return "", fmt.Errorf("size cut off: %w", unexpectedEOF(err))
+	}
+	r.offset += 8
+	if size > maxJournalField {
+		return "", fmt.Errorf("size %d exceeds %d", size, maxJournalField)
+	}
+	value := make([]byte, size+1)
+	n, err := io.ReadFull(r.input, value)
+	r.offset += int64(n)
+	if err != nil {
+		return "", fmt.Errorf("value of %d bytes cut off: %w", size, unexpectedEOF(err))
+	}
+	if value[size] != '\n' {
+		return "", fmt.Errorf("value of %d bytes not followed by a newline", size)
+	}
+	return string(value[:size]), nil
+}
+
+// addJournalField adds a value of field name to rec, making an array of
+// the values of repeated fields.
+func addJournalField(rec Record, name, value string) {
+	switch existing := rec[name].(type) {
+	case nil:
+		rec[name] = value
+	case []interface{}:
+		rec[name] = append(existing, value)
+	default:
+		rec[name] = []interface{}{existing, value}
+	}
+}
+
+// Close closes the underlying file.
+func (r *JournaldReader) Close() error {
+	return r.file.Close()
+}
@@ -0,0 +1,54 @@
+package datareader
+
+import (
+	"bytes"
+	"data-comparator/internal/pkg/config"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func journalBinaryField(name, value string) string {
+	var b bytes.Buffer
+	b.WriteString(name + "\n")
+	binary.Write(&b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value + "\n")
+	return b.String()
+}
+
+func TestJournaldReader(t *testing.T) {
+	export := "__CURSOR=s=1;i=1\n__REALTIME_TIMESTAMP=1700000000000000\n_PID=42\nMESSAGE=started\n\n" +
+		"__CURSOR=s=1;i=2\n" + journalBinaryField("MESSAGE", "line one\nline two") + "TAG=a\nTAG=b\nTAG=c\n\n\n" +
+		"__CURSOR=s=1;i=3\nMESSAGE=no trailing blank line"
+	reader, err := NewFromReader(strings.NewReader(export), config.Source{Type: "journald", Path: "journal.export"})
+	if err != nil {
+		t.Fatalf("NewFromReader() error = %v", err)
+	}
+	records, errs := readAll(t, reader)
+	if len(errs) != 0 {
+		t.Fatalf("Read() errors = %v", errs)
+	}
+	want := []Record{
+		{"__CURSOR": "s=1;i=1", "__REALTIME_TIMESTAMP": "1700000000000000", "_PID": "42", "MESSAGE": "started"},
+		{"__CURSOR": "s=1;i=2", "MESSAGE": "line one\nline two", "TAG": []interface{}{"a", "b", "c"}},
+		{"__CURSOR": "s=1;i=3", "MESSAGE": "no trailing blank line"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records got = %v, want %v", records, want)
+	}
+}
+
+func TestJournaldReader_Truncated(t *testing.T) {
+	export := "__CURSOR=s=1;i=1\n" + journalBinaryField("MESSAGE", "complete")[:15]
+	reader, err := NewFromReader(strings.NewReader(export), config.Source{Type: "journald", Path: "journal.export"})
+	if err != nil {
+		t.Fatalf("NewFromReader() error = %v", err)
+	}
+	_, errs := readAll(t, reader)
+	var perr *ParseError
+	if len(errs) != 1 || !errors.As(errs[0], &perr) || perr.Recoverable || !strings.Contains(perr.Error(), "cut off") {
+		t.Errorf("errors got = %v, want an unrecoverable parse error of a value cut off", errs)
+	}
+}
@@ -1,42 +1,187 @@
 package datareader
 
 import (
+	"bufio"
 	"data-comparator/internal/pkg/config"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 )
 
-// JSONReader reads records from a JSON-Lines file.
+// JSONNumberModes lists the values of config.ParserConfig.JSONNumbers.
+var JSONNumberModes = []string{"float", "exact"}
+
+// exactJSONNumbers reports whether the JSON numbers of pcfg are decoded as
+// json.Number rather than float64.
+func exactJSONNumbers(pcfg *config.ParserConfig) bool {
+	return pcfg != nil && pcfg.JSONNumbers == "exact"
+}
+
+// JSONReader reads records from a JSON-Lines file or a file holding a single
+// JSON array of objects.
 type JSONReader struct {
-	file    *os.File
+	path    string
+	records int
+	file    io.Closer
+	isFile  bool
 	decoder *json.Decoder
+	inArray bool
+	// base is the offset in the input the decoder started at.
+	base int64
+	// seeker is the file, if local and uncompressed, which Resume seeks.
+	seeker       io.ReadSeeker
+	exactNumbers bool
 }
 
-// NewJSONReader creates a new reader for JSON-Lines files.
+// NewJSONReader creates a new reader for JSON-Lines files. Files whose first
+// non-whitespace character is '[' are read as a JSON array of records.
+// Files may be gzip, zstd or bzip2 compressed.
 func NewJSONReader(cfg config.Source) (DataReader, error) {
-	file, err := os.Open(cfg.Path)
+	file, err := openSource(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open json file %s: %w", cfg.Path, err)
 	}
+	input, closer, compressed, err := decompress(file, file, cfg)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newJSONReader(input, closer, cfg)
+	if err != nil {
+		return nil, err
+	}
+	// Offsets into decompressed data cannot be located in the file.
+	r.isFile = !IsObjectPath(cfg.Path) && !IsRemotePath(cfg.Path) && !compressed
+	if f, ok := file.(localFile); ok && !compressed {
+		r.seeker = f
+	}
+	return r, nil
+}
+
+// newJSONReader reads JSON from input, named by cfg.Path, and closes closer
+// when done or on error.
+func newJSONReader(input io.Reader, file io.Closer, cfg config.Source) (*JSONReader, error) {
+	buffered := bufio.NewReader(input)
+	r := &JSONReader{
+		path:         cfg.Path,
+		file:         file,
+		exactNumbers: exactJSONNumbers(cfg.ParserConfig),
+	}
+	r.decoder = r.newDecoder(buffered)
+
+	first, skipped, err := peekNonSpace(buffered)
+	r.base = skipped
+	if err != nil && err != io.EOF {
+		file.Close()
+		return nil, fmt.Errorf("failed to read json file %s: %w", cfg.Path, err)
+	}
+	if first == '[' {
+		if _, err := r.decoder.Token(); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to read json array from %s: %w", cfg.Path, err)
+		}
+		r.inArray = true
+	}
 
-	return &JSONReader{
-		file:    file,
-		decoder: json.NewDecoder(file),
-	}, nil
+	return r, nil
 }
 
-// Read reads the next record from the JSON-Lines file.
+// Read reads the next record from the JSON-Lines file or array.
 func (r *JSONReader) Read() (Record, error) {
+	if r.inArray && !r.decoder.More() {
+		return nil, io.EOF
+	}
+
 	var record Record
 	err := r.decoder.Decode(&record) // Decode will return io.EOF at the end.
-	if err != nil {
+	if err == io.EOF {
 		return nil, err
 	}
+	r.records++
+	if err != nil {
+		return nil, r.parseError(err)
+	}
 	return record, nil
 }
 
-// Close closes the underlying file.
+func (r *JSONReader) newDecoder(input io.Reader) *json.Decoder {
+	decoder := json.NewDecoder(input)
+	if r.exactNumbers {
+		decoder.UseNumber()
+	}
+	return decoder
+}
+
+func (r *JSONReader) parseError(err error) error {
+	offset := r.decoder.InputOffset()
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	}
+	offset += r.base
+	if !r.isFile {
+		return &ParseError{Source: r.path, Record: r.records, Offset: offset, Err: err}
+	}
+	if f, ok := r.file.(*os.File); ok && err == io.ErrUnexpectedEOF {
+		// The offset of a truncated record is the end of the file
+		if info, statErr := f.Stat(); statErr == nil {
+			offset = info.Size()
+		}
+	}
+
+	line, snippet := locateOffset(r.path, max(offset-1, 0))
+	return &ParseError{Source: r.path, Record: r.records, Line: line, Offset: offset, Snippet: snippet, Err: err}
+}
+
+// Position returns the number of records read and the offset after the last.
+func (r *JSONReader) Position() Position {
+	return Position{Records: r.records, Offset: r.base + r.decoder.InputOffset()}
+}
+
+// Resume continues after the records up to pos, seeking a local JSON-Lines
+// file to its offset. The records of an array are read up to pos.
+func (r *JSONReader) Resume(pos Position) error {
+	if r.records > 0 {
+		return fmt.Errorf("cannot resume %s after reading from it", r.path)
+	}
+	if r.seeker == nil || r.inArray {
+		return skipRecords(r, pos.Records)
+	}
+	if _, err := r.seeker.Seek(pos.Offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to resume %s at offset %d: %w", r.path, pos.Offset, err)
+	}
+	r.decoder = r.newDecoder(bufio.NewReader(r.seeker))
+	r.records, r.base = pos.Records, pos.Offset
+	return nil
+}
+
+// Close closes the underlying file or stream.
 func (r *JSONReader) Close() error {
 	return r.file.Close()
 }
+
+// peekNonSpace returns the first non-whitespace byte without consuming it,
+// and the number of whitespace bytes consumed before it.
+func peekNonSpace(r *bufio.Reader) (byte, int64, error) {
+	var skipped int64
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return 0, skipped, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			if _, err := r.ReadByte(); err != nil {
+				return 0, skipped, err
+			}
+			skipped++
+		default:
+			return b[0], skipped, nil
+		}
+	}
+}
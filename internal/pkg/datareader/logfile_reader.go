@@ -0,0 +1,308 @@
+package datareader
+
+import (
+	"bufio"
+	"data-comparator/internal/pkg/config"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// LogUnmatchedModes lists what can happen to log lines no pattern matches,
+// the default first.
+var LogUnmatchedModes = []string{"skip", "error", "keep"}
+
+// grokPatterns are the built-in grok patterns, after those of Logstash,
+// without the lookarounds RE2 lacks.
+var grokPatterns = map[string]string{
+	"USERNAME":          `[a-zA-Z0-9._-]+`,
+	"USER":              `%{USERNAME}`,
+	"INT":               `(?:[+-]?[0-9]+)`,
+	"BASE10NUM":         `(?:[+-]?(?:[0-9]+(?:\.[0-9]+)?|\.[0-9]+))`,
+	"NUMBER":            `%{BASE10NUM}`,
+	"POSINT":            `\b[1-9][0-9]*\b`,
+	"NONNEGINT":         `\b[0-9]+\b`,
+	"WORD":              `\b\w+\b`,
+	"NOTSPACE":          `\S+`,
+	"SPACE":             `\s*`,
+	"DATA":              `.*?`,
+	"GREEDYDATA":        `.*`,
+	"QUOTEDSTRING":      `(?:"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*')`,
+	"QS":                `%{QUOTEDSTRING}`,
+	"UUID":              `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+	"IPV4":              `(?:(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])\.){3}(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])`,
+	"IPV6":              `(?:[0-9A-Fa-f]{0,4}:){2,7}[0-9A-Fa-f]{0,4}`,
+	"IP":                `(?:%{IPV6}|%{IPV4})`,
+	"HOSTNAME":          `\b[0-9A-Za-z][0-9A-Za-z-]{0,62}(?:\.[0-9A-Za-z][0-9A-Za-z-]{0,62})*\.?\b`,
+	"IPORHOST":          `(?:%{IP}|%{HOSTNAME})`,
+	"HOSTPORT":          `%{IPORHOST}:%{POSINT}`,
+	"PATH":              `(?:/[^\s]*)+`,
+	"URIPATH":           `(?:/[A-Za-z0-9$.+!*'(){},~:;=@#%&_\-]*)+`,
+	"URIPARAM":          `\?[A-Za-z0-9$.+!*'|(){},~@#%&/=:;_?\-\[\]<>]*`,
+	"URIPATHPARAM":      `%{URIPATH}(?:%{URIPARAM})?`,
+	"LOGLEVEL":          `(?:[Tt]race|TRACE|[Dd]ebug|DEBUG|[Nn]otice|NOTICE|[Ii]nfo|INFO|[Ww]arn(?:ing)?|WARN(?:ING)?|[Ee]rr(?:or)?|ERR(?:OR)?|[Cc]rit(?:ical)?|CRIT(?:ICAL)?|[Ff]atal|FATAL|[Ss]evere|SEVERE|[Aa]lert|ALERT|[Ee]merg(?:ency)?|EMERG(?:ENCY)?)`,
+	"YEAR":              `(?:\d\d){1,2}`,
+	"MONTH":             `\b(?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec)[a-z]*\b`,
+	"MONTHNUM":          `(?:0?[1-9]|1[0-2])`,
+	"MONTHDAY":          `(?:0[1-9]|[12][0-9]|3[01]|[1-9])`,
+	"HOUR":              `(?:2[0123]|[01]?[0-9])`,
+	"MINUTE":            `[0-5][0-9]`,
+	"SECOND":            `(?:(?:[0-5]?[0-9]|60)(?:[:.,][0-9]+)?)`,
+	"TIME":              `%{HOUR}:%{MINUTE}(?::%{SECOND})?`,
+	"ISO8601_TIMEZONE":  `(?:Z|[+-]%{HOUR}(?::?%{MINUTE}))`,
+	"TIMESTAMP_ISO8601": `%{YEAR}-%{MONTHNUM}-%{MONTHDAY}[T ]%{HOUR}:?%{MINUTE}(?::?%{SECOND})?%{ISO8601_TIMEZONE}?`,
+	"HTTPDATE":          `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} %{INT}`,
+	"SYSLOGTIMESTAMP":   `%{MONTH} +%{MONTHDAY} %{TIME}`,
+}
+
+// grokReference matches %{NAME}, %{NAME:field} and %{NAME:field:type}.
+var grokReference = regexp.MustCompile(`%\{(\w+)(?::([^:}]+))?(?::(int|float))?\}`)
+
+// maxGrokDepth bounds how deeply grok patterns may refer to each other.
+const maxGrokDepth = 20
+
+// logCapture is the field and type of a capture group of a log pattern.
+type logCapture struct {
+	field string
+	typ   string
+}
+
+// logPattern is a compiled log pattern and the fields of its groups, by
+// group index.
+type logPattern struct {
+	re       *regexp.Regexp
+	captures map[int]logCapture
+}
+
+// LogFileReader reads a plain-text log, one record per line, holding the
+// captures of the first of its patterns that matches the line. Captures of
+// type int or float become int64 and float64; others are strings.
+type LogFileReader struct {
+	path      string
+	file      io.Closer
+	input     *bufio.Reader
+	patterns  []logPattern
+	unmatched string
+	lineField string
+	records   int
+	line      int
+	offset    int64
+}
+
+// NewLogFileReader opens the log file of cfg, parsed by the patterns of
+// cfg.LogFile. Files may be gzip, zstd or bzip2 compressed.
+func NewLogFileReader(cfg config.Source) (DataReader, error) {
+	file, err := openSource(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file %s: %w", cfg.Path, err)
+	}
+	input, closer, _, err := decompress(file, file, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newLogFileReader(input, closer, cfg)
+}
+
+// newLogFileReader reads records from input, named by cfg.Path, and closes
+// closer when done or on error.
+func newLogFileReader(input io.Reader, closer io.Closer, cfg config.Source) (*LogFileReader, error) {
+	if err := ValidateLogFile(cfg.LogFile); err != nil {
+		closer.Close()
+		return nil, fmt.Errorf("logfile source %s: %w", cfg.Path, err)
+	}
+	lf := cfg.LogFile
+	patterns, _ := compileLogPatterns(lf)
+	unmatched := lf.Unmatched
+	if unmatched == "" {
+		unmatched = LogUnmatchedModes[0]
+	}
+	return &LogFileReader{
+		path:      cfg.Path,
+		file:      closer,
+		input:     bufio.NewReader(input),
+		patterns:  patterns,
+		unmatched: unmatched,
+		lineField: lf.LineField,
+	}, nil
+}
+
+// ValidateLogFile checks that lf has patterns, each compiling and
+// capturing fields, and a supported unmatched mode.
+func ValidateLogFile(lf *config.LogFileConfig) error {
+	if lf == nil || len(lf.Patterns) == 0 {
+		return fmt.Errorf("logfile.patterns is required")
+	}
+	if lf.Unmatched != "" && !slices.Contains(LogUnmatchedModes, lf.Unmatched) {
+		return fmt.Errorf("unsupported logfile.unmatched %s, use one of %s", lf.Unmatched, strings.Join(LogUnmatchedModes, ", "))
+	}
+	_, err := compileLogPatterns(lf)
+	return err
+}
+
+// compileLogPatterns expands the grok references of the patterns of lf and
+// compiles them.
+func compileLogPatterns(lf *config.LogFileConfig) ([]logPattern, error) {
+	definitions := make(map[string]string, len(grokPatterns)+len(lf.Definitions))
+	for name, pattern := range grokPatterns {
+		definitions[name] = pattern
+	}
+	for name, pattern := range lf.Definitions {
+		definitions[name] = pattern
+	}
+
+	patterns := make([]logPattern, 0, len(lf.Patterns))
+	for _, pattern := range lf.Patterns {
+		var named []logCapture
+		expanded, err := expandGrok(pattern, definitions, &named, 0)
+		if err != nil {
+			return nil, fmt.Errorf("logfile pattern %q: %w", pattern, err)
+		}
+		re, err := regexp.Compile(expanded)
+		if err != nil {
+			return nil, fmt.Errorf("logfile pattern %q: %w", pattern, err)
+		}
+		p := logPattern{re: re, captures: make(map[int]logCapture)}
+		for i, name := range re.SubexpNames() {
+			if n, err := strconv.Atoi(strings.TrimPrefix(name, "grok")); err == nil && strings.HasPrefix(name, "grok") && n < len(named) {
+				p.captures[i] = named[n]
+			} else if name != "" {
+				p.captures[i] = logCapture{field: name}
+			}
+		}
+		if len(p.captures) == 0 {
+			return nil, fmt.Errorf("logfile pattern %q captures no fields", pattern)
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+// expandGrok replaces the grok references of pattern by their definitions,
+// named references by groups grok0, grok1 and so on, whose fields are
+// appended to named.
+func expandGrok(pattern string, definitions map[string]string, named *[]logCapture, depth int) (string, error) {
+	if depth > maxGrokDepth {
+		return "", fmt.Errorf("grok patterns nested more than %d deep", maxGrokDepth)
+	}
+	var err error
+	expanded := grokReference.ReplaceAllStringFunc(pattern, func(ref string) string {
+		if err != nil {
+			return ""
+		}
+		m := grokReference.FindStringSubmatch(ref)
+		definition, ok := definitions[m[1]]
+		if !ok {
+			err = fmt.Errorf("unknown grok pattern %s", m[1])
+			return ""
+		}
+		var inner string
+		if inner, err = expandGrok(definition, definitions, named, depth+1); err != nil {
+			return ""
+		}
+		if m[2] == "" {
+			return "(?:" + inner + ")"
+		}
+		group := fmt.Sprintf("grok%d", len(*named))
+		*named = append(*named, logCapture{field: m[2], typ: m[3]})
+		return "(?P<" + group + ">" + inner + ")"
+	})
+	return expanded, err
+}
+
+// Read returns the record of the next matching line, or io.EOF at the end
+// of the file. Blank lines are skipped.
+func (r *LogFileReader) Read() (Record, error) {
+	for {
+		line, err := r.input.ReadString('\n')
+		start := r.offset
+		r.offset += int64(len(line))
+		if len(line) > 0 {
+			r.line++
+		}
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read %s: %w", r.path, err)
+		}
+		text := strings.TrimRight(line, "\r\n")
+		if strings.TrimSpace(text) == "" {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			continue
+		}
+
+		rec, matched, parseErr := r.parse(text)
+		if parseErr != nil || !matched && r.unmatched == "error" {
+			if parseErr == nil {
+				parseErr = fmt.Errorf("no pattern matches the line")
+			}
+			r.records++
+			return nil, &ParseError{Source: r.path, Record: r.records, Line: r.line, Offset: start, Snippet: truncate(text), Recoverable: true, Err: parseErr}
+		}
+		if !matched {
+			if r.unmatched == "skip" {
+				if err == io.EOF {
+					return nil, io.EOF
+				}
+				continue
+			}
+			rec = Record{"message": text}
+		}
+		r.records++
+		if r.lineField != "" {
+			rec[r.lineField] = int64(r.line)
+		}
+		return rec, nil
+	}
+}
+
+// parse returns the record of the captures of the first pattern matching
+// line, and false if none matches.
+func (r *LogFileReader) parse(line string) (Record, bool, error) {
+	for _, p := range r.patterns {
+		match := p.re.FindStringSubmatchIndex(line)
+		if match == nil {
+			continue
+		}
+		rec := make(Record, len(p.captures))
+		for i, capture := range p.captures {
+			if match[2*i] < 0 {
+				continue
+			}
+			value, err := logValue(line[match[2*i]:match[2*i+1]], capture)
+			if err != nil {
+				return nil, true, err
+			}
+			rec[capture.field] = value
+		}
+		return rec, true, nil
+	}
+	return nil, false, nil
+}
+
+// logValue converts a captured string to the type of its capture.
+func logValue(s string, capture logCapture) (interface{}, error) {
+	switch capture.typ {
+	case "int":
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: invalid int %q", capture.field, s)
+		}
+		return n, nil
+	case "float":
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: invalid float %q", capture.field, s)
+		}
+		return f, nil
+	default:
+		return s, nil
+	}
+}
+
+// Close closes the underlying file.
+func (r *LogFileReader) Close() error {
+	return r.file.Close()
+}
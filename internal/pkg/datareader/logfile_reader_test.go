@@ -0,0 +1,146 @@
+package datareader
+
+import (
+	"data-comparator/internal/pkg/config"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testLog = `2025-09-01T12:00:00Z INFO [req-1] GET /orders 200 12.5ms
+2025-09-01T12:00:01Z WARN [req-2] GET /orders/7 404 3.1ms
+starting worker pool
+
+2025-09-01T12:00:02Z ERROR [req-3] POST /orders 500 40ms
+`
+
+func readLog(t *testing.T, lf *config.LogFileConfig, log string) ([]Record, []error) {
+	t.Helper()
+	reader, err := NewFromReader(strings.NewReader(log), config.Source{Type: "logfile", Path: "app.log", LogFile: lf})
+	if err != nil {
+		t.Fatalf("NewFromReader() error = %v", err)
+	}
+	defer reader.Close()
+	var records []Record
+	var errs []error
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			return records, errs
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		records = append(records, rec)
+	}
+}
+
+func TestLogFileReader_Grok(t *testing.T) {
+	lf := &config.LogFileConfig{
+		Patterns:    []string{`^%{TIMESTAMP_ISO8601:time} %{LOGLEVEL:level} \[%{REQID:request.id}\] %{WORD:method} %{URIPATH:path} %{INT:status:int} %{NUMBER:duration:float}ms$`},
+		Definitions: map[string]string{"REQID": `req-\d+`},
+		LineField:   "line",
+	}
+	records, errs := readLog(t, lf, testLog)
+	if len(errs) != 0 {
+		t.Fatalf("Read() errors = %v", errs)
+	}
+	want := []Record{
+		{"time": "2025-09-01T12:00:00Z", "level": "INFO", "request.id": "req-1", "method": "GET", "path": "/orders", "status": int64(200), "duration": 12.5, "line": int64(1)},
+		{"time": "2025-09-01T12:00:01Z", "level": "WARN", "request.id": "req-2", "method": "GET", "path": "/orders/7", "status": int64(404), "duration": 3.1, "line": int64(2)},
+		{"time": "2025-09-01T12:00:02Z", "level": "ERROR", "request.id": "req-3", "method": "POST", "path": "/orders", "status": int64(500), "duration": 40.0, "line": int64(5)},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records got = %v, want %v", records, want)
+	}
+}
+
+func TestLogFileReader_Unmatched(t *testing.T) {
+	patterns := []string{`^\S+ (?P<level>[A-Z]+) `}
+
+	records, errs := readLog(t, &config.LogFileConfig{Patterns: patterns, Unmatched: "keep"}, testLog)
+	if len(errs) != 0 || len(records) != 4 || records[2]["message"] != "starting worker pool" {
+		t.Errorf("keep got records %v and errors %v, want 4 records, the third the unmatched line", records, errs)
+	}
+
+	records, errs = readLog(t, &config.LogFileConfig{Patterns: patterns, Unmatched: "error"}, testLog)
+	var perr *ParseError
+	if len(records) != 3 || len(errs) != 1 || !errors.As(errs[0], &perr) || perr.Line != 3 || !perr.Recoverable {
+		t.Errorf("error got records %v and errors %v, want 3 records and a recoverable parse error at line 3", records, errs)
+	}
+
+	records, _ = readLog(t, &config.LogFileConfig{Patterns: patterns}, testLog)
+	if len(records) != 3 {
+		t.Errorf("skip got %d records, want 3", len(records))
+	}
+}
+
+func TestLogFileReader_FirstMatchingPattern(t *testing.T) {
+	lf := &config.LogFileConfig{Patterns: []string{
+		`^%{IPV4:client} - %{NOTSPACE:user} \[%{HTTPDATE:time}\] %{QS:request}`,
+		`^%{SYSLOGTIMESTAMP:time} %{HOSTNAME:host} %{GREEDYDATA:message}`,
+	}}
+	log := `10.0.0.1 - alice [01/Sep/2025:12:00:00 +0000] "GET / HTTP/1.1"
+Sep  1 12:00:00 web-1 sshd started
+`
+	records, errs := readLog(t, lf, log)
+	want := []Record{
+		{"client": "10.0.0.1", "user": "alice", "time": "01/Sep/2025:12:00:00 +0000", "request": `"GET / HTTP/1.1"`},
+		{"time": "Sep  1 12:00:00", "host": "web-1", "message": "sshd started"},
+	}
+	if len(errs) != 0 || !reflect.DeepEqual(records, want) {
+		t.Errorf("records got = %v, errors %v, want %v", records, errs, want)
+	}
+}
+
+func TestLogFileReader_InvalidCapture(t *testing.T) {
+	lf := &config.LogFileConfig{Patterns: []string{`^%{NOTSPACE:n:int}$`}}
+	records, errs := readLog(t, lf, "12\nabc\n")
+	if len(records) != 1 || len(errs) != 1 || !strings.Contains(errs[0].Error(), `invalid int "abc"`) {
+		t.Errorf("got records %v and errors %v, want 1 record and an invalid int error", records, errs)
+	}
+}
+
+func TestValidateLogFile(t *testing.T) {
+	tests := []struct {
+		name string
+		lf   *config.LogFileConfig
+		want string
+	}{
+		{"missing", nil, "logfile.patterns is required"},
+		{"unknown grok", &config.LogFileConfig{Patterns: []string{"%{NOPE:x}"}}, "unknown grok pattern NOPE"},
+		{"no captures", &config.LogFileConfig{Patterns: []string{`^\d+$`}}, "captures no fields"},
+		{"recursive", &config.LogFileConfig{Patterns: []string{"%{A:x}"}, Definitions: map[string]string{"A": "%{A}"}}, "nested more than"},
+		{"invalid regexp", &config.LogFileConfig{Patterns: []string{"(?P<x>["}}, "missing closing ]"},
+		{"unmatched", &config.LogFileConfig{Patterns: []string{"(?P<x>.)"}, Unmatched: "drop"}, "unsupported logfile.unmatched drop"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateLogFile(tt.lf)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ValidateLogFile() error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewLogFileReader_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte(testLog), 0644); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := New(config.Source{Type: "logfile", Path: path, LogFile: &config.LogFileConfig{Patterns: []string{`^%{TIMESTAMP_ISO8601:time} %{LOGLEVEL:level}`}}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer reader.Close()
+	rec, err := reader.Read()
+	if err != nil || rec["level"] != "INFO" {
+		t.Errorf("Read() got = %v, %v, want level INFO", rec, err)
+	}
+}
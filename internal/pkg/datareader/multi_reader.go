@@ -0,0 +1,174 @@
+package datareader
+
+import (
+	"data-comparator/internal/pkg/config"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PathOrders lists the orders the files of a glob or directory path can be
+// read in.
+var PathOrders = []string{"lexical", "mtime"}
+
+// isMultiPath reports whether path names several files: a directory, or a
+// glob pattern that is not itself the name of a file.
+func isMultiPath(path string) bool {
+	if path == StdinPath || IsArchivePath(path) || IsObjectPath(path) || IsRemotePath(path) || IsHTTPPath(path) || IsListenPath(path) {
+		return false
+	}
+	if info, err := os.Stat(path); err == nil {
+		return info.IsDir()
+	}
+	return strings.ContainsAny(path, "*?[")
+}
+
+// SourceFiles returns the files the path of cfg reads, in the order of
+// cfg.Order: those matching a glob pattern or in a directory, or else the
+// path itself. Files in a directory whose names start with "." or "_", such
+// as _SUCCESS markers, are left out.
+func SourceFiles(cfg config.Source) ([]string, error) {
+	if !isMultiPath(cfg.Path) {
+		return []string{cfg.Path}, nil
+	}
+	if cfg.Order != "" && !slices.Contains(PathOrders, cfg.Order) {
+		return nil, fmt.Errorf("unsupported order %s, use one of %s", cfg.Order, strings.Join(PathOrders, ", "))
+	}
+
+	var files []string
+	if info, err := os.Stat(cfg.Path); err == nil && info.IsDir() {
+		entries, err := os.ReadDir(cfg.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", cfg.Path, err)
+		}
+		for _, e := range entries {
+			if e.Type().IsRegular() && !strings.HasPrefix(e.Name(), ".") && !strings.HasPrefix(e.Name(), "_") {
+				files = append(files, filepath.Join(cfg.Path, e.Name()))
+			}
+		}
+	} else {
+		matches, err := filepath.Glob(cfg.Path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path pattern %s: %w", cfg.Path, err)
+		}
+		for _, m := range matches {
+			if info, err := os.Stat(m); err == nil && info.Mode().IsRegular() {
+				files = append(files, m)
+			}
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files match %s", cfg.Path)
+	}
+
+	sort.Strings(files)
+	if cfg.Order == "mtime" {
+		modified := make(map[string]time.Time, len(files))
+		for _, f := range files {
+			info, err := os.Stat(f)
+			if err != nil {
+				return nil, fmt.Errorf("failed to stat %s: %w", f, err)
+			}
+			modified[f] = info.ModTime()
+		}
+		sort.SliceStable(files, func(i, j int) bool { return modified[files[i]].Before(modified[files[j]]) })
+	}
+	return files, nil
+}
+
+// MultiReader reads several files one after another as a single stream of
+// records, each file through the reader of its source type. Only one file
+// is open at a time.
+type MultiReader struct {
+	cfg      config.Source
+	files    []string
+	next     int
+	current  DataReader
+	warnings []string
+}
+
+// NewMultiReader opens the first of the files of the glob or directory path
+// of cfg, as listed by SourceFiles.
+func NewMultiReader(cfg config.Source) (*MultiReader, error) {
+	files, err := SourceFiles(cfg)
+	if err != nil {
+		return nil, err
+	}
+	r := &MultiReader{cfg: cfg, files: files}
+	// The transform and field names apply to the stream as a whole.
+	r.cfg.Transform, r.cfg.FieldNames = nil, nil
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open opens the next file.
+func (r *MultiReader) open() error {
+	cfg := r.cfg
+	cfg.Path = r.files[r.next]
+	r.next++
+	reader, err := New(cfg)
+	if err != nil {
+		return err
+	}
+	r.current = reader
+	return nil
+}
+
+// Read returns the next record, moving on to the next file at the end of
+// one, or io.EOF after the last file.
+func (r *MultiReader) Read() (Record, error) {
+	for {
+		if r.current == nil {
+			if r.next == len(r.files) {
+				return nil, io.EOF
+			}
+			if err := r.open(); err != nil {
+				return nil, err
+			}
+		}
+		rec, err := r.current.Read()
+		if err != io.EOF {
+			return rec, err
+		}
+		if err := r.closeCurrent(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (r *MultiReader) closeCurrent() error {
+	if w, ok := r.current.(Warner); ok {
+		for _, warning := range w.Warnings() {
+			r.warnings = append(r.warnings, fmt.Sprintf("%s: %s", r.files[r.next-1], warning))
+		}
+	}
+	err := r.current.Close()
+	r.current = nil
+	return err
+}
+
+// Warnings returns the warnings of the files read so far.
+func (r *MultiReader) Warnings() []string {
+	warnings := slices.Clone(r.warnings)
+	if w, ok := r.current.(Warner); ok {
+		for _, warning := range w.Warnings() {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", r.files[r.next-1], warning))
+		}
+	}
+	return warnings
+}
+
+// Close closes the file being read.
+func (r *MultiReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	return r.closeCurrent()
+}
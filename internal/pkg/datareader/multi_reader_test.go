@@ -0,0 +1,67 @@
+package datareader
+
+import (
+	"data-comparator/internal/pkg/config"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewMultiReader(t *testing.T) {
+	dir := t.TempDir()
+	parts := map[string][]byte{
+		"part-0.jsonl":    []byte(`{"id":"1"}` + "\n" + `{"id":"2"}` + "\n"),
+		"part-1.jsonl.gz": compressTestData(t, "gzip", []byte(`{"id":"3"}`+"\n")),
+		"part-2.jsonl":    []byte(`{"id":"4"}` + "\n"),
+		"_SUCCESS":        nil,
+	}
+	for name, data := range parts {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// part-0 was written last.
+	now := time.Now()
+	for i, name := range []string{"part-2.jsonl", "part-1.jsonl.gz", "part-0.jsonl"} {
+		at := now.Add(time.Duration(i-3) * time.Hour)
+		if err := os.Chtimes(filepath.Join(dir, name), at, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids := func(cfg config.Source) []interface{} {
+		t.Helper()
+		reader, err := New(cfg)
+		if err != nil {
+			t.Fatalf("New(%s) error = %v", cfg.Path, err)
+		}
+		var got []interface{}
+		for _, rec := range readAllRecords(t, reader) {
+			got = append(got, rec["id"])
+		}
+		return got
+	}
+	tests := []struct {
+		cfg  config.Source
+		want []interface{}
+	}{
+		{config.Source{Type: "json", Path: filepath.Join(dir, "part-*.jsonl*")}, []interface{}{"1", "2", "3", "4"}},
+		{config.Source{Type: "auto", Path: dir, Order: "mtime"}, []interface{}{"4", "3", "1", "2"}},
+		{config.Source{Type: "json", Path: filepath.Join(dir, "part-[02].jsonl")}, []interface{}{"1", "2", "4"}},
+	}
+	for _, tt := range tests {
+		if got := ids(tt.cfg); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Records of %s got = %v, want %v", tt.cfg.Path, got, tt.want)
+		}
+	}
+
+	if _, err := New(config.Source{Type: "json", Path: filepath.Join(dir, "*.csv")}); err == nil || !strings.Contains(err.Error(), "no files match") {
+		t.Errorf("New() without matches error got = %v", err)
+	}
+	if _, err := New(config.Source{Type: "json", Path: dir, Order: "size"}); err == nil || !strings.Contains(err.Error(), "unsupported order") {
+		t.Errorf("New() with an unknown order error got = %v", err)
+	}
+}
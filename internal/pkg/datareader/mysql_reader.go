@@ -0,0 +1,83 @@
+package datareader
+
+import (
+	"data-comparator/internal/pkg/config"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// mysqlDriver is the database/sql driver used for mysql sources.
+var mysqlDriver = "mysql"
+
+// mysqlDateTimeLayouts are the text forms of MySQL DATETIME, TIMESTAMP and
+// DATE values.
+var mysqlDateTimeLayouts = []string{"2006-01-02 15:04:05.999999", "2006-01-02"}
+
+// NewMySQLReader connects to cfg.DSN, e.g. user@tcp(host:3306)/db, and
+// streams the rows of cfg.Table or cfg.Query from a single result set that
+// the driver reads from the connection as rows are consumed, so results
+// larger than memory can be compared without exporting them first. Integers
+// are int64, floating point and decimal values float64, datetimes and dates
+// time.Time in UTC, json values nested records, binary and blob values
+// []byte and everything else strings.
+func NewMySQLReader(cfg config.Source) (DataReader, error) {
+	return newSQLReader(cfg, sqlDialect{
+		typ:     "mysql",
+		driver:  mysqlDriver,
+		quote:   quoteMySQLIdentifier,
+		cursor:  func(query string, _ int) (string, string) { return "", query },
+		convert: convertMySQLValue,
+	})
+}
+
+func quoteMySQLIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// convertMySQLValue turns a scanned value into the Record value of its
+// column's database type.
+func convertMySQLValue(v interface{}, typ string) interface{} {
+	if u, ok := v.(uint64); ok && u <= math.MaxInt64 {
+		return int64(u)
+	}
+	b, ok := v.([]byte)
+	if !ok {
+		return v
+	}
+	s := string(b)
+	switch typ {
+	case "BINARY", "VARBINARY", "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB", "BIT", "GEOMETRY":
+		return append([]byte(nil), b...)
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "BIGINT", "YEAR",
+		"UNSIGNED TINYINT", "UNSIGNED SMALLINT", "UNSIGNED MEDIUMINT", "UNSIGNED INT":
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+	case "UNSIGNED BIGINT":
+		// Values beyond int64 stay strings rather than losing precision.
+		if u, err := strconv.ParseUint(s, 10, 64); err == nil && u <= math.MaxInt64 {
+			return int64(u)
+		}
+	case "DECIMAL", "FLOAT", "DOUBLE":
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	case "JSON":
+		var value interface{}
+		if err := json.Unmarshal(b, &value); err == nil {
+			return value
+		}
+	case "DATETIME", "TIMESTAMP", "DATE":
+		for _, layout := range mysqlDateTimeLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t
+			}
+		}
+	}
+	return s
+}
@@ -0,0 +1,72 @@
+package datareader
+
+import (
+	"data-comparator/internal/pkg/config"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMySQLReader(t *testing.T) {
+	var rows [][]driver.Value
+	for i := 1; i <= 5; i++ {
+		rows = append(rows, []driver.Value{
+			int64(i), []byte("12.50"), []byte(`{"city": "Berlin"}`), []byte{0, 1},
+			[]byte("2024-03-01 12:30:00.5"), []byte("18446744073709551615"), []byte("name"),
+		})
+	}
+	d := useFakeSQL(t,
+		[]string{"id", "price", "address", "blob", "created", "big", "name"},
+		[]string{"BIGINT", "DECIMAL", "JSON", "BLOB", "DATETIME", "UNSIGNED BIGINT", "VARCHAR"},
+		rows)
+
+	reader, err := New(config.Source{Type: "mysql", DSN: "bob:secret@tcp(db:3306)/app", Table: "app.users", FetchSize: 2})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	got := readAllRecords(t, reader)
+	if len(got) != 5 {
+		t.Fatalf("Records got = %d, want 5", len(got))
+	}
+	want := Record{
+		"id": int64(5), "price": 12.5, "address": map[string]interface{}{"city": "Berlin"}, "blob": []byte{0, 1},
+		"created": time.Date(2024, 3, 1, 12, 30, 0, 500000000, time.UTC), "big": "18446744073709551615", "name": "name",
+	}
+	if !reflect.DeepEqual(got[4], want) {
+		t.Errorf("Record got = %v, want %v", got[4], want)
+	}
+
+	// The whole table is streamed from a single result set.
+	wantQueries := []string{"SELECT * FROM `app`.`users`"}
+	if !reflect.DeepEqual(d.queries, wantQueries) {
+		t.Errorf("Queries got = %q, want %q", d.queries, wantQueries)
+	}
+}
+
+func TestMySQLReader_Config(t *testing.T) {
+	useFakeSQL(t, []string{"id"}, []string{"INT"}, nil)
+	for _, cfg := range []config.Source{
+		{Type: "mysql", Table: "users"},
+		{Type: "mysql", DSN: "bob@/app"},
+		{Type: "mysql", DSN: "bob@/app", Table: "users", Query: "SELECT 1"},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("New(%+v) should fail", cfg)
+		}
+	}
+
+	reader, err := New(config.Source{Type: "mysql", DSN: "bob@/app", Query: "SELECT id FROM users;"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got := readAllRecords(t, reader); len(got) != 0 {
+		t.Errorf("Records got = %v, want none", got)
+	}
+}
+
+func TestQuoteMySQLIdentifier(t *testing.T) {
+	if got := quoteMySQLIdentifier("we`ird"); got != "`we``ird`" {
+		t.Errorf("quoteMySQLIdentifier() got = %s, want `we``ird`", got)
+	}
+}
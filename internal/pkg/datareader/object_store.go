@@ -0,0 +1,561 @@
+package datareader
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"data-comparator/internal/pkg/config"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Defaults of config.ObjectStore.
+const (
+	DefaultPartSize    = 8 << 20
+	DefaultConcurrency = 4
+)
+
+// objectAttempts is the number of times a part request is tried.
+const objectAttempts = 3
+
+// azureVersion is the Blob service API version requests are made with.
+const azureVersion = "2021-08-06"
+
+// objectSchemes are the URI schemes of object store paths.
+var objectSchemes = []string{"s3", "gs", "az"}
+
+// IsObjectPath reports whether path is an s3://, gs:// or az:// URI.
+func IsObjectPath(path string) bool {
+	for _, scheme := range objectSchemes {
+		if strings.HasPrefix(path, scheme+"://") {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceFile is the local file, or object store object, a file source reads.
+type sourceFile interface {
+	io.ReadCloser
+	io.ReaderAt
+	Size() (int64, error)
+}
+
+type localFile struct {
+	*os.File
+}
+
+func (f localFile) Size() (int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// openSource opens the file at cfg.Path, or the object it names.
+func openSource(cfg config.Source) (sourceFile, error) {
+	if IsObjectPath(cfg.Path) {
+		return openObject(cfg.Path, cfg.ObjectStore)
+	}
+	if IsRemotePath(cfg.Path) {
+		return openRemote(cfg.Path, cfg.Remote)
+	}
+	file, err := os.Open(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	return localFile{file}, nil
+}
+
+// ObjectInfo describes an object in object storage.
+type ObjectInfo struct {
+	Size int64
+	ETag string
+}
+
+// StatObject returns the size and ETag of the object at an s3://, gs:// or
+// az:// path without reading it.
+func StatObject(path string, store *config.ObjectStore) (ObjectInfo, error) {
+	o, err := openObject(path, store)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	o.Close()
+	return ObjectInfo{Size: o.size, ETag: o.etag}, nil
+}
+
+// RedactObjectStore returns a copy of store without its secrets, for reports.
+func RedactObjectStore(store *config.ObjectStore) *config.ObjectStore {
+	if store == nil {
+		return nil
+	}
+	redacted := *store
+	redacted.SecretAccessKey, redacted.SessionToken = "", ""
+	redacted.AccountKey, redacted.SASToken = "", ""
+	return &redacted
+}
+
+// object is an object in object storage, read through HTTP range requests.
+// Sequential reads fetch consecutive parts, several of them in parallel,
+// so large objects stream without being downloaded first; ReadAt fetches
+// just the requested range.
+type object struct {
+	path        string
+	client      *http.Client
+	url         string
+	sign        func(req *http.Request) error
+	size        int64
+	etag        string
+	partSize    int64
+	concurrency int
+
+	// sequential read state
+	cancel  context.CancelFunc
+	parts   chan chan objectPart
+	current []byte
+	err     error
+}
+
+// objectPart is the data of a part, or the error fetching it.
+type objectPart struct {
+	data []byte
+	err  error
+}
+
+// openObject resolves the endpoint and credentials of path and reads the
+// object's size.
+func openObject(path string, store *config.ObjectStore) (*object, error) {
+	if store == nil {
+		store = &config.ObjectStore{}
+	}
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid object path %s: %w", path, err)
+	}
+	bucket, key := u.Host, strings.TrimPrefix(u.Path, "/")
+	if bucket == "" || key == "" {
+		return nil, fmt.Errorf("object path %s needs a bucket and an object name", path)
+	}
+
+	client := &http.Client{Timeout: 5 * time.Minute}
+	o := &object{path: path, client: client, partSize: int64(store.PartSize), concurrency: store.Concurrency}
+	if o.partSize <= 0 {
+		o.partSize = DefaultPartSize
+	}
+	if o.concurrency <= 0 {
+		o.concurrency = DefaultConcurrency
+	}
+	switch u.Scheme {
+	case "s3":
+		err = o.useS3(bucket, key, store)
+	case "gs":
+		o.useGCS(bucket, key, store)
+	case "az":
+		err = o.useAzure(bucket, key, store)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := o.stat(); err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	return o, nil
+}
+
+// useS3 addresses the object in an S3 bucket, or in an S3-compatible store
+// at the configured endpoint, and signs requests with AWS Signature V4.
+func (o *object) useS3(bucket, key string, store *config.ObjectStore) error {
+	region := firstNonEmpty(store.Region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), "us-east-1")
+	endpoint := firstNonEmpty(store.Endpoint, os.Getenv("AWS_ENDPOINT_URL_S3"), os.Getenv("AWS_ENDPOINT_URL"))
+	switch {
+	case endpoint != "":
+		o.url = strings.TrimSuffix(endpoint, "/") + "/" + bucket + "/" + awsEscapePath(key)
+	case strings.Contains(bucket, "."):
+		// Dotted bucket names do not match the wildcard certificate.
+		o.url = fmt.Sprintf("https://s3.%s.amazonaws.com/%s/%s", region, bucket, awsEscapePath(key))
+	default:
+		o.url = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, region, awsEscapePath(key))
+	}
+
+	creds := awsCredentials{
+		accessKeyID:     firstNonEmpty(store.AccessKeyID, os.Getenv("AWS_ACCESS_KEY_ID")),
+		secretAccessKey: firstNonEmpty(store.SecretAccessKey, os.Getenv("AWS_SECRET_ACCESS_KEY")),
+		sessionToken:    firstNonEmpty(store.SessionToken, os.Getenv("AWS_SESSION_TOKEN")),
+	}
+	if (creds.accessKeyID == "") != (creds.secretAccessKey == "") {
+		return fmt.Errorf("s3 credentials need both an access key id and a secret access key")
+	}
+	o.sign = func(req *http.Request) error {
+		if creds.accessKeyID != "" {
+			signAWSV4(req, creds, region, "s3", time.Now())
+		}
+		return nil
+	}
+	return nil
+}
+
+// useGCS addresses the object through the Cloud Storage XML API with an
+// OAuth token of Google's application default credentials.
+func (o *object) useGCS(bucket, key string, store *config.ObjectStore) {
+	endpoint := firstNonEmpty(store.Endpoint, "https://storage.googleapis.com")
+	o.url = strings.TrimSuffix(endpoint, "/") + "/" + bucket + "/" + awsEscapePath(key)
+	tokens := newGoogleTokens(o.client, storageScope, store.CredentialsFile)
+	o.sign = func(req *http.Request) error {
+		token, err := tokens.get()
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
+
+// useAzure addresses the blob in a container of the storage account, with
+// a SAS token, a shared key or anonymously.
+func (o *object) useAzure(container, blob string, store *config.ObjectStore) error {
+	account := firstNonEmpty(store.Account, os.Getenv("AZURE_STORAGE_ACCOUNT"))
+	if account == "" {
+		return fmt.Errorf("az:// paths need a storage account: set object_store.account or AZURE_STORAGE_ACCOUNT")
+	}
+	endpoint := firstNonEmpty(store.Endpoint, "https://"+account+".blob.core.windows.net")
+	o.url = strings.TrimSuffix(endpoint, "/") + "/" + container + "/" + awsEscapePath(blob)
+
+	sas := strings.TrimPrefix(firstNonEmpty(store.SASToken, os.Getenv("AZURE_STORAGE_SAS_TOKEN")), "?")
+	accountKey := firstNonEmpty(store.AccountKey, os.Getenv("AZURE_STORAGE_KEY"))
+	var key []byte
+	switch {
+	case sas != "":
+		o.url += "?" + sas
+	case accountKey != "":
+		var err error
+		if key, err = base64.StdEncoding.DecodeString(accountKey); err != nil {
+			return fmt.Errorf("azure account key is not base64: %w", err)
+		}
+	}
+	o.sign = func(req *http.Request) error {
+		req.Header.Set("x-ms-version", azureVersion)
+		if key != nil {
+			signAzureSharedKey(req, account, key, time.Now())
+		}
+		return nil
+	}
+	return nil
+}
+
+// stat reads the size and ETag of the object.
+func (o *object) stat() error {
+	resp, err := o.do(context.Background(), http.MethodHead, "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.ContentLength < 0 {
+		return fmt.Errorf("object store returned no object size")
+	}
+	o.size, o.etag = resp.ContentLength, strings.Trim(resp.Header.Get("ETag"), `"`)
+	return nil
+}
+
+// do sends a signed request for the object, or a range of it.
+func (o *object) do(ctx context.Context, method, byteRange string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, o.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if byteRange != "" {
+		req.Header.Set("Range", byteRange)
+	}
+	if err := o.sign(req); err != nil {
+		return nil, err
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return nil, &objectStoreError{status: resp.Status, code: resp.StatusCode}
+	}
+	return resp, nil
+}
+
+// objectStoreError is an error response of the object store.
+type objectStoreError struct {
+	status string
+	code   int
+}
+
+func (e *objectStoreError) Error() string {
+	return "object store returned " + e.status
+}
+
+// Unwrap makes missing objects match os.ErrNotExist, like missing files.
+func (e *objectStoreError) Unwrap() error {
+	if e.code == http.StatusNotFound {
+		return os.ErrNotExist
+	}
+	return nil
+}
+
+// fetch reads length bytes of the object from offset, retrying failed requests.
+func (o *object) fetch(ctx context.Context, offset, length int64) ([]byte, error) {
+	var err error
+	for attempt := 0; attempt < objectAttempts; attempt++ {
+		var resp *http.Response
+		resp, err = o.do(ctx, http.MethodGet, fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+		if err != nil {
+			// Only server errors and failed connections are worth retrying.
+			var storeErr *objectStoreError
+			if ctx.Err() != nil || errors.As(err, &storeErr) && storeErr.code < 500 {
+				return nil, err
+			}
+			continue
+		}
+		if resp.StatusCode == http.StatusOK {
+			// The store ignored the range and sends the whole object.
+			if _, err = io.CopyN(io.Discard, resp.Body, offset); err != nil {
+				resp.Body.Close()
+				continue
+			}
+		}
+		data := make([]byte, length)
+		_, err = io.ReadFull(resp.Body, data)
+		resp.Body.Close()
+		if err == nil {
+			return data, nil
+		}
+	}
+	return nil, err
+}
+
+// Read reads the object sequentially. The first Read starts fetching parts
+// in the background, up to the configured concurrency ahead of the reader.
+func (o *object) Read(p []byte) (int, error) {
+	if o.parts == nil {
+		o.start()
+	}
+	for len(o.current) == 0 {
+		if o.err != nil {
+			return 0, o.err
+		}
+		part, ok := <-o.parts
+		if !ok {
+			o.err = io.EOF
+			continue
+		}
+		result := <-part
+		o.current, o.err = result.data, result.err
+	}
+	n := copy(p, o.current)
+	o.current = o.current[n:]
+	return n, nil
+}
+
+func (o *object) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	o.cancel = cancel
+	o.parts = make(chan chan objectPart, o.concurrency-1)
+	go func() {
+		defer close(o.parts)
+		for offset := int64(0); offset < o.size; offset += o.partSize {
+			part := make(chan objectPart, 1)
+			go func(offset int64) {
+				data, err := o.fetch(ctx, offset, min(o.partSize, o.size-offset))
+				if err != nil {
+					err = fmt.Errorf("failed to read %s at offset %d: %w", o.path, offset, err)
+				}
+				part <- objectPart{data, err}
+			}(offset)
+			select {
+			case o.parts <- part:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// ReadAt reads len(p) bytes of the object from off with a single range request.
+func (o *object) ReadAt(p []byte, off int64) (int, error) {
+	if off >= o.size {
+		return 0, io.EOF
+	}
+	length := min(int64(len(p)), o.size-off)
+	data, err := o.fetch(context.Background(), off, length)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s at offset %d: %w", o.path, off, err)
+	}
+	n := copy(p, data)
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Size returns the size of the object in bytes.
+func (o *object) Size() (int64, error) {
+	return o.size, nil
+}
+
+// Close stops fetching parts ahead.
+func (o *object) Close() error {
+	if o.cancel != nil {
+		o.cancel()
+		o.cancel = nil
+	}
+	return nil
+}
+
+type awsCredentials struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+// signAWSV4 signs req with AWS Signature Version 4. The host, range and
+// x-amz-* headers are signed; the payload is left unsigned unless its
+// hash is already set.
+func signAWSV4(req *http.Request, creds awsCredentials, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if req.Header.Get("X-Amz-Content-Sha256") == "" {
+		req.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+	}
+	if creds.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "range" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		awsCanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	digest := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(digest[:])
+
+	key := hmacSHA256([]byte("AWS4"+creds.secretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.accessKeyID, scope, signedHeaders, signature))
+}
+
+func awsCanonicalQuery(query url.Values) string {
+	var pairs []string
+	for name, values := range query {
+		for _, v := range values {
+			pairs = append(pairs, awsEscape(name)+"="+awsEscape(v))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// awsEscape percent-encodes everything but the unreserved characters of RFC 3986.
+func awsEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// awsEscapePath escapes each segment of an object name.
+func awsEscapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = awsEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// signAzureSharedKey signs a bodiless req with the storage account's shared key.
+func signAzureSharedKey(req *http.Request, account string, key []byte, now time.Time) {
+	req.Header.Set("x-ms-date", now.UTC().Format(http.TimeFormat))
+	var msHeaders []string
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-ms-") {
+			msHeaders = append(msHeaders, name+":"+strings.TrimSpace(strings.Join(values, ",")))
+		}
+	}
+	sort.Strings(msHeaders)
+
+	resource := "/" + account + req.URL.EscapedPath()
+	query := req.URL.Query()
+	params := make([]string, 0, len(query))
+	for name := range query {
+		params = append(params, name)
+	}
+	sort.Strings(params)
+	for _, name := range params {
+		values := query[name]
+		sort.Strings(values)
+		resource += "\n" + strings.ToLower(name) + ":" + strings.Join(values, ",")
+	}
+
+	stringToSign := strings.Join([]string{
+		req.Method,
+		"", "", "", "", "", "", "", "", "", "", // content and conditional headers, unused by GET and HEAD
+		req.Header.Get("Range"),
+		strings.Join(msHeaders, "\n"),
+		resource,
+	}, "\n")
+	signature := base64.StdEncoding.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", "SharedKey "+account+":"+signature)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,308 @@
+package datareader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// This file decodes the encodings and compression codecs of Parquet pages.
+
+// parquetZstd returns the decoder of zstd compressed pages, shared by all
+// readers as DecodeAll is safe for concurrent use.
+var parquetZstd = sync.OnceValues(func() (*zstd.Decoder, error) {
+	return zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+})
+
+// decompressParquet decompresses a page compressed with codec to size bytes.
+func decompressParquet(codec int64, data []byte, size int64) ([]byte, error) {
+	switch codec {
+	case codecUncompressed:
+		return data, nil
+	case codecSnappy:
+		return decodeSnappy(data)
+	case codecGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		out := bytes.NewBuffer(make([]byte, 0, size))
+		if _, err := io.Copy(out, zr); err != nil {
+			return nil, err
+		}
+		return out.Bytes(), nil
+	case codecZstd:
+		decoder, err := parquetZstd()
+		if err != nil {
+			return nil, err
+		}
+		return decoder.DecodeAll(data, make([]byte, 0, max(size, 0)))
+	default:
+		return nil, fmt.Errorf("unsupported compression codec %d (only uncompressed, snappy, gzip and zstd are supported)", codec)
+	}
+}
+
+// decodeSnappy decodes a snappy block, the raw format Parquet uses without
+// stream framing.
+func decodeSnappy(src []byte) ([]byte, error) {
+	length, n := binary.Uvarint(src)
+	if n <= 0 || length > math.MaxInt32 {
+		return nil, fmt.Errorf("invalid snappy length")
+	}
+	src = src[n:]
+	dst := make([]byte, 0, length)
+	for len(src) > 0 {
+		tag := src[0]
+		switch tag & 3 {
+		case 0: // literal
+			size := int(tag>>2) + 1
+			src = src[1:]
+			if size > 60 {
+				extra := size - 60
+				if len(src) < extra {
+					return nil, fmt.Errorf("truncated snappy literal")
+				}
+				size = 0
+				for i := extra - 1; i >= 0; i-- {
+					size = size<<8 | int(src[i])
+				}
+				size++
+				src = src[extra:]
+			}
+			if size > len(src) {
+				return nil, fmt.Errorf("truncated snappy literal")
+			}
+			dst = append(dst, src[:size]...)
+			src = src[size:]
+			continue
+		case 1: // copy with a 1-byte offset
+			if len(src) < 2 {
+				return nil, fmt.Errorf("truncated snappy copy")
+			}
+			size := 4 + int(tag>>2)&7
+			offset := int(tag>>5)<<8 | int(src[1])
+			src = src[2:]
+			if err := snappyCopy(&dst, offset, size); err != nil {
+				return nil, err
+			}
+		case 2: // copy with a 2-byte offset
+			if len(src) < 3 {
+				return nil, fmt.Errorf("truncated snappy copy")
+			}
+			size := 1 + int(tag>>2)
+			offset := int(binary.LittleEndian.Uint16(src[1:]))
+			src = src[3:]
+			if err := snappyCopy(&dst, offset, size); err != nil {
+				return nil, err
+			}
+		case 3: // copy with a 4-byte offset
+			if len(src) < 5 {
+				return nil, fmt.Errorf("truncated snappy copy")
+			}
+			size := 1 + int(tag>>2)
+			offset := int(binary.LittleEndian.Uint32(src[1:]))
+			src = src[5:]
+			if err := snappyCopy(&dst, offset, size); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if uint64(len(dst)) != length {
+		return nil, fmt.Errorf("snappy data decodes to %d bytes, want %d", len(dst), length)
+	}
+	return dst, nil
+}
+
+// snappyCopy appends size bytes starting offset bytes back, which may overlap
+// the bytes being appended.
+func snappyCopy(dst *[]byte, offset, size int) error {
+	if offset <= 0 || offset > len(*dst) {
+		return fmt.Errorf("invalid snappy copy offset %d", offset)
+	}
+	start := len(*dst) - offset
+	for i := 0; i < size; i++ {
+		*dst = append(*dst, (*dst)[start+i])
+	}
+	return nil
+}
+
+// decodeHybrid decodes count values of the given bit width from the RLE /
+// bit-packing hybrid encoding used for levels and dictionary indices.
+func decodeHybrid(data []byte, bitWidth, count int) ([]int32, error) {
+	if bitWidth < 0 || bitWidth > 32 {
+		return nil, fmt.Errorf("invalid bit width %d", bitWidth)
+	}
+	values := make([]int32, 0, count)
+	byteWidth := (bitWidth + 7) / 8
+	for len(values) < count {
+		header, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, fmt.Errorf("truncated run header after %d of %d values", len(values), count)
+		}
+		data = data[n:]
+		if header&1 == 0 {
+			// RLE run: a value repeated header>>1 times.
+			if len(data) < byteWidth {
+				return nil, fmt.Errorf("truncated rle run")
+			}
+			var v uint32
+			for i := byteWidth - 1; i >= 0; i-- {
+				v = v<<8 | uint32(data[i])
+			}
+			data = data[byteWidth:]
+			for run := header >> 1; run > 0 && len(values) < count; run-- {
+				values = append(values, int32(v))
+			}
+			continue
+		}
+
+		// Bit-packed run: header>>1 groups of 8 values, least significant bit first.
+		groups := int(header >> 1)
+		size := groups * bitWidth
+		if size > len(data) {
+			size = len(data)
+		}
+		packed := data[:size]
+		data = data[size:]
+		for i := 0; i < groups*8 && len(values) < count; i++ {
+			var v uint32
+			for b := 0; b < bitWidth; b++ {
+				bit := i*bitWidth + b
+				if bit/8 >= len(packed) {
+					return nil, fmt.Errorf("truncated bit-packed run")
+				}
+				v |= uint32(packed[bit/8]>>(bit%8)&1) << b
+			}
+			values = append(values, int32(v))
+		}
+	}
+	return values, nil
+}
+
+// levelBitWidth is the bit width of levels up to maxLevel.
+func levelBitWidth(maxLevel int) int {
+	return bits.Len(uint(maxLevel))
+}
+
+// decodePlain decodes count PLAIN-encoded values of the column's physical type.
+func decodePlain(data []byte, e *parquetSchemaElement, count int) ([]interface{}, error) {
+	values := make([]interface{}, 0, count)
+	short := func() error {
+		return fmt.Errorf("plain values end after %d of %d values", len(values), count)
+	}
+	switch e.physicalType {
+	case parquetBoolean:
+		if len(data)*8 < count {
+			return nil, short()
+		}
+		for i := 0; i < count; i++ {
+			values = append(values, data[i/8]>>(i%8)&1 == 1)
+		}
+	case parquetInt32:
+		for i := 0; i < count; i++ {
+			if len(data) < 4 {
+				return nil, short()
+			}
+			values = append(values, int64(int32(binary.LittleEndian.Uint32(data))))
+			data = data[4:]
+		}
+	case parquetInt64:
+		for i := 0; i < count; i++ {
+			if len(data) < 8 {
+				return nil, short()
+			}
+			values = append(values, int64(binary.LittleEndian.Uint64(data)))
+			data = data[8:]
+		}
+	case parquetInt96:
+		for i := 0; i < count; i++ {
+			if len(data) < 12 {
+				return nil, short()
+			}
+			values = append(values, append([]byte(nil), data[:12]...))
+			data = data[12:]
+		}
+	case parquetFloat:
+		for i := 0; i < count; i++ {
+			if len(data) < 4 {
+				return nil, short()
+			}
+			values = append(values, float64(math.Float32frombits(binary.LittleEndian.Uint32(data))))
+			data = data[4:]
+		}
+	case parquetDouble:
+		for i := 0; i < count; i++ {
+			if len(data) < 8 {
+				return nil, short()
+			}
+			values = append(values, math.Float64frombits(binary.LittleEndian.Uint64(data)))
+			data = data[8:]
+		}
+	case parquetByteArray:
+		for i := 0; i < count; i++ {
+			if len(data) < 4 {
+				return nil, short()
+			}
+			n := binary.LittleEndian.Uint32(data)
+			if uint64(n) > uint64(len(data)-4) {
+				return nil, short()
+			}
+			values = append(values, data[4:4+n])
+			data = data[4+n:]
+		}
+	case parquetFixedLenByteArray:
+		n := int(e.typeLength)
+		for i := 0; i < count; i++ {
+			if n <= 0 || len(data) < n {
+				return nil, short()
+			}
+			values = append(values, data[:n])
+			data = data[n:]
+		}
+	default:
+		return nil, fmt.Errorf("unsupported physical type %d", e.physicalType)
+	}
+	return values, nil
+}
+
+// julianEpoch is the Julian day number of 1970-01-01.
+const julianEpoch = 2440588
+
+// convertParquetValue turns a decoded physical value into the Record value of
+// its logical type: timestamps and dates become time.Time, decimals float64,
+// strings string and integers int64.
+func convertParquetValue(v interface{}, e *parquetSchemaElement) interface{} {
+	switch raw := v.(type) {
+	case int64:
+		switch {
+		case e.timestampUnit > 0:
+			return time.Unix(raw/e.timestampUnit, raw%e.timestampUnit*(1e9/e.timestampUnit)).UTC()
+		case e.isDate:
+			return time.Unix(raw*86400, 0).UTC()
+		case e.isDecimal:
+			return float64(raw) / math.Pow10(int(e.scale))
+		case e.physicalType == parquetInt32 && e.convertedType >= convertedUint8 && e.convertedType <= convertedUint32:
+			return int64(uint32(raw))
+		}
+		return raw
+	case []byte:
+		switch {
+		case e.physicalType == parquetInt96:
+			nanos := int64(binary.LittleEndian.Uint64(raw))
+			days := int64(binary.LittleEndian.Uint32(raw[8:]))
+			return time.Unix((days-julianEpoch)*86400, nanos).UTC()
+		case e.isDecimal:
+			return bigEndianDecimal(raw, int(e.scale))
+		}
+		return string(raw)
+	}
+	return v
+}
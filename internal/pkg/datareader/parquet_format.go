@@ -0,0 +1,420 @@
+package datareader
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// This file decodes the Thrift compact protocol structures of the Parquet
+// format: the file footer and the page headers.
+
+// Parquet physical types.
+const (
+	parquetBoolean           = 0
+	parquetInt32             = 1
+	parquetInt64             = 2
+	parquetInt96             = 3
+	parquetFloat             = 4
+	parquetDouble            = 5
+	parquetByteArray         = 6
+	parquetFixedLenByteArray = 7
+)
+
+// Parquet field repetition types.
+const (
+	parquetRequired = 0
+	parquetOptional = 1
+	parquetRepeated = 2
+)
+
+// Parquet converted types used when decoding values.
+const (
+	convertedMap             = 1
+	convertedMapKeyValue     = 2
+	convertedList            = 3
+	convertedDecimal         = 5
+	convertedDate            = 6
+	convertedTimestampMillis = 9
+	convertedTimestampMicros = 10
+	convertedUint8           = 11
+	convertedUint16          = 12
+	convertedUint32          = 13
+)
+
+// Parquet encodings.
+const (
+	encodingPlain           = 0
+	encodingPlainDictionary = 2
+	encodingRLE             = 3
+	encodingRLEDictionary   = 8
+)
+
+// Parquet page types.
+const (
+	pageData       = 0
+	pageDictionary = 2
+	pageDataV2     = 3
+)
+
+// Parquet compression codecs.
+const (
+	codecUncompressed = 0
+	codecSnappy       = 1
+	codecGzip         = 2
+	codecZstd         = 6
+)
+
+// Thrift compact protocol types.
+const (
+	thriftStop       = 0
+	thriftTrue       = 1
+	thriftFalse      = 2
+	thriftByte       = 3
+	thriftI16        = 4
+	thriftI32        = 5
+	thriftI64        = 6
+	thriftDouble     = 7
+	thriftBinary     = 8
+	thriftList       = 9
+	thriftSet        = 10
+	thriftMap        = 11
+	thriftStructType = 12
+	thriftMaxDepth   = 64
+	thriftMaxLength  = 1 << 28
+)
+
+// thriftStruct holds the fields of a decoded struct by field id. Integers are
+// int64, binaries []byte, lists []interface{} and structs thriftStruct.
+type thriftStruct map[int16]interface{}
+
+func (s thriftStruct) int(id int16) int64 {
+	v, _ := s[id].(int64)
+	return v
+}
+
+func (s thriftStruct) has(id int16) bool {
+	_, ok := s[id]
+	return ok
+}
+
+func (s thriftStruct) bool(id int16) bool {
+	v, _ := s[id].(bool)
+	return v
+}
+
+func (s thriftStruct) string(id int16) string {
+	v, _ := s[id].([]byte)
+	return string(v)
+}
+
+func (s thriftStruct) strct(id int16) thriftStruct {
+	v, _ := s[id].(thriftStruct)
+	return v
+}
+
+func (s thriftStruct) list(id int16) []interface{} {
+	v, _ := s[id].([]interface{})
+	return v
+}
+
+// thriftDecoder reads Thrift compact protocol values from a buffer.
+type thriftDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *thriftDecoder) byte() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, fmt.Errorf("unexpected end of thrift data")
+	}
+	b := d.data[d.pos]
+	d.pos++
+	return b, nil
+}
+
+func (d *thriftDecoder) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(d.data[d.pos:])
+	if n <= 0 {
+		return 0, fmt.Errorf("invalid thrift varint")
+	}
+	d.pos += n
+	return v, nil
+}
+
+func (d *thriftDecoder) varint() (int64, error) {
+	v, err := d.uvarint()
+	return int64(v>>1) ^ -int64(v&1), err
+}
+
+func (d *thriftDecoder) value(typ byte, depth int) (interface{}, error) {
+	if depth > thriftMaxDepth {
+		return nil, fmt.Errorf("thrift data nested too deeply")
+	}
+	switch typ {
+	case thriftTrue:
+		return true, nil
+	case thriftFalse:
+		return false, nil
+	case thriftByte:
+		b, err := d.byte()
+		return int64(int8(b)), err
+	case thriftI16, thriftI32, thriftI64:
+		return d.varint()
+	case thriftDouble:
+		if d.pos+8 > len(d.data) {
+			return nil, fmt.Errorf("unexpected end of thrift data")
+		}
+		v := math.Float64frombits(binary.LittleEndian.Uint64(d.data[d.pos:]))
+		d.pos += 8
+		return v, nil
+	case thriftBinary:
+		n, err := d.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(len(d.data)-d.pos) {
+			return nil, fmt.Errorf("thrift binary of %d bytes exceeds data", n)
+		}
+		v := d.data[d.pos : d.pos+int(n)]
+		d.pos += int(n)
+		return v, nil
+	case thriftList, thriftSet:
+		return d.list(depth)
+	case thriftMap:
+		return d.skipMap(depth)
+	case thriftStructType:
+		return d.strct(depth)
+	default:
+		return nil, fmt.Errorf("unknown thrift type %d", typ)
+	}
+}
+
+func (d *thriftDecoder) list(depth int) ([]interface{}, error) {
+	header, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	size := uint64(header >> 4)
+	if size == 15 {
+		if size, err = d.uvarint(); err != nil {
+			return nil, err
+		}
+	}
+	if size > thriftMaxLength {
+		return nil, fmt.Errorf("thrift list of %d elements is too long", size)
+	}
+	elemType := header & 0x0f
+	list := make([]interface{}, 0, min(size, 1024))
+	for i := uint64(0); i < size; i++ {
+		var v interface{}
+		if elemType == thriftTrue || elemType == thriftFalse {
+			// Booleans in containers take a byte each.
+			var b byte
+			b, err = d.byte()
+			v = b == thriftTrue
+		} else {
+			v, err = d.value(elemType, depth+1)
+		}
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+// skipMap reads past a map; no field read from Parquet metadata is a map.
+func (d *thriftDecoder) skipMap(depth int) (interface{}, error) {
+	size, err := d.uvarint()
+	if err != nil || size == 0 {
+		return nil, err
+	}
+	if size > thriftMaxLength {
+		return nil, fmt.Errorf("thrift map of %d entries is too long", size)
+	}
+	types, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < size; i++ {
+		if _, err := d.value(types>>4, depth+1); err != nil {
+			return nil, err
+		}
+		if _, err := d.value(types&0x0f, depth+1); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (d *thriftDecoder) strct(depth int) (thriftStruct, error) {
+	s := make(thriftStruct)
+	var id int16
+	for {
+		header, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		typ := header & 0x0f
+		if typ == thriftStop {
+			return s, nil
+		}
+		if delta := int16(header >> 4); delta != 0 {
+			id += delta
+		} else {
+			v, err := d.varint()
+			if err != nil {
+				return nil, err
+			}
+			id = int16(v)
+		}
+		if s[id], err = d.value(typ, depth+1); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parquetSchemaElement is a node of the flattened schema tree in the footer.
+type parquetSchemaElement struct {
+	name          string
+	physicalType  int64
+	typeLength    int64
+	repetition    int64
+	numChildren   int64
+	convertedType int64
+	scale         int64
+	// timestampUnit is 1000, 1000000 or 1000000000 ticks per second for
+	// timestamps, 0 otherwise.
+	timestampUnit int64
+	isDate        bool
+	isDecimal     bool
+	isLeaf        bool
+	// isList and isMap mark groups annotated as lists and maps, whose
+	// repeated child holds the elements or the key-value pairs.
+	isList, isMap bool
+	children      []*parquetSchemaElement
+}
+
+func newParquetSchemaElement(s thriftStruct) parquetSchemaElement {
+	e := parquetSchemaElement{
+		name:          s.string(4),
+		physicalType:  s.int(1),
+		typeLength:    s.int(2),
+		repetition:    s.int(3),
+		numChildren:   s.int(5),
+		convertedType: -1,
+		scale:         s.int(7),
+		isLeaf:        s.has(1),
+	}
+	if s.has(6) {
+		e.convertedType = s.int(6)
+	}
+	switch e.convertedType {
+	case convertedList:
+		e.isList = true
+	case convertedMap, convertedMapKeyValue:
+		e.isMap = true
+	case convertedDate:
+		e.isDate = true
+	case convertedDecimal:
+		e.isDecimal = true
+	case convertedTimestampMillis:
+		e.timestampUnit = 1e3
+	case convertedTimestampMicros:
+		e.timestampUnit = 1e6
+	}
+
+	logical := s.strct(10)
+	switch {
+	case logical.has(2):
+		e.isMap = true
+	case logical.has(3):
+		e.isList = true
+	case logical.has(5):
+		e.isDecimal = true
+		e.scale = logical.strct(5).int(1)
+	case logical.has(6):
+		e.isDate = true
+	case logical.has(8):
+		unit := logical.strct(8).strct(2)
+		switch {
+		case unit.has(1):
+			e.timestampUnit = 1e3
+		case unit.has(2):
+			e.timestampUnit = 1e6
+		case unit.has(3):
+			e.timestampUnit = 1e9
+		}
+	}
+	return e
+}
+
+// parquetColumnChunk locates the pages of one column in one row group.
+type parquetColumnChunk struct {
+	path             []string
+	codec            int64
+	numValues        int64
+	dataPageOffset   int64
+	dictionaryOffset int64
+	totalSize        int64
+}
+
+func newParquetColumnChunk(s thriftStruct) (parquetColumnChunk, error) {
+	meta := s.strct(3)
+	if meta == nil {
+		return parquetColumnChunk{}, fmt.Errorf("column chunk without metadata")
+	}
+	c := parquetColumnChunk{
+		codec:          meta.int(4),
+		numValues:      meta.int(5),
+		dataPageOffset: meta.int(9),
+		totalSize:      meta.int(7),
+	}
+	for _, p := range meta.list(3) {
+		name, _ := p.([]byte)
+		c.path = append(c.path, string(name))
+	}
+	if meta.has(11) && meta.int(11) > 0 {
+		c.dictionaryOffset = meta.int(11)
+	}
+	return c, nil
+}
+
+// parquetPageHeader is the header preceding every page of a column chunk.
+type parquetPageHeader struct {
+	pageType         int64
+	uncompressedSize int64
+	compressedSize   int64
+	numValues        int64
+	encoding         int64
+	// Levels of data page v2, stored uncompressed before the values.
+	defLevelsLength int64
+	repLevelsLength int64
+	isCompressed    bool
+}
+
+func newParquetPageHeader(s thriftStruct) parquetPageHeader {
+	h := parquetPageHeader{
+		pageType:         s.int(1),
+		uncompressedSize: s.int(2),
+		compressedSize:   s.int(3),
+		isCompressed:     true,
+	}
+	switch h.pageType {
+	case pageData:
+		data := s.strct(5)
+		h.numValues, h.encoding = data.int(1), data.int(2)
+	case pageDictionary:
+		dict := s.strct(7)
+		h.numValues, h.encoding = dict.int(1), dict.int(2)
+	case pageDataV2:
+		data := s.strct(8)
+		h.numValues, h.encoding = data.int(1), data.int(4)
+		h.defLevelsLength, h.repLevelsLength = data.int(5), data.int(6)
+		if data.has(7) {
+			h.isCompressed = data.bool(7)
+		}
+	}
+	return h
+}
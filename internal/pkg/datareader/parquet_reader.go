@@ -0,0 +1,610 @@
+package datareader
+
+import (
+	"bytes"
+	"data-comparator/internal/pkg/config"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// parquetMagic starts and ends every Parquet file.
+var parquetMagic = []byte("PAR1")
+
+// ParquetReader reads records from an Apache Parquet file, one row group at a
+// time. Values keep their types: integers are int64, floating point numbers
+// and decimals float64, timestamps and dates time.Time, and nested groups
+// become nested records. Lists become []interface{} and maps records keyed by
+// their keys as text, whether written in the three-level layout of the
+// Parquet spec, as by Arrow and Spark, or as legacy repeated fields. Pages may
+// be PLAIN, RLE (booleans) or dictionary encoded, uncompressed, snappy, gzip
+// or zstd compressed.
+type ParquetReader struct {
+	path      string
+	file      io.Closer
+	input     io.ReaderAt
+	root      *parquetSchemaElement
+	columns   []parquetColumn
+	rowGroups []thriftStruct
+	group     int
+	rows      []Record
+	records   int
+	// nested is set when the schema holds lists or maps, whose records are
+	// reshaped after assembly.
+	nested bool
+}
+
+// parquetColumn is a leaf column and the schema nodes on its path.
+type parquetColumn struct {
+	nodes  []*parquetSchemaElement
+	maxDef int
+	maxRep int
+}
+
+func (c *parquetColumn) leaf() *parquetSchemaElement {
+	return c.nodes[len(c.nodes)-1]
+}
+
+func (c *parquetColumn) name() string {
+	names := make([]string, len(c.nodes))
+	for i, node := range c.nodes {
+		names[i] = node.name
+	}
+	return strings.Join(names, ".")
+}
+
+// NewParquetReader creates a new reader for Parquet files.
+func NewParquetReader(cfg config.Source) (DataReader, error) {
+	file, err := openSource(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open parquet file %s: %w", cfg.Path, err)
+	}
+	size, err := file.Size()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to stat parquet file %s: %w", cfg.Path, err)
+	}
+	return newParquetReader(file, size, file, cfg.Path)
+}
+
+// newParquetReader reads the footer of the size bytes of Parquet data in
+// input, named path, and closes closer when done or on error.
+func newParquetReader(input io.ReaderAt, size int64, closer io.Closer, path string) (*ParquetReader, error) {
+	r, err := openParquet(input, size, path)
+	if err != nil {
+		closer.Close()
+		return nil, err
+	}
+	r.file = closer
+	return r, nil
+}
+
+func openParquet(input io.ReaderAt, size int64, path string) (*ParquetReader, error) {
+	if size < 12 {
+		return nil, fmt.Errorf("%s is not a parquet file: too short", path)
+	}
+	tail := make([]byte, 8)
+	if _, err := input.ReadAt(tail, size-8); err != nil {
+		return nil, fmt.Errorf("failed to read parquet footer of %s: %w", path, err)
+	}
+	if !bytes.Equal(tail[4:], parquetMagic) {
+		return nil, fmt.Errorf("%s is not a parquet file: missing magic number", path)
+	}
+	footerSize := int64(binary.LittleEndian.Uint32(tail))
+	if footerSize > size-12 {
+		return nil, fmt.Errorf("invalid parquet footer size %d in %s", footerSize, path)
+	}
+	footer := make([]byte, footerSize)
+	if _, err := input.ReadAt(footer, size-8-footerSize); err != nil {
+		return nil, fmt.Errorf("failed to read parquet footer of %s: %w", path, err)
+	}
+	d := &thriftDecoder{data: footer}
+	meta, err := d.strct(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode parquet footer of %s: %w", path, err)
+	}
+
+	var elements []*parquetSchemaElement
+	for _, e := range meta.list(2) {
+		s, _ := e.(thriftStruct)
+		element := newParquetSchemaElement(s)
+		elements = append(elements, &element)
+	}
+	if len(elements) == 0 {
+		return nil, fmt.Errorf("parquet file %s has no schema", path)
+	}
+	r := &ParquetReader{path: path, input: input, root: elements[0]}
+	next := 1
+	for i := int64(0); i < elements[0].numChildren; i++ {
+		if next, err = r.addColumns(elements, next, nil, 0, 0); err != nil {
+			return nil, fmt.Errorf("parquet file %s: %w", path, err)
+		}
+	}
+	for _, g := range meta.list(4) {
+		group, _ := g.(thriftStruct)
+		r.rowGroups = append(r.rowGroups, group)
+	}
+	return r, nil
+}
+
+// addColumns adds the leaf columns of the schema subtree at elements[i] and
+// returns the index following the subtree.
+func (r *ParquetReader) addColumns(elements []*parquetSchemaElement, i int, parents []*parquetSchemaElement, maxDef, maxRep int) (int, error) {
+	if i >= len(elements) {
+		return i, fmt.Errorf("schema ends unexpectedly")
+	}
+	e := elements[i]
+	parent := r.root
+	if len(parents) > 0 {
+		parent = parents[len(parents)-1]
+	}
+	parent.children = append(parent.children, e)
+	nodes := append(append([]*parquetSchemaElement(nil), parents...), e)
+	switch e.repetition {
+	case parquetRepeated:
+		maxDef++
+		maxRep++
+		r.nested = true
+	case parquetOptional:
+		maxDef++
+	}
+	if e.isList || e.isMap {
+		r.nested = true
+	}
+	if e.isLeaf {
+		r.columns = append(r.columns, parquetColumn{nodes: nodes, maxDef: maxDef, maxRep: maxRep})
+		return i + 1, nil
+	}
+
+	next := i + 1
+	for c := int64(0); c < e.numChildren; c++ {
+		var err error
+		if next, err = r.addColumns(elements, next, nodes, maxDef, maxRep); err != nil {
+			return next, err
+		}
+	}
+	return next, nil
+}
+
+// Read returns the next record, or io.EOF after the last row group.
+func (r *ParquetReader) Read() (Record, error) {
+	for len(r.rows) == 0 {
+		if r.group >= len(r.rowGroups) {
+			return nil, io.EOF
+		}
+		rows, err := r.readRowGroup(r.rowGroups[r.group])
+		r.group++
+		if err != nil {
+			return nil, &ParseError{Source: r.path, Record: r.records + 1, Offset: -1, Err: fmt.Errorf("row group %d: %w", r.group, err)}
+		}
+		r.rows = rows
+	}
+	rec := r.rows[0]
+	r.rows[0] = nil
+	r.rows = r.rows[1:]
+	r.records++
+	return rec, nil
+}
+
+// readRowGroup decodes every column of a row group and assembles its rows.
+func (r *ParquetReader) readRowGroup(group thriftStruct) ([]Record, error) {
+	numRows := int(group.int(3))
+	chunks := group.list(1)
+	if len(chunks) != len(r.columns) {
+		return nil, fmt.Errorf("%d column chunks for %d columns", len(chunks), len(r.columns))
+	}
+
+	rows := make([]Record, numRows)
+	for i := range rows {
+		rows[i] = make(Record, len(r.columns))
+	}
+	for i := range r.columns {
+		col := &r.columns[i]
+		s, _ := chunks[i].(thriftStruct)
+		chunk, err := newParquetColumnChunk(s)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", col.name(), err)
+		}
+		values, defs, reps, err := r.readColumnChunk(chunk, col)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", col.name(), err)
+		}
+		if err := col.assembleAll(rows, values, defs, reps); err != nil {
+			return nil, fmt.Errorf("column %s: %w", col.name(), err)
+		}
+	}
+	if r.nested {
+		for _, row := range rows {
+			shapeGroup(r.root, row)
+		}
+	}
+	return rows, nil
+}
+
+// assembleAll sets the values of the column in rows from the definition and
+// repetition levels of its entries. An entry of repetition level 0 starts a
+// row, and one of level l starts a new element of the l-th repeated node on
+// the path, within the current elements of those above it.
+func (c *parquetColumn) assembleAll(rows []Record, values []interface{}, defs, reps []int32) error {
+	row, next := -1, 0
+	indices := make([]int, c.maxRep+1)
+	for i, def := range defs {
+		rep := 0
+		if reps != nil {
+			rep = int(reps[i])
+		}
+		if rep > c.maxRep || int(def) > c.maxDef {
+			return fmt.Errorf("invalid levels %d and %d", rep, def)
+		}
+		if rep == 0 {
+			row++
+			if row >= len(rows) {
+				return fmt.Errorf("more values than the %d rows", len(rows))
+			}
+		} else if row < 0 {
+			return fmt.Errorf("first value continues a row")
+		}
+		for level := 1; level <= c.maxRep; level++ {
+			switch {
+			case rep < level:
+				indices[level] = 0
+			case rep == level:
+				indices[level]++
+			}
+		}
+		var value interface{}
+		if int(def) == c.maxDef {
+			if next >= len(values) {
+				return fmt.Errorf("%d values for more defined entries", len(values))
+			}
+			value = values[next]
+			next++
+		}
+		c.assemble(rows[row], int(def), indices, value)
+	}
+	if row+1 < len(rows) {
+		return fmt.Errorf("values of %d rows for %d rows", row+1, len(rows))
+	}
+	return nil
+}
+
+// assemble sets an entry of the column in rec, creating the nested records
+// of the groups on its path that are defined at level def, and the elements
+// of the repeated nodes at indices, by repetition level.
+func (c *parquetColumn) assemble(rec Record, def int, indices []int, value interface{}) {
+	current := map[string]interface{}(rec)
+	defined, repeated := 0, 0
+	for i, node := range c.nodes {
+		if node.repetition != parquetRequired {
+			defined++
+		}
+		if def < defined {
+			// This node is null, or an empty list if repeated; the nodes
+			// below it are absent.
+			if _, ok := current[node.name]; !ok {
+				if node.repetition == parquetRepeated {
+					current[node.name] = []interface{}{}
+				} else {
+					current[node.name] = nil
+				}
+			}
+			return
+		}
+		last := i == len(c.nodes)-1
+		if node.repetition == parquetRepeated {
+			repeated++
+			index := indices[repeated]
+			list, _ := current[node.name].([]interface{})
+			for len(list) <= index {
+				if last {
+					list = append(list, nil)
+				} else {
+					list = append(list, make(map[string]interface{}))
+				}
+			}
+			current[node.name] = list
+			if last {
+				list[index] = value
+				return
+			}
+			current = list[index].(map[string]interface{})
+			continue
+		}
+		if last {
+			current[node.name] = value
+			return
+		}
+		child, ok := current[node.name].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			current[node.name] = child
+		}
+		current = child
+	}
+}
+
+// shapeGroup replaces the list and map annotated groups among the children
+// of node in m, as assembled, by the lists and maps they hold.
+func shapeGroup(node *parquetSchemaElement, m map[string]interface{}) {
+	for _, child := range node.children {
+		v, ok := m[child.name]
+		if !ok {
+			continue
+		}
+		if child.repetition == parquetRepeated {
+			list, _ := v.([]interface{})
+			for i, element := range list {
+				list[i] = shapeValue(child, element)
+			}
+			continue
+		}
+		m[child.name] = shapeValue(child, v)
+	}
+}
+
+// shapeValue returns a single value of node, as assembled, with its list and
+// map annotated groups replaced by the lists and maps they hold.
+func shapeValue(node *parquetSchemaElement, v interface{}) interface{} {
+	group, ok := v.(map[string]interface{})
+	if !ok || node.isLeaf {
+		return v
+	}
+	if len(node.children) == 1 && node.children[0].repetition == parquetRepeated {
+		switch {
+		case node.isList:
+			return shapeList(node, group)
+		case node.isMap:
+			return shapeMap(node, group)
+		}
+	}
+	shapeGroup(node, group)
+	return group
+}
+
+// shapeList returns the elements of a list annotated group. In the three-level
+// layout, the repeated child wraps each element in a group of a single field;
+// in the legacy two-level layouts, it is the element itself.
+func shapeList(node *parquetSchemaElement, group map[string]interface{}) []interface{} {
+	repeated := node.children[0]
+	list, _ := group[repeated.name].([]interface{})
+	threeLevel := !repeated.isLeaf && len(repeated.children) == 1 && repeated.name != "array" && repeated.name != node.name+"_tuple"
+	out := make([]interface{}, len(list))
+	for i, element := range list {
+		if !threeLevel {
+			out[i] = shapeValue(repeated, element)
+			continue
+		}
+		child := repeated.children[0]
+		wrapper, _ := element.(map[string]interface{})
+		out[i] = shapeValue(child, wrapper[child.name])
+	}
+	return out
+}
+
+// shapeMap returns the entries of a map annotated group, whose repeated child
+// holds a key and a value per entry, keyed by the key as text.
+func shapeMap(node *parquetSchemaElement, group map[string]interface{}) map[string]interface{} {
+	keyValue := node.children[0]
+	entries, _ := group[keyValue.name].([]interface{})
+	out := make(map[string]interface{}, len(entries))
+	if len(keyValue.children) == 0 {
+		return out
+	}
+	key := keyValue.children[0]
+	for _, e := range entries {
+		entry, _ := e.(map[string]interface{})
+		var value interface{}
+		if len(keyValue.children) > 1 {
+			child := keyValue.children[1]
+			value = shapeValue(child, entry[child.name])
+		}
+		out[fmt.Sprint(entry[key.name])] = value
+	}
+	return out
+}
+
+// readColumnChunk decodes the pages of a column chunk into its non-null
+// values and the definition and repetition levels of every entry. The
+// repetition levels are nil for columns without repeated nodes.
+func (r *ParquetReader) readColumnChunk(chunk parquetColumnChunk, col *parquetColumn) ([]interface{}, []int32, []int32, error) {
+	start := chunk.dataPageOffset
+	if chunk.dictionaryOffset > 0 && chunk.dictionaryOffset < start {
+		start = chunk.dictionaryOffset
+	}
+	if chunk.totalSize <= 0 || chunk.totalSize > 1<<31 {
+		return nil, nil, nil, fmt.Errorf("invalid column chunk size %d", chunk.totalSize)
+	}
+	data := make([]byte, chunk.totalSize)
+	if _, err := r.input.ReadAt(data, start); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read column chunk: %w", err)
+	}
+
+	leaf := col.leaf()
+	var dictionary, values []interface{}
+	var defs, reps []int32
+	for int64(len(defs)) < chunk.numValues && len(data) > 0 {
+		d := &thriftDecoder{data: data}
+		s, err := d.strct(0)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid page header: %w", err)
+		}
+		header := newParquetPageHeader(s)
+		data = data[d.pos:]
+		if header.compressedSize < 0 || header.compressedSize > int64(len(data)) {
+			return nil, nil, nil, fmt.Errorf("page of %d bytes exceeds the column chunk", header.compressedSize)
+		}
+		page := data[:header.compressedSize]
+		data = data[header.compressedSize:]
+
+		switch header.pageType {
+		case pageDictionary:
+			raw, err := decompressParquet(chunk.codec, page, header.uncompressedSize)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if dictionary, err = decodePlain(raw, leaf, int(header.numValues)); err != nil {
+				return nil, nil, nil, fmt.Errorf("dictionary page: %w", err)
+			}
+			for i, v := range dictionary {
+				dictionary[i] = convertParquetValue(v, leaf)
+			}
+		case pageData, pageDataV2:
+			pageReps, pageDefs, raw, err := readPageLevels(header, chunk.codec, page, col.maxRep, col.maxDef)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			nonNull := 0
+			for _, def := range pageDefs {
+				if int(def) == col.maxDef {
+					nonNull++
+				}
+			}
+			pageValues, err := decodePageValues(raw, header.encoding, leaf, dictionary, nonNull)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			defs = append(defs, pageDefs...)
+			reps = append(reps, pageReps...)
+			values = append(values, pageValues...)
+		}
+	}
+	return values, defs, reps, nil
+}
+
+// readPageLevels returns the repetition and definition levels of a data
+// page and its decompressed values section. The repetition levels are nil
+// when maxRep is 0.
+func readPageLevels(header parquetPageHeader, codec int64, page []byte, maxRep, maxDef int) ([]int32, []int32, []byte, error) {
+	count := int(header.numValues)
+	allDefined := func() []int32 {
+		defs := make([]int32, count)
+		for i := range defs {
+			defs[i] = int32(maxDef)
+		}
+		return defs
+	}
+
+	if header.pageType == pageDataV2 {
+		levelsLength := header.repLevelsLength + header.defLevelsLength
+		if header.repLevelsLength < 0 || header.defLevelsLength < 0 || levelsLength > int64(len(page)) {
+			return nil, nil, nil, fmt.Errorf("page levels exceed the page")
+		}
+		raw := page[levelsLength:]
+		if header.isCompressed {
+			var err error
+			if raw, err = decompressParquet(codec, raw, header.uncompressedSize-levelsLength); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		var reps []int32
+		if maxRep > 0 {
+			var err error
+			if reps, err = decodeHybrid(page[:header.repLevelsLength], levelBitWidth(maxRep), count); err != nil {
+				return nil, nil, nil, fmt.Errorf("repetition levels: %w", err)
+			}
+		}
+		if maxDef == 0 {
+			return reps, allDefined(), raw, nil
+		}
+		defs, err := decodeHybrid(page[header.repLevelsLength:levelsLength], levelBitWidth(maxDef), count)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("definition levels: %w", err)
+		}
+		return reps, defs, raw, nil
+	}
+
+	raw, err := decompressParquet(codec, page, header.uncompressedSize)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// Version 1 pages hold the repetition, then the definition levels, each
+	// after its length.
+	levels := func(maxLevel int, kind string) ([]int32, error) {
+		if len(raw) < 4 {
+			return nil, fmt.Errorf("truncated %s levels", kind)
+		}
+		length := binary.LittleEndian.Uint32(raw)
+		if uint64(length) > uint64(len(raw)-4) {
+			return nil, fmt.Errorf("%s levels exceed the page", kind)
+		}
+		decoded, err := decodeHybrid(raw[4:4+length], levelBitWidth(maxLevel), count)
+		raw = raw[4+length:]
+		if err != nil {
+			return nil, fmt.Errorf("%s levels: %w", kind, err)
+		}
+		return decoded, nil
+	}
+	var reps []int32
+	if maxRep > 0 {
+		if reps, err = levels(maxRep, "repetition"); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if maxDef == 0 {
+		return reps, allDefined(), raw, nil
+	}
+	defs, err := levels(maxDef, "definition")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return reps, defs, raw, nil
+}
+
+// decodePageValues decodes the count non-null values of a data page.
+func decodePageValues(raw []byte, encoding int64, leaf *parquetSchemaElement, dictionary []interface{}, count int) ([]interface{}, error) {
+	switch encoding {
+	case encodingPlain:
+		values, err := decodePlain(raw, leaf, count)
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			values[i] = convertParquetValue(v, leaf)
+		}
+		return values, nil
+	case encodingRLE:
+		// Booleans, as bit width 1 runs after their length.
+		if leaf.physicalType != parquetBoolean {
+			return nil, fmt.Errorf("rle encoding of a non-boolean column")
+		}
+		if len(raw) < 4 || uint64(binary.LittleEndian.Uint32(raw)) > uint64(len(raw)-4) {
+			return nil, fmt.Errorf("truncated rle values")
+		}
+		bits, err := decodeHybrid(raw[4:4+binary.LittleEndian.Uint32(raw)], 1, count)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, count)
+		for i, b := range bits {
+			values[i] = b == 1
+		}
+		return values, nil
+	case encodingPlainDictionary, encodingRLEDictionary:
+		if count == 0 {
+			return nil, nil
+		}
+		if len(raw) == 0 {
+			return nil, fmt.Errorf("missing dictionary index bit width")
+		}
+		indices, err := decodeHybrid(raw[1:], int(raw[0]), count)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, count)
+		for i, index := range indices {
+			if index < 0 || int(index) >= len(dictionary) {
+				return nil, fmt.Errorf("dictionary index %d out of range", index)
+			}
+			values[i] = dictionary[index]
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("unsupported encoding %d (only plain, rle and dictionary encodings are supported)", encoding)
+	}
+}
+
+// Close closes the parquet file.
+func (r *ParquetReader) Close() error {
+	return r.file.Close()
+}
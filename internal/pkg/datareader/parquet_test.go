@@ -0,0 +1,726 @@
+package datareader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"data-comparator/internal/pkg/config"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// The helpers below write small Parquet files for the tests: a Thrift compact
+// protocol encoder and a writer for flat and nested, optional and repeated
+// columns.
+
+type tfield struct {
+	id int16
+	v  interface{}
+}
+
+// tstruct is a Thrift struct with fields in ascending id order.
+type tstruct []tfield
+
+type thriftWriter struct {
+	bytes.Buffer
+}
+
+func (w *thriftWriter) varint(v int64) {
+	w.Write(binary.AppendUvarint(nil, uint64(v<<1^v>>63)))
+}
+
+func thriftType(v interface{}) byte {
+	switch v := v.(type) {
+	case bool:
+		if v {
+			return thriftTrue
+		}
+		return thriftFalse
+	case int32:
+		return thriftI32
+	case int64:
+		return thriftI64
+	case string, []byte:
+		return thriftBinary
+	case tstruct:
+		return thriftStructType
+	default:
+		return thriftList
+	}
+}
+
+func (w *thriftWriter) value(v interface{}) {
+	switch v := v.(type) {
+	case bool:
+	case int32:
+		w.varint(int64(v))
+	case int64:
+		w.varint(v)
+	case string:
+		w.Write(binary.AppendUvarint(nil, uint64(len(v))))
+		w.WriteString(v)
+	case []byte:
+		w.Write(binary.AppendUvarint(nil, uint64(len(v))))
+		w.Write(v)
+	case tstruct:
+		w.strct(v)
+	case []int32:
+		w.WriteByte(byte(len(v))<<4 | thriftI32)
+		for _, e := range v {
+			w.varint(int64(e))
+		}
+	case []string:
+		w.WriteByte(byte(len(v))<<4 | thriftBinary)
+		for _, e := range v {
+			w.value(e)
+		}
+	case []tstruct:
+		w.listHeader(len(v), thriftStructType)
+		for _, e := range v {
+			w.strct(e)
+		}
+	}
+}
+
+// listHeader writes the header of a list of n elements of type typ.
+func (w *thriftWriter) listHeader(n int, typ byte) {
+	if n < 15 {
+		w.WriteByte(byte(n)<<4 | typ)
+		return
+	}
+	w.WriteByte(0xf0 | typ)
+	w.Write(binary.AppendUvarint(nil, uint64(n)))
+}
+
+func (w *thriftWriter) strct(s tstruct) {
+	var last int16
+	for _, f := range s {
+		w.WriteByte(byte(f.id-last)<<4 | thriftType(f.v))
+		w.value(f.v)
+		last = f.id
+	}
+	w.WriteByte(thriftStop)
+}
+
+// testParquetColumn is a leaf column written by writeTestParquet.
+type testParquetColumn struct {
+	path       []string
+	optional   []bool // per node on the path, set for repeated ones too
+	typ        int32
+	dictionary bool
+	// maxRep and shred are set for columns with repeated nodes, which shred
+	// a row into the repetition and definition levels of its entries and
+	// the non-null values.
+	maxRep int
+	shred  func(row map[string]interface{}) (reps, defs []int32, values []interface{})
+}
+
+// levels returns the definition level of the column in rec and its value.
+func (c testParquetColumn) levels(rec map[string]interface{}) (int32, interface{}) {
+	var def int32
+	var current interface{} = rec
+	for i, name := range c.path {
+		m, _ := current.(map[string]interface{})
+		current = m[name]
+		if current == nil {
+			return def, nil
+		}
+		if c.optional[i] {
+			def++
+		}
+	}
+	return def, current
+}
+
+func (c testParquetColumn) maxDef() int {
+	n := 0
+	for _, o := range c.optional {
+		if o {
+			n++
+		}
+	}
+	return n
+}
+
+func plainValue(typ int32, v interface{}) []byte {
+	var buf []byte
+	switch typ {
+	case parquetInt32:
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(v.(int32)))
+	case parquetInt64:
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(v.(int64)))
+	case parquetDouble:
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(v.(float64)))
+	case parquetByteArray:
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(v.(string))))
+		buf = append(buf, v.(string)...)
+	}
+	return buf
+}
+
+// bitPack encodes values as a single bit-packed run of the hybrid encoding.
+func bitPack(values []int32, width int) []byte {
+	groups := (len(values) + 7) / 8
+	out := binary.AppendUvarint(nil, uint64(groups<<1|1))
+	packed := make([]byte, groups*width)
+	for i, v := range values {
+		for b := 0; b < width; b++ {
+			bit := i*width + b
+			packed[bit/8] |= byte(v>>b&1) << (bit % 8)
+		}
+	}
+	return append(out, packed...)
+}
+
+// snappyLiteral encodes data as a snappy block of literals.
+func snappyLiteral(data []byte) []byte {
+	out := binary.AppendUvarint(nil, uint64(len(data)))
+	for len(data) > 0 {
+		n := min(len(data), 60)
+		out = append(out, byte(n-1)<<2)
+		out = append(out, data[:n]...)
+		data = data[n:]
+	}
+	return out
+}
+
+func compressTest(codec int32, data []byte) []byte {
+	switch codec {
+	case codecSnappy:
+		return snappyLiteral(data)
+	case codecGzip:
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(data)
+		zw.Close()
+		return buf.Bytes()
+	case codecZstd:
+		zw, _ := zstd.NewWriter(nil)
+		defer zw.Close()
+		return zw.EncodeAll(data, nil)
+	}
+	return data
+}
+
+// writeTestParquet writes rows as a Parquet file with one row group per
+// element of groups and one data page per column chunk, of version 1 or 2.
+func writeTestParquet(t *testing.T, schema []tstruct, columns []testParquetColumn, groups [][]map[string]interface{}, codec int32, pageVersion int) string {
+	t.Helper()
+	var file bytes.Buffer
+	file.Write(parquetMagic)
+
+	var rowGroups []tstruct
+	total := 0
+	for _, rows := range groups {
+		var chunks []tstruct
+		for _, col := range columns {
+			var reps, defs []int32
+			var values []interface{}
+			for _, row := range rows {
+				if col.shred != nil {
+					rowReps, rowDefs, rowValues := col.shred(row)
+					reps, defs, values = append(reps, rowReps...), append(defs, rowDefs...), append(values, rowValues...)
+					continue
+				}
+				def, v := col.levels(row)
+				defs = append(defs, def)
+				if v != nil {
+					values = append(values, v)
+				}
+			}
+			var repLevels, levels []byte
+			if col.maxRep > 0 {
+				repLevels = bitPack(reps, levelBitWidth(col.maxRep))
+			}
+			if col.maxDef() > 0 {
+				levels = bitPack(defs, levelBitWidth(col.maxDef()))
+			}
+
+			start := int64(file.Len())
+			var dictOffset int64
+			var body []byte
+			encoding := int32(encodingPlain)
+			if col.dictionary {
+				var dict []interface{}
+				var indices []int32
+				for _, v := range values {
+					index := -1
+					for i, d := range dict {
+						if d == v {
+							index = i
+						}
+					}
+					if index < 0 {
+						index = len(dict)
+						dict = append(dict, v)
+					}
+					indices = append(indices, int32(index))
+				}
+				var dictData []byte
+				for _, v := range dict {
+					dictData = append(dictData, plainValue(col.typ, v)...)
+				}
+				compressed := compressTest(codec, dictData)
+				header := thriftWriter{}
+				header.strct(tstruct{{1, int32(pageDictionary)}, {2, int32(len(dictData))}, {3, int32(len(compressed))},
+					{7, tstruct{{1, int32(len(dict))}, {2, int32(encodingPlain)}}}})
+				dictOffset = start
+				file.Write(header.Bytes())
+				file.Write(compressed)
+
+				// Indices as RLE runs of one value each.
+				body = []byte{8}
+				for _, index := range indices {
+					body = append(body, 2, byte(index))
+				}
+				encoding = encodingRLEDictionary
+			} else {
+				for _, v := range values {
+					body = append(body, plainValue(col.typ, v)...)
+				}
+			}
+
+			dataOffset := int64(file.Len())
+			header := thriftWriter{}
+			var page []byte
+			if pageVersion == 2 {
+				compressed := compressTest(codec, body)
+				page = append(append(append(page, repLevels...), levels...), compressed...)
+				header.strct(tstruct{{1, int32(pageDataV2)}, {2, int32(len(repLevels) + len(levels) + len(body))}, {3, int32(len(page))},
+					{8, tstruct{{1, int32(len(defs))}, {2, int32(len(defs) - len(values))}, {3, int32(len(rows))}, {4, encoding},
+						{5, int32(len(levels))}, {6, int32(len(repLevels))}}}})
+			} else {
+				var raw []byte
+				if len(repLevels) > 0 {
+					raw = binary.LittleEndian.AppendUint32(raw, uint32(len(repLevels)))
+					raw = append(raw, repLevels...)
+				}
+				if len(levels) > 0 {
+					raw = binary.LittleEndian.AppendUint32(raw, uint32(len(levels)))
+					raw = append(raw, levels...)
+				}
+				raw = append(raw, body...)
+				page = compressTest(codec, raw)
+				header.strct(tstruct{{1, int32(pageData)}, {2, int32(len(raw))}, {3, int32(len(page))},
+					{5, tstruct{{1, int32(len(defs))}, {2, encoding}, {3, int32(encodingRLE)}, {4, int32(encodingRLE)}}}})
+			}
+			file.Write(header.Bytes())
+			file.Write(page)
+
+			size := int64(file.Len()) - start
+			meta := tstruct{{1, col.typ}, {2, []int32{encodingPlain, encodingRLE}}, {3, col.path}, {4, codec},
+				{5, int64(len(defs))}, {6, size}, {7, size}, {9, dataOffset}}
+			if dictOffset > 0 {
+				meta = append(meta, tfield{11, dictOffset})
+			}
+			chunks = append(chunks, tstruct{{2, start}, {3, meta}})
+		}
+		rowGroups = append(rowGroups, tstruct{{1, chunks}, {2, int64(0)}, {3, int64(len(rows))}})
+		total += len(rows)
+	}
+
+	footer := thriftWriter{}
+	footer.strct(tstruct{{1, int32(1)}, {2, schema}, {3, int64(total)}, {4, rowGroups}})
+	file.Write(footer.Bytes())
+	file.Write(binary.LittleEndian.AppendUint32(nil, uint32(footer.Len())))
+	file.Write(parquetMagic)
+
+	path := filepath.Join(t.TempDir(), "data.parquet")
+	if err := os.WriteFile(path, file.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write parquet file: %v", err)
+	}
+	return path
+}
+
+func TestParquetReader(t *testing.T) {
+	timestampMicros := tstruct{{8, tstruct{{1, true}, {2, tstruct{{2, tstruct{}}}}}}}
+	schema := []tstruct{
+		{{4, "schema"}, {5, int32(5)}},
+		{{1, int32(parquetInt64)}, {3, int32(0)}, {4, "id"}},
+		{{1, int32(parquetByteArray)}, {3, int32(parquetOptional)}, {4, "name"}, {6, int32(0)}},
+		{{1, int32(parquetDouble)}, {3, int32(parquetOptional)}, {4, "score"}},
+		{{1, int32(parquetInt64)}, {3, int32(0)}, {4, "created"}, {10, timestampMicros}},
+		{{3, int32(parquetOptional)}, {4, "address"}, {5, int32(2)}},
+		{{1, int32(parquetByteArray)}, {3, int32(parquetOptional)}, {4, "city"}, {6, int32(0)}},
+		{{1, int32(parquetInt32)}, {3, int32(0)}, {4, "zip"}},
+	}
+	columns := []testParquetColumn{
+		{path: []string{"id"}, optional: []bool{false}, typ: parquetInt64},
+		{path: []string{"name"}, optional: []bool{true}, typ: parquetByteArray},
+		{path: []string{"score"}, optional: []bool{true}, typ: parquetDouble},
+		{path: []string{"created"}, optional: []bool{false}, typ: parquetInt64},
+		{path: []string{"address", "city"}, optional: []bool{true, true}, typ: parquetByteArray, dictionary: true},
+		{path: []string{"address", "zip"}, optional: []bool{true, false}, typ: parquetInt32},
+	}
+	created := time.Date(2025, 9, 10, 12, 0, 0, 123456000, time.UTC)
+	rows := []map[string]interface{}{
+		{"id": int64(1), "name": "alice", "score": 1.5, "created": created.UnixMicro(), "address": map[string]interface{}{"city": "Berlin", "zip": int32(10115)}},
+		{"id": int64(2), "name": nil, "score": 2.25, "created": created.UnixMicro() + 1, "address": nil},
+		{"id": int64(3), "name": "carol", "score": nil, "created": created.UnixMicro() + 2, "address": map[string]interface{}{"city": nil, "zip": int32(20095)}},
+		{"id": int64(4), "name": "dave", "score": 4.0, "created": created.UnixMicro() + 3, "address": map[string]interface{}{"city": "Berlin", "zip": int32(10117)}},
+	}
+	want := []Record{
+		{"id": int64(1), "name": "alice", "score": 1.5, "created": created, "address": map[string]interface{}{"city": "Berlin", "zip": int64(10115)}},
+		{"id": int64(2), "name": nil, "score": 2.25, "created": created.Add(time.Microsecond), "address": nil},
+		{"id": int64(3), "name": "carol", "score": nil, "created": created.Add(2 * time.Microsecond), "address": map[string]interface{}{"city": nil, "zip": int64(20095)}},
+		{"id": int64(4), "name": "dave", "score": 4.0, "created": created.Add(3 * time.Microsecond), "address": map[string]interface{}{"city": "Berlin", "zip": int64(10117)}},
+	}
+
+	tests := []struct {
+		name        string
+		codec       int32
+		pageVersion int
+	}{
+		{"uncompressed", codecUncompressed, 1},
+		{"snappy", codecSnappy, 1},
+		{"gzip", codecGzip, 1},
+		{"snappy page v2", codecSnappy, 2},
+		{"zstd", codecZstd, 1},
+	}
+	for _, tt := range tests {
+		path := writeTestParquet(t, schema, columns, [][]map[string]interface{}{rows[:3], rows[3:]}, tt.codec, tt.pageVersion)
+		reader, err := New(config.Source{Type: "auto", Path: path})
+		if err != nil {
+			t.Fatalf("%s: New() error = %v", tt.name, err)
+		}
+		var got []Record
+		for {
+			rec, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: Read() error = %v", tt.name, err)
+			}
+			got = append(got, rec)
+		}
+		reader.Close()
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: records got = %v, want %v", tt.name, got, want)
+		}
+	}
+}
+
+// shredList returns a shred func for the list at name in rows, an optional
+// list of optional values in the three-level layout, or of required values
+// in the legacy two-level one if required.
+func shredList(name string, required bool) func(row map[string]interface{}) ([]int32, []int32, []interface{}) {
+	return func(row map[string]interface{}) (reps, defs []int32, values []interface{}) {
+		list, ok := row[name].([]interface{})
+		if !ok {
+			return []int32{0}, []int32{0}, nil
+		}
+		if len(list) == 0 {
+			return []int32{0}, []int32{1}, nil
+		}
+		defined := int32(3)
+		if required {
+			defined = 2
+		}
+		for i, v := range list {
+			reps = append(reps, min(int32(i), 1))
+			if v == nil {
+				defs = append(defs, defined-1)
+				continue
+			}
+			defs = append(defs, defined)
+			values = append(values, v)
+		}
+		return reps, defs, values
+	}
+}
+
+// shredMap returns a shred func for the keys, or the optional values, of the
+// optional map at name in rows, in key order.
+func shredMap(name string, keys bool) func(row map[string]interface{}) ([]int32, []int32, []interface{}) {
+	return func(row map[string]interface{}) (reps, defs []int32, values []interface{}) {
+		m, ok := row[name].(map[string]interface{})
+		if !ok {
+			return []int32{0}, []int32{0}, nil
+		}
+		if len(m) == 0 {
+			return []int32{0}, []int32{1}, nil
+		}
+		var sorted []string
+		for k := range m {
+			sorted = append(sorted, k)
+		}
+		slices.Sort(sorted)
+		for i, k := range sorted {
+			reps = append(reps, min(int32(i), 1))
+			switch {
+			case keys:
+				defs, values = append(defs, 2), append(values, k)
+			case m[k] == nil:
+				defs = append(defs, 2)
+			default:
+				defs, values = append(defs, 3), append(values, m[k])
+			}
+		}
+		return reps, defs, values
+	}
+}
+
+// shredRepeated returns a shred func for the required field of the groups
+// repeated at name in rows.
+func shredRepeated(name, field string) func(row map[string]interface{}) ([]int32, []int32, []interface{}) {
+	return func(row map[string]interface{}) (reps, defs []int32, values []interface{}) {
+		groups, _ := row[name].([]interface{})
+		if len(groups) == 0 {
+			return []int32{0}, []int32{0}, nil
+		}
+		for i, g := range groups {
+			reps, defs = append(reps, min(int32(i), 1)), append(defs, 1)
+			values = append(values, g.(map[string]interface{})[field])
+		}
+		return reps, defs, values
+	}
+}
+
+// shredMatrix shreds the optional list of optional lists of required values
+// at "matrix" in row.
+func shredMatrix(row map[string]interface{}) (reps, defs []int32, values []interface{}) {
+	outer, ok := row["matrix"].([]interface{})
+	if !ok {
+		return []int32{0}, []int32{0}, nil
+	}
+	if len(outer) == 0 {
+		return []int32{0}, []int32{1}, nil
+	}
+	for i, v := range outer {
+		rep := min(int32(i), 1)
+		inner, ok := v.([]interface{})
+		switch {
+		case !ok:
+			reps, defs = append(reps, rep), append(defs, 2)
+		case len(inner) == 0:
+			reps, defs = append(reps, rep), append(defs, 3)
+		}
+		for j, e := range inner {
+			if j > 0 {
+				rep = 2
+			}
+			reps, defs, values = append(reps, rep), append(defs, 4), append(values, e)
+		}
+	}
+	return reps, defs, values
+}
+
+func TestParquetReader_Nested(t *testing.T) {
+	utf8 := tfield{6, int32(0)}
+	schema := []tstruct{
+		{{4, "schema"}, {5, int32(6)}},
+		{{1, int32(parquetInt64)}, {3, int32(0)}, {4, "id"}},
+		// A list of strings, as written by Arrow and Spark.
+		{{3, int32(parquetOptional)}, {4, "tags"}, {5, int32(1)}, {6, int32(convertedList)}},
+		{{3, int32(parquetRepeated)}, {4, "list"}, {5, int32(1)}},
+		{{1, int32(parquetByteArray)}, {3, int32(parquetOptional)}, {4, "element"}, utf8},
+		// A map of strings to integers.
+		{{3, int32(parquetOptional)}, {4, "attrs"}, {5, int32(1)}, {6, int32(convertedMap)}},
+		{{3, int32(parquetRepeated)}, {4, "key_value"}, {5, int32(2)}},
+		{{1, int32(parquetByteArray)}, {3, int32(0)}, {4, "key"}, utf8},
+		{{1, int32(parquetInt64)}, {3, int32(parquetOptional)}, {4, "value"}},
+		// A repeated group without a list annotation.
+		{{3, int32(parquetRepeated)}, {4, "points"}, {5, int32(2)}},
+		{{1, int32(parquetInt32)}, {3, int32(0)}, {4, "x"}},
+		{{1, int32(parquetInt32)}, {3, int32(0)}, {4, "y"}},
+		// A legacy two-level list.
+		{{3, int32(parquetOptional)}, {4, "legacy"}, {5, int32(1)}, {6, int32(convertedList)}},
+		{{1, int32(parquetInt32)}, {3, int32(parquetRepeated)}, {4, "array"}},
+		// A list of lists, the inner one annotated by a logical type.
+		{{3, int32(parquetOptional)}, {4, "matrix"}, {5, int32(1)}, {6, int32(convertedList)}},
+		{{3, int32(parquetRepeated)}, {4, "list"}, {5, int32(1)}},
+		{{3, int32(parquetOptional)}, {4, "element"}, {5, int32(1)}, {10, tstruct{{3, tstruct{}}}}},
+		{{3, int32(parquetRepeated)}, {4, "list"}, {5, int32(1)}},
+		{{1, int32(parquetInt64)}, {3, int32(0)}, {4, "element"}},
+	}
+	columns := []testParquetColumn{
+		{path: []string{"id"}, optional: []bool{false}, typ: parquetInt64},
+		{path: []string{"tags", "list", "element"}, optional: []bool{true, true, true}, typ: parquetByteArray, dictionary: true,
+			maxRep: 1, shred: shredList("tags", false)},
+		{path: []string{"attrs", "key_value", "key"}, optional: []bool{true, true, false}, typ: parquetByteArray,
+			maxRep: 1, shred: shredMap("attrs", true)},
+		{path: []string{"attrs", "key_value", "value"}, optional: []bool{true, true, true}, typ: parquetInt64,
+			maxRep: 1, shred: shredMap("attrs", false)},
+		{path: []string{"points", "x"}, optional: []bool{true, false}, typ: parquetInt32, maxRep: 1, shred: shredRepeated("points", "x")},
+		{path: []string{"points", "y"}, optional: []bool{true, false}, typ: parquetInt32, maxRep: 1, shred: shredRepeated("points", "y")},
+		{path: []string{"legacy", "array"}, optional: []bool{true, true}, typ: parquetInt32, maxRep: 1, shred: shredList("legacy", true)},
+		{path: []string{"matrix", "list", "element", "list", "element"}, optional: []bool{true, true, true, true, false}, typ: parquetInt64,
+			maxRep: 2, shred: shredMatrix},
+	}
+	type list = []interface{}
+	type object = map[string]interface{}
+	rows := []map[string]interface{}{
+		{"id": int64(1), "tags": list{"a", "b"}, "attrs": object{"k1": int64(1), "k2": nil},
+			"points": list{object{"x": int32(1), "y": int32(2)}}, "legacy": list{int32(5), int32(6)},
+			"matrix": list{list{int64(1), int64(2)}, list{int64(3)}}},
+		{"id": int64(2), "tags": nil, "attrs": object{}, "points": list{}, "legacy": list{}, "matrix": list{list{}, nil}},
+		{"id": int64(3), "tags": list{nil, "a"}, "attrs": nil,
+			"points": list{object{"x": int32(3), "y": int32(4)}, object{"x": int32(5), "y": int32(6)}}, "legacy": nil, "matrix": list{}},
+	}
+	want := []Record{
+		{"id": int64(1), "tags": list{"a", "b"}, "attrs": object{"k1": int64(1), "k2": nil},
+			"points": list{object{"x": int64(1), "y": int64(2)}}, "legacy": list{int64(5), int64(6)},
+			"matrix": list{list{int64(1), int64(2)}, list{int64(3)}}},
+		{"id": int64(2), "tags": nil, "attrs": object{}, "points": list{}, "legacy": list{}, "matrix": list{list{}, nil}},
+		{"id": int64(3), "tags": list{nil, "a"}, "attrs": nil,
+			"points": list{object{"x": int64(3), "y": int64(4)}, object{"x": int64(5), "y": int64(6)}}, "legacy": nil, "matrix": list{}},
+	}
+
+	tests := []struct {
+		name        string
+		codec       int32
+		pageVersion int
+	}{
+		{"uncompressed", codecUncompressed, 1},
+		{"zstd", codecZstd, 1},
+		{"zstd page v2", codecZstd, 2},
+	}
+	for _, tt := range tests {
+		path := writeTestParquet(t, schema, columns, [][]map[string]interface{}{rows[:2], rows[2:]}, tt.codec, tt.pageVersion)
+		reader, err := New(config.Source{Type: "parquet", Path: path})
+		if err != nil {
+			t.Fatalf("%s: New() error = %v", tt.name, err)
+		}
+		var got []Record
+		for {
+			rec, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: Read() error = %v", tt.name, err)
+			}
+			got = append(got, rec)
+		}
+		reader.Close()
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: records got = %v, want %v", tt.name, got, want)
+		}
+	}
+}
+
+// TestParquetReader_Interop reads the files written by other Parquet
+// implementations in testdata/parquet, generated by its generate.py, and
+// compares their records to the JSON Lines file next to each.
+func TestParquetReader_Interop(t *testing.T) {
+	paths, err := filepath.Glob("../../../testdata/parquet/*.parquet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Skip("no interop fixtures, run testdata/parquet/generate.py to write them")
+	}
+	for _, path := range paths {
+		reader, err := New(config.Source{Type: "parquet", Path: path})
+		if err != nil {
+			t.Fatalf("%s: New() error = %v", path, err)
+		}
+		var got []interface{}
+		for {
+			rec, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: Read() error = %v", path, err)
+			}
+			// Compare as JSON, whose numbers have a single type.
+			data, err := json.Marshal(rec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var v interface{}
+			json.Unmarshal(data, &v)
+			got = append(got, v)
+		}
+		reader.Close()
+
+		expected, err := os.ReadFile(strings.TrimSuffix(path, ".parquet") + ".jsonl")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want []interface{}
+		for _, line := range strings.Split(strings.TrimSpace(string(expected)), "\n") {
+			var v interface{}
+			if err := json.Unmarshal([]byte(line), &v); err != nil {
+				t.Fatalf("%s: %v", path, err)
+			}
+			want = append(want, v)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: records got = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestParquetReader_FromStream(t *testing.T) {
+	schema := []tstruct{
+		{{4, "schema"}, {5, int32(1)}},
+		{{1, int32(parquetInt64)}, {3, int32(0)}, {4, "id"}},
+	}
+	columns := []testParquetColumn{{path: []string{"id"}, optional: []bool{false}, typ: parquetInt64}}
+	rows := []map[string]interface{}{{"id": int64(7)}}
+	data, err := os.ReadFile(writeTestParquet(t, schema, columns, [][]map[string]interface{}{rows}, codecUncompressed, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := NewFromReader(bytes.NewReader(data), config.Source{Type: "auto", Path: "inline"})
+	if err != nil {
+		t.Fatalf("NewFromReader() error = %v", err)
+	}
+	defer reader.Close()
+	rec, err := reader.Read()
+	if err != nil || rec["id"] != int64(7) {
+		t.Errorf("Read() got = %v, %v, want id 7", rec, err)
+	}
+}
+
+func TestParquetReader_Errors(t *testing.T) {
+	dir := t.TempDir()
+	notParquet := filepath.Join(dir, "data.parquet")
+	if err := os.WriteFile(notParquet, []byte("id,name\n1,alice\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewParquetReader(config.Source{Type: "parquet", Path: notParquet}); err == nil || !strings.Contains(err.Error(), "not a parquet file") {
+		t.Errorf("NewParquetReader() error got = %v, want not a parquet file", err)
+	}
+
+	// Brotli, codec 4, is not supported.
+	schema := []tstruct{
+		{{4, "schema"}, {5, int32(1)}},
+		{{1, int32(parquetInt64)}, {3, int32(0)}, {4, "id"}},
+	}
+	columns := []testParquetColumn{{path: []string{"id"}, optional: []bool{false}, typ: parquetInt64}}
+	path := writeTestParquet(t, schema, columns, [][]map[string]interface{}{{{"id": int64(1)}}}, 4, 1)
+	reader, err := NewParquetReader(config.Source{Type: "parquet", Path: path})
+	if err != nil {
+		t.Fatalf("NewParquetReader() error = %v", err)
+	}
+	defer reader.Close()
+	if _, err := reader.Read(); err == nil || !strings.Contains(err.Error(), "unsupported compression codec 4") {
+		t.Errorf("Read() error got = %v, want an unsupported codec", err)
+	}
+}
+
+func TestDecodeSnappy(t *testing.T) {
+	// "abcd" as a literal, then a copy of 8 bytes from 4 bytes back.
+	got, err := decodeSnappy([]byte{12, 3 << 2, 'a', 'b', 'c', 'd', (8-4)<<2 | 1, 4})
+	if err != nil {
+		t.Fatalf("decodeSnappy() error = %v", err)
+	}
+	if string(got) != "abcdabcdabcd" {
+		t.Errorf("decodeSnappy() got = %q, want abcdabcdabcd", got)
+	}
+}
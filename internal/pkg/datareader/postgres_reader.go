@@ -0,0 +1,60 @@
+package datareader
+
+import (
+	"data-comparator/internal/pkg/config"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+// postgresDriver is the database/sql driver used for postgres sources.
+var postgresDriver = "postgres"
+
+// postgresCursor names the server-side cursor rows are fetched through.
+const postgresCursor = "stream_diff_rows"
+
+// NewPostgresReader connects to cfg.DSN and streams the rows of cfg.Table or
+// cfg.Query through a server-side cursor, FetchSize rows at a time, so
+// results larger than memory can be compared without exporting them first.
+// Integers are int64, floating point and numeric values float64, timestamps
+// and dates time.Time, json and jsonb values nested records, bytea []byte
+// and everything else strings.
+func NewPostgresReader(cfg config.Source) (DataReader, error) {
+	return newSQLReader(cfg, sqlDialect{
+		typ:     "postgres",
+		driver:  postgresDriver,
+		quote:   pq.QuoteIdentifier,
+		cursor:  postgresCursorQueries,
+		convert: convertPostgresValue,
+	})
+}
+
+func postgresCursorQueries(query string, fetchSize int) (string, string) {
+	return fmt.Sprintf("DECLARE %s NO SCROLL CURSOR FOR %s", postgresCursor, query),
+		fmt.Sprintf("FETCH FORWARD %d FROM %s", fetchSize, postgresCursor)
+}
+
+// convertPostgresValue turns a scanned value into the Record value of its
+// column's database type.
+func convertPostgresValue(v interface{}, typ string) interface{} {
+	b, ok := v.([]byte)
+	if !ok {
+		return v
+	}
+	switch typ {
+	case "BYTEA":
+		return append([]byte(nil), b...)
+	case "NUMERIC":
+		if f, err := strconv.ParseFloat(string(b), 64); err == nil {
+			return f
+		}
+	case "JSON", "JSONB":
+		var value interface{}
+		if err := json.Unmarshal(b, &value); err == nil {
+			return value
+		}
+	}
+	return string(b)
+}
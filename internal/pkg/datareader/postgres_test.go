@@ -0,0 +1,60 @@
+package datareader
+
+import (
+	"data-comparator/internal/pkg/config"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+func TestPostgresReader(t *testing.T) {
+	var rows [][]driver.Value
+	for i := 1; i <= 5; i++ {
+		rows = append(rows, []driver.Value{int64(i), []byte("12.50"), []byte(`{"city": "Berlin"}`), []byte{0, 1}, []byte("name")})
+	}
+	d := useFakeSQL(t, []string{"id", "price", "address", "blob", "name"}, []string{"INT8", "NUMERIC", "JSONB", "BYTEA", "TEXT"}, rows)
+
+	reader, err := New(config.Source{Type: "postgres", DSN: "postgres://localhost/db", Table: "public.users", FetchSize: 2})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	got := readAllRecords(t, reader)
+	if len(got) != 5 {
+		t.Fatalf("Records got = %d, want 5", len(got))
+	}
+	want := Record{"id": int64(5), "price": 12.5, "address": map[string]interface{}{"city": "Berlin"}, "blob": []byte{0, 1}, "name": "name"}
+	if !reflect.DeepEqual(got[4], want) {
+		t.Errorf("Record got = %v, want %v", got[4], want)
+	}
+
+	wantQueries := []string{
+		`DECLARE stream_diff_rows NO SCROLL CURSOR FOR SELECT * FROM "public"."users"`,
+		"FETCH FORWARD 2 FROM stream_diff_rows",
+		"FETCH FORWARD 2 FROM stream_diff_rows",
+		"FETCH FORWARD 2 FROM stream_diff_rows",
+	}
+	if !reflect.DeepEqual(d.queries, wantQueries) {
+		t.Errorf("Queries got = %q, want %q", d.queries, wantQueries)
+	}
+}
+
+func TestPostgresReader_Config(t *testing.T) {
+	useFakeSQL(t, []string{"id"}, []string{"INT8"}, nil)
+	for _, cfg := range []config.Source{
+		{Type: "postgres", Table: "users"},
+		{Type: "postgres", DSN: "postgres://localhost/db"},
+		{Type: "postgres", DSN: "postgres://localhost/db", Table: "users", Query: "SELECT 1"},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("New(%+v) should fail", cfg)
+		}
+	}
+
+	reader, err := New(config.Source{Type: "postgres", DSN: "postgres://localhost/db", Query: "SELECT id FROM users;"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got := readAllRecords(t, reader); len(got) != 0 {
+		t.Errorf("Records got = %v, want none", got)
+	}
+}
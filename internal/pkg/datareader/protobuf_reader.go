@@ -0,0 +1,283 @@
+package datareader
+
+import (
+	"bufio"
+	"data-comparator/internal/pkg/config"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// maxProtobufMessage bounds the length of a single message, so a corrupt
+// length prefix does not allocate unbounded memory.
+const maxProtobufMessage = 64 << 20
+
+// ProtobufFramings lists the values accepted for ProtobufParserConfig.Framing.
+var ProtobufFramings = []string{"varint", "length_prefixed"}
+
+// ProtobufReader reads a stream of binary protobuf messages of one type,
+// each preceded by its length, decoding them with the type's descriptor.
+// Fields become record fields by their proto name: integers are int64,
+// except uint64 values beyond its range, which are strings, floating point
+// values float64, enums the names of their values, bytes []byte, nested
+// messages records, repeated fields lists and maps records keyed by the
+// map key's text. google.protobuf.Timestamp values are time.Time in UTC
+// and wrapper types their wrapped value. Fields with explicit presence
+// that are not set, including the other members of a oneof, are nil.
+type ProtobufReader struct {
+	path    string
+	file    io.Closer
+	input   *bufio.Reader
+	message protoreflect.MessageType
+	framing string
+	records int
+	offset  int64
+	// seeker is the file, if local and uncompressed, which Resume seeks.
+	seeker io.ReadSeeker
+}
+
+// NewProtobufReader opens a file of protobuf messages described by cfg.Protobuf.
+func NewProtobufReader(cfg config.Source) (DataReader, error) {
+	message, err := loadProtobufType(cfg)
+	if err != nil {
+		return nil, err
+	}
+	file, err := openSource(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open protobuf file %s: %w", cfg.Path, err)
+	}
+	input, closer, compressed, err := decompress(file, file, cfg)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newProtobufReader(input, closer, message, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if f, ok := file.(localFile); ok && !compressed {
+		r.seeker = f
+	}
+	return r, nil
+}
+
+// newProtobufReader reads messages from input, named by cfg.Path, and closes
+// closer when done or on error.
+func newProtobufReader(input io.Reader, closer io.Closer, message protoreflect.MessageType, cfg config.Source) (*ProtobufReader, error) {
+	framing := cfg.Protobuf.Framing
+	switch framing {
+	case "":
+		framing = "varint"
+	case "varint", "length_prefixed":
+	default:
+		closer.Close()
+		return nil, fmt.Errorf("unsupported protobuf framing %q (use varint or length_prefixed)", framing)
+	}
+	return &ProtobufReader{path: cfg.Path, file: closer, input: bufio.NewReader(input), message: message, framing: framing}, nil
+}
+
+// loadProtobufType finds the configured message type in the descriptor set.
+func loadProtobufType(cfg config.Source) (protoreflect.MessageType, error) {
+	pcfg := cfg.Protobuf
+	if pcfg == nil || pcfg.Descriptor == "" || pcfg.MessageType == "" {
+		return nil, fmt.Errorf("protobuf source %s needs protobuf.descriptor and protobuf.message_type", cfg.Path)
+	}
+	data, err := os.ReadFile(pcfg.Descriptor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read protobuf descriptor: %w", err)
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse protobuf descriptor %s: %w", pcfg.Descriptor, err)
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid protobuf descriptor %s: %w", pcfg.Descriptor, err)
+	}
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(pcfg.MessageType))
+	if err != nil {
+		return nil, fmt.Errorf("protobuf descriptor %s has no message type %s", pcfg.Descriptor, pcfg.MessageType)
+	}
+	message, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s in protobuf descriptor %s is not a message type", pcfg.MessageType, pcfg.Descriptor)
+	}
+	return dynamicpb.NewMessageType(message), nil
+}
+
+// Read returns the next message, or io.EOF at the end of the stream.
+func (r *ProtobufReader) Read() (Record, error) {
+	start := r.offset
+	data, err := r.next()
+	if err == io.EOF {
+		return nil, err
+	}
+	r.records++
+	if err != nil {
+		// The stream cannot be resynchronized after a broken length prefix.
+		return nil, &ParseError{Source: r.path, Record: r.records, Offset: start, Err: err}
+	}
+	message := r.message.New()
+	if err := proto.Unmarshal(data, message.Interface()); err != nil {
+		return nil, &ParseError{Source: r.path, Record: r.records, Offset: start, Recoverable: true, Err: err}
+	}
+	return Record(protobufRecord(message)), nil
+}
+
+// next reads the next length-prefixed message.
+func (r *ProtobufReader) next() ([]byte, error) {
+	var length uint64
+	if r.framing == "length_prefixed" {
+		prefix := make([]byte, 4)
+		n, err := io.ReadFull(r.input, prefix)
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, fmt.Errorf("truncated length prefix: %w", err)
+		}
+		r.offset += int64(n)
+		length = uint64(binary.BigEndian.Uint32(prefix))
+	} else {
+		var err error
+		length, err = binary.ReadUvarint(r.input)
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid length prefix: %w", err)
+		}
+		r.offset += int64(protowire.SizeVarint(length))
+	}
+	if length > maxProtobufMessage {
+		return nil, fmt.Errorf("message length %d exceeds %d bytes", length, maxProtobufMessage)
+	}
+	data := make([]byte, length)
+	n, err := io.ReadFull(r.input, data)
+	r.offset += int64(n)
+	if err != nil {
+		return nil, fmt.Errorf("truncated message of %d bytes: %w", length, err)
+	}
+	return data, nil
+}
+
+// Position returns the number of messages read and the offset after the last.
+func (r *ProtobufReader) Position() Position {
+	return Position{Records: r.records, Offset: r.offset}
+}
+
+// Resume continues after the messages up to pos, seeking a local file to
+// its offset.
+func (r *ProtobufReader) Resume(pos Position) error {
+	if r.records > 0 {
+		return fmt.Errorf("cannot resume %s after reading from it", r.path)
+	}
+	if r.seeker == nil {
+		return skipRecords(r, pos.Records)
+	}
+	if _, err := r.seeker.Seek(pos.Offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to resume %s at offset %d: %w", r.path, pos.Offset, err)
+	}
+	r.input.Reset(r.seeker)
+	r.records, r.offset = pos.Records, pos.Offset
+	return nil
+}
+
+// Close closes the underlying file.
+func (r *ProtobufReader) Close() error {
+	return r.file.Close()
+}
+
+// protobufRecord converts a message to a record of its fields.
+func protobufRecord(m protoreflect.Message) map[string]interface{} {
+	fields := m.Descriptor().Fields()
+	rec := make(map[string]interface{}, fields.Len())
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.HasPresence() && !m.Has(fd) {
+			rec[string(fd.Name())] = nil
+			continue
+		}
+		rec[string(fd.Name())] = protobufField(fd, m.Get(fd))
+	}
+	return rec
+}
+
+func protobufField(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch {
+	case fd.IsList():
+		list := v.List()
+		out := make([]interface{}, list.Len())
+		for i := range out {
+			out[i] = protobufValue(fd, list.Get(i))
+		}
+		return out
+	case fd.IsMap():
+		out := make(map[string]interface{}, v.Map().Len())
+		v.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+			out[k.String()] = protobufValue(fd.MapValue(), v)
+			return true
+		})
+		return out
+	}
+	return protobufValue(fd, v)
+}
+
+// protobufValue converts a single value of the field's kind.
+func protobufValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch fd.Kind() {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return v.Int()
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if u := v.Uint(); u > math.MaxInt64 {
+			return strconv.FormatUint(u, 10)
+		} else {
+			return int64(u)
+		}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return v.Float()
+	case protoreflect.BoolKind:
+		return v.Bool()
+	case protoreflect.StringKind:
+		return v.String()
+	case protoreflect.BytesKind:
+		return v.Bytes()
+	case protoreflect.EnumKind:
+		if value := fd.Enum().Values().ByNumber(v.Enum()); value != nil {
+			return string(value.Name())
+		}
+		return int64(v.Enum())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return protobufMessage(v.Message())
+	}
+	return v.Interface()
+}
+
+// protobufMessage converts a nested message, unwrapping well-known types.
+func protobufMessage(m protoreflect.Message) interface{} {
+	desc := m.Descriptor()
+	switch desc.FullName() {
+	case "google.protobuf.Timestamp":
+		seconds := m.Get(desc.Fields().ByName("seconds")).Int()
+		nanos := m.Get(desc.Fields().ByName("nanos")).Int()
+		return time.Unix(seconds, nanos).UTC()
+	case "google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+		"google.protobuf.Int64Value", "google.protobuf.UInt64Value",
+		"google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+		"google.protobuf.BoolValue", "google.protobuf.StringValue", "google.protobuf.BytesValue":
+		value := desc.Fields().ByName("value")
+		return protobufValue(value, m.Get(value))
+	}
+	return protobufRecord(m)
+}
@@ -0,0 +1,309 @@
+package datareader
+
+import (
+	"bytes"
+	"data-comparator/internal/pkg/config"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// testOrderDescriptor describes
+//
+//	message Order {
+//	  enum Status { PENDING = 0; SHIPPED = 1; }
+//	  message Line { string sku = 1; uint32 quantity = 2; }
+//	  int64 id = 1;
+//	  Status status = 2;
+//	  repeated Line lines = 3;
+//	  map<string, double> prices = 4;
+//	  google.protobuf.Timestamp created = 5;
+//	  google.protobuf.StringValue note = 6;
+//	  optional uint64 total = 7;
+//	  bytes token = 8;
+//	}
+func testOrderDescriptor() *descriptorpb.FileDescriptorSet {
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, label descriptorpb.FieldDescriptorProto_Label, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{Name: proto.String(name), JsonName: proto.String(name), Number: proto.Int32(number), Type: typ.Enum(), Label: label.Enum()}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	optional, repeated := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	total := field("total", 7, descriptorpb.FieldDescriptorProto_TYPE_UINT64, optional, "")
+	total.Proto3Optional, total.OneofIndex = proto.Bool(true), proto.Int32(0)
+
+	order := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("shop/order.proto"),
+		Package:    proto.String("shop.v1"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/timestamp.proto", "google/protobuf/wrappers.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Order"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, optional, ""),
+				field("status", 2, descriptorpb.FieldDescriptorProto_TYPE_ENUM, optional, ".shop.v1.Order.Status"),
+				field("lines", 3, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, repeated, ".shop.v1.Order.Line"),
+				field("prices", 4, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, repeated, ".shop.v1.Order.PricesEntry"),
+				field("created", 5, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, optional, ".google.protobuf.Timestamp"),
+				field("note", 6, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, optional, ".google.protobuf.StringValue"),
+				total,
+				field("token", 8, descriptorpb.FieldDescriptorProto_TYPE_BYTES, optional, ""),
+			},
+			NestedType: []*descriptorpb.DescriptorProto{
+				{
+					Name: proto.String("Line"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("sku", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional, ""),
+						field("quantity", 2, descriptorpb.FieldDescriptorProto_TYPE_UINT32, optional, ""),
+					},
+				},
+				{
+					Name: proto.String("PricesEntry"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("key", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional, ""),
+						field("value", 2, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, optional, ""),
+					},
+					Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+				},
+			},
+			EnumType: []*descriptorpb.EnumDescriptorProto{{
+				Name: proto.String("Status"),
+				Value: []*descriptorpb.EnumValueDescriptorProto{
+					{Name: proto.String("PENDING"), Number: proto.Int32(0)},
+					{Name: proto.String("SHIPPED"), Number: proto.Int32(1)},
+				},
+			}},
+			OneofDecl: []*descriptorpb.OneofDescriptorProto{{Name: proto.String("_total")}},
+		}},
+	}
+	return &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{
+		protodesc.ToFileDescriptorProto(timestamppb.File_google_protobuf_timestamp_proto),
+		protodesc.ToFileDescriptorProto(wrapperspb.File_google_protobuf_wrappers_proto),
+		order,
+	}}
+}
+
+// writeTestProtobuf writes the descriptor set to dir and returns its path
+// along with the Order message type.
+func writeTestProtobuf(t *testing.T, dir string) (string, protoreflect.MessageType) {
+	t.Helper()
+	set := testOrderDescriptor()
+	data, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "order.desc")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc, err := files.FindDescriptorByName("shop.v1.Order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path, dynamicpb.NewMessageType(desc.(protoreflect.MessageDescriptor))
+}
+
+// testOrders encodes two orders, the first with every field set.
+func testOrders(t *testing.T, typ protoreflect.MessageType) [][]byte {
+	t.Helper()
+	first := typ.New()
+	fields := first.Descriptor().Fields()
+	first.Set(fields.ByName("id"), protoreflect.ValueOfInt64(1))
+	first.Set(fields.ByName("status"), protoreflect.ValueOfEnum(1))
+	lines := first.Mutable(fields.ByName("lines")).List()
+	line := lines.NewElement()
+	line.Message().Set(line.Message().Descriptor().Fields().ByName("sku"), protoreflect.ValueOfString("A-1"))
+	line.Message().Set(line.Message().Descriptor().Fields().ByName("quantity"), protoreflect.ValueOfUint32(2))
+	lines.Append(line)
+	first.Mutable(fields.ByName("prices")).Map().Set(protoreflect.ValueOfString("A-1").MapKey(), protoreflect.ValueOfFloat64(9.5))
+	first.Set(fields.ByName("created"), protoreflect.ValueOfMessage(timestamppb.New(time.Date(2025, 9, 10, 12, 0, 0, 0, time.UTC)).ProtoReflect()))
+	first.Set(fields.ByName("note"), protoreflect.ValueOfMessage(wrapperspb.String("gift").ProtoReflect()))
+	first.Set(fields.ByName("total"), protoreflect.ValueOfUint64(1<<63))
+	first.Set(fields.ByName("token"), protoreflect.ValueOfBytes([]byte{0xca, 0xfe}))
+
+	second := typ.New()
+	second.Set(fields.ByName("id"), protoreflect.ValueOfInt64(2))
+
+	var messages [][]byte
+	for _, m := range []protoreflect.Message{first, second} {
+		data, err := proto.Marshal(m.Interface())
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, data)
+	}
+	return messages
+}
+
+var testOrderRecords = []Record{
+	{
+		"id":      int64(1),
+		"status":  "SHIPPED",
+		"lines":   []interface{}{map[string]interface{}{"sku": "A-1", "quantity": int64(2)}},
+		"prices":  map[string]interface{}{"A-1": 9.5},
+		"created": time.Date(2025, 9, 10, 12, 0, 0, 0, time.UTC),
+		"note":    "gift",
+		"total":   "9223372036854775808",
+		"token":   []byte{0xca, 0xfe},
+	},
+	{
+		"id":      int64(2),
+		"status":  "PENDING",
+		"lines":   []interface{}{},
+		"prices":  map[string]interface{}{},
+		"created": nil,
+		"note":    nil,
+		"total":   nil,
+		"token":   []byte(nil),
+	},
+}
+
+func TestNewProtobufReader(t *testing.T) {
+	dir := t.TempDir()
+	descriptor, typ := writeTestProtobuf(t, dir)
+	messages := testOrders(t, typ)
+
+	framings := map[string]func([]byte) []byte{
+		"varint": func(m []byte) []byte { return protowire.AppendBytes(nil, m) },
+		"length_prefixed": func(m []byte) []byte {
+			return append(binary.BigEndian.AppendUint32(nil, uint32(len(m))), m...)
+		},
+	}
+	for framing, frame := range framings {
+		var data []byte
+		for _, m := range messages {
+			data = append(data, frame(m)...)
+		}
+		path := filepath.Join(dir, framing+".pb")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		reader, err := New(config.Source{Type: "protobuf", Path: path, Protobuf: &config.ProtobufParserConfig{
+			Descriptor: descriptor, MessageType: "shop.v1.Order", Framing: framing,
+		}})
+		if err != nil {
+			t.Fatalf("New(%s) error = %v", framing, err)
+		}
+		if got := readAllRecords(t, reader); !reflect.DeepEqual(got, testOrderRecords) {
+			t.Errorf("New(%s) records got = %v, want %v", framing, got, testOrderRecords)
+		}
+	}
+}
+
+func TestNewFromReader_Protobuf(t *testing.T) {
+	descriptor, typ := writeTestProtobuf(t, t.TempDir())
+	var data []byte
+	for _, m := range testOrders(t, typ) {
+		data = protowire.AppendBytes(data, m)
+	}
+	reader, err := NewFromReader(bytes.NewReader(compressTestData(t, "gzip", data)), config.Source{
+		Type: "protobuf", Path: "orders.pb.gz",
+		Protobuf: &config.ProtobufParserConfig{Descriptor: descriptor, MessageType: "shop.v1.Order"},
+	})
+	if err != nil {
+		t.Fatalf("NewFromReader() error = %v", err)
+	}
+	if got := readAllRecords(t, reader); !reflect.DeepEqual(got, testOrderRecords) {
+		t.Errorf("Records got = %v, want %v", got, testOrderRecords)
+	}
+}
+
+func TestProtobufReader_Errors(t *testing.T) {
+	dir := t.TempDir()
+	descriptor, typ := writeTestProtobuf(t, dir)
+	messages := testOrders(t, typ)
+	cfg := config.Source{Type: "protobuf", Path: "orders.pb", Protobuf: &config.ProtobufParserConfig{Descriptor: descriptor, MessageType: "shop.v1.Order"}}
+
+	// A message that is not valid protobuf is skipped, a truncated one ends the stream.
+	data := protowire.AppendBytes(nil, []byte{0xff})
+	data = protowire.AppendBytes(data, messages[1])
+	data = append(data, protowire.AppendBytes(nil, messages[0])[:5]...)
+	reader, err := NewFromReader(bytes.NewReader(data), cfg)
+	if err != nil {
+		t.Fatalf("NewFromReader() error = %v", err)
+	}
+	defer reader.Close()
+
+	var perr *ParseError
+	if _, err := reader.Read(); !errors.As(err, &perr) || !perr.Recoverable || perr.Record != 1 || perr.Offset != 0 {
+		t.Errorf("Read() of an invalid message error got = %v, want a recoverable parse error of record 1", err)
+	}
+	if rec, err := reader.Read(); err != nil || rec["id"] != int64(2) {
+		t.Errorf("Read() got = %v, %v, want order 2", rec, err)
+	}
+	if _, err := reader.Read(); !errors.As(err, &perr) || perr.Recoverable || perr.Record != 3 {
+		t.Errorf("Read() of a truncated message error got = %v, want an unrecoverable parse error of record 3", err)
+	}
+
+	cfg.Protobuf = &config.ProtobufParserConfig{Descriptor: descriptor, MessageType: "shop.v1.Missing"}
+	if _, err := NewFromReader(bytes.NewReader(nil), cfg); err == nil {
+		t.Errorf("NewFromReader() with an unknown message type error got = nil")
+	}
+	cfg.Protobuf = &config.ProtobufParserConfig{Descriptor: descriptor, MessageType: "shop.v1.Order", Framing: "fixed64"}
+	if _, err := NewFromReader(io.NopCloser(bytes.NewReader(nil)), cfg); err == nil {
+		t.Errorf("NewFromReader() with an unsupported framing error got = nil")
+	}
+}
+
+func TestProtobufReader_Resume(t *testing.T) {
+	dir := t.TempDir()
+	descriptor, typ := writeTestProtobuf(t, dir)
+	messages := testOrders(t, typ)
+	var data []byte
+	for _, m := range messages {
+		data = protowire.AppendBytes(data, m)
+	}
+	path := filepath.Join(dir, "orders.pb")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Source{Type: "protobuf", Path: path, Protobuf: &config.ProtobufParserConfig{Descriptor: descriptor, MessageType: "shop.v1.Order"}}
+
+	first, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := first.Read(); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	pos := first.(Resumer).Position()
+	first.Close()
+	if want := (Position{Records: 1, Offset: int64(len(protowire.AppendBytes(nil, messages[0])))}); pos != want {
+		t.Errorf("Position() got = %+v, want %+v", pos, want)
+	}
+
+	reader, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := reader.(Resumer).Resume(pos); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	if got := readAllRecords(t, reader); !reflect.DeepEqual(got, testOrderRecords[1:]) {
+		t.Errorf("Records after Resume() got = %v, want %v", got, testOrderRecords[1:])
+	}
+	if got := reader.(Resumer).Position(); got.Records != 2 || got.Offset != int64(len(data)) {
+		t.Errorf("Position() at the end got = %+v, want 2 records at offset %d", got, len(data))
+	}
+}
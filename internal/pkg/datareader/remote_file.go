@@ -0,0 +1,115 @@
+package datareader
+
+import (
+	"data-comparator/internal/pkg/config"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// remoteTimeout bounds connecting to a remote server and each of its
+// replies outside of transfers.
+const remoteTimeout = 30 * time.Second
+
+// remoteSchemes are the URL schemes of files read from SFTP and FTP servers.
+var remoteSchemes = []string{"sftp", "ftp"}
+
+// IsRemotePath reports whether path is an sftp:// or ftp:// URL.
+func IsRemotePath(path string) bool {
+	for _, scheme := range remoteSchemes {
+		if strings.HasPrefix(path, scheme+"://") {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteLogin is where and as whom a remote file is read.
+type remoteLogin struct {
+	scheme   string
+	host     string
+	port     string
+	username string
+	password string
+	// file is the path of the file on the server.
+	file string
+}
+
+// parseRemotePath resolves the server, file and credentials of an sftp://
+// or ftp:// path. The file path is absolute, or relative to the login
+// directory if it starts with /~/.
+func parseRemotePath(path string, remote *config.RemoteConfig) (remoteLogin, error) {
+	if remote == nil {
+		remote = &config.RemoteConfig{}
+	}
+	u, err := url.Parse(path)
+	if err != nil {
+		return remoteLogin{}, fmt.Errorf("invalid remote path %s: %w", path, err)
+	}
+	if _, ok := u.User.Password(); ok {
+		return remoteLogin{}, fmt.Errorf("remote path %s holds a password; set source.remote.password or %s_PASSWORD instead", RedactDSN(path), strings.ToUpper(u.Scheme))
+	}
+	login := remoteLogin{
+		scheme:   u.Scheme,
+		host:     u.Hostname(),
+		port:     u.Port(),
+		username: firstNonEmpty(remote.Username, u.User.Username()),
+		password: firstNonEmpty(os.ExpandEnv(remote.Password), os.Getenv(strings.ToUpper(u.Scheme)+"_PASSWORD")),
+		file:     u.Path,
+	}
+	if rel, ok := strings.CutPrefix(login.file, "/~/"); ok {
+		login.file = rel
+	}
+	if login.host == "" || login.file == "" || strings.HasSuffix(login.file, "/") {
+		return remoteLogin{}, fmt.Errorf("remote path %s needs a host and a file name", path)
+	}
+	// ssh would take a host or user starting with - as one of its options,
+	// such as -oProxyCommand running any command.
+	if strings.HasPrefix(login.host, "-") || strings.HasPrefix(login.username, "-") {
+		return remoteLogin{}, fmt.Errorf("remote path %s has a host or user name starting with -", RedactDSN(path))
+	}
+	return login, nil
+}
+
+// openRemote opens the file at an sftp:// or ftp:// path.
+func openRemote(path string, remote *config.RemoteConfig) (sourceFile, error) {
+	login, err := parseRemotePath(path, remote)
+	if err != nil {
+		return nil, err
+	}
+	var file sourceFile
+	if login.scheme == "sftp" {
+		file, err = openSFTP(login, remote)
+	} else {
+		file, err = openFTP(login)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	return file, nil
+}
+
+// StatRemote returns the size of the file at an sftp:// or ftp:// path.
+func StatRemote(path string, remote *config.RemoteConfig) (int64, error) {
+	file, err := openRemote(path, remote)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	return file.Size()
+}
+
+// RedactRemote returns a copy of remote without its password, for reports,
+// unless it references the environment.
+func RedactRemote(remote *config.RemoteConfig) *config.RemoteConfig {
+	if remote == nil {
+		return nil
+	}
+	redacted := *remote
+	if !strings.HasPrefix(remote.Password, "$") {
+		redacted.Password = ""
+	}
+	return &redacted
+}
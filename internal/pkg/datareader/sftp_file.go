@@ -0,0 +1,488 @@
+package datareader
+
+import (
+	"bufio"
+	"data-comparator/internal/pkg/config"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SFTP version 3 packet types, as sent by OpenSSH's sftp-server.
+const (
+	sftpInit    = 1
+	sftpVersion = 2
+	sftpOpen    = 3
+	sftpClose   = 4
+	sftpRead    = 5
+	sftpFstat   = 8
+	sftpStatus  = 101
+	sftpHandle  = 102
+	sftpData    = 103
+	sftpAttrs   = 105
+)
+
+const (
+	sftpStatusEOF   = 1
+	sftpOpenRead    = 1
+	sftpAttrSize    = 1
+	sftpMaxPacket   = 256 << 10
+	sftpChunk       = 32 << 10
+	sftpReadAhead   = 16
+	sftpPasswordEnv = "STREAM_DIFF_SFTP_PASSWORD"
+)
+
+// sftpFile is a file read over SFTP. Sequential reads keep several chunks
+// in flight, as a round trip per chunk would bound the transfer rate by the
+// latency to the server.
+type sftpFile struct {
+	client  *sftpClient
+	session *sshSession
+	handle  string
+	size    int64
+
+	// sequential read state
+	pending []sftpPendingRead
+	next    int64
+	buf     []byte
+}
+
+type sftpPendingRead struct {
+	offset int64
+	reply  chan sftpPacket
+}
+
+// openSFTP runs the system ssh client with the sftp subsystem and opens
+// login.file. A password is handed to ssh through SSH_ASKPASS; without one,
+// ssh must authenticate by key or agent without prompting.
+func openSFTP(login remoteLogin, remote *config.RemoteConfig) (*sftpFile, error) {
+	session, err := startSSH(login, remote)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newSFTPClient(session.stdin, session.stdout)
+	var f *sftpFile
+	if err == nil {
+		f, err = newSFTPFile(client, login.file)
+	}
+	if err == nil {
+		f.session = session
+		return f, nil
+	}
+	// ssh failing, e.g. to authenticate, explains the failure better.
+	session.stdin.Close()
+	if waitErr := session.wait(client); waitErr != nil {
+		return nil, waitErr
+	}
+	return nil, err
+}
+
+// sshSession is an ssh process running the sftp subsystem.
+type sshSession struct {
+	host    string
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	stdout  io.Reader
+	stderr  strings.Builder
+	askpass string
+}
+
+func startSSH(login remoteLogin, remote *config.RemoteConfig) (*sshSession, error) {
+	if remote == nil {
+		remote = &config.RemoteConfig{}
+	}
+	args := []string{"-x", "-a", "-o", "ClearAllForwardings=yes", "-o", fmt.Sprintf("ConnectTimeout=%d", int(remoteTimeout.Seconds()))}
+	if login.port != "" {
+		args = append(args, "-p", login.port)
+	}
+	if login.username != "" {
+		args = append(args, "-l", login.username)
+	}
+	if keyFile := firstNonEmpty(remote.KeyFile, os.Getenv("SFTP_KEY_FILE")); keyFile != "" {
+		args = append(args, "-i", keyFile)
+	}
+	if remote.KnownHosts != "" {
+		args = append(args, "-o", "UserKnownHostsFile="+remote.KnownHosts)
+	}
+	s := &sshSession{host: login.host}
+	if login.password == "" {
+		args = append(args, "-o", "BatchMode=yes")
+	} else {
+		// The askpass script answers every prompt with the password, so
+		// unknown host keys must fail rather than be confirmed by it.
+		askpass, err := os.CreateTemp("", "stream-diff-askpass-*")
+		if err != nil {
+			return nil, err
+		}
+		s.askpass = askpass.Name()
+		_, err = fmt.Fprintf(askpass, "#!/bin/sh\nprintf '%%s\\n' \"$%s\"\n", sftpPasswordEnv)
+		if closeErr := askpass.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Chmod(s.askpass, 0o700)
+		}
+		if err != nil {
+			os.Remove(s.askpass)
+			return nil, fmt.Errorf("failed to create the ssh askpass script: %w", err)
+		}
+		args = append(args, "-o", "StrictHostKeyChecking=yes", "-o", "NumberOfPasswordPrompts=1")
+	}
+	args = append(args, "-s", "--", login.host, "sftp")
+
+	s.cmd = exec.Command("ssh", args...)
+	if s.askpass != "" {
+		s.cmd.Env = append(os.Environ(), "SSH_ASKPASS="+s.askpass, "SSH_ASKPASS_REQUIRE=force", sftpPasswordEnv+"="+login.password)
+	}
+	s.cmd.Stderr = &s.stderr
+	var err error
+	if s.stdin, err = s.cmd.StdinPipe(); err == nil {
+		s.stdout, err = s.cmd.StdoutPipe()
+	}
+	if err == nil {
+		err = s.cmd.Start()
+	}
+	if err != nil {
+		os.Remove(s.askpass)
+		return nil, fmt.Errorf("failed to start ssh: %w", err)
+	}
+	return s, nil
+}
+
+// wait waits for ssh to exit once client, whose stdin must be closed, has
+// stopped reading its output, and removes the askpass script. ssh still
+// running after remoteTimeout is killed.
+func (s *sshSession) wait(client *sftpClient) error {
+	defer os.Remove(s.askpass)
+	if client != nil {
+		select {
+		case <-client.done:
+		case <-time.After(remoteTimeout):
+			s.cmd.Process.Kill()
+		}
+	}
+	if err := s.cmd.Wait(); err != nil {
+		return fmt.Errorf("ssh to %s failed: %w: %s", s.host, err, strings.TrimSpace(s.stderr.String()))
+	}
+	return nil
+}
+
+// newSFTPFile opens file for reading over client.
+func newSFTPFile(client *sftpClient, file string) (*sftpFile, error) {
+	reply, err := client.send(sftpOpen, sftpString(file), sftpUint32(sftpOpenRead), sftpUint32(0))
+	if err != nil {
+		return nil, err
+	}
+	p, err := client.wait(reply, sftpHandle)
+	if err != nil {
+		return nil, err
+	}
+	handle, _, err := p.string()
+	if err != nil {
+		return nil, err
+	}
+	f := &sftpFile{client: client, handle: handle, size: -1}
+	if reply, err = client.send(sftpFstat, sftpString(handle)); err == nil {
+		p, err = client.wait(reply, sftpAttrs)
+	}
+	if err != nil {
+		f.closeHandle()
+		return nil, err
+	}
+	if flags, rest, err := p.uint32(); err == nil && flags&sftpAttrSize != 0 && len(rest) >= 8 {
+		f.size = int64(binary.BigEndian.Uint64(rest))
+	}
+	return f, nil
+}
+
+// request asks for length bytes from offset.
+func (f *sftpFile) request(offset int64, length int) (chan sftpPacket, error) {
+	return f.client.send(sftpRead, sftpString(f.handle), sftpUint64(uint64(offset)), sftpUint32(uint32(length)))
+}
+
+// data waits for the reply of a read request. The end of the file is
+// io.EOF.
+func (f *sftpFile) data(reply chan sftpPacket) ([]byte, error) {
+	p, err := f.client.wait(reply, sftpData)
+	if err != nil {
+		return nil, err
+	}
+	data, _, err := p.string()
+	if err == nil && data == "" {
+		err = io.EOF
+	}
+	return []byte(data), err
+}
+
+// Read returns the file in order, requesting the chunks ahead of it.
+func (f *sftpFile) Read(p []byte) (int, error) {
+	for len(f.buf) == 0 {
+		for len(f.pending) < sftpReadAhead {
+			reply, err := f.request(f.next, sftpChunk)
+			if err != nil {
+				return 0, err
+			}
+			f.pending = append(f.pending, sftpPendingRead{offset: f.next, reply: reply})
+			f.next += sftpChunk
+		}
+		r := f.pending[0]
+		f.pending = f.pending[1:]
+		data, err := f.data(r.reply)
+		if err != nil {
+			f.pending, f.next = nil, r.offset
+			return 0, err
+		}
+		if len(data) < sftpChunk {
+			// Servers may return less than asked for; the requests after it
+			// started at the wrong offsets.
+			f.pending, f.next = nil, r.offset+int64(len(data))
+		}
+		f.buf = data
+	}
+	n := copy(p, f.buf)
+	f.buf = f.buf[n:]
+	return n, nil
+}
+
+// ReadAt reads len(p) bytes from off, with several chunks in flight.
+func (f *sftpFile) ReadAt(p []byte, off int64) (int, error) {
+	n := 0
+	for n < len(p) {
+		var pending []sftpPendingRead
+		for start := n; start < len(p) && len(pending) < sftpReadAhead; start += sftpChunk {
+			reply, err := f.request(off+int64(start), min(sftpChunk, len(p)-start))
+			if err != nil {
+				return n, err
+			}
+			pending = append(pending, sftpPendingRead{offset: int64(start), reply: reply})
+		}
+		for _, r := range pending {
+			data, err := f.data(r.reply)
+			if err != nil {
+				return n, err
+			}
+			n = int(r.offset) + copy(p[r.offset:], data)
+			if len(data) < min(sftpChunk, len(p)-int(r.offset)) {
+				// Request the rest again from where this one ended.
+				break
+			}
+		}
+	}
+	return n, nil
+}
+
+// Size returns the size the server reported for the open file.
+func (f *sftpFile) Size() (int64, error) {
+	if f.size < 0 {
+		return 0, fmt.Errorf("the SFTP server does not report the size of the file")
+	}
+	return f.size, nil
+}
+
+func (f *sftpFile) closeHandle() error {
+	reply, err := f.client.send(sftpClose, sftpString(f.handle))
+	if err != nil {
+		return err
+	}
+	_, err = f.client.wait(reply, sftpStatus)
+	return err
+}
+
+// Close closes the file and ends the SFTP session.
+func (f *sftpFile) Close() error {
+	err := f.closeHandle()
+	f.client.w.Close()
+	if f.session != nil {
+		if waitErr := f.session.wait(f.client); err == nil {
+			err = waitErr
+		}
+	}
+	return err
+}
+
+// sftpClient sends requests to an SFTP server and routes each reply to its
+// request by id, so several requests can be in flight.
+type sftpClient struct {
+	w    io.WriteCloser
+	done chan struct{}
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	id      uint32
+	replies map[uint32]chan sftpPacket
+	err     error
+}
+
+// sftpPacket is a reply without its id.
+type sftpPacket struct {
+	typ  byte
+	data sftpBytes
+}
+
+// newSFTPClient negotiates version 3 with the server at the other end of w
+// and r.
+func newSFTPClient(w io.WriteCloser, r io.Reader) (*sftpClient, error) {
+	c := &sftpClient{w: w, done: make(chan struct{}), replies: map[uint32]chan sftpPacket{}}
+	if err := c.write(sftpInit, sftpUint32(3)); err != nil {
+		close(c.done)
+		return c, err
+	}
+	br := bufio.NewReader(r)
+	typ, _, err := readSFTPPacket(br)
+	if err == nil && typ != sftpVersion {
+		err = fmt.Errorf("unexpected SFTP packet type %d", typ)
+	}
+	if err != nil {
+		close(c.done)
+		return c, fmt.Errorf("SFTP session failed to start: %w", err)
+	}
+	go c.dispatch(br)
+	return c, nil
+}
+
+// dispatch hands replies to their requests until the server goes away,
+// which fails the requests still waiting.
+func (c *sftpClient) dispatch(r *bufio.Reader) {
+	defer close(c.done)
+	for {
+		typ, data, err := readSFTPPacket(r)
+		if err == nil && len(data) < 4 {
+			err = fmt.Errorf("SFTP packet type %d has no id", typ)
+		}
+		if err != nil {
+			if err == io.EOF {
+				err = errors.New("SFTP session closed")
+			}
+			c.mu.Lock()
+			c.err = err
+			for id, reply := range c.replies {
+				close(reply)
+				delete(c.replies, id)
+			}
+			c.mu.Unlock()
+			return
+		}
+		id := binary.BigEndian.Uint32(data)
+		c.mu.Lock()
+		reply, ok := c.replies[id]
+		delete(c.replies, id)
+		c.mu.Unlock()
+		if ok {
+			reply <- sftpPacket{typ: typ, data: data[4:]}
+		}
+	}
+}
+
+// send writes a request and returns the channel its reply arrives on.
+func (c *sftpClient) send(typ byte, fields ...[]byte) (chan sftpPacket, error) {
+	reply := make(chan sftpPacket, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, c.err
+	}
+	c.id++
+	id := c.id
+	c.replies[id] = reply
+	c.mu.Unlock()
+	if err := c.write(typ, append([][]byte{sftpUint32(id)}, fields...)...); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+func (c *sftpClient) write(typ byte, fields ...[]byte) error {
+	length := 1
+	for _, field := range fields {
+		length += len(field)
+	}
+	packet := append(sftpUint32(uint32(length)), typ)
+	for _, field := range fields {
+		packet = append(packet, field...)
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err := c.w.Write(packet)
+	return err
+}
+
+// wait returns the reply on reply, which must be of type want. A status
+// reply is returned as an error, the end of a file as io.EOF.
+func (c *sftpClient) wait(reply chan sftpPacket, want byte) (sftpBytes, error) {
+	p, ok := <-reply
+	if !ok {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return nil, c.err
+	}
+	if p.typ == want {
+		return p.data, nil
+	}
+	if p.typ != sftpStatus {
+		return nil, fmt.Errorf("unexpected SFTP packet type %d", p.typ)
+	}
+	code, rest, err := p.data.uint32()
+	if err != nil {
+		return nil, err
+	}
+	if code == 0 && want == sftpStatus {
+		return nil, nil
+	}
+	if code == sftpStatusEOF {
+		return nil, io.EOF
+	}
+	message, _, _ := rest.string()
+	return nil, fmt.Errorf("SFTP error %d: %s", code, message)
+}
+
+func readSFTPPacket(r *bufio.Reader) (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length < 1 || length > sftpMaxPacket {
+		return 0, nil, fmt.Errorf("invalid SFTP packet length %d", length)
+	}
+	data := make([]byte, length-1)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, unexpectedEOF(err)
+	}
+	return header[4], data, nil
+}
+
+// sftpBytes is the rest of a packet being decoded.
+type sftpBytes []byte
+
+func (b sftpBytes) uint32() (uint32, sftpBytes, error) {
+	if len(b) < 4 {
+		return 0, nil, errors.New("SFTP packet too short")
+	}
+	return binary.BigEndian.Uint32(b), b[4:], nil
+}
+
+func (b sftpBytes) string() (string, sftpBytes, error) {
+	length, rest, err := b.uint32()
+	if err != nil || uint32(len(rest)) < length {
+		return "", nil, errors.New("SFTP packet too short")
+	}
+	return string(rest[:length]), rest[length:], nil
+}
+
+func sftpUint32(v uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, v)
+}
+
+func sftpUint64(v uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, v)
+}
+
+func sftpString(s string) []byte {
+	return append(sftpUint32(uint32(len(s))), s...)
+}
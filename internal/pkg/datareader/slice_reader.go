@@ -0,0 +1,28 @@
+package datareader
+
+import "io"
+
+// SliceReader reads records from an in-memory slice.
+type SliceReader struct {
+	records []Record
+}
+
+// NewSliceReader creates a reader that returns the given records in order.
+func NewSliceReader(records []Record) *SliceReader {
+	return &SliceReader{records: records}
+}
+
+// Read returns the next record, or io.EOF when all records have been read.
+func (r *SliceReader) Read() (Record, error) {
+	if len(r.records) == 0 {
+		return nil, io.EOF
+	}
+	rec := r.records[0]
+	r.records = r.records[1:]
+	return rec, nil
+}
+
+// Close is a no-op.
+func (r *SliceReader) Close() error {
+	return nil
+}
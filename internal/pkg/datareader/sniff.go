@@ -0,0 +1,260 @@
+package datareader
+
+import (
+	"bufio"
+	"bytes"
+	"data-comparator/internal/pkg/config"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// sniffSize is the number of bytes inspected at the start of a file.
+const sniffSize = 64 * 1024
+
+// sniffRows is the number of CSV rows inspected for delimiter and content detection.
+const sniffRows = 20
+
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// csvDelimiters are the candidate CSV separators, in order of preference.
+var csvDelimiters = []rune{',', ';', '\t', '|'}
+
+// SniffResult holds the source settings detected from the start of a file.
+type SniffResult struct {
+	Type         string              `yaml:"type"`
+	Compression  string              `yaml:"compression,omitempty"`
+	JSONArray    bool                `yaml:"json_array,omitempty"`
+	ParserConfig config.ParserConfig `yaml:"parser_config"`
+	// XML holds the record element guessed for an xml file: the first
+	// element inside the document's root.
+	XML *config.XMLParserConfig `yaml:"xml,omitempty"`
+}
+
+// Sniff inspects the first kilobytes of a file to detect its format: compression,
+// JSON-Lines vs JSON array, the record element of XML, or the CSV delimiter,
+// header presence and whether string cells hold embedded JSON. The format of compressed files is sniffed
+// from their decompressed start.
+func Sniff(path string) (*SniffResult, error) {
+	return sniffSource(config.Source{Path: path})
+}
+
+// sniffSource sniffs the file, or object, of cfg. Only the first kilobytes
+// of an object are fetched.
+func sniffSource(cfg config.Source) (*SniffResult, error) {
+	file, err := openSource(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %w", cfg.Path, err)
+	}
+	defer file.Close()
+
+	buf := make([]byte, sniffSize)
+	n, err := file.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read file %s: %w", cfg.Path, err)
+	}
+	result := sniffBytes(buf[:n])
+	if result.Compression == "" || cfg.Compression == "none" {
+		return result, nil
+	}
+
+	// Sniff the start of the decompressed data instead.
+	size, err := file.Size()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", cfg.Path, err)
+	}
+	compressed := bufio.NewReaderSize(io.NewSectionReader(file, 0, size), sniffSize)
+	decoded, closer, _, err := decompress(compressed, io.NopCloser(nil), config.Source{Path: cfg.Path, Compression: result.Compression})
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+	n, err = io.ReadFull(decoded, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("failed to decompress %s: %w", cfg.Path, err)
+	}
+	sniffed := sniffBytes(buf[:n])
+	sniffed.Compression = result.Compression
+	return sniffed, nil
+}
+
+func sniffBytes(data []byte) *SniffResult {
+	if bytes.HasPrefix(data, parquetMagic) {
+		return &SniffResult{Type: "parquet"}
+	}
+	if bytes.HasPrefix(data, avroMagic) {
+		return &SniffResult{Type: "avro"}
+	}
+	if bytes.HasPrefix(data, xlsxMagic) {
+		return &SniffResult{Type: "xlsx"}
+	}
+	if compression := detectCompression(data); compression != "" {
+		return &SniffResult{Compression: compression}
+	}
+
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(data, utf8BOM), " \t\r\n")
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return &SniffResult{Type: "json", JSONArray: trimmed[0] == '['}
+	}
+	if len(trimmed) > 0 && trimmed[0] == '<' {
+		return sniffXML(trimmed)
+	}
+
+	// Drop a trailing partial line, which may have been cut off mid-record
+	if len(data) == sniffSize {
+		if i := bytes.LastIndexByte(data, '\n'); i > 0 {
+			data = data[:i+1]
+		}
+	}
+
+	result := &SniffResult{Type: "csv"}
+	delimiter, rows := detectDelimiter(data)
+	if delimiter != ',' {
+		result.ParserConfig.Delimiter = string(delimiter)
+	}
+	result.ParserConfig.NoHeader = !looksLikeHeader(rows)
+	result.ParserConfig.JSONInString = hasEmbeddedJSON(rows)
+	return result
+}
+
+func detectCompression(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		return "gzip"
+	case bytes.HasPrefix(data, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return "zstd"
+	case bytes.HasPrefix(data, []byte("BZh")):
+		return "bzip2"
+	default:
+		return ""
+	}
+}
+
+// detectDelimiter picks the candidate delimiter that splits the sampled rows
+// into the most columns with a consistent column count.
+func detectDelimiter(data []byte) (rune, [][]string) {
+	best, bestRows, bestColumns := ',', [][]string(nil), 0
+	for _, delimiter := range csvDelimiters {
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.Comma = delimiter
+		reader.LazyQuotes = true
+
+		var rows [][]string
+		consistent := true
+		for len(rows) < sniffRows {
+			row, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				consistent = false
+				break
+			}
+			rows = append(rows, row)
+		}
+		if !consistent || len(rows) == 0 {
+			continue
+		}
+		if columns := len(rows[0]); columns > bestColumns {
+			best, bestRows, bestColumns = delimiter, rows, columns
+		}
+	}
+	return best, bestRows
+}
+
+// looksLikeHeader assumes the first row is a header unless one of its cells is
+// numeric or it is the only row, as column names are rarely numbers.
+func looksLikeHeader(rows [][]string) bool {
+	if len(rows) == 0 {
+		return true
+	}
+	for _, cell := range rows[0] {
+		if _, err := strconv.ParseFloat(strings.TrimSpace(cell), 64); err == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// hasEmbeddedJSON reports whether any column has a majority of non-empty data
+// cells holding JSON objects or arrays.
+func hasEmbeddedJSON(rows [][]string) bool {
+	if len(rows) < 2 {
+		return false
+	}
+	data := rows[1:]
+	for col := range rows[0] {
+		nonEmpty, jsonCells := 0, 0
+		for _, row := range data {
+			if col >= len(row) || strings.TrimSpace(row[col]) == "" {
+				continue
+			}
+			nonEmpty++
+			cell := strings.TrimSpace(row[col])
+			if (cell[0] == '{' || cell[0] == '[') && json.Valid([]byte(cell)) {
+				jsonCells++
+			}
+		}
+		if nonEmpty > 0 && jsonCells*2 > nonEmpty {
+			return true
+		}
+	}
+	return false
+}
+
+// autoSource fills in the type and parser config of a source from a sniff of its
+// file. Explicitly configured parser settings are kept.
+func autoSource(cfg config.Source) (config.Source, error) {
+	sniffed, err := sniffSource(cfg)
+	if err != nil {
+		return cfg, err
+	}
+	return applySniffed(cfg, sniffed)
+}
+
+// applySniffed sets the detected type, and the parser config unless one is configured.
+func applySniffed(cfg config.Source, sniffed *SniffResult) (config.Source, error) {
+	if sniffed.Type == "" {
+		return cfg, fmt.Errorf("cannot detect the format of %s: %s compressed files are not supported", cfg.Path, sniffed.Compression)
+	}
+
+	cfg.Type = sniffed.Type
+	if cfg.Compression == "" {
+		cfg.Compression = sniffed.Compression
+	}
+	if cfg.ParserConfig == nil {
+		pcfg := sniffed.ParserConfig
+		cfg.ParserConfig = &pcfg
+	}
+	if cfg.XML == nil {
+		cfg.XML = sniffed.XML
+	}
+	return cfg, nil
+}
+
+// sniffXML guesses the record element of an XML document from its start.
+func sniffXML(data []byte) *SniffResult {
+	result := &SniffResult{Type: "xml"}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = xmlCharsetReader
+	depth := 0
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return result
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if depth++; depth == 2 {
+				result.XML = &config.XMLParserConfig{RecordElement: t.Name.Local}
+				return result
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package datareader
+
+import (
+	"context"
+	"data-comparator/internal/pkg/config"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// DefaultFetchSize is the number of rows fetched from a database per round trip.
+const DefaultFetchSize = 10000
+
+// DatabaseTypes lists the source types read from a database instead of from
+// a file.
+var DatabaseTypes = []string{"postgres", "mysql", "bigquery"}
+
+// IsDatabaseType reports whether sources of the type are read from a database.
+func IsDatabaseType(typ string) bool {
+	for _, t := range DatabaseTypes {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// sqlDialect adapts the sqlReader to one database.
+type sqlDialect struct {
+	// typ is the source type, naming the database in errors.
+	typ string
+	// driver is the database/sql driver name.
+	driver string
+	// quote quotes one part of a table name.
+	quote func(string) string
+	// cursor returns the statement declaring a cursor over query and the
+	// query fetching its next batch of rows. Without a declare statement the
+	// fetch query returns all rows, streamed from a single result set.
+	cursor func(query string, fetchSize int) (declare, fetch string)
+	// convert turns a scanned value into the Record value of its column's
+	// database type.
+	convert func(v interface{}, typ string) interface{}
+}
+
+// sqlReader streams the rows of a table or query in a read-only transaction,
+// either batch by batch through a server-side cursor or from a single result
+// set the driver reads as rows are consumed.
+type sqlReader struct {
+	name      string
+	dialect   sqlDialect
+	db        *sql.DB
+	tx        *sql.Tx
+	fetch     string
+	batched   bool
+	rows      *sql.Rows
+	columns   []string
+	types     []string
+	fetched   int
+	done      bool
+	records   int
+	fetchSize int
+}
+
+// newSQLReader connects to cfg.DSN and starts reading cfg.Table or cfg.Query.
+// The transaction is rolled back on Close.
+func newSQLReader(cfg config.Source, dialect sqlDialect) (*sqlReader, error) {
+	query, name, err := sqlQuery(cfg, dialect)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.DSN == "" {
+		return nil, fmt.Errorf("%s source %s needs a dsn", dialect.typ, name)
+	}
+	fetchSize := cfg.FetchSize
+	if fetchSize <= 0 {
+		fetchSize = DefaultFetchSize
+	}
+
+	db, err := sql.Open(dialect.driver, cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s connection for %s: %w", dialect.typ, name, err)
+	}
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to %s for %s: %w", dialect.typ, name, err)
+	}
+	declare, fetch := dialect.cursor(query, fetchSize)
+	r := &sqlReader{
+		name:      name,
+		dialect:   dialect,
+		db:        db,
+		tx:        tx,
+		fetch:     fetch,
+		batched:   declare != "",
+		fetchSize: fetchSize,
+	}
+	if r.batched {
+		_, err = tx.ExecContext(ctx, declare)
+	} else {
+		err = r.fetchBatch()
+	}
+	if err != nil {
+		tx.Rollback()
+		db.Close()
+		return nil, fmt.Errorf("failed to query %s: %w", name, err)
+	}
+	return r, nil
+}
+
+// sqlQuery returns the query reading the source and a name for it in errors.
+func sqlQuery(cfg config.Source, dialect sqlDialect) (string, string, error) {
+	switch {
+	case cfg.Table != "" && cfg.Query != "":
+		return "", "", fmt.Errorf("%s source sets both table %s and a query", dialect.typ, cfg.Table)
+	case cfg.Table != "":
+		parts := strings.Split(cfg.Table, ".")
+		for i, part := range parts {
+			parts[i] = dialect.quote(part)
+		}
+		return "SELECT * FROM " + strings.Join(parts, "."), "table " + cfg.Table, nil
+	case cfg.Query != "":
+		return strings.TrimRight(strings.TrimSpace(cfg.Query), ";"), "query", nil
+	default:
+		return "", "", fmt.Errorf("%s source needs a table or a query", dialect.typ)
+	}
+}
+
+// Read returns the next row, fetching the next batch when the current one is used up.
+func (r *sqlReader) Read() (Record, error) {
+	for {
+		if r.rows == nil {
+			if r.done {
+				return nil, io.EOF
+			}
+			if err := r.fetchBatch(); err != nil {
+				return nil, &ParseError{Source: r.name, Record: r.records + 1, Offset: -1, Err: err}
+			}
+			continue
+		}
+		if r.rows.Next() {
+			break
+		}
+		err := r.rows.Err()
+		r.rows.Close()
+		r.rows = nil
+		if err != nil {
+			return nil, &ParseError{Source: r.name, Record: r.records + 1, Offset: -1, Err: err}
+		}
+		// A single result set, or a short batch, was the last one.
+		r.done = !r.batched || r.fetched < r.fetchSize
+	}
+
+	r.fetched++
+	r.records++
+	values := make([]interface{}, len(r.columns))
+	ptrs := make([]interface{}, len(values))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := r.rows.Scan(ptrs...); err != nil {
+		return nil, &ParseError{Source: r.name, Record: r.records, Offset: -1, Err: err}
+	}
+	rec := make(Record, len(values))
+	for i, v := range values {
+		rec[r.columns[i]] = r.dialect.convert(v, r.types[i])
+	}
+	return rec, nil
+}
+
+func (r *sqlReader) fetchBatch() error {
+	rows, err := r.tx.Query(r.fetch)
+	if err != nil {
+		return err
+	}
+	if r.columns == nil {
+		if r.columns, err = rows.Columns(); err != nil {
+			rows.Close()
+			return err
+		}
+		types, err := rows.ColumnTypes()
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		for _, t := range types {
+			r.types = append(r.types, t.DatabaseTypeName())
+		}
+	}
+	r.rows, r.fetched = rows, 0
+	return nil
+}
+
+// Close closes the result set and the connection.
+func (r *sqlReader) Close() error {
+	if r.rows != nil {
+		r.rows.Close()
+	}
+	r.tx.Rollback()
+	return r.db.Close()
+}
+
+// dsnPassword matches the password of a key=value connection string.
+var dsnPassword = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// mysqlDSNPassword matches the password of a user:password@protocol(address)/db
+// connection string, which may itself hold an @.
+var mysqlDSNPassword = regexp.MustCompile(`^([^:/@]*:)(.*)@(\w*\(|/)`)
+
+// RedactDSN masks the password in a connection string, in URL, key=value or
+// MySQL form, so it can be written to reports.
+func RedactDSN(dsn string) string {
+	if dsn == "" {
+		return dsn
+	}
+	if mysqlDSNPassword.MatchString(dsn) {
+		return mysqlDSNPassword.ReplaceAllString(dsn, "${1}xxxxx@${3}")
+	}
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+		}
+		q := u.Query()
+		if q.Has("password") {
+			q.Set("password", "xxxxx")
+			u.RawQuery = q.Encode()
+		}
+		return u.String()
+	}
+	return dsnPassword.ReplaceAllString(dsn, "${1}xxxxx")
+}
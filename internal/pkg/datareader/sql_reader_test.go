@@ -0,0 +1,134 @@
+package datareader
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSQL is a database/sql driver serving a fixed table, either through the
+// DECLARE and FETCH statements of a postgres cursor or as the result of any
+// SELECT query.
+type fakeSQL struct {
+	mu      sync.Mutex
+	columns []string
+	types   []string
+	rows    [][]driver.Value
+	queries []string
+}
+
+var fakeSQLDriver = &fakeSQL{}
+
+func init() {
+	sql.Register("fakesql", fakeSQLDriver)
+}
+
+func (d *fakeSQL) Open(string) (driver.Conn, error) { return &fakeSQLConn{d: d}, nil }
+
+type fakeSQLConn struct {
+	d   *fakeSQL
+	pos int
+}
+
+func (c *fakeSQLConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeSQLStmt{c: c, query: query}, nil
+}
+func (c *fakeSQLConn) Close() error              { return nil }
+func (c *fakeSQLConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *fakeSQLConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return c, nil
+}
+func (c *fakeSQLConn) Commit() error   { return nil }
+func (c *fakeSQLConn) Rollback() error { return nil }
+
+type fakeSQLStmt struct {
+	c     *fakeSQLConn
+	query string
+}
+
+func (s *fakeSQLStmt) Close() error  { return nil }
+func (s *fakeSQLStmt) NumInput() int { return 0 }
+
+func (s *fakeSQLStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.c.d.mu.Lock()
+	s.c.d.queries = append(s.c.d.queries, s.query)
+	s.c.d.mu.Unlock()
+	if !strings.HasPrefix(s.query, "DECLARE ") {
+		return nil, fmt.Errorf("unexpected statement %q", s.query)
+	}
+	s.c.pos = 0
+	return driver.RowsAffected(0), nil
+}
+
+func (s *fakeSQLStmt) Query([]driver.Value) (driver.Rows, error) {
+	s.c.d.mu.Lock()
+	s.c.d.queries = append(s.c.d.queries, s.query)
+	s.c.d.mu.Unlock()
+	var n int
+	if strings.HasPrefix(s.query, "SELECT ") {
+		n = len(s.c.d.rows)
+	} else if _, err := fmt.Sscanf(s.query, "FETCH FORWARD %d FROM", &n); err != nil {
+		return nil, fmt.Errorf("unexpected query %q", s.query)
+	}
+	end := min(s.c.pos+n, len(s.c.d.rows))
+	rows := &fakeSQLRows{d: s.c.d, rows: s.c.d.rows[s.c.pos:end]}
+	s.c.pos = end
+	return rows, nil
+}
+
+type fakeSQLRows struct {
+	d    *fakeSQL
+	rows [][]driver.Value
+}
+
+func (r *fakeSQLRows) Columns() []string { return r.d.columns }
+func (r *fakeSQLRows) Close() error      { return nil }
+func (r *fakeSQLRows) ColumnTypeDatabaseTypeName(i int) string {
+	return r.d.types[i]
+}
+
+func (r *fakeSQLRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// useFakeSQL serves the given table to postgres and mysql sources.
+func useFakeSQL(t *testing.T, columns, types []string, rows [][]driver.Value) *fakeSQL {
+	t.Helper()
+	previousPostgres, previousMySQL := postgresDriver, mysqlDriver
+	postgresDriver, mysqlDriver = "fakesql", "fakesql"
+	t.Cleanup(func() { postgresDriver, mysqlDriver = previousPostgres, previousMySQL })
+	d := fakeSQLDriver
+	d.mu.Lock()
+	d.columns, d.types, d.rows, d.queries = columns, types, rows, nil
+	d.mu.Unlock()
+	return d
+}
+
+func TestRedactDSN(t *testing.T) {
+	tests := map[string]string{
+		"postgres://bob:secret@db:5432/app?sslmode=disable": "postgres://bob:xxxxx@db:5432/app?sslmode=disable",
+		"postgres://db/app?password=secret":                 "postgres://db/app?password=xxxxx",
+		"host=db user=bob password=secret dbname=app":       "host=db user=bob password=xxxxx dbname=app",
+		"host=db password='se cret' dbname=app":             "host=db password=xxxxx dbname=app",
+		"postgres://bob@db/app":                             "postgres://bob@db/app",
+		"bob:se@cret@tcp(db:3306)/app?parseTime=true":       "bob:xxxxx@tcp(db:3306)/app?parseTime=true",
+		"bob:secret@/app":                                   "bob:xxxxx@/app",
+		"bob@tcp(db)/app":                                   "bob@tcp(db)/app",
+		"":                                                  "",
+	}
+	for dsn, want := range tests {
+		if got := RedactDSN(dsn); got != want {
+			t.Errorf("RedactDSN(%q) got = %q, want %q", dsn, got, want)
+		}
+	}
+}
@@ -0,0 +1,497 @@
+package datareader
+
+import (
+	"bufio"
+	"data-comparator/internal/pkg/config"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSyslogIdleTimeout is how long a syslog source listening on an
+// address waits for the next message before it ends.
+const DefaultSyslogIdleTimeout = 10 * time.Second
+
+// maxSyslogMessage bounds the length of an octet-counted syslog message.
+const maxSyslogMessage = 1 << 20
+
+// SyslogNetworks lists the networks syslog sources listen on, as the scheme
+// of their path.
+var SyslogNetworks = []string{"udp", "tcp"}
+
+// IsListenPath reports whether path is a udp:// or tcp:// address a syslog
+// source listens on.
+func IsListenPath(path string) bool {
+	for _, network := range SyslogNetworks {
+		if strings.HasPrefix(path, network+"://") {
+			return true
+		}
+	}
+	return false
+}
+
+// syslogMessages is where a SyslogReader gets its messages from.
+type syslogMessages interface {
+	// next returns the next message and its byte offset, -1 if unknown.
+	next() (string, int64, error)
+	Close() error
+}
+
+// SyslogReader reads RFC 5424 syslog messages, one record per message, with
+// the fields facility, severity, version, timestamp, hostname, app_name,
+// proc_id, msg_id, structured_data and message. Header fields of the nil
+// value "-" are left out, and structured data maps each SD-ID to its
+// parameters.
+type SyslogReader struct {
+	path          string
+	messages      syslogMessages
+	maxMessages   int
+	sequenceField string
+	records       int
+}
+
+// NewSyslogReader opens the syslog source of cfg: a file of messages, one
+// per line or octet-counted, or a udp:// or tcp:// address it receives them
+// on until cfg.Syslog.IdleTimeout passes without one. Files may be gzip,
+// zstd or bzip2 compressed.
+func NewSyslogReader(cfg config.Source) (DataReader, error) {
+	if err := ValidateSyslog(cfg.Syslog); err != nil {
+		return nil, fmt.Errorf("syslog source %s: %w", cfg.Path, err)
+	}
+	if IsListenPath(cfg.Path) {
+		listener, err := listenSyslog(cfg.Path, syslogIdleTimeout(cfg.Syslog))
+		if err != nil {
+			return nil, err
+		}
+		return newSyslogReader(listener, cfg), nil
+	}
+	file, err := openSource(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open syslog file %s: %w", cfg.Path, err)
+	}
+	input, closer, _, err := decompress(file, file, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newSyslogReader(newSyslogFrames(input, closer), cfg), nil
+}
+
+func newSyslogReader(messages syslogMessages, cfg config.Source) *SyslogReader {
+	r := &SyslogReader{path: cfg.Path, messages: messages}
+	if s := cfg.Syslog; s != nil {
+		r.maxMessages = s.MaxMessages
+		r.sequenceField = s.SequenceField
+	}
+	return r
+}
+
+// ValidateSyslog checks that the limits of s are not negative.
+func ValidateSyslog(s *config.SyslogConfig) error {
+	if s == nil {
+		return nil
+	}
+	if s.IdleTimeout < 0 {
+		return fmt.Errorf("syslog.idle_timeout must not be negative, got %s", s.IdleTimeout)
+	}
+	if s.MaxMessages < 0 {
+		return fmt.Errorf("syslog.max_messages must not be negative, got %d", s.MaxMessages)
+	}
+	return nil
+}
+
+func syslogIdleTimeout(s *config.SyslogConfig) time.Duration {
+	if s == nil || s.IdleTimeout == 0 {
+		return DefaultSyslogIdleTimeout
+	}
+	return s.IdleTimeout
+}
+
+// SpoolSyslog receives the messages of a syslog source listening on an
+// address until it ends, and writes them octet-counted to w, from where a
+// syslog source of the same config reads them again. It returns the number
+// of messages received.
+func SpoolSyslog(cfg config.Source, w io.Writer) (int, error) {
+	if err := ValidateSyslog(cfg.Syslog); err != nil {
+		return 0, fmt.Errorf("syslog source %s: %w", cfg.Path, err)
+	}
+	listener, err := listenSyslog(cfg.Path, syslogIdleTimeout(cfg.Syslog))
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	count := 0
+	for cfg.Syslog == nil || cfg.Syslog.MaxMessages == 0 || count < cfg.Syslog.MaxMessages {
+		message, _, err := listener.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return count, err
+		}
+		if _, err := fmt.Fprintf(w, "%d %s", len(message), message); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Read returns the record of the next message, or io.EOF once the source
+// ends. Malformed messages are returned as recoverable parse errors.
+func (r *SyslogReader) Read() (Record, error) {
+	if r.maxMessages > 0 && r.records >= r.maxMessages {
+		return nil, io.EOF
+	}
+	message, offset, err := r.messages.next()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", r.path, err)
+	}
+	r.records++
+	rec, err := parseSyslog(message)
+	if err != nil {
+		return nil, &ParseError{Source: r.path, Record: r.records, Offset: offset, Snippet: truncate(message), Recoverable: true, Err: err}
+	}
+	if r.sequenceField != "" {
+		rec[r.sequenceField] = int64(r.records)
+	}
+	return rec, nil
+}
+
+// Close stops listening, or closes the underlying file.
+func (r *SyslogReader) Close() error {
+	return r.messages.Close()
+}
+
+// parseSyslog parses an RFC 5424 message:
+// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG].
+func parseSyslog(message string) (Record, error) {
+	rest, ok := strings.CutPrefix(message, "<")
+	end := strings.IndexByte(rest, '>')
+	if !ok || end < 1 || end > 3 {
+		return nil, fmt.Errorf("message does not start with a <PRI>")
+	}
+	pri, err := strconv.Atoi(rest[:end])
+	if err != nil || pri > 191 {
+		return nil, fmt.Errorf("invalid PRI %q", rest[:end])
+	}
+	rest = rest[end+1:]
+
+	fields := strings.SplitN(rest, " ", 7)
+	if len(fields) < 7 {
+		return nil, fmt.Errorf("message has no structured data")
+	}
+	version, err := strconv.Atoi(fields[0])
+	if err != nil || version < 1 {
+		return nil, fmt.Errorf("invalid version %q", fields[0])
+	}
+	rec := Record{
+		"facility": int64(pri / 8),
+		"severity": int64(pri % 8),
+		"version":  int64(version),
+	}
+	for i, name := range []string{"timestamp", "hostname", "app_name", "proc_id", "msg_id"} {
+		if value := fields[i+1]; value != "-" {
+			rec[name] = value
+		}
+	}
+
+	sd, rest, err := parseStructuredData(fields[6])
+	if err != nil {
+		return nil, err
+	}
+	if sd != nil {
+		rec["structured_data"] = sd
+	}
+	if rest != "" {
+		msg, ok := strings.CutPrefix(rest, " ")
+		if !ok {
+			return nil, fmt.Errorf("no space between structured data and message")
+		}
+		rec["message"] = strings.TrimPrefix(msg, "\ufeff")
+	}
+	return rec, nil
+}
+
+// parseStructuredData parses the structured data at the start of s, the
+// nil value "-" or SD elements [id name="value" ...], into a map of each
+// SD-ID to its parameters. It returns the rest of s.
+func parseStructuredData(s string) (map[string]interface{}, string, error) {
+	if rest, ok := strings.CutPrefix(s, "-"); ok {
+		return nil, rest, nil
+	}
+	if !strings.HasPrefix(s, "[") {
+		return nil, "", fmt.Errorf("invalid structured data")
+	}
+	sd := make(map[string]interface{})
+	for strings.HasPrefix(s, "[") {
+		end := strings.IndexAny(s, " ]")
+		if end < 2 {
+			return nil, "", fmt.Errorf("structured data element without an SD-ID")
+		}
+		id := s[1:end]
+		params := make(map[string]interface{})
+		s = s[end:]
+		for strings.HasPrefix(s, " ") {
+			eq := strings.Index(s, `="`)
+			if eq < 2 {
+				return nil, "", fmt.Errorf("invalid parameter of structured data element %s", id)
+			}
+			name := s[1:eq]
+			value, rest, err := unescapeParamValue(s[eq+2:])
+			if err != nil {
+				return nil, "", fmt.Errorf("parameter %s of structured data element %s: %w", name, id, err)
+			}
+			params[name] = value
+			s = rest
+		}
+		if !strings.HasPrefix(s, "]") {
+			return nil, "", fmt.Errorf("structured data element %s is not closed", id)
+		}
+		sd[id] = params
+		s = s[1:]
+	}
+	return sd, s, nil
+}
+
+// unescapeParamValue returns the parameter value up to the closing quote
+// at the start of s, with \", \\ and \] unescaped, and the rest of s after
+// the quote.
+func unescapeParamValue(s string) (string, string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			return b.String(), s[i+1:], nil
+		case c == '\\' && i+1 < len(s) && strings.IndexByte(`"\]`, s[i+1]) >= 0:
+			b.WriteByte(s[i+1])
+			i++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", fmt.Errorf("value is not closed")
+}
+
+// syslogFrames splits a stream into syslog messages: octet-counted, as
+// sent over TCP per RFC 6587, where a frame starts with a digit, else one
+// per line.
+type syslogFrames struct {
+	input  *bufio.Reader
+	closer io.Closer
+	offset int64
+}
+
+func newSyslogFrames(input io.Reader, closer io.Closer) *syslogFrames {
+	return &syslogFrames{input: bufio.NewReader(input), closer: closer}
+}
+
+func (f *syslogFrames) next() (string, int64, error) {
+	for {
+		c, err := f.input.ReadByte()
+		if err != nil {
+			return "", f.offset, err
+		}
+		f.offset++
+		switch {
+		case c == '\n' || c == '\r' || c == ' ':
+			continue
+		case c >= '0' && c <= '9':
+			start := f.offset - 1
+			length := int(c - '0')
+			for {
+				if c, err = f.input.ReadByte(); err != nil {
+					return "", start, fmt.Errorf("octet count cut off: %w", unexpectedEOF(err))
+				}
+				f.offset++
+				if c == ' ' {
+					break
+				}
+				if c < '0' || c > '9' || length > maxSyslogMessage/10 {
+					return "", start, fmt.Errorf("invalid octet count at offset %d", start)
+				}
+				length = length*10 + int(c-'0')
+			}
+			if length > maxSyslogMessage {
+				return "", start, fmt.Errorf("message of %d octets exceeds %d", length, maxSyslogMessage)
+			}
+			message := make([]byte, length)
+			n, err := io.ReadFull(f.input, message)
+			f.offset += int64(n)
+			if err != nil {
+				return "", start, fmt.Errorf("message of %d octets cut off: %w", length, unexpectedEOF(err))
+			}
+			return string(message), start, nil
+		default:
+			start := f.offset - 1
+			line, err := f.input.ReadString('\n')
+			f.offset += int64(len(line))
+			if err != nil && err != io.EOF {
+				return "", start, err
+			}
+			return strings.TrimRight(string(c)+line, "\r\n"), start, nil
+		}
+	}
+}
+
+func (f *syslogFrames) Close() error {
+	return f.closer.Close()
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// syslogListener receives syslog messages on a UDP or TCP address, one per
+// datagram, or framed like a syslogFrames stream per TCP connection.
+type syslogListener struct {
+	addr     net.Addr
+	idle     time.Duration
+	messages chan string
+	errs     chan error
+	stop     chan struct{}
+	closing  sync.Once
+	closer   io.Closer
+	mu       sync.Mutex
+	conns    map[net.Conn]bool
+}
+
+// listenSyslog listens on the udp:// or tcp:// address of path until the
+// returned listener is closed.
+func listenSyslog(path string, idle time.Duration) (*syslogListener, error) {
+	network, address, _ := strings.Cut(path, "://")
+	l := &syslogListener{
+		idle:     idle,
+		messages: make(chan string),
+		errs:     make(chan error, 1),
+		stop:     make(chan struct{}),
+		conns:    make(map[net.Conn]bool),
+	}
+	switch network {
+	case "udp":
+		conn, err := net.ListenPacket("udp", address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+		}
+		l.addr, l.closer = conn.LocalAddr(), conn
+		go l.receive(conn)
+	case "tcp":
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+		}
+		l.addr, l.closer = listener.Addr(), listener
+		go l.accept(listener)
+	default:
+		return nil, fmt.Errorf("unsupported syslog address %s, use udp:// or tcp://", path)
+	}
+	return l, nil
+}
+
+// receive sends the messages of the datagrams conn receives.
+func (l *syslogListener) receive(conn net.PacketConn) {
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			l.fail(err)
+			return
+		}
+		if !l.send(strings.TrimRight(string(buf[:n]), "\r\n")) {
+			return
+		}
+	}
+}
+
+// accept serves the connections of listener.
+func (l *syslogListener) accept(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			l.fail(err)
+			return
+		}
+		l.mu.Lock()
+		l.conns[conn] = true
+		l.mu.Unlock()
+		go l.serve(conn)
+	}
+}
+
+// serve sends the messages of conn until it closes. A connection sending
+// malformed frames is dropped; others go on.
+func (l *syslogListener) serve(conn net.Conn) {
+	defer func() {
+		l.mu.Lock()
+		delete(l.conns, conn)
+		l.mu.Unlock()
+		conn.Close()
+	}()
+	frames := newSyslogFrames(conn, conn)
+	for {
+		message, _, err := frames.next()
+		if err != nil || !l.send(message) {
+			return
+		}
+	}
+}
+
+func (l *syslogListener) send(message string) bool {
+	select {
+	case l.messages <- message:
+		return true
+	case <-l.stop:
+		return false
+	}
+}
+
+// fail passes err to next, unless the listener was closed.
+func (l *syslogListener) fail(err error) {
+	select {
+	case <-l.stop:
+	case l.errs <- err:
+	}
+}
+
+// next returns the next message received, or io.EOF once none arrived for
+// the idle timeout.
+func (l *syslogListener) next() (string, int64, error) {
+	timer := time.NewTimer(l.idle)
+	defer timer.Stop()
+	select {
+	case message := <-l.messages:
+		return message, -1, nil
+	case err := <-l.errs:
+		if errors.Is(err, net.ErrClosed) {
+			return "", -1, io.EOF
+		}
+		return "", -1, err
+	case <-timer.C:
+		return "", -1, io.EOF
+	}
+}
+
+// Close stops listening and drops the open connections.
+func (l *syslogListener) Close() error {
+	var err error
+	l.closing.Do(func() {
+		close(l.stop)
+		err = l.closer.Close()
+		l.mu.Lock()
+		for conn := range l.conns {
+			conn.Close()
+		}
+		l.mu.Unlock()
+	})
+	return err
+}
@@ -0,0 +1,186 @@
+package datareader
+
+import (
+	"bytes"
+	"data-comparator/internal/pkg/config"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSyslog = `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"][examplePriority@32473 class="high"] An application event log entry...
+<34>1 2003-10-11T22:14:15.003Z mymachine.example.com su - ID47 - ` + "\ufeff" + `'su root' failed for lonvick on /dev/pts/8
+not syslog at all
+
+<13>1 - - - - - [meta x="a\"b\]c"]
+`
+
+func readSyslog(t *testing.T, cfg config.Source, input string) ([]Record, []error) {
+	t.Helper()
+	cfg.Type = "syslog"
+	reader, err := NewFromReader(strings.NewReader(input), cfg)
+	if err != nil {
+		t.Fatalf("NewFromReader() error = %v", err)
+	}
+	return readAll(t, reader)
+}
+
+func readAll(t *testing.T, reader DataReader) ([]Record, []error) {
+	t.Helper()
+	defer reader.Close()
+	var records []Record
+	var errs []error
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			return records, errs
+		}
+		if err != nil {
+			errs = append(errs, err)
+			var perr *ParseError
+			if !errors.As(err, &perr) || !perr.Recoverable {
+				return records, errs
+			}
+			continue
+		}
+		records = append(records, rec)
+	}
+}
+
+func TestSyslogReader(t *testing.T) {
+	records, errs := readSyslog(t, config.Source{Path: "messages.log", Syslog: &config.SyslogConfig{SequenceField: "seq"}}, testSyslog)
+	want := []Record{
+		{
+			"facility": int64(20), "severity": int64(5), "version": int64(1), "timestamp": "2003-10-11T22:14:15.003Z",
+			"hostname": "mymachine.example.com", "app_name": "evntslog", "msg_id": "ID47",
+			"structured_data": map[string]interface{}{
+				"exampleSDID@32473":     map[string]interface{}{"iut": "3", "eventSource": "Application", "eventID": "1011"},
+				"examplePriority@32473": map[string]interface{}{"class": "high"},
+			},
+			"message": "An application event log entry...", "seq": int64(1),
+		},
+		{
+			"facility": int64(4), "severity": int64(2), "version": int64(1), "timestamp": "2003-10-11T22:14:15.003Z",
+			"hostname": "mymachine.example.com", "app_name": "su", "msg_id": "ID47",
+			"message": "'su root' failed for lonvick on /dev/pts/8", "seq": int64(2),
+		},
+		{
+			"facility": int64(1), "severity": int64(5), "version": int64(1),
+			"structured_data": map[string]interface{}{"meta": map[string]interface{}{"x": `a"b]c`}}, "seq": int64(4),
+		},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records got = %v, want %v", records, want)
+	}
+	var perr *ParseError
+	if len(errs) != 1 || !errors.As(errs[0], &perr) || perr.Record != 3 || !perr.Recoverable {
+		t.Errorf("errors got = %v, want a recoverable parse error of record 3", errs)
+	}
+}
+
+func TestSyslogReader_OctetCounted(t *testing.T) {
+	messages := []string{"<14>1 - host app 12 - - first\nspans lines", "<14>1 - host app 12 - - second"}
+	var input strings.Builder
+	for _, m := range messages {
+		fmt.Fprintf(&input, "%d %s", len(m), m)
+	}
+	records, errs := readSyslog(t, config.Source{Path: "framed.log", Syslog: &config.SyslogConfig{MaxMessages: 1}}, input.String())
+	if len(errs) != 0 || len(records) != 1 || records[0]["message"] != "first\nspans lines" || records[0]["proc_id"] != "12" {
+		t.Errorf("got records %v and errors %v, want the first message only", records, errs)
+	}
+
+	_, errs = readSyslog(t, config.Source{Path: "cut.log"}, "40 <14>1 - - - - - -")
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "cut off") {
+		t.Errorf("errors got = %v, want a message cut off", errs)
+	}
+}
+
+func TestParseSyslog_Errors(t *testing.T) {
+	for _, message := range []string{
+		"<192>1 - - - - - -",
+		"<13> - - - - - -",
+		"<13>1 - - - - -",
+		"<13>1 - - - - - [id x=\"unclosed]",
+		"<13>1 - - - - - [id x=\"y\"]message",
+		"<13>1 - - - - - nosd",
+	} {
+		if rec, err := parseSyslog(message); err == nil {
+			t.Errorf("parseSyslog(%q) got = %v, want an error", message, rec)
+		}
+	}
+}
+
+func TestSyslogListener(t *testing.T) {
+	for _, network := range SyslogNetworks {
+		t.Run(network, func(t *testing.T) {
+			listener, err := listenSyslog(network+"://127.0.0.1:0", 200*time.Millisecond)
+			if err != nil {
+				t.Fatalf("listenSyslog() error = %v", err)
+			}
+			reader := newSyslogReader(listener, config.Source{Path: network})
+			conn, err := net.Dial(network, listener.addr.String())
+			if err != nil {
+				t.Fatalf("Dial() error = %v", err)
+			}
+			defer conn.Close()
+			for _, m := range []string{"<14>1 - - app - - - one", "<14>1 - - app - - - two"} {
+				if network == "tcp" {
+					m = fmt.Sprintf("%d %s", len(m), m)
+				}
+				if _, err := conn.Write([]byte(m)); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+			}
+			records, errs := readAll(t, reader)
+			if len(errs) != 0 || len(records) != 2 || records[1]["message"] != "two" {
+				t.Errorf("got records %v and errors %v, want both messages", records, errs)
+			}
+		})
+	}
+}
+
+func TestSpoolSyslog(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() error = %v", err)
+	}
+	addr := listener.LocalAddr().String()
+	listener.Close()
+	cfg := config.Source{Type: "syslog", Path: "udp://" + addr, Syslog: &config.SyslogConfig{MaxMessages: 2, IdleTimeout: 2 * time.Second}}
+
+	var spooled bytes.Buffer
+	done := make(chan error)
+	go func() {
+		_, err := SpoolSyslog(cfg, &spooled)
+		done <- err
+	}()
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+	// Datagrams sent before the spool listens are lost, so keep sending.
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	for sending := true; sending; {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("SpoolSyslog() error = %v", err)
+			}
+			sending = false
+		case <-ticker.C:
+			conn.Write([]byte("<14>1 - - app - - - hello\n"))
+		}
+	}
+
+	records, errs := readSyslog(t, config.Source{Path: "spool", Syslog: cfg.Syslog}, spooled.String())
+	if len(errs) != 0 || len(records) != 2 || records[0]["message"] != "hello" {
+		t.Errorf("got records %v and errors %v, want the 2 spooled messages", records, errs)
+	}
+}
@@ -0,0 +1,534 @@
+package datareader
+
+import (
+	"archive/zip"
+	"data-comparator/internal/pkg/config"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// xlsxMagic starts every zip archive, and so every xlsx workbook.
+var xlsxMagic = []byte("PK\x03\x04")
+
+// xlsxHeaderScanRows is the number of non-empty rows looked at to detect the
+// header row.
+const xlsxHeaderScanRows = 20
+
+// XLSXReader reads the rows of a sheet of an Excel workbook as records keyed
+// by the header row. Numbers become float64, booleans bool, cells formatted
+// as dates time.Time, and text, including error values such as #N/A, string.
+// Empty cells are nil and empty rows are skipped.
+type XLSXReader struct {
+	path       string
+	file       io.Closer
+	sheetName  string
+	sheet      io.ReadCloser
+	decoder    *xml.Decoder
+	strings    []string
+	dateStyles []bool
+	date1904   bool
+	noHeader   bool
+	columns    []string
+	// pending holds rows read ahead while looking for the header.
+	pending []xlsxRow
+	records int
+}
+
+// NewXLSXReader opens the xlsx workbook of cfg and reads the sheet selected
+// by cfg.XLSX.
+func NewXLSXReader(cfg config.Source) (DataReader, error) {
+	file, err := openSource(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open xlsx file %s: %w", cfg.Path, err)
+	}
+	size, err := file.Size()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to stat xlsx file %s: %w", cfg.Path, err)
+	}
+	return newXLSXReader(file, size, file, cfg)
+}
+
+// newXLSXReader reads the size bytes of the workbook in input, named by
+// cfg.Path, and closes closer when done or on error.
+func newXLSXReader(input io.ReaderAt, size int64, closer io.Closer, cfg config.Source) (*XLSXReader, error) {
+	r, err := openXLSX(input, size, cfg)
+	if err != nil {
+		closer.Close()
+		return nil, err
+	}
+	r.file = closer
+	return r, nil
+}
+
+func openXLSX(input io.ReaderAt, size int64, cfg config.Source) (*XLSXReader, error) {
+	var xcfg config.XLSXConfig
+	if cfg.XLSX != nil {
+		xcfg = *cfg.XLSX
+	}
+	if xcfg.HeaderRow < 0 {
+		return nil, fmt.Errorf("xlsx source %s: header_row must be 1 or more, or 0 to detect it", cfg.Path)
+	}
+	archive, err := zip.NewReader(input, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open xlsx file %s: %w", cfg.Path, err)
+	}
+	entries := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		entries[f.Name] = f
+	}
+	if entries["xl/workbook.xml"] == nil {
+		return nil, fmt.Errorf("%s is not an xlsx workbook: it has no xl/workbook.xml", cfg.Path)
+	}
+
+	var workbook struct {
+		Properties struct {
+			Date1904 bool `xml:"date1904,attr"`
+		} `xml:"workbookPr"`
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			ID   string `xml:"id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodeXLSXPart(entries, "xl/workbook.xml", &workbook); err != nil {
+		return nil, fmt.Errorf("failed to read xlsx file %s: %w", cfg.Path, err)
+	}
+	if len(workbook.Sheets) == 0 {
+		return nil, fmt.Errorf("xlsx file %s has no sheets", cfg.Path)
+	}
+
+	index := -1
+	names := make([]string, len(workbook.Sheets))
+	for i, s := range workbook.Sheets {
+		names[i] = s.Name
+		if s.Name == xcfg.Sheet {
+			index = i
+		}
+	}
+	if xcfg.Sheet == "" {
+		index = 0
+	} else if n, err := strconv.Atoi(xcfg.Sheet); index < 0 && err == nil && n >= 1 && n <= len(names) {
+		index = n - 1
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("xlsx file %s has no sheet %q (sheets: %s)", cfg.Path, xcfg.Sheet, strings.Join(names, ", "))
+	}
+
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeXLSXPart(entries, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, fmt.Errorf("failed to read xlsx file %s: %w", cfg.Path, err)
+	}
+	sheetPath := fmt.Sprintf("xl/worksheets/sheet%d.xml", index+1)
+	for _, rel := range rels.Relationships {
+		if rel.ID == workbook.Sheets[index].ID {
+			if strings.HasPrefix(rel.Target, "/") {
+				sheetPath = strings.TrimPrefix(rel.Target, "/")
+			} else {
+				sheetPath = path.Join("xl", rel.Target)
+			}
+		}
+	}
+	sheetFile := entries[sheetPath]
+	if sheetFile == nil {
+		return nil, fmt.Errorf("xlsx file %s is missing sheet %s (%s)", cfg.Path, names[index], sheetPath)
+	}
+
+	r := &XLSXReader{
+		path:      cfg.Path,
+		sheetName: names[index],
+		date1904:  workbook.Properties.Date1904,
+		noHeader:  xcfg.NoHeader,
+	}
+	if r.strings, err = loadSharedStrings(entries); err != nil {
+		return nil, fmt.Errorf("failed to read xlsx file %s: %w", cfg.Path, err)
+	}
+	if r.dateStyles, err = loadDateStyles(entries); err != nil {
+		return nil, fmt.Errorf("failed to read xlsx file %s: %w", cfg.Path, err)
+	}
+	if r.sheet, err = sheetFile.Open(); err != nil {
+		return nil, fmt.Errorf("failed to read sheet %s of %s: %w", r.sheetName, cfg.Path, err)
+	}
+	r.decoder = xml.NewDecoder(r.sheet)
+	if !r.noHeader {
+		if err := r.readHeader(xcfg.HeaderRow); err != nil {
+			r.sheet.Close()
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// decodeXLSXPart decodes the XML part name of a workbook into v. Missing
+// parts leave v unchanged.
+func decodeXLSXPart(entries map[string]*zip.File, name string, v interface{}) error {
+	f := entries[name]
+	if f == nil {
+		return nil
+	}
+	part, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	defer part.Close()
+	if err := xml.NewDecoder(part).Decode(v); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// xlsxText is rich or plain text of a shared string or inline string cell.
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	b.WriteString(t.Text)
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+// loadSharedStrings reads the table of strings that text cells refer to by index.
+func loadSharedStrings(entries map[string]*zip.File) ([]string, error) {
+	var table struct {
+		Items []xlsxText `xml:"si"`
+	}
+	if err := decodeXLSXPart(entries, "xl/sharedStrings.xml", &table); err != nil {
+		return nil, err
+	}
+	result := make([]string, len(table.Items))
+	for i, item := range table.Items {
+		result[i] = item.String()
+	}
+	return result, nil
+}
+
+// builtinDateFormats are the built-in number formats that show dates or times.
+var builtinDateFormats = map[int]bool{
+	14: true, 15: true, 16: true, 17: true, 18: true, 19: true, 20: true, 21: true, 22: true,
+	27: true, 28: true, 29: true, 30: true, 31: true, 32: true, 33: true, 34: true, 35: true, 36: true,
+	45: true, 46: true, 47: true, 50: true, 51: true, 52: true, 53: true, 54: true, 55: true, 56: true, 57: true, 58: true,
+}
+
+// loadDateStyles reports, per cell style index, whether the style formats
+// numbers as dates.
+func loadDateStyles(entries map[string]*zip.File) ([]bool, error) {
+	var styles struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if err := decodeXLSXPart(entries, "xl/styles.xml", &styles); err != nil {
+		return nil, err
+	}
+	custom := make(map[int]bool, len(styles.NumFmts))
+	for _, f := range styles.NumFmts {
+		custom[f.ID] = isDateFormat(f.Code)
+	}
+	result := make([]bool, len(styles.CellXfs))
+	for i, xf := range styles.CellXfs {
+		result[i] = builtinDateFormats[xf.NumFmtID] || custom[xf.NumFmtID]
+	}
+	return result, nil
+}
+
+// isDateFormat reports whether a custom number format code shows a date or
+// time, going by the date and time letters outside of quoted text, escaped
+// characters and bracketed colors or conditions.
+func isDateFormat(code string) bool {
+	for i := 0; i < len(code); i++ {
+		switch c := code[i]; c {
+		case '"':
+			if end := strings.IndexByte(code[i+1:], '"'); end >= 0 {
+				i += end + 1
+			}
+		case '[':
+			end := strings.IndexByte(code[i:], ']')
+			if end < 0 {
+				return false
+			}
+			// Elapsed time such as [h]:mm is a time, colors and locales are not.
+			if strings.Trim(strings.ToLower(code[i+1:i+end]), "hms") == "" {
+				return true
+			}
+			i += end
+		case '\\', '_', '*':
+			i++
+		case 'y', 'Y', 'm', 'M', 'd', 'D', 'h', 'H', 's', 'S':
+			return true
+		}
+	}
+	return false
+}
+
+// xlsxRow is a row of a sheet as stored.
+type xlsxRow struct {
+	Number int        `xml:"r,attr"`
+	Cells  []xlsxCell `xml:"c"`
+	// values holds the typed cell values by 0-based column.
+	values map[int]interface{}
+	width  int
+	// err is the error of a cell that could not be read, its record not yet set.
+	err *ParseError
+}
+
+type xlsxCell struct {
+	Ref    string   `xml:"r,attr"`
+	Type   string   `xml:"t,attr"`
+	Style  int      `xml:"s,attr"`
+	Value  string   `xml:"v"`
+	Inline xlsxText `xml:"is"`
+}
+
+// nextRow returns the next row holding a value, or io.EOF at the end of the sheet.
+func (r *XLSXReader) nextRow() (xlsxRow, error) {
+	if len(r.pending) > 0 {
+		row := r.pending[0]
+		r.pending = r.pending[1:]
+		return row, nil
+	}
+	last := 0
+	for {
+		tok, err := r.decoder.Token()
+		if err == io.EOF {
+			return xlsxRow{}, io.EOF
+		}
+		if err != nil {
+			return xlsxRow{}, &ParseError{Source: r.path, Record: r.records + 1, Offset: -1, Err: fmt.Errorf("sheet %s: %w", r.sheetName, err)}
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+		var row xlsxRow
+		if err := r.decoder.DecodeElement(&row, &start); err != nil {
+			return xlsxRow{}, &ParseError{Source: r.path, Record: r.records + 1, Line: last + 1, Offset: -1, Err: fmt.Errorf("sheet %s: %w", r.sheetName, err)}
+		}
+		if row.Number == 0 {
+			row.Number = last + 1
+		}
+		last = row.Number
+		r.decodeCells(&row)
+		if row.width > 0 || row.err != nil {
+			return row, nil
+		}
+	}
+}
+
+// decodeCells sets the typed values of the cells of row, or its error.
+func (r *XLSXReader) decodeCells(row *xlsxRow) {
+	row.values = make(map[int]interface{}, len(row.Cells))
+	column := -1
+	for _, c := range row.Cells {
+		column++
+		if c.Ref != "" {
+			column = xlsxColumnIndex(c.Ref)
+		}
+		value, err := r.cellValue(c)
+		if err != nil {
+			row.err = &ParseError{Source: r.path, Line: row.Number, Offset: -1, Snippet: truncate(c.Ref + "=" + c.Value),
+				Recoverable: true, Err: fmt.Errorf("sheet %s cell %s: %w", r.sheetName, c.Ref, err)}
+			return
+		}
+		if value == nil || value == "" {
+			continue
+		}
+		row.values[column] = value
+		row.width = max(row.width, column+1)
+	}
+}
+
+// cellValue returns the typed value of a cell.
+func (r *XLSXReader) cellValue(c xlsxCell) (interface{}, error) {
+	switch c.Type {
+	case "s":
+		i, err := strconv.Atoi(strings.TrimSpace(c.Value))
+		if err != nil || i < 0 || i >= len(r.strings) {
+			return nil, fmt.Errorf("invalid shared string index %q", c.Value)
+		}
+		return r.strings[i], nil
+	case "inlineStr":
+		return c.Inline.String(), nil
+	case "str", "e":
+		return c.Value, nil
+	case "b":
+		return c.Value == "1" || strings.EqualFold(c.Value, "true"), nil
+	case "d":
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02", "15:04:05"} {
+			if t, err := time.Parse(layout, c.Value); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid date %q", c.Value)
+	}
+	if c.Value == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(c.Value), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q", c.Value)
+	}
+	if c.Style >= 0 && c.Style < len(r.dateStyles) && r.dateStyles[c.Style] {
+		return xlsxSerialTime(f, r.date1904), nil
+	}
+	return f, nil
+}
+
+// xlsxSerialTime converts a date serial number, days since the epoch of the
+// workbook with the time of day as fraction, to UTC rounded to milliseconds.
+func xlsxSerialTime(serial float64, date1904 bool) time.Time {
+	// Day 0 is 1899-12-30 so that days after the non-existent 1900-02-29,
+	// which Excel keeps for compatibility with Lotus 1-2-3, line up.
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if date1904 {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return epoch.Add(time.Duration(math.Round(serial*86400e3)) * time.Millisecond)
+}
+
+// xlsxColumnIndex returns the 0-based column of a cell reference such as AB12.
+func xlsxColumnIndex(ref string) int {
+	column := 0
+	for _, c := range strings.ToUpper(ref) {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		column = column*26 + int(c-'A'+1)
+	}
+	return column - 1
+}
+
+// xlsxColumnName returns the letters of a 0-based column, e.g. AB for 27.
+func xlsxColumnName(column int) string {
+	var name []byte
+	for column++; column > 0; column = (column - 1) / 26 {
+		name = append([]byte{byte('A' + (column-1)%26)}, name...)
+	}
+	return string(name)
+}
+
+// readHeader finds the header row, numbered headerRow or else detected, and
+// takes the column names from it. Rows above it are skipped.
+func (r *XLSXReader) readHeader(headerRow int) error {
+	var header xlsxRow
+	if headerRow > 0 {
+		for {
+			row, err := r.nextRow()
+			if err == io.EOF || err == nil && row.Number > headerRow {
+				return fmt.Errorf("header row %d of sheet %s of %s is empty", headerRow, r.sheetName, r.path)
+			}
+			if err != nil {
+				return err
+			}
+			if row.Number == headerRow {
+				header = row
+				break
+			}
+		}
+	} else {
+		var rows []xlsxRow
+		for len(rows) < xlsxHeaderScanRows {
+			row, err := r.nextRow()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			rows = append(rows, row)
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		found := 0
+		for i := len(rows) - 1; i >= 0; i-- {
+			widest := 0
+			for _, below := range rows[i+1:] {
+				widest = max(widest, len(below.values))
+			}
+			if isTextRow(rows[i]) && len(rows[i].values) >= widest {
+				found = i
+			}
+		}
+		header, r.pending = rows[found], rows[found+1:]
+	}
+
+	r.columns = make([]string, header.width)
+	seen := make(map[string]bool, header.width)
+	for column := range r.columns {
+		name := strings.TrimSpace(fmt.Sprint(header.values[column]))
+		if header.values[column] == nil || name == "" || seen[name] {
+			name = xlsxColumnName(column)
+		}
+		seen[name] = true
+		r.columns[column] = name
+	}
+	return nil
+}
+
+// isTextRow reports whether all values of row are text.
+func isTextRow(row xlsxRow) bool {
+	if row.err != nil {
+		return false
+	}
+	for _, value := range row.values {
+		if _, ok := value.(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Read returns the next non-empty row below the header, or io.EOF at the end
+// of the sheet. Cells outside of the header's columns are named by their
+// letters.
+func (r *XLSXReader) Read() (Record, error) {
+	row, err := r.nextRow()
+	if err != nil {
+		return nil, err
+	}
+	r.records++
+	if row.err != nil {
+		row.err.Record = r.records
+		return nil, row.err
+	}
+	rec := make(Record, max(len(r.columns), row.width))
+	for column, name := range r.columns {
+		rec[name] = row.values[column]
+	}
+	for column := len(r.columns); column < row.width; column++ {
+		if value, ok := row.values[column]; ok || r.noHeader {
+			rec[xlsxColumnName(column)] = value
+		}
+	}
+	return rec, nil
+}
+
+// Close closes the sheet and the workbook file.
+func (r *XLSXReader) Close() error {
+	r.sheet.Close()
+	return r.file.Close()
+}
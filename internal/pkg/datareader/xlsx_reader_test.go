@@ -0,0 +1,150 @@
+package datareader
+
+import (
+	"archive/zip"
+	"bytes"
+	"data-comparator/internal/pkg/config"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testWorkbook builds an xlsx workbook of the given sheets, by name, from
+// their sheetData rows.
+func testWorkbook(t *testing.T, sharedStrings []string, sheets ...[2]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	add := func(name, content string) {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var sheetList, rels strings.Builder
+	for i, sheet := range sheets {
+		id := string(rune('1' + i))
+		sheetList.WriteString(`<sheet name="` + sheet[0] + `" sheetId="` + id + `" r:id="rId` + id + `"/>`)
+		// Sheets are stored in reverse, so only the relationships find them.
+		target := "worksheets/sheet" + string(rune('1'+len(sheets)-1-i)) + ".xml"
+		rels.WriteString(`<Relationship Id="rId` + id + `" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="` + target + `"/>`)
+		add("xl/"+target, `<?xml version="1.0" encoding="UTF-8"?><worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`+sheet[1]+`</sheetData></worksheet>`)
+	}
+	add("xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8"?><workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><workbookPr/><sheets>`+sheetList.String()+`</sheets></workbook>`)
+	add("xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8"?><Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`+rels.String()+`</Relationships>`)
+
+	var sst strings.Builder
+	for _, s := range sharedStrings {
+		sst.WriteString("<si><t>" + s + "</t></si>")
+	}
+	// The last shared string is rich text in two runs.
+	sst.WriteString(`<si><r><t>Net </t></r><r><rPr><b/></rPr><t>30</t></r></si>`)
+	add("xl/sharedStrings.xml", `<?xml version="1.0" encoding="UTF-8"?><sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`+sst.String()+`</sst>`)
+	// Style 1 is the built-in date format, style 2 a custom date and time
+	// and style 3 a currency with a quoted literal.
+	add("xl/styles.xml", `<?xml version="1.0" encoding="UTF-8"?><styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`+
+		`<numFmts><numFmt numFmtId="164" formatCode="yyyy\-mm\-dd hh:mm"/><numFmt numFmtId="165" formatCode="[Red]#,##0.00&quot; EUR&quot;"/></numFmts>`+
+		`<cellXfs><xf numFmtId="0"/><xf numFmtId="14"/><xf numFmtId="164"/><xf numFmtId="165"/></cellXfs></styleSheet>`)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+var testInvoiceRows = `<row r="1"><c r="A1" t="s"><v>0</v></c></row>` +
+	`<row r="3"><c r="A3" t="s"><v>1</v></c><c r="B3" t="s"><v>2</v></c><c r="C3" t="s"><v>3</v></c><c r="D3" t="s"><v>4</v></c><c r="E3" t="s"><v>5</v></c></row>` +
+	`<row r="4"><c r="A4"><v>1001</v></c><c r="B4" s="1"><v>45658</v></c><c r="C4" s="3"><v>1250.5</v></c><c r="D4" t="b"><v>1</v></c><c r="E4" t="s"><v>6</v></c></row>` +
+	`<row r="5"><c r="A5" s="1"/></row>` +
+	`<row r="6"><c r="A6"><v>1002</v></c><c r="B6" s="2"><v>45659.75</v></c><c r="C6" t="e"><v>#N/A</v></c><c r="E6" t="inlineStr"><is><t>Due on receipt</t></is></c><c r="G6" t="str"><f>UPPER(E6)</f><v>DUE</v></c></row>`
+
+var testInvoiceStrings = []string{"Q1 invoices", "Invoice", "Date", "Amount", "Paid", "Terms"}
+
+func TestNewXLSXReader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invoices.xlsx")
+	data := testWorkbook(t, testInvoiceStrings, [2]string{"Summary", `<row r="1"><c r="A1"><v>2</v></c></row>`}, [2]string{"Invoices", testInvoiceRows})
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The title above the header is passed over.
+	reader, err := New(config.Source{Type: "xlsx", Path: path, XLSX: &config.XLSXConfig{Sheet: "Invoices"}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	want := []Record{
+		{"Invoice": 1001.0, "Date": time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), "Amount": 1250.5, "Paid": true, "Terms": "Net 30"},
+		{"Invoice": 1002.0, "Date": time.Date(2025, 1, 2, 18, 0, 0, 0, time.UTC), "Amount": "#N/A", "Paid": nil, "Terms": "Due on receipt", "G": "DUE"},
+	}
+	if got := readAllRecords(t, reader); !reflect.DeepEqual(got, want) {
+		t.Errorf("Records got = %v, want %v", got, want)
+	}
+
+	reader, err = New(config.Source{Type: "auto", Path: path, XLSX: &config.XLSXConfig{Sheet: "1", NoHeader: true}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got, want := readAllRecords(t, reader), []Record{{"A": 2.0}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Records of sheet 1 got = %v, want %v", got, want)
+	}
+
+	if _, err := New(config.Source{Type: "xlsx", Path: path, XLSX: &config.XLSXConfig{Sheet: "Credits"}}); err == nil || !strings.Contains(err.Error(), "sheets: Summary, Invoices") {
+		t.Errorf("New() of a missing sheet error got = %v", err)
+	}
+}
+
+func TestNewFromReader_XLSXHeaderRow(t *testing.T) {
+	data := testWorkbook(t, testInvoiceStrings, [2]string{"Invoices", testInvoiceRows})
+	reader, err := NewFromReader(bytes.NewReader(data), config.Source{Type: "xlsx", Path: "invoices.xlsx", XLSX: &config.XLSXConfig{HeaderRow: 1}})
+	if err != nil {
+		t.Fatalf("NewFromReader() error = %v", err)
+	}
+	got := readAllRecords(t, reader)
+	if len(got) != 3 || got[0]["Q1 invoices"] != "Invoice" || got[1]["Q1 invoices"] != 1001.0 || got[1]["B"] == nil {
+		t.Errorf("Records got = %v, want the title as header", got)
+	}
+}
+
+func TestXLSXReader_ParseError(t *testing.T) {
+	rows := `<row r="1"><c r="A1" t="inlineStr"><is><t>id</t></is></c></row>` +
+		`<row r="2"><c r="A2"><v>x1</v></c></row>` +
+		`<row r="3"><c r="A3"><v>3</v></c></row>`
+	data := testWorkbook(t, nil, [2]string{"Sheet1", rows})
+	reader, err := NewFromReader(bytes.NewReader(data), config.Source{Type: "xlsx", Path: "ids.xlsx"})
+	if err != nil {
+		t.Fatalf("NewFromReader() error = %v", err)
+	}
+	defer reader.Close()
+
+	var perr *ParseError
+	if _, err := reader.Read(); !errors.As(err, &perr) || !perr.Recoverable || perr.Record != 1 || perr.Line != 2 {
+		t.Errorf("Read() of an invalid number error got = %v, want a recoverable parse error of record 1 on line 2", err)
+	}
+	if rec, err := reader.Read(); err != nil || rec["id"] != 3.0 {
+		t.Errorf("Read() got = %v, %v, want id 3", rec, err)
+	}
+}
+
+func TestIsDateFormat(t *testing.T) {
+	tests := map[string]bool{
+		"yyyy-mm-dd":           true,
+		"[h]:mm:ss":            true,
+		`[$-409]d\-mmm`:        true,
+		"#,##0.00":             false,
+		`0.00" days"`:          false,
+		"[Red][<0]0.0;General": false,
+		`_(* #,##0_)`:          false,
+	}
+	for code, want := range tests {
+		if got := isDateFormat(code); got != want {
+			t.Errorf("isDateFormat(%s) got = %v, want %v", code, got, want)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package datareader
+
+import (
+	"bufio"
+	"data-comparator/internal/pkg/config"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// xmlTextField is the field holding the text of an element that also has
+// attributes or child elements.
+const xmlTextField = "#text"
+
+// XMLReader streams records from an XML document, one per occurrence of the
+// record element. Attributes and child elements of a record become its
+// fields, nested elements nested records, and elements repeated under the
+// same parent lists. Values are strings; an element with only text is its
+// text, and the text of one with attributes or children is kept under
+// "#text". Namespace prefixes are dropped from names.
+type XMLReader struct {
+	path     string
+	file     io.Closer
+	isFile   bool
+	decoder  *xml.Decoder
+	selector []string
+	stack    []string
+	records  int
+}
+
+// NewXMLReader creates a reader for the XML file of cfg, whose records are
+// selected by cfg.XML.RecordElement. Files may be gzip, zstd or bzip2
+// compressed.
+func NewXMLReader(cfg config.Source) (DataReader, error) {
+	file, err := openSource(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open xml file %s: %w", cfg.Path, err)
+	}
+	input, closer, compressed, err := decompress(file, file, cfg)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newXMLReader(input, closer, cfg)
+	if err != nil {
+		return nil, err
+	}
+	// Offsets into decompressed data cannot be located in the file.
+	r.isFile = !IsObjectPath(cfg.Path) && !IsRemotePath(cfg.Path) && !compressed
+	return r, nil
+}
+
+// newXMLReader reads XML from input, named by cfg.Path, and closes closer
+// when done or on error.
+func newXMLReader(input io.Reader, closer io.Closer, cfg config.Source) (*XMLReader, error) {
+	if cfg.XML == nil || strings.Trim(cfg.XML.RecordElement, "/") == "" {
+		closer.Close()
+		return nil, fmt.Errorf("xml source %s needs xml.record_element", cfg.Path)
+	}
+	decoder := xml.NewDecoder(bufio.NewReader(input))
+	decoder.CharsetReader = xmlCharsetReader
+	return &XMLReader{
+		path:     cfg.Path,
+		file:     closer,
+		decoder:  decoder,
+		selector: strings.Split(strings.Trim(cfg.XML.RecordElement, "/"), "/"),
+	}, nil
+}
+
+// Read returns the next record element, or io.EOF at the end of the document.
+func (r *XMLReader) Read() (Record, error) {
+	for {
+		tok, err := r.decoder.Token()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err != nil {
+			r.records++
+			return nil, r.parseError(err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			r.stack = append(r.stack, t.Name.Local)
+			if !r.atRecord() {
+				continue
+			}
+			value, err := decodeXMLElement(r.decoder, t)
+			r.stack = r.stack[:len(r.stack)-1]
+			r.records++
+			if err != nil {
+				return nil, r.parseError(err)
+			}
+			if rec, ok := value.(map[string]interface{}); ok {
+				return Record(rec), nil
+			}
+			return Record{xmlTextField: value}, nil
+		case xml.EndElement:
+			r.stack = r.stack[:len(r.stack)-1]
+		}
+	}
+}
+
+// atRecord reports whether the open elements end in the record selector.
+func (r *XMLReader) atRecord() bool {
+	if len(r.stack) < len(r.selector) {
+		return false
+	}
+	tail := r.stack[len(r.stack)-len(r.selector):]
+	for i, name := range r.selector {
+		if tail[i] != name {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *XMLReader) parseError(err error) error {
+	offset := r.decoder.InputOffset()
+	perr := &ParseError{Source: r.path, Record: r.records, Offset: offset, Err: err}
+	var syntaxErr *xml.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		perr.Line = syntaxErr.Line
+	}
+	if r.isFile {
+		perr.Line, perr.Snippet = locateOffset(r.path, max(offset-1, 0))
+	}
+	return perr
+}
+
+// Close closes the underlying file or stream.
+func (r *XMLReader) Close() error {
+	return r.file.Close()
+}
+
+// decodeXMLElement reads the content of the element opened by start up to its
+// end, returning its text or, with attributes or children, its fields.
+func decodeXMLElement(decoder *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	fields := make(map[string]interface{})
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+			continue
+		}
+		addXMLField(fields, attr.Name.Local, attr.Value)
+	}
+
+	var text strings.Builder
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			child, err := decodeXMLElement(decoder, t)
+			if err != nil {
+				return nil, err
+			}
+			addXMLField(fields, t.Name.Local, child)
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			content := strings.TrimSpace(text.String())
+			if len(fields) == 0 {
+				return content, nil
+			}
+			if content != "" {
+				fields[xmlTextField] = content
+			}
+			return fields, nil
+		}
+	}
+}
+
+// addXMLField sets a field, collecting repeated names into a list.
+func addXMLField(fields map[string]interface{}, name string, value interface{}) {
+	existing, ok := fields[name]
+	if !ok {
+		fields[name] = value
+		return
+	}
+	if list, ok := existing.([]interface{}); ok {
+		fields[name] = append(list, value)
+		return
+	}
+	fields[name] = []interface{}{existing, value}
+}
+
+// xmlCharsetReader decodes the single-byte encodings legacy XML documents
+// declare besides UTF-8.
+func xmlCharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "latin-1", "us-ascii", "ascii":
+		return &latin1Reader{input: bufio.NewReader(input)}, nil
+	}
+	return nil, fmt.Errorf("unsupported xml encoding %q", charset)
+}
+
+// latin1Reader converts ISO-8859-1 text to UTF-8.
+type latin1Reader struct {
+	input   *bufio.Reader
+	pending []byte
+}
+
+func (r *latin1Reader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(r.pending) > 0 {
+			c := copy(p[n:], r.pending)
+			r.pending = r.pending[c:]
+			n += c
+			continue
+		}
+		b, err := r.input.ReadByte()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, err
+		}
+		if b < utf8.RuneSelf {
+			p[n] = b
+			n++
+			continue
+		}
+		r.pending = utf8.AppendRune(r.pending[:0], rune(b))
+	}
+	return n, nil
+}
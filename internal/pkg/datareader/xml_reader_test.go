@@ -0,0 +1,116 @@
+package datareader
+
+import (
+	"bytes"
+	"data-comparator/internal/pkg/config"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testOrdersXML = `<?xml version="1.0" encoding="UTF-8"?>
+<export xmlns:o="urn:orders">
+  <meta><order>not a record</order></meta>
+  <orders>
+    <o:order id="1" status="shipped">
+      <customer><name>Alice</name><email>alice@example.com</email></customer>
+      <line sku="A-1">2</line>
+      <line sku="B-2">1</line>
+      <note/>
+    </o:order>
+    <o:order id="2">
+      <customer><name>Bob</name></customer>
+      <line sku="C-3">5</line>
+    </o:order>
+  </orders>
+</export>
+`
+
+var testOrdersXMLRecords = []Record{
+	{
+		"id":       "1",
+		"status":   "shipped",
+		"customer": map[string]interface{}{"name": "Alice", "email": "alice@example.com"},
+		"line": []interface{}{
+			map[string]interface{}{"sku": "A-1", "#text": "2"},
+			map[string]interface{}{"sku": "B-2", "#text": "1"},
+		},
+		"note": "",
+	},
+	{
+		"id":       "2",
+		"customer": map[string]interface{}{"name": "Bob"},
+		"line":     map[string]interface{}{"sku": "C-3", "#text": "5"},
+	},
+}
+
+func TestNewXMLReader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.xml")
+	if err := os.WriteFile(path, []byte(testOrdersXML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := New(config.Source{Type: "xml", Path: path, XML: &config.XMLParserConfig{RecordElement: "orders/order"}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got := readAllRecords(t, reader); !reflect.DeepEqual(got, testOrdersXMLRecords) {
+		t.Errorf("Records got = %v, want %v", got, testOrdersXMLRecords)
+	}
+
+	// Without the path, the order inside meta is a record too.
+	reader, err = New(config.Source{Type: "xml", Path: path, XML: &config.XMLParserConfig{RecordElement: "order"}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got := readAllRecords(t, reader); len(got) != 3 || got[0]["#text"] != "not a record" {
+		t.Errorf("Records of order got = %v, want the one in meta first", got)
+	}
+
+	if _, err := New(config.Source{Type: "xml", Path: path}); err == nil || !strings.Contains(err.Error(), "xml.record_element") {
+		t.Errorf("New() without a record element error got = %v", err)
+	}
+}
+
+func TestNew_AutoXML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feed.xml.gz")
+	data := `<?xml version="1.0" encoding="ISO-8859-1"?>` + "\n<feed><item code=\"1\">caf\xe9</item><item code=\"2\">th\xe9</item></feed>\n"
+	if err := os.WriteFile(path, compressTestData(t, "gzip", []byte(data)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sniffed, err := Sniff(path)
+	if err != nil || sniffed.Type != "xml" || sniffed.XML == nil || sniffed.XML.RecordElement != "item" {
+		t.Errorf("Sniff() got = %+v, %v, want xml with record element item", sniffed, err)
+	}
+	reader, err := New(config.Source{Type: "auto", Path: path})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	want := []Record{{"code": "1", "#text": "café"}, {"code": "2", "#text": "thé"}}
+	if got := readAllRecords(t, reader); !reflect.DeepEqual(got, want) {
+		t.Errorf("Records got = %v, want %v", got, want)
+	}
+}
+
+func TestXMLReader_ParseError(t *testing.T) {
+	input := "<orders>\n<order id=\"1\"><name>a</name></order>\n<order id=\"2\"><name>b</order>\n</orders>\n"
+	reader, err := NewFromReader(bytes.NewReader([]byte(input)), config.Source{
+		Type: "xml", Path: "orders.xml", XML: &config.XMLParserConfig{RecordElement: "order"},
+	})
+	if err != nil {
+		t.Fatalf("NewFromReader() error = %v", err)
+	}
+	defer reader.Close()
+
+	if rec, err := reader.Read(); err != nil || rec["id"] != "1" {
+		t.Errorf("Read() got = %v, %v, want order 1", rec, err)
+	}
+	var perr *ParseError
+	if _, err := reader.Read(); !errors.As(err, &perr) || perr.Record != 2 || perr.Line != 3 || perr.Recoverable {
+		t.Errorf("Read() of a mismatched element error got = %v, want an unrecoverable parse error of record 2 on line 3", err)
+	}
+}
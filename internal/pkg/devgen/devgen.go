@@ -0,0 +1,283 @@
+// Package devgen generates synthetic test cases from code with fixed seeds,
+// so that every run writes byte-identical fixtures. Each case is a pair of
+// sources of one format whose differences are known, with a config for each.
+package devgen
+
+import (
+	"bytes"
+	"data-comparator/internal/pkg/config"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultDir is the directory test cases are written to by default,
+// relative to the repository root.
+const DefaultDir = "testdata/generated"
+
+// Records is the number of records of source1 of every case.
+const Records = 20
+
+// Record is a generated record, with the values its format holds.
+type Record map[string]interface{}
+
+// Case is a generated test case. Source2 is source1 with the field Changed
+// of every fifth record changed, its last record removed and a record with a
+// new key added.
+type Case struct {
+	Name string
+	// Type is the source type of both sources.
+	Type string
+	// Key is the key field of the records.
+	Key string
+	// Changed is the field changed in the records that differ.
+	Changed string
+	// Seed makes the records of the case the same on every run.
+	Seed uint64
+
+	ext      string
+	generate func(r *rand.Rand, i int) Record
+	encode   func(records []Record) ([]byte, error)
+	// source configures the format of a source beyond its type and path.
+	source func(dir string, src *config.Source)
+	// files writes files the sources need besides their data.
+	files func(dir string) error
+}
+
+// Expected is the outcome of comparing the sources of a case.
+type Expected struct {
+	MatchingKeys, IdenticalRows, KeysOnlyInSource1, KeysOnlyInSource2 int
+}
+
+// Expected returns the outcome of comparing the sources of c.
+func (c Case) Expected() Expected {
+	changed := 0
+	for i := 0; i < Records-1; i++ {
+		if isChanged(i) {
+			changed++
+		}
+	}
+	return Expected{MatchingKeys: Records - 1, IdenticalRows: Records - 1 - changed, KeysOnlyInSource1: 1, KeysOnlyInSource2: 1}
+}
+
+func isChanged(i int) bool {
+	return i%5 == 2
+}
+
+// Sources returns the records of source1 and source2 of c.
+func (c Case) Sources() ([]Record, []Record) {
+	r := rand.New(rand.NewPCG(c.Seed, 0))
+	source1 := make([]Record, Records)
+	for i := range source1 {
+		source1[i] = c.generate(r, i)
+	}
+	source2 := make([]Record, 0, Records)
+	for i, rec := range source1[:Records-1] {
+		if isChanged(i) {
+			rec = Record{c.Key: source1[i][c.Key], c.Changed: changedValue(source1[i][c.Changed])}
+			for field, value := range source1[i] {
+				if _, ok := rec[field]; !ok {
+					rec[field] = value
+				}
+			}
+		}
+		source2 = append(source2, rec)
+	}
+	source2 = append(source2, c.generate(r, Records))
+	return source1, source2
+}
+
+// changedValue returns a value of the type of v that differs from it.
+func changedValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int:
+		return v + 1
+	case int64:
+		return v + 1
+	case float64:
+		return v + 0.5
+	case bool:
+		return !v
+	case string:
+		return v + "-changed"
+	default:
+		return fmt.Sprint(v) + "-changed"
+	}
+}
+
+// Write writes the sources and configs of c to dir/c.Name. Configs name
+// their files by paths under dir, so dir is relative to where comparisons
+// run, usually the repository root.
+func (c Case) Write(dir string) error {
+	dir = filepath.Join(dir, c.Name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	if c.files != nil {
+		if err := c.files(dir); err != nil {
+			return err
+		}
+	}
+	source1, source2 := c.Sources()
+	for i, records := range [][]Record{source1, source2} {
+		data, err := c.encode(records)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s source%d: %w", c.Name, i+1, err)
+		}
+		path := filepath.Join(dir, fmt.Sprintf("source%d%s", i+1, c.ext))
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+
+		cfg := config.Config{Source: config.Source{Type: c.Type, Path: path, Key: c.Key}}
+		if c.source != nil {
+			c.source(dir, &cfg.Source)
+		}
+		data, err = yaml.Marshal(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s config%d: %w", c.Name, i+1, err)
+		}
+		path = filepath.Join(dir, fmt.Sprintf("config%d.yaml", i+1))
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// WriteAll writes every case of Cases to dir.
+func WriteAll(dir string) error {
+	for _, c := range Cases {
+		if err := c.Write(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Cases lists the generated test cases.
+var Cases = []Case{
+	{
+		Name: "simple_csv", Type: "csv", Key: "user_id", Changed: "age", Seed: 1,
+		ext:      ".csv",
+		generate: generateUser,
+		encode:   encodeCSV("user_id", "email", "age", "city", "plan_type", "last_login"),
+	},
+	{
+		Name: "nested_json", Type: "json", Key: "event_id", Changed: "payload_size", Seed: 2,
+		ext:      ".jsonl",
+		generate: generateEvent,
+		encode:   encodeJSONLines,
+	},
+	{
+		Name: "csv_with_json", Type: "csv", Key: "record_id", Changed: "event_name", Seed: 3,
+		ext:      ".csv",
+		generate: generateDetailed,
+		encode:   encodeCSV("record_id", "event_name", "details"),
+		source: func(dir string, src *config.Source) {
+			src.ParserConfig = &config.ParserConfig{JSONInString: true}
+		},
+	},
+	protobufCase,
+}
+
+var (
+	cities  = []string{"New York", "Los Angeles", "Chicago", "Berlin", "Tokyo"}
+	plans   = []string{"basic", "premium", "enterprise"}
+	regions = []string{"us-east-1", "eu-west-1", "ap-south-1"}
+	teams   = []string{"backend", "frontend", "data"}
+	events  = []string{"user_signup", "payment_success", "password_reset"}
+	// epoch is the earliest generated time.
+	epoch = time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func pick(r *rand.Rand, values []string) string {
+	return values[r.IntN(len(values))]
+}
+
+func generateUser(r *rand.Rand, i int) Record {
+	return Record{
+		"user_id":    i + 1,
+		"email":      fmt.Sprintf("user%d@example.com", i+1),
+		"age":        18 + r.IntN(60),
+		"city":       pick(r, cities),
+		"plan_type":  pick(r, plans),
+		"last_login": epoch.Add(time.Duration(r.IntN(30*24*3600)) * time.Second).Format(time.RFC3339),
+	}
+}
+
+func generateEvent(r *rand.Rand, i int) Record {
+	var tags []interface{}
+	for n := r.IntN(3); n > 0; n-- {
+		tags = append(tags, map[string]interface{}{"key": "team", "value": pick(r, teams)})
+	}
+	features := []interface{}{}
+	for n := r.IntN(3); n > 0; n-- {
+		features = append(features, fmt.Sprintf("f%d", 1+r.IntN(9)))
+	}
+	return Record{
+		"event_id":     fmt.Sprintf("evt-%03d", i+1),
+		"timestamp":    epoch.Add(time.Duration(i)*5*time.Minute + time.Duration(r.IntN(1000))*time.Millisecond).Format("2006-01-02T15:04:05.000Z07:00"),
+		"customer":     map[string]interface{}{"id": fmt.Sprintf("cust-%c", 'a'+rune(r.IntN(26))), "region": pick(r, regions)},
+		"tags":         tags,
+		"metrics":      map[string]interface{}{"latency_ms": 10 + r.IntN(500), "errors": r.IntN(3)},
+		"payload_size": 256 << r.IntN(4),
+		"features":     features,
+	}
+}
+
+func generateDetailed(r *rand.Rand, i int) Record {
+	// The payload is JSON in a string in the JSON of the details column.
+	payload, _ := json.Marshal(map[string]interface{}{
+		"source":    pick(r, []string{"web", "api", "mobile"}),
+		"timestamp": epoch.Add(time.Duration(i) * time.Minute).Format(time.RFC3339),
+	})
+	details, _ := json.Marshal(map[string]interface{}{
+		"user_id": fmt.Sprintf("usr-%03d", r.IntN(1000)),
+		"amount":  r.IntN(500),
+		"payload": string(payload),
+	})
+	return Record{
+		"record_id":  fmt.Sprintf("rec-%02d", i+1),
+		"event_name": pick(r, events),
+		"details":    string(details),
+	}
+}
+
+// encodeCSV returns an encoder writing records as CSV with a header of
+// columns.
+func encodeCSV(columns ...string) func(records []Record) ([]byte, error) {
+	return func(records []Record) ([]byte, error) {
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write(columns)
+		row := make([]string, len(columns))
+		for _, rec := range records {
+			for i, column := range columns {
+				row[i] = fmt.Sprint(rec[column])
+			}
+			w.Write(row)
+		}
+		w.Flush()
+		return buf.Bytes(), w.Error()
+	}
+}
+
+func encodeJSONLines(records []Record) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, rec := range records {
+		line, err := json.Marshal(rec)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
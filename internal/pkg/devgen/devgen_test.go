@@ -0,0 +1,85 @@
+package devgen
+
+import (
+	"bytes"
+	"data-comparator/internal/pkg/comparator"
+	"data-comparator/internal/pkg/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCases_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	for _, c := range Cases {
+		t.Run(c.Name, func(t *testing.T) {
+			if err := c.Write(dir); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			config1, err := config.Load(filepath.Join(dir, c.Name, "config1.yaml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			config2, err := config.Load(filepath.Join(dir, c.Name, "config2.yaml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := comparator.CompareConfigs(config1, config2, "", "")
+			if err != nil {
+				t.Fatalf("CompareConfigs() error = %v", err)
+			}
+			s := result.Summary
+			got := Expected{MatchingKeys: s.MatchingKeys, IdenticalRows: s.IdenticalRows, KeysOnlyInSource1: s.KeysOnlyInSource1, KeysOnlyInSource2: s.KeysOnlyInSource2}
+			if want := c.Expected(); got != want || s.Source1Rows != Records {
+				t.Errorf("Summary got = %+v, want %+v and %d source1 rows", s, want, Records)
+			}
+			for key, diffs := range result.ValueDiffs {
+				if len(diffs) != 1 || diffs[0].Field != c.Changed {
+					t.Errorf("diffs of %s got = %v, want one of %s", key, diffs, c.Changed)
+				}
+			}
+		})
+	}
+}
+
+// TestCases_UpToDate checks that testdata/generated holds what the cases
+// generate; run "data-comparator devgen testcases" from the repository root
+// to update it.
+func TestCases_UpToDate(t *testing.T) {
+	root, err := filepath.Abs("../../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := WriteAll(DefaultDir); err != nil {
+		t.Fatalf("WriteAll() error = %v", err)
+	}
+
+	err = filepath.WalkDir(DefaultDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		want, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		got, err := os.ReadFile(filepath.Join(root, path))
+		if err != nil {
+			t.Errorf("%s is missing: %v", path, err)
+		} else if !bytes.Equal(got, want) {
+			t.Errorf("%s is out of date", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
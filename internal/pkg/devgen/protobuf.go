@@ -0,0 +1,111 @@
+package devgen
+
+import (
+	"data-comparator/internal/pkg/config"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// orderDescriptor describes
+//
+//	message Order {
+//	  int64 id = 1;
+//	  string customer = 2;
+//	  double amount = 3;
+//	  repeated string items = 4;
+//	}
+var orderDescriptor = &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{{
+	Name:    proto.String("devgen/order.proto"),
+	Package: proto.String("devgen.v1"),
+	Syntax:  proto.String("proto3"),
+	MessageType: []*descriptorpb.DescriptorProto{{
+		Name: proto.String("Order"),
+		Field: []*descriptorpb.FieldDescriptorProto{
+			orderField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL),
+			orderField("customer", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL),
+			orderField("amount", 3, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL),
+			orderField("items", 4, descriptorpb.FieldDescriptorProto_TYPE_STRING, descriptorpb.FieldDescriptorProto_LABEL_REPEATED),
+		},
+	}},
+}}}
+
+func orderField(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, label descriptorpb.FieldDescriptorProto_Label) *descriptorpb.FieldDescriptorProto {
+	return &descriptorpb.FieldDescriptorProto{Name: proto.String(name), JsonName: proto.String(name), Number: proto.Int32(number), Type: typ.Enum(), Label: label.Enum()}
+}
+
+var protobufCase = Case{
+	Name: "protobuf", Type: "protobuf", Key: "id", Changed: "amount", Seed: 4,
+	ext:      ".pb",
+	generate: generateOrder,
+	encode:   encodeOrders,
+	source: func(dir string, src *config.Source) {
+		src.Protobuf = &config.ProtobufParserConfig{Descriptor: filepath.Join(dir, "order.desc"), MessageType: "devgen.v1.Order"}
+	},
+	files: func(dir string) error {
+		data, err := proto.MarshalOptions{Deterministic: true}.Marshal(orderDescriptor)
+		if err != nil {
+			return fmt.Errorf("failed to marshal the order descriptor: %w", err)
+		}
+		path := filepath.Join(dir, "order.desc")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		return nil
+	},
+}
+
+func generateOrder(r *rand.Rand, i int) Record {
+	items := []interface{}{}
+	for n := 1 + r.IntN(3); n > 0; n-- {
+		items = append(items, fmt.Sprintf("sku-%04d", r.IntN(10000)))
+	}
+	return Record{
+		"id":       int64(1000 + i),
+		"customer": fmt.Sprintf("cust-%c", 'a'+rune(r.IntN(26))),
+		"amount":   float64(r.IntN(100000)) / 100,
+		"items":    items,
+	}
+}
+
+// encodeOrders writes records as Order messages, each preceded by its
+// varint length.
+func encodeOrders(records []Record) ([]byte, error) {
+	files, err := protodesc.NewFiles(orderDescriptor)
+	if err != nil {
+		return nil, err
+	}
+	desc, err := files.FindDescriptorByName("devgen.v1.Order")
+	if err != nil {
+		return nil, err
+	}
+	order := desc.(protoreflect.MessageDescriptor)
+	fields := order.Fields()
+
+	var out []byte
+	for _, rec := range records {
+		msg := dynamicpb.NewMessage(order)
+		msg.Set(fields.ByName("id"), protoreflect.ValueOfInt64(rec["id"].(int64)))
+		msg.Set(fields.ByName("customer"), protoreflect.ValueOfString(rec["customer"].(string)))
+		msg.Set(fields.ByName("amount"), protoreflect.ValueOfFloat64(rec["amount"].(float64)))
+		items := msg.Mutable(fields.ByName("items")).List()
+		for _, item := range rec["items"].([]interface{}) {
+			items.Append(protoreflect.ValueOfString(item.(string)))
+		}
+		data, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+		if err != nil {
+			return nil, err
+		}
+		out = protowire.AppendVarint(out, uint64(len(data)))
+		out = append(out, data...)
+	}
+	return out, nil
+}
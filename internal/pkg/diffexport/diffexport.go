@@ -0,0 +1,135 @@
+// Package diffexport writes the value diffs of a comparison to a CSV or
+// Parquet file, a row per field diff, so they can be loaded into a database
+// or spreadsheet rather than read out of the report.
+package diffexport
+
+import (
+	"bufio"
+	"data-comparator/internal/pkg/comparator"
+	"data-comparator/internal/pkg/datareader"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Columns are the columns of an export, in order.
+var Columns = []string{"key", "field", "source1_value", "source2_value", "category"}
+
+// Formats lists the export formats, chosen by the extension of the path.
+var Formats = []string{"csv", "parquet"}
+
+// Writer writes field diffs to a file as they are found.
+type Writer struct {
+	path     string
+	file     *os.File
+	buffered *bufio.Writer
+	csv      *csv.Writer
+	parquet  *parquetWriter
+	// err is the first error writing, which Close returns.
+	err error
+}
+
+// Create creates the export file at path, a CSV file if it ends in .csv
+// and a Parquet file if in .parquet.
+func Create(path string) (*Writer, error) {
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	if format != "csv" && format != "parquet" {
+		return nil, fmt.Errorf("unsupported diff export %s, use a .csv or .parquet file", path)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create diff export: %w", err)
+	}
+	w := &Writer{path: path, file: file, buffered: bufio.NewWriter(file)}
+	if format == "csv" {
+		w.csv = csv.NewWriter(w.buffered)
+		w.err = w.csv.Write(Columns)
+	} else {
+		w.parquet, w.err = newParquetWriter(w.buffered)
+	}
+	if w.err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write diff export %s: %w", path, w.err)
+	}
+	return w, nil
+}
+
+// Write writes a row per diff of key. After an error, rows are dropped and
+// Close returns the error.
+func (w *Writer) Write(key string, diffs []comparator.FieldDiff) error {
+	if w.err != nil {
+		return w.err
+	}
+	for _, diff := range diffs {
+		row := [5]Value{{Text: key, Valid: true}, {Text: diff.Field, Valid: true},
+			NewValue(diff.Source1Value), NewValue(diff.Source2Value), {Text: diff.Category, Valid: diff.Category != ""}}
+		if w.csv != nil {
+			w.err = w.csv.Write([]string{row[0].Text, row[1].Text, row[2].Text, row[3].Text, row[4].Text})
+		} else {
+			w.err = w.parquet.write(row)
+		}
+		if w.err != nil {
+			w.err = fmt.Errorf("failed to write diff export %s: %w", w.path, w.err)
+			return w.err
+		}
+	}
+	return nil
+}
+
+// Close finishes the file and closes it.
+func (w *Writer) Close() error {
+	if w.err == nil {
+		if w.csv != nil {
+			w.csv.Flush()
+			w.err = w.csv.Error()
+		} else {
+			w.err = w.parquet.close()
+		}
+		if w.err == nil {
+			w.err = w.buffered.Flush()
+		}
+		if w.err != nil {
+			w.err = fmt.Errorf("failed to write diff export %s: %w", w.path, w.err)
+		}
+	}
+	if err := w.file.Close(); err != nil && w.err == nil {
+		w.err = fmt.Errorf("failed to write diff export %s: %w", w.path, err)
+	}
+	return w.err
+}
+
+// Value is a value of an export as text, which is null if not Valid.
+type Value struct {
+	Text  string
+	Valid bool
+}
+
+// NewValue renders a value of a field diff: strings as they are, times in
+// RFC 3339, binary values in base64, records and lists as JSON and other
+// values as printed. Nil is null, which CSV writes as an empty cell, as it
+// does empty strings; their diffs have the null_vs_empty category.
+func NewValue(v interface{}) Value {
+	switch v := v.(type) {
+	case nil:
+		return Value{}
+	case string:
+		return Value{Text: v, Valid: true}
+	case time.Time:
+		return Value{Text: v.Format(time.RFC3339Nano), Valid: true}
+	case []byte:
+		return Value{Text: base64.StdEncoding.EncodeToString(v), Valid: true}
+	case map[string]interface{}, datareader.Record, []interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return Value{Text: fmt.Sprint(v), Valid: true}
+		}
+		return Value{Text: string(data), Valid: true}
+	default:
+		return Value{Text: fmt.Sprint(v), Valid: true}
+	}
+}
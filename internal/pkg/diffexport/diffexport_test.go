@@ -0,0 +1,98 @@
+package diffexport
+
+import (
+	"data-comparator/internal/pkg/comparator"
+	"data-comparator/internal/pkg/config"
+	"data-comparator/internal/pkg/datareader"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testDiffs = []comparator.FieldDiff{
+	{Field: "name", Source1Value: "Alice", Source2Value: "alice", Category: "case_change"},
+	{Field: "address.city", Source1Value: nil, Source2Value: "", Category: "null_vs_empty"},
+	{Field: "tags", Source1Value: []interface{}{"a", "b"}, Source2Value: map[string]interface{}{"x": 1.5}},
+	{Field: "seen", Source1Value: time.Date(2025, 9, 10, 12, 0, 0, 0, time.UTC), Source2Value: []byte("hi")},
+}
+
+func writeExport(t *testing.T, path string, keys int) {
+	t.Helper()
+	w, err := Create(path)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	for i := 0; i < keys; i++ {
+		if err := w.Write(fmt.Sprint(i+1), testDiffs); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+}
+
+func TestCreate_CSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "diffs.csv")
+	writeExport(t, path, 1)
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "key,field,source1_value,source2_value,category\n" +
+		"1,name,Alice,alice,case_change\n" +
+		"1,address.city,,,null_vs_empty\n" +
+		"1,tags,\"[\"\"a\"\",\"\"b\"\"]\",\"{\"\"x\"\":1.5}\",\n" +
+		"1,seen,2025-09-10T12:00:00Z,aGk=,\n"
+	if string(got) != want {
+		t.Errorf("CSV export got = %q, want %q", got, want)
+	}
+}
+
+func TestCreate_Parquet(t *testing.T) {
+	defer func(rows int) { rowGroupRows = rows }(rowGroupRows)
+	// 20 row groups take the long form of Thrift list headers.
+	rowGroupRows = 6
+	path := filepath.Join(t.TempDir(), "diffs.parquet")
+	writeExport(t, path, 30)
+
+	reader, err := datareader.New(config.Source{Type: "parquet", Path: path})
+	if err != nil {
+		t.Fatalf("datareader.New() error = %v", err)
+	}
+	defer reader.Close()
+	var records []datareader.Record
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		records = append(records, rec)
+	}
+	if len(records) != 30*len(testDiffs) {
+		t.Fatalf("records got = %d, want %d", len(records), 30*len(testDiffs))
+	}
+	want := []datareader.Record{
+		{"key": "1", "field": "name", "source1_value": "Alice", "source2_value": "alice", "category": "case_change"},
+		{"key": "1", "field": "address.city", "source1_value": nil, "source2_value": "", "category": "null_vs_empty"},
+	}
+	if !reflect.DeepEqual(records[:2], want) {
+		t.Errorf("records got = %v, want %v", records[:2], want)
+	}
+	if last := records[len(records)-1]; last["key"] != "30" || last["source2_value"] != "aGk=" || last["category"] != nil {
+		t.Errorf("last record got = %v, want the seen diff of key 30", last)
+	}
+}
+
+func TestCreate_UnsupportedFormat(t *testing.T) {
+	if _, err := Create(filepath.Join(t.TempDir(), "diffs.yaml")); err == nil {
+		t.Error("Create() of a .yaml file expected an error, got nil")
+	}
+}
@@ -0,0 +1,248 @@
+package diffexport
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// This file writes exports as Parquet files of UTF-8 string columns, with
+// uncompressed, PLAIN encoded pages and the Thrift compact protocol
+// structures describing them.
+
+// rowGroupRows is the number of rows buffered per row group, a variable so
+// tests can write many row groups.
+var rowGroupRows = 1 << 16
+
+var parquetMagic = []byte("PAR1")
+
+// Parquet format values used by the writer.
+const (
+	parquetByteArray  = 6
+	parquetRequired   = 0
+	parquetOptional   = 1
+	convertedUTF8     = 0
+	encodingPlain     = 0
+	encodingRLE       = 3
+	pageData          = 0
+	codecUncompressed = 0
+)
+
+// Thrift compact protocol types.
+const (
+	thriftStop   = 0
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// parquetWriter writes rows of Columns in row groups of rowGroupRows.
+type parquetWriter struct {
+	out       io.Writer
+	offset    int64
+	rows      [][5]Value
+	total     int64
+	rowGroups []*thriftWriter
+}
+
+func newParquetWriter(out io.Writer) (*parquetWriter, error) {
+	w := &parquetWriter{out: out}
+	return w, w.emit(parquetMagic)
+}
+
+func (w *parquetWriter) emit(data []byte) error {
+	n, err := w.out.Write(data)
+	w.offset += int64(n)
+	return err
+}
+
+// optional reports whether column i may be null; key and field may not.
+func optional(i int) bool {
+	return i >= 2
+}
+
+func (w *parquetWriter) write(row [5]Value) error {
+	w.rows = append(w.rows, row)
+	if len(w.rows) >= rowGroupRows {
+		return w.flush()
+	}
+	return nil
+}
+
+// flush writes the buffered rows as a row group, a data page per column.
+func (w *parquetWriter) flush() error {
+	if len(w.rows) == 0 {
+		return nil
+	}
+	var chunks []*thriftWriter
+	var groupSize int64
+	for i, name := range Columns {
+		var page []byte
+		if optional(i) {
+			levels := make([]byte, 0, len(w.rows)/8+8)
+			levels = binary.AppendUvarint(levels, uint64((len(w.rows)+7)/8)<<1|1)
+			packed := make([]byte, (len(w.rows)+7)/8)
+			for r, row := range w.rows {
+				if row[i].Valid {
+					packed[r/8] |= 1 << (r % 8)
+				}
+			}
+			levels = append(levels, packed...)
+			page = binary.LittleEndian.AppendUint32(page, uint32(len(levels)))
+			page = append(page, levels...)
+		}
+		for _, row := range w.rows {
+			if row[i].Valid {
+				page = binary.LittleEndian.AppendUint32(page, uint32(len(row[i].Text)))
+				page = append(page, row[i].Text...)
+			}
+		}
+
+		header := &thriftWriter{}
+		header.i32(1, pageData)
+		header.i32(2, int32(len(page)))
+		header.i32(3, int32(len(page)))
+		dataHeader := &thriftWriter{}
+		dataHeader.i32(1, int32(len(w.rows)))
+		dataHeader.i32(2, encodingPlain)
+		dataHeader.i32(3, encodingRLE)
+		dataHeader.i32(4, encodingRLE)
+		header.strct(5, dataHeader)
+
+		start := w.offset
+		if err := w.emit(header.end()); err != nil {
+			return err
+		}
+		if err := w.emit(page); err != nil {
+			return err
+		}
+		size := w.offset - start
+		groupSize += size
+
+		meta := &thriftWriter{}
+		meta.i32(1, parquetByteArray)
+		meta.i32s(2, []int32{encodingPlain, encodingRLE})
+		meta.strings(3, []string{name})
+		meta.i32(4, codecUncompressed)
+		meta.i64(5, int64(len(w.rows)))
+		meta.i64(6, size)
+		meta.i64(7, size)
+		meta.i64(9, start)
+		chunk := &thriftWriter{}
+		chunk.i64(2, start)
+		chunk.strct(3, meta)
+		chunks = append(chunks, chunk)
+	}
+	group := &thriftWriter{}
+	group.structs(1, chunks)
+	group.i64(2, groupSize)
+	group.i64(3, int64(len(w.rows)))
+	w.rowGroups = append(w.rowGroups, group)
+	w.total += int64(len(w.rows))
+	w.rows = w.rows[:0]
+	return nil
+}
+
+// close writes the last row group and the footer.
+func (w *parquetWriter) close() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+	root := &thriftWriter{}
+	root.str(4, "schema")
+	root.i32(5, int32(len(Columns)))
+	schema := []*thriftWriter{root}
+	for i, name := range Columns {
+		column := &thriftWriter{}
+		column.i32(1, parquetByteArray)
+		if optional(i) {
+			column.i32(3, parquetOptional)
+		} else {
+			column.i32(3, parquetRequired)
+		}
+		column.str(4, name)
+		column.i32(6, convertedUTF8)
+		schema = append(schema, column)
+	}
+	footer := &thriftWriter{}
+	footer.i32(1, 1)
+	footer.structs(2, schema)
+	footer.i64(3, w.total)
+	footer.structs(4, w.rowGroups)
+	footer.str(6, "data-comparator")
+	data := footer.end()
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(data)))
+	return w.emit(append(data, parquetMagic...))
+}
+
+// thriftWriter encodes a Thrift struct in the compact protocol, its fields
+// added in ascending id order.
+type thriftWriter struct {
+	buf  []byte
+	last int16
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	t.buf = append(t.buf, byte(id-t.last)<<4|typ)
+	t.last = id
+}
+
+func (t *thriftWriter) varint(v int64) {
+	t.buf = binary.AppendUvarint(t.buf, uint64(v<<1^v>>63))
+}
+
+func (t *thriftWriter) listHeader(id int16, n int, typ byte) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.buf = append(t.buf, byte(n)<<4|typ)
+		return
+	}
+	t.buf = binary.AppendUvarint(append(t.buf, 0xf0|typ), uint64(n))
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(v)
+}
+
+func (t *thriftWriter) str(id int16, v string) {
+	t.field(id, thriftBinary)
+	t.buf = append(binary.AppendUvarint(t.buf, uint64(len(v))), v...)
+}
+
+func (t *thriftWriter) strct(id int16, v *thriftWriter) {
+	t.field(id, thriftStruct)
+	t.buf = append(t.buf, v.end()...)
+}
+
+func (t *thriftWriter) i32s(id int16, v []int32) {
+	t.listHeader(id, len(v), thriftI32)
+	for _, e := range v {
+		t.varint(int64(e))
+	}
+}
+
+func (t *thriftWriter) strings(id int16, v []string) {
+	t.listHeader(id, len(v), thriftBinary)
+	for _, e := range v {
+		t.buf = append(binary.AppendUvarint(t.buf, uint64(len(e))), e...)
+	}
+}
+
+func (t *thriftWriter) structs(id int16, v []*thriftWriter) {
+	t.listHeader(id, len(v), thriftStruct)
+	for _, e := range v {
+		t.buf = append(t.buf, e.end()...)
+	}
+}
+
+// end returns the encoded struct.
+func (t *thriftWriter) end() []byte {
+	return append(t.buf[:len(t.buf):len(t.buf)], thriftStop)
+}
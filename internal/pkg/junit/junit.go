@@ -0,0 +1,219 @@
+// Package junit renders a comparison as a JUnit XML report, with a test case
+// per field and per check, so CI servers such as Jenkins and GitLab show the
+// failing ones natively.
+package junit
+
+import (
+	"data-comparator/internal/pkg/comparator"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Name names the report and prefixes the class names of its test cases.
+const Name = "data-comparator"
+
+// maxListedKeys is the number of keys listed in the output of a failing
+// check of keys only in a source.
+const maxListedKeys = 20
+
+// TestSuites is the root element of a JUnit XML report.
+type TestSuites struct {
+	XMLName  xml.Name    `xml:"testsuites"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     string      `xml:"time,attr"`
+	Suites   []TestSuite `xml:"testsuite"`
+}
+
+// TestSuite groups the test cases of the fields or of the checks.
+type TestSuite struct {
+	Name      string     `xml:"name,attr"`
+	Tests     int        `xml:"tests,attr"`
+	Failures  int        `xml:"failures,attr"`
+	Time      string     `xml:"time,attr"`
+	Timestamp string     `xml:"timestamp,attr,omitempty"`
+	Cases     []TestCase `xml:"testcase"`
+}
+
+// TestCase is a field or check, which failed if Failure is set.
+type TestCase struct {
+	Name      string   `xml:"name,attr"`
+	ClassName string   `xml:"classname,attr"`
+	Failure   *Failure `xml:"failure,omitempty"`
+}
+
+// Failure describes why a test case failed.
+type Failure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Details string `xml:",cdata"`
+}
+
+// New builds the report of result. The fields suite has a case per field
+// that differed, failing if its mismatch rate exceeds
+// thresholds.max_field_mismatch_rate, or at all without that threshold,
+// and a passing case per other field in fields. The checks suite has cases
+// for keys only in either source, the configured thresholds, schema
+// violations and sources not read to their end.
+func New(result *comparator.Result, fields []string) TestSuites {
+	// CI servers read times as decimal seconds, never in exponent form.
+	time := strconv.FormatFloat(result.FinishedAt.Sub(result.StartedAt).Seconds(), 'f', 3, 64)
+	timestamp := ""
+	if !result.StartedAt.IsZero() {
+		timestamp = result.StartedAt.UTC().Format("2006-01-02T15:04:05")
+	}
+	report := TestSuites{Name: Name, Time: time}
+	for _, suite := range []TestSuite{
+		{Name: "fields", Cases: fieldCases(result, fields)},
+		{Name: "checks", Cases: checkCases(result)},
+	} {
+		suite.Time, suite.Timestamp = time, timestamp
+		suite.Tests = len(suite.Cases)
+		for _, c := range suite.Cases {
+			if c.Failure != nil {
+				suite.Failures++
+			}
+		}
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Suites = append(report.Suites, suite)
+	}
+	return report
+}
+
+func fieldCases(result *comparator.Result, fields []string) []TestCase {
+	className := Name + ".fields"
+	failAny := result.Thresholds == nil || result.Thresholds.MaxFieldMismatchRate == nil
+	var cases []TestCase
+	differed := make(map[string]bool, len(result.FieldStats))
+	for _, stat := range result.FieldStats {
+		differed[stat.Field] = true
+		c := TestCase{Name: stat.Field, ClassName: className}
+		if failAny || result.FieldExceeds(stat) {
+			message := fmt.Sprintf("%d records differ, a mismatch rate of %.2f%%", stat.Records, stat.MismatchRate)
+			if !failAny {
+				message += fmt.Sprintf(" exceeding max_field_mismatch_rate %v", *result.Thresholds.MaxFieldMismatchRate)
+			}
+			var details strings.Builder
+			for _, pair := range stat.TopValuePairs {
+				fmt.Fprintf(&details, "source1: %v, source2: %v (%d records)\n", pair.Source1Value, pair.Source2Value, pair.Count)
+			}
+			c.Failure = &Failure{Message: message, Type: "field_mismatch", Details: details.String()}
+		}
+		cases = append(cases, c)
+	}
+	passing := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if !differed[field] {
+			differed[field] = true
+			passing = append(passing, field)
+		}
+	}
+	sort.Strings(passing)
+	for _, field := range passing {
+		cases = append(cases, TestCase{Name: field, ClassName: className})
+	}
+	return cases
+}
+
+func checkCases(result *comparator.Result) []TestCase {
+	className := Name + ".checks"
+	var cases []TestCase
+	check := func(name, failureType, message, details string) {
+		c := TestCase{Name: name, ClassName: className}
+		if message != "" {
+			c.Failure = &Failure{Message: message, Type: failureType, Details: details}
+		}
+		cases = append(cases, c)
+	}
+
+	s := result.Summary
+	for _, side := range []struct {
+		side  comparator.Side
+		name  string
+		count int
+		keys  []string
+	}{
+		{comparator.Source1, "source1", s.KeysOnlyInSource1, result.KeysOnly.InSource1},
+		{comparator.Source2, "source2", s.KeysOnlyInSource2, result.KeysOnly.InSource2},
+	} {
+		if result.AllowsOnlyIn(side.side) {
+			continue
+		}
+		message, details := "", ""
+		if side.count > 0 {
+			message = fmt.Sprintf("%d keys only in %s", side.count, side.name)
+			details = strings.Join(side.keys[:min(len(side.keys), maxListedKeys)], "\n")
+		}
+		check("keys only in "+side.name, "missing_keys", message, details)
+	}
+
+	if t := result.Thresholds; t != nil {
+		if t.MaxDiffs != nil {
+			message := ""
+			if result.DiscrepantKeys() > *t.MaxDiffs {
+				message = fmt.Sprintf("%d discrepant keys exceed max_diffs %d", result.DiscrepantKeys(), *t.MaxDiffs)
+			}
+			check("max_diffs", "threshold_exceeded", message, "")
+		}
+		if t.MaxDiffPercent != nil {
+			message := ""
+			if result.DiffPercent() > *t.MaxDiffPercent {
+				message = fmt.Sprintf("%.2f%% discrepant keys exceed max_diff_percent %v", result.DiffPercent(), *t.MaxDiffPercent)
+			}
+			check("max_diff_percent", "threshold_exceeded", message, "")
+		}
+	}
+
+	if result.Violations != nil {
+		for _, side := range []struct {
+			name  string
+			count int
+		}{
+			{"source1", s.Source1Violations},
+			{"source2", s.Source2Violations},
+		} {
+			message := ""
+			if side.count > 0 {
+				message = fmt.Sprintf("%d schema and constraint violations in %s", side.count, side.name)
+			}
+			check("schema violations in "+side.name, "violations", message, "")
+		}
+	}
+
+	message, details := "", ""
+	if len(result.Incomplete) > 0 {
+		message = fmt.Sprintf("%d sources were not read to their end", len(result.Incomplete))
+		for _, incomplete := range result.Incomplete {
+			details += fmt.Sprintf("%s: %s after %d records\n", incomplete.Source, incomplete.Reason, incomplete.Records)
+		}
+	}
+	check("sources read completely", "incomplete", message, details)
+	return cases
+}
+
+// XML renders the report with an XML declaration.
+func (s TestSuites) XML() ([]byte, error) {
+	data, err := xml.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JUnit report: %w", err)
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// Write writes the report of result to path.
+func Write(path string, result *comparator.Result, fields []string) error {
+	data, err := New(result, fields).XML()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write JUnit report %s: %w", path, err)
+	}
+	return nil
+}
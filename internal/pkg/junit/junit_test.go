@@ -0,0 +1,91 @@
+package junit
+
+import (
+	"data-comparator/internal/pkg/comparator"
+	"data-comparator/internal/pkg/datareader"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func compare(t *testing.T, thresholds comparator.Thresholds) *comparator.Result {
+	t.Helper()
+	// status differs in 1 of 4 matching keys, 5 is only in source1.
+	records1 := []datareader.Record{
+		{"id": "1", "status": "open", "note": "a"}, {"id": "2", "status": "open", "note": "b"},
+		{"id": "3", "status": "closed", "note": "c"}, {"id": "4", "status": "open", "note": "d"}, {"id": "5", "status": "open", "note": "e"},
+	}
+	records2 := []datareader.Record{
+		{"id": "1", "status": "open", "note": "a"}, {"id": "2", "status": "closed", "note": "b"},
+		{"id": "3", "status": "closed", "note": "c"}, {"id": "4", "status": "open", "note": "d"},
+	}
+	c := comparator.New("id")
+	c.SetThresholds(thresholds)
+	result, err := c.Compare(datareader.NewSliceReader(records1), datareader.NewSliceReader(records2))
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	return result
+}
+
+func failures(suite TestSuite) []string {
+	var failed []string
+	for _, c := range suite.Cases {
+		if c.Failure != nil {
+			failed = append(failed, c.Name)
+		}
+	}
+	return failed
+}
+
+func TestNew(t *testing.T) {
+	report := New(compare(t, comparator.Thresholds{}), []string{"note", "status"})
+	if len(report.Suites) != 2 || report.Tests != 5 || report.Failures != 2 {
+		t.Fatalf("New() got %d suites, %d tests, %d failures, want 2, 5, 2", len(report.Suites), report.Tests, report.Failures)
+	}
+	fields := report.Suites[0]
+	if got := failures(fields); len(got) != 1 || got[0] != "status" || len(fields.Cases) != 2 || fields.Cases[1].Name != "note" {
+		t.Errorf("fields suite got = %+v, want status failing and note passing", fields.Cases)
+	}
+	if !strings.Contains(fields.Cases[0].Failure.Details, "source1: open, source2: closed (1 records)") {
+		t.Errorf("status failure details got = %q, want its value pair", fields.Cases[0].Failure.Details)
+	}
+	checks := report.Suites[1]
+	if got := failures(checks); len(got) != 1 || got[0] != "keys only in source1" || checks.Cases[0].Failure.Details != "5" {
+		t.Errorf("checks suite got = %+v, want keys only in source1 failing with key 5", checks.Cases)
+	}
+}
+
+func TestNew_Thresholds(t *testing.T) {
+	one, thirty, ten := 1, 30.0, 10.0
+	report := New(compare(t, comparator.Thresholds{MaxDiffs: &one, MaxDiffPercent: &thirty, MaxFieldMismatchRate: &thirty}), nil)
+	// 2 of 5 keys are discrepant, status differs in 25% of the matching keys.
+	if got := failures(report.Suites[0]); len(got) != 0 {
+		t.Errorf("fields failing got = %v, want none within max_field_mismatch_rate", got)
+	}
+	if got := strings.Join(failures(report.Suites[1]), ","); got != "keys only in source1,max_diffs,max_diff_percent" {
+		t.Errorf("checks failing got = %s, want keys only in source1,max_diffs,max_diff_percent", got)
+	}
+
+	report = New(compare(t, comparator.Thresholds{MaxFieldMismatchRate: &ten}), nil)
+	if got := failures(report.Suites[0]); len(got) != 1 || !strings.Contains(report.Suites[0].Cases[0].Failure.Message, "max_field_mismatch_rate 10") {
+		t.Errorf("fields suite got = %+v, want status exceeding max_field_mismatch_rate", report.Suites[0].Cases)
+	}
+}
+
+func TestXML(t *testing.T) {
+	data, err := New(compare(t, comparator.Thresholds{}), nil).XML()
+	if err != nil {
+		t.Fatalf("XML() error = %v", err)
+	}
+	if !strings.HasPrefix(string(data), xml.Header+"<testsuites") {
+		t.Errorf("XML() got = %s, want an XML declaration and testsuites root", data)
+	}
+	var parsed TestSuites
+	if err := xml.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if parsed.Failures != 2 || len(parsed.Suites) != 2 || parsed.Suites[0].Cases[0].Failure == nil {
+		t.Errorf("parsed report got = %+v, want 2 failures", parsed)
+	}
+}
@@ -0,0 +1,145 @@
+// Package k8s renders comparison results as Kubernetes-style status objects,
+// so an operator scheduling comparison Jobs can record parity on its resources.
+package k8s
+
+import (
+	"data-comparator/internal/pkg/comparator"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Phases of a comparison status.
+const (
+	PhaseInSync  = "InSync"
+	PhaseDrifted = "Drifted"
+)
+
+// ConditionInSync is the condition type reporting whether both sources agree.
+const ConditionInSync = "InSync"
+
+// StatusKey is the ConfigMap data key holding the status JSON.
+const StatusKey = "status.json"
+
+// Status is the status of a comparison, shaped like the status of a custom resource.
+type Status struct {
+	Phase        string      `json:"phase"`
+	ObservedTime time.Time   `json:"observedTime"`
+	Keys         KeyMapping  `json:"keys"`
+	Summary      Summary     `json:"summary"`
+	Conditions   []Condition `json:"conditions"`
+}
+
+// KeyMapping records the key field of each source.
+type KeyMapping struct {
+	Source1 string `json:"source1"`
+	Source2 string `json:"source2"`
+}
+
+// Summary holds the counts of a comparison and the share of matching keys
+// whose records differ.
+type Summary struct {
+	Source1Rows       int     `json:"source1Rows"`
+	Source2Rows       int     `json:"source2Rows"`
+	MatchingKeys      int     `json:"matchingKeys"`
+	IdenticalRows     int     `json:"identicalRows"`
+	KeysOnlyInSource1 int     `json:"keysOnlyInSource1"`
+	KeysOnlyInSource2 int     `json:"keysOnlyInSource2"`
+	DiffRate          float64 `json:"diffRate"`
+}
+
+// Condition follows the fields of a Kubernetes metav1.Condition.
+type Condition struct {
+	Type               string    `json:"type"`
+	Status             string    `json:"status"`
+	LastTransitionTime time.Time `json:"lastTransitionTime"`
+	Reason             string    `json:"reason"`
+	Message            string    `json:"message"`
+}
+
+// NewStatus builds the status of a finished comparison.
+func NewStatus(result *comparator.Result) Status {
+	s := result.Summary
+	status := Status{
+		Phase:        PhaseInSync,
+		ObservedTime: result.FinishedAt.UTC(),
+		Keys:         KeyMapping{Source1: result.Keys.Source1, Source2: result.Keys.Source2},
+		Summary: Summary{
+			Source1Rows:       s.Source1Rows,
+			Source2Rows:       s.Source2Rows,
+			MatchingKeys:      s.MatchingKeys,
+			IdenticalRows:     s.IdenticalRows,
+			KeysOnlyInSource1: s.KeysOnlyInSource1,
+			KeysOnlyInSource2: s.KeysOnlyInSource2,
+			DiffRate:          s.DiffRate(),
+		},
+	}
+
+	condition := Condition{
+		Type:               ConditionInSync,
+		Status:             "True",
+		LastTransitionTime: status.ObservedTime,
+		Reason:             "NoDiffs",
+		Message:            fmt.Sprintf("%d matching keys, all identical", s.MatchingKeys),
+	}
+	var problems []string
+	if n := s.MatchingKeys - s.IdenticalRows; n > 0 {
+		problems = append(problems, fmt.Sprintf("%d keys with differing values", n))
+	}
+	if s.KeysOnlyInSource1 > 0 && !result.AllowsOnlyIn(comparator.Source1) {
+		problems = append(problems, fmt.Sprintf("%d keys only in source1", s.KeysOnlyInSource1))
+	}
+	if s.KeysOnlyInSource2 > 0 && !result.AllowsOnlyIn(comparator.Source2) {
+		problems = append(problems, fmt.Sprintf("%d keys only in source2", s.KeysOnlyInSource2))
+	}
+	if len(problems) > 0 {
+		status.Phase = PhaseDrifted
+		condition.Status = "False"
+		condition.Reason = "DiffsFound"
+		condition.Message = strings.Join(problems, ", ")
+	}
+	status.Conditions = []Condition{condition}
+	return status
+}
+
+// StatusPatch renders status as a JSON merge patch for the status subresource
+// of a custom resource, e.g. for kubectl patch --subresource=status --type=merge.
+func StatusPatch(status Status) ([]byte, error) {
+	data, err := json.MarshalIndent(map[string]interface{}{"status": status}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal status patch: %w", err)
+	}
+	return data, nil
+}
+
+// ConfigMap renders a ConfigMap manifest named "namespace/name", or just
+// "name", holding the status JSON under StatusKey.
+func ConfigMap(ref string, status Status) ([]byte, error) {
+	namespace, name, ok := strings.Cut(ref, "/")
+	if !ok {
+		namespace, name = "", ref
+	}
+	if name == "" {
+		return nil, fmt.Errorf("configmap name is empty in %q", ref)
+	}
+
+	statusJSON, err := json.Marshal(status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal status: %w", err)
+	}
+	metadata := map[string]string{"name": name}
+	if namespace != "" {
+		metadata["namespace"] = namespace
+	}
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   metadata,
+		"data":       map[string]string{StatusKey: string(statusJSON)},
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal configmap: %w", err)
+	}
+	return data, nil
+}
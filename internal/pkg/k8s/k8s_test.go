@@ -0,0 +1,92 @@
+package k8s
+
+import (
+	"data-comparator/internal/pkg/comparator"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func testResult() *comparator.Result {
+	return &comparator.Result{
+		FinishedAt: time.Date(2025, 9, 10, 12, 0, 0, 0, time.UTC),
+		Keys:       comparator.KeyMapping{Source1: "user_id", Source2: "user_id"},
+		Summary: comparator.Summary{
+			Source1Rows: 5, Source2Rows: 5, MatchingKeys: 4, IdenticalRows: 3,
+			KeysOnlyInSource1: 1, KeysOnlyInSource2: 1,
+		},
+	}
+}
+
+func TestNewStatus(t *testing.T) {
+	status := NewStatus(testResult())
+	if status.Phase != PhaseDrifted {
+		t.Errorf("Phase got = %v, want %v", status.Phase, PhaseDrifted)
+	}
+	if status.Summary.DiffRate != 0.25 {
+		t.Errorf("DiffRate got = %v, want 0.25", status.Summary.DiffRate)
+	}
+	if len(status.Conditions) != 1 || status.Conditions[0].Status != "False" {
+		t.Fatalf("Conditions got = %+v, want one False condition", status.Conditions)
+	}
+	want := "1 keys with differing values, 1 keys only in source1, 1 keys only in source2"
+	if status.Conditions[0].Message != want {
+		t.Errorf("Message got = %q, want %q", status.Conditions[0].Message, want)
+	}
+
+	subset := testResult()
+	subset.Expectation = comparator.ExpectSubset
+	want = "1 keys with differing values, 1 keys only in source1"
+	if got := NewStatus(subset).Conditions[0].Message; got != want {
+		t.Errorf("Message with subset expectation got = %q, want %q", got, want)
+	}
+
+	inSync := NewStatus(&comparator.Result{Summary: comparator.Summary{MatchingKeys: 2, IdenticalRows: 2}})
+	if inSync.Phase != PhaseInSync || inSync.Conditions[0].Status != "True" {
+		t.Errorf("Status got = %+v, want InSync", inSync)
+	}
+}
+
+func TestConfigMap(t *testing.T) {
+	data, err := ConfigMap("parity/orders", NewStatus(testResult()))
+	if err != nil {
+		t.Fatalf("ConfigMap() error = %v", err)
+	}
+
+	var cm struct {
+		Kind     string            `json:"kind"`
+		Metadata map[string]string `json:"metadata"`
+		Data     map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(data, &cm); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if cm.Kind != "ConfigMap" || cm.Metadata["namespace"] != "parity" || cm.Metadata["name"] != "orders" {
+		t.Errorf("ConfigMap got = %+v", cm)
+	}
+	var status Status
+	if err := json.Unmarshal([]byte(cm.Data[StatusKey]), &status); err != nil {
+		t.Fatalf("Unmarshal(status) error = %v", err)
+	}
+	if status.Phase != PhaseDrifted {
+		t.Errorf("Embedded phase got = %v, want %v", status.Phase, PhaseDrifted)
+	}
+
+	if _, err := ConfigMap("parity/", NewStatus(testResult())); err == nil {
+		t.Error("ConfigMap() expected error for empty name, got nil")
+	}
+}
+
+func TestStatusPatch(t *testing.T) {
+	data, err := StatusPatch(NewStatus(testResult()))
+	if err != nil {
+		t.Fatalf("StatusPatch() error = %v", err)
+	}
+	var patch map[string]Status
+	if err := json.Unmarshal(data, &patch); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if patch["status"].Keys.Source1 != "user_id" {
+		t.Errorf("Patch status got = %+v", patch["status"])
+	}
+}
@@ -0,0 +1,310 @@
+// Package mapping proposes field mappings between two sources whose schemas
+// name the same data differently, scoring candidate pairs by name
+// similarity, inferred type and the overlap of their sampled values.
+package mapping
+
+import (
+	"bufio"
+	"data-comparator/internal/pkg/config"
+	"data-comparator/internal/pkg/datareader"
+	"data-comparator/internal/pkg/schema"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MinScore is the lowest score of a proposed mapping.
+const MinScore = 0.5
+
+// Weights of the name, type and value overlap scores in a candidate's score.
+const (
+	nameWeight    = 0.4
+	typeWeight    = 0.2
+	overlapWeight = 0.4
+)
+
+// Sample is what is known about the top-level fields of a source: their
+// inferred schema and their sampled values.
+type Sample struct {
+	Schema *schema.Schema
+	Values map[string][]interface{}
+}
+
+// Candidate is a proposed mapping of a source1 field to a source2 field.
+type Candidate struct {
+	Field1 string
+	Field2 string
+	// Score is the weighted sum of the scores below, between 0 and 1.
+	Score float64
+	// Name is the similarity of the field names, between 0 and 1.
+	Name float64
+	// SameType is set when both fields have the same inferred type.
+	SameType bool
+	// Overlap is the share of distinct sampled values of the smaller field
+	// found in the other, between 0 and 1.
+	Overlap float64
+}
+
+// String describes the candidate and why it was proposed.
+func (c Candidate) String() string {
+	typ := "different types"
+	if c.SameType {
+		typ = "same type"
+	}
+	return fmt.Sprintf("%s -> %s (score %.2f: name similarity %.2f, %s, value overlap %.0f%%)",
+		c.Field1, c.Field2, c.Score, c.Name, typ, c.Overlap*100)
+}
+
+// SampleSource reads up to the sampler's sample size of records from reader
+// and infers their schema.
+func SampleSource(reader datareader.DataReader, sampler *config.Sampler) (*Sample, error) {
+	sampleSize := schema.DefaultSampleSize
+	if sampler != nil && sampler.SampleSize > 0 {
+		sampleSize = sampler.SampleSize
+	}
+	sample := &Sample{Values: make(map[string][]interface{})}
+	var records []datareader.Record
+	for len(records) < sampleSize {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to sample records: %w", err)
+		}
+		records = append(records, rec)
+		for name, v := range rec {
+			sample.Values[name] = append(sample.Values[name], v)
+		}
+	}
+	s, err := schema.Generate(datareader.NewSliceReader(records), sampler)
+	if err != nil {
+		return nil, err
+	}
+	sample.Schema = s
+	return sample, nil
+}
+
+// Propose pairs the top-level fields only one of the samples has with the
+// best scoring field of the other, best pairs first. Each field is proposed
+// at most once and pairs scoring below MinScore are dropped.
+func Propose(sample1, sample2 *Sample) []Candidate {
+	var all []Candidate
+	for _, field1 := range unmatched(sample1, sample2) {
+		for _, field2 := range unmatched(sample2, sample1) {
+			c := score(field1, field2, sample1, sample2)
+			if c.Score >= MinScore {
+				all = append(all, c)
+			}
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Score != all[j].Score {
+			return all[i].Score > all[j].Score
+		}
+		if all[i].Field1 != all[j].Field1 {
+			return all[i].Field1 < all[j].Field1
+		}
+		return all[i].Field2 < all[j].Field2
+	})
+
+	used1, used2 := make(map[string]bool), make(map[string]bool)
+	var proposed []Candidate
+	for _, c := range all {
+		if used1[c.Field1] || used2[c.Field2] {
+			continue
+		}
+		used1[c.Field1], used2[c.Field2] = true, true
+		proposed = append(proposed, c)
+	}
+	return proposed
+}
+
+// unmatched returns the sorted top-level fields of sample missing from other.
+func unmatched(sample, other *Sample) []string {
+	var names []string
+	for name := range sample.Values {
+		if _, ok := other.Values[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func score(field1, field2 string, sample1, sample2 *Sample) Candidate {
+	c := Candidate{
+		Field1:  field1,
+		Field2:  field2,
+		Name:    nameSimilarity(field1, field2),
+		Overlap: valueOverlap(sample1.Values[field1], sample2.Values[field2]),
+	}
+	if f1, f2 := sample1.Schema.Fields[field1], sample2.Schema.Fields[field2]; f1 != nil && f2 != nil {
+		c.SameType = f1.Type == f2.Type && f1.Type != "unknown"
+	}
+	c.Score = nameWeight*c.Name + overlapWeight*c.Overlap
+	if c.SameType {
+		c.Score += typeWeight
+	}
+	return c
+}
+
+// nameSimilarity compares two field names by their words, where a word
+// matches another it abbreviates, such as "cust" and "customer", and by the
+// edit distance of the names without separators and case. It returns the
+// higher of both similarities.
+func nameSimilarity(name1, name2 string) float64 {
+	words1, words2 := words(name1), words(name2)
+	if len(words1) == 0 || len(words2) == 0 {
+		return 0
+	}
+
+	matched := 0
+	used := make([]bool, len(words2))
+	for _, w1 := range words1 {
+		for j, w2 := range words2 {
+			if !used[j] && abbreviates(w1, w2) {
+				used[j] = true
+				matched++
+				break
+			}
+		}
+	}
+	wordScore := float64(2*matched) / float64(len(words1)+len(words2))
+
+	joined1, joined2 := strings.Join(words1, ""), strings.Join(words2, "")
+	longest := max(len([]rune(joined1)), len([]rune(joined2)))
+	editScore := 1 - float64(levenshtein(joined1, joined2))/float64(longest)
+	return max(wordScore, editScore)
+}
+
+// words splits a field name into lower case words at separators and at
+// camelCase boundaries.
+func words(name string) []string {
+	var out []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			out = append(out, string(word))
+			word = nil
+		}
+	}
+	prev := rune(0)
+	for _, r := range name {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+			flush()
+			word = append(word, unicode.ToLower(r))
+		default:
+			word = append(word, unicode.ToLower(r))
+		}
+		prev = r
+	}
+	flush()
+	return out
+}
+
+// abbreviates reports whether one word equals the other or, being at least
+// three letters long, is its prefix.
+func abbreviates(w1, w2 string) bool {
+	if w1 == w2 {
+		return true
+	}
+	short, long := w1, w2
+	if len(short) > len(long) {
+		short, long = long, short
+	}
+	return len(short) >= 3 && strings.HasPrefix(long, short)
+}
+
+func levenshtein(s1, s2 string) int {
+	r1, r2 := []rune(s1), []rune(s2)
+	row := make([]int, len(r2)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(r1); i++ {
+		diag := row[0]
+		row[0] = i
+		for j := 1; j <= len(r2); j++ {
+			cost := 1
+			if r1[i-1] == r2[j-1] {
+				cost = 0
+			}
+			next := min(row[j]+1, row[j-1]+1, diag+cost)
+			diag, row[j] = row[j], next
+		}
+	}
+	return row[len(r2)]
+}
+
+// valueOverlap returns the share of distinct non-null values of the field
+// with fewer of them that the other field also has. Values are compared by
+// their text, so 1 and "1" overlap.
+func valueOverlap(values1, values2 []interface{}) float64 {
+	set1, set2 := distinct(values1), distinct(values2)
+	if len(set1) == 0 || len(set2) == 0 {
+		return 0
+	}
+	if len(set1) > len(set2) {
+		set1, set2 = set2, set1
+	}
+	shared := 0
+	for v := range set1 {
+		if set2[v] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(set1))
+}
+
+func distinct(values []interface{}) map[string]bool {
+	set := make(map[string]bool)
+	for _, v := range values {
+		switch v.(type) {
+		case nil, map[string]interface{}, []interface{}:
+			continue
+		}
+		set[fmt.Sprintf("%v", v)] = true
+	}
+	return set
+}
+
+// Confirm asks on out whether to accept each candidate and reads the answers
+// from in: y accepts, q stops asking and anything else skips the candidate.
+// It returns the accepted mappings from source1 to source2 fields.
+func Confirm(candidates []Candidate, in io.Reader, out io.Writer) (map[string]string, error) {
+	accepted := make(map[string]string)
+	scanner := bufio.NewScanner(in)
+	for i, c := range candidates {
+		fmt.Fprintf(out, "[%d/%d] map %s? [y/N/q] ", i+1, len(candidates), c)
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			break
+		}
+		answer := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if answer == "q" || answer == "quit" {
+			break
+		}
+		if answer == "y" || answer == "yes" {
+			accepted[c.Field1] = c.Field2
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read answer: %w", err)
+	}
+	return accepted, nil
+}
+
+// ConfigBlock renders mappings as the comparison section of a source1 config.
+func ConfigBlock(mappings map[string]string) ([]byte, error) {
+	return yaml.Marshal(map[string]interface{}{
+		"comparison": config.Comparison{FieldMappings: mappings},
+	})
+}
@@ -0,0 +1,108 @@
+package mapping
+
+import (
+	"bytes"
+	"data-comparator/internal/pkg/datareader"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testSamples(t *testing.T) (*Sample, *Sample) {
+	t.Helper()
+	var records1, records2 []datareader.Record
+	for i, name := range []string{"Alice", "Bob", "Carol", "Dave"} {
+		records1 = append(records1, datareader.Record{
+			"id": i, "cust_id": 1000 + i, "full_name": name, "signup": "2024-01-0" + string(rune('1'+i)), "notes": "n/a",
+		})
+		records2 = append(records2, datareader.Record{
+			"id": i, "customerId": 1000 + i, "name": name, "created": "2024-01-0" + string(rune('1'+i)), "score": 0.5,
+		})
+	}
+	sample1, err := SampleSource(datareader.NewSliceReader(records1), nil)
+	if err != nil {
+		t.Fatalf("SampleSource() error = %v", err)
+	}
+	sample2, err := SampleSource(datareader.NewSliceReader(records2), nil)
+	if err != nil {
+		t.Fatalf("SampleSource() error = %v", err)
+	}
+	return sample1, sample2
+}
+
+func TestPropose(t *testing.T) {
+	sample1, sample2 := testSamples(t)
+	got := make(map[string]string)
+	for _, c := range Propose(sample1, sample2) {
+		got[c.Field1] = c.Field2
+	}
+	want := map[string]string{"cust_id": "customerId", "full_name": "name", "signup": "created"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Propose() got = %v, want %v", got, want)
+	}
+}
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		name1, name2 string
+		want         float64
+	}{
+		{"cust_id", "customerId", 1},
+		{"CustomerID", "customer_id", 1},
+		{"amount", "amout", 1 - 1.0/6},
+		{"id", "zip", 1 - 2.0/3},
+	}
+	for _, tt := range tests {
+		if got := nameSimilarity(tt.name1, tt.name2); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("nameSimilarity(%q, %q) got = %v, want %v", tt.name1, tt.name2, got, tt.want)
+		}
+	}
+}
+
+func TestValueOverlap(t *testing.T) {
+	got := valueOverlap([]interface{}{1, 2, nil}, []interface{}{"1", "2", "3", "4"})
+	if got != 1 {
+		t.Errorf("valueOverlap() got = %v, want 1", got)
+	}
+	if got := valueOverlap([]interface{}{"a"}, nil); got != 0 {
+		t.Errorf("valueOverlap() of no values got = %v, want 0", got)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	candidates := []Candidate{
+		{Field1: "a", Field2: "b"},
+		{Field1: "c", Field2: "d"},
+		{Field1: "e", Field2: "f"},
+		{Field1: "g", Field2: "h"},
+	}
+	var out bytes.Buffer
+	got, err := Confirm(candidates, strings.NewReader("y\nn\nYes\nq\n"), &out)
+	if err != nil {
+		t.Fatalf("Confirm() error = %v", err)
+	}
+	want := map[string]string{"a": "b", "e": "f"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Confirm() got = %v, want %v", got, want)
+	}
+	if !strings.Contains(out.String(), "[4/4] map g -> h") {
+		t.Errorf("Confirm() prompts got = %q", out.String())
+	}
+
+	got, err = Confirm(candidates, strings.NewReader("y\n"), &out)
+	if err != nil || !reflect.DeepEqual(got, map[string]string{"a": "b"}) {
+		t.Errorf("Confirm() at end of input got = %v, %v, want only a", got, err)
+	}
+}
+
+func TestConfigBlock(t *testing.T) {
+	got, err := ConfigBlock(map[string]string{"cust_id": "customerId"})
+	if err != nil {
+		t.Fatalf("ConfigBlock() error = %v", err)
+	}
+	want := "comparison:\n    field_mappings:\n        cust_id: customerId\n"
+	if string(got) != want {
+		t.Errorf("ConfigBlock() got = %q, want %q", got, want)
+	}
+}
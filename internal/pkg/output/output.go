@@ -0,0 +1,126 @@
+// Package output renders reports as YAML, or as JSON with the field names
+// and order of their YAML, for tooling that consumes them programmatically.
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Formats lists the output formats, the default first: json is indented,
+// json-compact on a single line.
+var Formats = []string{"yaml", "json", "json-compact"}
+
+// CheckFormat returns an error unless format is one of Formats or empty.
+func CheckFormat(format string) error {
+	if format == "" || slices.Contains(Formats, format) {
+		return nil
+	}
+	return fmt.Errorf("unsupported format %s, use one of %s", format, strings.Join(Formats, ", "))
+}
+
+// Marshal renders v in format, the default for an empty one. The output
+// ends with a newline.
+func Marshal(v interface{}, format string) ([]byte, error) {
+	if err := CheckFormat(format); err != nil {
+		return nil, err
+	}
+	var data []byte
+	var err error
+	switch format {
+	case "json":
+		data, err = JSON(v, "  ")
+	case "json-compact":
+		data, err = JSON(v, "")
+	default:
+		return yaml.Marshal(v)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// JSON renders v as JSON with the field names and order of its YAML,
+// indented by indent per level unless it is empty.
+func JSON(v interface{}, indent string) ([]byte, error) {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("failed to convert to JSON: %w", err)
+	}
+	var b bytes.Buffer
+	if err := writeJSON(&b, &node); err != nil {
+		return nil, fmt.Errorf("failed to convert to JSON: %w", err)
+	}
+	if indent == "" {
+		return b.Bytes(), nil
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, b.Bytes(), "", indent); err != nil {
+		return nil, err
+	}
+	return indented.Bytes(), nil
+}
+
+// writeJSON writes the compact JSON of node to b, keeping the order of
+// mapping keys.
+func writeJSON(b *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			b.WriteString("null")
+			return nil
+		}
+		return writeJSON(b, node.Content[0])
+	case yaml.AliasNode:
+		return writeJSON(b, node.Alias)
+	case yaml.MappingNode:
+		b.WriteByte('{')
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			key, err := json.Marshal(node.Content[i].Value)
+			if err != nil {
+				return err
+			}
+			b.Write(key)
+			b.WriteByte(':')
+			if err := writeJSON(b, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+	case yaml.SequenceNode:
+		b.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := writeJSON(b, item); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+	default:
+		var value interface{}
+		if err := node.Decode(&value); err != nil {
+			return err
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		b.Write(data)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package output
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+type report struct {
+	Valid    bool              `yaml:"valid"`
+	Name     string            `yaml:"name"`
+	Count    int               `yaml:"count"`
+	Rate     float64           `yaml:"rate"`
+	Started  time.Time         `yaml:"started"`
+	Keys     []string          `yaml:"keys"`
+	Labels   map[string]string `yaml:"labels,omitempty"`
+	Internal string            `yaml:"-"`
+}
+
+func TestJSON(t *testing.T) {
+	r := report{
+		Valid:    true,
+		Name:     "007",
+		Count:    3,
+		Rate:     0.5,
+		Started:  time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC),
+		Keys:     []string{"1", "true", "null"},
+		Internal: "hidden",
+	}
+	data, err := JSON(r, "")
+	if err != nil {
+		t.Fatalf("JSON() error = %v", err)
+	}
+	want := `{"valid":true,"name":"007","count":3,"rate":0.5,"started":"2025-09-01T12:00:00Z","keys":["1","true","null"]}`
+	if string(data) != want {
+		t.Errorf("JSON() got = %s, want %s", data, want)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	r := map[string]interface{}{"summary": map[string]int{"rows": 2}}
+	for _, tt := range []struct {
+		format string
+		want   string
+	}{
+		{"", "summary:\n    rows: 2\n"},
+		{"yaml", "summary:\n    rows: 2\n"},
+		{"json", "{\n  \"summary\": {\n    \"rows\": 2\n  }\n}\n"},
+		{"json-compact", "{\"summary\":{\"rows\":2}}\n"},
+	} {
+		data, err := Marshal(r, tt.format)
+		if err != nil {
+			t.Fatalf("Marshal(%q) error = %v", tt.format, err)
+		}
+		if string(data) != tt.want {
+			t.Errorf("Marshal(%q) got = %q, want %q", tt.format, data, tt.want)
+		}
+		if strings.HasPrefix(tt.format, "json") && !json.Valid(data) {
+			t.Errorf("Marshal(%q) got invalid JSON %s", tt.format, data)
+		}
+	}
+	if _, err := Marshal(r, "xml"); err == nil {
+		t.Error("Marshal() with format xml expected an error, got nil")
+	}
+}
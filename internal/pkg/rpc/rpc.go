@@ -0,0 +1,392 @@
+// Package rpc lets other programs drive comparisons over a stream, speaking
+// JSON-RPC 2.0 with one message per line, typically on stdin and stdout.
+//
+// Methods:
+//
+//   - start {config1, config2, key1?, key2?} starts a comparison job and
+//     returns {"job": id}. config1 and config2 are config file paths or inline
+//     config objects; empty keys are taken from the configs or inferred.
+//   - cancel {job} stops a running job.
+//   - snapshot {job} asks a running job for a "snapshot" notification with
+//     its current counts, without waiting for it to finish.
+//   - pause {job} stops a running job from reading its sources until
+//     resume {job}, keeping its state. Paused time does not count toward
+//     its timeouts.
+//   - shutdown waits for running jobs and ends the session.
+//
+// While a job runs, the server sends notifications: "finding" for every
+// finding as soon as it is known, then either "summary" when the job completes
+// or "failed" when it stops on an error or is cancelled.
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"data-comparator/internal/pkg/comparator"
+	"data-comparator/internal/pkg/config"
+	"data-comparator/internal/pkg/datareader"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"sync"
+	"time"
+)
+
+// JSON-RPC 2.0 error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeJobFailed      = -32000
+)
+
+// Request is a JSON-RPC request or notification.
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Response answers a Request.
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Notification is a message from the server without a response.
+type Notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+// Error is a JSON-RPC error object.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// StartParams are the parameters of the start method.
+type StartParams struct {
+	Config1 json.RawMessage `json:"config1"`
+	Config2 json.RawMessage `json:"config2"`
+	Key1    string          `json:"key1,omitempty"`
+	Key2    string          `json:"key2,omitempty"`
+}
+
+// JobParams identify a job.
+type JobParams struct {
+	Job int `json:"job"`
+}
+
+// FindingParams are the parameters of a finding notification.
+type FindingParams struct {
+	Job   int                    `json:"job"`
+	Kind  string                 `json:"kind"`
+	Key   string                 `json:"key,omitempty"`
+	Side  string                 `json:"side,omitempty"`
+	Diffs []comparator.FieldDiff `json:"diffs,omitempty"`
+	// Severity ranks a missing_key finding.
+	Severity string `json:"severity,omitempty"`
+	// Record and Truncations describe a truncated_record finding.
+	Record      int                     `json:"record,omitempty"`
+	Truncations []comparator.Truncation `json:"truncations,omitempty"`
+	Error       string                  `json:"error,omitempty"`
+	// Reason explains an incomplete finding.
+	Reason string `json:"reason,omitempty"`
+	// Latency is the time a resolved_diff finding took to resolve, in nanoseconds.
+	Latency time.Duration `json:"latency,omitempty"`
+}
+
+// SnapshotParams are the parameters of a snapshot notification.
+type SnapshotParams struct {
+	Job      int                 `json:"job"`
+	Snapshot comparator.Snapshot `json:"snapshot"`
+}
+
+// SummaryParams are the parameters of a summary notification.
+type SummaryParams struct {
+	Job     int                   `json:"job"`
+	Keys    comparator.KeyMapping `json:"keys"`
+	Summary comparator.Summary    `json:"summary"`
+}
+
+// FailedParams are the parameters of a failed notification.
+type FailedParams struct {
+	Job   int    `json:"job"`
+	Error string `json:"error"`
+}
+
+// Server runs comparison jobs requested over a stream.
+type Server struct {
+	in  io.Reader
+	out io.Writer
+
+	mu     sync.Mutex // guards writes to out and the fields below
+	nextID int
+	jobs   map[int]*runningJob
+	wg     sync.WaitGroup
+}
+
+// runningJob is a comparison job in progress.
+type runningJob struct {
+	cancel     context.CancelFunc
+	comparator *comparator.StreamComparator
+}
+
+// stop cancels the job. A paused job is resumed, as it only notices the
+// cancellation on its next read.
+func (j *runningJob) stop() {
+	j.cancel()
+	j.comparator.Resume()
+}
+
+// NewServer creates a server reading requests from in and writing responses
+// and notifications to out.
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{in: in, out: out, jobs: make(map[int]*runningJob)}
+}
+
+// Serve handles requests until shutdown or the end of input, then waits for
+// running jobs to finish. On end of input running jobs are cancelled.
+func (s *Server) Serve() error {
+	scanner := bufio.NewScanner(s.in)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var req Request
+		if err := json.Unmarshal(line, &req); err != nil {
+			s.respond(Response{ID: json.RawMessage("null"), Error: &Error{Code: CodeParseError, Message: err.Error()}})
+			continue
+		}
+		if req.Method == "shutdown" {
+			s.wg.Wait()
+			s.reply(req, nil, nil)
+			return nil
+		}
+		s.handle(req)
+	}
+
+	s.mu.Lock()
+	for _, j := range s.jobs {
+		j.stop()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read requests: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) handle(req Request) {
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		s.reply(req, nil, &Error{Code: CodeInvalidRequest, Message: "not a JSON-RPC 2.0 request"})
+		return
+	}
+	switch req.Method {
+	case "start":
+		var params StartParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			s.reply(req, nil, &Error{Code: CodeInvalidParams, Message: err.Error()})
+			return
+		}
+		job, err := s.start(params)
+		if err != nil {
+			s.reply(req, nil, &Error{Code: CodeJobFailed, Message: err.Error()})
+			return
+		}
+		s.reply(req, JobParams{Job: job}, nil)
+	case "cancel", "snapshot", "pause", "resume":
+		var params JobParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			s.reply(req, nil, &Error{Code: CodeInvalidParams, Message: err.Error()})
+			return
+		}
+		s.mu.Lock()
+		j, ok := s.jobs[params.Job]
+		s.mu.Unlock()
+		if !ok {
+			s.reply(req, nil, &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("no running job %d", params.Job)})
+			return
+		}
+		switch req.Method {
+		case "cancel":
+			j.stop()
+		case "snapshot":
+			j.comparator.RequestSnapshot()
+		case "pause":
+			j.comparator.Pause()
+		case "resume":
+			j.comparator.Resume()
+		}
+		s.reply(req, params, nil)
+	default:
+		s.reply(req, nil, &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("unknown method %s", req.Method)})
+	}
+}
+
+// RequestSnapshots asks every running job for a snapshot notification, as
+// the snapshot method does for one.
+func (s *Server) RequestSnapshots() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		j.comparator.RequestSnapshot()
+	}
+}
+
+// PauseJobs pauses every running job, as the pause method does for one.
+func (s *Server) PauseJobs() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		j.comparator.Pause()
+	}
+}
+
+// ResumeJobs resumes every paused job.
+func (s *Server) ResumeJobs() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		j.comparator.Resume()
+	}
+}
+
+// start opens the sources of a job and runs it in the background.
+func (s *Server) start(params StartParams) (int, error) {
+	config1, err := loadConfig(params.Config1)
+	if err != nil {
+		return 0, fmt.Errorf("config1: %w", err)
+	}
+	config2, err := loadConfig(params.Config2)
+	if err != nil {
+		return 0, fmt.Errorf("config2: %w", err)
+	}
+	c, reader1, reader2, err := comparator.OpenConfigs(config1, config2, params.Key1, params.Key2)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	s.nextID++
+	job := s.nextID
+	s.jobs[job] = &runningJob{cancel: cancel, comparator: c}
+	s.mu.Unlock()
+	c.SetHooks(comparator.Hooks{OnSnapshot: func(snapshot comparator.Snapshot) {
+		s.notify("snapshot", SnapshotParams{Job: job, Snapshot: snapshot})
+	}})
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer reader1.Close()
+		defer reader2.Close()
+		defer func() {
+			s.mu.Lock()
+			delete(s.jobs, job)
+			s.mu.Unlock()
+			cancel()
+		}()
+		s.run(ctx, job, c.Findings(ctxReader{ctx, reader1}, ctxReader{ctx, reader2}))
+	}()
+	return job, nil
+}
+
+func (s *Server) run(ctx context.Context, job int, findings iter.Seq[comparator.Finding]) {
+	var lastErr string
+	for f := range findings {
+		if ctx.Err() != nil {
+			break
+		}
+		switch f := f.(type) {
+		case comparator.Completed:
+			s.notify("summary", SummaryParams{Job: job, Keys: f.Keys, Summary: f.Summary})
+			return
+		case comparator.MissingKey:
+			s.notify("finding", FindingParams{Job: job, Kind: f.Kind(), Key: f.Key, Side: f.Side.String(), Severity: string(f.Severity)})
+		case comparator.DuplicateKey:
+			s.notify("finding", FindingParams{Job: job, Kind: f.Kind(), Key: f.Key, Side: f.Side.String()})
+		case comparator.EmptySource:
+			s.notify("finding", FindingParams{Job: job, Kind: f.Kind(), Side: f.Side.String()})
+		case comparator.TruncatedRecord:
+			s.notify("finding", FindingParams{Job: job, Kind: f.Kind(), Side: f.Side.String(), Record: f.Record, Truncations: f.Truncations})
+		case comparator.RecordDiff:
+			s.notify("finding", FindingParams{Job: job, Kind: f.Kind(), Key: f.Key, Diffs: f.Diffs})
+		case comparator.ResolvedDiff:
+			s.notify("finding", FindingParams{Job: job, Kind: f.Kind(), Key: f.Key, Latency: f.Latency})
+		case comparator.Stalled:
+			s.notify("finding", FindingParams{Job: job, Kind: f.Kind(), Side: f.Side.String()})
+		case comparator.Incomplete:
+			s.notify("finding", FindingParams{Job: job, Kind: f.Kind(), Side: f.Side.String(), Reason: f.Reason})
+		case comparator.ParseFailure:
+			lastErr = f.Err.Error()
+			s.notify("finding", FindingParams{Job: job, Kind: f.Kind(), Side: f.Side.String(), Error: lastErr})
+		}
+	}
+
+	// Findings ends without a summary only on an unrecoverable error.
+	if ctx.Err() != nil {
+		lastErr = "cancelled"
+	}
+	s.notify("failed", FailedParams{Job: job, Error: lastErr})
+}
+
+// ctxReader stops reading once its context is cancelled, which ends the
+// comparison even while no findings are produced.
+type ctxReader struct {
+	ctx context.Context
+	datareader.DataReader
+}
+
+func (r ctxReader) Read() (datareader.Record, error) {
+	if err := r.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.DataReader.Read()
+}
+
+// loadConfig reads a config given as a file path string or an inline object.
+func loadConfig(raw json.RawMessage) (*config.Config, error) {
+	var path string
+	if err := json.Unmarshal(raw, &path); err == nil {
+		return config.Load(path)
+	}
+	return config.Parse(raw)
+}
+
+func (s *Server) reply(req Request, result interface{}, rpcErr *Error) {
+	if req.ID == nil {
+		return // notifications get no response
+	}
+	s.respond(Response{ID: req.ID, Result: result, Error: rpcErr})
+}
+
+func (s *Server) respond(resp Response) {
+	resp.JSONRPC = "2.0"
+	s.write(resp)
+}
+
+func (s *Server) notify(method string, params interface{}) {
+	s.write(Notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (s *Server) write(msg interface{}) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		data, _ = json.Marshal(Notification{JSONRPC: "2.0", Method: "error", Params: err.Error()})
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.out.Write(append(data, '\n'))
+}
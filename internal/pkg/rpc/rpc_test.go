@@ -0,0 +1,85 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func serve(t *testing.T, requests ...string) []map[string]interface{} {
+	t.Helper()
+	var out bytes.Buffer
+	if err := NewServer(strings.NewReader(strings.Join(requests, "\n")), &out).Serve(); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+
+	var messages []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var msg map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			t.Fatalf("Unmarshal(%s) error = %v", line, err)
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+func TestServe_Job(t *testing.T) {
+	messages := serve(t,
+		`{"jsonrpc": "2.0", "id": 1, "method": "start", "params": {`+
+			`"config1": {"source": {"type": "csv", "path": "../../../testdata/testcase1_simple_csv/source1.csv"}},`+
+			`"config2": {"source": {"type": "csv", "path": "../../../testdata/testcase1_simple_csv/source2.csv"}},`+
+			`"key1": "user_id", "key2": "user_id"}}`,
+		`{"jsonrpc": "2.0", "id": 2, "method": "shutdown"}`,
+	)
+
+	if result, ok := messages[0]["result"].(map[string]interface{}); !ok || result["job"] != 1.0 {
+		t.Fatalf("First message got = %v, want the start response for job 1", messages[0])
+	}
+	kinds := make(map[string]int)
+	var summary map[string]interface{}
+	for _, msg := range messages[1 : len(messages)-1] {
+		params := msg["params"].(map[string]interface{})
+		switch msg["method"] {
+		case "finding":
+			kinds[params["kind"].(string)]++
+		case "summary":
+			summary = params["summary"].(map[string]interface{})
+		default:
+			t.Errorf("Unexpected message %v", msg)
+		}
+	}
+	if kinds["field_diff"] != 1 || kinds["missing_key"] != 2 {
+		t.Errorf("Finding kinds got = %v, want 1 field_diff and 2 missing_key", kinds)
+	}
+	if summary == nil || summary["matching_keys"] != 4.0 {
+		t.Errorf("Summary got = %v, want 4 matching keys", summary)
+	}
+	if last := messages[len(messages)-1]; last["id"] != 2.0 {
+		t.Errorf("Last message got = %v, want the shutdown response", last)
+	}
+}
+
+func TestServe_Errors(t *testing.T) {
+	messages := serve(t,
+		`not json`,
+		`{"jsonrpc": "2.0", "id": 1, "method": "explode"}`,
+		`{"jsonrpc": "2.0", "id": 2, "method": "cancel", "params": {"job": 7}}`,
+		`{"jsonrpc": "2.0", "id": 3, "method": "start", "params": {"config1": "missing.yaml", "config2": "missing.yaml"}}`,
+		`{"id": 4, "method": "start"}`,
+		`{"jsonrpc": "2.0", "id": 5, "method": "snapshot", "params": {"job": 7}}`,
+		`{"jsonrpc": "2.0", "id": 6, "method": "pause", "params": {"job": 7}}`,
+	)
+
+	want := []int{CodeParseError, CodeMethodNotFound, CodeInvalidParams, CodeJobFailed, CodeInvalidRequest, CodeInvalidParams, CodeInvalidParams}
+	if len(messages) != len(want) {
+		t.Fatalf("Got %d messages, want %d: %v", len(messages), len(want), messages)
+	}
+	for i, msg := range messages {
+		rpcErr, ok := msg["error"].(map[string]interface{})
+		if !ok || rpcErr["code"] != float64(want[i]) {
+			t.Errorf("Message %d got = %v, want error code %d", i, msg, want[i])
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package schema
+
+import (
+	"data-comparator/internal/pkg/datareader"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// MinRenameConfidence is the confidence from which a field only in the
+// first schema and one only in the second are reported as a probable rename.
+const MinRenameConfidence = 0.6
+
+// maxProfileValues is the number of distinct sampled values kept per field
+// for detecting renames.
+const maxProfileValues = 1000
+
+// DiffOptions configures how two schemas are compared.
+type DiffOptions struct {
+	// FoldNames treats field names that differ only in case and word
+	// separators, such as createdAt, created_at and CreatedAt, as the same
+	// field, reported as renamed.
+	FoldNames bool
+}
+
+// SchemaDiff lists how the fields of two schemas differ.
+type SchemaDiff struct {
+	// MissingFields are only in the first schema.
+	MissingFields []string `yaml:"missing_fields,omitempty"`
+	// ExtraFields are only in the second schema.
+	ExtraFields []string     `yaml:"extra_fields,omitempty"`
+	Renames     []Rename     `yaml:"renames,omitempty"`
+	TypeChanges []TypeChange `yaml:"type_changes,omitempty"`
+	// ProbableRenames pair fields only in one schema each whose sampled
+	// values look alike.
+	ProbableRenames []ProbableRename `yaml:"probable_renames,omitempty"`
+}
+
+// Rename is a field named differently in the second schema.
+type Rename struct {
+	Source1Field string `yaml:"source1_field"`
+	Source2Field string `yaml:"source2_field"`
+}
+
+// ProbableRename is a field likely renamed in the second schema, judged by
+// its values, with a confidence from 0 to 1.
+type ProbableRename struct {
+	Source1Field string  `yaml:"source1_field"`
+	Source2Field string  `yaml:"source2_field"`
+	Confidence   float64 `yaml:"confidence"`
+}
+
+// TypeChange is a field of a different type in the second schema, named as
+// in the first.
+type TypeChange struct {
+	Field       string `yaml:"field"`
+	Source1Type string `yaml:"source1_type"`
+	Source2Type string `yaml:"source2_type"`
+}
+
+// Empty reports whether the schemas have the same fields of the same types.
+func (d *SchemaDiff) Empty() bool {
+	return len(d.MissingFields) == 0 && len(d.ExtraFields) == 0 && len(d.Renames) == 0 &&
+		len(d.TypeChanges) == 0 && len(d.ProbableRenames) == 0
+}
+
+// Diff compares the fields of s1 and s2. Fields present under the same name
+// in both are matched first; with FoldNames, those left are matched by their
+// folded names, in name order. Of those still left, fields of the same type
+// whose sampled values are alike are paired as probable renames, the most
+// confident first.
+func Diff(s1, s2 *Schema, options DiffOptions) *SchemaDiff {
+	diff := &SchemaDiff{}
+	var left1, left2 []string
+	for _, name := range sortedFields(s1) {
+		if field2, ok := s2.Fields[name]; ok {
+			diff.compareTypes(name, s1.Fields[name], field2)
+		} else {
+			left1 = append(left1, name)
+		}
+	}
+	for _, name := range sortedFields(s2) {
+		if _, ok := s1.Fields[name]; !ok {
+			left2 = append(left2, name)
+		}
+	}
+
+	if options.FoldNames {
+		byFolded := make(map[string][]string)
+		for _, name := range left2 {
+			folded := FoldFieldName(name)
+			byFolded[folded] = append(byFolded[folded], name)
+		}
+		renamed := make(map[string]bool)
+		var missing []string
+		for _, name := range left1 {
+			folded := FoldFieldName(name)
+			candidates := byFolded[folded]
+			if len(candidates) == 0 {
+				missing = append(missing, name)
+				continue
+			}
+			name2 := candidates[0]
+			byFolded[folded] = candidates[1:]
+			renamed[name2] = true
+			diff.Renames = append(diff.Renames, Rename{Source1Field: name, Source2Field: name2})
+			diff.compareTypes(name, s1.Fields[name], s2.Fields[name2])
+		}
+		left1 = missing
+		var extra []string
+		for _, name := range left2 {
+			if !renamed[name] {
+				extra = append(extra, name)
+			}
+		}
+		left2 = extra
+	}
+	diff.MissingFields, diff.ExtraFields = diff.matchValues(s1, s2, left1, left2)
+	return diff
+}
+
+// matchValues pairs fields of left1 and left2 as probable renames and
+// returns those left unpaired.
+func (d *SchemaDiff) matchValues(s1, s2 *Schema, left1, left2 []string) ([]string, []string) {
+	var candidates []ProbableRename
+	for _, name1 := range left1 {
+		for _, name2 := range left2 {
+			confidence, ok := renameConfidence(s1.Fields[name1], s2.Fields[name2])
+			if ok && confidence >= MinRenameConfidence {
+				candidates = append(candidates, ProbableRename{Source1Field: name1, Source2Field: name2, Confidence: confidence})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return candidates[a].Confidence > candidates[b].Confidence
+	})
+
+	paired := make(map[string]bool)
+	for _, candidate := range candidates {
+		if paired["1:"+candidate.Source1Field] || paired["2:"+candidate.Source2Field] {
+			continue
+		}
+		paired["1:"+candidate.Source1Field], paired["2:"+candidate.Source2Field] = true, true
+		d.ProbableRenames = append(d.ProbableRenames, candidate)
+	}
+	sort.Slice(d.ProbableRenames, func(a, b int) bool {
+		return d.ProbableRenames[a].Source1Field < d.ProbableRenames[b].Source1Field
+	})
+
+	var missing, extra []string
+	for _, name := range left1 {
+		if !paired["1:"+name] {
+			missing = append(missing, name)
+		}
+	}
+	for _, name := range left2 {
+		if !paired["2:"+name] {
+			extra = append(extra, name)
+		}
+	}
+	return missing, extra
+}
+
+// renameConfidence rates how likely field2 is field1 renamed. Both must be
+// scalars of the same type with sampled values or examples. With the
+// profiles of generated schemas, the overlap of the distinct values counts
+// most, then how often the fields are present and how distinct their values
+// are; with examples only, their overlap is the confidence.
+func renameConfidence(field1, field2 *Field) (float64, bool) {
+	if field1 == nil || field2 == nil || field1.Type != field2.Type || field1.Type == "object" || field1.Type == "array" {
+		return 0, false
+	}
+	p1, p2 := field1.profile, field2.profile
+	if p1 == nil || p2 == nil {
+		p1, p2 = examplesProfile(field1), examplesProfile(field2)
+	}
+	if len(p1.values) == 0 || len(p2.values) == 0 {
+		return 0, false
+	}
+
+	shared := 0
+	for v := range p1.values {
+		if p2.values[v] {
+			shared++
+		}
+	}
+	overlap := float64(shared) / float64(len(p1.values)+len(p2.values)-shared)
+	confidence := overlap
+	if field1.profile != nil && field2.profile != nil {
+		presence := 1 - math.Abs(p1.presence-p2.presence)
+		distinct := math.Min(p1.distinct, p2.distinct) / math.Max(p1.distinct, p2.distinct)
+		confidence = 0.6*overlap + 0.2*presence + 0.2*distinct
+	}
+	return math.Round(confidence*100) / 100, true
+}
+
+// valueProfile summarizes the sampled values of a field.
+type valueProfile struct {
+	// values are the first distinct values, formatted.
+	values map[string]bool
+	// presence is the share of sampled records with a value.
+	presence float64
+	// distinct is the share of distinct values among the kept ones.
+	distinct float64
+}
+
+func newValueProfile(values []interface{}, recordCount int) *valueProfile {
+	p := &valueProfile{values: make(map[string]bool)}
+	kept := 0
+	for _, v := range values {
+		switch v.(type) {
+		case datareader.Record, map[string]interface{}, []interface{}:
+			continue
+		}
+		s := fmt.Sprintf("%v", v)
+		if !p.values[s] && len(p.values) == maxProfileValues {
+			break
+		}
+		p.values[s] = true
+		kept++
+	}
+	if recordCount > 0 {
+		p.presence = math.Min(1, float64(len(values))/float64(recordCount))
+	}
+	if kept > 0 {
+		p.distinct = float64(len(p.values)) / float64(kept)
+	}
+	return p
+}
+
+// examplesProfile profiles the examples of a loaded schema's field.
+func examplesProfile(field *Field) *valueProfile {
+	p := &valueProfile{values: make(map[string]bool)}
+	for _, v := range field.Examples {
+		p.values[fmt.Sprintf("%v", v)] = true
+	}
+	return p
+}
+
+func (d *SchemaDiff) compareTypes(name string, field1, field2 *Field) {
+	if field1 == nil || field2 == nil || field1.Type == field2.Type {
+		return
+	}
+	d.TypeChanges = append(d.TypeChanges, TypeChange{Field: name, Source1Type: field1.Type, Source2Type: field2.Type})
+}
+
+// FoldFieldName lowercases a dotted field name and drops the word separators
+// of its segments, so "Order.createdAt" and "order.created_at" fold alike.
+// Path separators and the [] of array elements are kept.
+func FoldFieldName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '.' || r == '[' || r == ']':
+			return r
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			return unicode.ToLower(r)
+		default:
+			return -1
+		}
+	}, name)
+}
+
+func sortedFields(s *Schema) []string {
+	names := make([]string, 0, len(s.Fields))
+	for name := range s.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,98 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxExampleLength is the number of characters kept of an example value.
+const maxExampleLength = 64
+
+// addExamples keeps the first n distinct scalar values of each field as its
+// examples. Fields matched by redact, by name or "*" for all, keep only the
+// shape of their values.
+func addExamples(fields map[string]*Field, fieldValues map[string][]interface{}, n int, redact []string) {
+	if n <= 0 {
+		return
+	}
+	redactAll := false
+	redacted := make(map[string]bool, len(redact))
+	for _, name := range redact {
+		redactAll = redactAll || name == "*"
+		redacted[name] = true
+	}
+
+	for name, field := range fields {
+		if field.Type == "object" || field.Type == "array" {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, v := range fieldValues[name] {
+			example, ok := exampleValue(v)
+			if !ok {
+				continue
+			}
+			if redactAll || redacted[name] {
+				example = redactExample(fmt.Sprintf("%v", example))
+			}
+			key := fmt.Sprintf("%T:%v", example, example)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			field.Examples = append(field.Examples, example)
+			if len(field.Examples) == n {
+				break
+			}
+		}
+	}
+}
+
+// exampleValue returns v as written to the schema, or false for values that
+// make no example, such as nested records.
+func exampleValue(v interface{}) (interface{}, bool) {
+	switch val := v.(type) {
+	case nil, map[string]interface{}, []interface{}:
+		return nil, false
+	case string:
+		return truncateExample(val), true
+	case bool, int, int64, float64:
+		return val, true
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i, true
+		}
+		return val.String(), true
+	case time.Time:
+		return val.Format(time.RFC3339Nano), true
+	default:
+		return truncateExample(fmt.Sprintf("%v", val)), true
+	}
+}
+
+func truncateExample(s string) string {
+	if utf8.RuneCountInString(s) <= maxExampleLength {
+		return s
+	}
+	return string([]rune(s)[:maxExampleLength]) + "…"
+}
+
+// redactExample masks a value while keeping its shape: letters become x or
+// X and digits 9, so formats stay recognizable without revealing data.
+func redactExample(s string) string {
+	out := []rune(s)
+	for i, r := range out {
+		switch {
+		case unicode.IsUpper(r):
+			out[i] = 'X'
+		case unicode.IsLetter(r):
+			out[i] = 'x'
+		case unicode.IsDigit(r):
+			out[i] = '9'
+		}
+	}
+	return string(out)
+}
@@ -5,13 +5,23 @@ import (
 	"data-comparator/internal/pkg/datareader"
 	"fmt"
 	"io"
+	"math"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // DefaultSampleSize is the number of records to sample if not specified in the config.
 const DefaultSampleSize = 1000
 
+// dateTimeLayouts are the layouts a value may match to be considered a datetime.
+var dateTimeLayouts = []string{
+	time.RFC3339, time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02", "01/02/2006",
+}
+
 // Generate creates a schema by sampling records from a data reader.
 func Generate(reader datareader.DataReader, samplerConfig *config.Sampler) (*Schema, error) {
 	sampleSize := DefaultSampleSize
@@ -28,38 +38,179 @@ func Generate(reader datareader.DataReader, samplerConfig *config.Sampler) (*Sch
 	}
 
 	fieldValues := make(map[string][]interface{})
+	collector := NewCollector(samplerConfig)
 	for _, record := range records {
-		CollectFieldValues(record, fieldValues)
+		collector.Collect(record, fieldValues)
 	}
 
-	fields := analyzeFields(fieldValues)
+	fields := analyzeFields(fieldValues, len(records))
+	if samplerConfig != nil {
+		addExamples(fields, fieldValues, samplerConfig.Examples, samplerConfig.RedactExamples)
+	}
 	schema := &Schema{
-		Fields: fields,
+		Key:      identifyKey(fieldValues, fields, len(records)),
+		Fields:   fields,
+		Warnings: collector.Warnings(),
+	}
+	if w, ok := reader.(datareader.Warner); ok {
+		schema.Warnings = append(w.Warnings(), schema.Warnings...)
 	}
 
-	// TODO: Implement key identification
 	return schema, nil
 }
 
-func analyzeFields(fieldValues map[string][]interface{}) map[string]*Field {
+// identifyKey picks a candidate key: a top-level scalar field present in every
+// sampled record with unique values. Fields named like an ID are preferred,
+// then the alphabetically first candidate. It returns "" if none qualifies.
+func identifyKey(fieldValues map[string][]interface{}, fields map[string]*Field, recordCount int) string {
+	best, bestRank := "", -1
+	for name, values := range fieldValues {
+		if strings.ContainsAny(name, ".[") || len(values) != recordCount {
+			continue
+		}
+		if t := fields[name].Type; t == "object" || t == "array" {
+			continue
+		}
+		if !allUnique(values) {
+			continue
+		}
+
+		rank := 0
+		lower := strings.ToLower(name)
+		switch {
+		case lower == "id":
+			rank = 2
+		case strings.HasSuffix(lower, "id"):
+			rank = 1
+		}
+		if rank > bestRank || (rank == bestRank && name < best) {
+			best, bestRank = name, rank
+		}
+	}
+	return best
+}
+
+func allUnique(values []interface{}) bool {
+	seen := make(map[string]struct{}, len(values))
+	for _, val := range values {
+		s := fmt.Sprintf("%v", val)
+		if _, ok := seen[s]; ok {
+			return false
+		}
+		seen[s] = struct{}{}
+	}
+	return true
+}
+
+func analyzeFields(fieldValues map[string][]interface{}, recordCount int) map[string]*Field {
 	fields := make(map[string]*Field)
 	for name, values := range fieldValues {
+		fieldType := inferType(values)
 		fields[name] = &Field{
-			Type:  inferType(values),
-			Stats: []string{}, // TODO: Calculate stats based on type
+			Type:         fieldType,
+			Stats:        []string{}, // TODO: Calculate stats based on type
+			Matchers:     inferMatchers(name, fieldType, values, recordCount),
+			LeadingZeros: hasLeadingZeros(values),
+			profile:      newValueProfile(values, recordCount),
 		}
 	}
 	return fields
 }
 
+// maxOneOfValues is the largest number of distinct string values inferred as
+// an allowed-value set.
+const maxOneOfValues = 10
+
+// inferMatchers derives constraints from the sampled values of a field: notNull
+// when every record has a value, the numeric range of numeric fields, and the
+// length range and, for low-cardinality fields, allowed values of strings.
+func inferMatchers(name, fieldType string, values []interface{}, recordCount int) []Matcher {
+	var matchers []Matcher
+	if len(values) == recordCount && !strings.Contains(name, "[]") {
+		matchers = append(matchers, Matcher{"notNull": true})
+	}
+
+	switch fieldType {
+	case "numeric":
+		matchers = append(matchers, numericRange(values)...)
+	case "string":
+		minLen, maxLen := math.MaxInt, 0
+		distinct := make(map[string]struct{})
+		for _, val := range values {
+			s := fmt.Sprintf("%v", val)
+			n := utf8.RuneCountInString(s)
+			minLen, maxLen = min(minLen, n), max(maxLen, n)
+			distinct[s] = struct{}{}
+		}
+		matchers = append(matchers, Matcher{"minLength": minLen}, Matcher{"maxLength": maxLen})
+
+		// Only sets whose values repeat look like enumerations rather than free text.
+		if len(distinct) <= maxOneOfValues && len(values) >= 2*len(distinct) {
+			allowed := make([]string, 0, len(distinct))
+			for s := range distinct {
+				allowed = append(allowed, s)
+			}
+			sort.Strings(allowed)
+			oneOf := make([]interface{}, len(allowed))
+			for i, s := range allowed {
+				oneOf[i] = s
+			}
+			matchers = append(matchers, Matcher{"oneOf": oneOf})
+		}
+	}
+	return matchers
+}
+
+// numericRange returns the min and max matchers of numeric values. They are
+// integers when every value is one, as float64 would round those beyond 2^53.
+func numericRange(values []interface{}) []Matcher {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	var intLo, intHi int64 = math.MaxInt64, math.MinInt64
+	ints := true
+	for _, val := range values {
+		if val == nil {
+			continue
+		}
+		s := fmt.Sprintf("%v", val)
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			lo, hi = math.Min(lo, f), math.Max(hi, f)
+		}
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			intLo, intHi = min(intLo, n), max(intHi, n)
+		} else {
+			ints = false
+		}
+	}
+	if ints && intLo <= intHi {
+		return []Matcher{{"min": intLo}, {"max": intHi}}
+	}
+	return []Matcher{{"min": lo}, {"max": hi}}
+}
+
+// hasLeadingZeros reports whether any value is a numeric string with a
+// significant leading zero, like "007" or "-0123".
+func hasLeadingZeros(values []interface{}) bool {
+	for _, val := range values {
+		s, ok := val.(string)
+		if !ok {
+			continue
+		}
+		s = strings.TrimLeft(s, "+-")
+		if len(s) < 2 || s[0] != '0' || s[1] < '0' || s[1] > '9' {
+			continue
+		}
+		if _, err := strconv.ParseFloat(s, 64); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
 func inferType(values []interface{}) string {
 	if len(values) == 0 {
 		return "unknown"
 	}
 	isNumeric, isDateTime, isObject, isArray := true, true, true, true
-	dateTimeLayouts := []string{
-		time.RFC3339, time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02", "01/02/2006",
-	}
 	nonNilCount := 0
 	for _, val := range values {
 		if val == nil {
@@ -76,14 +227,7 @@ func inferType(values []interface{}) string {
 		if _, err := strconv.ParseFloat(sVal, 64); err != nil {
 			isNumeric = false
 		}
-		canBeDateTime := false
-		for _, layout := range dateTimeLayouts {
-			if _, err := time.Parse(layout, sVal); err == nil {
-				canBeDateTime = true
-				break
-			}
-		}
-		if !canBeDateTime {
+		if !isDateTimeValue(val) {
 			isDateTime = false
 		}
 	}
@@ -105,6 +249,24 @@ func inferType(values []interface{}) string {
 	return "string"
 }
 
+// isDateTimeValue reports whether a value is a time.Time, as read from typed
+// sources such as Parquet and Avro, or a datetime string.
+func isDateTimeValue(v interface{}) bool {
+	if _, ok := v.(time.Time); ok {
+		return true
+	}
+	return isDateTimeString(fmt.Sprintf("%v", v))
+}
+
+func isDateTimeString(s string) bool {
+	for _, layout := range dateTimeLayouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
 func sampleRecords(reader datareader.DataReader, sampleSize int) ([]datareader.Record, error) {
 	var records []datareader.Record
 	for i := 0; i < sampleSize; i++ {
@@ -120,66 +282,121 @@ func sampleRecords(reader datareader.DataReader, sampleSize int) ([]datareader.R
 	return records, nil
 }
 
-type workItem struct {
-	data   interface{}
-	prefix string
+// DefaultMaxDepth is the nesting depth up to which values are flattened into fields.
+const DefaultMaxDepth = 32
+
+// DefaultMaxFields is the number of flattened fields collected.
+const DefaultMaxFields = 10000
+
+// Collector flattens records into the values of their fields, with nested
+// fields named by dotted paths and array elements by a "[]" suffix. It stops
+// at defensive cutoffs instead of following pathological data, and counts
+// what it cut off.
+type Collector struct {
+	// MaxDepth is the nesting depth flattened, top-level fields being at
+	// depth 1. Deeper containers are kept as values of their field.
+	MaxDepth int
+	// MaxFields is the number of distinct fields collected; values of
+	// further fields are dropped.
+	MaxFields int
+
+	tooDeep   int
+	dropped   int
+	cycles    int
+	ancestors map[uintptr]bool
+}
+
+// NewCollector creates a Collector with the limits of samplerConfig, or the
+// default limits where it sets none.
+func NewCollector(samplerConfig *config.Sampler) *Collector {
+	c := &Collector{MaxDepth: DefaultMaxDepth, MaxFields: DefaultMaxFields}
+	if samplerConfig != nil && samplerConfig.MaxDepth > 0 {
+		c.MaxDepth = samplerConfig.MaxDepth
+	}
+	if samplerConfig != nil && samplerConfig.MaxFields > 0 {
+		c.MaxFields = samplerConfig.MaxFields
+	}
+	return c
 }
 
+// CollectFieldValues flattens data into fieldValues with the default limits.
 func CollectFieldValues(data interface{}, fieldValues map[string][]interface{}) {
-	queue := []workItem{{data: data, prefix: ""}}
+	NewCollector(nil).Collect(data, fieldValues)
+}
 
-	for len(queue) > 0 {
-		item := queue[0]
-		queue = queue[1:]
+// Collect flattens data, a record or nested value, into fieldValues.
+func (c *Collector) Collect(data interface{}, fieldValues map[string][]interface{}) {
+	c.ancestors = make(map[uintptr]bool)
+	c.collect(data, "", 0, fieldValues)
+}
 
-		if item.data == nil {
-			continue
+func (c *Collector) collect(data interface{}, prefix string, depth int, fieldValues map[string][]interface{}) {
+	if data == nil {
+		return
+	}
+	if prefix != "" {
+		if _, ok := fieldValues[prefix]; !ok && len(fieldValues) >= c.MaxFields {
+			c.dropped++
+			return
 		}
+		fieldValues[prefix] = append(fieldValues[prefix], data)
+	}
 
-		var m map[string]interface{}
-		var ok bool
-		if record, isRecord := item.data.(datareader.Record); isRecord {
-			m = map[string]interface{}(record)
-			ok = true
-		} else if mapData, isMap := item.data.(map[string]interface{}); isMap {
-			m = mapData
-			ok = true
-		}
-		
-		if ok {
-			if item.prefix != "" {
-				fieldValues[item.prefix] = append(fieldValues[item.prefix], m)
-			}
-			for key, value := range m {
-				newKey := key
-				if item.prefix != "" {
-					newKey = item.prefix + "." + key
-				}
-				queue = append(queue, workItem{data: value, prefix: newKey})
-			}
-		} else if r, ok := item.data.(datareader.Record); ok {
-			if item.prefix != "" {
-				fieldValues[item.prefix] = append(fieldValues[item.prefix], r)
-			}
-			for key, value := range r {
-				newKey := key
-				if item.prefix != "" {
-					newKey = item.prefix + "." + key
-				}
-				queue = append(queue, workItem{data: value, prefix: newKey})
-			}
-		} else if a, ok := item.data.([]interface{}); ok {
-			if item.prefix != "" {
-				fieldValues[item.prefix] = append(fieldValues[item.prefix], a)
-			}
-			arrayKey := item.prefix + "[]"
-			for _, v := range a {
-				queue = append(queue, workItem{data: v, prefix: arrayKey})
-			}
-		} else {
-			if item.prefix != "" {
-				fieldValues[item.prefix] = append(fieldValues[item.prefix], item.data)
+	var m map[string]interface{}
+	var a []interface{}
+	switch v := data.(type) {
+	case datareader.Record:
+		m = v
+	case map[string]interface{}:
+		m = v
+	case []interface{}:
+		a = v
+	default:
+		return
+	}
+	if m == nil && len(a) == 0 {
+		return
+	}
+	if prefix != "" && depth >= c.MaxDepth {
+		c.tooDeep++
+		return
+	}
+
+	// A container holding itself, directly or not, would be flattened forever.
+	ptr := reflect.ValueOf(data).Pointer()
+	if c.ancestors[ptr] {
+		c.cycles++
+		return
+	}
+	c.ancestors[ptr] = true
+	defer delete(c.ancestors, ptr)
+
+	if m != nil {
+		for key, value := range m {
+			name := key
+			if prefix != "" {
+				name = prefix + "." + key
 			}
+			c.collect(value, name, depth+1, fieldValues)
 		}
+		return
+	}
+	for _, value := range a {
+		c.collect(value, prefix+"[]", depth+1, fieldValues)
+	}
+}
+
+// Warnings describes what the collector cut off.
+func (c *Collector) Warnings() []string {
+	var warnings []string
+	if c.tooDeep > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d values nested deeper than %d levels were not flattened", c.tooDeep, c.MaxDepth))
+	}
+	if c.dropped > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d values of fields beyond the first %d were dropped", c.dropped, c.MaxFields))
+	}
+	if c.cycles > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d values containing themselves were not flattened", c.cycles))
 	}
+	return warnings
 }
@@ -1,19 +1,91 @@
 package schema
 
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
 // Schema represents the learned or defined structure of a data source.
 type Schema struct {
-	Key        string           `yaml:"key"`
-	MaxKeySize int              `yaml:"max_key_size,omitempty"`
+	Key        string            `yaml:"key"`
+	MaxKeySize int               `yaml:"max_key_size,omitempty"`
 	Fields     map[string]*Field `yaml:"fields"`
+	// Warnings describe the cutoffs hit while generating the schema, such as
+	// values nested too deeply to flatten.
+	Warnings []string `yaml:"warnings,omitempty"`
 }
 
 // Field represents the schema for a single field within the data source.
 type Field struct {
-	Type     string      `yaml:"type"`
-	Stats    []string    `yaml:"stats,omitempty"`
+	Type     string    `yaml:"type"`
+	Stats    []string  `yaml:"stats,omitempty"`
 	Matchers []Matcher `yaml:"matchers,omitempty"`
+	// LeadingZeros is set when sampled values such as "00123" would lose
+	// their leading zeros if treated as numbers.
+	LeadingZeros bool `yaml:"leading_zeros,omitempty"`
+	// Examples are the first distinct sampled values, when the sampler keeps
+	// examples. Redacted examples only keep the shape of the values.
+	Examples []interface{} `yaml:"examples,omitempty"`
+
+	// Annotations are written by humans and never inferred.
+	Description string   `yaml:"description,omitempty"`
+	Tags        []string `yaml:"tags,omitempty"`
+	Owner       string   `yaml:"owner,omitempty"`
+
+	// profile summarizes the sampled values of generated schemas, for
+	// detecting renamed fields. It is not written.
+	profile *valueProfile
 }
 
 // Matcher is a flexible map to represent matcher configurations,
 // e.g., {"isNumeric": true} or {"regex": "pattern"}.
 type Matcher map[string]interface{}
+
+// Load reads a schema YAML file, such as a previously generated and edited one.
+func Load(filePath string) (*Schema, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema file %s: %w", filePath, err)
+	}
+
+	var s Schema
+	err = yaml.Unmarshal(data, &s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal yaml from %s: %w", filePath, err)
+	}
+	if s.Fields == nil {
+		s.Fields = make(map[string]*Field)
+	}
+
+	return &s, nil
+}
+
+// MergeAnnotations copies the human annotations of fields present in both
+// schemas from previous into s, so regenerating a schema keeps them.
+func (s *Schema) MergeAnnotations(previous *Schema) {
+	if previous == nil {
+		return
+	}
+	for name, prev := range previous.Fields {
+		field, ok := s.Fields[name]
+		if !ok || prev == nil {
+			continue
+		}
+		field.Description = prev.Description
+		field.Tags = prev.Tags
+		field.Owner = prev.Owner
+	}
+}
+
+// FieldsByTag returns the names of annotated fields grouped by tag.
+func (s *Schema) FieldsByTag() map[string][]string {
+	byTag := make(map[string][]string)
+	for name, field := range s.Fields {
+		for _, tag := range field.Tags {
+			byTag[tag] = append(byTag[tag], name)
+		}
+	}
+	return byTag
+}
@@ -3,8 +3,12 @@ package schema
 import (
 	"data-comparator/internal/pkg/config"
 	"data-comparator/internal/pkg/datareader"
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestGenerate_SimpleCSV(t *testing.T) {
@@ -43,6 +47,10 @@ func TestGenerate_SimpleCSV(t *testing.T) {
 		"last_login": "datetime",
 	}
 
+	if schema.Key != "user_id" {
+		t.Errorf("Key got = %q, want %q", schema.Key, "user_id")
+	}
+
 	for fieldName, expectedType := range expectedTypes {
 		field, ok := schema.Fields[fieldName]
 		if !ok {
@@ -75,3 +83,371 @@ func TestCollectFieldValues(t *testing.T) {
 		t.Errorf("Expected %d fields, got %d. Keys: %v", len(expectedKeys), len(fieldValues), reflect.ValueOf(fieldValues).MapKeys())
 	}
 }
+
+func TestCollector_Cutoffs(t *testing.T) {
+	t.Run("depth", func(t *testing.T) {
+		record := map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"c": 1}}}
+		fieldValues := make(map[string][]interface{})
+		c := &Collector{MaxDepth: 2, MaxFields: 10}
+		c.Collect(record, fieldValues)
+		if _, ok := fieldValues["a.b"]; !ok {
+			t.Errorf("Expected a.b to be collected as a value, got %v", fieldValues)
+		}
+		if _, ok := fieldValues["a.b.c"]; ok {
+			t.Errorf("Expected a.b.c beyond the max depth not to be collected")
+		}
+		if w := c.Warnings(); len(w) != 1 {
+			t.Errorf("Warnings() got = %v, want one", w)
+		}
+	})
+
+	t.Run("fields", func(t *testing.T) {
+		fieldValues := make(map[string][]interface{})
+		c := &Collector{MaxDepth: 10, MaxFields: 2}
+		c.Collect(map[string]interface{}{"a": 1, "b": 2, "c": 3}, fieldValues)
+		c.Collect(map[string]interface{}{"a": 1, "b": 2, "c": 3}, fieldValues)
+		if len(fieldValues) != 2 {
+			t.Errorf("Expected 2 fields, got %v", fieldValues)
+		}
+		for name, values := range fieldValues {
+			if len(values) != 2 {
+				t.Errorf("Expected collected field %s to keep its values, got %v", name, values)
+			}
+		}
+	})
+
+	t.Run("cycles", func(t *testing.T) {
+		self := map[string]interface{}{"id": 1}
+		self["self"] = self
+		list := []interface{}{"x", nil}
+		list[1] = list
+		fieldValues := make(map[string][]interface{})
+		c := NewCollector(nil)
+		c.Collect(map[string]interface{}{"node": self, "list": list}, fieldValues)
+		if _, ok := fieldValues["node.self"]; !ok {
+			t.Errorf("Expected node.self to be collected as a value, got %v", fieldValues)
+		}
+		if _, ok := fieldValues["node.self.id"]; ok {
+			t.Errorf("Expected the cycle not to be followed")
+		}
+		if w := c.Warnings(); len(w) != 1 {
+			t.Errorf("Warnings() got = %v, want one for the cycles", w)
+		}
+	})
+}
+
+func TestGenerate_Examples(t *testing.T) {
+	records := []datareader.Record{
+		{"id": "A-1", "email": "ann@example.com", "n": 1.0, "tags": []interface{}{"x"}},
+		{"id": "A-2", "email": "ann@example.com", "n": 1.0},
+		{"id": "B-3", "email": "bob@example.com", "n": 2.0},
+		{"id": "B-4", "email": nil, "n": 3.0},
+	}
+	s, err := Generate(datareader.NewSliceReader(records), &config.Sampler{Examples: 2, RedactExamples: []string{"email"}})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	want := map[string][]interface{}{
+		"id":     {"A-1", "A-2"},
+		"email":  {"xxx@xxxxxxx.xxx"},
+		"n":      {1.0, 2.0},
+		"tags[]": {"x"},
+	}
+	for name, examples := range want {
+		if got := s.Fields[name].Examples; !reflect.DeepEqual(got, examples) {
+			t.Errorf("Examples of %s got = %v, want %v", name, got, examples)
+		}
+	}
+	if got := s.Fields["tags"].Examples; got != nil {
+		t.Errorf("Examples of an array field got = %v, want none", got)
+	}
+
+	s, err = Generate(datareader.NewSliceReader(records), nil)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if got := s.Fields["id"].Examples; got != nil {
+		t.Errorf("Examples without a sampler setting got = %v, want none", got)
+	}
+}
+
+func TestHasLeadingZeros(t *testing.T) {
+	tests := []struct {
+		values []interface{}
+		want   bool
+	}{
+		{[]interface{}{"00123", "456"}, true},
+		{[]interface{}{"-0123"}, true},
+		{[]interface{}{"0", "0.5", "10"}, false},
+		{[]interface{}{"0abc"}, false},
+		{[]interface{}{float64(7), nil}, false},
+	}
+	for _, tt := range tests {
+		if got := hasLeadingZeros(tt.values); got != tt.want {
+			t.Errorf("hasLeadingZeros(%v) got = %v, want %v", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestLoad_WithAnnotations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.yaml")
+	content := `key: user_id
+fields:
+  email:
+    type: string
+    description: Primary contact address
+    tags: [pii]
+    owner: accounts-team
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write schema: %v", err)
+	}
+
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	email := s.Fields["email"]
+	if email == nil || email.Description != "Primary contact address" || email.Owner != "accounts-team" {
+		t.Fatalf("Annotations not loaded, got %+v", email)
+	}
+
+	generated := &Schema{Fields: map[string]*Field{
+		"email": {Type: "string"},
+		"age":   {Type: "numeric"},
+	}}
+	generated.MergeAnnotations(s)
+	if !reflect.DeepEqual(generated.Fields["email"].Tags, []string{"pii"}) {
+		t.Errorf("MergeAnnotations() tags got = %v, want [pii]", generated.Fields["email"].Tags)
+	}
+	if generated.Fields["email"].Type != "string" {
+		t.Errorf("MergeAnnotations() must keep the inferred type")
+	}
+	if !reflect.DeepEqual(generated.FieldsByTag(), map[string][]string{"pii": {"email"}}) {
+		t.Errorf("FieldsByTag() got = %v", generated.FieldsByTag())
+	}
+}
+
+func TestValidate_PinnedSchema(t *testing.T) {
+	pinned, err := Load("../../../testdata/testcase1_simple_csv/expected_schema.yaml")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	reader, err := datareader.New(config.Source{Type: "csv", Path: "../../../testdata/testcase1_simple_csv/source1.csv"})
+	if err != nil {
+		t.Fatalf("Failed to create data reader: %v", err)
+	}
+	defer reader.Close()
+
+	violations, err := Validate(reader, pinned, nil)
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if len(violations) != 0 {
+		t.Errorf("Expected no violations, got %v", violations)
+	}
+
+	bad := pinned.Check(datareader.Record{"age": "thirty", "email": "not-an-email"})
+	rules := make(map[string]bool)
+	for _, v := range bad {
+		rules[v.Field+"/"+v.Rule] = true
+	}
+	for _, want := range []string{"age/type", "age/isNumeric", "email/regex"} {
+		if !rules[want] {
+			t.Errorf("Expected violation %s, got %v", want, bad)
+		}
+	}
+}
+
+func TestIdentifyKey(t *testing.T) {
+	fieldValues := map[string][]interface{}{
+		"email":      {"a@x.com", "b@x.com", "c@x.com"},
+		"event_id":   {"e1", "e2", "e3"},
+		"city":       {"Paris", "Paris", "Rome"},
+		"partial_id": {"p1", "p2"},
+		"meta.id":    {"m1", "m2", "m3"},
+	}
+	fields := analyzeFields(fieldValues, 3)
+	if got := identifyKey(fieldValues, fields, 3); got != "event_id" {
+		t.Errorf("identifyKey() got = %q, want %q", got, "event_id")
+	}
+
+	delete(fieldValues, "event_id")
+	if got := identifyKey(fieldValues, fields, 3); got != "email" {
+		t.Errorf("identifyKey() got = %q, want %q", got, "email")
+	}
+}
+
+func TestInferMatchers(t *testing.T) {
+	fieldValues := map[string][]interface{}{
+		"age":   {"30", "25", "41", "30"},
+		"plan":  {"basic", "premium", "basic", "basic"},
+		"email": {"a@x.com", "bb@x.com", "c@x.com"},
+		"id":    {json.Number("9007199254740993"), json.Number("9007199254740995")},
+		"score": {"1", "2.5"},
+	}
+	fields := analyzeFields(fieldValues, 4)
+
+	expected := map[string][]Matcher{
+		"age":   {{"notNull": true}, {"min": int64(25)}, {"max": int64(41)}},
+		"id":    {{"min": int64(9007199254740993)}, {"max": int64(9007199254740995)}},
+		"score": {{"min": 1.0}, {"max": 2.5}},
+		"plan":  {{"notNull": true}, {"minLength": 5}, {"maxLength": 7}, {"oneOf": []interface{}{"basic", "premium"}}},
+		"email": {{"minLength": 7}, {"maxLength": 8}},
+	}
+	for name, want := range expected {
+		if got := fields[name].Matchers; !reflect.DeepEqual(got, want) {
+			t.Errorf("Matchers of %s got = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestCheck_ConstraintMatchers(t *testing.T) {
+	s := &Schema{Fields: map[string]*Field{
+		"age":  {Type: "numeric", Matchers: []Matcher{{"min": 18}, {"max": 99.5}}},
+		"code": {Type: "string", Matchers: []Matcher{{"minLength": 2}, {"maxLength": 3}}},
+		"plan": {Type: "string", Matchers: []Matcher{{"oneOf": []interface{}{"basic", "premium"}}}},
+		"name": {Type: "string", Matchers: []Matcher{{"notNull": true}}},
+		"id":   {Type: "numeric", Matchers: []Matcher{{"max": int64(9007199254740992)}}},
+	}}
+
+	tests := []struct {
+		record datareader.Record
+		want   []string
+	}{
+		{datareader.Record{"age": "30", "code": "ab", "plan": "basic", "name": "x"}, nil},
+		{datareader.Record{"age": "17", "code": "a", "plan": "gold", "name": nil}, []string{"age/min", "code/minLength", "name/notNull", "plan/oneOf"}},
+		{datareader.Record{"age": 100, "code": "abcd"}, []string{"age/max", "code/maxLength", "name/notNull"}},
+		{datareader.Record{"id": json.Number("9007199254740993"), "name": "x"}, []string{"id/max"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, v := range s.Check(tt.record) {
+			got = append(got, v.Field+"/"+v.Rule)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Check(%v) got = %v, want %v", tt.record, got, tt.want)
+		}
+	}
+}
+
+func TestGenerate_EmptySource(t *testing.T) {
+	for _, records := range [][]datareader.Record{nil, {{}, {}}} {
+		s, err := Generate(datareader.NewSliceReader(records), nil)
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		if s.Fields == nil || len(s.Fields) != 0 || s.Key != "" {
+			t.Errorf("Generate(%v) got = %+v, want an empty, keyless schema", records, s)
+		}
+	}
+}
+
+func TestGenerate_TypedValues(t *testing.T) {
+	records := []datareader.Record{
+		{"id": int64(1), "created": time.UnixMilli(1700000000000).UTC(), "address": map[string]interface{}{"city": "Berlin"}},
+		{"id": int64(2), "created": time.Unix(0, 0).UTC(), "address": nil},
+	}
+	s, err := Generate(datareader.NewSliceReader(records), nil)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	want := map[string]string{"id": "numeric", "created": "datetime", "address": "object", "address.city": "string"}
+	for name, typ := range want {
+		if field, ok := s.Fields[name]; !ok || field.Type != typ {
+			t.Errorf("Field %s got = %+v, want type %s", name, field, typ)
+		}
+	}
+	if v := s.Check(records[0]); len(v) != 0 {
+		t.Errorf("Check() of a sampled record got = %v, want no violations", v)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	s1 := &Schema{Fields: map[string]*Field{
+		"id":              {Type: "numeric"},
+		"createdAt":       {Type: "datetime"},
+		"Customer.Name":   {Type: "string"},
+		"items[].unitQty": {Type: "numeric"},
+		"legacy":          {Type: "string"},
+	}}
+	s2 := &Schema{Fields: map[string]*Field{
+		"id":                {Type: "string"},
+		"created_at":        {Type: "datetime"},
+		"customer.name":     {Type: "string"},
+		"items[].unit_qty":  {Type: "string"},
+		"added":             {Type: "boolean"},
+		"items.unit_qty":    {Type: "numeric"},
+		"customer_name_raw": {Type: "string"},
+	}}
+
+	got := Diff(s1, s2, DiffOptions{})
+	want := &SchemaDiff{
+		MissingFields: []string{"Customer.Name", "createdAt", "items[].unitQty", "legacy"},
+		ExtraFields:   []string{"added", "created_at", "customer.name", "customer_name_raw", "items.unit_qty", "items[].unit_qty"},
+		TypeChanges:   []TypeChange{{Field: "id", Source1Type: "numeric", Source2Type: "string"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() got = %+v, want %+v", got, want)
+	}
+
+	got = Diff(s1, s2, DiffOptions{FoldNames: true})
+	want = &SchemaDiff{
+		MissingFields: []string{"legacy"},
+		ExtraFields:   []string{"added", "customer_name_raw", "items.unit_qty"},
+		Renames: []Rename{
+			{Source1Field: "Customer.Name", Source2Field: "customer.name"},
+			{Source1Field: "createdAt", Source2Field: "created_at"},
+			{Source1Field: "items[].unitQty", Source2Field: "items[].unit_qty"},
+		},
+		TypeChanges: []TypeChange{
+			{Field: "id", Source1Type: "numeric", Source2Type: "string"},
+			{Field: "items[].unitQty", Source1Type: "numeric", Source2Type: "string"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff(FoldNames) got = %+v, want %+v", got, want)
+	}
+	if !Diff(s1, s1, DiffOptions{}).Empty() {
+		t.Errorf("Diff() of a schema with itself is not empty")
+	}
+}
+
+func TestDiff_ProbableRenames(t *testing.T) {
+	var records1, records2 []datareader.Record
+	for i, email := range []string{"a@x.io", "b@x.io", "c@x.io", "d@x.io"} {
+		records1 = append(records1, datareader.Record{"id": i, "cust_email": email, "note": "n" + email})
+		records2 = append(records2, datareader.Record{"id": i, "email_address": email, "status": "active"})
+	}
+	s1, err := Generate(datareader.NewSliceReader(records1), nil)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	s2, err := Generate(datareader.NewSliceReader(records2), nil)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	got := Diff(s1, s2, DiffOptions{})
+	want := &SchemaDiff{
+		MissingFields:   []string{"note"},
+		ExtraFields:     []string{"status"},
+		ProbableRenames: []ProbableRename{{Source1Field: "cust_email", Source2Field: "email_address", Confidence: 1}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() got = %+v, want %+v", got, want)
+	}
+
+	// Loaded schemas only have their examples to go by.
+	loaded1 := &Schema{Fields: map[string]*Field{"code": {Type: "string", Examples: []interface{}{"DE", "FR", "IT"}}}}
+	loaded2 := &Schema{Fields: map[string]*Field{"country": {Type: "string", Examples: []interface{}{"DE", "FR", "ES"}}}}
+	if got := Diff(loaded1, loaded2, DiffOptions{}); len(got.ProbableRenames) != 0 {
+		t.Errorf("Diff() of half overlapping examples got = %+v, want no probable renames", got.ProbableRenames)
+	}
+	loaded2.Fields["country"].Examples = []interface{}{"DE", "FR", "IT"}
+	if got := Diff(loaded1, loaded2, DiffOptions{}); len(got.ProbableRenames) != 1 || got.ProbableRenames[0].Confidence != 1 {
+		t.Errorf("Diff() of equal examples got = %+v, want a probable rename", got.ProbableRenames)
+	}
+}
@@ -0,0 +1,214 @@
+package schema
+
+import (
+	"data-comparator/internal/pkg/config"
+	"data-comparator/internal/pkg/datareader"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Violation describes a value that does not conform to its field's schema.
+type Violation struct {
+	Record  int         `yaml:"record"`
+	Field   string      `yaml:"field"`
+	Rule    string      `yaml:"rule"`
+	Value   interface{} `yaml:"value"`
+	Message string      `yaml:"message"`
+}
+
+// Validate checks sampled records from the reader against a schema, typically a
+// pinned one, and returns the violations found. Records are numbered from 1.
+func Validate(reader datareader.DataReader, s *Schema, samplerConfig *config.Sampler) ([]Violation, error) {
+	sampleSize := DefaultSampleSize
+	if samplerConfig != nil && samplerConfig.SampleSize > 0 {
+		sampleSize = samplerConfig.SampleSize
+	}
+
+	records, err := sampleRecords(reader, sampleSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample records: %w", err)
+	}
+
+	var violations []Violation
+	for i, record := range records {
+		for _, v := range s.Check(record) {
+			v.Record = i + 1
+			violations = append(violations, v)
+		}
+	}
+	return violations, nil
+}
+
+// Check validates the fields of a single record against their type and matchers.
+// Fields absent from the schema are not checked, and fields absent from the
+// record only violate a notNull matcher.
+func (s *Schema) Check(record datareader.Record) []Violation {
+	fieldValues := make(map[string][]interface{})
+	CollectFieldValues(record, fieldValues)
+
+	names := make([]string, 0, len(s.Fields))
+	for name := range s.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var violations []Violation
+	for _, name := range names {
+		field := s.Fields[name]
+		if field == nil {
+			continue
+		}
+		values, present := fieldValues[name]
+		if !present && field.RequiresValue() {
+			violations = append(violations, Violation{Field: name, Rule: "notNull", Message: "value is missing or null"})
+		}
+		for _, value := range values {
+			violations = append(violations, field.check(name, value)...)
+		}
+	}
+	return violations
+}
+
+// RequiresValue reports whether the field has a notNull matcher.
+func (f *Field) RequiresValue() bool {
+	for _, matcher := range f.Matchers {
+		if matcher["notNull"] == true {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Field) check(name string, value interface{}) []Violation {
+	if value == nil {
+		return nil
+	}
+
+	var violations []Violation
+	if !matchesType(value, f.Type) {
+		violations = append(violations, Violation{
+			Field:   name,
+			Rule:    "type",
+			Value:   value,
+			Message: fmt.Sprintf("value is not of type %s", f.Type),
+		})
+	}
+	for _, matcher := range f.Matchers {
+		for rule, arg := range matcher {
+			if msg := applyMatcher(rule, arg, value); msg != "" {
+				violations = append(violations, Violation{Field: name, Rule: rule, Value: value, Message: msg})
+			}
+		}
+	}
+	return violations
+}
+
+func matchesType(value interface{}, fieldType string) bool {
+	switch fieldType {
+	case "numeric":
+		_, err := strconv.ParseFloat(fmt.Sprintf("%v", value), 64)
+		return err == nil
+	case "datetime":
+		return isDateTimeValue(value)
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	default:
+		return true
+	}
+}
+
+// applyMatcher evaluates one matcher rule and returns a message describing the
+// violation, or "" when the value matches. Unknown rules are ignored.
+func applyMatcher(rule string, arg, value interface{}) string {
+	sVal := fmt.Sprintf("%v", value)
+	switch rule {
+	case "isNumeric":
+		if arg == true && !matchesType(value, "numeric") {
+			return "value is not numeric"
+		}
+	case "isDateTime":
+		if arg == true && !matchesType(value, "datetime") {
+			return "value is not a datetime"
+		}
+	case "regex":
+		pattern, ok := arg.(string)
+		if !ok {
+			return fmt.Sprintf("invalid regex matcher %v", arg)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Sprintf("invalid regex %q: %v", pattern, err)
+		}
+		if !re.MatchString(sVal) {
+			return fmt.Sprintf("value does not match %q", pattern)
+		}
+	case "min", "max":
+		limit, ok := toFloat(arg)
+		if !ok {
+			return fmt.Sprintf("invalid %s matcher %v", rule, arg)
+		}
+		n, ok := toFloat(value)
+		if !ok {
+			return "" // reported by the type check
+		}
+		// Integers are compared exactly, as float64 rounds them beyond 2^53.
+		if intLimit, ok := toInt(arg); ok {
+			if i, ok := toInt(value); ok {
+				if rule == "min" && i < intLimit {
+					return fmt.Sprintf("value is less than %v", arg)
+				}
+				if rule == "max" && i > intLimit {
+					return fmt.Sprintf("value is greater than %v", arg)
+				}
+				return ""
+			}
+		}
+		if rule == "min" && n < limit {
+			return fmt.Sprintf("value is less than %v", arg)
+		}
+		if rule == "max" && n > limit {
+			return fmt.Sprintf("value is greater than %v", arg)
+		}
+	case "minLength", "maxLength":
+		limit, ok := arg.(int)
+		if !ok {
+			return fmt.Sprintf("invalid %s matcher %v", rule, arg)
+		}
+		length := utf8.RuneCountInString(sVal)
+		if rule == "minLength" && length < limit {
+			return fmt.Sprintf("value is shorter than %d characters", limit)
+		}
+		if rule == "maxLength" && length > limit {
+			return fmt.Sprintf("value is longer than %d characters", limit)
+		}
+	case "oneOf":
+		allowed, ok := arg.([]interface{})
+		if !ok {
+			return fmt.Sprintf("invalid oneOf matcher %v", arg)
+		}
+		for _, a := range allowed {
+			if fmt.Sprintf("%v", a) == sVal {
+				return ""
+			}
+		}
+		return fmt.Sprintf("value is not one of %v", allowed)
+	}
+	return ""
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	f, err := strconv.ParseFloat(fmt.Sprintf("%v", v), 64)
+	return f, err == nil
+}
+
+func toInt(v interface{}) (int64, bool) {
+	i, err := strconv.ParseInt(fmt.Sprintf("%v", v), 10, 64)
+	return i, err == nil
+}
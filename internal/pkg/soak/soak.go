@@ -0,0 +1,275 @@
+// Package soak runs a time-boxed self-test of the streaming comparator:
+// paired generators feed it records at a fixed rate, source2 diverging from
+// source1 in known ways, and every finding is checked against the divergence
+// injected. Verify checks any comparison result against a log of the faults
+// planted in its sources the same way.
+package soak
+
+import (
+	"data-comparator/internal/pkg/comparator"
+	"data-comparator/internal/pkg/datareader"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"strconv"
+	"time"
+)
+
+// Defaults of the shares of records diverging in source2.
+const (
+	DefaultChanged = 0.01
+	DefaultDropped = 0.001
+	DefaultAdded   = 0.001
+)
+
+// MaxUnexpected is the number of unexpected findings listed in a Report.
+// All of them fail it.
+const MaxUnexpected = 100
+
+// keyField and changedField name the fields of generated records.
+const (
+	keyField     = "id"
+	changedField = "amount"
+)
+
+// Options configure a soak run.
+type Options struct {
+	// Duration is how long records are generated for.
+	Duration time.Duration
+	// Rate is the number of records generated per second for source1.
+	Rate int
+	// Seed makes the records and their divergence the same on every run.
+	Seed uint64
+	// Changed, Dropped and Added are the shares of source1 records that are
+	// changed in source2, missing from it, and followed there by a record
+	// with a new key.
+	Changed, Dropped, Added float64
+	// Progress, if set, is called with the running counts every
+	// ProgressInterval.
+	Progress         func(summary comparator.Summary)
+	ProgressInterval time.Duration
+}
+
+// DefaultOptions returns options for a run of duration at rate, with the
+// default divergence.
+func DefaultOptions(duration time.Duration, rate int) Options {
+	return Options{
+		Duration: duration,
+		Rate:     rate,
+		Seed:     1,
+		Changed:  DefaultChanged,
+		Dropped:  DefaultDropped,
+		Added:    DefaultAdded,
+	}
+}
+
+// Report is the outcome of a soak run. It passes if the comparator found
+// exactly the divergence injected.
+type Report struct {
+	Elapsed time.Duration `yaml:"elapsed"`
+	// Records is the number of source1 records generated.
+	Records int `yaml:"records"`
+	// Rate is the number of source1 records compared per second.
+	Rate     float64 `yaml:"rate"`
+	Injected Counts  `yaml:"injected"`
+	Detected Counts  `yaml:"detected"`
+	// Unexpected lists the first findings that do not match the injected
+	// divergence.
+	Unexpected []string `yaml:"unexpected,omitempty"`
+	Passed     bool     `yaml:"passed"`
+}
+
+// Counts are the records diverging in each way.
+type Counts struct {
+	Changed int `yaml:"changed"`
+	Dropped int `yaml:"dropped"`
+	Added   int `yaml:"added"`
+}
+
+// Run generates records for the duration of opts and compares them as they
+// are generated, checking each finding against the divergence of its key.
+func Run(opts Options) (*Report, error) {
+	records := int(opts.Duration.Seconds() * float64(opts.Rate))
+	if opts.Rate <= 0 || records <= 0 {
+		return nil, fmt.Errorf("soak needs a positive duration and rate, got %s at %d records per second", opts.Duration, opts.Rate)
+	}
+	if opts.Changed+opts.Dropped > 1 {
+		return nil, fmt.Errorf("changed and dropped shares add up to more than 1")
+	}
+
+	report := &Report{Records: records}
+	start := time.Now()
+	source1 := &generator{opts: opts, side: comparator.Source1, records: records, start: start}
+	source2 := &generator{opts: opts, side: comparator.Source2, records: records, start: start, injected: &report.Injected}
+
+	c := comparator.New(keyField)
+	if opts.Progress != nil {
+		c.SetHooks(comparator.Hooks{OnProgress: opts.Progress})
+		c.SetProgressInterval(opts.ProgressInterval)
+	}
+	for finding := range c.Findings(source1, source2) {
+		switch f := finding.(type) {
+		case comparator.RecordDiff:
+			report.Detected.Changed++
+			if i, ok := index(f.Key); !ok || !opts.fate(i).changed || len(f.Diffs) != 1 || f.Diffs[0].Field != changedField {
+				report.unexpected("diff of key %s: %v", f.Key, f.Diffs)
+			}
+		case comparator.MissingKey:
+			i, ok := index(f.Key)
+			if f.Side == comparator.Source1 {
+				report.Detected.Dropped++
+				ok = ok && opts.fate(i).dropped
+			} else {
+				report.Detected.Added++
+				ok = ok && i < 0 && opts.fate(-i-1).added
+			}
+			if !ok {
+				report.unexpected("key %s only in %s", f.Key, f.Side)
+			}
+		case comparator.ParseFailure:
+			return nil, fmt.Errorf("soak comparison failed: %w", f.Err)
+		case comparator.Completed:
+			report.Elapsed = time.Since(start).Round(time.Millisecond)
+		default:
+			report.unexpected("%s finding: %+v", finding.Kind(), finding)
+		}
+	}
+	if report.Elapsed > 0 {
+		report.Rate = float64(records) / report.Elapsed.Seconds()
+	}
+	report.Passed = len(report.Unexpected) == 0 && report.Detected == report.Injected
+	return report, nil
+}
+
+func (r *Report) unexpected(format string, args ...interface{}) {
+	if len(r.Unexpected) < MaxUnexpected {
+		r.Unexpected = append(r.Unexpected, fmt.Sprintf(format, args...))
+	}
+}
+
+// fate is how source2 diverges at the i-th record of source1.
+type fate struct {
+	changed, dropped, added bool
+}
+
+func (opts Options) fate(i int) fate {
+	r := rand.NewPCG(opts.Seed, uint64(i))
+	u := unit(r.Uint64())
+	return fate{
+		dropped: u < opts.Dropped,
+		changed: u >= opts.Dropped && u < opts.Dropped+opts.Changed,
+		added:   unit(r.Uint64()) < opts.Added,
+	}
+}
+
+// Mutations returns the log of the faults planted in source2 by a run of
+// opts, for checking a comparison of its records with Verify.
+func (opts Options) Mutations() []Mutation {
+	var mutations []Mutation
+	records := int(opts.Duration.Seconds() * float64(opts.Rate))
+	for i := 0; i < records; i++ {
+		fate := opts.fate(i)
+		key := strconv.Itoa(i + 1)
+		switch {
+		case fate.dropped:
+			mutations = append(mutations, Mutation{Kind: MutationDropped, Key: key})
+		case fate.changed:
+			mutations = append(mutations, Mutation{Kind: MutationChanged, Key: key, Fields: []string{changedField}})
+		}
+		if fate.added {
+			mutations = append(mutations, Mutation{Kind: MutationAdded, Key: strconv.Itoa(-(i + 1))})
+		}
+	}
+	return mutations
+}
+
+// unit maps n to [0, 1).
+func unit(n uint64) float64 {
+	return float64(n>>11) / (1 << 53)
+}
+
+// index returns the index of the record of key. Added records have the
+// negative key of the record they follow.
+func index(key string) (int, bool) {
+	id, err := strconv.Atoi(key)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	if id < 0 {
+		return id, true
+	}
+	return id - 1, true
+}
+
+// record returns the i-th record of source1.
+func (opts Options) record(i int) datareader.Record {
+	r := rand.NewPCG(opts.Seed^0x9e3779b97f4a7c15, uint64(i))
+	return datareader.Record{
+		keyField:     i + 1,
+		"name":       fmt.Sprintf("user-%d", i+1),
+		changedField: float64(r.Uint64()%1000000) / 100,
+	}
+}
+
+// generator is a source generating the records of one side, paced to the
+// rate of its options.
+type generator struct {
+	opts    Options
+	side    comparator.Side
+	records int
+	start   time.Time
+	next    int
+	// added is a record with a new key to return next.
+	added datareader.Record
+	// injected counts the divergence of source2.
+	injected *Counts
+}
+
+// Read returns the next record once it is due.
+func (g *generator) Read() (datareader.Record, error) {
+	if rec := g.added; rec != nil {
+		g.added = nil
+		return rec, nil
+	}
+	for g.next < g.records {
+		i := g.next
+		g.next++
+		g.pace(i)
+		rec := g.opts.record(i)
+		if g.side == comparator.Source1 {
+			return rec, nil
+		}
+
+		fate := g.opts.fate(i)
+		if fate.added {
+			g.injected.Added++
+			g.added = datareader.Record{keyField: -(i + 1), "name": "added", changedField: 0.0}
+		}
+		switch {
+		case fate.dropped:
+			g.injected.Dropped++
+			if rec, g.added = g.added, nil; rec != nil {
+				return rec, nil
+			}
+			continue
+		case fate.changed:
+			g.injected.Changed++
+			rec[changedField] = rec[changedField].(float64) + 1
+		}
+		return rec, nil
+	}
+	return nil, io.EOF
+}
+
+// pace waits until the i-th record is due.
+func (g *generator) pace(i int) {
+	due := g.start.Add(time.Duration(float64(i) / float64(g.opts.Rate) * float64(time.Second)))
+	if wait := time.Until(due); wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+// Close is a no-op.
+func (g *generator) Close() error {
+	return nil
+}
@@ -0,0 +1,75 @@
+package soak
+
+import (
+	"data-comparator/internal/pkg/comparator"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	opts := DefaultOptions(200*time.Millisecond, 5000)
+	opts.Changed, opts.Dropped, opts.Added = 0.05, 0.02, 0.02
+	report, err := Run(opts)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !report.Passed {
+		t.Errorf("Passed got = false, want true; unexpected %v, injected %+v, detected %+v", report.Unexpected, report.Injected, report.Detected)
+	}
+	if report.Records != 1000 {
+		t.Errorf("Records got = %d, want 1000", report.Records)
+	}
+	if report.Injected.Changed == 0 || report.Injected.Dropped == 0 || report.Injected.Added == 0 {
+		t.Errorf("Injected got = %+v, want every kind of divergence", report.Injected)
+	}
+	if report.Elapsed < opts.Duration*9/10 {
+		t.Errorf("Elapsed got = %v, want about %v", report.Elapsed, opts.Duration)
+	}
+}
+
+func TestRun_Deterministic(t *testing.T) {
+	opts := DefaultOptions(50*time.Millisecond, 10000)
+	opts.Changed = 0.1
+	first, err := Run(opts)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	second, err := Run(opts)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if first.Injected != second.Injected {
+		t.Errorf("Injected got = %+v and %+v, want the same", first.Injected, second.Injected)
+	}
+}
+
+func TestGenerator_Divergence(t *testing.T) {
+	opts := Options{Duration: time.Second, Rate: 1000000, Changed: 0.3, Dropped: 0.2, Added: 0.1}
+	records := 200
+	var injected Counts
+	source2 := &generator{opts: opts, side: comparator.Source2, records: records, start: time.Now(), injected: &injected}
+	kept, added := 0, 0
+	for {
+		rec, err := source2.Read()
+		if err != nil {
+			break
+		}
+		if rec[keyField].(int) < 0 {
+			added++
+		} else {
+			kept++
+		}
+	}
+	if kept != records-injected.Dropped || added != injected.Added {
+		t.Errorf("read %d kept and %d added records, want %d and %d", kept, added, records-injected.Dropped, injected.Added)
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	if _, err := Run(Options{Duration: time.Second}); err == nil {
+		t.Error("Run() without a rate expected an error, got nil")
+	}
+	if _, err := Run(Options{Duration: time.Second, Rate: 10, Changed: 0.7, Dropped: 0.5}); err == nil {
+		t.Error("Run() with shares over 1 expected an error, got nil")
+	}
+}
@@ -0,0 +1,189 @@
+package soak
+
+import (
+	"bufio"
+	"data-comparator/internal/pkg/comparator"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// MutationKind is how a record of source2 was made to diverge from source1.
+type MutationKind string
+
+const (
+	// MutationChanged is a record with the same key but changed fields.
+	MutationChanged MutationKind = "changed"
+	// MutationDropped is a record of source1 left out of source2.
+	MutationDropped MutationKind = "dropped"
+	// MutationAdded is a record of source2 with a key not in source1.
+	MutationAdded MutationKind = "added"
+)
+
+// Mutation is a fault planted in source2, as logged by whoever planted it.
+type Mutation struct {
+	Kind MutationKind `yaml:"kind" json:"kind"`
+	Key  string       `yaml:"key" json:"key"`
+	// Fields are the fields changed by a MutationChanged. If empty, any
+	// differing fields are accepted.
+	Fields []string `yaml:"fields,omitempty" json:"fields,omitempty"`
+}
+
+// Discrepancies is how the outcome of a comparison departs from the faults
+// planted in its sources. It passes if every fault was detected and nothing
+// else was.
+type Discrepancies struct {
+	Injected Counts `yaml:"injected"`
+	Detected Counts `yaml:"detected"`
+	// Missed are the planted faults, or the fields of them, not detected.
+	Missed []Mutation `yaml:"missed,omitempty"`
+	// Unexpected are the detected divergences, or the fields of them, not
+	// planted.
+	Unexpected []Mutation `yaml:"unexpected,omitempty"`
+	Passed     bool       `yaml:"passed"`
+}
+
+// ReadMutations reads a mutation log of one JSON mutation per line. Blank
+// lines are skipped.
+func ReadMutations(r io.Reader) ([]Mutation, error) {
+	var mutations []Mutation
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var m Mutation
+		if err := json.Unmarshal([]byte(text), &m); err != nil {
+			return nil, fmt.Errorf("mutation log line %d: %w", line, err)
+		}
+		mutations = append(mutations, m)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read mutation log: %w", err)
+	}
+	return mutations, nil
+}
+
+// Verify checks the result of a comparison against the mutations planted in
+// its sources. The result must hold its value diffs, as returned by Compare;
+// results of Findings, or compared with a baseline accepting planted diffs,
+// cannot be verified.
+func Verify(mutations []Mutation, result *comparator.Result) (*Discrepancies, error) {
+	if result == nil {
+		return nil, fmt.Errorf("no comparison result to verify")
+	}
+	differing := result.Summary.MatchingKeys - result.Summary.IdenticalRows
+	if len(result.ValueDiffs) != differing {
+		return nil, fmt.Errorf("result holds the value diffs of %d of %d differing keys", len(result.ValueDiffs), differing)
+	}
+
+	planted := make(map[MutationKind]map[string]Mutation)
+	for _, kind := range []MutationKind{MutationChanged, MutationDropped, MutationAdded} {
+		planted[kind] = make(map[string]Mutation)
+	}
+	d := &Discrepancies{}
+	for _, m := range mutations {
+		byKey, ok := planted[m.Kind]
+		if !ok {
+			return nil, fmt.Errorf("mutation of key %s: unsupported kind %q", m.Key, m.Kind)
+		}
+		if _, repeated := byKey[m.Key]; repeated {
+			return nil, fmt.Errorf("key %s is %s more than once", m.Key, m.Kind)
+		}
+		byKey[m.Key] = m
+		*count(&d.Injected, m.Kind)++
+	}
+
+	d.Detected = Counts{
+		Changed: differing,
+		Dropped: len(result.KeysOnly.InSource1),
+		Added:   len(result.KeysOnly.InSource2),
+	}
+	for _, key := range sortedKeys(result.ValueDiffs) {
+		d.verifyChanged(key, result.ValueDiffs[key], planted[MutationChanged])
+	}
+	d.verifyKeys(MutationDropped, result.KeysOnly.InSource1, planted[MutationDropped])
+	d.verifyKeys(MutationAdded, result.KeysOnly.InSource2, planted[MutationAdded])
+	for _, kind := range []MutationKind{MutationChanged, MutationDropped, MutationAdded} {
+		for _, key := range sortedKeys(planted[kind]) {
+			d.Missed = append(d.Missed, planted[kind][key])
+		}
+	}
+	d.Passed = len(d.Missed) == 0 && len(d.Unexpected) == 0
+	return d, nil
+}
+
+// count returns the count of kind in counts.
+func count(counts *Counts, kind MutationKind) *int {
+	switch kind {
+	case MutationDropped:
+		return &counts.Dropped
+	case MutationAdded:
+		return &counts.Added
+	default:
+		return &counts.Changed
+	}
+}
+
+// verifyChanged checks the diffs of key against the change planted there,
+// which is removed from planted.
+func (d *Discrepancies) verifyChanged(key string, diffs []comparator.FieldDiff, planted map[string]Mutation) {
+	fields := make([]string, 0, len(diffs))
+	for _, diff := range diffs {
+		fields = append(fields, diff.Field)
+	}
+	m, ok := planted[key]
+	if !ok {
+		d.Unexpected = append(d.Unexpected, Mutation{Kind: MutationChanged, Key: key, Fields: fields})
+		return
+	}
+	delete(planted, key)
+	if len(m.Fields) == 0 {
+		return
+	}
+	if extra := subtract(fields, m.Fields); len(extra) > 0 {
+		d.Unexpected = append(d.Unexpected, Mutation{Kind: MutationChanged, Key: key, Fields: extra})
+	}
+	if missed := subtract(m.Fields, fields); len(missed) > 0 {
+		d.Missed = append(d.Missed, Mutation{Kind: MutationChanged, Key: key, Fields: missed})
+	}
+}
+
+// verifyKeys checks keys only in one source against the mutations of kind
+// planted, removing those found.
+func (d *Discrepancies) verifyKeys(kind MutationKind, keys []string, planted map[string]Mutation) {
+	for _, key := range keys {
+		if _, ok := planted[key]; ok {
+			delete(planted, key)
+			continue
+		}
+		d.Unexpected = append(d.Unexpected, Mutation{Kind: kind, Key: key})
+	}
+}
+
+// subtract returns the names of a not in b, in the order of a.
+func subtract(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, name := range b {
+		in[name] = true
+	}
+	var out []string
+	for _, name := range a {
+		if !in[name] {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,128 @@
+package soak
+
+import (
+	"data-comparator/internal/pkg/comparator"
+	"data-comparator/internal/pkg/datareader"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func compare(t *testing.T, records1, records2 []datareader.Record) *comparator.Result {
+	t.Helper()
+	result, err := comparator.New("id").Compare(datareader.NewSliceReader(records1), datareader.NewSliceReader(records2))
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	return result
+}
+
+func TestVerify(t *testing.T) {
+	records1 := []datareader.Record{
+		{"id": "1", "a": 1, "b": 1},
+		{"id": "2", "a": 1, "b": 1},
+		{"id": "3", "a": 1, "b": 1},
+		{"id": "4", "a": 1, "b": 1},
+	}
+	records2 := []datareader.Record{
+		{"id": "1", "a": 2, "b": 1},
+		{"id": "2", "a": 2, "b": 2},
+		{"id": "3", "a": 1, "b": 1},
+		{"id": "5", "a": 1, "b": 1},
+	}
+	result := compare(t, records1, records2)
+
+	mutations := []Mutation{
+		{Kind: MutationChanged, Key: "1", Fields: []string{"a"}},
+		{Kind: MutationChanged, Key: "2"},
+		{Kind: MutationDropped, Key: "4"},
+		{Kind: MutationAdded, Key: "5"},
+	}
+	d, err := Verify(mutations, result)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if !d.Passed || d.Injected != d.Detected {
+		t.Errorf("Verify() got = %+v, want passed", d)
+	}
+
+	mutations = []Mutation{
+		{Kind: MutationChanged, Key: "2", Fields: []string{"a", "c"}},
+		{Kind: MutationChanged, Key: "3"},
+		{Kind: MutationDropped, Key: "4"},
+	}
+	d, err = Verify(mutations, result)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	wantMissed := []Mutation{
+		{Kind: MutationChanged, Key: "2", Fields: []string{"c"}},
+		{Kind: MutationChanged, Key: "3"},
+	}
+	wantUnexpected := []Mutation{
+		{Kind: MutationChanged, Key: "1", Fields: []string{"a"}},
+		{Kind: MutationChanged, Key: "2", Fields: []string{"b"}},
+		{Kind: MutationAdded, Key: "5"},
+	}
+	if d.Passed || !reflect.DeepEqual(d.Missed, wantMissed) || !reflect.DeepEqual(d.Unexpected, wantUnexpected) {
+		t.Errorf("Verify() got missed %+v and unexpected %+v, want %+v and %+v", d.Missed, d.Unexpected, wantMissed, wantUnexpected)
+	}
+	if want := (Counts{Changed: 2, Dropped: 1}); d.Injected != want {
+		t.Errorf("Injected got = %+v, want %+v", d.Injected, want)
+	}
+}
+
+func TestVerify_Errors(t *testing.T) {
+	result := compare(t, []datareader.Record{{"id": "1"}}, []datareader.Record{{"id": "1"}})
+	if _, err := Verify([]Mutation{{Kind: "renamed", Key: "1"}}, result); err == nil {
+		t.Error("Verify() with an unknown kind expected an error, got nil")
+	}
+	if _, err := Verify([]Mutation{{Kind: MutationAdded, Key: "1"}, {Kind: MutationAdded, Key: "1"}}, result); err == nil {
+		t.Error("Verify() with a repeated mutation expected an error, got nil")
+	}
+	if _, err := Verify(nil, &comparator.Result{Summary: comparator.Summary{MatchingKeys: 1}}); err == nil {
+		t.Error("Verify() without value diffs expected an error, got nil")
+	}
+}
+
+func TestVerify_SoakMutations(t *testing.T) {
+	opts := DefaultOptions(10*time.Millisecond, 100000)
+	opts.Changed, opts.Dropped, opts.Added = 0.05, 0.02, 0.02
+	var injected Counts
+	start := time.Now()
+	var records [2][]datareader.Record
+	for i, g := range []*generator{
+		{opts: opts, side: comparator.Source1, records: 1000, start: start},
+		{opts: opts, side: comparator.Source2, records: 1000, start: start, injected: &injected},
+	} {
+		for rec, err := g.Read(); err == nil; rec, err = g.Read() {
+			records[i] = append(records[i], rec)
+		}
+	}
+	d, err := Verify(opts.Mutations(), compare(t, records[0], records[1]))
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if !d.Passed || d.Injected != injected {
+		t.Errorf("Verify() got = %+v, want passed with %+v injected", d, injected)
+	}
+}
+
+func TestReadMutations(t *testing.T) {
+	log := `{"kind": "changed", "key": "1", "fields": ["a"]}
+
+{"kind": "dropped", "key": "2"}
+`
+	got, err := ReadMutations(strings.NewReader(log))
+	if err != nil {
+		t.Fatalf("ReadMutations() error = %v", err)
+	}
+	want := []Mutation{{Kind: MutationChanged, Key: "1", Fields: []string{"a"}}, {Kind: MutationDropped, Key: "2"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadMutations() got = %+v, want %+v", got, want)
+	}
+	if _, err := ReadMutations(strings.NewReader("{")); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("ReadMutations() error got = %v, want one naming line 1", err)
+	}
+}
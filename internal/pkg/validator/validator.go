@@ -0,0 +1,378 @@
+package validator
+
+import (
+	"data-comparator/internal/pkg/config"
+	"data-comparator/internal/pkg/datareader"
+	"data-comparator/internal/pkg/schema"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// DefaultProbeRecords is the number of records read per source in deep mode.
+const DefaultProbeRecords = 100
+
+// Severity levels of validation findings.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// Finding is a single issue or hint found while validating a configuration.
+type Finding struct {
+	Config   string `yaml:"config"`
+	Type     string `yaml:"type"`
+	Severity string `yaml:"severity"`
+	Message  string `yaml:"message"`
+}
+
+// Probe summarizes the records read from a source in deep mode.
+type Probe struct {
+	Config       string         `yaml:"config"`
+	RecordsRead  int            `yaml:"records_read"`
+	ParseErrors  int            `yaml:"parse_errors"`
+	SuccessRate  float64        `yaml:"success_rate"`
+	CandidateKey string         `yaml:"candidate_key,omitempty"`
+	Schema       *schema.Schema `yaml:"schema_preview,omitempty"`
+}
+
+// ValidationResult is the outcome of validating one or more configurations.
+type ValidationResult struct {
+	Valid    bool      `yaml:"valid"`
+	FailOn   string    `yaml:"fail_on"`
+	Counts   Counts    `yaml:"counts"`
+	Findings []Finding `yaml:"findings"`
+	Probes   []Probe   `yaml:"probes,omitempty"`
+}
+
+// Counts holds the number of reported findings per severity, plus the number
+// of findings suppressed by ignore rules.
+type Counts struct {
+	Errors     int `yaml:"errors"`
+	Warnings   int `yaml:"warnings"`
+	Infos      int `yaml:"infos"`
+	Suppressed int `yaml:"suppressed"`
+}
+
+// Options control how deep validation goes and which findings fail it.
+type Options struct {
+	// Deep reads records through each source's reader.
+	Deep bool
+	// ProbeRecords is the number of records read per source in deep mode.
+	ProbeRecords int
+	// FailOn is the lowest severity that makes the result invalid.
+	// Defaults to SeverityError.
+	FailOn string
+	// Ignore lists finding types that are dropped from the result.
+	Ignore []string
+}
+
+// severityRank orders severities from least to most severe.
+var severityRank = map[string]int{
+	SeverityInfo:    1,
+	SeverityWarning: 2,
+	SeverityError:   3,
+}
+
+// ParseSeverity checks that s names a known severity.
+func ParseSeverity(s string) (string, error) {
+	if _, ok := severityRank[s]; !ok {
+		return "", fmt.Errorf("unknown severity %q, expected error, warning or info", s)
+	}
+	return s, nil
+}
+
+// Validate loads and checks the given configuration files. Without Deep it only
+// checks the YAML shape and that source files exist; with Deep it also reads the
+// first records of each source. Ignore rules from the configurations' validation
+// sections are added to opts, and their fail_on applies unless opts sets one;
+// configurations setting it differently are an error.
+func Validate(paths []string, opts Options) *ValidationResult {
+	result := &ValidationResult{Findings: []Finding{}}
+	var keys []string
+	var failOn, failOnPath string
+
+	for _, path := range paths {
+		cfg, err := config.Load(path)
+		if err != nil {
+			result.add(path, "load_failed", SeverityError, err.Error())
+			continue
+		}
+
+		if cfg.Validation != nil {
+			opts.Ignore = append(opts.Ignore, cfg.Validation.Ignore...)
+			switch setting := cfg.Validation.FailOn; {
+			case setting == "":
+			case failOn == "":
+				failOn, failOnPath = setting, path
+			case setting != failOn && opts.FailOn == "":
+				result.add(path, "fail_on_conflict", SeverityError,
+					fmt.Sprintf("validation.fail_on is set differently in %s and %s", failOnPath, path))
+			}
+		}
+
+		findings := checkSource(path, cfg.Source)
+		result.Findings = append(result.Findings, findings...)
+		if !opts.Deep || hasErrors(findings) {
+			continue
+		}
+
+		probe, err := probeSource(path, cfg.Source, opts.ProbeRecords)
+		if err != nil {
+			result.add(path, "probe_failed", SeverityError, err.Error())
+			continue
+		}
+		result.Probes = append(result.Probes, *probe)
+		keys = append(keys, probe.CandidateKey)
+
+		if probe.ParseErrors > 0 {
+			result.add(path, "parse_errors", SeverityWarning,
+				fmt.Sprintf("%d of %d probed records failed to parse", probe.ParseErrors, probe.RecordsRead+probe.ParseErrors))
+		}
+		if probe.RecordsRead+probe.ParseErrors == 0 {
+			result.add(path, "empty_source", SeverityWarning, "the source holds no records")
+		}
+		for _, warning := range probe.Schema.Warnings {
+			result.add(path, "cutoff", SeverityWarning, warning)
+		}
+		if probe.CandidateKey == "" && probe.RecordsRead > 0 {
+			result.add(path, "no_candidate_key", SeverityWarning, "no field is unique across the probed records")
+		}
+	}
+
+	if len(keys) == 2 && keys[0] != "" && keys[1] != "" && keys[0] != keys[1] {
+		result.add(paths[1], "key_mismatch", SeverityWarning,
+			fmt.Sprintf("candidate keys differ: %s vs %s", keys[0], keys[1]))
+	}
+
+	if opts.FailOn == "" {
+		opts.FailOn = failOn
+	}
+	result.applyPolicy(opts)
+	return result
+}
+
+// applyPolicy drops ignored findings, counts the rest by severity and decides
+// validity: the result is invalid if any finding is at or above FailOn.
+func (r *ValidationResult) applyPolicy(opts Options) {
+	r.FailOn = opts.FailOn
+	if _, err := ParseSeverity(r.FailOn); err != nil {
+		if r.FailOn != "" {
+			r.Findings = append(r.Findings, Finding{Type: "invalid_fail_on", Severity: SeverityError, Message: err.Error()})
+		}
+		r.FailOn = SeverityError
+	}
+	threshold := severityRank[r.FailOn]
+
+	kept := r.Findings[:0]
+	r.Valid = true
+	for _, f := range r.Findings {
+		if contains(opts.Ignore, f.Type) {
+			r.Counts.Suppressed++
+			continue
+		}
+		kept = append(kept, f)
+
+		switch f.Severity {
+		case SeverityError:
+			r.Counts.Errors++
+		case SeverityWarning:
+			r.Counts.Warnings++
+		case SeverityInfo:
+			r.Counts.Infos++
+		}
+		if severityRank[f.Severity] >= threshold {
+			r.Valid = false
+		}
+	}
+	r.Findings = kept
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *ValidationResult) add(path, findingType, severity, message string) {
+	r.Findings = append(r.Findings, Finding{Config: path, Type: findingType, Severity: severity, Message: message})
+}
+
+// checkSource performs the shallow checks that do not read any data.
+func checkSource(path string, src config.Source) []Finding {
+	var findings []Finding
+	add := func(findingType, severity, message string) {
+		findings = append(findings, Finding{Config: path, Type: findingType, Severity: severity, Message: message})
+	}
+
+	supported := false
+	for _, t := range datareader.SupportedTypes {
+		if src.Type == t {
+			supported = true
+		}
+	}
+	switch {
+	case src.Type == "":
+		add("missing_type", SeverityError, "source.type is required")
+	case !supported:
+		add("unsupported_type", SeverityError, fmt.Sprintf("unsupported source type: %s", src.Type))
+	}
+	if src.Compression != "" && !slices.Contains(datareader.Compressions, src.Compression) {
+		add("unsupported_compression", SeverityError, fmt.Sprintf("unsupported compression %s, use one of %s", src.Compression, strings.Join(datareader.Compressions, ", ")))
+	}
+
+	if src.Type == "xml" && (src.XML == nil || src.XML.RecordElement == "") {
+		add("missing_record_element", SeverityError, "source.xml.record_element is required for xml sources")
+	}
+	if src.Type == "fixed_width" && (src.FixedWidth == nil || len(src.FixedWidth.Columns) == 0 && src.FixedWidth.Layout == "") {
+		add("missing_columns", SeverityError, "source.fixed_width.columns or source.fixed_width.layout is required for fixed_width sources")
+	}
+	if src.Type == "logfile" {
+		if err := datareader.ValidateLogFile(src.LogFile); err != nil {
+			add("invalid_logfile", SeverityError, fmt.Sprintf("source.%v", err))
+		}
+	}
+	if src.Type == "syslog" {
+		if err := datareader.ValidateSyslog(src.Syslog); err != nil {
+			add("invalid_syslog", SeverityError, fmt.Sprintf("source.%v", err))
+		}
+	}
+	if src.Type == "protobuf" {
+		switch {
+		case src.Protobuf == nil || src.Protobuf.Descriptor == "" || src.Protobuf.MessageType == "":
+			add("missing_protobuf_type", SeverityError, "source.protobuf.descriptor and source.protobuf.message_type are required for protobuf sources")
+		case src.Protobuf.Framing != "" && !slices.Contains(datareader.ProtobufFramings, src.Protobuf.Framing):
+			add("unsupported_framing", SeverityError, fmt.Sprintf("unsupported protobuf framing %s, use one of %s", src.Protobuf.Framing, strings.Join(datareader.ProtobufFramings, ", ")))
+		}
+	}
+
+	if p := src.ParserConfig; p != nil && p.JSONNumbers != "" && !slices.Contains(datareader.JSONNumberModes, p.JSONNumbers) {
+		add("unsupported_json_numbers", SeverityError, fmt.Sprintf("unsupported source.parser_config.json_numbers %s, use one of %s", p.JSONNumbers, strings.Join(datareader.JSONNumberModes, ", ")))
+	}
+
+	if _, err := datareader.CompileFieldTransforms(src.Transform); err != nil {
+		add("invalid_transform", SeverityError, err.Error())
+	}
+
+	if src.Type == "http" && src.HTTP != nil && src.HTTP.Format != "" && !slices.Contains(datareader.HTTPFormats, src.HTTP.Format) {
+		add("unsupported_format", SeverityError, fmt.Sprintf("unsupported source.http.format %s, use one of %s", src.HTTP.Format, strings.Join(datareader.HTTPFormats, ", ")))
+	}
+
+	if datareader.IsDatabaseType(src.Type) {
+		switch {
+		case src.DSN == "" && src.Type != "bigquery":
+			add("missing_dsn", SeverityError, fmt.Sprintf("source.dsn is required for %s sources", src.Type))
+		case src.Table == "" && src.Query == "":
+			add("missing_table", SeverityError, fmt.Sprintf("source.table or source.query is required for %s sources", src.Type))
+		case src.Table != "" && src.Query != "":
+			add("table_and_query", SeverityError, "source.table and source.query are mutually exclusive")
+		case src.Type == "bigquery" && src.Query != "" && src.Project == "":
+			add("missing_project", SeverityError, "source.project is required for bigquery queries")
+		}
+	} else if src.Path == "" {
+		add("missing_path", SeverityError, "source.path is required")
+	} else if src.Path == datareader.StdinPath {
+		// Standard input is only there once the comparison reads it.
+	} else if src.Type == "http" {
+		if !datareader.IsHTTPPath(src.Path) {
+			add("invalid_url", SeverityError, fmt.Sprintf("source.path %s of an http source is not an http:// or https:// URL", src.Path))
+		}
+	} else if datareader.IsListenPath(src.Path) {
+		if src.Type != "syslog" {
+			add("invalid_path", SeverityError, fmt.Sprintf("source.path %s is a network address, which only syslog sources listen on", src.Path))
+		}
+	} else if datareader.IsArchivePath(src.Path) {
+		if _, err := datareader.ArchiveMembers(src); err != nil {
+			add("file_not_found", SeverityError, fmt.Sprintf("source archive members %s are not accessible: %v", src.Path, err))
+		}
+	} else if datareader.IsObjectPath(src.Path) {
+		if _, err := datareader.StatObject(src.Path, src.ObjectStore); err != nil {
+			add("file_not_found", SeverityError, fmt.Sprintf("source object %s is not accessible: %v", src.Path, err))
+		}
+	} else if datareader.IsRemotePath(src.Path) {
+		if _, err := datareader.StatRemote(src.Path, src.Remote); err != nil {
+			add("file_not_found", SeverityError, fmt.Sprintf("source file %s is not accessible: %v", src.Path, err))
+		}
+	} else if src.Order != "" && !slices.Contains(datareader.PathOrders, src.Order) {
+		add("unsupported_order", SeverityError, fmt.Sprintf("unsupported source.order %s, use one of %s", src.Order, strings.Join(datareader.PathOrders, ", ")))
+	} else if files, err := datareader.SourceFiles(src); err != nil {
+		add("file_not_found", SeverityError, fmt.Sprintf("source files %s are not accessible: %v", src.Path, err))
+	} else if _, err := os.Stat(files[0]); err != nil {
+		add("file_not_found", SeverityError, fmt.Sprintf("source file %s is not accessible: %v", src.Path, err))
+	}
+
+	if src.Sampler == nil || src.Sampler.SampleSize <= 0 {
+		add("no_sampler", SeverityInfo,
+			fmt.Sprintf("no sampler configured, schema inference reads the first %d records", schema.DefaultSampleSize))
+	}
+	return findings
+}
+
+// probeSource reads up to n records through the source's reader, counting parse
+// errors and inferring a schema preview from the records that parsed.
+func probeSource(path string, src config.Source, n int) (*Probe, error) {
+	if n <= 0 {
+		n = DefaultProbeRecords
+	}
+
+	reader, err := datareader.New(src)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	probe := &Probe{Config: path}
+	var records []datareader.Record
+	for probe.RecordsRead+probe.ParseErrors < n {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			probe.ParseErrors++
+			var perr *datareader.ParseError
+			if errors.As(err, &perr) && perr.Recoverable {
+				continue
+			}
+			break
+		}
+		probe.RecordsRead++
+		records = append(records, rec)
+	}
+
+	if total := probe.RecordsRead + probe.ParseErrors; total > 0 {
+		probe.SuccessRate = float64(probe.RecordsRead) / float64(total)
+	}
+
+	sampler := config.Sampler{SampleSize: len(records) + 1}
+	if src.Sampler != nil {
+		sampler.MaxDepth, sampler.MaxFields = src.Sampler.MaxDepth, src.Sampler.MaxFields
+		sampler.Examples, sampler.RedactExamples = src.Sampler.Examples, src.Sampler.RedactExamples
+	}
+	preview, err := schema.Generate(datareader.NewSliceReader(records), &sampler)
+	if err != nil {
+		return nil, err
+	}
+	if w, ok := reader.(datareader.Warner); ok {
+		preview.Warnings = append(w.Warnings(), preview.Warnings...)
+	}
+	probe.Schema = preview
+	probe.CandidateKey = preview.Key
+	return probe, nil
+}
+
+func hasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package validator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, sourceType, dataPath string) string {
+	t.Helper()
+	abs, err := filepath.Abs(dataPath)
+	if err != nil {
+		t.Fatalf("Failed to resolve path: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := fmt.Sprintf("source:\n  type: %s\n  path: %s\n  sampler:\n    sample_size: 10\n", sourceType, abs)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func findingTypes(result *ValidationResult) map[string]bool {
+	types := make(map[string]bool)
+	for _, f := range result.Findings {
+		types[f.Type] = true
+	}
+	return types
+}
+
+func TestValidate_Deep(t *testing.T) {
+	config1 := writeConfig(t, "csv", "../../../testdata/testcase1_simple_csv/source1.csv")
+	config2 := writeConfig(t, "csv", "../../../testdata/testcase1_simple_csv/source2.csv")
+
+	result := Validate([]string{config1, config2}, Options{Deep: true, ProbeRecords: 3})
+	if !result.Valid {
+		t.Fatalf("Expected valid result, got findings %v", result.Findings)
+	}
+	if len(result.Probes) != 2 {
+		t.Fatalf("Expected 2 probes, got %d", len(result.Probes))
+	}
+	probe := result.Probes[0]
+	if probe.RecordsRead != 3 || probe.SuccessRate != 1 || probe.CandidateKey != "user_id" {
+		t.Errorf("Probe got = %+v, want 3 records, full success and key user_id", probe)
+	}
+	if probe.Schema == nil || len(probe.Schema.Fields) != 6 {
+		t.Errorf("Expected a schema preview with 6 fields, got %+v", probe.Schema)
+	}
+}
+
+func TestValidate_ParseErrors(t *testing.T) {
+	data := filepath.Join(t.TempDir(), "data.csv")
+	if err := os.WriteFile(data, []byte("id,name\n1,alice\n2,bob,extra\n3,carol\n"), 0644); err != nil {
+		t.Fatalf("Failed to write data: %v", err)
+	}
+
+	result := Validate([]string{writeConfig(t, "csv", data)}, Options{Deep: true})
+	if len(result.Probes) != 1 {
+		t.Fatalf("Expected 1 probe, got %d", len(result.Probes))
+	}
+	probe := result.Probes[0]
+	if probe.RecordsRead != 2 || probe.ParseErrors != 1 {
+		t.Errorf("Probe got = %+v, want 2 records and 1 parse error", probe)
+	}
+	if !findingTypes(result)["parse_errors"] {
+		t.Errorf("Expected parse_errors finding, got %v", result.Findings)
+	}
+}
+
+func TestValidate_MissingFile(t *testing.T) {
+	result := Validate([]string{writeConfig(t, "csv", "does-not-exist.csv")}, Options{Deep: true})
+	if result.Valid {
+		t.Error("Expected invalid result for missing source file")
+	}
+	if !findingTypes(result)["file_not_found"] {
+		t.Errorf("Expected file_not_found finding, got %v", result.Findings)
+	}
+	if len(result.Probes) != 0 {
+		t.Errorf("Expected no probe for a missing file, got %d", len(result.Probes))
+	}
+}
+
+func TestValidate_DatabaseSource(t *testing.T) {
+	for _, sourceType := range []string{"postgres", "mysql"} {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		content := "source:\n  type: " + sourceType + "\n  table: users\n"
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		types := findingTypes(Validate([]string{path}, Options{}))
+		if !types["missing_dsn"] || types["missing_path"] {
+			t.Errorf("Expected missing_dsn and no missing_path finding for %s, got %v", sourceType, types)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("source:\n  type: bigquery\n  query: SELECT 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	types := findingTypes(Validate([]string{path}, Options{}))
+	if !types["missing_project"] || types["missing_dsn"] || types["missing_path"] {
+		t.Errorf("Expected only missing_project for a bigquery query, got %v", types)
+	}
+}
+
+func TestValidate_SeverityPolicy(t *testing.T) {
+	// The config has no issues besides the info-level no_sampler hint.
+	abs, err := filepath.Abs("../../../testdata/testcase1_simple_csv/source1.csv")
+	if err != nil {
+		t.Fatalf("Failed to resolve path: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("source:\n  type: csv\n  path: "+abs+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		opts      Options
+		wantValid bool
+		wantInfos int
+	}{
+		{"info does not fail by default", Options{}, true, 1},
+		{"fail on info", Options{FailOn: SeverityInfo}, false, 1},
+		{"ignored finding does not fail", Options{FailOn: SeverityInfo, Ignore: []string{"no_sampler"}}, true, 0},
+		{"unknown fail-on is an error", Options{FailOn: "fatal"}, false, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Validate([]string{path}, tt.opts)
+			if result.Valid != tt.wantValid {
+				t.Errorf("Valid got = %v, want %v (findings %v)", result.Valid, tt.wantValid, result.Findings)
+			}
+			if result.Counts.Infos != tt.wantInfos {
+				t.Errorf("Counts.Infos got = %d, want %d", result.Counts.Infos, tt.wantInfos)
+			}
+		})
+	}
+}
+
+func TestValidate_IgnoreFromConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "source:\n  type: csv\n  path: missing.csv\nvalidation:\n  fail_on: info\n  ignore: [file_not_found, no_sampler]\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	result := Validate([]string{path}, Options{})
+	if !result.Valid || result.Counts.Suppressed != 2 || result.FailOn != SeverityInfo {
+		t.Errorf("Result got = %+v, want valid with 2 suppressed findings and fail_on info", result)
+	}
+}
+
+func TestValidate_FailOnConflict(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for i, failOn := range []string{"info", "warning"} {
+		path := filepath.Join(dir, fmt.Sprintf("config%d.yaml", i+1))
+		content := "source:\n  type: csv\n  path: missing.csv\nvalidation:\n  fail_on: " + failOn + "\n  ignore: [file_not_found, no_sampler]\n"
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		paths = append(paths, path)
+	}
+
+	result := Validate(paths, Options{})
+	if result.Valid || !findingTypes(result)["fail_on_conflict"] {
+		t.Errorf("Result got = %+v, want invalid with a fail_on_conflict finding", result)
+	}
+	if result := Validate(paths, Options{FailOn: SeverityWarning}); !result.Valid || result.FailOn != SeverityWarning {
+		t.Errorf("Result with -fail-on got = %+v, want valid with fail_on warning", result)
+	}
+	if result := Validate([]string{paths[0], paths[0]}, Options{}); !result.Valid || result.FailOn != SeverityInfo {
+		t.Errorf("Result of equal settings got = %+v, want valid with fail_on info", result)
+	}
+}
+
+func TestValidate_EmptySource(t *testing.T) {
+	data := filepath.Join(t.TempDir(), "data.csv")
+	if err := os.WriteFile(data, nil, 0644); err != nil {
+		t.Fatalf("Failed to write data: %v", err)
+	}
+
+	result := Validate([]string{writeConfig(t, "csv", data)}, Options{Deep: true})
+	if !result.Valid {
+		t.Errorf("Expected an empty source to be valid, got findings %v", result.Findings)
+	}
+	if !findingTypes(result)["empty_source"] {
+		t.Errorf("Expected empty_source finding, got %v", result.Findings)
+	}
+}
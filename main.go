@@ -1,13 +1,32 @@
 package main
 
 import (
+	"data-comparator/internal/pkg/airflow"
+	"data-comparator/internal/pkg/badge"
+	"data-comparator/internal/pkg/capture"
+	"data-comparator/internal/pkg/comparator"
 	"data-comparator/internal/pkg/config"
 	"data-comparator/internal/pkg/datareader"
+	"data-comparator/internal/pkg/devgen"
+	"data-comparator/internal/pkg/diffexport"
+	"data-comparator/internal/pkg/junit"
+	"data-comparator/internal/pkg/k8s"
+	"data-comparator/internal/pkg/mapping"
+	"data-comparator/internal/pkg/output"
+	"data-comparator/internal/pkg/rpc"
 	"data-comparator/internal/pkg/schema"
+	"data-comparator/internal/pkg/soak"
+	"data-comparator/internal/pkg/validator"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -16,17 +35,85 @@ func main() {
 	var (
 		configPath1 = flag.String("config1", "", "Path to first configuration file")
 		configPath2 = flag.String("config2", "", "Path to second configuration file")
+		runConfig   = flag.String("run", "", "Inline JSON run configuration with config1 and config2 objects, instead of config files")
 		outputPath  = flag.String("output", "", "Path to output file (optional, prints to stdout if not provided)")
+		format      = flag.String("format", "yaml", "Format of the comparison report and the results of -validate, -rerun, -sniff, -baseline-from and soak: yaml, json (indented) or json-compact")
+		schemaPath  = flag.String("schema", "", "Path to a pinned schema used for both sources instead of inference; the comparison checks every record against its matchers and groups diffs by its tags (optional)")
+		schemaOnly  = flag.Bool("schema-only", false, "Only generate the schemas of both sources, or validate them against -schema, instead of comparing their records")
+		foldNames   = flag.Bool("schema-fold-names", false, "With -schema-only, match fields of the two schemas whose names differ only in case and separators, e.g. createdAt and created_at, reporting them as renamed")
+		key1        = flag.String("key1", "", "Key field of source1, overriding source.key and the inferred key (optional)")
+		key2        = flag.String("key2", "", "Key field of source2, overriding source.key and the inferred key (optional)")
+		sniffPath   = flag.String("sniff", "", "Detect the format of a data file and print a suggested source config")
+		capturePath = flag.String("capture", "", "Write the records of the source of this config to the -output capture file, for replay with source type capture")
+		rerunPath   = flag.String("rerun", "", "Rerun the comparison of a previous report from its embedded configuration and check whether its discrepancies reproduce")
+		baselineOf  = flag.String("baseline-from", "", "Print a baseline accepting every value diff of a previous comparison report")
+		knownDiffs  = flag.String("baseline", "", "Path to a baseline of known, accepted diffs left out of the comparison, overriding comparison.baseline (optional)")
+		validate    = flag.Bool("validate", false, "Validate the given configuration files instead of comparing")
+		deep        = flag.Bool("deep", false, "With -validate, read the first records of each source through its reader")
+		probeCount  = flag.Int("probe-records", validator.DefaultProbeRecords, "Number of records read per source with -deep")
+		failOn      = flag.String("fail-on", "", "Lowest severity, error, warning or info, that fails -validate (default error) or, if set, a comparison with exit status 2")
+		maxDiffs    = flag.Int("max-diffs", -1, "Largest number of discrepant keys before a comparison fails with exit status 2, overriding comparison.thresholds.max_diffs (optional)")
+		maxDiffPct  = flag.Float64("max-diff-percent", -1, "Largest percentage of discrepant keys before a comparison fails with exit status 2, overriding comparison.thresholds.max_diff_percent (optional)")
+		ignore      = flag.String("ignore", "", "With -validate, comma-separated finding types to suppress")
+		k8sStatus   = flag.Bool("k8s-status", false, "Run the full comparison and print its status as a JSON merge patch for a custom resource's status")
+		k8sConfig   = flag.String("k8s-configmap", "", "Run the full comparison and print a ConfigMap manifest [namespace/]name holding its status")
+		airflowMode = flag.Bool("airflow", false, "Run the full comparison and write its key metrics as an Airflow XCom return value")
+		xcomPath    = flag.String("xcom-path", airflow.DefaultXComPath, "Path of the XCom file written with -airflow")
+		badgePath   = flag.String("badge", "", "Run the full comparison and write a parity badge: SVG for a .svg path, else shields.io endpoint JSON")
+		diffsPath   = flag.String("diffs", "", "Run the full comparison and write its value diffs to this .csv or .parquet file, a row per field diff, instead of into the report")
+		junitPath   = flag.String("junit", "", "Run the full comparison and write it to this path as a JUnit XML report, with a test case per field and per check, for CI")
+		quietMode   = flag.Bool("quiet", false, "Print only results and errors, without progress messages, warnings or exceeded thresholds on stderr")
+		porcelain   = flag.Bool("porcelain", false, "Print for scripts: -quiet, with reports and results in json-compact unless -format is given")
+		rpcMode     = flag.Bool("rpc", false, "Speak JSON-RPC 2.0 over stdin and stdout to start comparisons and stream their findings")
 		help        = flag.Bool("help", false, "Show help")
 		version     = flag.Bool("version", false, "Show version")
 	)
 	flag.Parse()
+	if err := applyEnv(flag.CommandLine); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *porcelain {
+		*quietMode = true
+		formatGiven := false
+		flag.Visit(func(f *flag.Flag) { formatGiven = formatGiven || f.Name == "format" })
+		if !formatGiven {
+			*format = "json-compact"
+		}
+	}
+	quiet = *quietMode
+
+	if err := output.CheckFormat(*format); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: -format: %v\n", err)
+		os.Exit(1)
+	}
 
 	if *help {
 		fmt.Println("Data Stream Comparator")
 		fmt.Println()
 		fmt.Println("Usage:")
-		fmt.Println("  data-comparator -config1 <path> -config2 <path> [-output <path>]")
+		fmt.Println("  data-comparator -config1 <path> -config2 <path> [-key1 <field>] [-key2 <field>] [-schema <path>] [-baseline <path>] [-schema-only] [-output <path>]")
+		fmt.Println("  data-comparator -validate [-deep] -config1 <path> [-config2 <path>]")
+		fmt.Println("  data-comparator -baseline-from <report> [-output <path>]")
+		fmt.Println("  data-comparator -capture <config> -output <capture>")
+		fmt.Println("  data-comparator -rerun <report>")
+		fmt.Println("  data-comparator [-output <path>] map <config1> <config2>")
+		fmt.Println("  data-comparator [-output <dir>] devgen testcases")
+		fmt.Println("  data-comparator soak [--duration 1h] [--rate 5000] [--seed <n>] [--changed <share>] [--dropped <share>] [--added <share>]")
+		fmt.Println()
+		fmt.Println("  data-comparator -run '{\"config1\": {...}, \"config2\": {...}}'")
+		fmt.Println("  data-comparator -rpc")
+		fmt.Println()
+		fmt.Println("Send SIGUSR1 to a running comparison to print a snapshot of its counts")
+		fmt.Println("to stderr, or to -rpc to send a snapshot notification for every job.")
+		fmt.Println("SIGTSTP pauses reading the sources and SIGCONT resumes it.")
+		fmt.Println()
+		fmt.Println("A comparison exits with status 2 if it exceeds -max-diffs, -max-diff-percent,")
+		fmt.Println("comparison.thresholds or -fail-on, and with status 1 if it fails to run.")
+		fmt.Println()
+		fmt.Println("Every flag can also be set through an environment variable named")
+		fmt.Println(envPrefix + "<FLAG>, e.g. " + envPrefix + "CONFIG1 or " + envPrefix + "PROBE_RECORDS.")
 		fmt.Println()
 		fmt.Println("Options:")
 		flag.PrintDefaults()
@@ -38,64 +125,750 @@ func main() {
 		return
 	}
 
-	if *configPath1 == "" || *configPath2 == "" {
-		fmt.Fprintf(os.Stderr, "Error: Both -config1 and -config2 are required\n")
-		fmt.Fprintf(os.Stderr, "Use -help for usage information\n")
-		os.Exit(1)
+	if *rpcMode {
+		server := rpc.NewServer(os.Stdin, os.Stdout)
+		stop := notifyControls(server.RequestSnapshots, server.PauseJobs, server.ResumeJobs)
+		defer stop()
+		if err := server.Serve(); err != nil {
+			log.Fatalf("RPC server failed: %v", err)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "map" {
+		if flag.NArg() != 3 {
+			fmt.Fprintf(os.Stderr, "Error: map requires two config files\n")
+			os.Exit(1)
+		}
+		// map asks on the terminal, so it cannot be quiet, and its result is
+		// a YAML config block.
+		if *quietMode || *format != "yaml" {
+			fmt.Fprintf(os.Stderr, "Error: map asks on the terminal and prints YAML; it does not take -quiet, -porcelain or -format\n")
+			os.Exit(1)
+		}
+		block, err := proposeMappings(flag.Arg(1), flag.Arg(2))
+		if err != nil {
+			log.Fatalf("Failed to map fields: %v", err)
+		}
+		if *outputPath != "" {
+			if err := os.WriteFile(*outputPath, block, 0644); err != nil {
+				log.Fatalf("Failed to write to file %s: %v", *outputPath, err)
+			}
+			inform("Field mappings written to %s\n", *outputPath)
+		} else {
+			fmt.Print(string(block))
+		}
+		return
+	}
+
+	if flag.Arg(0) == "devgen" {
+		if flag.NArg() != 2 || flag.Arg(1) != "testcases" {
+			fmt.Fprintf(os.Stderr, "Error: usage is devgen testcases\n")
+			os.Exit(1)
+		}
+		dir := *outputPath
+		if dir == "" {
+			dir = devgen.DefaultDir
+		}
+		if err := devgen.WriteAll(dir); err != nil {
+			log.Fatalf("Failed to generate test cases: %v", err)
+		}
+		inform("Generated %d test cases in %s\n", len(devgen.Cases), dir)
+		return
+	}
+
+	if flag.Arg(0) == "soak" {
+		report, err := runSoak(flag.Args()[1:])
+		if err != nil {
+			log.Fatalf("Soak failed: %v", err)
+		}
+		data, err := output.Marshal(report, *format)
+		if err != nil {
+			log.Fatalf("Failed to marshal soak report: %v", err)
+		}
+		fmt.Print(string(data))
+		if !report.Passed {
+			os.Exit(1)
+		}
+		return
 	}
 
-	// Load configurations
-	config1, err := config.Load(*configPath1)
+	if *sniffPath != "" {
+		sniffed, err := datareader.Sniff(*sniffPath)
+		if err != nil {
+			log.Fatalf("Failed to sniff %s: %v", *sniffPath, err)
+		}
+		data, err := output.Marshal(map[string]interface{}{"source": sniffed}, *format)
+		if err != nil {
+			log.Fatalf("Failed to marshal result: %v", err)
+		}
+		fmt.Print(string(data))
+		return
+	}
+
+	if *capturePath != "" {
+		if *outputPath == "" {
+			fmt.Fprintf(os.Stderr, "Error: -capture requires -output\n")
+			os.Exit(1)
+		}
+		cfg, err := config.Load(*capturePath)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		count, err := writeCapture(*outputPath, cfg.Source)
+		if err != nil {
+			log.Fatalf("Failed to capture %s: %v", cfg.Source.Path, err)
+		}
+		inform("Captured %d records to %s\n", count, *outputPath)
+		return
+	}
+
+	if *rerunPath != "" {
+		rerun, err := comparator.Rerun(*rerunPath)
+		if err != nil {
+			log.Fatalf("Failed to rerun %s: %v", *rerunPath, err)
+		}
+		data, err := output.Marshal(rerun, *format)
+		if err != nil {
+			log.Fatalf("Failed to marshal result: %v", err)
+		}
+		fmt.Print(string(data))
+		if !rerun.Reproduced {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *baselineOf != "" {
+		baseline, err := comparator.BaselineFromReport(*baselineOf)
+		if err != nil {
+			log.Fatalf("Failed to build baseline: %v", err)
+		}
+		data, err := output.Marshal(baseline, *format)
+		if err != nil {
+			log.Fatalf("Failed to marshal baseline: %v", err)
+		}
+		if *outputPath != "" {
+			if err := os.WriteFile(*outputPath, data, 0644); err != nil {
+				log.Fatalf("Failed to write to file %s: %v", *outputPath, err)
+			}
+			inform("Baseline written to %s\n", *outputPath)
+		} else {
+			fmt.Print(string(data))
+		}
+		return
+	}
+
+	if *validate {
+		var paths []string
+		for _, p := range []string{*configPath1, *configPath2} {
+			if p != "" {
+				paths = append(paths, p)
+			}
+		}
+		if len(paths) == 0 {
+			fmt.Fprintf(os.Stderr, "Error: -validate requires -config1 and/or -config2\n")
+			os.Exit(1)
+		}
+
+		if *failOn != "" {
+			if _, err := validator.ParseSeverity(*failOn); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		var ignored []string
+		if *ignore != "" {
+			ignored = strings.Split(*ignore, ",")
+		}
+
+		result := validator.Validate(paths, validator.Options{
+			Deep:         *deep,
+			ProbeRecords: *probeCount,
+			FailOn:       *failOn,
+			Ignore:       ignored,
+		})
+		data, err := output.Marshal(result, *format)
+		if err != nil {
+			log.Fatalf("Failed to marshal result: %v", err)
+		}
+		fmt.Print(string(data))
+		if !result.Valid {
+			os.Exit(1)
+		}
+		return
+	}
+
+	var config1, config2 *config.Config
+	if *runConfig != "" {
+		run, err := config.ParseRun(*runConfig)
+		if err != nil {
+			log.Fatalf("Failed to parse -run: %v", err)
+		}
+		config1, config2 = run.Config1, run.Config2
+	} else {
+		if *configPath1 == "" || *configPath2 == "" {
+			fmt.Fprintf(os.Stderr, "Error: Both -config1 and -config2 (or -run) are required\n")
+			fmt.Fprintf(os.Stderr, "Use -help for usage information\n")
+			os.Exit(1)
+		}
+
+		// Load configurations
+		var err error
+		config1, err = config.Load(*configPath1)
+		if err != nil {
+			log.Fatalf("Failed to load config1: %v", err)
+		}
+
+		config2, err = config.Load(*configPath2)
+		if err != nil {
+			log.Fatalf("Failed to load config2: %v", err)
+		}
+	}
+
+	var failThreshold comparator.Severity
+	if *failOn != "" {
+		var err error
+		if failThreshold, err = comparator.ParseSeverity(*failOn); err != nil {
+			log.Fatalf("Invalid -fail-on: %v", err)
+		}
+	}
+
+	applyThresholdFlags(config1, config2, *maxDiffs, *maxDiffPct)
+	settings, err := comparator.Settings(config1, config2)
 	if err != nil {
-		log.Fatalf("Failed to load config1: %v", err)
+		log.Fatalf("Invalid comparison settings: %v", err)
 	}
 
-	config2, err := config.Load(*configPath2)
+	// The sources are read more than once, for their schemas, keys and the
+	// comparison, so standard input is spooled to a file first.
+	removeSpool, err := spoolStdin(&config1.Source, &config2.Source)
+	if err != nil {
+		log.Fatalf("Failed to read standard input: %v", err)
+	}
+	defer removeSpool()
+	removeReceived, err := spoolListeners(&config1.Source, &config2.Source)
 	if err != nil {
-		log.Fatalf("Failed to load config2: %v", err)
+		log.Fatalf("Failed to receive syslog messages: %v", err)
 	}
+	defer removeReceived()
 
-	// Create data readers
-	reader1, err := datareader.New(config1.Source)
+	var baseline *comparator.Baseline
+	if *knownDiffs != "" {
+		if baseline, err = comparator.LoadBaseline(*knownDiffs); err != nil {
+			log.Fatalf("Failed to load baseline: %v", err)
+		}
+	}
+
+	// A pinned schema validates the records of the comparison, or the
+	// sources alone with -schema-only.
+	var pinned *schema.Schema
+	if *schemaPath != "" {
+		if pinned, err = schema.Load(*schemaPath); err != nil {
+			log.Fatalf("Failed to load schema: %v", err)
+		}
+	}
+
+	// Multiset, ordered and windowed comparisons need no key, so they skip
+	// the schemas.
+	if settings != nil && (settings.Multiset != nil || settings.Ordered != nil || settings.Window != nil) && !*schemaOnly {
+		if pinned != nil {
+			log.Fatalf("-schema needs a keyed comparison or -schema-only")
+		}
+		if baseline != nil {
+			log.Fatalf("-baseline needs a keyed comparison")
+		}
+		var unkeyed interface{}
+		if settings.Window != nil {
+			unkeyed, err = comparator.CompareWindowedConfigs(config1, config2)
+		} else if settings.Ordered != nil {
+			unkeyed, err = comparator.CompareOrderedConfigs(config1, config2)
+		} else {
+			unkeyed, err = comparator.CompareMultisetConfigs(config1, config2)
+		}
+		if err != nil {
+			log.Fatalf("Failed to compare sources: %v", err)
+		}
+		data, err := output.Marshal(unkeyed, *format)
+		if err != nil {
+			log.Fatalf("Failed to marshal result: %v", err)
+		}
+		if *outputPath != "" {
+			if err := os.WriteFile(*outputPath, data, 0644); err != nil {
+				log.Fatalf("Failed to write to file %s: %v", *outputPath, err)
+			}
+			inform("Comparison result written to %s\n", *outputPath)
+		} else {
+			fmt.Print(string(data))
+		}
+		return
+	}
+
+	result := map[string]interface{}{}
+	var inferredKey1, inferredKey2 string
+	var schemas []*schema.Schema
+	var schemaWarnings []string
+
+	// A comparison reads the schemas only to infer the keys not configured
+	// and to list the fields compared in the JUnit report.
+	keysKnown := resolveKey(*key1, config1.Source, "") != "" && resolveKey(*key2, config2.Source, "") != ""
+	if *schemaOnly || *junitPath != "" || !keysKnown {
+		reader1, err := datareader.New(config1.Source)
+		if err != nil {
+			log.Fatalf("Failed to create reader for config1: %v", err)
+		}
+		defer reader1.Close()
+
+		reader2, err := datareader.New(config2.Source)
+		if err != nil {
+			log.Fatalf("Failed to create reader for config2: %v", err)
+		}
+		defer reader2.Close()
+
+		if pinned != nil {
+			// Use the pinned schema for both sources and validate the data against it
+			violations1, err := schema.Validate(reader1, pinned, config1.Source.Sampler)
+			if err != nil {
+				log.Fatalf("Failed to validate source1 against schema: %v", err)
+			}
+
+			violations2, err := schema.Validate(reader2, pinned, config2.Source.Sampler)
+			if err != nil {
+				log.Fatalf("Failed to validate source2 against schema: %v", err)
+			}
+
+			inferredKey1, inferredKey2 = pinned.Key, pinned.Key
+			schemas = []*schema.Schema{pinned}
+			result["schema"] = pinned
+			result["source1_violations"] = violations1
+			result["source2_violations"] = violations2
+		} else {
+			// Generate schemas
+			schema1, err := schema.Generate(reader1, config1.Source.Sampler)
+			if err != nil {
+				log.Fatalf("Failed to generate schema for config1: %v", err)
+			}
+
+			schema2, err := schema.Generate(reader2, config2.Source.Sampler)
+			if err != nil {
+				log.Fatalf("Failed to generate schema for config2: %v", err)
+			}
+
+			schema1.Key = resolveKey(*key1, config1.Source, schema1.Key)
+			schema2.Key = resolveKey(*key2, config2.Source, schema2.Key)
+			inferredKey1, inferredKey2 = schema1.Key, schema2.Key
+			schemas = []*schema.Schema{schema1, schema2}
+			for i, s := range schemas {
+				for _, warning := range s.Warnings {
+					schemaWarnings = append(schemaWarnings, fmt.Sprintf("source%d: %s", i+1, warning))
+				}
+			}
+			result["source1_schema"] = schema1
+			result["source2_schema"] = schema2
+			result["schema_diff"] = schema.Diff(schema1, schema2, schema.DiffOptions{FoldNames: *foldNames})
+		}
+	}
+
+	keyField1 := resolveKey(*key1, config1.Source, inferredKey1)
+	keyField2 := resolveKey(*key2, config2.Source, inferredKey2)
+	// An empty source has no key to infer; it is joined on the other's key.
+	if keyField1 == "" {
+		keyField1 = keyField2
+	}
+	if keyField2 == "" {
+		keyField2 = keyField1
+	}
+	result["metadata"] = map[string]interface{}{
+		"key_mapping": map[string]string{
+			"source1": keyField1,
+			"source2": keyField2,
+		},
+	}
+
+	var comparison *comparator.Result
+	if !*schemaOnly || *k8sStatus || *k8sConfig != "" || *airflowMode || *badgePath != "" || *junitPath != "" || *diffsPath != "" {
+		if keyField1 == "" || keyField2 == "" {
+			log.Fatalf("No key field found; set source.key or -key1/-key2")
+		}
+		var diffs *diffexport.Writer
+		if *diffsPath != "" {
+			if diffs, err = diffexport.Create(*diffsPath); err != nil {
+				log.Fatalf("Failed to export diffs: %v", err)
+			}
+		}
+		stop := func() {}
+		comparison, err = comparator.CompareConfigsWith(config1, config2, keyField1, keyField2, func(c *comparator.StreamComparator) {
+			if pinned != nil {
+				c.SetSchema(pinned)
+			}
+			if baseline != nil {
+				c.SetBaseline(baseline)
+			}
+			hooks := comparator.Hooks{OnSnapshot: printSnapshot}
+			if diffs != nil {
+				// Write errors are returned by Close.
+				hooks.OnDiff = func(key string, d []comparator.FieldDiff, _, _ datareader.Record) { diffs.Write(key, d) }
+				c.SetDiscardDiffs(true)
+			}
+			c.SetHooks(hooks)
+			stop = notifyControls(c.RequestSnapshot, c.Pause, c.Resume)
+		})
+		stop()
+		if diffs != nil {
+			if closeErr := diffs.Close(); closeErr != nil && err == nil {
+				log.Fatalf("Failed to export diffs: %v", closeErr)
+			}
+		}
+		if err != nil {
+			log.Fatalf("Failed to compare sources: %v", err)
+		}
+		printWarnings(comparison.Warnings)
+		if *airflowMode {
+			if err := airflow.WriteXCom(*xcomPath, comparison); err != nil {
+				log.Fatalf("Failed to write XCom: %v", err)
+			}
+		}
+		if *badgePath != "" {
+			if err := writeBadge(*badgePath, comparison); err != nil {
+				log.Fatalf("Failed to write badge: %v", err)
+			}
+		}
+		if *junitPath != "" {
+			if err := junit.Write(*junitPath, comparison, comparedFields(schemas, keyField1, keyField2)); err != nil {
+				log.Fatalf("Failed to write JUnit report: %v", err)
+			}
+		}
+		if *k8sStatus || *k8sConfig != "" {
+			status := k8s.NewStatus(comparison)
+			var data []byte
+			if *k8sConfig != "" {
+				data, err = k8s.ConfigMap(*k8sConfig, status)
+			} else {
+				data, err = k8s.StatusPatch(status)
+			}
+			if err != nil {
+				log.Fatalf("Failed to render Kubernetes status: %v", err)
+			}
+			fmt.Println(string(data))
+			exitIfFailed(comparison, failThreshold)
+			return
+		}
+	}
+
+	// Output the comparison report, which -rerun and -baseline-from read, or
+	// the schemas alone.
+	var report interface{} = result
+	if !*schemaOnly {
+		report = comparison
+	} else if comparison == nil {
+		// A comparison reads the sources again and has its own warnings.
+		printWarnings(schemaWarnings)
+	}
+	data, err := output.Marshal(report, *format)
 	if err != nil {
-		log.Fatalf("Failed to create reader for config1: %v", err)
+		log.Fatalf("Failed to marshal result: %v", err)
+	}
+
+	if *outputPath != "" {
+		err = os.WriteFile(*outputPath, data, 0644)
+		if err != nil {
+			log.Fatalf("Failed to write to file %s: %v", *outputPath, err)
+		}
+		inform("Comparison result written to %s\n", *outputPath)
+	} else {
+		fmt.Print(string(data))
+	}
+	exitIfFailed(comparison, failThreshold)
+}
+
+// printWarnings writes warnings to stderr, keeping stdout for the result.
+func printWarnings(warnings []string) {
+	for _, warning := range warnings {
+		inform("Warning: %s\n", warning)
+	}
+}
+
+// quiet suppresses the messages inform writes, with -quiet or -porcelain.
+var quiet bool
+
+// inform writes a message for people to stderr, unless quiet. Results and
+// errors are not messages.
+func inform(format string, args ...interface{}) {
+	if !quiet {
+		fmt.Fprintf(os.Stderr, format, args...)
+	}
+}
+
+// exitFailed is the exit status of a comparison that ran but exceeded its
+// thresholds or -fail-on, as opposed to status 1 of one that failed to run.
+const exitFailed = 2
+
+// exitIfFailed exits with status exitFailed, naming the thresholds exceeded,
+// if the comparison exceeds its thresholds or has findings of at least
+// severity failThreshold, if set.
+func exitIfFailed(comparison *comparator.Result, failThreshold comparator.Severity) {
+	if comparison == nil {
+		return
 	}
+	for _, exceeded := range comparison.ThresholdsExceeded {
+		inform("Threshold exceeded: %s\n", exceeded)
+	}
+	if len(comparison.ThresholdsExceeded) > 0 || failThreshold != "" && comparison.Fails(failThreshold) {
+		os.Exit(exitFailed)
+	}
+}
+
+// applyThresholdFlags sets the thresholds of the comparison settings of the
+// configs to -max-diffs and -max-diff-percent, where given, so that reports
+// embed them for -rerun. They are set in config1, and replace those of
+// config2 so the two do not conflict.
+func applyThresholdFlags(config1, config2 *config.Config, maxDiffs int, maxDiffPercent float64) {
+	if maxDiffs < 0 && maxDiffPercent < 0 {
+		return
+	}
+	if config1.Comparison == nil {
+		config1.Comparison = &config.Comparison{}
+	}
+	for _, cfg := range []*config.Config{config1, config2} {
+		settings := cfg.Comparison
+		if settings == nil || cfg == config2 && settings.Thresholds == nil {
+			continue
+		}
+		if settings.Thresholds == nil {
+			settings.Thresholds = &config.Thresholds{}
+		}
+		if maxDiffs >= 0 {
+			settings.Thresholds.MaxDiffs = &maxDiffs
+		}
+		if maxDiffPercent >= 0 {
+			settings.Thresholds.MaxDiffPercent = &maxDiffPercent
+		}
+	}
+}
+
+// runSoak parses the flags of the soak command and runs it, printing its
+// running counts to stderr every minute.
+func runSoak(args []string) (*soak.Report, error) {
+	fs := flag.NewFlagSet("soak", flag.ContinueOnError)
+	opts := soak.DefaultOptions(0, 0)
+	fs.DurationVar(&opts.Duration, "duration", time.Hour, "How long records are generated for")
+	fs.IntVar(&opts.Rate, "rate", 5000, "Records generated per second per source")
+	fs.Uint64Var(&opts.Seed, "seed", opts.Seed, "Seed of the records and their divergence")
+	fs.Float64Var(&opts.Changed, "changed", opts.Changed, "Share of records changed in source2")
+	fs.Float64Var(&opts.Dropped, "dropped", opts.Dropped, "Share of records missing from source2")
+	fs.Float64Var(&opts.Added, "added", opts.Added, "Share of records followed by a new key in source2")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	opts.Progress = printSoakProgress
+	opts.ProgressInterval = time.Minute
+	return soak.Run(opts)
+}
+
+func printSoakProgress(s comparator.Summary) {
+	inform("soak: %d records compared, %d differing, %d only in source1, %d only in source2\n",
+		s.Source1Rows, s.MatchingKeys-s.IdenticalRows, s.KeysOnlyInSource1, s.KeysOnlyInSource2)
+}
 
-	reader2, err := datareader.New(config2.Source)
+// printSnapshot writes a snapshot of the comparison in progress to stderr,
+// keeping stdout for the result.
+func printSnapshot(s comparator.Snapshot) {
+	data, err := yaml.Marshal(map[string]interface{}{"snapshot": s})
 	if err != nil {
-		log.Fatalf("Failed to create reader for config2: %v", err)
+		log.Printf("Failed to marshal snapshot to YAML: %v", err)
+		return
 	}
+	fmt.Fprint(os.Stderr, string(data))
+}
 
-	// Generate schemas
-	schema1, err := schema.Generate(reader1, config1.Source.Sampler)
+// proposeMappings samples the sources of two configs, asks on the terminal
+// which proposed field mappings to keep and returns them as a config block.
+func proposeMappings(path1, path2 string) ([]byte, error) {
+	var samples [2]*mapping.Sample
+	for i, path := range []string{path1, path2} {
+		cfg, err := config.Load(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", path, err)
+		}
+		reader, err := datareader.New(cfg.Source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create reader for %s: %w", path, err)
+		}
+		samples[i], err = mapping.SampleSource(reader, cfg.Source.Sampler)
+		reader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to sample %s: %w", path, err)
+		}
+	}
+
+	candidates := mapping.Propose(samples[0], samples[1])
+	if len(candidates) == 0 {
+		fmt.Fprintln(os.Stderr, "No field mappings to propose.")
+	}
+	mappings, err := mapping.Confirm(candidates, os.Stdin, os.Stderr)
 	if err != nil {
-		log.Fatalf("Failed to generate schema for config1: %v", err)
+		return nil, err
+	}
+	return mapping.ConfigBlock(mappings)
+}
+
+// resolveKey picks the key field of a source: the command line flag first, then
+// the source config, then the key inferred from the data.
+func resolveKey(flagValue string, src config.Source, inferred string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if src.Key != "" {
+		return src.Key
 	}
+	return inferred
+}
 
-	schema2, err := schema.Generate(reader2, config2.Source.Sampler)
+// comparedFields lists the fields of schemas other than the keys, the
+// fields a comparison of their sources checks.
+func comparedFields(schemas []*schema.Schema, keys ...string) []string {
+	var fields []string
+	for _, s := range schemas {
+		for field := range s.Fields {
+			if !slices.Contains(keys, field) && !slices.Contains(fields, field) {
+				fields = append(fields, field)
+			}
+		}
+	}
+	return fields
+}
+
+// writeBadge writes the parity badge of result, as SVG if path ends in .svg.
+func writeBadge(path string, result *comparator.Result) error {
+	b := badge.New(result)
+	data := b.SVG()
+	if !strings.HasSuffix(path, ".svg") {
+		var err error
+		if data, err = b.JSON(); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeCapture captures the records of src to the file at path.
+func writeCapture(path string, src config.Source) (int, error) {
+	f, err := os.Create(path)
 	if err != nil {
-		log.Fatalf("Failed to generate schema for config2: %v", err)
+		return 0, fmt.Errorf("failed to create %s: %w", path, err)
 	}
+	count, err := capture.Write(f, src, nil)
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write %s: %w", path, closeErr)
+	}
+	return count, err
+}
 
-	// Create comparison result
-	result := map[string]interface{}{
-		"source1_schema": schema1,
-		"source2_schema": schema2,
+// spoolStdin copies standard input to a temporary file and points the
+// source reading it there, so it can be read more than once. The returned
+// function removes the file.
+func spoolStdin(sources ...*config.Source) (func(), error) {
+	var spooled *config.Source
+	for _, src := range sources {
+		if src.Path != datareader.StdinPath || datareader.IsDatabaseType(src.Type) {
+			continue
+		}
+		if spooled != nil {
+			return nil, fmt.Errorf("only one source can read standard input")
+		}
+		spooled = src
+	}
+	if spooled == nil {
+		return func() {}, nil
 	}
 
-	// Output result
-	yamlData, err := yaml.Marshal(result)
+	f, err := os.CreateTemp("", "stream-diff-stdin-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spool file: %w", err)
+	}
+	remove := func() { os.Remove(f.Name()) }
+	_, err = io.Copy(f, os.Stdin)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
-		log.Fatalf("Failed to marshal result to YAML: %v", err)
+		remove()
+		return nil, fmt.Errorf("failed to spool standard input: %w", err)
 	}
+	spooled.Path = f.Name()
+	return remove, nil
+}
 
-	if *outputPath != "" {
-		err = os.WriteFile(*outputPath, yamlData, 0644)
+// spoolListeners receives the messages of the syslog sources listening on
+// a network address, all at once, into temporary files and points the
+// sources there, so they can be read more than once. The returned function
+// removes the files.
+func spoolListeners(sources ...*config.Source) (func(), error) {
+	var files []string
+	remove := func() {
+		for _, name := range files {
+			os.Remove(name)
+		}
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, len(sources))
+	for i, src := range sources {
+		if src.Type != "syslog" || !datareader.IsListenPath(src.Path) {
+			continue
+		}
+		f, err := os.CreateTemp("", "stream-diff-syslog-*")
 		if err != nil {
-			log.Fatalf("Failed to write to file %s: %v", *outputPath, err)
+			remove()
+			return nil, fmt.Errorf("failed to create spool file: %w", err)
 		}
-		fmt.Printf("Comparison result written to %s\n", *outputPath)
-	} else {
-		fmt.Print(string(yamlData))
+		files = append(files, f.Name())
+		inform("Receiving syslog messages on %s\n", src.Path)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			count, err := datareader.SpoolSyslog(*src, f)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", src.Path, err)
+				return
+			}
+			inform("Received %d syslog messages on %s\n", count, src.Path)
+			src.Path = f.Name()
+		}()
 	}
-}
\ No newline at end of file
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		remove()
+		return nil, err
+	}
+	return remove, nil
+}
+
+// envPrefix prefixes the environment variables that set flags.
+const envPrefix = "STREAM_DIFF_"
+
+// applyEnv sets each flag not given on the command line from its environment
+// variable, so containers can be configured without arguments or mounted files.
+func applyEnv(fs *flag.FlagSet) error {
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if given[f.Name] || err != nil {
+			return
+		}
+		name := envPrefix + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		if value, ok := os.LookupEnv(name); ok {
+			if setErr := fs.Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("invalid value %q for %s: %w", value, name, setErr)
+			}
+		}
+	})
+	return err
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// runMainEnv makes the test binary run main on its arguments instead of the
+// tests, so runMain can check what a run prints and its exit status.
+const runMainEnv = "DATA_COMPARATOR_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runMain runs the command with args and returns its stdout, stderr and
+// exit status.
+func runMain(t *testing.T, args ...string) (string, string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), runMainEnv+"=1")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatalf("failed to run %v: %v", args, err)
+	}
+	return stdout.String(), stderr.String(), cmd.ProcessState.ExitCode()
+}
+
+func TestOutputModes(t *testing.T) {
+	config1 := "testdata/testcase1_simple_csv/config1.yaml"
+	config2 := "testdata/testcase1_simple_csv/config2.yaml"
+	report := filepath.Join(t.TempDir(), "report.yaml")
+	if _, stderr, code := runMain(t, "-quiet", "-config1", config1, "-config2", config2, "-output", report); code != 0 || stderr != "" {
+		t.Fatalf("compare exit status got = %d, stderr %q, want 0 and none", code, stderr)
+	}
+
+	runs := [][]string{
+		{"-sniff", "testdata/testcase1_simple_csv/source1.csv"},
+		{"-rerun", report},
+		{"-baseline-from", report},
+		{"soak", "-duration", "100ms", "-rate", "100"},
+	}
+	for _, run := range runs {
+		// -quiet prints YAML and nothing on stderr.
+		stdout, stderr, code := runMain(t, append([]string{"-quiet"}, run...)...)
+		var v interface{}
+		if code != 0 || stderr != "" || yaml.Unmarshal([]byte(stdout), &v) != nil || v == nil {
+			t.Errorf("-quiet %v got = %d, stdout %q, stderr %q, want 0, YAML and no stderr", run, code, stdout, stderr)
+		}
+
+		// -porcelain prints a single line of JSON and nothing on stderr.
+		stdout, stderr, code = runMain(t, append([]string{"-porcelain"}, run...)...)
+		v = nil
+		if code != 0 || stderr != "" || strings.Count(stdout, "\n") != 1 || json.Unmarshal([]byte(stdout), &v) != nil || v == nil {
+			t.Errorf("-porcelain %v got = %d, stdout %q, stderr %q, want 0, a line of JSON and no stderr", run, code, stdout, stderr)
+		}
+	}
+
+	for _, flag := range []string{"-quiet", "-porcelain"} {
+		stdout, stderr, code := runMain(t, flag, "map", config1, config2)
+		if code != 1 || stdout != "" || !strings.Contains(stderr, "map asks on the terminal") {
+			t.Errorf("%s map got = %d, stdout %q, stderr %q, want it rejected", flag, code, stdout, stderr)
+		}
+	}
+}
+
+func TestPinnedSchema(t *testing.T) {
+	pinned := filepath.Join(t.TempDir(), "schema.yaml")
+	if err := os.WriteFile(pinned, []byte("key: user_id\nfields:\n  email:\n    type: string\n    matchers:\n    - regex: ^NEVERMATCH$\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	stdout, stderr, code := runMain(t, "-porcelain", "-schema", pinned,
+		"-config1", "testdata/testcase1_simple_csv/config1.yaml", "-config2", "testdata/testcase1_simple_csv/config2.yaml")
+	var report struct {
+		Summary struct {
+			Source1Violations int `json:"source1_violations"`
+			Source2Violations int `json:"source2_violations"`
+		} `json:"summary"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &report); err != nil {
+		t.Fatalf("compare got stdout %q, stderr %q, status %d: %v", stdout, stderr, code, err)
+	}
+	if report.Summary.Source1Violations == 0 || report.Summary.Source2Violations == 0 {
+		t.Errorf("compare with -schema got violations %+v, want the emails of both sources reported", report.Summary)
+	}
+}
+
+func TestBaseline(t *testing.T) {
+	config1 := "testdata/testcase1_simple_csv/config1.yaml"
+	config2 := "testdata/testcase1_simple_csv/config2.yaml"
+	dir := t.TempDir()
+	report, baseline := filepath.Join(dir, "report.yaml"), filepath.Join(dir, "baseline.yaml")
+	if _, stderr, code := runMain(t, "-quiet", "-config1", config1, "-config2", config2, "-output", report); code != 0 {
+		t.Fatalf("compare exit status got = %d, stderr %q, want 0", code, stderr)
+	}
+	if _, stderr, code := runMain(t, "-quiet", "-baseline-from", report, "-output", baseline); code != 0 {
+		t.Fatalf("-baseline-from exit status got = %d, stderr %q, want 0", code, stderr)
+	}
+
+	// Every diff of the earlier run is accepted by its baseline.
+	stdout, stderr, code := runMain(t, "-porcelain", "-baseline", baseline, "-config1", config1, "-config2", config2)
+	var got struct {
+		Summary struct {
+			MatchingKeys  int `json:"matching_keys"`
+			IdenticalRows int `json:"identical_rows"`
+		} `json:"summary"`
+		ValueDiffs map[string]interface{} `json:"value_diffs_by_key"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &got); err != nil {
+		t.Fatalf("compare got stdout %q, stderr %q, status %d: %v", stdout, stderr, code, err)
+	}
+	if len(got.ValueDiffs) != 0 || got.Summary.MatchingKeys == 0 || got.Summary.IdenticalRows != got.Summary.MatchingKeys {
+		t.Errorf("compare with -baseline got diffs %v and summary %+v, want every diff accepted", got.ValueDiffs, got.Summary)
+	}
+}
+
+func TestWindowedComparison(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"source1.json": `{"ts": "2025-01-01T00:00:00Z", "level": "info", "msg": "started"}
+{"ts": "2025-01-01T00:00:10Z", "level": "warn", "msg": "slow disk"}
+{"ts": "2025-01-01T00:01:30Z", "level": "error", "msg": "lost"}
+`,
+		"source2.json": `{"ts": "2025-01-01T00:00:01Z", "level": "info", "msg": "started"}
+{"ts": "2025-01-01T00:00:12Z", "level": "warn", "msg": "slow disk"}
+`,
+		"config1.yaml": "source:\n  type: json\n  path: " + filepath.Join(dir, "source1.json") + "\ncomparison:\n  window:\n    timestamp_field: ts\n    window: 30s\n    min_similarity: 1\n",
+		"config2.yaml": "source:\n  type: json\n  path: " + filepath.Join(dir, "source2.json") + "\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	stdout, stderr, code := runMain(t, "-porcelain", "-config1", filepath.Join(dir, "config1.yaml"), "-config2", filepath.Join(dir, "config2.yaml"))
+	var got struct {
+		Summary struct {
+			Matched            int `json:"matched"`
+			UnmatchedInSource1 int `json:"unmatched_in_source1"`
+			UnmatchedInSource2 int `json:"unmatched_in_source2"`
+		} `json:"summary"`
+		Windows []interface{} `json:"windows"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &got); err != nil || code != 0 {
+		t.Fatalf("compare got stdout %q, stderr %q, status %d: %v", stdout, stderr, code, err)
+	}
+	if got.Summary.Matched != 2 || got.Summary.UnmatchedInSource1 != 1 || got.Summary.UnmatchedInSource2 != 0 || len(got.Windows) != 2 {
+		t.Errorf("compare with comparison.window got %+v, want 2 matched, the error unmatched in source1 and 2 windows", got)
+	}
+}
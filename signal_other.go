@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+// notifyControls does nothing on systems without SIGUSR1, SIGTSTP and SIGCONT.
+func notifyControls(snapshot, pause, resume func()) (stop func()) {
+	return func() {}
+}
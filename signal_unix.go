@@ -0,0 +1,38 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyControls calls snapshot whenever the process receives SIGUSR1, pause
+// on SIGTSTP and resume on SIGCONT, until the returned stop is called.
+func notifyControls(snapshot, pause, resume func()) (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGTSTP, syscall.SIGCONT)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				switch sig {
+				case syscall.SIGUSR1:
+					snapshot()
+				case syscall.SIGTSTP:
+					pause()
+				case syscall.SIGCONT:
+					resume()
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
@@ -0,0 +1,64 @@
+#!/usr/bin/env python3
+"""Writes the Parquet interop fixtures read by TestParquetReader_Interop.
+
+Each fixture is written by pyarrow with a codec and data page version, next
+to a .jsonl file of the records the reader is expected to return. Run it
+from this directory with pyarrow installed:
+
+    python3 generate.py
+"""
+
+import json
+
+import pyarrow as pa
+import pyarrow.parquet as pq
+
+SCHEMA = pa.schema([
+    ("id", pa.int64()),
+    ("name", pa.string()),
+    ("score", pa.float64()),
+    ("tags", pa.list_(pa.string())),
+    ("attrs", pa.map_(pa.string(), pa.int64())),
+    ("points", pa.list_(pa.struct([("x", pa.int32()), ("y", pa.int32())]))),
+    ("matrix", pa.list_(pa.list_(pa.int64()))),
+    ("address", pa.struct([("city", pa.string()), ("zip", pa.int32())])),
+])
+
+ROWS = [
+    {"id": 1, "name": "alice", "score": 1.5, "tags": ["a", "b"],
+     "attrs": [("k1", 1), ("k2", None)], "points": [{"x": 1, "y": 2}],
+     "matrix": [[1, 2], [3]], "address": {"city": "Berlin", "zip": 10115}},
+    {"id": 2, "name": None, "score": 2.25, "tags": None, "attrs": [],
+     "points": [], "matrix": [[], None], "address": None},
+    {"id": 3, "name": "carol", "score": None, "tags": [None, "a"],
+     "attrs": None, "points": [{"x": 3, "y": 4}, {"x": 5, "y": 6}],
+     "matrix": [], "address": {"city": None, "zip": 20095}},
+]
+
+FIXTURES = [
+    ("pyarrow_snappy_v1", "snappy", "1.0"),
+    ("pyarrow_zstd_v2", "zstd", "2.0"),
+    ("pyarrow_gzip_v1", "gzip", "1.0"),
+]
+
+
+def expected(row):
+    """Returns row as the reader returns it, with maps as objects."""
+    out = dict(row)
+    if row["attrs"] is not None:
+        out["attrs"] = dict(row["attrs"])
+    return out
+
+
+def main():
+    table = pa.Table.from_pylist(ROWS, schema=SCHEMA)
+    for name, codec, page_version in FIXTURES:
+        pq.write_table(table, name + ".parquet", compression=codec,
+                       data_page_version=page_version, row_group_size=2)
+        with open(name + ".jsonl", "w") as f:
+            for row in ROWS:
+                f.write(json.dumps(expected(row)) + "\n")
+
+
+if __name__ == "__main__":
+    main()